@@ -22,6 +22,11 @@ import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 type DownloadRequestSpec struct {
 	// Target is what to download (e.g. logs for a backup).
 	Target DownloadTarget `json:"target"`
+
+	// LogOffset is the number of lines of a restore log that have
+	// already been read, for following the log of a restore that's in
+	// progress. Only used for RestoreLog targets. Optional.
+	LogOffset int64 `json:"logOffset,omitempty"`
 }
 
 // DownloadTargetKind represents what type of file to download.
@@ -64,6 +69,17 @@ type DownloadRequestStatus struct {
 	DownloadURL string `json:"downloadURL"`
 	// Expiration is when this DownloadRequest expires and can be deleted by the system.
 	Expiration metav1.Time `json:"expiration"`
+	// LogInProgress is true if the target is the log of a restore that's
+	// in progress, in which case there's no DownloadURL yet and the
+	// lines logged so far are in LogLines.
+	LogInProgress bool `json:"logInProgress,omitempty"`
+	// LogLines are the lines of the log of a restore that's in progress,
+	// starting at the request's LogOffset. Lines that are no longer
+	// buffered by the server are left out.
+	LogLines []string `json:"logLines,omitempty"`
+	// NextLogOffset is the LogOffset to request the lines logged after
+	// LogLines with.
+	NextLogOffset int64 `json:"nextLogOffset,omitempty"`
 }
 
 // +genclient
@@ -40,6 +40,19 @@ const (
 	// restic backups/restores).
 	PodVolumeOperationTimeoutAnnotation = "velero.io/pod-volume-timeout"
 
+	// AppliedTransformsAnnotation is the annotation key used to list the
+	// transforms that changed an item during its restore.
+	AppliedTransformsAnnotation = "velero.io/applied-transforms"
+
+	// LastRestoreAnnotation is the annotation key used to identify, by name,
+	// the most recent restore that restored items into a namespace.
+	LastRestoreAnnotation = "velero.io/last-restore"
+
+	// OriginalReplicasAnnotation is the annotation key used to record the
+	// replica count that a workload was backed up with when a restore
+	// scales it down.
+	OriginalReplicasAnnotation = "velero.io/original-replicas"
+
 	// StorageLocationLabel is the label key used to identify the storage
 	// location of a backup.
 	StorageLocationLabel = "velero.io/storage-location"
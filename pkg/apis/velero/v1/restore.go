@@ -16,7 +16,11 @@ limitations under the License.
 
 package v1
 
-import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+import (
+	corev1api "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
 
 // RestoreSpec defines the specification for a Velero restore.
 type RestoreSpec struct {
@@ -56,6 +60,12 @@ type RestoreSpec struct {
 	// or nil, all objects are included. Optional.
 	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
 
+	// LabelSelectorExclude is a metav1.LabelSelector to filter with
+	// when restoring individual objects from the backup. Objects that
+	// match it are excluded, even if they match LabelSelector. If nil,
+	// no objects are excluded. Optional.
+	LabelSelectorExclude *metav1.LabelSelector `json:"labelSelectorExclude,omitempty"`
+
 	// RestorePVs specifies whether to restore all included
 	// PVs from snapshot (via the cloudprovider).
 	RestorePVs *bool `json:"restorePVs,omitempty"`
@@ -64,8 +74,992 @@ type RestoreSpec struct {
 	// should be included for consideration in the restore. If null, defaults
 	// to true.
 	IncludeClusterResources *bool `json:"includeClusterResources,omitempty"`
+
+	// WaitForCRDConversionWebhooks specifies whether, after restoring
+	// custom resource definitions, the restore should wait for the
+	// services backing their conversion webhooks to have ready endpoints
+	// before moving on to the remaining resources. Optional.
+	WaitForCRDConversionWebhooks bool `json:"waitForCRDConversionWebhooks,omitempty"`
+
+	// WaitForCRDsEstablished specifies whether the restore waits, up to
+	// the readiness timeout, for the custom resource definitions it
+	// creates to be established, then restores the resources they add,
+	// which aren't served until then. Optional.
+	WaitForCRDsEstablished bool `json:"waitForCRDsEstablished,omitempty"`
+
+	// ReadinessTimeout is how long the restore waits for restored
+	// resources to become ready before giving up and recording a warning.
+	// If zero, a default of 10 minutes is used. Optional.
+	ReadinessTimeout metav1.Duration `json:"readinessTimeout,omitempty"`
+
+	// ImagePullPolicyOverride, if set, is applied as the image pull
+	// policy of every container in restored pods and workload pod
+	// templates. Optional.
+	ImagePullPolicyOverride corev1api.PullPolicy `json:"imagePullPolicyOverride,omitempty"`
+
+	// StripHPAManagedReplicas specifies whether to remove the replica
+	// count from restored workloads that are the scale target of a
+	// horizontal pod autoscaler in the backup, so that the autoscaler
+	// determines the replica count after the restore. Optional.
+	StripHPAManagedReplicas bool `json:"stripHPAManagedReplicas,omitempty"`
+
+	// MissingNamespacePolicy specifies how to handle items of a namespaced
+	// resource that don't have a namespace in the backup. If empty, such
+	// items are recorded as errors. Optional.
+	MissingNamespacePolicy MissingNamespacePolicy `json:"missingNamespacePolicy,omitempty"`
+
+	// DefaultNamespace is the namespace that items of a namespaced resource
+	// without a namespace are restored into when MissingNamespacePolicy is
+	// UseDefault. Optional.
+	DefaultNamespace string `json:"defaultNamespace,omitempty"`
+
+	// DataKeyMappings is a list of keys to rename or remove in the data
+	// of restored config maps and secrets. Optional.
+	DataKeyMappings []DataKeyMapping `json:"dataKeyMappings,omitempty"`
+
+	// ObserveDriftSeconds, if greater than zero, is how long the restore
+	// waits after restoring all items before re-reading each created item
+	// and recording a warning for each one whose generation or spec was
+	// changed in the cluster during that time. Optional.
+	ObserveDriftSeconds int64 `json:"observeDriftSeconds,omitempty"`
+
+	// NamespaceFanout is a map of source namespace names to lists of
+	// target namespace names to restore into, so that a source namespace's
+	// items are restored into each of the targets. For a source namespace
+	// in both this map and NamespaceMapping, this map takes precedence.
+	// Optional.
+	NamespaceFanout map[string][]string `json:"namespaceFanout,omitempty"`
+
+	// RespectBackupExclusions specifies whether namespaces that were
+	// excluded by the backup's spec are also excluded from the restore,
+	// unless they're explicitly listed in IncludedNamespaces. Optional.
+	RespectBackupExclusions bool `json:"respectBackupExclusions,omitempty"`
+
+	// GatewayClassMappings is a map of gateway class names to the names
+	// to replace them with in restored Gateway API gateways. Optional.
+	GatewayClassMappings map[string]string `json:"gatewayClassMappings,omitempty"`
+
+	// GatewayBackendRefMappings is a map of backend names to the names
+	// to replace them with in the backend references of restored Gateway
+	// API routes. Optional.
+	GatewayBackendRefMappings map[string]string `json:"gatewayBackendRefMappings,omitempty"`
+
+	// MaxObjectAge, if non-zero, is the maximum age, based on their
+	// creation timestamps in the backup, of items to restore. Older
+	// items are skipped. Optional.
+	MaxObjectAge metav1.Duration `json:"maxObjectAge,omitempty"`
+
+	// TruncateOversizeMetadata specifies whether label values and
+	// annotations that exceed Kubernetes' size limits are fixed up
+	// before restoring an item: label values are truncated, keeping a
+	// hash suffix, and the largest annotations are dropped. If false,
+	// items with oversize metadata are not restored. Optional.
+	TruncateOversizeMetadata bool `json:"truncateOversizeMetadata,omitempty"`
+
+	// KeepDefaultStorageClassAnnotation specifies whether restored storage
+	// classes keep their default class annotation even if the cluster
+	// already has a default storage class. If false, the annotation is
+	// removed in that case so the cluster doesn't end up with two default
+	// storage classes. Optional.
+	KeepDefaultStorageClassAnnotation bool `json:"keepDefaultStorageClassAnnotation,omitempty"`
+
+	// StorageClassPreferences is an ordered list of storage classes to
+	// choose from for restored persistent volume claims that will be
+	// dynamically provisioned. Each claim is given the first storage
+	// class that supports all of its access modes and its volume mode.
+	// Claims with an explicitly empty storage class are statically
+	// bound, so they keep it. Optional.
+	StorageClassPreferences []StorageClassPreference `json:"storageClassPreferences,omitempty"`
+
+	// TraceItems specifies whether the restore emits a tracing span for
+	// each item it restores, in addition to the spans for the overall
+	// restore and each resource. Optional.
+	TraceItems bool `json:"traceItems,omitempty"`
+
+	// AddTolerations is a list of rules for adding tolerations to restored
+	// pods and workload pod templates, so they can be scheduled onto
+	// tainted nodes in the target cluster. Optional.
+	AddTolerations []TolerationRule `json:"addTolerations,omitempty"`
+
+	// ExpectedCounts is a map of group-qualified resource name (e.g.
+	// "deployments.apps") to the number of items of that resource the
+	// restore is expected to create. Mismatches are reported once the
+	// restore completes. Optional.
+	ExpectedCounts map[string]int `json:"expectedCounts,omitempty"`
+
+	// StrictExpectedCounts specifies whether mismatches with ExpectedCounts
+	// are reported as errors rather than warnings. Optional.
+	StrictExpectedCounts bool `json:"strictExpectedCounts,omitempty"`
+
+	// CSIDriverMappings is a map of CSI driver names in the backup to the
+	// driver names to use for restored persistent volumes and volume
+	// snapshot contents. Optional.
+	CSIDriverMappings map[string]string `json:"csiDriverMappings,omitempty"`
+
+	// StripPriorityClass selects the pods and workloads, by their labels,
+	// whose pod specs have their priority class name and priority removed,
+	// so the target cluster's defaulting assigns them. An empty selector
+	// selects all items with a pod spec. Optional.
+	StripPriorityClass *metav1.LabelSelector `json:"stripPriorityClass,omitempty"`
+
+	// AnnotateTransforms specifies whether each restored item is annotated
+	// with the list of transforms that changed it during the restore.
+	// Optional.
+	AnnotateTransforms bool `json:"annotateTransforms,omitempty"`
+
+	// ClusterScopedOnly specifies whether the restore restores only
+	// cluster-scoped resources, skipping all namespaced items. Namespaces
+	// are not created. It's the same as a ScopeFilter of ClusterOnly, so
+	// it can't be combined with a ScopeFilter of NamespacedOnly. Optional.
+	ClusterScopedOnly bool `json:"clusterScopedOnly,omitempty"`
+
+	// SchedulerName, if set, is applied as the scheduler name of restored
+	// pods and workload pod templates, so they're scheduled by a custom
+	// scheduler in the target cluster. Optional.
+	SchedulerName string `json:"schedulerName,omitempty"`
+
+	// Parallelism is the number of items of each resource that are
+	// created concurrently. If zero, items are created one at a time.
+	// Optional.
+	Parallelism int `json:"parallelism,omitempty"`
+
+	// ResourceParallelism is a map of group-qualified resource name (e.g.
+	// "customresourcedefinitions.apiextensions.k8s.io") to the number of
+	// items of that resource that are created concurrently, overriding
+	// Parallelism. Optional.
+	ResourceParallelism map[string]int `json:"resourceParallelism,omitempty"`
+
+	// IndependentResourceParallelism is the number of resources that are
+	// restored concurrently once the resources named in the resource
+	// priorities, and those before them, are restored in order. Custom
+	// resource definitions and Gateway API resources are always restored
+	// in order. If zero, resources are restored one at a time. Optional.
+	IndependentResourceParallelism int `json:"independentResourceParallelism,omitempty"`
+
+	// DryRun specifies whether the restore only reports what it would do,
+	// without creating, patching, or provisioning anything. The restore's
+	// item results record which items would be created or provisioned from
+	// snapshots, under the "dry-run" outcome, and which would be skipped
+	// because they already exist. Items that would be created aren't counted
+	// as restored. Optional.
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// ResourcePriorities is the ordered list of resources to restore
+	// first. If set, it replaces the server's default resource priorities
+	// for this restore. Optional.
+	ResourcePriorities []string `json:"resourcePriorities,omitempty"`
+
+	// ValidateRBACReferences specifies whether to check, once the restore
+	// completes, that the roles and service accounts referenced by each
+	// restored role binding and cluster role binding exist, recording a
+	// warning for each dangling reference. Optional.
+	ValidateRBACReferences bool `json:"validateRBACReferences,omitempty"`
+
+	// Hooks represent custom behaviors that should be executed after
+	// items are restored. Optional.
+	Hooks RestoreHooks `json:"hooks,omitempty"`
+
+	// CredentialSecretMappings is a map of source secret names to target
+	// secret names. References to a source secret from pod specs (volumes,
+	// env, envFrom and image pull secrets), from service accounts, and at
+	// CredentialSecretPaths are changed to reference the target secret.
+	// The secrets themselves are not renamed. Optional.
+	CredentialSecretMappings map[string]string `json:"credentialSecretMappings,omitempty"`
+
+	// CredentialSecretPaths are dot-separated field paths, such as
+	// "spec.credentials.secretName", at which items of any resource
+	// reference a secret by name. CredentialSecretMappings is applied to
+	// the secret names found at these paths. Optional.
+	CredentialSecretPaths []string `json:"credentialSecretPaths,omitempty"`
+
+	// DecodeErrorPolicy specifies how item files in the backup that
+	// can't be decoded are recorded. Either way, the rest of the items
+	// are still restored. If not specified, they're recorded as errors.
+	// Optional.
+	DecodeErrorPolicy DecodeErrorPolicy `json:"decodeErrorPolicy,omitempty"`
+
+	// HostAliasRewrites is a map of hostnames to the IPs they should
+	// resolve to in the host aliases of restored pod specs. A hostname
+	// mapped to an empty string is removed from the host aliases.
+	// Optional.
+	HostAliasRewrites map[string]string `json:"hostAliasRewrites,omitempty"`
+
+	// KeepAnnotationPrefixes is a list of annotation key prefixes. If
+	// specified, only the annotations of restored items whose keys start
+	// with one of the prefixes are kept. Optional.
+	KeepAnnotationPrefixes []string `json:"keepAnnotationPrefixes,omitempty"`
+
+	// StripAnnotationPrefixes is a list of annotation key prefixes. The
+	// annotations of restored items whose keys start with one of the
+	// prefixes are removed, even if they match KeepAnnotationPrefixes.
+	// Optional.
+	StripAnnotationPrefixes []string `json:"stripAnnotationPrefixes,omitempty"`
+
+	// VolumeRestoreParallelism is the maximum number of pod volume
+	// restores that run concurrently, independent of Parallelism. If
+	// zero, the number isn't limited. Optional.
+	VolumeRestoreParallelism int `json:"volumeRestoreParallelism,omitempty"`
+
+	// StorageClassMappings is a map of source storage class names to
+	// target storage class names, applied to restored persistent volumes
+	// and persistent volume claims. Optional.
+	StorageClassMappings map[string]string `json:"storageClassMappings,omitempty"`
+
+	// ReportUnreferenced specifies whether to record a warning for each
+	// restored config map and secret that isn't referenced by any
+	// workload or service account the restore includes. Optional.
+	ReportUnreferenced bool `json:"reportUnreferenced,omitempty"`
+
+	// PruneUnreferenced specifies whether to skip restoring config maps
+	// and secrets that aren't referenced by any workload or service
+	// account the restore includes. Optional.
+	PruneUnreferenced bool `json:"pruneUnreferenced,omitempty"`
+
+	// ScopeFilter specifies whether the restore restores only
+	// cluster-scoped resources, only namespaced resources, or both,
+	// according to whether the cluster reports each resource as
+	// namespaced. If empty, both are restored, unless ClusterScopedOnly
+	// is set. Optional.
+	ScopeFilter ScopeFilter `json:"scopeFilter,omitempty"`
+
+	// PodSecurityPolicy specifies how the restore handles items rejected
+	// by the PodSecurity admission level enforced on their namespace. If
+	// empty, such items are recorded as errors listing the violated
+	// controls. Optional.
+	PodSecurityPolicy PodSecurityPolicy `json:"podSecurityPolicy,omitempty"`
+
+	// CSIVolumeAttributeMappings is a list of rewrites of the volume
+	// attributes of restored CSI persistent volumes. Attributes without
+	// a mapping are restored unchanged. Optional.
+	CSIVolumeAttributeMappings []CSIVolumeAttributeMapping `json:"csiVolumeAttributeMappings,omitempty"`
+
+	// ExistingResourcePolicy specifies how the restore handles items
+	// that already exist in the cluster and differ from the backed-up
+	// version. If empty, the none policy is used. Optional.
+	ExistingResourcePolicy ExistingResourcePolicy `json:"existingResourcePolicy,omitempty"`
+
+	// ServiceFieldOverrides specifies traffic policy fields to set on
+	// restored services. If nil, services keep their backed-up values.
+	// Optional.
+	ServiceFieldOverrides *ServiceFieldOverrides `json:"serviceFieldOverrides,omitempty"`
+
+	// VersionCompatibilityMode specifies how the restore handles items
+	// with fields that the cluster's Kubernetes version has removed or
+	// renamed. If empty, items are restored unchanged. Optional.
+	VersionCompatibilityMode VersionCompatibilityMode `json:"versionCompatibilityMode,omitempty"`
+
+	// PreBindVolumes specifies whether restored persistent volumes
+	// that were bound to a claim the restore also restores are created
+	// with a claim reference to the claim, in its remapped namespace if
+	// any, so that no other claim can bind to them. The bindings are
+	// checked after the restore, and a warning is recorded for each
+	// volume that isn't bound to its claim. Optional.
+	PreBindVolumes bool `json:"preBindVolumes,omitempty"`
+
+	// IncludedResourceNames is a map of group-qualified resource name
+	// (e.g. "deployments.apps") to the names of the items of that resource
+	// to restore. A name may be qualified with a namespace, either the
+	// item's namespace in the backup or the namespace it's restored into,
+	// as "namespace/name". Items of resources with a non-empty list are
+	// only restored if they're in it. Optional.
+	IncludedResourceNames map[string][]string `json:"includedResourceNames,omitempty"`
+
+	// PreRestoreBackup specifies whether the namespaces the restore
+	// restores into are backed up before any items are restored, so the
+	// restore can be reverted. The restore fails without restoring
+	// anything if the backup fails. Optional.
+	PreRestoreBackup bool `json:"preRestoreBackup,omitempty"`
+
+	// KubeconfigRewrites specifies how kubeconfigs stored in restored
+	// secrets, under the "config" or "kubeconfig" key, are rewritten
+	// to refer to the target cluster. Optional.
+	KubeconfigRewrites *KubeconfigRewrites `json:"kubeconfigRewrites,omitempty"`
+
+	// NamespaceLabels are labels added to each namespace the restore
+	// creates, overriding backed-up labels with the same keys. Namespaces
+	// that already exist get them too if the existing resource policy is
+	// update. Optional.
+	NamespaceLabels map[string]string `json:"namespaceLabels,omitempty"`
+
+	// NamespaceAnnotations are annotations added to each namespace the
+	// restore creates, overriding backed-up annotations with the same
+	// keys. Namespaces that already exist get them too if the existing
+	// resource policy is update. Optional.
+	NamespaceAnnotations map[string]string `json:"namespaceAnnotations,omitempty"`
+
+	// GenerateNamePolicy specifies how to handle items in the backup that
+	// have a generate name but no name. If empty, such items are recorded
+	// as errors. Optional.
+	GenerateNamePolicy GenerateNamePolicy `json:"generateNamePolicy,omitempty"`
+
+	// NamespaceReadyDelay is how long the restore waits after creating a
+	// namespace before restoring items into it, so admission controllers
+	// and operators can finish setting it up. Namespaces that already
+	// exist aren't waited for. Optional.
+	NamespaceReadyDelay metav1.Duration `json:"namespaceReadyDelay,omitempty"`
+
+	// ReportUnresolvedReferences specifies whether to check, once the
+	// restore completes, that the secrets, config maps, storage classes,
+	// roles, service accounts and services referenced by restored items
+	// exist. The dangling references, grouped by the type of the referenced
+	// item and the referencing item, are stored as
+	// restore-unresolved-references.json with the restore's results in
+	// backup storage, and a warning counts the items that have any.
+	// Optional.
+	ReportUnresolvedReferences bool `json:"reportUnresolvedReferences,omitempty"`
+
+	// VerifyAfterRestore specifies whether to check, once the restore
+	// completes, that each item it created or updated exists in the
+	// cluster, recording a verification failure for each missing item.
+	// Optional.
+	VerifyAfterRestore bool `json:"verifyAfterRestore,omitempty"`
+
+	// GroupAliases rewrite the API group of items in the backup, keeping
+	// their version, so that custom resources whose group has been renamed
+	// are restored under the new group. Optional.
+	GroupAliases []GroupAlias `json:"groupAliases,omitempty"`
+
+	// SuspendVeleroObjects specifies whether Velero objects included in
+	// the restore are restored in a suspended state so that they don't
+	// immediately act on the cluster, e.g. schedules are restored paused.
+	// Optional.
+	SuspendVeleroObjects bool `json:"suspendVeleroObjects,omitempty"`
+
+	// RelaxVolumeSettings specifies how the volume settings of restored
+	// pod specs are relaxed so that targets that reject privileged
+	// volume settings accept them. If set, bidirectional mount
+	// propagation is downgraded to None. Optional.
+	RelaxVolumeSettings *RelaxVolumeSettings `json:"relaxVolumeSettings,omitempty"`
+
+	// PVCMinimumSize is the smallest storage request of restored
+	// persistent volume claims that will be dynamically provisioned.
+	// Claims that request less storage are increased to it, and claims
+	// are never decreased. Optional.
+	PVCMinimumSize *resource.Quantity `json:"pvcMinimumSize,omitempty"`
+
+	// CheckpointInterval is the number of items the restore creates or
+	// updates between checkpoints of the items it has restored, which
+	// are stored in the backup storage location of the restored backup.
+	// A checkpointed restore that's still in progress when the Velero
+	// server restarts is resumed, skipping the checkpointed items. If
+	// zero, the restore isn't checkpointed. Optional.
+	CheckpointInterval int `json:"checkpointInterval,omitempty"`
+
+	// SkipRestoreLabels specifies whether to restore resources without
+	// labeling them with the restore's name and the restored backup's
+	// name. Optional.
+	SkipRestoreLabels bool `json:"skipRestoreLabels,omitempty"`
+
+	// GroupVersionKindMappings rewrite the API group, version and kind of
+	// items in the backup, so that items of deprecated APIs are restored
+	// under the APIs that replaced them. Items whose new API isn't served
+	// by the cluster are skipped with a warning. Optional.
+	GroupVersionKindMappings []GroupVersionKindMapping `json:"groupVersionKindMappings,omitempty"`
+
+	// CaptureReplay specifies whether the items the restore creates are
+	// stored, as they were created, in the backup storage location so
+	// that a later restore can replay them. Optional.
+	CaptureReplay bool `json:"captureReplay,omitempty"`
+
+	// ReplayRestoreName is the name of a restore of the same backup that
+	// captured a replay. If set, the items that restore created are
+	// re-created instead of restoring the backup. Optional.
+	ReplayRestoreName string `json:"replayRestoreName,omitempty"`
+
+	// NameRewrite specifies how the names of restored items are
+	// rewritten, along with the references to them from other items
+	// in the backup. Optional.
+	NameRewrite *NameRewrite `json:"nameRewrite,omitempty"`
+
+	// StrictResourcePriorities specifies whether the restore fails before
+	// restoring anything if any of its resource priorities names a resource
+	// the cluster doesn't serve. By default, such priorities are logged
+	// and ignored. Optional.
+	StrictResourcePriorities bool `json:"strictResourcePriorities,omitempty"`
+
+	// RestorePVCDataSelector is a metav1.LabelSelector to filter the
+	// persistent volume claims whose restic volume data is restored with.
+	// Pod volumes whose claims don't match it are restored empty, while
+	// the claims themselves are still restored. If nil, the data of all
+	// volumes is restored. Optional.
+	RestorePVCDataSelector *metav1.LabelSelector `json:"restorePVCDataSelector,omitempty"`
+
+	// NamespaceCreationPolicy specifies whether the restore creates the
+	// namespaces it restores items into. If empty, namespaces that don't
+	// exist are created. Optional.
+	NamespaceCreationPolicy NamespaceCreationPolicy `json:"namespaceCreationPolicy,omitempty"`
+
+	// SecretSubstitutions is a list of values to replace the values of
+	// keys in the data of restored secrets with. Optional.
+	SecretSubstitutions []SecretSubstitution `json:"secretSubstitutions,omitempty"`
+
+	// ExtraLabels are labels added to each restored item. Labels the item
+	// already has in the backup keep their backed-up values unless
+	// OverwriteExistingLabels is set. Optional.
+	ExtraLabels map[string]string `json:"extraLabels,omitempty"`
+
+	// OverwriteExistingLabels specifies whether the restore's extra labels
+	// replace the values of labels the restored items already have in the
+	// backup. Optional.
+	OverwriteExistingLabels bool `json:"overwriteExistingLabels,omitempty"`
+
+	// LowPriorityResources is the ordered list of resources to restore
+	// last, after all other resources, such as webhooks and network
+	// policies that could otherwise block restoring other items. A
+	// resource can't be both a resource priority and a low-priority
+	// resource. Optional.
+	LowPriorityResources []string `json:"lowPriorityResources,omitempty"`
+
+	// WaitForReady is the list of resources whose restored items the
+	// restore waits for to become ready, up to the readiness timeout,
+	// before moving on to the next resource. Pods and deployments are
+	// supported. Optional.
+	WaitForReady []string `json:"waitForReady,omitempty"`
+
+	// FailOnReadinessTimeout specifies whether items that aren't ready
+	// within the readiness timeout are restore errors rather than
+	// warnings. Optional.
+	FailOnReadinessTimeout bool `json:"failOnReadinessTimeout,omitempty"`
+
+	// SourceSubPath is the relative path, within the backup tarball, of
+	// the directory that holds the backup's resources directory, for
+	// restoring from partial or re-packaged backups. If empty, the
+	// resources directory is at the root of the tarball. Optional.
+	SourceSubPath string `json:"sourceSubPath,omitempty"`
+
+	// RemapOwnerReferences specifies whether restored items keep their
+	// owner references, rewritten to the UIDs of the owners restored
+	// before them. Owners must be restored before their dependents, so
+	// the resource priorities may need to be changed. If false, owner
+	// references aren't restored. Optional.
+	RemapOwnerReferences bool `json:"remapOwnerReferences,omitempty"`
+
+	// KeepUnresolvedOwnerReferences specifies whether owner references
+	// to owners that weren't restored are kept unchanged when owner
+	// references are remapped, rather than being dropped. Optional.
+	KeepUnresolvedOwnerReferences bool `json:"keepUnresolvedOwnerReferences,omitempty"`
+
+	// LatestOnly is a list of filters that restore only the latest
+	// revisions of the items of a resource, skipping the older ones.
+	// Optional.
+	LatestOnly []LatestOnlyFilter `json:"latestOnly,omitempty"`
+
+	// NamespaceParallelism is the number of namespaces whose items of a
+	// namespaced resource are restored concurrently, so a namespace
+	// whose items are slow to create doesn't hold up the others.
+	// Cluster-scoped resources are still restored in order. If zero,
+	// namespaces are restored one at a time. Optional.
+	NamespaceParallelism int `json:"namespaceParallelism,omitempty"`
+
+	// NamespaceTimeout is how long restoring the items of a single
+	// namespace may take, from when the first of them is restored. A
+	// namespace that times out gets an error, the rest of its items are
+	// skipped, and the hooks and readiness checks of its restored items
+	// stop waiting, so the restore moves on without it. If zero,
+	// namespaces don't time out. Optional.
+	NamespaceTimeout metav1.Duration `json:"namespaceTimeout,omitempty"`
+
+	// FieldExclusions is a map of group-qualified resource name (e.g.
+	// "deployments.apps") to the paths of fields that are removed from
+	// the items of that resource before they're restored, such as
+	// spec.nodeName or metadata.annotations["example.com/key"]. Fields
+	// an item doesn't have are ignored. Optional.
+	FieldExclusions map[string][]string `json:"fieldExclusions,omitempty"`
+
+	// KeepLeaderElectionRecords specifies whether leader election records
+	// are restored unchanged. By default, leader election annotations are
+	// removed from restored items, and the holders of restored leases are
+	// cleared, so restored controllers don't act on stale leases.
+	// Optional.
+	KeepLeaderElectionRecords bool `json:"keepLeaderElectionRecords,omitempty"`
+
+	// LeaderElectionAnnotations are the annotations that hold leader
+	// election records. If empty, the well-known
+	// control-plane.alpha.kubernetes.io/leader annotation is used.
+	// Optional.
+	LeaderElectionAnnotations []string `json:"leaderElectionAnnotations,omitempty"`
+
+	// ApplyMethod specifies how the restore creates items in the
+	// cluster. If empty, items are created.
+	// Optional.
+	ApplyMethod ApplyMethod `json:"applyMethod,omitempty"`
+
+	// ForceApply specifies whether items applied with server-side apply
+	// take over fields owned by other field managers. By default, items
+	// whose fields conflict with another field manager aren't restored,
+	// and a warning is recorded for each one. Only used when ApplyMethod
+	// is serverSideApply.
+	// Optional.
+	ForceApply bool `json:"forceApply,omitempty"`
+
+	// CheckResourceQuotas specifies whether the restore compares the
+	// resource requests of the pods and persistent volume claims it
+	// restores into each namespace against the namespace's resource
+	// quotas before restoring any items. A warning is recorded for each
+	// namespace whose quotas the restore would exceed.
+	// Optional.
+	CheckResourceQuotas bool `json:"checkResourceQuotas,omitempty"`
+
+	// NodeMappings is a list of rewrites of the node labels referenced
+	// by the node affinity of restored persistent volumes, so volumes
+	// bound to the source cluster's topology target the restore
+	// cluster's nodes. A warning is recorded for each persistent volume
+	// whose node affinity no node in the cluster satisfies. Optional.
+	NodeMappings []NodeMapping `json:"nodeMappings,omitempty"`
+
+	// ScaleDownOnRestore specifies whether restored deployments, stateful
+	// sets, replica sets and replication controllers are created with
+	// zero replicas, and deployments paused, so they can be validated
+	// before they run. The backed-up replica count is recorded in the
+	// velero.io/original-replicas annotation. Optional.
+	ScaleDownOnRestore bool `json:"scaleDownOnRestore,omitempty"`
 }
 
+// DecodeErrorPolicy defines how a restore records item files that can't be
+// decoded.
+type DecodeErrorPolicy string
+
+const (
+	// DecodeErrorPolicyError means that an item file that can't be
+	// decoded is recorded as an error, and the item as failed.
+	DecodeErrorPolicyError DecodeErrorPolicy = "Error"
+
+	// DecodeErrorPolicyWarn means that an item file that can't be
+	// decoded is recorded as a warning, and the item as skipped.
+	DecodeErrorPolicyWarn DecodeErrorPolicy = "Warn"
+)
+
+// ScopeFilter is a string representation of which scopes of resources
+// a restore restores.
+type ScopeFilter string
+
+const (
+	// ScopeFilterClusterOnly means only cluster-scoped resources are
+	// restored.
+	ScopeFilterClusterOnly ScopeFilter = "ClusterOnly"
+
+	// ScopeFilterNamespacedOnly means only namespaced resources are
+	// restored.
+	ScopeFilterNamespacedOnly ScopeFilter = "NamespacedOnly"
+
+	// ScopeFilterBoth means both cluster-scoped and namespaced resources
+	// are restored.
+	ScopeFilterBoth ScopeFilter = "Both"
+)
+
+// ExistingResourcePolicy is a string representation of how a restore
+// handles items that already exist in the cluster.
+type ExistingResourcePolicy string
+
+const (
+	// ExistingResourcePolicyNone means items that already exist in the
+	// cluster aren't restored, and a warning is recorded for each one
+	// that differs from the backed-up version.
+	ExistingResourcePolicyNone ExistingResourcePolicy = "none"
+
+	// ExistingResourcePolicySkipQuiet means items that already exist in
+	// the cluster aren't restored, and are recorded as skipped without
+	// a warning.
+	ExistingResourcePolicySkipQuiet ExistingResourcePolicy = "skipQuiet"
+
+	// ExistingResourcePolicyUpdate means items that already exist in the
+	// cluster are patched to match the backed-up version, keeping the
+	// fields that can't be changed once set. Items whose patch is
+	// rejected are recorded as skipped with a warning.
+	ExistingResourcePolicyUpdate ExistingResourcePolicy = "update"
+)
+
+// VersionCompatibilityMode is a string representation of how a restore
+// handles items with fields that the cluster's Kubernetes version has
+// removed or renamed.
+type VersionCompatibilityMode string
+
+const (
+	// VersionCompatibilityModeNone means items are restored unchanged,
+	// regardless of the cluster's Kubernetes version.
+	VersionCompatibilityModeNone VersionCompatibilityMode = "None"
+
+	// VersionCompatibilityModeFixup means fields that the cluster's
+	// Kubernetes version has removed are dropped from restored items, and
+	// renamed fields are moved to their new names, with a warning
+	// recorded for each changed item.
+	VersionCompatibilityModeFixup VersionCompatibilityMode = "Fixup"
+
+	// VersionCompatibilityModeStrict means items with fields that the
+	// cluster's Kubernetes version has removed or renamed are not
+	// restored and are recorded as errors.
+	VersionCompatibilityModeStrict VersionCompatibilityMode = "Strict"
+)
+
+// PodSecurityPolicy is a string representation of how a restore handles
+// items rejected by PodSecurity admission.
+type PodSecurityPolicy string
+
+const (
+	// PodSecurityPolicyError means items rejected by PodSecurity admission
+	// are not restored and are recorded as errors listing the violated
+	// controls.
+	PodSecurityPolicyError PodSecurityPolicy = "Error"
+
+	// PodSecurityPolicyRelaxNamespace means the PodSecurity enforce level
+	// of the namespace of an item rejected by PodSecurity admission is
+	// set to privileged for the rest of the restore, and the item is
+	// created again. The namespace's original level is put back once
+	// the restore completes.
+	PodSecurityPolicyRelaxNamespace PodSecurityPolicy = "RelaxNamespace"
+)
+
+// RestoreHooks contains custom behaviors that should be executed during
+// a restore.
+type RestoreHooks struct {
+	// Resources are hooks that should be executed when restoring
+	// individual pods.
+	Resources []RestoreResourceHookSpec `json:"resources,omitempty"`
+}
+
+// RestoreResourceHookSpec defines one or more RestoreResourceHooks that
+// should be executed for the restored pods selected by its namespaces and
+// label selector.
+type RestoreResourceHookSpec struct {
+	// Name is the name of this hook.
+	Name string `json:"name"`
+
+	// IncludedNamespaces specifies the namespaces to which this hook spec
+	// applies. If empty, it applies to all namespaces. Optional.
+	IncludedNamespaces []string `json:"includedNamespaces,omitempty"`
+
+	// ExcludedNamespaces specifies the namespaces to which this hook spec
+	// does not apply. Optional.
+	ExcludedNamespaces []string `json:"excludedNamespaces,omitempty"`
+
+	// LabelSelector, if specified, filters the pods to which this hook
+	// spec applies. Optional.
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
+
+	// PostHooks is a list of RestoreResourceHooks to execute, in order,
+	// once a restored pod is running.
+	PostHooks []RestoreResourceHook `json:"post,omitempty"`
+}
+
+// RestoreResourceHook defines a hook for a restored pod.
+type RestoreResourceHook struct {
+	// Exec defines an exec hook.
+	Exec *RestoreExecHook `json:"exec"`
+}
+
+// RestoreExecHook is a hook that uses the pod exec API to execute a
+// command in a container in a restored pod.
+type RestoreExecHook struct {
+	// Container is the container in the pod where the command should be
+	// executed. If not specified, the pod's first container is used.
+	// Optional.
+	Container string `json:"container,omitempty"`
+
+	// Command is the command and arguments to execute.
+	Command []string `json:"command"`
+
+	// OnError specifies how Velero should behave if it encounters an
+	// error executing this hook. If not specified, an error is recorded
+	// as a warning. Optional.
+	OnError HookErrorMode `json:"onError,omitempty"`
+
+	// ExecTimeout defines the maximum amount of time Velero should wait
+	// for the hook to complete before considering the execution a
+	// failure. Optional.
+	ExecTimeout metav1.Duration `json:"execTimeout,omitempty"`
+
+	// WaitTimeout defines the maximum amount of time Velero should wait
+	// for the pod to be running before considering the hook a failure.
+	// If not specified, the restore's readiness timeout is used.
+	// Optional.
+	WaitTimeout metav1.Duration `json:"waitTimeout,omitempty"`
+}
+
+// TolerationRule adds tolerations to the pod specs of restored items that
+// match a label selector.
+type TolerationRule struct {
+	// LabelSelector selects the items, by their labels, that the rule
+	// applies to. If nil, the rule applies to all items with a pod spec.
+	// Optional.
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
+
+	// Tolerations are the tolerations to add to the pod spec of each
+	// selected item. Tolerations the pod spec already has are not added
+	// again.
+	Tolerations []corev1api.Toleration `json:"tolerations"`
+}
+
+// GroupAlias renames an API group for the items of a restore.
+type GroupAlias struct {
+	// FromGroup is the API group of the items in the backup.
+	FromGroup string `json:"fromGroup"`
+
+	// ToGroup is the API group the items are restored under.
+	ToGroup string `json:"toGroup"`
+
+	// Kinds are the kinds of the items whose group is rewritten. If
+	// empty, the group of items of every kind is rewritten. Optional.
+	Kinds []string `json:"kinds,omitempty"`
+}
+
+// GroupVersionKindMapping rewrites the API group, version and kind of the
+// items of a restore.
+type GroupVersionKindMapping struct {
+	// From is the group, version and kind of the items in the backup.
+	From metav1.GroupVersionKind `json:"from"`
+
+	// To is the group, version and kind the items are restored as.
+	To metav1.GroupVersionKind `json:"to"`
+}
+
+// NameRewrite rewrites the names of restored items. If a template is set,
+// the prefix and suffix are applied to the name it produces.
+type NameRewrite struct {
+	// Prefix is prepended to the names of restored items.
+	// Optional.
+	Prefix string `json:"prefix,omitempty"`
+
+	// Suffix is appended to the names of restored items.
+	// Optional.
+	Suffix string `json:"suffix,omitempty"`
+
+	// Template is a Go template that produces the names of restored
+	// items from their backed-up names, namespaces and resources, e.g.
+	// "{{.Name}}-{{.Namespace}}". Optional.
+	Template string `json:"template,omitempty"`
+
+	// Resources is a list of the resources, in resource.group form, whose
+	// items are renamed. If empty, the items of all resources other than
+	// namespaces and custom resource definitions are renamed. Optional.
+	Resources []string `json:"resources,omitempty"`
+}
+
+// StorageClassPreference is a storage class that restored persistent volume
+// claims can be given, along with the capabilities of its provisioner.
+type StorageClassPreference struct {
+	// StorageClassName is the name of the storage class.
+	StorageClassName string `json:"storageClassName"`
+
+	// AccessModes are the access modes the storage class's provisioner
+	// supports.
+	AccessModes []corev1api.PersistentVolumeAccessMode `json:"accessModes"`
+
+	// VolumeModes are the volume modes the storage class's provisioner
+	// supports. If empty, only the Filesystem volume mode is supported.
+	// Optional.
+	VolumeModes []corev1api.PersistentVolumeMode `json:"volumeModes,omitempty"`
+}
+
+// DataKeyMapping renames or removes a key in the data of a config map
+// or secret being restored.
+type DataKeyMapping struct {
+	// Resource is the resource the mapping applies to, either
+	// "configmaps" or "secrets".
+	Resource string `json:"resource"`
+
+	// Namespace is the namespace, as stored in the backup, of the config
+	// map or secret the mapping applies to. If empty, the mapping applies
+	// in all namespaces. Optional.
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name is the name of the config map or secret the mapping applies to.
+	Name string `json:"name"`
+
+	// Key is the data key to rename or remove.
+	Key string `json:"key"`
+
+	// NewKey is the name the key is renamed to. If empty, the key is
+	// removed. Optional.
+	NewKey string `json:"newKey,omitempty"`
+}
+
+// SecretSubstitution replaces the value of a key in the data of a secret
+// being restored.
+type SecretSubstitution struct {
+	// Namespace is the namespace, as stored in the backup, of the secret
+	// the substitution applies to. If empty, the substitution applies in
+	// all namespaces. Optional.
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name is the name of the secret the substitution applies to.
+	Name string `json:"name"`
+
+	// Key is the data key whose value is replaced. Secrets without the
+	// key are left unchanged.
+	Key string `json:"key"`
+
+	// Value is the unencoded value the key's value is replaced with.
+	Value string `json:"value"`
+}
+
+// LatestOnlyFilter restores only the latest revisions of the items of a
+// resource, as ordered by a numeric field of the items.
+type LatestOnlyFilter struct {
+	// Resource is the resource whose items are filtered.
+	Resource string `json:"resource"`
+
+	// FieldPath is the path of the numeric field that orders the items'
+	// revisions, such as revision or metadata.annotations["revision"].
+	// Items whose field is missing or isn't numeric are the oldest
+	// revisions.
+	FieldPath string `json:"fieldPath"`
+
+	// Count is how many of the latest revisions are restored. If zero,
+	// only the latest revision is restored. Optional.
+	Count int `json:"count,omitempty"`
+
+	// GroupByLabel is a label whose values group the items into separate
+	// sets of revisions, in addition to their namespaces. Optional.
+	GroupByLabel string `json:"groupByLabel,omitempty"`
+}
+
+// ApplyMethod is a string representation of how a restore creates items
+// in the cluster.
+type ApplyMethod string
+
+const (
+	// ApplyMethodCreate means items are created, and items that already
+	// exist in the cluster are handled according to the restore's
+	// ExistingResourcePolicy.
+	ApplyMethodCreate ApplyMethod = "create"
+
+	// ApplyMethodServerSideApply means items are applied with server-side
+	// apply, using velero as the field manager, so items that already
+	// exist in the cluster have the fields the backed-up version sets
+	// updated to match it, and ExistingResourcePolicy isn't used.
+	ApplyMethodServerSideApply ApplyMethod = "serverSideApply"
+)
+
+// NodeMapping renames a node label referenced by the node affinity of
+// restored persistent volumes, rewrites the label's values, or both.
+type NodeMapping struct {
+	// Key is the node label to rewrite.
+	Key string `json:"key"`
+
+	// NewKey is the name the label is renamed to. If empty, the label
+	// keeps its name. Optional.
+	NewKey string `json:"newKey,omitempty"`
+
+	// Values is a map of label values in the backup to the values to
+	// restore. Values without a mapping are restored unchanged.
+	// Optional.
+	Values map[string]string `json:"values,omitempty"`
+}
+
+// CSIVolumeAttributeMapping renames a volume attribute of the restored
+// CSI persistent volumes of a driver, rewrites its values, or both.
+type CSIVolumeAttributeMapping struct {
+	// Driver is the CSI driver, as stored in the backup, of the
+	// persistent volumes the mapping applies to.
+	Driver string `json:"driver"`
+
+	// Key is the volume attribute to rewrite.
+	Key string `json:"key"`
+
+	// NewKey is the name the attribute is renamed to. If empty, the
+	// attribute keeps its name. Optional.
+	NewKey string `json:"newKey,omitempty"`
+
+	// Values is a map of attribute values in the backup to the values
+	// to restore. Values without a mapping are restored unchanged.
+	// Optional.
+	Values map[string]string `json:"values,omitempty"`
+}
+
+// ServiceFieldOverrides are values for the traffic policy fields of restored
+// services. Each field is only set on the services whose type supports it.
+type ServiceFieldOverrides struct {
+	// ExternalTrafficPolicy is set on restored NodePort and LoadBalancer
+	// services. If empty, services keep their backed-up value. Optional.
+	ExternalTrafficPolicy corev1api.ServiceExternalTrafficPolicyType `json:"externalTrafficPolicy,omitempty"`
+
+	// InternalTrafficPolicy is set on restored services other than
+	// ExternalName services. If empty, services keep their backed-up
+	// value. Optional.
+	InternalTrafficPolicy string `json:"internalTrafficPolicy,omitempty"`
+
+	// AllocateLoadBalancerNodePorts is set on restored LoadBalancer
+	// services. If nil, services keep their backed-up value. Optional.
+	AllocateLoadBalancerNodePorts *bool `json:"allocateLoadBalancerNodePorts,omitempty"`
+}
+
+// KubeconfigRewrites are the changes made to kubeconfigs stored in
+// restored secrets. Secret data that isn't a kubeconfig is left unchanged.
+type KubeconfigRewrites struct {
+	// Servers is a map of API server URLs in the backup to the URLs
+	// that restored kubeconfigs' clusters use instead. Optional.
+	Servers map[string]string `json:"servers,omitempty"`
+
+	// ClusterNames is a map of cluster names in the backup to new
+	// names, applied to clusters and to the contexts that refer to
+	// them. Optional.
+	ClusterNames map[string]string `json:"clusterNames,omitempty"`
+
+	// ContextNames is a map of context names in the backup to new
+	// names, applied to contexts and to the current context. Optional.
+	ContextNames map[string]string `json:"contextNames,omitempty"`
+}
+
+// RelaxVolumeSettings are the changes made to the volume settings of
+// restored pod specs, in addition to downgrading bidirectional mount
+// propagation.
+type RelaxVolumeSettings struct {
+	// ConvertVolumeDevices specifies whether containers' raw block
+	// volume devices are converted to volume mounts at the same paths.
+	// The claims of the converted volumes must provide filesystem
+	// volumes. Optional.
+	ConvertVolumeDevices bool `json:"convertVolumeDevices,omitempty"`
+}
+
+// MissingNamespacePolicy is a string representation of how a restore
+// handles items of a namespaced resource that don't have a namespace.
+type MissingNamespacePolicy string
+
+const (
+	// MissingNamespacePolicyError means items without a namespace are
+	// not restored and are recorded as errors.
+	MissingNamespacePolicyError MissingNamespacePolicy = "Error"
+
+	// MissingNamespacePolicyUseDefault means items without a namespace
+	// are restored into the restore's DefaultNamespace.
+	MissingNamespacePolicyUseDefault MissingNamespacePolicy = "UseDefault"
+)
+
+// NamespaceCreationPolicy is a string representation of whether a restore
+// creates the namespaces it restores items into.
+type NamespaceCreationPolicy string
+
+const (
+	// NamespaceCreationPolicyCreate means namespaces that don't exist
+	// are created before items are restored into them.
+	NamespaceCreationPolicyCreate NamespaceCreationPolicy = "Create"
+
+	// NamespaceCreationPolicyRequireExisting means namespaces are never
+	// created or changed, and items whose namespaces don't exist are not
+	// restored and are recorded as errors.
+	NamespaceCreationPolicyRequireExisting NamespaceCreationPolicy = "RequireExisting"
+
+	// NamespaceCreationPolicySkipCreate means namespaces are never created
+	// or changed, and items are restored into them whether they exist or
+	// not, e.g. because they're created by another system.
+	NamespaceCreationPolicySkipCreate NamespaceCreationPolicy = "SkipCreate"
+)
+
+// GenerateNamePolicy is a string representation of how a restore
+// handles items that have a generate name but no name.
+type GenerateNamePolicy string
+
+const (
+	// GenerateNamePolicyError means items without a name are not
+	// restored and are recorded as errors.
+	GenerateNamePolicyError GenerateNamePolicy = "Error"
+
+	// GenerateNamePolicyGenerate means items without a name are created
+	// with their generate name, and are recorded with the name the
+	// cluster assigns them.
+	GenerateNamePolicyGenerate GenerateNamePolicy = "Generate"
+)
+
 // RestorePhase is a string representation of the lifecycle phase
 // of a Velero restore
 type RestorePhase string
@@ -114,6 +1108,30 @@ type RestoreStatus struct {
 
 	// FailureReason is an error that caused the entire restore to fail.
 	FailureReason string `json:"failureReason"`
+
+	// Progress contains information about the restore's execution progress. Note
+	// that this information is best-effort only -- if Velero fails to update it
+	// during a restore for any reason, it may be inaccurate/stale.
+	Progress *RestoreProgress `json:"progress,omitempty"`
+}
+
+// RestoreProgress stores information about the restore's execution progress.
+type RestoreProgress struct {
+	// TotalItems is the number of items the restore is expected to
+	// process, counted from the backup's item files before the restore
+	// starts. Items that don't match the restore's label selectors are
+	// included in the count.
+	TotalItems int `json:"totalItems,omitempty"`
+
+	// ItemsRestored is the number of items that have been created or
+	// updated so far.
+	ItemsRestored int `json:"itemsRestored,omitempty"`
+
+	// ItemsSkipped is the number of items that have been skipped so far.
+	ItemsSkipped int `json:"itemsSkipped,omitempty"`
+
+	// Warnings is the number of warnings recorded so far.
+	Warnings int `json:"warnings,omitempty"`
 }
 
 // +genclient
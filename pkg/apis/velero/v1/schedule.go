@@ -27,6 +27,10 @@ type ScheduleSpec struct {
 	// Schedule is a Cron expression defining when to run
 	// the Backup.
 	Schedule string `json:"schedule"`
+
+	// Paused specifies whether the schedule is paused, in which case
+	// no Backups are run for it. Optional.
+	Paused bool `json:"paused,omitempty"`
 }
 
 // SchedulePhase is a string representation of the lifecycle phase
@@ -21,6 +21,7 @@ limitations under the License.
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
@@ -369,6 +370,45 @@ func (in *BackupStorageLocationStatus) DeepCopy() *BackupStorageLocationStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CSIVolumeAttributeMapping) DeepCopyInto(out *CSIVolumeAttributeMapping) {
+	*out = *in
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CSIVolumeAttributeMapping.
+func (in *CSIVolumeAttributeMapping) DeepCopy() *CSIVolumeAttributeMapping {
+	if in == nil {
+		return nil
+	}
+	out := new(CSIVolumeAttributeMapping)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataKeyMapping) DeepCopyInto(out *DataKeyMapping) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataKeyMapping.
+func (in *DataKeyMapping) DeepCopy() *DataKeyMapping {
+	if in == nil {
+		return nil
+	}
+	out := new(DataKeyMapping)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DeleteBackupRequest) DeepCopyInto(out *DeleteBackupRequest) {
 	*out = *in
@@ -549,6 +589,11 @@ func (in *DownloadRequestSpec) DeepCopy() *DownloadRequestSpec {
 func (in *DownloadRequestStatus) DeepCopyInto(out *DownloadRequestStatus) {
 	*out = *in
 	in.Expiration.DeepCopyInto(&out.Expiration)
+	if in.LogLines != nil {
+		in, out := &in.LogLines, &out.LogLines
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -600,6 +645,142 @@ func (in *ExecHook) DeepCopy() *ExecHook {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GroupAlias) DeepCopyInto(out *GroupAlias) {
+	*out = *in
+	if in.Kinds != nil {
+		in, out := &in.Kinds, &out.Kinds
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GroupAlias.
+func (in *GroupAlias) DeepCopy() *GroupAlias {
+	if in == nil {
+		return nil
+	}
+	out := new(GroupAlias)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GroupVersionKindMapping) DeepCopyInto(out *GroupVersionKindMapping) {
+	*out = *in
+	out.From = in.From
+	out.To = in.To
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GroupVersionKindMapping.
+func (in *GroupVersionKindMapping) DeepCopy() *GroupVersionKindMapping {
+	if in == nil {
+		return nil
+	}
+	out := new(GroupVersionKindMapping)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeconfigRewrites) DeepCopyInto(out *KubeconfigRewrites) {
+	*out = *in
+	if in.Servers != nil {
+		in, out := &in.Servers, &out.Servers
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ClusterNames != nil {
+		in, out := &in.ClusterNames, &out.ClusterNames
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ContextNames != nil {
+		in, out := &in.ContextNames, &out.ContextNames
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeconfigRewrites.
+func (in *KubeconfigRewrites) DeepCopy() *KubeconfigRewrites {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeconfigRewrites)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LatestOnlyFilter) DeepCopyInto(out *LatestOnlyFilter) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LatestOnlyFilter.
+func (in *LatestOnlyFilter) DeepCopy() *LatestOnlyFilter {
+	if in == nil {
+		return nil
+	}
+	out := new(LatestOnlyFilter)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NameRewrite) DeepCopyInto(out *NameRewrite) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NameRewrite.
+func (in *NameRewrite) DeepCopy() *NameRewrite {
+	if in == nil {
+		return nil
+	}
+	out := new(NameRewrite)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeMapping) DeepCopyInto(out *NodeMapping) {
+	*out = *in
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeMapping.
+func (in *NodeMapping) DeepCopy() *NodeMapping {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeMapping)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ObjectStorageLocation) DeepCopyInto(out *ObjectStorageLocation) {
 	*out = *in
@@ -831,6 +1012,22 @@ func (in *PodVolumeRestoreStatus) DeepCopy() *PodVolumeRestoreStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RelaxVolumeSettings) DeepCopyInto(out *RelaxVolumeSettings) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RelaxVolumeSettings.
+func (in *RelaxVolumeSettings) DeepCopy() *RelaxVolumeSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(RelaxVolumeSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ResticRepository) DeepCopyInto(out *ResticRepository) {
 	*out = *in
@@ -954,6 +1151,52 @@ func (in *Restore) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoreExecHook) DeepCopyInto(out *RestoreExecHook) {
+	*out = *in
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	out.ExecTimeout = in.ExecTimeout
+	out.WaitTimeout = in.WaitTimeout
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoreExecHook.
+func (in *RestoreExecHook) DeepCopy() *RestoreExecHook {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreExecHook)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoreHooks) DeepCopyInto(out *RestoreHooks) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]RestoreResourceHookSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoreHooks.
+func (in *RestoreHooks) DeepCopy() *RestoreHooks {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreHooks)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RestoreList) DeepCopyInto(out *RestoreList) {
 	*out = *in
@@ -987,6 +1230,81 @@ func (in *RestoreList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoreProgress) DeepCopyInto(out *RestoreProgress) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoreProgress.
+func (in *RestoreProgress) DeepCopy() *RestoreProgress {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreProgress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoreResourceHook) DeepCopyInto(out *RestoreResourceHook) {
+	*out = *in
+	if in.Exec != nil {
+		in, out := &in.Exec, &out.Exec
+		*out = new(RestoreExecHook)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoreResourceHook.
+func (in *RestoreResourceHook) DeepCopy() *RestoreResourceHook {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreResourceHook)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoreResourceHookSpec) DeepCopyInto(out *RestoreResourceHookSpec) {
+	*out = *in
+	if in.IncludedNamespaces != nil {
+		in, out := &in.IncludedNamespaces, &out.IncludedNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludedNamespaces != nil {
+		in, out := &in.ExcludedNamespaces, &out.ExcludedNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LabelSelector != nil {
+		in, out := &in.LabelSelector, &out.LabelSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PostHooks != nil {
+		in, out := &in.PostHooks, &out.PostHooks
+		*out = make([]RestoreResourceHook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoreResourceHookSpec.
+func (in *RestoreResourceHookSpec) DeepCopy() *RestoreResourceHookSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreResourceHookSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RestoreSpec) DeepCopyInto(out *RestoreSpec) {
 	*out = *in
@@ -1022,6 +1340,11 @@ func (in *RestoreSpec) DeepCopyInto(out *RestoreSpec) {
 		*out = new(metav1.LabelSelector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.LabelSelectorExclude != nil {
+		in, out := &in.LabelSelectorExclude, &out.LabelSelectorExclude
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.RestorePVs != nil {
 		in, out := &in.RestorePVs, &out.RestorePVs
 		*out = new(bool)
@@ -1032,6 +1355,258 @@ func (in *RestoreSpec) DeepCopyInto(out *RestoreSpec) {
 		*out = new(bool)
 		**out = **in
 	}
+	out.ReadinessTimeout = in.ReadinessTimeout
+	if in.DataKeyMappings != nil {
+		in, out := &in.DataKeyMappings, &out.DataKeyMappings
+		*out = make([]DataKeyMapping, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamespaceFanout != nil {
+		in, out := &in.NamespaceFanout, &out.NamespaceFanout
+		*out = make(map[string][]string, len(*in))
+		for key, val := range *in {
+			var outVal []string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make([]string, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.GatewayClassMappings != nil {
+		in, out := &in.GatewayClassMappings, &out.GatewayClassMappings
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.GatewayBackendRefMappings != nil {
+		in, out := &in.GatewayBackendRefMappings, &out.GatewayBackendRefMappings
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	out.MaxObjectAge = in.MaxObjectAge
+	if in.StorageClassPreferences != nil {
+		in, out := &in.StorageClassPreferences, &out.StorageClassPreferences
+		*out = make([]StorageClassPreference, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AddTolerations != nil {
+		in, out := &in.AddTolerations, &out.AddTolerations
+		*out = make([]TolerationRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ExpectedCounts != nil {
+		in, out := &in.ExpectedCounts, &out.ExpectedCounts
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.CSIDriverMappings != nil {
+		in, out := &in.CSIDriverMappings, &out.CSIDriverMappings
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.StripPriorityClass != nil {
+		in, out := &in.StripPriorityClass, &out.StripPriorityClass
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ResourceParallelism != nil {
+		in, out := &in.ResourceParallelism, &out.ResourceParallelism
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ResourcePriorities != nil {
+		in, out := &in.ResourcePriorities, &out.ResourcePriorities
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Hooks.DeepCopyInto(&out.Hooks)
+	if in.CredentialSecretMappings != nil {
+		in, out := &in.CredentialSecretMappings, &out.CredentialSecretMappings
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.CredentialSecretPaths != nil {
+		in, out := &in.CredentialSecretPaths, &out.CredentialSecretPaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.HostAliasRewrites != nil {
+		in, out := &in.HostAliasRewrites, &out.HostAliasRewrites
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.KeepAnnotationPrefixes != nil {
+		in, out := &in.KeepAnnotationPrefixes, &out.KeepAnnotationPrefixes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.StripAnnotationPrefixes != nil {
+		in, out := &in.StripAnnotationPrefixes, &out.StripAnnotationPrefixes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.StorageClassMappings != nil {
+		in, out := &in.StorageClassMappings, &out.StorageClassMappings
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.CSIVolumeAttributeMappings != nil {
+		in, out := &in.CSIVolumeAttributeMappings, &out.CSIVolumeAttributeMappings
+		*out = make([]CSIVolumeAttributeMapping, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ServiceFieldOverrides != nil {
+		in, out := &in.ServiceFieldOverrides, &out.ServiceFieldOverrides
+		*out = new(ServiceFieldOverrides)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.IncludedResourceNames != nil {
+		in, out := &in.IncludedResourceNames, &out.IncludedResourceNames
+		*out = make(map[string][]string, len(*in))
+		for key, val := range *in {
+			var outVal []string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make([]string, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.KubeconfigRewrites != nil {
+		in, out := &in.KubeconfigRewrites, &out.KubeconfigRewrites
+		*out = new(KubeconfigRewrites)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NamespaceLabels != nil {
+		in, out := &in.NamespaceLabels, &out.NamespaceLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.NamespaceAnnotations != nil {
+		in, out := &in.NamespaceAnnotations, &out.NamespaceAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	out.NamespaceReadyDelay = in.NamespaceReadyDelay
+	if in.GroupAliases != nil {
+		in, out := &in.GroupAliases, &out.GroupAliases
+		*out = make([]GroupAlias, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RelaxVolumeSettings != nil {
+		in, out := &in.RelaxVolumeSettings, &out.RelaxVolumeSettings
+		*out = new(RelaxVolumeSettings)
+		**out = **in
+	}
+	if in.PVCMinimumSize != nil {
+		in, out := &in.PVCMinimumSize, &out.PVCMinimumSize
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.GroupVersionKindMappings != nil {
+		in, out := &in.GroupVersionKindMappings, &out.GroupVersionKindMappings
+		*out = make([]GroupVersionKindMapping, len(*in))
+		copy(*out, *in)
+	}
+	if in.NameRewrite != nil {
+		in, out := &in.NameRewrite, &out.NameRewrite
+		*out = new(NameRewrite)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RestorePVCDataSelector != nil {
+		in, out := &in.RestorePVCDataSelector, &out.RestorePVCDataSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SecretSubstitutions != nil {
+		in, out := &in.SecretSubstitutions, &out.SecretSubstitutions
+		*out = make([]SecretSubstitution, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExtraLabels != nil {
+		in, out := &in.ExtraLabels, &out.ExtraLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.LowPriorityResources != nil {
+		in, out := &in.LowPriorityResources, &out.LowPriorityResources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.WaitForReady != nil {
+		in, out := &in.WaitForReady, &out.WaitForReady
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LatestOnly != nil {
+		in, out := &in.LatestOnly, &out.LatestOnly
+		*out = make([]LatestOnlyFilter, len(*in))
+		copy(*out, *in)
+	}
+	out.NamespaceTimeout = in.NamespaceTimeout
+	if in.FieldExclusions != nil {
+		in, out := &in.FieldExclusions, &out.FieldExclusions
+		*out = make(map[string][]string, len(*in))
+		for key, val := range *in {
+			var outVal []string
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make([]string, len(*in))
+				copy(*out, *in)
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.LeaderElectionAnnotations != nil {
+		in, out := &in.LeaderElectionAnnotations, &out.LeaderElectionAnnotations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NodeMappings != nil {
+		in, out := &in.NodeMappings, &out.NodeMappings
+		*out = make([]NodeMapping, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -1053,6 +1628,11 @@ func (in *RestoreStatus) DeepCopyInto(out *RestoreStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Progress != nil {
+		in, out := &in.Progress, &out.Progress
+		*out = new(RestoreProgress)
+		**out = **in
+	}
 	return
 }
 
@@ -1166,6 +1746,22 @@ func (in *ScheduleStatus) DeepCopy() *ScheduleStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretSubstitution) DeepCopyInto(out *SecretSubstitution) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretSubstitution.
+func (in *SecretSubstitution) DeepCopy() *SecretSubstitution {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretSubstitution)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ServerStatusRequest) DeepCopyInto(out *ServerStatusRequest) {
 	*out = *in
@@ -1265,6 +1861,53 @@ func (in *ServerStatusRequestStatus) DeepCopy() *ServerStatusRequestStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceFieldOverrides) DeepCopyInto(out *ServiceFieldOverrides) {
+	*out = *in
+	if in.AllocateLoadBalancerNodePorts != nil {
+		in, out := &in.AllocateLoadBalancerNodePorts, &out.AllocateLoadBalancerNodePorts
+		*out = new(bool)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceFieldOverrides.
+func (in *ServiceFieldOverrides) DeepCopy() *ServiceFieldOverrides {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceFieldOverrides)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageClassPreference) DeepCopyInto(out *StorageClassPreference) {
+	*out = *in
+	if in.AccessModes != nil {
+		in, out := &in.AccessModes, &out.AccessModes
+		*out = make([]corev1.PersistentVolumeAccessMode, len(*in))
+		copy(*out, *in)
+	}
+	if in.VolumeModes != nil {
+		in, out := &in.VolumeModes, &out.VolumeModes
+		*out = make([]corev1.PersistentVolumeMode, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageClassPreference.
+func (in *StorageClassPreference) DeepCopy() *StorageClassPreference {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageClassPreference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StorageType) DeepCopyInto(out *StorageType) {
 	*out = *in
@@ -1286,6 +1929,34 @@ func (in *StorageType) DeepCopy() *StorageType {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TolerationRule) DeepCopyInto(out *TolerationRule) {
+	*out = *in
+	if in.LabelSelector != nil {
+		in, out := &in.LabelSelector, &out.LabelSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TolerationRule.
+func (in *TolerationRule) DeepCopy() *TolerationRule {
+	if in == nil {
+		return nil
+	}
+	out := new(TolerationRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VolumeSnapshotLocation) DeepCopyInto(out *VolumeSnapshotLocation) {
 	*out = *in
@@ -17,6 +17,9 @@ limitations under the License.
 package client
 
 import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -81,6 +84,14 @@ type Patcher interface {
 	Patch(name string, data []byte) (*unstructured.Unstructured, error)
 }
 
+// Applier applies an object with server-side apply.
+type Applier interface {
+	// Apply applies the provided object as the provided field manager, creating it if it
+	// doesn't exist. If force is true, fields owned by other field managers are taken over
+	// instead of being reported as conflicts. The applied object is returned.
+	Apply(obj *unstructured.Unstructured, fieldManager string, force bool) (*unstructured.Unstructured, error)
+}
+
 // Dynamic contains client methods that Velero needs for backing up and restoring resources.
 type Dynamic interface {
 	Creator
@@ -88,6 +99,7 @@ type Dynamic interface {
 	Watcher
 	Getter
 	Patcher
+	Applier
 }
 
 // dynamicResourceClient implements Dynamic.
@@ -116,3 +128,12 @@ func (d *dynamicResourceClient) Get(name string, opts metav1.GetOptions) (*unstr
 func (d *dynamicResourceClient) Patch(name string, data []byte) (*unstructured.Unstructured, error) {
 	return d.resourceClient.Patch(name, types.MergePatchType, data, metav1.PatchOptions{})
 }
+
+func (d *dynamicResourceClient) Apply(obj *unstructured.Unstructured, fieldManager string, force bool) (*unstructured.Unstructured, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return d.resourceClient.Patch(obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{FieldManager: fieldManager, Force: &force})
+}
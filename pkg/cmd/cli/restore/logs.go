@@ -32,6 +32,8 @@ import (
 
 func NewLogsCommand(f client.Factory) *cobra.Command {
 	timeout := time.Minute
+	follow := false
+	pollInterval := 2 * time.Second
 
 	c := &cobra.Command{
 		Use:   "logs RESTORE",
@@ -53,17 +55,31 @@ func NewLogsCommand(f client.Factory) *cobra.Command {
 			switch restore.Status.Phase {
 			case v1.RestorePhaseCompleted, v1.RestorePhaseFailed, v1.RestorePhasePartiallyFailed:
 				// terminal phases, don't exit.
+			case "", v1.RestorePhaseNew, v1.RestorePhaseInProgress:
+				if follow {
+					// restores that haven't finished processing can be followed.
+					break
+				}
+				fallthrough
 			default:
 				cmd.Exit("Logs for restore %q are not available until it's finished processing. Please wait "+
 					"until the restore has a phase of Completed or Failed and try again.", restoreName)
 			}
 
+			if follow {
+				err = downloadrequest.Follow(veleroClient.VeleroV1(), f.Namespace(), restoreName, os.Stdout, timeout, pollInterval)
+				cmd.CheckError(err)
+				return
+			}
+
 			err = downloadrequest.Stream(veleroClient.VeleroV1(), f.Namespace(), restoreName, v1.DownloadTargetKindRestoreLog, os.Stdout, timeout)
 			cmd.CheckError(err)
 		},
 	}
 
 	c.Flags().DurationVar(&timeout, "timeout", timeout, "how long to wait to receive logs")
+	c.Flags().BoolVarP(&follow, "follow", "f", follow, "stream the logs of a restore that hasn't finished processing until it has")
+	c.Flags().DurationVar(&pollInterval, "poll-interval", pollInterval, "how often to check for new logs when following")
 
 	return c
 }
@@ -29,6 +29,7 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -73,6 +74,20 @@ const (
 	defaultPodVolumeOperationTimeout  = 60 * time.Minute
 	defaultResourceTerminatingTimeout = 10 * time.Minute
 
+	// how restored items' creates are retried on transient errors
+	defaultRestoreCreateMaxAttempts = 3
+	defaultRestoreCreateRetryDelay  = time.Second
+
+	// how long each get, create or patch of a restored item may take
+	defaultRestoreItemOperationTimeout = 5 * time.Minute
+
+	// how long a restore waits for the backup of its target namespaces
+	defaultPreRestoreBackupTimeout = 30 * time.Minute
+
+	// how many of the most recent log lines of a running restore are
+	// kept for following its log
+	defaultRestoreLogBufferLines = 1000
+
 	// server's client default qps and burst
 	defaultClientQPS   float32 = 20.0
 	defaultClientBurst int     = 30
@@ -119,6 +134,10 @@ type serverConfig struct {
 	clientQPS                                                               float32
 	clientBurst                                                             int
 	profilerAddress                                                         string
+	restoreCreateMaxAttempts                                                int
+	restoreCreateRetryDelay                                                 time.Duration
+	restoreItemOperationTimeout                                             time.Duration
+	restoreItemSourceDir                                                    string
 }
 
 type controllerRunInfo struct {
@@ -143,6 +162,9 @@ func NewCommand() *cobra.Command {
 			clientBurst:                    defaultClientBurst,
 			profilerAddress:                defaultProfilerAddress,
 			resourceTerminatingTimeout:     defaultResourceTerminatingTimeout,
+			restoreCreateMaxAttempts:       defaultRestoreCreateMaxAttempts,
+			restoreCreateRetryDelay:        defaultRestoreCreateRetryDelay,
+			restoreItemOperationTimeout:    defaultRestoreItemOperationTimeout,
 		}
 	)
 
@@ -204,6 +226,10 @@ func NewCommand() *cobra.Command {
 	command.Flags().IntVar(&config.clientBurst, "client-burst", config.clientBurst, "maximum number of requests by the server to the Kubernetes API in a short period of time")
 	command.Flags().StringVar(&config.profilerAddress, "profiler-address", config.profilerAddress, "the address to expose the pprof profiler")
 	command.Flags().DurationVar(&config.resourceTerminatingTimeout, "terminating-resource-timeout", config.resourceTerminatingTimeout, "how long to wait on persistent volumes and namespaces to terminate during a restore before timing out")
+	command.Flags().IntVar(&config.restoreCreateMaxAttempts, "restore-create-max-attempts", config.restoreCreateMaxAttempts, "maximum number of times to attempt creating each item during a restore when the create fails with a transient error such as a conflict, server timeout or rate limit")
+	command.Flags().DurationVar(&config.restoreCreateRetryDelay, "restore-create-retry-delay", config.restoreCreateRetryDelay, "how long to wait before retrying a restored item's failed create; the delay doubles with each retry")
+	command.Flags().DurationVar(&config.restoreItemOperationTimeout, "restore-item-operation-timeout", config.restoreItemOperationTimeout, "how long to wait for each get, create or patch of an item during a restore before recording the item as failed; zero means no timeout")
+	command.Flags().StringVar(&config.restoreItemSourceDir, "restore-item-source-dir", config.restoreItemSourceDir, "directory laid out like a backup's contents to read the items of restores from instead of their backups; the resources and namespaces restored are still those in the backups")
 	command.Flags().DurationVar(&config.defaultBackupTTL, "default-backup-ttl", config.defaultBackupTTL, "how long to wait by default before backups can be garbage collected")
 
 	return command
@@ -576,6 +602,14 @@ func (s *server) runControllers(defaultVolumeSnapshotLocations map[string]string
 
 	backupTracker := controller.NewBackupTracker()
 
+	// the restore controller buffers the logs of running restores, which
+	// the download request controller serves to followers
+	restoreLogBuffers := logging.NewLogBuffers(defaultRestoreLogBufferLines)
+
+	// the restorer stores the checkpoints and replays of running restores
+	// with the backup stores the restore controller sets up for them
+	restoreBackupStores := controller.NewRestoreBackupStores()
+
 	backupControllerRunInfo := func() controllerRunInfo {
 		backupper, err := backup.NewKubernetesBackupper(
 			s.discoveryHelper,
@@ -663,15 +697,40 @@ func (s *server) runControllers(defaultVolumeSnapshotLocations map[string]string
 	}
 
 	restoreControllerRunInfo := func() controllerRunInfo {
+		var itemSource restore.ItemSource
+		if s.config.restoreItemSourceDir != "" {
+			itemSource = restore.NewFileSystemItemSource(s.config.restoreItemSourceDir, s.logger)
+		}
+
+		// each of the restorer's requests times out on its own, so that
+		// an item operation that hangs doesn't hold up the restore
+		restoreClientConfig := rest.CopyConfig(s.kubeClientConfig)
+		restoreClientConfig.Timeout = s.config.restoreItemOperationTimeout
+		restoreDynamicClient, err := dynamic.NewForConfig(restoreClientConfig)
+		cmd.CheckError(err)
 
 		restorer, err := restore.NewKubernetesRestorer(
 			s.discoveryHelper,
-			client.NewDynamicFactory(s.dynamicClient),
+			client.NewDynamicFactory(restoreDynamicClient),
 			s.config.restoreResourcePriorities,
 			s.kubeClient.CoreV1().Namespaces(),
 			s.resticManager,
 			s.config.podVolumeOperationTimeout,
 			s.config.resourceTerminatingTimeout,
+			restore.CreateRetryPolicy{
+				MaxAttempts: s.config.restoreCreateMaxAttempts,
+				BaseDelay:   s.config.restoreCreateRetryDelay,
+			},
+			s.config.restoreItemOperationTimeout,
+			podexec.NewPodCommandExecutor(s.kubeClientConfig, s.kubeClient.CoreV1().RESTClient()),
+			nil, // tracer
+			controller.NewRestoreProgressUpdater(s.veleroClient.VeleroV1()),
+			controller.NewRestoreCheckpointer(restoreBackupStores),
+			controller.NewRestoreReplayWriterFactory(restoreBackupStores),
+			controller.NewRestoreUnresolvedReferencesWriter(restoreBackupStores),
+			controller.NewPreRestoreBackupper(s.veleroClient.VeleroV1(), s.namespace, defaultPreRestoreBackupTimeout),
+			itemSource,
+			prometheus.DefaultRegisterer,
 			s.logger,
 		)
 		cmd.CheckError(err)
@@ -687,8 +746,11 @@ func (s *server) runControllers(defaultVolumeSnapshotLocations map[string]string
 			s.sharedInformerFactory.Velero().V1().VolumeSnapshotLocations(),
 			s.logger,
 			s.logLevel,
+			restoreLogBuffers,
+			restoreBackupStores,
 			newPluginManager,
 			s.config.defaultBackupLocation,
+			s.config.restoreResourcePriorities,
 			s.metrics,
 		)
 
@@ -720,6 +782,7 @@ func (s *server) runControllers(defaultVolumeSnapshotLocations map[string]string
 			s.sharedInformerFactory.Velero().V1().Restores(),
 			s.sharedInformerFactory.Velero().V1().BackupStorageLocations(),
 			s.sharedInformerFactory.Velero().V1().Backups(),
+			restoreLogBuffers,
 			newPluginManager,
 			s.logger,
 		)
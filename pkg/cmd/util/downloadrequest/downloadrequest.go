@@ -17,6 +17,7 @@ limitations under the License.
 package downloadrequest
 
 import (
+	"bytes"
 	"compress/gzip"
 	"fmt"
 	"io"
@@ -33,6 +34,54 @@ import (
 )
 
 func Stream(client velerov1client.DownloadRequestsGetter, namespace, name string, kind v1.DownloadTargetKind, w io.Writer, timeout time.Duration) error {
+	req, err := process(client, namespace, name, kind, 0, timeout)
+	if err != nil {
+		return err
+	}
+
+	if req.Status.DownloadURL == "" {
+		return errors.New("file not found")
+	}
+
+	return download(req.Status.DownloadURL, kind, w)
+}
+
+// Follow writes the log of the specified restore to w, including the lines that are
+// logged while the restore is processed, until the restore has finished and its log
+// has been uploaded. Lines are polled for every pollInterval.
+func Follow(client velerov1client.DownloadRequestsGetter, namespace, name string, w io.Writer, timeout, pollInterval time.Duration) error {
+	var offset int64
+	for {
+		req, err := process(client, namespace, name, v1.DownloadTargetKindRestoreLog, offset, timeout)
+		if err != nil {
+			return err
+		}
+
+		if !req.Status.LogInProgress {
+			if req.Status.DownloadURL == "" {
+				return errors.New("file not found")
+			}
+
+			// the uploaded log starts with the lines that have already
+			// been written
+			return download(req.Status.DownloadURL, v1.DownloadTargetKindRestoreLog, &lineSkipper{w: w, skip: offset})
+		}
+
+		for _, line := range req.Status.LogLines {
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		offset = req.Status.NextLogOffset
+
+		time.Sleep(pollInterval)
+	}
+}
+
+// process creates a download request for the specified target, starting at logOffset
+// if the target is an in-progress restore's log, and waits for it to be processed. The
+// download request is deleted before returning.
+func process(client velerov1client.DownloadRequestsGetter, namespace, name string, kind v1.DownloadTargetKind, logOffset int64, timeout time.Duration) (*v1.DownloadRequest, error) {
 	req := &v1.DownloadRequest{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: namespace,
@@ -43,12 +92,13 @@ func Stream(client velerov1client.DownloadRequestsGetter, namespace, name string
 				Kind: kind,
 				Name: name,
 			},
+			LogOffset: logOffset,
 		},
 	}
 
 	req, err := client.DownloadRequests(namespace).Create(req)
 	if err != nil {
-		return errors.WithStack(err)
+		return nil, errors.WithStack(err)
 	}
 	defer client.DownloadRequests(namespace).Delete(req.Name, nil)
 
@@ -60,22 +110,21 @@ func Stream(client velerov1client.DownloadRequestsGetter, namespace, name string
 	}
 	watcher, err := client.DownloadRequests(namespace).Watch(listOptions)
 	if err != nil {
-		return errors.WithStack(err)
+		return nil, errors.WithStack(err)
 	}
 	defer watcher.Stop()
 
 	expired := time.NewTimer(timeout)
 	defer expired.Stop()
 
-Loop:
 	for {
 		select {
 		case <-expired.C:
-			return errors.New("timed out waiting for download URL")
+			return nil, errors.New("timed out waiting for download URL")
 		case e := <-watcher.ResultChan():
 			updated, ok := e.Object.(*v1.DownloadRequest)
 			if !ok {
-				return errors.Errorf("unexpected type %T", e.Object)
+				return nil, errors.Errorf("unexpected type %T", e.Object)
 			}
 
 			// TODO: once the minimum supported Kubernetes version is v1.9.0, remove the following check.
@@ -88,21 +137,20 @@ Loop:
 			case watch.Deleted:
 				errors.New("download request was unexpectedly deleted")
 			case watch.Modified:
-				if updated.Status.DownloadURL != "" {
-					req = updated
-					break Loop
+				if updated.Status.DownloadURL != "" || updated.Status.LogInProgress {
+					return updated, nil
 				}
 			}
 		}
 	}
+}
 
-	if req.Status.DownloadURL == "" {
-		return errors.New("file not found")
-	}
-
+// download writes the content at the provided download URL to w, decompressing
+// it unless kind is backup contents.
+func download(url string, kind v1.DownloadTargetKind, w io.Writer) error {
 	httpClient := new(http.Client)
 
-	httpReq, err := http.NewRequest("GET", req.Status.DownloadURL, nil)
+	httpReq, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return err
 	}
@@ -141,3 +189,29 @@ Loop:
 	_, err = io.Copy(w, reader)
 	return err
 }
+
+// lineSkipper is an io.Writer that discards the first skip lines written to it
+// and writes the rest to w.
+type lineSkipper struct {
+	w    io.Writer
+	skip int64
+}
+
+func (s *lineSkipper) Write(p []byte) (int, error) {
+	n := len(p)
+	for s.skip > 0 && len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			return n, nil
+		}
+		p = p[i+1:]
+		s.skip--
+	}
+
+	if len(p) > 0 {
+		if _, err := s.w.Write(p); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
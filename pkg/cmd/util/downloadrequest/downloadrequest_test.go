@@ -209,6 +209,75 @@ func TestStream(t *testing.T) {
 	}
 }
 
+func TestFollow(t *testing.T) {
+	client := fake.NewSimpleClientset()
+
+	created := make(chan *v1.DownloadRequest, 1)
+	client.PrependReactor("create", "downloadrequests", func(action core.Action) (bool, runtime.Object, error) {
+		createAction := action.(core.CreateAction)
+		created <- createAction.GetObject().(*v1.DownloadRequest)
+		return true, createAction.GetObject(), nil
+	})
+
+	// each download request gets a watch of its own
+	watches := make(chan *watch.FakeWatcher, 1)
+	client.PrependWatchReactor("downloadrequests", func(action core.Action) (bool, watch.Interface, error) {
+		fakeWatch := watch.NewFake()
+		watches <- fakeWatch
+		return true, fakeWatch, nil
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		gzipWriter := gzip.NewWriter(w)
+		fmt.Fprintf(gzipWriter, "line 1\nline 2\nline 3\nline 4\n")
+		gzipWriter.Close()
+	}))
+	defer server.Close()
+
+	const testTimeout = 30 * time.Second
+
+	output := new(bytes.Buffer)
+	errCh := make(chan error)
+	go func() {
+		errCh <- Follow(client.VeleroV1(), "namespace", "name", output, testTimeout, time.Millisecond)
+	}()
+
+	// the first two requests get the lines of the in-progress restore's
+	// log, and the last one its uploaded log
+	var offsets []int64
+	for _, status := range []v1.DownloadRequestStatus{
+		{LogInProgress: true, LogLines: []string{"line 1"}, NextLogOffset: 1},
+		{LogInProgress: true, LogLines: []string{"line 2", "line 3"}, NextLogOffset: 3},
+		{DownloadURL: server.URL},
+	} {
+		var req *v1.DownloadRequest
+		select {
+		case req = <-created:
+		case <-time.After(testTimeout):
+			t.Fatal("created object not received")
+		}
+		offsets = append(offsets, req.Spec.LogOffset)
+
+		req.Status = status
+		select {
+		case fakeWatch := <-watches:
+			fakeWatch.Modify(req)
+		case <-time.After(testTimeout):
+			t.Fatal("watch not created")
+		}
+	}
+
+	select {
+	case err := <-errCh:
+		require.NoError(t, err)
+	case <-time.After(testTimeout):
+		t.Fatal("test timed out")
+	}
+
+	assert.Equal(t, []int64{0, 1, 3}, offsets)
+	assert.Equal(t, "line 1\nline 2\nline 3\nline 4\n", output.String())
+}
+
 type downloadRequest struct {
 	*v1.DownloadRequest
 }
@@ -154,6 +154,9 @@ func describeRestoreResult(d *Describer, name string, result pkgrestore.Result)
 			d.DescribeSlice(2, ns, warnings)
 		}
 	}
+	if len(result.VerificationFailures) > 0 {
+		d.DescribeSlice(1, "Verification Failures", result.VerificationFailures)
+	}
 }
 
 // describePodVolumeRestores describes pod volume restores in human-readable format.
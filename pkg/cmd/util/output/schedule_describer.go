@@ -52,6 +52,9 @@ func DescribeSchedule(schedule *v1.Schedule) string {
 
 func DescribeScheduleSpec(d *Describer, spec v1.ScheduleSpec) {
 	d.Printf("Schedule:\t%s\n", spec.Schedule)
+	if spec.Paused {
+		d.Printf("Paused:\t%t\n", spec.Paused)
+	}
 
 	d.Println()
 	d.Println("Backup Template:")
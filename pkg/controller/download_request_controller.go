@@ -37,6 +37,7 @@ import (
 	"github.com/heptio/velero/pkg/persistence"
 	"github.com/heptio/velero/pkg/plugin/clientmgmt"
 	"github.com/heptio/velero/pkg/util/kube"
+	"github.com/heptio/velero/pkg/util/logging"
 )
 
 type downloadRequestController struct {
@@ -45,6 +46,7 @@ type downloadRequestController struct {
 	downloadRequestClient velerov1client.DownloadRequestsGetter
 	downloadRequestLister listers.DownloadRequestLister
 	restoreLister         listers.RestoreLister
+	restoreLogBuffers     *logging.LogBuffers
 	clock                 clock.Clock
 	backupLocationLister  listers.BackupStorageLocationLister
 	backupLister          listers.BackupLister
@@ -59,6 +61,7 @@ func NewDownloadRequestController(
 	restoreInformer informers.RestoreInformer,
 	backupLocationInformer informers.BackupStorageLocationInformer,
 	backupInformer informers.BackupInformer,
+	restoreLogBuffers *logging.LogBuffers,
 	newPluginManager func(logrus.FieldLogger) clientmgmt.Manager,
 	logger logrus.FieldLogger,
 ) Interface {
@@ -69,6 +72,7 @@ func NewDownloadRequestController(
 		restoreLister:         restoreInformer.Lister(),
 		backupLocationLister:  backupLocationInformer.Lister(),
 		backupLister:          backupInformer.Lister(),
+		restoreLogBuffers:     restoreLogBuffers,
 
 		// use variables to refer to these functions so they can be
 		// replaced with fakes for testing.
@@ -156,6 +160,11 @@ func (c *downloadRequestController) generatePreSignedURL(downloadRequest *v1.Dow
 			return errors.Wrap(err, "error getting Restore")
 		}
 
+		// the log of a restore that hasn't finished isn't in storage yet
+		if downloadRequest.Spec.Target.Kind == v1.DownloadTargetKindRestoreLog && !isRestoreFinished(restore) {
+			return c.readInProgressRestoreLog(downloadRequest, restore)
+		}
+
 		backupName = restore.Spec.BackupName
 	default:
 		backupName = downloadRequest.Spec.Target.Name
@@ -190,6 +199,39 @@ func (c *downloadRequestController) generatePreSignedURL(downloadRequest *v1.Dow
 	return errors.WithStack(err)
 }
 
+// isRestoreFinished returns true if the provided restore has finished processing, in
+// which case its log has been uploaded to storage.
+func isRestoreFinished(restore *v1.Restore) bool {
+	switch restore.Status.Phase {
+	case "", v1.RestorePhaseNew, v1.RestorePhaseInProgress:
+		return false
+	default:
+		return true
+	}
+}
+
+// readInProgressRestoreLog sets the status of downloadRequest to the lines of the provided
+// restore's log that have been buffered since the request's log offset, changes the phase
+// to Processed, and persists the changes to storage. Restores that haven't started or
+// whose log is being uploaded don't have any buffered lines.
+func (c *downloadRequestController) readInProgressRestoreLog(downloadRequest *v1.DownloadRequest, restore *v1.Restore) error {
+	update := downloadRequest.DeepCopy()
+
+	update.Status.LogInProgress = true
+	update.Status.NextLogOffset = downloadRequest.Spec.LogOffset
+	if c.restoreLogBuffers != nil {
+		if buffer := c.restoreLogBuffers.Get(kube.NamespaceAndName(restore)); buffer != nil {
+			update.Status.LogLines, update.Status.NextLogOffset = buffer.Lines(downloadRequest.Spec.LogOffset)
+		}
+	}
+
+	update.Status.Phase = v1.DownloadRequestPhaseProcessed
+	update.Status.Expiration = metav1.NewTime(c.clock.Now().Add(persistence.DownloadURLTTL))
+
+	_, err := patchDownloadRequest(downloadRequest, update, c.downloadRequestClient)
+	return errors.WithStack(err)
+}
+
 // deleteIfExpired deletes downloadRequest if it has expired.
 func (c *downloadRequestController) deleteIfExpired(downloadRequest *v1.DownloadRequest) error {
 	log := c.logger.WithField("key", kube.NamespaceAndName(downloadRequest))
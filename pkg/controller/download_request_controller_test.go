@@ -17,11 +17,13 @@ limitations under the License.
 package controller
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -35,6 +37,7 @@ import (
 	"github.com/heptio/velero/pkg/plugin/clientmgmt"
 	pluginmocks "github.com/heptio/velero/pkg/plugin/mocks"
 	kubeutil "github.com/heptio/velero/pkg/util/kube"
+	"github.com/heptio/velero/pkg/util/logging"
 	velerotest "github.com/heptio/velero/pkg/util/test"
 )
 
@@ -43,6 +46,7 @@ type downloadRequestTestHarness struct {
 	informerFactory informers.SharedInformerFactory
 	pluginManager   *pluginmocks.Manager
 	backupStore     *persistencemocks.BackupStore
+	logBuffers      *logging.LogBuffers
 
 	controller *downloadRequestController
 }
@@ -53,12 +57,14 @@ func newDownloadRequestTestHarness(t *testing.T) *downloadRequestTestHarness {
 		informerFactory = informers.NewSharedInformerFactory(client, 0)
 		pluginManager   = new(pluginmocks.Manager)
 		backupStore     = new(persistencemocks.BackupStore)
+		logBuffers      = logging.NewLogBuffers(10)
 		controller      = NewDownloadRequestController(
 			client.VeleroV1(),
 			informerFactory.Velero().V1().DownloadRequests(),
 			informerFactory.Velero().V1().Restores(),
 			informerFactory.Velero().V1().BackupStorageLocations(),
 			informerFactory.Velero().V1().Backups(),
+			logBuffers,
 			func(logrus.FieldLogger) clientmgmt.Manager { return pluginManager },
 			velerotest.NewLogger(),
 		).(*downloadRequestController)
@@ -79,6 +85,7 @@ func newDownloadRequestTestHarness(t *testing.T) *downloadRequestTestHarness {
 		informerFactory: informerFactory,
 		pluginManager:   pluginManager,
 		backupStore:     backupStore,
+		logBuffers:      logBuffers,
 		controller:      controller,
 	}
 }
@@ -313,3 +320,64 @@ func TestProcessDownloadRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestProcessInProgressRestoreLogRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		phase     v1.RestorePhase
+		logged    []string
+		offset    int64
+		wantLines []string
+		wantNext  int64
+	}{
+		{
+			name:      "the lines logged since the offset by a restore in progress are returned",
+			phase:     v1.RestorePhaseInProgress,
+			logged:    []string{"line-0", "line-1", "line-2"},
+			offset:    1,
+			wantLines: []string{"line-1", "line-2"},
+			wantNext:  3,
+		},
+		{
+			name:     "a restore that hasn't started doesn't have any lines",
+			phase:    v1.RestorePhaseNew,
+			offset:   0,
+			wantNext: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			harness := newDownloadRequestTestHarness(t)
+
+			restore := velerotest.NewTestRestore(v1.DefaultNamespace, "a-restore", tc.phase).WithBackup("a-backup").Restore
+			require.NoError(t, harness.informerFactory.Velero().V1().Restores().Informer().GetStore().Add(restore))
+
+			if tc.logged != nil {
+				buffer := harness.logBuffers.Add(kubeutil.NamespaceAndName(restore))
+				for _, line := range tc.logged {
+					fmt.Fprintln(buffer, line)
+				}
+			}
+
+			downloadRequest := newDownloadRequest("", v1.DownloadTargetKindRestoreLog, "a-restore")
+			downloadRequest.Spec.LogOffset = tc.offset
+			require.NoError(t, harness.informerFactory.Velero().V1().DownloadRequests().Informer().GetStore().Add(downloadRequest))
+			_, err := harness.client.VeleroV1().DownloadRequests(downloadRequest.Namespace).Create(downloadRequest)
+			require.NoError(t, err)
+
+			require.NoError(t, harness.controller.processDownloadRequest(kubeutil.NamespaceAndName(downloadRequest)))
+
+			// the log isn't in storage yet, so no URL is requested
+			harness.backupStore.AssertNotCalled(t, "GetDownloadURL", mock.Anything)
+
+			output, err := harness.client.VeleroV1().DownloadRequests(downloadRequest.Namespace).Get(downloadRequest.Name, metav1.GetOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, v1.DownloadRequestPhaseProcessed, output.Status.Phase)
+			assert.True(t, output.Status.LogInProgress)
+			assert.Empty(t, output.Status.DownloadURL)
+			assert.Equal(t, tc.wantLines, output.Status.LogLines)
+			assert.Equal(t, tc.wantNext, output.Status.NextLogOffset)
+		})
+	}
+}
@@ -25,25 +25,32 @@ import (
 	"io/ioutil"
 	"os"
 	"sort"
+	"sync"
 	"time"
 
 	jsonpatch "github.com/evanphx/json-patch"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/clock"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/cache"
 
 	api "github.com/heptio/velero/pkg/apis/velero/v1"
 	velerov1api "github.com/heptio/velero/pkg/apis/velero/v1"
+	pkgbackup "github.com/heptio/velero/pkg/backup"
 	velerov1client "github.com/heptio/velero/pkg/generated/clientset/versioned/typed/velero/v1"
 	informers "github.com/heptio/velero/pkg/generated/informers/externalversions/velero/v1"
 	listers "github.com/heptio/velero/pkg/generated/listers/velero/v1"
+	"github.com/heptio/velero/pkg/label"
 	"github.com/heptio/velero/pkg/metrics"
 	"github.com/heptio/velero/pkg/persistence"
 	"github.com/heptio/velero/pkg/plugin/clientmgmt"
 	pkgrestore "github.com/heptio/velero/pkg/restore"
+	"github.com/heptio/velero/pkg/util/boolptr"
 	"github.com/heptio/velero/pkg/util/collections"
 	kubeutil "github.com/heptio/velero/pkg/util/kube"
 	"github.com/heptio/velero/pkg/util/logging"
@@ -82,7 +89,10 @@ type restoreController struct {
 	backupLocationLister   listers.BackupStorageLocationLister
 	snapshotLocationLister listers.VolumeSnapshotLocationLister
 	restoreLogLevel        logrus.Level
+	restoreLogBuffers      *logging.LogBuffers
+	restoreBackupStores    *RestoreBackupStores
 	defaultBackupLocation  string
+	defaultPriorities      []string
 	metrics                *metrics.ServerMetrics
 
 	newPluginManager func(logger logrus.FieldLogger) clientmgmt.Manager
@@ -100,8 +110,11 @@ func NewRestoreController(
 	snapshotLocationInformer informers.VolumeSnapshotLocationInformer,
 	logger logrus.FieldLogger,
 	restoreLogLevel logrus.Level,
+	restoreLogBuffers *logging.LogBuffers,
+	restoreBackupStores *RestoreBackupStores,
 	newPluginManager func(logrus.FieldLogger) clientmgmt.Manager,
 	defaultBackupLocation string,
+	defaultResourcePriorities []string,
 	metrics *metrics.ServerMetrics,
 ) Interface {
 	c := &restoreController{
@@ -115,7 +128,10 @@ func NewRestoreController(
 		backupLocationLister:   backupLocationInformer.Lister(),
 		snapshotLocationLister: snapshotLocationInformer.Lister(),
 		restoreLogLevel:        restoreLogLevel,
+		restoreLogBuffers:      restoreLogBuffers,
+		restoreBackupStores:    restoreBackupStores,
 		defaultBackupLocation:  defaultBackupLocation,
+		defaultPriorities:      defaultResourcePriorities,
 		metrics:                metrics,
 
 		// use variables to refer to these functions so they can be
@@ -139,9 +155,13 @@ func NewRestoreController(
 			AddFunc: func(obj interface{}) {
 				restore := obj.(*api.Restore)
 
-				switch restore.Status.Phase {
-				case "", api.RestorePhaseNew:
+				switch {
+				case restore.Status.Phase == "", restore.Status.Phase == api.RestorePhaseNew:
 					// only process new restores
+				case isResumable(restore):
+					// restores that were interrupted by a server restart
+					// resume from their last checkpoint
+					c.logger.WithField("restore", kubeutil.NamespaceAndName(restore)).Info("Resuming checkpointed restore")
 				default:
 					c.logger.WithFields(logrus.Fields{
 						"restore": kubeutil.NamespaceAndName(restore),
@@ -196,9 +216,11 @@ func (c *restoreController) processQueueItem(key string) error {
 	// state to something else. So any time it's re-queued it will
 	// still have its initial state, which we've already confirmed
 	// is ("" | New)
-	switch restore.Status.Phase {
-	case "", api.RestorePhaseNew:
+	switch {
+	case restore.Status.Phase == "", restore.Status.Phase == api.RestorePhaseNew:
 		// only process new restores
+	case isResumable(restore):
+		// and checkpointed restores that are being resumed
 	default:
 		return nil
 	}
@@ -209,6 +231,12 @@ func (c *restoreController) processQueueItem(key string) error {
 	return c.processRestore(restore.DeepCopy())
 }
 
+// isResumable returns true if the provided restore was checkpointed and was still in
+// progress when the server stopped, so that it can resume from its last checkpoint.
+func isResumable(restore *api.Restore) bool {
+	return restore.Status.Phase == api.RestorePhaseInProgress && restore.Spec.CheckpointInterval > 0
+}
+
 func (c *restoreController) processRestore(restore *api.Restore) error {
 	// Developer note: any error returned by this method will
 	// cause the restore to be re-enqueued and re-processed by
@@ -306,6 +334,80 @@ func (c *restoreController) validateAndComplete(restore *api.Restore, pluginMana
 		restore.Status.ValidationErrors = append(restore.Status.ValidationErrors, fmt.Sprintf("Invalid included/excluded namespace lists: %v", err))
 	}
 
+	// validate that no two namespaces are mapped to the same namespace
+	for _, err := range pkgrestore.ValidateNamespaceMapping(restore.Spec.NamespaceMapping) {
+		restore.Status.ValidationErrors = append(restore.Status.ValidationErrors, fmt.Sprintf("Invalid namespace mapping: %v", err))
+	}
+
+	// validate the name rewrite's template
+	for _, err := range pkgrestore.ValidateNameRewrite(restore.Spec.NameRewrite) {
+		restore.Status.ValidationErrors = append(restore.Status.ValidationErrors, fmt.Sprintf("Invalid name rewrite: %v", err))
+	}
+
+	// validate that no resource is restored both first and last
+	for _, err := range pkgrestore.ValidateLowPriorityResources(c.defaultPriorities, restore.Spec.ResourcePriorities, restore.Spec.LowPriorityResources) {
+		restore.Status.ValidationErrors = append(restore.Status.ValidationErrors, fmt.Sprintf("Invalid low-priority resources: %v", err))
+	}
+
+	// validate that the source sub-path stays within the backup
+	for _, err := range pkgrestore.ValidateSourceSubPath(restore.Spec.SourceSubPath) {
+		restore.Status.ValidationErrors = append(restore.Status.ValidationErrors, fmt.Sprintf("Invalid source sub-path: %v", err))
+	}
+
+	// validate the latest only filters' field paths and counts
+	for _, err := range pkgrestore.ValidateLatestOnly(restore.Spec.LatestOnly) {
+		restore.Status.ValidationErrors = append(restore.Status.ValidationErrors, fmt.Sprintf("Invalid latest only filter: %v", err))
+	}
+
+	// validate the field exclusions' field paths
+	for _, err := range pkgrestore.ValidateFieldExclusions(restore.Spec.FieldExclusions) {
+		restore.Status.ValidationErrors = append(restore.Status.ValidationErrors, fmt.Sprintf("Invalid field exclusion: %v", err))
+	}
+
+	// validate the apply method
+	switch restore.Spec.ApplyMethod {
+	case "", api.ApplyMethodCreate, api.ApplyMethodServerSideApply:
+	default:
+		restore.Status.ValidationErrors = append(restore.Status.ValidationErrors, fmt.Sprintf("Invalid apply method %q", restore.Spec.ApplyMethod))
+	}
+
+	// validate the scope filter, which can't contradict IncludeClusterResources
+	// or ClusterScopedOnly
+	switch restore.Spec.ScopeFilter {
+	case "", api.ScopeFilterBoth:
+	case api.ScopeFilterClusterOnly:
+		if boolptr.IsSetToFalse(restore.Spec.IncludeClusterResources) {
+			restore.Status.ValidationErrors = append(restore.Status.ValidationErrors, "Scope filter ClusterOnly can't be combined with excluding cluster-scoped resources")
+		}
+	case api.ScopeFilterNamespacedOnly:
+		if boolptr.IsSetToTrue(restore.Spec.IncludeClusterResources) {
+			restore.Status.ValidationErrors = append(restore.Status.ValidationErrors, "Scope filter NamespacedOnly can't be combined with including cluster-scoped resources")
+		}
+		if restore.Spec.ClusterScopedOnly {
+			restore.Status.ValidationErrors = append(restore.Status.ValidationErrors, "Scope filter NamespacedOnly can't be combined with restoring only cluster-scoped resources")
+		}
+	default:
+		restore.Status.ValidationErrors = append(restore.Status.ValidationErrors, fmt.Sprintf("Invalid scope filter %q", restore.Spec.ScopeFilter))
+	}
+
+	// a cluster-scoped only restore is the same as the ClusterOnly scope filter
+	if restore.Spec.ClusterScopedOnly && restore.Spec.ScopeFilter != api.ScopeFilterClusterOnly && boolptr.IsSetToFalse(restore.Spec.IncludeClusterResources) {
+		restore.Status.ValidationErrors = append(restore.Status.ValidationErrors, "Restoring only cluster-scoped resources can't be combined with excluding cluster-scoped resources")
+	}
+
+	// validate that a replayed restore captured a replay of the same backup
+	if restore.Spec.ReplayRestoreName != "" {
+		replayed, err := c.restoreLister.Restores(restore.Namespace).Get(restore.Spec.ReplayRestoreName)
+		switch {
+		case err != nil:
+			restore.Status.ValidationErrors = append(restore.Status.ValidationErrors, fmt.Sprintf("Error getting restore %s to replay: %v", restore.Spec.ReplayRestoreName, err))
+		case !replayed.Spec.CaptureReplay:
+			restore.Status.ValidationErrors = append(restore.Status.ValidationErrors, fmt.Sprintf("Restore %s didn't capture a replay", restore.Spec.ReplayRestoreName))
+		case restore.Spec.BackupName != replayed.Spec.BackupName:
+			restore.Status.ValidationErrors = append(restore.Status.ValidationErrors, fmt.Sprintf("Restore %s replays restore %s, which restored a different backup", restore.Name, restore.Spec.ReplayRestoreName))
+		}
+	}
+
 	// validate that exactly one of BackupName and ScheduleName have been specified
 	if !backupXorScheduleProvided(restore) {
 		restore.Status.ValidationErrors = append(restore.Status.ValidationErrors, "Either a backup or schedule must be specified as a source for the restore, but not both")
@@ -410,9 +512,18 @@ func (c *restoreController) fetchBackupInfo(backupName string, pluginManager cli
 // means that the restore failed. This function updates the restore API object with warning and error
 // counts, but *does not* update its phase or patch it via the API.
 func (c *restoreController) runValidatedRestore(restore *api.Restore, info backupInfo) error {
-	// instantiate the per-restore logger that will output both to a temp file
-	// (for upload to object storage) and to stdout.
-	restoreLog, err := newRestoreLogger(restore, c.logger, c.restoreLogLevel)
+	// buffer the restore's log while it runs, so that it can be followed
+	// before it's uploaded to object storage
+	var logBuffer io.Writer = ioutil.Discard
+	if c.restoreLogBuffers != nil {
+		restoreKey := kubeutil.NamespaceAndName(restore)
+		logBuffer = c.restoreLogBuffers.Add(restoreKey)
+		defer c.restoreLogBuffers.Remove(restoreKey)
+	}
+
+	// instantiate the per-restore logger that will output to a temp file
+	// (for upload to object storage), to the log buffer, and to stdout.
+	restoreLog, err := newRestoreLogger(restore, c.logger, c.restoreLogLevel, logBuffer)
 	if err != nil {
 		return err
 	}
@@ -421,25 +532,47 @@ func (c *restoreController) runValidatedRestore(restore *api.Restore, info backu
 	pluginManager := c.newPluginManager(restoreLog)
 	defer pluginManager.CleanupClients()
 
-	actions, err := pluginManager.GetRestoreItemActions()
-	if err != nil {
-		return errors.Wrap(err, "error getting restore item actions")
-	}
+	// the restorer checkpoints the restore and stores its replay
+	// with the same backup store
+	if c.restoreBackupStores != nil {
+		c.restoreBackupStores.add(restore, info.backupStore)
+		defer c.restoreBackupStores.remove(restore)
+	}
+
+	var restoreWarnings, restoreErrors pkgrestore.Result
+	var itemResults pkgrestore.ItemResults
+
+	if restore.Spec.ReplayRestoreName != "" {
+		// a replay re-creates the items an earlier restore of the backup
+		// created, so the backup itself isn't needed
+		restoreLog.Infof("starting replay of restore %s", restore.Spec.ReplayRestoreName)
+		restoreWarnings, restoreErrors, itemResults = c.restorer.Replay(restoreLog, restore, &restoreReplayReader{
+			backupName:  restore.Spec.BackupName,
+			restoreName: restore.Spec.ReplayRestoreName,
+			backupStore: info.backupStore,
+		})
+		restoreLog.Info("replay completed")
+	} else {
+		actions, err := pluginManager.GetRestoreItemActions()
+		if err != nil {
+			return errors.Wrap(err, "error getting restore item actions")
+		}
 
-	backupFile, err := downloadToTempFile(restore.Spec.BackupName, info.backupStore, restoreLog)
-	if err != nil {
-		return errors.Wrap(err, "error downloading backup")
-	}
-	defer closeAndRemoveFile(backupFile, c.logger)
+		backupFile, err := downloadToTempFile(restore.Spec.BackupName, info.backupStore, restoreLog)
+		if err != nil {
+			return errors.Wrap(err, "error downloading backup")
+		}
+		defer closeAndRemoveFile(backupFile, c.logger)
 
-	volumeSnapshots, err := info.backupStore.GetBackupVolumeSnapshots(restore.Spec.BackupName)
-	if err != nil {
-		return errors.Wrap(err, "error fetching volume snapshots metadata")
-	}
+		volumeSnapshots, err := info.backupStore.GetBackupVolumeSnapshots(restore.Spec.BackupName)
+		if err != nil {
+			return errors.Wrap(err, "error fetching volume snapshots metadata")
+		}
 
-	restoreLog.Info("starting restore")
-	restoreWarnings, restoreErrors := c.restorer.Restore(restoreLog, restore, info.backup, volumeSnapshots, backupFile, actions, c.snapshotLocationLister, pluginManager)
-	restoreLog.Info("restore completed")
+		restoreLog.Info("starting restore")
+		restoreWarnings, restoreErrors, itemResults = c.restorer.Restore(restoreLog, restore, info.backup, volumeSnapshots, backupFile, actions, c.snapshotLocationLister, pluginManager)
+		restoreLog.Info("restore completed")
+	}
 
 	if logReader, err := restoreLog.done(c.logger); err != nil {
 		restoreErrors.Velero = append(restoreErrors.Velero, fmt.Sprintf("error getting restore log reader: %v", err))
@@ -457,7 +590,7 @@ func (c *restoreController) runValidatedRestore(restore *api.Restore, info backu
 		restore.Status.Warnings += len(w)
 	}
 
-	restore.Status.Errors = len(restoreErrors.Velero) + len(restoreErrors.Cluster)
+	restore.Status.Errors = len(restoreErrors.Velero) + len(restoreErrors.Cluster) + len(restoreErrors.VerificationFailures)
 	for _, e := range restoreErrors.Namespaces {
 		restore.Status.Errors += len(e)
 	}
@@ -471,6 +604,10 @@ func (c *restoreController) runValidatedRestore(restore *api.Restore, info backu
 		c.logger.WithError(err).Error("Error uploading restore results to backup storage")
 	}
 
+	if err := putItemResults(restore, itemResults, info.backupStore); err != nil {
+		c.logger.WithError(err).Error("Error uploading restore item results to backup storage")
+	}
+
 	return nil
 }
 
@@ -494,6 +631,17 @@ func putResults(restore *api.Restore, results map[string]pkgrestore.Result, back
 	return nil
 }
 
+// putItemResults uploads the machine-readable record of what the restore did
+// with each item to backup storage.
+func putItemResults(restore *api.Restore, itemResults pkgrestore.ItemResults, backupStore persistence.BackupStore) error {
+	buf := new(bytes.Buffer)
+	if err := json.NewEncoder(buf).Encode(itemResults.Records()); err != nil {
+		return errors.Wrap(err, "error encoding restore item results to JSON")
+	}
+
+	return backupStore.PutRestoreItemResults(restore.Spec.BackupName, restore.Name, buf)
+}
+
 func downloadToTempFile(backupName string, backupStore persistence.BackupStore, logger logrus.FieldLogger) (*os.File, error) {
 	readCloser, err := backupStore.GetBackupContents(backupName)
 	if err != nil {
@@ -555,7 +703,7 @@ type restoreLogger struct {
 	w    *gzip.Writer
 }
 
-func newRestoreLogger(restore *api.Restore, baseLogger logrus.FieldLogger, logLevel logrus.Level) (*restoreLogger, error) {
+func newRestoreLogger(restore *api.Restore, baseLogger logrus.FieldLogger, logLevel logrus.Level, buffer io.Writer) (*restoreLogger, error) {
 	file, err := ioutil.TempFile("", "")
 	if err != nil {
 		return nil, errors.Wrap(err, "error creating temp file")
@@ -563,7 +711,7 @@ func newRestoreLogger(restore *api.Restore, baseLogger logrus.FieldLogger, logLe
 	w := gzip.NewWriter(file)
 
 	logger := logging.DefaultLogger(logLevel)
-	logger.Out = io.MultiWriter(os.Stdout, w)
+	logger.Out = io.MultiWriter(os.Stdout, w, buffer)
 
 	return &restoreLogger{
 		FieldLogger: logger.WithField("restore", kubeutil.NamespaceAndName(restore)),
@@ -595,3 +743,265 @@ func (l *restoreLogger) done(log logrus.FieldLogger) (io.Reader, error) {
 func (l *restoreLogger) closeAndRemove(log logrus.FieldLogger) {
 	closeAndRemoveFile(l.file, log)
 }
+
+// restoreProgressUpdater records the progress of running restores in their status.
+type restoreProgressUpdater struct {
+	restoreClient velerov1client.RestoresGetter
+}
+
+// NewRestoreProgressUpdater returns a restore progress updater that patches the progress
+// of running restores into their status.
+func NewRestoreProgressUpdater(restoreClient velerov1client.RestoresGetter) pkgrestore.ProgressUpdater {
+	return &restoreProgressUpdater{restoreClient: restoreClient}
+}
+
+func (u *restoreProgressUpdater) UpdateProgress(restore *api.Restore, progress api.RestoreProgress) error {
+	patchBytes, err := json.Marshal(map[string]interface{}{
+		"status": map[string]interface{}{
+			"progress": progress,
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "error marshalling restore progress patch")
+	}
+
+	if _, err := u.restoreClient.Restores(restore.Namespace).Patch(restore.Name, types.MergePatchType, patchBytes); err != nil {
+		return errors.Wrapf(err, "error patching progress of restore %s", kubeutil.NamespaceAndName(restore))
+	}
+
+	return nil
+}
+
+// RestoreBackupStores tracks the backup stores the restore controller uses for the
+// restores it's running, so that the restorer can store a restore's checkpoints and
+// replay items with the same store, rather than setting up its own. It's safe for
+// concurrent use.
+type RestoreBackupStores struct {
+	lock   sync.Mutex
+	stores map[string]persistence.BackupStore
+}
+
+// NewRestoreBackupStores returns an empty RestoreBackupStores.
+func NewRestoreBackupStores() *RestoreBackupStores {
+	return &RestoreBackupStores{stores: make(map[string]persistence.BackupStore)}
+}
+
+// add records the backup store of the provided running restore.
+func (s *RestoreBackupStores) add(restore *api.Restore, backupStore persistence.BackupStore) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.stores[kubeutil.NamespaceAndName(restore)] = backupStore
+}
+
+// remove forgets the backup store of the provided restore once it's done.
+func (s *RestoreBackupStores) remove(restore *api.Restore) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.stores, kubeutil.NamespaceAndName(restore))
+}
+
+// get returns the backup store of the provided running restore.
+func (s *RestoreBackupStores) get(restore *api.Restore) (persistence.BackupStore, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	backupStore, ok := s.stores[kubeutil.NamespaceAndName(restore)]
+	if !ok {
+		return nil, errors.Errorf("no backup store for restore %s, which isn't running", kubeutil.NamespaceAndName(restore))
+	}
+	return backupStore, nil
+}
+
+// restoreCheckpointer stores the items running restores have restored in the backup
+// storage location of the restored backup.
+type restoreCheckpointer struct {
+	backupStores *RestoreBackupStores
+}
+
+// NewRestoreCheckpointer returns a restore checkpointer that stores the items running
+// restores have restored in the backup storage location of the restored backup.
+func NewRestoreCheckpointer(backupStores *RestoreBackupStores) pkgrestore.Checkpointer {
+	return &restoreCheckpointer{backupStores: backupStores}
+}
+
+func (c *restoreCheckpointer) Checkpoint(restore *api.Restore, restoredItems []string) error {
+	backupStore, err := c.backupStores.get(restore)
+	if err != nil {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	gzw := gzip.NewWriter(buf)
+	if err := json.NewEncoder(gzw).Encode(restoredItems); err != nil {
+		return errors.Wrap(err, "error encoding restore checkpoint")
+	}
+	if err := gzw.Close(); err != nil {
+		return errors.Wrap(err, "error closing gzip writer")
+	}
+
+	if err := backupStore.PutRestoreCheckpoint(restore.Spec.BackupName, restore.Name, buf); err != nil {
+		return errors.Wrapf(err, "error storing checkpoint of restore %s", kubeutil.NamespaceAndName(restore))
+	}
+
+	return nil
+}
+
+func (c *restoreCheckpointer) LoadCheckpoint(restore *api.Restore) ([]string, error) {
+	backupStore, err := c.backupStores.get(restore)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := backupStore.GetRestoreCheckpoint(restore.Spec.BackupName, restore.Name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error getting checkpoint of restore %s", kubeutil.NamespaceAndName(restore))
+	}
+	return items, nil
+}
+
+// restoreUnresolvedReferencesWriter stores the unresolved references reports of running
+// restores in the backup storage location of the restored backup.
+type restoreUnresolvedReferencesWriter struct {
+	backupStores *RestoreBackupStores
+}
+
+// NewRestoreUnresolvedReferencesWriter returns an unresolved references writer that stores
+// the reports of running restores in the backup storage location of the restored backup.
+func NewRestoreUnresolvedReferencesWriter(backupStores *RestoreBackupStores) pkgrestore.UnresolvedReferencesWriter {
+	return &restoreUnresolvedReferencesWriter{backupStores: backupStores}
+}
+
+func (w *restoreUnresolvedReferencesWriter) PutUnresolvedReferences(restore *api.Restore, report pkgrestore.UnresolvedReferences) error {
+	backupStore, err := w.backupStores.get(restore)
+	if err != nil {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	if err := json.NewEncoder(buf).Encode(report); err != nil {
+		return errors.Wrap(err, "error encoding restore unresolved references to JSON")
+	}
+
+	if err := backupStore.PutRestoreUnresolvedReferences(restore.Spec.BackupName, restore.Name, buf); err != nil {
+		return errors.Wrapf(err, "error storing unresolved references of restore %s", kubeutil.NamespaceAndName(restore))
+	}
+
+	return nil
+}
+
+// restoreReplayWriterFactory constructs replay writers that store the items restores
+// create in the backup storage location of the restored backup.
+type restoreReplayWriterFactory struct {
+	backupStores *RestoreBackupStores
+}
+
+// NewRestoreReplayWriterFactory returns a replay writer factory whose writers store the
+// items restores create in the backup storage location of the restored backup.
+func NewRestoreReplayWriterFactory(backupStores *RestoreBackupStores) pkgrestore.ReplayWriterFactory {
+	return &restoreReplayWriterFactory{backupStores: backupStores}
+}
+
+func (f *restoreReplayWriterFactory) NewReplayWriter(restore *api.Restore) (pkgrestore.ReplayWriter, error) {
+	backupStore, err := f.backupStores.get(restore)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting backup store to store the restore's replay")
+	}
+
+	return &restoreReplayWriter{
+		backupName:  restore.Spec.BackupName,
+		restoreName: restore.Name,
+		backupStore: backupStore,
+	}, nil
+}
+
+// restoreReplayWriter stores the items a restore creates under its replay directory
+// in backup storage.
+type restoreReplayWriter struct {
+	backupName  string
+	restoreName string
+	backupStore persistence.BackupStore
+}
+
+func (w *restoreReplayWriter) PutReplayItem(path string, contents io.Reader) error {
+	return w.backupStore.PutRestoreReplayItem(w.backupName, w.restoreName, path, contents)
+}
+
+// Close is a no-op, since the backup store belongs to the restore controller.
+func (w *restoreReplayWriter) Close() {}
+
+// restoreReplayReader reads the items a restore stored under its replay directory
+// in backup storage.
+type restoreReplayReader struct {
+	backupName  string
+	restoreName string
+	backupStore persistence.BackupStore
+}
+
+func (r *restoreReplayReader) ListReplayItems() ([]string, error) {
+	return r.backupStore.ListRestoreReplayItems(r.backupName, r.restoreName)
+}
+
+func (r *restoreReplayReader) GetReplayItem(path string) (io.ReadCloser, error) {
+	return r.backupStore.GetRestoreReplayItem(r.backupName, r.restoreName, path)
+}
+
+// preRestoreBackupper backs up the namespaces a restore restores into by creating
+// a backup of them and waiting for it to complete.
+type preRestoreBackupper struct {
+	backupClient velerov1client.BackupsGetter
+	namespace    string
+	timeout      time.Duration
+	clock        clock.Clock
+}
+
+// NewPreRestoreBackupper returns a pre-restore backupper that creates backups in the
+// provided namespace, waiting up to the provided timeout for each to complete.
+func NewPreRestoreBackupper(backupClient velerov1client.BackupsGetter, namespace string, timeout time.Duration) pkgrestore.PreRestoreBackupper {
+	return &preRestoreBackupper{
+		backupClient: backupClient,
+		namespace:    namespace,
+		timeout:      timeout,
+		clock:        clock.RealClock{},
+	}
+}
+
+func (b *preRestoreBackupper) BackupNamespaces(restore *api.Restore, namespaces []string) error {
+	// each attempt at the restore gets its own backup, timestamped like
+	// scheduled backups, since a restore can be processed more than once
+	name := fmt.Sprintf("%s-pre-restore-%s", restore.Name, b.clock.Now().UTC().Format("20060102150405"))
+
+	backup := pkgbackup.NewNamedBuilder(b.namespace, name).
+		Labels(api.RestoreNameLabel, label.GetValidName(restore.Name)).
+		IncludedNamespaces(namespaces...).
+		IncludeClusterResources(false).
+		SnapshotVolumes(false).
+		Backup()
+
+	created, err := b.backupClient.Backups(b.namespace).Create(backup)
+	if err != nil {
+		return errors.Wrapf(err, "error creating pre-restore backup %s", kubeutil.NamespaceAndName(backup))
+	}
+
+	err = wait.PollImmediate(time.Second, b.timeout, func() (bool, error) {
+		current, err := b.backupClient.Backups(created.Namespace).Get(created.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, errors.Wrapf(err, "error getting pre-restore backup %s", kubeutil.NamespaceAndName(created))
+		}
+
+		switch current.Status.Phase {
+		case api.BackupPhaseCompleted:
+			return true, nil
+		case api.BackupPhaseFailed, api.BackupPhasePartiallyFailed, api.BackupPhaseFailedValidation:
+			return false, errors.Errorf("pre-restore backup %s finished with phase %s", kubeutil.NamespaceAndName(created), current.Status.Phase)
+		default:
+			return false, nil
+		}
+	})
+	if err == wait.ErrWaitTimeout {
+		return errors.Errorf("timed out waiting for pre-restore backup %s to complete", kubeutil.NamespaceAndName(created))
+	}
+
+	return err
+}
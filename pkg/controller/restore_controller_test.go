@@ -18,9 +18,11 @@ package controller
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"io"
 	"io/ioutil"
+	"strings"
 	"testing"
 	"time"
 
@@ -32,6 +34,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/clock"
 	core "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/cache"
 
@@ -47,6 +50,7 @@ import (
 	pluginmocks "github.com/heptio/velero/pkg/plugin/mocks"
 	"github.com/heptio/velero/pkg/plugin/velero"
 	pkgrestore "github.com/heptio/velero/pkg/restore"
+	"github.com/heptio/velero/pkg/util/logging"
 	velerotest "github.com/heptio/velero/pkg/util/test"
 	"github.com/heptio/velero/pkg/volume"
 )
@@ -110,8 +114,11 @@ func TestFetchBackupInfo(t *testing.T) {
 				sharedInformers.Velero().V1().VolumeSnapshotLocations(),
 				logger,
 				logrus.InfoLevel,
+				nil,
+				nil,
 				func(logrus.FieldLogger) clientmgmt.Manager { return pluginManager },
 				"default",
+				nil, // default resource priorities
 				metrics.NewServerMetrics(),
 			).(*restoreController)
 
@@ -204,7 +211,10 @@ func TestProcessQueueItemSkips(t *testing.T) {
 				logger,
 				logrus.InfoLevel,
 				nil,
+				nil,
+				nil,
 				"default",
+				nil, // default resource priorities
 				metrics.NewServerMetrics(),
 			).(*restoreController)
 
@@ -219,6 +229,99 @@ func TestProcessQueueItemSkips(t *testing.T) {
 	}
 }
 
+func TestIsResumable(t *testing.T) {
+	checkpointed := func(phase api.RestorePhase) *api.Restore {
+		restore := velerotest.NewTestRestore("foo", "bar", phase).Restore
+		restore.Spec.CheckpointInterval = 10
+		return restore
+	}
+
+	tests := []struct {
+		name    string
+		restore *api.Restore
+		want    bool
+	}{
+		{
+			name:    "checkpointed restore with phase InProgress is resumable",
+			restore: checkpointed(api.RestorePhaseInProgress),
+			want:    true,
+		},
+		{
+			name:    "restore with phase InProgress that isn't checkpointed is not resumable",
+			restore: velerotest.NewTestRestore("foo", "bar", api.RestorePhaseInProgress).Restore,
+		},
+		{
+			name:    "checkpointed restore with phase Completed is not resumable",
+			restore: checkpointed(api.RestorePhaseCompleted),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.want, isResumable(test.restore))
+		})
+	}
+}
+
+func TestRestoreCheckpointer(t *testing.T) {
+	var (
+		backupStores = NewRestoreBackupStores()
+		backupStore  = &persistencemocks.BackupStore{}
+		checkpointer = NewRestoreCheckpointer(backupStores)
+		restore      = NewRestore(api.DefaultNamespace, "restore-1", "backup-1", "*", "", api.RestorePhaseInProgress).Restore
+		items        = []string{"pods/ns-1/pod-1", "persistentvolumes/pv-1"}
+	)
+
+	// restores that aren't running have no backup store to checkpoint to
+	assert.Error(t, checkpointer.Checkpoint(restore, items))
+
+	backupStores.add(restore, backupStore)
+
+	// the checkpoint is stored as gzip-compressed JSON
+	var stored []string
+	backupStore.On("PutRestoreCheckpoint", "backup-1", "restore-1", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		gzr, err := gzip.NewReader(args.Get(2).(io.Reader))
+		require.NoError(t, err)
+		require.NoError(t, json.NewDecoder(gzr).Decode(&stored))
+	})
+	require.NoError(t, checkpointer.Checkpoint(restore, items))
+	assert.Equal(t, items, stored)
+
+	backupStore.On("GetRestoreCheckpoint", "backup-1", "restore-1").Return(items, nil).Once()
+	loaded, err := checkpointer.LoadCheckpoint(restore)
+	require.NoError(t, err)
+	assert.Equal(t, items, loaded)
+
+	backupStores.remove(restore)
+	_, err = checkpointer.LoadCheckpoint(restore)
+	assert.Error(t, err)
+}
+
+func TestRestoreUnresolvedReferencesWriter(t *testing.T) {
+	var (
+		backupStores = NewRestoreBackupStores()
+		backupStore  = &persistencemocks.BackupStore{}
+		writer       = NewRestoreUnresolvedReferencesWriter(backupStores)
+		restore      = NewRestore(api.DefaultNamespace, "restore-1", "backup-1", "*", "", api.RestorePhaseInProgress).Restore
+		report       = pkgrestore.UnresolvedReferences{
+			"secrets": {"deployments.apps/ns-1/web": {"ns-1/db-creds"}},
+		}
+	)
+
+	// restores that aren't running have no backup store to write to
+	assert.Error(t, writer.PutUnresolvedReferences(restore, report))
+
+	backupStores.add(restore, backupStore)
+
+	// the report is stored as JSON
+	var stored pkgrestore.UnresolvedReferences
+	backupStore.On("PutRestoreUnresolvedReferences", "backup-1", "restore-1", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		require.NoError(t, json.NewDecoder(args.Get(2).(io.Reader)).Decode(&stored))
+	})
+	require.NoError(t, writer.PutUnresolvedReferences(restore, report))
+	assert.Equal(t, report, stored)
+}
+
 func TestProcessQueueItem(t *testing.T) {
 	tests := []struct {
 		name                            string
@@ -255,6 +358,51 @@ func TestProcessQueueItem(t *testing.T) {
 			expectedPhase:            string(api.RestorePhaseFailedValidation),
 			expectedValidationErrors: []string{"Invalid included/excluded resource lists: excludes list cannot contain an item in the includes list: a-resource"},
 		},
+		{
+			name:                     "restore with multiple namespaces mapped to the same namespace fails validation",
+			location:                 velerotest.NewTestBackupStorageLocation().WithName("default").WithProvider("myCloud").WithObjectStorage("bucket").BackupStorageLocation,
+			restore:                  NewRestore("foo", "bar", "backup-1", "*", "", api.RestorePhaseNew).WithMappedNamespace("ns-1", "ns-a").WithMappedNamespace("ns-2", "ns-a").Restore,
+			backup:                   defaultBackup().StorageLocation("default").Backup(),
+			expectedErr:              false,
+			expectedPhase:            string(api.RestorePhaseFailedValidation),
+			expectedValidationErrors: []string{"Invalid namespace mapping: namespaces ns-1, ns-2 are all mapped to namespace ns-a"},
+		},
+		{
+			name:                     "restore with scope filter ClusterOnly and cluster-scoped resources excluded fails validation",
+			location:                 velerotest.NewTestBackupStorageLocation().WithName("default").WithProvider("myCloud").WithObjectStorage("bucket").BackupStorageLocation,
+			restore:                  NewRestore("foo", "bar", "backup-1", "*", "", api.RestorePhaseNew).WithScopeFilter(api.ScopeFilterClusterOnly).WithIncludeClusterResources(false).Restore,
+			backup:                   defaultBackup().StorageLocation("default").Backup(),
+			expectedErr:              false,
+			expectedPhase:            string(api.RestorePhaseFailedValidation),
+			expectedValidationErrors: []string{"Scope filter ClusterOnly can't be combined with excluding cluster-scoped resources"},
+		},
+		{
+			name:                     "restore with scope filter NamespacedOnly that's cluster-scoped only fails validation",
+			location:                 velerotest.NewTestBackupStorageLocation().WithName("default").WithProvider("myCloud").WithObjectStorage("bucket").BackupStorageLocation,
+			restore:                  NewRestore("foo", "bar", "backup-1", "*", "", api.RestorePhaseNew).WithScopeFilter(api.ScopeFilterNamespacedOnly).WithClusterScopedOnly(true).Restore,
+			backup:                   defaultBackup().StorageLocation("default").Backup(),
+			expectedErr:              false,
+			expectedPhase:            string(api.RestorePhaseFailedValidation),
+			expectedValidationErrors: []string{"Scope filter NamespacedOnly can't be combined with restoring only cluster-scoped resources"},
+		},
+		{
+			name:                     "cluster-scoped only restore with cluster-scoped resources excluded fails validation",
+			location:                 velerotest.NewTestBackupStorageLocation().WithName("default").WithProvider("myCloud").WithObjectStorage("bucket").BackupStorageLocation,
+			restore:                  NewRestore("foo", "bar", "backup-1", "*", "", api.RestorePhaseNew).WithClusterScopedOnly(true).WithIncludeClusterResources(false).Restore,
+			backup:                   defaultBackup().StorageLocation("default").Backup(),
+			expectedErr:              false,
+			expectedPhase:            string(api.RestorePhaseFailedValidation),
+			expectedValidationErrors: []string{"Restoring only cluster-scoped resources can't be combined with excluding cluster-scoped resources"},
+		},
+		{
+			name:                     "restore with invalid scope filter fails validation",
+			location:                 velerotest.NewTestBackupStorageLocation().WithName("default").WithProvider("myCloud").WithObjectStorage("bucket").BackupStorageLocation,
+			restore:                  NewRestore("foo", "bar", "backup-1", "*", "", api.RestorePhaseNew).WithScopeFilter("Everything").Restore,
+			backup:                   defaultBackup().StorageLocation("default").Backup(),
+			expectedErr:              false,
+			expectedPhase:            string(api.RestorePhaseFailedValidation),
+			expectedValidationErrors: []string{"Invalid scope filter \"Everything\""},
+		},
 		{
 			name:                     "new restore with empty backup and schedule names fails validation",
 			restore:                  NewRestore("foo", "bar", "", "ns-1", "", api.RestorePhaseNew).Restore,
@@ -286,6 +434,15 @@ func TestProcessQueueItem(t *testing.T) {
 			expectedValidationErrors:        []string{"Error retrieving backup: backup.velero.io \"backup-1\" not found"},
 			backupStoreGetBackupMetadataErr: errors.New("no backup here"),
 		},
+		{
+			name:                     "restore replaying a non-existent restore fails validation",
+			location:                 velerotest.NewTestBackupStorageLocation().WithName("default").WithProvider("myCloud").WithObjectStorage("bucket").BackupStorageLocation,
+			restore:                  NewRestore("foo", "bar", "backup-1", "ns-1", "", api.RestorePhaseNew).WithReplayRestoreName("missing").Restore,
+			backup:                   defaultBackup().StorageLocation("default").Backup(),
+			expectedErr:              false,
+			expectedPhase:            string(api.RestorePhaseFailedValidation),
+			expectedValidationErrors: []string{"Error getting restore missing to replay: restore.velero.io \"missing\" not found"},
+		},
 		{
 			name:                  "restorer throwing an error causes the restore to fail",
 			location:              velerotest.NewTestBackupStorageLocation().WithName("default").WithProvider("myCloud").WithObjectStorage("bucket").BackupStorageLocation,
@@ -403,8 +560,11 @@ func TestProcessQueueItem(t *testing.T) {
 				sharedInformers.Velero().V1().VolumeSnapshotLocations(),
 				logger,
 				logrus.InfoLevel,
+				nil,
+				nil,
 				func(logrus.FieldLogger) clientmgmt.Manager { return pluginManager },
 				"default",
+				nil, // default resource priorities
 				metrics.NewServerMetrics(),
 			).(*restoreController)
 
@@ -476,12 +636,25 @@ func TestProcessQueueItem(t *testing.T) {
 			if test.expectedRestorerCall != nil {
 				backupStore.On("GetBackupContents", test.backup.Name).Return(ioutil.NopCloser(bytes.NewReader([]byte("hello world"))), nil)
 
-				restorer.On("Restore", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(warnings, errors)
+				itemResults := pkgrestore.ItemResults{
+					{GroupResource: "persistentvolumes", Name: "test-pv", Outcome: pkgrestore.ItemOutcomeCreated, Action: pkgrestore.ItemActionProvisionedPV, Reason: "provisioned from a volume snapshot"},
+					{GroupResource: "persistentvolumeclaims", Namespace: "ns-1", Name: "test-pvc", Outcome: pkgrestore.ItemOutcomeCreated, Action: pkgrestore.ItemActionCreated},
+				}
+				restorer.On("Restore", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(warnings, errors, itemResults)
 
 				backupStore.On("PutRestoreLog", test.backup.Name, test.restore.Name, mock.Anything).Return(test.putRestoreLogErr)
 
 				backupStore.On("PutRestoreResults", test.backup.Name, test.restore.Name, mock.Anything).Return(nil)
 
+				backupStore.On("PutRestoreItemResults", test.backup.Name, test.restore.Name, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+					var records []pkgrestore.ItemRecord
+					require.NoError(t, json.NewDecoder(args.Get(2).(io.Reader)).Decode(&records))
+					assert.Equal(t, []pkgrestore.ItemRecord{
+						{Resource: "persistentvolumes", Name: "test-pv", Action: pkgrestore.ItemActionProvisionedPV, Reason: "provisioned from a volume snapshot"},
+						{Resource: "persistentvolumeclaims", Namespace: "ns-1", Name: "test-pvc", Action: pkgrestore.ItemActionCreated},
+					}, records)
+				})
+
 				volumeSnapshots := []*volume.Snapshot{
 					{
 						Spec: volume.SnapshotSpec{
@@ -606,6 +779,83 @@ func TestProcessQueueItem(t *testing.T) {
 	}
 }
 
+func TestRestoreLogBuffer(t *testing.T) {
+	var (
+		client          = fake.NewSimpleClientset()
+		restorer        = &fakeRestorer{}
+		sharedInformers = informers.NewSharedInformerFactory(client, 0)
+		pluginManager   = &pluginmocks.Manager{}
+		backupStore     = &persistencemocks.BackupStore{}
+		logBuffers      = logging.NewLogBuffers(100)
+	)
+
+	c := NewRestoreController(
+		api.DefaultNamespace,
+		sharedInformers.Velero().V1().Restores(),
+		client.VeleroV1(),
+		client.VeleroV1(),
+		restorer,
+		sharedInformers.Velero().V1().Backups(),
+		sharedInformers.Velero().V1().BackupStorageLocations(),
+		sharedInformers.Velero().V1().VolumeSnapshotLocations(),
+		velerotest.NewLogger(),
+		logrus.InfoLevel,
+		logBuffers,
+		nil,
+		func(logrus.FieldLogger) clientmgmt.Manager { return pluginManager },
+		"default",
+		nil, // default resource priorities
+		metrics.NewServerMetrics(),
+	).(*restoreController)
+
+	restore := NewRestore(api.DefaultNamespace, "restore-1", "backup-1", "ns-1", "", api.RestorePhaseInProgress).Restore
+	backup := defaultBackup().Name("backup-1").Backup()
+
+	pluginManager.On("GetRestoreItemActions").Return(nil, nil)
+	pluginManager.On("CleanupClients").Return()
+	backupStore.On("GetBackupContents", "backup-1").Return(ioutil.NopCloser(bytes.NewReader([]byte("hello world"))), nil)
+	backupStore.On("GetBackupVolumeSnapshots", "backup-1").Return(nil, nil)
+	backupStore.On("PutRestoreResults", "backup-1", "restore-1", mock.Anything).Return(nil)
+	backupStore.On("PutRestoreItemResults", "backup-1", "restore-1", mock.Anything).Return(nil)
+
+	// the log is only uploaded once the restore is done, so the lines
+	// logged so far can only be read from the buffer
+	var interim []string
+	restorer.On("Restore", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			log := args.Get(0).(logrus.FieldLogger)
+			log.Info("restoring item-1")
+			log.Info("restoring item-2")
+
+			buffer := logBuffers.Get("velero/restore-1")
+			require.NotNil(t, buffer)
+			interim, _ = buffer.Lines(0)
+		}).
+		Return(pkgrestore.Result{}, pkgrestore.Result{}, pkgrestore.ItemResults(nil))
+
+	var uploaded []string
+	backupStore.On("PutRestoreLog", "backup-1", "restore-1", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		gzr, err := gzip.NewReader(args.Get(2).(io.Reader))
+		require.NoError(t, err)
+		contents, err := ioutil.ReadAll(gzr)
+		require.NoError(t, err)
+		uploaded = strings.Split(strings.TrimSpace(string(contents)), "\n")
+	})
+
+	require.NoError(t, c.runValidatedRestore(restore, backupInfo{backup: backup, backupStore: backupStore}))
+
+	require.Len(t, interim, 3)
+	assert.Contains(t, interim[0], "starting restore")
+	assert.Contains(t, interim[1], "restoring item-1")
+	assert.Contains(t, interim[2], "restoring item-2")
+
+	// the whole log is still uploaded, and the buffer is removed once it is
+	require.Len(t, uploaded, 4)
+	assert.Equal(t, interim, uploaded[:3])
+	assert.Contains(t, uploaded[3], "restore completed")
+	assert.Nil(t, logBuffers.Get("velero/restore-1"))
+}
+
 func TestvalidateAndCompleteWhenScheduleNameSpecified(t *testing.T) {
 	var (
 		client          = fake.NewSimpleClientset()
@@ -626,8 +876,11 @@ func TestvalidateAndCompleteWhenScheduleNameSpecified(t *testing.T) {
 		logger,
 		logrus.DebugLevel,
 		nil,
+		nil,
+		nil,
 		"default",
 		nil,
+		nil,
 	).(*restoreController)
 
 	restore := &api.Restore{
@@ -708,6 +961,64 @@ func TestBackupXorScheduleProvided(t *testing.T) {
 
 }
 
+func TestRestoreProgressUpdater(t *testing.T) {
+	restore := NewRestore("velero", "restore-1", "backup-1", "*", "", api.RestorePhaseInProgress).WithErrors(1).Restore
+	client := fake.NewSimpleClientset(restore)
+
+	updater := NewRestoreProgressUpdater(client.VeleroV1())
+	progress := api.RestoreProgress{TotalItems: 10, ItemsRestored: 4, ItemsSkipped: 1, Warnings: 2}
+	require.NoError(t, updater.UpdateProgress(restore, progress))
+
+	res, err := client.VeleroV1().Restores("velero").Get("restore-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.NotNil(t, res.Status.Progress)
+	assert.Equal(t, progress, *res.Status.Progress)
+
+	// the rest of the status is unchanged
+	assert.Equal(t, api.RestorePhaseInProgress, res.Status.Phase)
+	assert.Equal(t, 1, res.Status.Errors)
+
+	assert.Error(t, updater.UpdateProgress(NewRestore("velero", "restore-2", "backup-1", "*", "", api.RestorePhaseInProgress).Restore, progress))
+}
+
+func TestPreRestoreBackupper(t *testing.T) {
+	for _, phase := range []api.BackupPhase{api.BackupPhaseCompleted, api.BackupPhasePartiallyFailed} {
+		t.Run(string(phase), func(t *testing.T) {
+			// the backup controller isn't running, so the backup is
+			// reported to be in the phase under test once it's created
+			var created *api.Backup
+			client := fake.NewSimpleClientset()
+			client.PrependReactor("create", "backups", func(action core.Action) (bool, runtime.Object, error) {
+				created = action.(core.CreateAction).GetObject().(*api.Backup).DeepCopy()
+				return false, nil, nil
+			})
+			client.PrependReactor("get", "backups", func(action core.Action) (bool, runtime.Object, error) {
+				backup := created.DeepCopy()
+				backup.Status.Phase = phase
+				return true, backup, nil
+			})
+
+			backupper := NewPreRestoreBackupper(client.VeleroV1(), "velero", time.Minute).(*preRestoreBackupper)
+			backupper.clock = clock.NewFakeClock(time.Date(2019, 6, 1, 12, 30, 45, 0, time.UTC))
+			restore := NewRestore("velero", "restore-1", "backup-1", "*", "", api.RestorePhaseInProgress).Restore
+
+			err := backupper.BackupNamespaces(restore, []string{"ns-1", "ns-2"})
+			if phase == api.BackupPhaseCompleted {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+
+			require.NotNil(t, created)
+			assert.Equal(t, "restore-1-pre-restore-20190601123045", created.Name)
+			assert.Equal(t, []string{"ns-1", "ns-2"}, created.Spec.IncludedNamespaces)
+			assert.Equal(t, "restore-1", created.Labels[api.RestoreNameLabel])
+			require.NotNil(t, created.Spec.SnapshotVolumes)
+			assert.False(t, *created.Spec.SnapshotVolumes)
+		})
+	}
+}
+
 func TestMostRecentCompletedBackup(t *testing.T) {
 	backups := []*api.Backup{
 		{
@@ -812,10 +1123,18 @@ func (r *fakeRestorer) Restore(
 	actions []velero.RestoreItemAction,
 	snapshotLocationLister listers.VolumeSnapshotLocationLister,
 	volumeSnapshotterGetter pkgrestore.VolumeSnapshotterGetter,
-) (pkgrestore.Result, pkgrestore.Result) {
+) (pkgrestore.Result, pkgrestore.Result, pkgrestore.ItemResults) {
 	res := r.Called(log, restore, backup, backupReader, actions)
 
 	r.calledWithArg = *restore
 
-	return res.Get(0).(pkgrestore.Result), res.Get(1).(pkgrestore.Result)
+	return res.Get(0).(pkgrestore.Result), res.Get(1).(pkgrestore.Result), res.Get(2).(pkgrestore.ItemResults)
+}
+
+func (r *fakeRestorer) Replay(log logrus.FieldLogger, restore *api.Restore, replay pkgrestore.ReplayReader) (pkgrestore.Result, pkgrestore.Result, pkgrestore.ItemResults) {
+	res := r.Called(log, restore, replay)
+
+	r.calledWithArg = *restore
+
+	return res.Get(0).(pkgrestore.Result), res.Get(1).(pkgrestore.Result), res.Get(2).(pkgrestore.ItemResults)
 }
@@ -251,6 +251,11 @@ func (c *scheduleController) submitBackupIfDue(item *api.Schedule, cronSchedule
 		return nil
 	}
 
+	if item.Spec.Paused {
+		log.WithField("nextRunTime", nextRunTime).Info("Schedule is due but paused, skipping")
+		return nil
+	}
+
 	// Don't attempt to "catch up" if there are any missed or failed runs - simply
 	// trigger a Backup if it's time.
 	//
@@ -104,6 +104,12 @@ func TestProcessSchedule(t *testing.T) {
 			expectedBackupCreate: defaultBackup().Namespace("ns").Name("name-20170101120000").Labels(velerov1api.ScheduleNameLabel, "name").NoTypeMeta().Backup(),
 			expectedLastBackup:   "2017-01-01 12:00:00",
 		},
+		{
+			name:          "paused schedule that's due doesn't trigger a backup",
+			schedule:      velerotest.NewTestSchedule("ns", "name").WithPhase(api.SchedulePhaseEnabled).WithCronSchedule("@every 5m").WithPaused(true).Schedule,
+			fakeClockTime: "2017-01-01 12:00:00",
+			expectedErr:   false,
+		},
 		{
 			name: "schedule that's already run gets LastBackup updated",
 			schedule: velerotest.NewTestSchedule("ns", "name").WithPhase(api.SchedulePhaseEnabled).
@@ -247,6 +253,12 @@ func TestProcessSchedule(t *testing.T) {
 
 				velerotest.ValidatePatch(t, actions[index], expected, decode)
 			}
+
+			if test.expectedBackupCreate == nil {
+				for _, action := range actions {
+					assert.False(t, action.Matches("create", "backups"), "unexpected backup create")
+				}
+			}
 		})
 	}
 }
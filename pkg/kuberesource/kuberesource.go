@@ -21,12 +21,21 @@ import (
 )
 
 var (
-	ClusterRoleBindings    = schema.GroupResource{Group: "rbac.authorization.k8s.io", Resource: "clusterrolebindings"}
-	ClusterRoles           = schema.GroupResource{Group: "rbac.authorization.k8s.io", Resource: "clusterroles"}
-	Jobs                   = schema.GroupResource{Group: "batch", Resource: "jobs"}
-	Namespaces             = schema.GroupResource{Group: "", Resource: "namespaces"}
-	PersistentVolumeClaims = schema.GroupResource{Group: "", Resource: "persistentvolumeclaims"}
-	PersistentVolumes      = schema.GroupResource{Group: "", Resource: "persistentvolumes"}
-	Pods                   = schema.GroupResource{Group: "", Resource: "pods"}
-	ServiceAccounts        = schema.GroupResource{Group: "", Resource: "serviceaccounts"}
+	ClusterRoleBindings       = schema.GroupResource{Group: "rbac.authorization.k8s.io", Resource: "clusterrolebindings"}
+	ClusterRoles              = schema.GroupResource{Group: "rbac.authorization.k8s.io", Resource: "clusterroles"}
+	ConfigMaps                = schema.GroupResource{Group: "", Resource: "configmaps"}
+	CustomResourceDefinitions = schema.GroupResource{Group: "apiextensions.k8s.io", Resource: "customresourcedefinitions"}
+	Endpoints                 = schema.GroupResource{Group: "", Resource: "endpoints"}
+	Jobs                      = schema.GroupResource{Group: "batch", Resource: "jobs"}
+	Leases                    = schema.GroupResource{Group: "coordination.k8s.io", Resource: "leases"}
+	Namespaces                = schema.GroupResource{Group: "", Resource: "namespaces"}
+	PersistentVolumeClaims    = schema.GroupResource{Group: "", Resource: "persistentvolumeclaims"}
+	PersistentVolumes         = schema.GroupResource{Group: "", Resource: "persistentvolumes"}
+	Pods                      = schema.GroupResource{Group: "", Resource: "pods"}
+	RoleBindings              = schema.GroupResource{Group: "rbac.authorization.k8s.io", Resource: "rolebindings"}
+	Roles                     = schema.GroupResource{Group: "rbac.authorization.k8s.io", Resource: "roles"}
+	Secrets                   = schema.GroupResource{Group: "", Resource: "secrets"}
+	ServiceAccounts           = schema.GroupResource{Group: "", Resource: "serviceaccounts"}
+	Services                  = schema.GroupResource{Group: "", Resource: "services"}
+	StorageClasses            = schema.GroupResource{Group: "storage.k8s.io", Resource: "storageclasses"}
 )
@@ -237,6 +237,20 @@ func (_m *BackupStore) PutRestoreLog(backup string, restore string, log io.Reade
 	return r0
 }
 
+// PutRestoreItemResults provides a mock function with given fields: backup, restore, results
+func (_m *BackupStore) PutRestoreItemResults(backup string, restore string, results io.Reader) error {
+	ret := _m.Called(backup, restore, results)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, io.Reader) error); ok {
+		r0 = rf(backup, restore, results)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // PutRestoreResults provides a mock function with given fields: backup, restore, results
 func (_m *BackupStore) PutRestoreResults(backup string, restore string, results io.Reader) error {
 	ret := _m.Called(backup, restore, results)
@@ -250,3 +264,114 @@ func (_m *BackupStore) PutRestoreResults(backup string, restore string, results
 
 	return r0
 }
+
+// PutRestoreUnresolvedReferences provides a mock function with given fields: backup, restore, report
+func (_m *BackupStore) PutRestoreUnresolvedReferences(backup string, restore string, report io.Reader) error {
+	ret := _m.Called(backup, restore, report)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, io.Reader) error); ok {
+		r0 = rf(backup, restore, report)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// PutRestoreReplayItem provides a mock function with given fields: backup, restore, item, contents
+func (_m *BackupStore) PutRestoreReplayItem(backup string, restore string, item string, contents io.Reader) error {
+	ret := _m.Called(backup, restore, item, contents)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, string, io.Reader) error); ok {
+		r0 = rf(backup, restore, item, contents)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ListRestoreReplayItems provides a mock function with given fields: backup, restore
+func (_m *BackupStore) ListRestoreReplayItems(backup string, restore string) ([]string, error) {
+	ret := _m.Called(backup, restore)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(string, string) []string); ok {
+		r0 = rf(backup, restore)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(backup, restore)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRestoreReplayItem provides a mock function with given fields: backup, restore, item
+func (_m *BackupStore) GetRestoreReplayItem(backup string, restore string, item string) (io.ReadCloser, error) {
+	ret := _m.Called(backup, restore, item)
+
+	var r0 io.ReadCloser
+	if rf, ok := ret.Get(0).(func(string, string, string) io.ReadCloser); ok {
+		r0 = rf(backup, restore, item)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(io.ReadCloser)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, string) error); ok {
+		r1 = rf(backup, restore, item)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PutRestoreCheckpoint provides a mock function with given fields: backup, restore, checkpoint
+func (_m *BackupStore) PutRestoreCheckpoint(backup string, restore string, checkpoint io.Reader) error {
+	ret := _m.Called(backup, restore, checkpoint)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, io.Reader) error); ok {
+		r0 = rf(backup, restore, checkpoint)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetRestoreCheckpoint provides a mock function with given fields: backup, restore
+func (_m *BackupStore) GetRestoreCheckpoint(backup string, restore string) ([]string, error) {
+	ret := _m.Called(backup, restore)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(string, string) []string); ok {
+		r0 = rf(backup, restore)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(backup, restore)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"io"
 	"io/ioutil"
+	"sort"
 	"strings"
 	"time"
 
@@ -55,6 +56,13 @@ type BackupStore interface {
 
 	PutRestoreLog(backup, restore string, log io.Reader) error
 	PutRestoreResults(backup, restore string, results io.Reader) error
+	PutRestoreItemResults(backup, restore string, results io.Reader) error
+	PutRestoreUnresolvedReferences(backup, restore string, report io.Reader) error
+	PutRestoreReplayItem(backup, restore, item string, contents io.Reader) error
+	ListRestoreReplayItems(backup, restore string) ([]string, error)
+	GetRestoreReplayItem(backup, restore, item string) (io.ReadCloser, error)
+	PutRestoreCheckpoint(backup, restore string, checkpoint io.Reader) error
+	GetRestoreCheckpoint(backup, restore string) ([]string, error)
 	DeleteRestore(name string) error
 
 	GetDownloadURL(target velerov1api.DownloadTarget) (string, error)
@@ -350,6 +358,86 @@ func (s *objectBackupStore) PutRestoreResults(backup string, restore string, res
 	return s.objectStore.PutObject(s.bucket, s.layout.getRestoreResultsKey(restore), results)
 }
 
+func (s *objectBackupStore) PutRestoreItemResults(backup string, restore string, results io.Reader) error {
+	return s.objectStore.PutObject(s.bucket, s.layout.getRestoreItemResultsKey(restore), results)
+}
+
+// PutRestoreUnresolvedReferences stores the JSON report of the references of the items
+// a restore restored to items that don't exist.
+func (s *objectBackupStore) PutRestoreUnresolvedReferences(backup, restore string, report io.Reader) error {
+	return s.objectStore.PutObject(s.bucket, s.layout.getRestoreUnresolvedReferencesKey(restore), report)
+}
+
+// PutRestoreReplayItem stores the contents of an item a restore created under its
+// replay directory, at the provided path relative to the directory.
+func (s *objectBackupStore) PutRestoreReplayItem(backup, restore, item string, contents io.Reader) error {
+	return s.objectStore.PutObject(s.bucket, s.layout.getRestoreReplayDir(restore)+item, contents)
+}
+
+// ListRestoreReplayItems returns the paths, relative to the restore's replay directory,
+// of the items stored under it.
+func (s *objectBackupStore) ListRestoreReplayItems(backup, restore string) ([]string, error) {
+	dir := s.layout.getRestoreReplayDir(restore)
+
+	keys, err := s.objectStore.ListObjects(s.bucket, dir)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	items := make([]string, 0, len(keys))
+	for _, key := range keys {
+		items = append(items, strings.TrimPrefix(key, dir))
+	}
+	sort.Strings(items)
+
+	return items, nil
+}
+
+// GetRestoreReplayItem returns the contents of the item stored under the restore's replay
+// directory at the provided path relative to the directory.
+func (s *objectBackupStore) GetRestoreReplayItem(backup, restore, item string) (io.ReadCloser, error) {
+	return s.objectStore.GetObject(s.bucket, s.layout.getRestoreReplayDir(restore)+item)
+}
+
+// PutRestoreCheckpoint stores the gzip-compressed JSON list of the items a restore
+// has restored as of its last checkpoint, replacing any earlier checkpoint.
+func (s *objectBackupStore) PutRestoreCheckpoint(backup, restore string, checkpoint io.Reader) error {
+	return s.objectStore.PutObject(s.bucket, s.layout.getRestoreCheckpointKey(restore), checkpoint)
+}
+
+// GetRestoreCheckpoint returns the items in a restore's last checkpoint, or none if
+// the restore hasn't been checkpointed.
+func (s *objectBackupStore) GetRestoreCheckpoint(backup, restore string) ([]string, error) {
+	key := s.layout.getRestoreCheckpointKey(restore)
+
+	ok, err := keyExists(s.objectStore, s.bucket, s.layout.getRestoreDir(restore), key)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	res, err := s.objectStore.GetObject(s.bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+
+	gzr, err := gzip.NewReader(res)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer gzr.Close()
+
+	var items []string
+	if err := json.NewDecoder(gzr).Decode(&items); err != nil {
+		return nil, errors.Wrap(err, "error decoding object data")
+	}
+
+	return items, nil
+}
+
 func (s *objectBackupStore) GetDownloadURL(target velerov1api.DownloadTarget) (string, error) {
 	switch target.Kind {
 	case velerov1api.DownloadTargetKindBackupContents:
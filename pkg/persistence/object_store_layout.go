@@ -94,3 +94,19 @@ func (l *ObjectStoreLayout) getRestoreLogKey(restore string) string {
 func (l *ObjectStoreLayout) getRestoreResultsKey(restore string) string {
 	return path.Join(l.subdirs["restores"], restore, fmt.Sprintf("restore-%s-results.gz", restore))
 }
+
+func (l *ObjectStoreLayout) getRestoreItemResultsKey(restore string) string {
+	return path.Join(l.subdirs["restores"], restore, "restore-results.json")
+}
+
+func (l *ObjectStoreLayout) getRestoreUnresolvedReferencesKey(restore string) string {
+	return path.Join(l.subdirs["restores"], restore, "restore-unresolved-references.json")
+}
+
+func (l *ObjectStoreLayout) getRestoreCheckpointKey(restore string) string {
+	return path.Join(l.subdirs["restores"], restore, fmt.Sprintf("restore-%s-checkpoint.json.gz", restore))
+}
+
+func (l *ObjectStoreLayout) getRestoreReplayDir(restore string) string {
+	return path.Join(l.subdirs["restores"], restore, "replay") + "/"
+}
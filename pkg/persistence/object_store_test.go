@@ -416,6 +416,55 @@ func TestGetBackupContents(t *testing.T) {
 	assert.Equal(t, "foo", string(data))
 }
 
+func TestRestoreReplayItems(t *testing.T) {
+	harness := newObjectBackupStoreTestHarness("test-bucket", "velero-backups/")
+
+	require.NoError(t, harness.PutRestoreReplayItem("test-backup", "test-restore", "pods/namespaces/ns-1/pod-1.json", newStringReadSeeker("pod")))
+	require.NoError(t, harness.PutRestoreReplayItem("test-backup", "test-restore", "persistentvolumes/cluster/pv-1.json", newStringReadSeeker("pv")))
+	require.NoError(t, harness.PutRestoreReplayItem("test-backup", "other-restore", "pods/namespaces/ns-1/pod-2.json", newStringReadSeeker("other")))
+
+	assert.Contains(t, harness.objectStore.Data["test-bucket"], "velero-backups/restores/test-restore/replay/pods/namespaces/ns-1/pod-1.json")
+
+	items, err := harness.ListRestoreReplayItems("test-backup", "test-restore")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"persistentvolumes/cluster/pv-1.json", "pods/namespaces/ns-1/pod-1.json"}, items)
+
+	rc, err := harness.GetRestoreReplayItem("test-backup", "test-restore", "pods/namespaces/ns-1/pod-1.json")
+	require.NoError(t, err)
+
+	data, err := ioutil.ReadAll(rc)
+	require.NoError(t, err)
+	assert.Equal(t, "pod", string(data))
+}
+
+func TestRestoreCheckpoint(t *testing.T) {
+	harness := newObjectBackupStoreTestHarness("test-bucket", "velero-backups/")
+
+	// a restore that hasn't been checkpointed has no items
+	items, err := harness.GetRestoreCheckpoint("test-backup", "test-restore")
+	require.NoError(t, err)
+	assert.Nil(t, items)
+
+	for _, checkpoint := range [][]string{
+		{"pods/ns-1/pod-1"},
+		{"pods/ns-1/pod-1", "persistentvolumes/pv-1"},
+	} {
+		buf := new(bytes.Buffer)
+		gzw := gzip.NewWriter(buf)
+		require.NoError(t, json.NewEncoder(gzw).Encode(checkpoint))
+		require.NoError(t, gzw.Close())
+
+		require.NoError(t, harness.PutRestoreCheckpoint("test-backup", "test-restore", buf))
+	}
+
+	assert.Contains(t, harness.objectStore.Data["test-bucket"], "velero-backups/restores/test-restore/restore-test-restore-checkpoint.json.gz")
+
+	// the last checkpoint replaces the earlier one
+	items, err = harness.GetRestoreCheckpoint("test-backup", "test-restore")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pods/ns-1/pod-1", "persistentvolumes/pv-1"}, items)
+}
+
 func TestDeleteBackup(t *testing.T) {
 	tests := []struct {
 		name             string
@@ -77,6 +77,21 @@ func (r *restartableRestoreItemAction) AppliesTo() (velero.ResourceSelector, err
 	return delegate.AppliesTo()
 }
 
+// Priority restarts the plugin's process if needed, then delegates the call. Delegates
+// that aren't prioritized have a priority of 0.
+func (r *restartableRestoreItemAction) Priority() (int, error) {
+	delegate, err := r.getDelegate()
+	if err != nil {
+		return 0, err
+	}
+
+	prioritized, ok := delegate.(velero.PrioritizedRestoreItemAction)
+	if !ok {
+		return 0, nil
+	}
+	return prioritized.Priority()
+}
+
 // Execute restarts the plugin's process if needed, then delegates the call.
 func (r *restartableRestoreItemAction) Execute(input *velero.RestoreItemActionExecuteInput) (*velero.RestoreItemActionExecuteOutput, error) {
 	delegate, err := r.getDelegate()
@@ -67,6 +67,17 @@ func (c *RestoreItemActionGRPCClient) AppliesTo() (velero.ResourceSelector, erro
 	}, nil
 }
 
+// Priority returns the priority of the plugin's restore item action, which is
+// sent along with the resources it applies to.
+func (c *RestoreItemActionGRPCClient) Priority() (int, error) {
+	res, err := c.grpcClient.AppliesTo(context.Background(), &proto.RestoreItemActionAppliesToRequest{Plugin: c.plugin})
+	if err != nil {
+		return 0, fromGRPCError(err)
+	}
+
+	return int(res.Priority), nil
+}
+
 func (c *RestoreItemActionGRPCClient) Execute(input *velero.RestoreItemActionExecuteInput) (*velero.RestoreItemActionExecuteOutput, error) {
 	itemJSON, err := json.Marshal(input.Item.UnstructuredContent())
 	if err != nil {
@@ -65,14 +65,22 @@ func (s *RestoreItemActionGRPCServer) AppliesTo(ctx context.Context, req *proto.
 		return nil, newGRPCError(err)
 	}
 
+	var priority int
+	if prioritized, ok := impl.(velero.PrioritizedRestoreItemAction); ok {
+		if priority, err = prioritized.Priority(); err != nil {
+			return nil, newGRPCError(err)
+		}
+	}
+
 	return &proto.RestoreItemActionAppliesToResponse{
-		&proto.ResourceSelector{
+		ResourceSelector: &proto.ResourceSelector{
 			IncludedNamespaces: resourceSelector.IncludedNamespaces,
 			ExcludedNamespaces: resourceSelector.ExcludedNamespaces,
 			IncludedResources:  resourceSelector.IncludedResources,
 			ExcludedResources:  resourceSelector.ExcludedResources,
 			Selector:           resourceSelector.LabelSelector,
 		},
+		Priority: int32(priority),
 	}, nil
 }
 
@@ -111,6 +111,7 @@ func (m *RestoreItemActionAppliesToRequest) GetPlugin() string {
 
 type RestoreItemActionAppliesToResponse struct {
 	ResourceSelector *ResourceSelector `protobuf:"bytes,1,opt,name=ResourceSelector" json:"ResourceSelector,omitempty"`
+	Priority         int32             `protobuf:"varint,2,opt,name=priority" json:"priority,omitempty"`
 }
 
 func (m *RestoreItemActionAppliesToResponse) Reset()         { *m = RestoreItemActionAppliesToResponse{} }
@@ -127,6 +128,13 @@ func (m *RestoreItemActionAppliesToResponse) GetResourceSelector() *ResourceSele
 	return nil
 }
 
+func (m *RestoreItemActionAppliesToResponse) GetPriority() int32 {
+	if m != nil {
+		return m.Priority
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*RestoreItemActionExecuteRequest)(nil), "generated.RestoreItemActionExecuteRequest")
 	proto.RegisterType((*RestoreItemActionExecuteResponse)(nil), "generated.RestoreItemActionExecuteResponse")
@@ -242,26 +250,27 @@ var _RestoreItemAction_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("RestoreItemAction.proto", fileDescriptor3) }
 
 var fileDescriptor3 = []byte{
-	// 332 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x52, 0xdd, 0x4e, 0xc2, 0x30,
-	0x14, 0x4e, 0x81, 0x80, 0x1c, 0x88, 0x3f, 0xbd, 0xd0, 0x06, 0x63, 0x9c, 0xbb, 0x30, 0xc4, 0x1f,
-	0x2e, 0xf0, 0xd2, 0x2b, 0x4c, 0x94, 0x70, 0x5b, 0x7c, 0x81, 0xb1, 0x1d, 0xa1, 0x61, 0x5b, 0x6b,
-	0xdb, 0x25, 0xbe, 0x85, 0xcf, 0xe0, 0xa3, 0xf9, 0x26, 0x86, 0x31, 0x96, 0xc1, 0x74, 0x72, 0xd7,
-	0x73, 0xfa, 0x7d, 0xe7, 0xfb, 0xbe, 0xf6, 0xc0, 0x19, 0x47, 0x63, 0xa5, 0xc6, 0x89, 0xc5, 0x68,
-	0xe4, 0x5b, 0x21, 0xe3, 0x81, 0xd2, 0xd2, 0x4a, 0xda, 0x9e, 0x63, 0x8c, 0xda, 0xb3, 0x18, 0xf4,
-	0xba, 0xd3, 0x85, 0xa7, 0x31, 0x58, 0x5f, 0xb8, 0x9f, 0x04, 0x2e, 0x4b, 0xa4, 0xe7, 0x0f, 0xf4,
-	0x13, 0x8b, 0x1c, 0xdf, 0x13, 0x34, 0x96, 0x9e, 0x42, 0x53, 0x85, 0xc9, 0x5c, 0xc4, 0x8c, 0x38,
-	0xa4, 0xdf, 0xe6, 0x59, 0x45, 0x29, 0x34, 0x84, 0xc5, 0x88, 0xd5, 0x1c, 0xd2, 0xef, 0xf2, 0xf4,
-	0x4c, 0x19, 0xb4, 0xf4, 0x7a, 0x1c, 0xab, 0xa7, 0xed, 0x4d, 0x49, 0xaf, 0xe1, 0x70, 0x85, 0x78,
-	0xd1, 0x32, 0x7a, 0xf2, 0xfc, 0x65, 0xa2, 0x58, 0x23, 0x05, 0xec, 0x74, 0xdd, 0x2f, 0x02, 0xce,
-	0xdf, 0x8e, 0x8c, 0x92, 0xb1, 0xc1, 0x5c, 0x9a, 0x14, 0xa4, 0xc7, 0x70, 0xe4, 0x05, 0x81, 0x58,
-	0xc1, 0xbd, 0x70, 0x45, 0x35, 0xac, 0xe6, 0xd4, 0xfb, 0x9d, 0xe1, 0xc5, 0x20, 0x4f, 0x3f, 0xe0,
-	0x68, 0x64, 0xa2, 0x7d, 0x9c, 0x04, 0x18, 0x5b, 0xf1, 0x26, 0x50, 0xf3, 0x5d, 0x16, 0x75, 0xa0,
-	0x63, 0x96, 0x42, 0xf1, 0x42, 0x8e, 0x03, 0x5e, 0x6c, 0xb9, 0x8f, 0x70, 0x55, 0xb2, 0x38, 0x52,
-	0x2a, 0x14, 0x68, 0x5e, 0xe5, 0x3f, 0xcf, 0xe6, 0x46, 0xe0, 0x56, 0x91, 0xb3, 0x84, 0x63, 0x38,
-	0xde, 0x78, 0x9d, 0x62, 0x88, 0xbe, 0x95, 0x3a, 0x9d, 0xd3, 0x19, 0x9e, 0xff, 0x12, 0x67, 0x03,
-	0xe1, 0x25, 0xd2, 0xf0, 0x9b, 0xc0, 0x49, 0x49, 0x8f, 0x2e, 0xa0, 0x9d, 0x6b, 0xd2, 0xbb, 0xed,
-	0x89, 0xd5, 0xb9, 0x7a, 0xf7, 0x7b, 0xa2, 0xb3, 0x20, 0x33, 0x68, 0x65, 0xbf, 0x47, 0x6f, 0xaa,
-	0x98, 0xdb, 0x4b, 0xd7, 0xbb, 0xdd, 0x0b, 0xbb, 0xd6, 0x98, 0x35, 0xd3, 0x65, 0x7e, 0xf8, 0x09,
-	0x00, 0x00, 0xff, 0xff, 0x1b, 0x4c, 0xdc, 0xb7, 0x00, 0x03, 0x00, 0x00,
+	// 345 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x52, 0xcb, 0x4e, 0xc2, 0x40,
+	0x14, 0xcd, 0x00, 0xf2, 0xb8, 0x10, 0x1f, 0xb3, 0xd0, 0xa6, 0xc6, 0x58, 0xbb, 0x30, 0xc4, 0x07,
+	0x0b, 0x5c, 0xba, 0xc2, 0x44, 0x09, 0xdb, 0xc1, 0x1f, 0x28, 0xed, 0x15, 0x26, 0x94, 0xce, 0x38,
+	0x33, 0x4d, 0xf4, 0x13, 0x5c, 0xf9, 0x0d, 0x7e, 0x9a, 0x7f, 0x62, 0x18, 0x4a, 0x03, 0x54, 0x91,
+	0x5d, 0xef, 0xed, 0x39, 0xf7, 0x9c, 0x33, 0xf7, 0xc2, 0x09, 0x43, 0x6d, 0x84, 0xc2, 0x81, 0xc1,
+	0x59, 0x2f, 0x34, 0x5c, 0x24, 0x1d, 0xa9, 0x84, 0x11, 0xb4, 0x31, 0xc6, 0x04, 0x55, 0x60, 0x30,
+	0x72, 0x5b, 0xc3, 0x49, 0xa0, 0x30, 0x5a, 0xfc, 0xf0, 0x3f, 0x09, 0x9c, 0x17, 0x48, 0x8f, 0x6f,
+	0x18, 0xa6, 0x06, 0x19, 0xbe, 0xa6, 0xa8, 0x0d, 0x3d, 0x86, 0xaa, 0x8c, 0xd3, 0x31, 0x4f, 0x1c,
+	0xe2, 0x91, 0x76, 0x83, 0x65, 0x15, 0xa5, 0x50, 0xe1, 0x06, 0x67, 0x4e, 0xc9, 0x23, 0xed, 0x16,
+	0xb3, 0xdf, 0xd4, 0x81, 0x9a, 0x5a, 0x8c, 0x73, 0xca, 0xb6, 0xbd, 0x2c, 0xe9, 0x25, 0xec, 0xcf,
+	0x11, 0x4f, 0x4a, 0xcc, 0x1e, 0x82, 0x70, 0x9a, 0x4a, 0xa7, 0x62, 0x01, 0x1b, 0x5d, 0xff, 0x8b,
+	0x80, 0xf7, 0xb7, 0x23, 0x2d, 0x45, 0xa2, 0x31, 0x97, 0x26, 0x2b, 0xd2, 0x7d, 0x38, 0x08, 0xa2,
+	0x88, 0xcf, 0xe1, 0x41, 0x3c, 0xa7, 0x6a, 0xa7, 0xe4, 0x95, 0xdb, 0xcd, 0xee, 0x59, 0x27, 0x4f,
+	0xdf, 0x61, 0xa8, 0x45, 0xaa, 0x42, 0x1c, 0x44, 0x98, 0x18, 0xfe, 0xc2, 0x51, 0xb1, 0x4d, 0x16,
+	0xf5, 0xa0, 0xa9, 0xa7, 0x5c, 0xb2, 0x95, 0x1c, 0x75, 0xb6, 0xda, 0xf2, 0xef, 0xe1, 0xa2, 0x60,
+	0xb1, 0x27, 0x65, 0xcc, 0x51, 0x3f, 0x8b, 0x7f, 0x9e, 0xcd, 0xff, 0x20, 0xe0, 0x6f, 0x63, 0x67,
+	0x11, 0xfb, 0x70, 0xb8, 0x34, 0x3b, 0xc4, 0x18, 0x43, 0x23, 0x94, 0x1d, 0xd4, 0xec, 0x9e, 0xfe,
+	0x92, 0x67, 0x09, 0x61, 0x05, 0x12, 0x75, 0xa1, 0x2e, 0x15, 0x17, 0x8a, 0x9b, 0x77, 0xbb, 0xaa,
+	0x3d, 0x96, 0xd7, 0xdd, 0x6f, 0x02, 0x47, 0x05, 0x2f, 0x74, 0x02, 0x8d, 0xdc, 0x0f, 0xbd, 0x59,
+	0x57, 0xdb, 0x1e, 0xda, 0xbd, 0xdd, 0x11, 0x9d, 0x85, 0x1c, 0x41, 0x2d, 0x5b, 0x2d, 0xbd, 0xda,
+	0xc6, 0x5c, 0xbf, 0x48, 0xf7, 0x7a, 0x27, 0xec, 0x42, 0x63, 0x54, 0xb5, 0x97, 0x7e, 0xf7, 0x33,
+	0x00, 0x11, 0x53, 0xd1, 0x1e, 0x1d, 0x03, 0x00, 0x00,
 }
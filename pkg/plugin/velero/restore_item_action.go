@@ -38,6 +38,18 @@ type RestoreItemAction interface {
 	Execute(input *RestoreItemActionExecuteInput) (*RestoreItemActionExecuteOutput, error)
 }
 
+// PrioritizedRestoreItemAction is a RestoreItemAction that runs in a specific order
+// relative to the other actions that apply to an item. Actions run in decreasing
+// order of priority, and actions with the same priority run in the order they're
+// registered. RestoreItemActions that don't implement this interface have a
+// priority of 0.
+type PrioritizedRestoreItemAction interface {
+	RestoreItemAction
+
+	// Priority returns the action's priority.
+	Priority() (int, error)
+}
+
 // RestoreItemActionExecuteInput contains the input parameters for the ItemAction's Execute function.
 type RestoreItemActionExecuteInput struct {
 	// Item is the item being restored. It is likely different from the pristine backed up version
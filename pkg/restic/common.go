@@ -91,6 +91,18 @@ func SetPodSnapshotAnnotation(obj metav1.Object, volumeName, snapshotID string)
 	obj.SetAnnotations(annotations)
 }
 
+// RemovePodSnapshotAnnotation removes the annotation indicating that the
+// specified volume of a pod has a restic snapshot, if there is one.
+func RemovePodSnapshotAnnotation(obj metav1.Object, volumeName string) {
+	annotations := obj.GetAnnotations()
+	if _, ok := annotations[podAnnotationPrefix+volumeName]; !ok {
+		return
+	}
+
+	delete(annotations, podAnnotationPrefix+volumeName)
+	obj.SetAnnotations(annotations)
+}
+
 // GetVolumesToBackup returns a list of volume names to backup for
 // the provided pod.
 func GetVolumesToBackup(obj metav1.Object) []string {
@@ -169,6 +169,18 @@ func TestSetPodSnapshotAnnotation(t *testing.T) {
 	}
 }
 
+func TestRemovePodSnapshotAnnotation(t *testing.T) {
+	pod := &corev1api.Pod{}
+	pod.Annotations = map[string]string{"existing": "annotation", podAnnotationPrefix + "foo": "bar", podAnnotationPrefix + "baz": "qux"}
+
+	RemovePodSnapshotAnnotation(pod, "foo")
+	assert.Equal(t, map[string]string{"existing": "annotation", podAnnotationPrefix + "baz": "qux"}, pod.Annotations)
+
+	// removing an annotation that doesn't exist is a no-op
+	RemovePodSnapshotAnnotation(pod, "missing")
+	assert.Equal(t, map[string]string{"existing": "annotation", podAnnotationPrefix + "baz": "qux"}, pod.Annotations)
+}
+
 func TestGetVolumesToBackup(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -0,0 +1,77 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	api "github.com/heptio/velero/pkg/apis/velero/v1"
+	"github.com/heptio/velero/pkg/client"
+)
+
+// applyFieldManager is the field manager that items are applied as when
+// they're restored with server-side apply.
+const applyFieldManager = "velero"
+
+const applyConflictReason = "conflicts with fields owned by another field manager"
+
+// createOrApply creates the provided item, or applies it with server-side apply if
+// that's the restore's apply method. Items that only have a generated name can't be
+// applied, so they're always created. Applying an item that already exists in the
+// cluster updates the fields it sets, and fails with a conflict for fields that another
+// field manager owns unless the restore forces the apply.
+// It only reads the restore's spec, so it's safe to call without ctx.itemLock held.
+func (ctx *context) createOrApply(obj *unstructured.Unstructured, resourceClient client.Dynamic) (*unstructured.Unstructured, error) {
+	if ctx.restore.Spec.ApplyMethod != api.ApplyMethodServerSideApply || obj.GetName() == "" {
+		return resourceClient.Create(obj)
+	}
+
+	return resourceClient.Apply(obj, applyFieldManager, ctx.restore.Spec.ForceApply)
+}
+
+// appliedOverExisting returns true if the provided item, as returned by applying it,
+// has fields owned by field managers other than velero, i.e. it already existed in
+// the cluster before it was applied.
+func appliedOverExisting(obj *unstructured.Unstructured) bool {
+	for _, entry := range obj.GetManagedFields() {
+		if entry.Manager != applyFieldManager {
+			return true
+		}
+	}
+	return false
+}
+
+// isApplyConflict returns true if the provided error is from applying an item whose
+// fields are owned by another field manager.
+func isApplyConflict(err error) bool {
+	if !apierrors.IsConflict(err) {
+		return false
+	}
+
+	status, ok := err.(apierrors.APIStatus)
+	if !ok || status.Status().Details == nil {
+		return false
+	}
+	for _, cause := range status.Status().Details.Causes {
+		if cause.Type == metav1.CauseTypeFieldManagerConflict {
+			return true
+		}
+	}
+	return false
+}
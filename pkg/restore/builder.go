@@ -17,6 +17,10 @@ limitations under the License.
 package restore
 
 import (
+	"time"
+
+	corev1api "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	velerov1api "github.com/heptio/velero/pkg/apis/velero/v1"
@@ -96,6 +100,12 @@ func (b *Builder) LabelSelector(selector *metav1.LabelSelector) *Builder {
 	return b
 }
 
+// LabelSelectorExclude sets the Restore's exclude label selector.
+func (b *Builder) LabelSelectorExclude(selector *metav1.LabelSelector) *Builder {
+	b.restore.Spec.LabelSelectorExclude = selector
+	return b
+}
+
 // NamespaceMappings sets the Restore's namespace mappings.
 func (b *Builder) NamespaceMappings(mapping ...string) *Builder {
 	if b.restore.Spec.NamespaceMapping == nil {
@@ -112,3 +122,669 @@ func (b *Builder) NamespaceMappings(mapping ...string) *Builder {
 
 	return b
 }
+
+// WaitForCRDConversionWebhooks sets the Restore's "wait for CRD conversion webhooks" flag.
+func (b *Builder) WaitForCRDConversionWebhooks(val bool) *Builder {
+	b.restore.Spec.WaitForCRDConversionWebhooks = val
+	return b
+}
+
+// WaitForCRDsEstablished sets the Restore's wait for CRDs established flag.
+func (b *Builder) WaitForCRDsEstablished(val bool) *Builder {
+	b.restore.Spec.WaitForCRDsEstablished = val
+	return b
+}
+
+// ReadinessTimeout sets the Restore's readiness timeout.
+func (b *Builder) ReadinessTimeout(timeout time.Duration) *Builder {
+	b.restore.Spec.ReadinessTimeout.Duration = timeout
+	return b
+}
+
+// ImagePullPolicyOverride sets the Restore's image pull policy override.
+func (b *Builder) ImagePullPolicyOverride(policy corev1api.PullPolicy) *Builder {
+	b.restore.Spec.ImagePullPolicyOverride = policy
+	return b
+}
+
+// StripHPAManagedReplicas sets the Restore's "strip HPA-managed replicas" flag.
+func (b *Builder) StripHPAManagedReplicas(val bool) *Builder {
+	b.restore.Spec.StripHPAManagedReplicas = val
+	return b
+}
+
+// MissingNamespacePolicy sets the Restore's missing namespace policy.
+func (b *Builder) MissingNamespacePolicy(policy velerov1api.MissingNamespacePolicy) *Builder {
+	b.restore.Spec.MissingNamespacePolicy = policy
+	return b
+}
+
+// DefaultNamespace sets the Restore's default namespace.
+func (b *Builder) DefaultNamespace(namespace string) *Builder {
+	b.restore.Spec.DefaultNamespace = namespace
+	return b
+}
+
+// DataKeyMappings appends to the Restore's data key mappings.
+func (b *Builder) DataKeyMappings(mappings ...velerov1api.DataKeyMapping) *Builder {
+	b.restore.Spec.DataKeyMappings = append(b.restore.Spec.DataKeyMappings, mappings...)
+	return b
+}
+
+// ObserveDriftSeconds sets the Restore's drift observation window, in seconds.
+func (b *Builder) ObserveDriftSeconds(seconds int64) *Builder {
+	b.restore.Spec.ObserveDriftSeconds = seconds
+	return b
+}
+
+// NamespaceFanout sets the target namespaces that the specified source namespace
+// is restored into.
+func (b *Builder) NamespaceFanout(source string, targets ...string) *Builder {
+	if b.restore.Spec.NamespaceFanout == nil {
+		b.restore.Spec.NamespaceFanout = make(map[string][]string)
+	}
+	b.restore.Spec.NamespaceFanout[source] = targets
+	return b
+}
+
+// RespectBackupExclusions sets the Restore's "respect backup exclusions" flag.
+func (b *Builder) RespectBackupExclusions(val bool) *Builder {
+	b.restore.Spec.RespectBackupExclusions = val
+	return b
+}
+
+// GatewayClassMappings sets the Restore's gateway class mappings.
+func (b *Builder) GatewayClassMappings(mapping ...string) *Builder {
+	if b.restore.Spec.GatewayClassMappings == nil {
+		b.restore.Spec.GatewayClassMappings = make(map[string]string)
+	}
+
+	if len(mapping)%2 != 0 {
+		panic("mapping must contain an even number of values")
+	}
+
+	for i := 0; i < len(mapping); i += 2 {
+		b.restore.Spec.GatewayClassMappings[mapping[i]] = mapping[i+1]
+	}
+
+	return b
+}
+
+// GatewayBackendRefMappings sets the Restore's gateway backend reference mappings.
+func (b *Builder) GatewayBackendRefMappings(mapping ...string) *Builder {
+	if b.restore.Spec.GatewayBackendRefMappings == nil {
+		b.restore.Spec.GatewayBackendRefMappings = make(map[string]string)
+	}
+
+	if len(mapping)%2 != 0 {
+		panic("mapping must contain an even number of values")
+	}
+
+	for i := 0; i < len(mapping); i += 2 {
+		b.restore.Spec.GatewayBackendRefMappings[mapping[i]] = mapping[i+1]
+	}
+
+	return b
+}
+
+// MaxObjectAge sets the Restore's maximum object age.
+func (b *Builder) MaxObjectAge(age time.Duration) *Builder {
+	b.restore.Spec.MaxObjectAge.Duration = age
+	return b
+}
+
+// TruncateOversizeMetadata sets the Restore's "truncate oversize metadata" flag.
+func (b *Builder) TruncateOversizeMetadata(val bool) *Builder {
+	b.restore.Spec.TruncateOversizeMetadata = val
+	return b
+}
+
+// KeepDefaultStorageClassAnnotation sets the Restore's "keep default storage class annotation" flag.
+func (b *Builder) KeepDefaultStorageClassAnnotation(val bool) *Builder {
+	b.restore.Spec.KeepDefaultStorageClassAnnotation = val
+	return b
+}
+
+// StorageClassPreferences appends to the Restore's storage class preferences.
+func (b *Builder) StorageClassPreferences(preferences ...velerov1api.StorageClassPreference) *Builder {
+	b.restore.Spec.StorageClassPreferences = append(b.restore.Spec.StorageClassPreferences, preferences...)
+	return b
+}
+
+// TraceItems sets the Restore's "trace items" flag.
+func (b *Builder) TraceItems(val bool) *Builder {
+	b.restore.Spec.TraceItems = val
+	return b
+}
+
+// AddTolerations appends to the Restore's toleration rules.
+func (b *Builder) AddTolerations(rules ...velerov1api.TolerationRule) *Builder {
+	b.restore.Spec.AddTolerations = append(b.restore.Spec.AddTolerations, rules...)
+	return b
+}
+
+// ExpectedCount sets the number of items of the specified resource the Restore is expected to create.
+func (b *Builder) ExpectedCount(resource string, count int) *Builder {
+	if b.restore.Spec.ExpectedCounts == nil {
+		b.restore.Spec.ExpectedCounts = make(map[string]int)
+	}
+	b.restore.Spec.ExpectedCounts[resource] = count
+	return b
+}
+
+// StrictExpectedCounts sets the Restore's "strict expected counts" flag.
+func (b *Builder) StrictExpectedCounts(val bool) *Builder {
+	b.restore.Spec.StrictExpectedCounts = val
+	return b
+}
+
+// CSIDriverMappings sets the Restore's CSI driver mappings.
+func (b *Builder) CSIDriverMappings(mapping ...string) *Builder {
+	if b.restore.Spec.CSIDriverMappings == nil {
+		b.restore.Spec.CSIDriverMappings = make(map[string]string)
+	}
+
+	if len(mapping)%2 != 0 {
+		panic("mapping must contain an even number of values")
+	}
+
+	for i := 0; i < len(mapping); i += 2 {
+		b.restore.Spec.CSIDriverMappings[mapping[i]] = mapping[i+1]
+	}
+
+	return b
+}
+
+// StripPriorityClass sets the Restore's selector for items whose priority class is removed.
+func (b *Builder) StripPriorityClass(selector *metav1.LabelSelector) *Builder {
+	b.restore.Spec.StripPriorityClass = selector
+	return b
+}
+
+// AnnotateTransforms sets the Restore's "annotate transforms" flag.
+func (b *Builder) AnnotateTransforms(val bool) *Builder {
+	b.restore.Spec.AnnotateTransforms = val
+	return b
+}
+
+// ClusterScopedOnly sets the Restore's "cluster-scoped only" flag.
+func (b *Builder) ClusterScopedOnly(val bool) *Builder {
+	b.restore.Spec.ClusterScopedOnly = val
+	return b
+}
+
+// SchedulerName sets the Restore's scheduler name.
+func (b *Builder) SchedulerName(name string) *Builder {
+	b.restore.Spec.SchedulerName = name
+	return b
+}
+
+// Parallelism sets the Restore's parallelism.
+func (b *Builder) Parallelism(val int) *Builder {
+	b.restore.Spec.Parallelism = val
+	return b
+}
+
+// ResourceParallelism sets the Restore's parallelism for the specified resource.
+func (b *Builder) ResourceParallelism(resource string, val int) *Builder {
+	if b.restore.Spec.ResourceParallelism == nil {
+		b.restore.Spec.ResourceParallelism = make(map[string]int)
+	}
+	b.restore.Spec.ResourceParallelism[resource] = val
+	return b
+}
+
+// IndependentResourceParallelism sets the Restore's independent resource parallelism.
+func (b *Builder) IndependentResourceParallelism(val int) *Builder {
+	b.restore.Spec.IndependentResourceParallelism = val
+	return b
+}
+
+// DryRun sets the Restore's "dry run" flag.
+func (b *Builder) DryRun(val bool) *Builder {
+	b.restore.Spec.DryRun = val
+	return b
+}
+
+// ResourcePriorities appends to the Restore's resource priorities.
+func (b *Builder) ResourcePriorities(resources ...string) *Builder {
+	b.restore.Spec.ResourcePriorities = append(b.restore.Spec.ResourcePriorities, resources...)
+	return b
+}
+
+// ValidateRBACReferences sets the Restore's "validate RBAC references" flag.
+func (b *Builder) ValidateRBACReferences(val bool) *Builder {
+	b.restore.Spec.ValidateRBACReferences = val
+	return b
+}
+
+// Hooks sets the Restore's hooks.
+func (b *Builder) Hooks(hooks velerov1api.RestoreHooks) *Builder {
+	b.restore.Spec.Hooks = hooks
+	return b
+}
+
+// CredentialSecretMappings sets the Restore's credential secret mappings.
+func (b *Builder) CredentialSecretMappings(mapping ...string) *Builder {
+	if b.restore.Spec.CredentialSecretMappings == nil {
+		b.restore.Spec.CredentialSecretMappings = make(map[string]string)
+	}
+
+	if len(mapping)%2 != 0 {
+		panic("mapping must contain an even number of values")
+	}
+
+	for i := 0; i < len(mapping); i += 2 {
+		b.restore.Spec.CredentialSecretMappings[mapping[i]] = mapping[i+1]
+	}
+
+	return b
+}
+
+// CredentialSecretPaths appends to the Restore's credential secret paths.
+func (b *Builder) CredentialSecretPaths(paths ...string) *Builder {
+	b.restore.Spec.CredentialSecretPaths = append(b.restore.Spec.CredentialSecretPaths, paths...)
+	return b
+}
+
+// DecodeErrorPolicy sets the Restore's decode error policy.
+func (b *Builder) DecodeErrorPolicy(policy velerov1api.DecodeErrorPolicy) *Builder {
+	b.restore.Spec.DecodeErrorPolicy = policy
+	return b
+}
+
+// HostAliasRewrites sets the Restore's host alias rewrites.
+func (b *Builder) HostAliasRewrites(rewrite ...string) *Builder {
+	if b.restore.Spec.HostAliasRewrites == nil {
+		b.restore.Spec.HostAliasRewrites = make(map[string]string)
+	}
+
+	if len(rewrite)%2 != 0 {
+		panic("rewrite must contain an even number of values")
+	}
+
+	for i := 0; i < len(rewrite); i += 2 {
+		b.restore.Spec.HostAliasRewrites[rewrite[i]] = rewrite[i+1]
+	}
+
+	return b
+}
+
+// KeepAnnotationPrefixes appends to the Restore's annotation prefixes to keep.
+func (b *Builder) KeepAnnotationPrefixes(prefixes ...string) *Builder {
+	b.restore.Spec.KeepAnnotationPrefixes = append(b.restore.Spec.KeepAnnotationPrefixes, prefixes...)
+	return b
+}
+
+// StripAnnotationPrefixes appends to the Restore's annotation prefixes to strip.
+func (b *Builder) StripAnnotationPrefixes(prefixes ...string) *Builder {
+	b.restore.Spec.StripAnnotationPrefixes = append(b.restore.Spec.StripAnnotationPrefixes, prefixes...)
+	return b
+}
+
+// VolumeRestoreParallelism sets the Restore's volume restore parallelism.
+func (b *Builder) VolumeRestoreParallelism(val int) *Builder {
+	b.restore.Spec.VolumeRestoreParallelism = val
+	return b
+}
+
+// StorageClassMappings sets the Restore's storage class mappings.
+func (b *Builder) StorageClassMappings(mapping ...string) *Builder {
+	if b.restore.Spec.StorageClassMappings == nil {
+		b.restore.Spec.StorageClassMappings = make(map[string]string)
+	}
+
+	if len(mapping)%2 != 0 {
+		panic("mapping must contain an even number of values")
+	}
+
+	for i := 0; i < len(mapping); i += 2 {
+		b.restore.Spec.StorageClassMappings[mapping[i]] = mapping[i+1]
+	}
+
+	return b
+}
+
+// ReportUnreferenced sets the Restore's ReportUnreferenced flag.
+func (b *Builder) ReportUnreferenced(val bool) *Builder {
+	b.restore.Spec.ReportUnreferenced = val
+	return b
+}
+
+// PruneUnreferenced sets the Restore's PruneUnreferenced flag.
+func (b *Builder) PruneUnreferenced(val bool) *Builder {
+	b.restore.Spec.PruneUnreferenced = val
+	return b
+}
+
+// ScopeFilter sets the Restore's scope filter.
+func (b *Builder) ScopeFilter(filter velerov1api.ScopeFilter) *Builder {
+	b.restore.Spec.ScopeFilter = filter
+	return b
+}
+
+// PodSecurityPolicy sets the Restore's pod security policy.
+func (b *Builder) PodSecurityPolicy(policy velerov1api.PodSecurityPolicy) *Builder {
+	b.restore.Spec.PodSecurityPolicy = policy
+	return b
+}
+
+// CSIVolumeAttributeMappings appends to the Restore's CSI volume attribute mappings.
+func (b *Builder) CSIVolumeAttributeMappings(mappings ...velerov1api.CSIVolumeAttributeMapping) *Builder {
+	b.restore.Spec.CSIVolumeAttributeMappings = append(b.restore.Spec.CSIVolumeAttributeMappings, mappings...)
+	return b
+}
+
+// ExistingResourcePolicy sets the Restore's existing resource policy.
+func (b *Builder) ExistingResourcePolicy(policy velerov1api.ExistingResourcePolicy) *Builder {
+	b.restore.Spec.ExistingResourcePolicy = policy
+	return b
+}
+
+// ServiceFieldOverrides sets the Restore's service field overrides.
+func (b *Builder) ServiceFieldOverrides(overrides velerov1api.ServiceFieldOverrides) *Builder {
+	b.restore.Spec.ServiceFieldOverrides = &overrides
+	return b
+}
+
+// VersionCompatibilityMode sets the Restore's version compatibility mode.
+func (b *Builder) VersionCompatibilityMode(mode velerov1api.VersionCompatibilityMode) *Builder {
+	b.restore.Spec.VersionCompatibilityMode = mode
+	return b
+}
+
+// PreBindVolumes sets the Restore's "pre-bind volumes" flag.
+func (b *Builder) PreBindVolumes(val bool) *Builder {
+	b.restore.Spec.PreBindVolumes = val
+	return b
+}
+
+// IncludedResourceNames appends to the Restore's included names for the
+// specified resource.
+func (b *Builder) IncludedResourceNames(resource string, names ...string) *Builder {
+	if b.restore.Spec.IncludedResourceNames == nil {
+		b.restore.Spec.IncludedResourceNames = make(map[string][]string)
+	}
+	b.restore.Spec.IncludedResourceNames[resource] = append(b.restore.Spec.IncludedResourceNames[resource], names...)
+	return b
+}
+
+// PreRestoreBackup sets the Restore's "pre-restore backup" flag.
+func (b *Builder) PreRestoreBackup(val bool) *Builder {
+	b.restore.Spec.PreRestoreBackup = val
+	return b
+}
+
+// KubeconfigRewrites sets the Restore's kubeconfig rewrites.
+func (b *Builder) KubeconfigRewrites(rewrites velerov1api.KubeconfigRewrites) *Builder {
+	b.restore.Spec.KubeconfigRewrites = &rewrites
+	return b
+}
+
+// NamespaceLabels sets the Restore's namespace labels. "vals" is
+// a list of key-value pairs, so it must be of even length.
+func (b *Builder) NamespaceLabels(vals ...string) *Builder {
+	if b.restore.Spec.NamespaceLabels == nil {
+		b.restore.Spec.NamespaceLabels = make(map[string]string)
+	}
+
+	if len(vals)%2 != 0 {
+		panic("labels must contain an even number of values")
+	}
+
+	for i := 0; i < len(vals); i += 2 {
+		b.restore.Spec.NamespaceLabels[vals[i]] = vals[i+1]
+	}
+
+	return b
+}
+
+// NamespaceAnnotations sets the Restore's namespace annotations. "vals" is
+// a list of key-value pairs, so it must be of even length.
+func (b *Builder) NamespaceAnnotations(vals ...string) *Builder {
+	if b.restore.Spec.NamespaceAnnotations == nil {
+		b.restore.Spec.NamespaceAnnotations = make(map[string]string)
+	}
+
+	if len(vals)%2 != 0 {
+		panic("annotations must contain an even number of values")
+	}
+
+	for i := 0; i < len(vals); i += 2 {
+		b.restore.Spec.NamespaceAnnotations[vals[i]] = vals[i+1]
+	}
+
+	return b
+}
+
+// GenerateNamePolicy sets the Restore's generate name policy.
+func (b *Builder) GenerateNamePolicy(policy velerov1api.GenerateNamePolicy) *Builder {
+	b.restore.Spec.GenerateNamePolicy = policy
+	return b
+}
+
+// NamespaceReadyDelay sets the Restore's namespace ready delay.
+func (b *Builder) NamespaceReadyDelay(delay time.Duration) *Builder {
+	b.restore.Spec.NamespaceReadyDelay.Duration = delay
+	return b
+}
+
+// ReportUnresolvedReferences sets the Restore's "report unresolved references" flag.
+func (b *Builder) ReportUnresolvedReferences(val bool) *Builder {
+	b.restore.Spec.ReportUnresolvedReferences = val
+	return b
+}
+
+// VerifyAfterRestore sets the Restore's "verify after restore" flag.
+func (b *Builder) VerifyAfterRestore(val bool) *Builder {
+	b.restore.Spec.VerifyAfterRestore = val
+	return b
+}
+
+// GroupAliases appends to the Restore's group aliases.
+func (b *Builder) GroupAliases(aliases ...velerov1api.GroupAlias) *Builder {
+	b.restore.Spec.GroupAliases = append(b.restore.Spec.GroupAliases, aliases...)
+	return b
+}
+
+// SuspendVeleroObjects sets the Restore's "suspend Velero objects" flag.
+func (b *Builder) SuspendVeleroObjects(val bool) *Builder {
+	b.restore.Spec.SuspendVeleroObjects = val
+	return b
+}
+
+// RelaxVolumeSettings sets the Restore's volume settings relaxation.
+func (b *Builder) RelaxVolumeSettings(settings velerov1api.RelaxVolumeSettings) *Builder {
+	b.restore.Spec.RelaxVolumeSettings = &settings
+	return b
+}
+
+// PVCMinimumSize sets the Restore's minimum persistent volume claim size.
+func (b *Builder) PVCMinimumSize(size resource.Quantity) *Builder {
+	b.restore.Spec.PVCMinimumSize = &size
+	return b
+}
+
+// CheckpointInterval sets the number of items the Restore restores between checkpoints.
+func (b *Builder) CheckpointInterval(val int) *Builder {
+	b.restore.Spec.CheckpointInterval = val
+	return b
+}
+
+// SkipRestoreLabels sets the Restore's "skip restore labels" flag.
+func (b *Builder) SkipRestoreLabels(val bool) *Builder {
+	b.restore.Spec.SkipRestoreLabels = val
+	return b
+}
+
+// GroupVersionKindMappings appends to the Restore's group version kind mappings.
+func (b *Builder) GroupVersionKindMappings(mappings ...velerov1api.GroupVersionKindMapping) *Builder {
+	b.restore.Spec.GroupVersionKindMappings = append(b.restore.Spec.GroupVersionKindMappings, mappings...)
+	return b
+}
+
+// CaptureReplay sets the Restore's "capture replay" flag.
+func (b *Builder) CaptureReplay(val bool) *Builder {
+	b.restore.Spec.CaptureReplay = val
+	return b
+}
+
+// ReplayRestoreName sets the name of the restore the Restore replays.
+func (b *Builder) ReplayRestoreName(name string) *Builder {
+	b.restore.Spec.ReplayRestoreName = name
+	return b
+}
+
+// NameRewrite sets the Restore's name rewrite.
+func (b *Builder) NameRewrite(rewrite *velerov1api.NameRewrite) *Builder {
+	b.restore.Spec.NameRewrite = rewrite
+	return b
+}
+
+// StrictResourcePriorities sets the Restore's strict resource priorities flag.
+func (b *Builder) StrictResourcePriorities(val bool) *Builder {
+	b.restore.Spec.StrictResourcePriorities = val
+	return b
+}
+
+// RestorePVCDataSelector sets the Restore's PVC data selector.
+func (b *Builder) RestorePVCDataSelector(selector *metav1.LabelSelector) *Builder {
+	b.restore.Spec.RestorePVCDataSelector = selector
+	return b
+}
+
+// NamespaceCreationPolicy sets the Restore's namespace creation policy.
+func (b *Builder) NamespaceCreationPolicy(policy velerov1api.NamespaceCreationPolicy) *Builder {
+	b.restore.Spec.NamespaceCreationPolicy = policy
+	return b
+}
+
+// SecretSubstitutions appends to the Restore's secret substitutions.
+func (b *Builder) SecretSubstitutions(substitutions ...velerov1api.SecretSubstitution) *Builder {
+	b.restore.Spec.SecretSubstitutions = append(b.restore.Spec.SecretSubstitutions, substitutions...)
+	return b
+}
+
+// ExtraLabels sets the Restore's extra labels. "vals" is a list of
+// key-value pairs, so it must be of even length.
+func (b *Builder) ExtraLabels(vals ...string) *Builder {
+	if b.restore.Spec.ExtraLabels == nil {
+		b.restore.Spec.ExtraLabels = make(map[string]string)
+	}
+	for i := 0; i < len(vals)-1; i += 2 {
+		b.restore.Spec.ExtraLabels[vals[i]] = vals[i+1]
+	}
+	return b
+}
+
+// OverwriteExistingLabels sets the Restore's overwrite existing labels flag.
+func (b *Builder) OverwriteExistingLabels(val bool) *Builder {
+	b.restore.Spec.OverwriteExistingLabels = val
+	return b
+}
+
+// LowPriorityResources appends to the Restore's low-priority resources.
+func (b *Builder) LowPriorityResources(resources ...string) *Builder {
+	b.restore.Spec.LowPriorityResources = append(b.restore.Spec.LowPriorityResources, resources...)
+	return b
+}
+
+// WaitForReady appends to the Restore's resources to wait for readiness of.
+func (b *Builder) WaitForReady(resources ...string) *Builder {
+	b.restore.Spec.WaitForReady = append(b.restore.Spec.WaitForReady, resources...)
+	return b
+}
+
+// FailOnReadinessTimeout sets the Restore's fail on readiness timeout flag.
+func (b *Builder) FailOnReadinessTimeout(val bool) *Builder {
+	b.restore.Spec.FailOnReadinessTimeout = val
+	return b
+}
+
+// SourceSubPath sets the Restore's source sub-path.
+func (b *Builder) SourceSubPath(subPath string) *Builder {
+	b.restore.Spec.SourceSubPath = subPath
+	return b
+}
+
+// RemapOwnerReferences sets the Restore's remap owner references flag.
+func (b *Builder) RemapOwnerReferences(val bool) *Builder {
+	b.restore.Spec.RemapOwnerReferences = val
+	return b
+}
+
+// KeepUnresolvedOwnerReferences sets the Restore's keep unresolved owner references flag.
+func (b *Builder) KeepUnresolvedOwnerReferences(val bool) *Builder {
+	b.restore.Spec.KeepUnresolvedOwnerReferences = val
+	return b
+}
+
+// LatestOnly appends to the Restore's latest only filters.
+func (b *Builder) LatestOnly(filters ...velerov1api.LatestOnlyFilter) *Builder {
+	b.restore.Spec.LatestOnly = append(b.restore.Spec.LatestOnly, filters...)
+	return b
+}
+
+// NamespaceParallelism sets the Restore's namespace parallelism.
+func (b *Builder) NamespaceParallelism(val int) *Builder {
+	b.restore.Spec.NamespaceParallelism = val
+	return b
+}
+
+// NamespaceTimeout sets the Restore's namespace timeout.
+func (b *Builder) NamespaceTimeout(timeout time.Duration) *Builder {
+	b.restore.Spec.NamespaceTimeout.Duration = timeout
+	return b
+}
+
+// FieldExclusions appends to the Restore's excluded field paths for the
+// provided resource.
+func (b *Builder) FieldExclusions(resource string, fieldPaths ...string) *Builder {
+	if b.restore.Spec.FieldExclusions == nil {
+		b.restore.Spec.FieldExclusions = make(map[string][]string)
+	}
+	b.restore.Spec.FieldExclusions[resource] = append(b.restore.Spec.FieldExclusions[resource], fieldPaths...)
+	return b
+}
+
+// KeepLeaderElectionRecords sets the Restore's keep leader election records flag.
+func (b *Builder) KeepLeaderElectionRecords(val bool) *Builder {
+	b.restore.Spec.KeepLeaderElectionRecords = val
+	return b
+}
+
+// LeaderElectionAnnotations appends to the Restore's leader election annotations.
+func (b *Builder) LeaderElectionAnnotations(annotations ...string) *Builder {
+	b.restore.Spec.LeaderElectionAnnotations = append(b.restore.Spec.LeaderElectionAnnotations, annotations...)
+	return b
+}
+
+// ApplyMethod sets the Restore's apply method.
+func (b *Builder) ApplyMethod(method velerov1api.ApplyMethod) *Builder {
+	b.restore.Spec.ApplyMethod = method
+	return b
+}
+
+// ForceApply sets the Restore's force apply flag.
+func (b *Builder) ForceApply(val bool) *Builder {
+	b.restore.Spec.ForceApply = val
+	return b
+}
+
+// CheckResourceQuotas sets the Restore's check resource quotas flag.
+func (b *Builder) CheckResourceQuotas(val bool) *Builder {
+	b.restore.Spec.CheckResourceQuotas = val
+	return b
+}
+
+// NodeMappings appends to the Restore's node mappings.
+func (b *Builder) NodeMappings(mappings ...velerov1api.NodeMapping) *Builder {
+	b.restore.Spec.NodeMappings = append(b.restore.Spec.NodeMappings, mappings...)
+	return b
+}
+
+// ScaleDownOnRestore sets the Restore's scale down on restore flag.
+func (b *Builder) ScaleDownOnRestore(val bool) *Builder {
+	b.restore.Spec.ScaleDownOnRestore = val
+	return b
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	api "github.com/heptio/velero/pkg/apis/velero/v1"
+)
+
+// Checkpointer records the items a running restore has restored, e.g. in its backup
+// storage location, so that the restore can be resumed without restoring them again.
+// Items are identified as <group resource>/<namespace>/<name> for namespaced items and
+// <group resource>/<name> for cluster-scoped items.
+type Checkpointer interface {
+	// Checkpoint records the provided items as restored by the provided restore.
+	Checkpoint(restore *api.Restore, restoredItems []string) error
+
+	// LoadCheckpoint returns the items in the provided restore's last
+	// checkpoint, or none if it hasn't been checkpointed.
+	LoadCheckpoint(restore *api.Restore) ([]string, error)
+}
+
+// restoreCheckpoint tracks the items a checkpointed restore has restored, including
+// those it restored before it was resumed.
+type restoreCheckpoint struct {
+	interval int
+
+	// resumed are the items checkpointed before the restore was resumed.
+	resumed sets.String
+
+	// items are the items restored so far, and pending is the number of
+	// them restored since the last checkpoint.
+	items   []string
+	pending int
+
+	// lock serializes checkpoints, and written is the number of items in
+	// the last checkpoint, so that a checkpoint never replaces a newer one.
+	lock    sync.Mutex
+	written int
+}
+
+// newRestoreCheckpoint returns a restoreCheckpoint for the provided restore, resuming
+// from the provided items of its last checkpoint.
+func newRestoreCheckpoint(restore *api.Restore, checkpointed []string) *restoreCheckpoint {
+	return &restoreCheckpoint{
+		interval: restore.Spec.CheckpointInterval,
+		resumed:  sets.NewString(checkpointed...),
+		items:    append([]string(nil), checkpointed...),
+		written:  len(checkpointed),
+	}
+}
+
+// loadCheckpoint returns the items in the provided restore's last checkpoint from the
+// restorer's checkpointer. Restores that aren't checkpointed, or restored without a
+// checkpointer, have none.
+func (kr *kubernetesRestorer) loadCheckpoint(restore *api.Restore) ([]string, error) {
+	if restore.Spec.CheckpointInterval <= 0 || restore.Spec.DryRun || kr.checkpointer == nil {
+		return nil, nil
+	}
+
+	items, err := kr.checkpointer.LoadCheckpoint(restore)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error loading checkpoint of restore %s", restore.Name)
+	}
+	return items, nil
+}
+
+// has returns true if the specified item was checkpointed before the restore
+// was resumed.
+func (c *restoreCheckpoint) has(resourceID string) bool {
+	return c != nil && c.resumed.Has(resourceID)
+}
+
+// checkpointItem records that the specified item has been restored, and sends the
+// restored items to the restorer's checkpointer once every checkpoint interval items.
+// It's a no-op if the restore isn't checkpointed.
+func (ctx *context) checkpointItem(resourceID string) {
+	c := ctx.checkpoint
+	if c == nil || c.interval <= 0 || ctx.dryRun {
+		return
+	}
+
+	c.items = append(c.items, resourceID)
+	if c.pending++; c.pending < c.interval {
+		return
+	}
+	c.pending = 0
+
+	if ctx.checkpointer == nil {
+		return
+	}
+
+	var (
+		items        = append([]string(nil), c.items...)
+		restore      = ctx.restore
+		checkpointer = ctx.checkpointer
+		log          = ctx.log
+	)
+	ctx.withoutItemLock(func() {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+
+		if len(items) <= c.written {
+			return
+		}
+
+		if err := checkpointer.Checkpoint(restore, items); err != nil {
+			log.WithError(err).Warn("Error checkpointing restore")
+			return
+		}
+		c.written = len(items)
+
+		log.Debugf("Checkpointed %d restored items", len(items))
+	})
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/heptio/velero/pkg/kuberesource"
+)
+
+// CompletionCheck returns whether the provided item, as backed up, has reached
+// a terminal state. Completed items aren't restored.
+type CompletionCheck func(obj *unstructured.Unstructured) (bool, error)
+
+var (
+	completionChecksMu sync.RWMutex
+
+	// completionChecks is the registry of completion checks, keyed by the
+	// group resource of the items they apply to.
+	completionChecks = map[schema.GroupResource]CompletionCheck{
+		kuberesource.Pods: podCompleted,
+		kuberesource.Jobs: jobCompleted,
+	}
+)
+
+// RegisterCompletionCheck registers the provided completion check for items of the
+// specified group resource, replacing any check already registered for it.
+func RegisterCompletionCheck(groupResource schema.GroupResource, check CompletionCheck) {
+	completionChecksMu.Lock()
+	defer completionChecksMu.Unlock()
+
+	completionChecks[groupResource] = check
+}
+
+// podCompleted returns true if the provided pod has failed or succeeded.
+func podCompleted(obj *unstructured.Unstructured) (bool, error) {
+	phase, _, err := unstructured.NestedString(obj.UnstructuredContent(), "status", "phase")
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return phase == string(v1.PodFailed) || phase == string(v1.PodSucceeded), nil
+}
+
+// jobCompleted returns true if the provided job has a completion time.
+func jobCompleted(obj *unstructured.Unstructured) (bool, error) {
+	ct, found, err := unstructured.NestedString(obj.UnstructuredContent(), "status", "completionTime")
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return found && ct != "", nil
+}
+
+// isCompleted returns whether or not an object is considered completed.
+// Used to identify whether or not an object should be restored. Only objects
+// of the group resources with a registered completion check are considered.
+func isCompleted(obj *unstructured.Unstructured, groupResource schema.GroupResource) (bool, error) {
+	completionChecksMu.RLock()
+	check, ok := completionChecks[groupResource]
+	completionChecksMu.RUnlock()
+
+	// Assume any other resource isn't complete and can be restored
+	if !ok {
+		return false, nil
+	}
+	return check(obj)
+}
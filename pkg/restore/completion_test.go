@@ -0,0 +1,88 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	velerotest "github.com/heptio/velero/pkg/util/test"
+)
+
+func TestRegisterCompletionCheck(t *testing.T) {
+	pipelineRuns := schema.GroupResource{Group: "tekton.dev", Resource: "pipelineruns"}
+
+	RegisterCompletionCheck(pipelineRuns, func(obj *unstructured.Unstructured) (bool, error) {
+		completionTime, _, err := unstructured.NestedString(obj.Object, "status", "completionTime")
+		return completionTime != "", err
+	})
+	defer func() {
+		completionChecksMu.Lock()
+		delete(completionChecks, pipelineRuns)
+		completionChecksMu.Unlock()
+	}()
+
+	tests := []struct {
+		name          string
+		content       string
+		groupResource schema.GroupResource
+		want          bool
+	}{
+		{
+			name:          "an item of a registered group resource that the check considers terminal is complete",
+			content:       `{"apiVersion":"tekton.dev/v1beta1","kind":"PipelineRun","metadata":{"namespace":"ns","name":"run-1"},"status":{"completionTime":"2019-01-01T00:00:00Z"}}`,
+			groupResource: pipelineRuns,
+			want:          true,
+		},
+		{
+			name:          "an item of a registered group resource that the check doesn't consider terminal isn't complete",
+			content:       `{"apiVersion":"tekton.dev/v1beta1","kind":"PipelineRun","metadata":{"namespace":"ns","name":"run-1"},"status":{}}`,
+			groupResource: pipelineRuns,
+			want:          false,
+		},
+		{
+			name:          "an item of an unregistered group resource isn't complete",
+			content:       `{"apiVersion":"tekton.dev/v1beta1","kind":"TaskRun","metadata":{"namespace":"ns","name":"run-1"},"status":{"completionTime":"2019-01-01T00:00:00Z"}}`,
+			groupResource: schema.GroupResource{Group: "tekton.dev", Resource: "taskruns"},
+			want:          false,
+		},
+		{
+			name:          "the registered pod check is unchanged",
+			content:       `{"apiVersion":"v1","kind":"Pod","metadata":{"namespace":"ns","name":"pod1"},"status":{"phase":"Succeeded"}}`,
+			groupResource: schema.GroupResource{Resource: "pods"},
+			want:          true,
+		},
+		{
+			name:          "the registered job check is unchanged",
+			content:       `{"apiVersion":"batch/v1","kind":"Job","metadata":{"namespace":"ns","name":"job1"},"status":{"completionTime":"bar"}}`,
+			groupResource: schema.GroupResource{Group: "batch", Resource: "jobs"},
+			want:          true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			complete, err := isCompleted(velerotest.UnstructuredOrDie(tc.content), tc.groupResource)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, complete)
+		})
+	}
+}
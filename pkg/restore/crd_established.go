@@ -0,0 +1,88 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// waitForCRDsEstablished waits for each custom resource definition created by the
+// restore to be established, then refreshes the restore's discovery helper so the
+// resources the definitions add are served. Definitions that aren't established
+// within the readiness timeout, and discovery errors, are returned as cluster-scoped
+// warnings.
+func (ctx *context) waitForCRDsEstablished() Result {
+	warnings := Result{}
+
+	if len(ctx.restoredCRDs) == 0 {
+		return warnings
+	}
+
+	for _, item := range ctx.restoredCRDs {
+		name := item.obj.GetName()
+		log := ctx.log.WithField("customResourceDefinition", name)
+		log.Info("Waiting for custom resource definition to be established")
+
+		err := pollItem(item.client, name, ctx.readinessTimeout(), "it to be established", log, isCRDEstablished)
+		if err != nil {
+			addToResult(&warnings, "", errors.Wrapf(err, "custom resource definition %s is not established", name))
+			continue
+		}
+
+		log.Info("Custom resource definition is established")
+	}
+	ctx.restoredCRDs = nil
+
+	if err := ctx.discoveryHelper.Refresh(); err != nil {
+		addToResult(&warnings, "", errors.Wrap(err, "error refreshing discovery after restoring custom resource definitions"))
+	}
+
+	return warnings
+}
+
+// isCRDEstablished returns true if the provided custom resource definition has a
+// true Established condition.
+func isCRDEstablished(crd *unstructured.Unstructured) bool {
+	return hasTrueCondition(crd, "Established")
+}
+
+// newlyServedResources returns the resources the restore includes that discovery serves
+// but that aren't in the provided resources, in priority order. These are the resources
+// of custom resource definitions created by the restore.
+func (ctx *context) newlyServedResources(resources []schema.GroupResource) []schema.GroupResource {
+	prioritized, err := prioritizeResources(ctx.discoveryHelper, ctx.resourcePriorities, ctx.restore.Spec.LowPriorityResources, ctx.resourceIncludesExcludes, false, ctx.log)
+	if err != nil {
+		ctx.log.WithError(err).Warn("Error prioritizing the resources of restored custom resource definitions")
+		return nil
+	}
+
+	known := sets.NewString()
+	for _, resource := range resources {
+		known.Insert(resource.String())
+	}
+
+	var added []schema.GroupResource
+	for _, resource := range prioritized {
+		if !known.Has(resource.String()) {
+			added = append(added, resource)
+		}
+	}
+	return added
+}
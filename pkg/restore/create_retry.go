@@ -0,0 +1,85 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/heptio/velero/pkg/client"
+	"github.com/heptio/velero/pkg/util/kube"
+)
+
+// CreateRetryPolicy configures how many times the restorer attempts to create
+// an item when the create fails with a transient error, and how long it waits
+// before the first retry. The delay doubles with each subsequent retry.
+type CreateRetryPolicy struct {
+	// MaxAttempts is the maximum number of times an item's create is
+	// attempted. Values less than 2 disable retrying.
+	MaxAttempts int
+
+	// BaseDelay is how long to wait before the first retry.
+	BaseDelay time.Duration
+}
+
+// isRetriableCreateError returns true if the provided error from creating the provided
+// item is transient, i.e. the same create may succeed if it's attempted again. A create
+// that timed out may still have succeeded, so an item whose name is generated by the
+// API server isn't created again after a timeout, since that would create a duplicate.
+func isRetriableCreateError(obj *unstructured.Unstructured, err error) bool {
+	if apierrors.IsServerTimeout(err) || apierrors.IsTimeout(err) {
+		return !isGenerateNameOnly(obj)
+	}
+
+	return apierrors.IsConflict(err) || apierrors.IsTooManyRequests(err)
+}
+
+// createWithRetry creates the provided item, retrying with exponential backoff
+// according to the restore's create retry policy as long as the create fails with
+// a retriable error. The error from the last attempt is returned if none succeed.
+// Other items are restored while it waits to retry.
+func (ctx *context) createWithRetry(obj *unstructured.Unstructured, resourceClient client.Dynamic) (*unstructured.Unstructured, error) {
+	steps := ctx.createRetryPolicy.MaxAttempts
+	if steps < 1 {
+		steps = 1
+	}
+	backoff := wait.Backoff{
+		Duration: ctx.createRetryPolicy.BaseDelay,
+		Factor:   2,
+		Steps:    steps,
+	}
+
+	for attempt := 1; ; attempt++ {
+		createdObj, err := ctx.createWithoutItemLock(obj, resourceClient)
+		if err == nil {
+			return createdObj, nil
+		}
+		// apply conflicts are conflicts too, but applying the
+		// item again won't resolve them
+		if attempt >= steps || !isRetriableCreateError(obj, err) || isApplyConflict(err) {
+			return createdObj, err
+		}
+
+		ctx.log.Infof("Retrying create of %s after attempt %d of %d failed: %v", kube.NamespaceAndName(obj), attempt, steps, err)
+
+		delay := backoff.Step()
+		ctx.withoutItemLock(func() { time.Sleep(delay) })
+	}
+}
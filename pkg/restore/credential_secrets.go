@@ -0,0 +1,110 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/heptio/velero/pkg/kuberesource"
+)
+
+// remapCredentialSecrets changes the provided item's references to secrets named in the
+// restore's credential secret mappings to reference the mapped secrets instead. References
+// from an embedded pod spec, from a service account, and at the restore's credential secret
+// paths are remapped.
+func (ctx *context) remapCredentialSecrets(obj *unstructured.Unstructured, groupResource schema.GroupResource) {
+	if len(ctx.restore.Spec.CredentialSecretMappings) == 0 {
+		return
+	}
+
+	remap := func(parent map[string]interface{}, field string) {
+		name, ok := parent[field].(string)
+		if !ok {
+			return
+		}
+		if newName, ok := ctx.restore.Spec.CredentialSecretMappings[name]; ok {
+			ctx.log.Infof("Updating reference to secret %s in %s %s/%s to %s", name, groupResource, obj.GetNamespace(), obj.GetName(), newName)
+			parent[field] = newName
+		}
+	}
+
+	if podSpec, ok := getPodSpec(obj, groupResource); ok {
+		forEachMap(podSpec["imagePullSecrets"], func(ref map[string]interface{}) { remap(ref, "name") })
+
+		forEachMap(podSpec["volumes"], func(volume map[string]interface{}) {
+			if secret, ok := volume["secret"].(map[string]interface{}); ok {
+				remap(secret, "secretName")
+			}
+			if projected, ok := volume["projected"].(map[string]interface{}); ok {
+				forEachMap(projected["sources"], func(source map[string]interface{}) {
+					if secret, ok := source["secret"].(map[string]interface{}); ok {
+						remap(secret, "name")
+					}
+				})
+			}
+		})
+
+		forEachContainer(podSpec, func(container map[string]interface{}) {
+			forEachMap(container["envFrom"], func(envFrom map[string]interface{}) {
+				if secretRef, ok := envFrom["secretRef"].(map[string]interface{}); ok {
+					remap(secretRef, "name")
+				}
+			})
+			forEachMap(container["env"], func(env map[string]interface{}) {
+				if secretKeyRef, found, _ := unstructured.NestedFieldNoCopy(env, "valueFrom", "secretKeyRef"); found {
+					if secretKeyRef, ok := secretKeyRef.(map[string]interface{}); ok {
+						remap(secretKeyRef, "name")
+					}
+				}
+			})
+		})
+	}
+
+	if groupResource == kuberesource.ServiceAccounts {
+		forEachMap(obj.Object["secrets"], func(ref map[string]interface{}) { remap(ref, "name") })
+		forEachMap(obj.Object["imagePullSecrets"], func(ref map[string]interface{}) { remap(ref, "name") })
+	}
+
+	for _, path := range ctx.restore.Spec.CredentialSecretPaths {
+		fields := strings.Split(path, ".")
+		parent, found, _ := unstructured.NestedFieldNoCopy(obj.Object, fields[:len(fields)-1]...)
+		if !found {
+			continue
+		}
+		if parent, ok := parent.(map[string]interface{}); ok {
+			remap(parent, fields[len(fields)-1])
+		}
+	}
+}
+
+// forEachMap calls fn for each map in the provided value, if it's a slice.
+// Changes made by fn are reflected in the value.
+func forEachMap(val interface{}, fn func(map[string]interface{})) {
+	items, ok := val.([]interface{})
+	if !ok {
+		return
+	}
+
+	for _, item := range items {
+		if m, ok := item.(map[string]interface{}); ok {
+			fn(m)
+		}
+	}
+}
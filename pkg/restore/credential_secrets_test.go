@@ -0,0 +1,194 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	velerov1api "github.com/heptio/velero/pkg/apis/velero/v1"
+	"github.com/heptio/velero/pkg/kuberesource"
+	velerotest "github.com/heptio/velero/pkg/util/test"
+)
+
+func TestRemapCredentialSecrets(t *testing.T) {
+	tests := []struct {
+		name          string
+		restore       *velerov1api.Restore
+		groupResource schema.GroupResource
+		obj           *unstructured.Unstructured
+		want          *unstructured.Unstructured
+	}{
+		{
+			name:          "a secret referenced by a container's envFrom is remapped",
+			restore:       NewBuilder().CredentialSecretMappings("prod-creds", "dr-creds").Restore(),
+			groupResource: kuberesource.Pods,
+			obj: &unstructured.Unstructured{Object: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{
+							"name": "container-1",
+							"envFrom": []interface{}{
+								map[string]interface{}{"secretRef": map[string]interface{}{"name": "prod-creds"}},
+								map[string]interface{}{"secretRef": map[string]interface{}{"name": "other-secret"}},
+								map[string]interface{}{"configMapRef": map[string]interface{}{"name": "prod-creds"}},
+							},
+						},
+					},
+				},
+			}},
+			want: &unstructured.Unstructured{Object: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{
+							"name": "container-1",
+							"envFrom": []interface{}{
+								map[string]interface{}{"secretRef": map[string]interface{}{"name": "dr-creds"}},
+								map[string]interface{}{"secretRef": map[string]interface{}{"name": "other-secret"}},
+								map[string]interface{}{"configMapRef": map[string]interface{}{"name": "prod-creds"}},
+							},
+						},
+					},
+				},
+			}},
+		},
+		{
+			name:          "secrets referenced by a deployment's volumes, env and image pull secrets are remapped",
+			restore:       NewBuilder().CredentialSecretMappings("prod-creds", "dr-creds", "prod-registry", "dr-registry").Restore(),
+			groupResource: schema.GroupResource{Group: "apps", Resource: "deployments"},
+			obj: &unstructured.Unstructured{Object: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"template": map[string]interface{}{
+						"spec": map[string]interface{}{
+							"imagePullSecrets": []interface{}{
+								map[string]interface{}{"name": "prod-registry"},
+							},
+							"volumes": []interface{}{
+								map[string]interface{}{"name": "creds", "secret": map[string]interface{}{"secretName": "prod-creds"}},
+								map[string]interface{}{"name": "projected", "projected": map[string]interface{}{
+									"sources": []interface{}{
+										map[string]interface{}{"secret": map[string]interface{}{"name": "prod-creds"}},
+									},
+								}},
+							},
+							"initContainers": []interface{}{
+								map[string]interface{}{
+									"name": "init-1",
+									"env": []interface{}{
+										map[string]interface{}{"name": "KEY", "valueFrom": map[string]interface{}{
+											"secretKeyRef": map[string]interface{}{"name": "prod-creds", "key": "key"},
+										}},
+									},
+								},
+							},
+						},
+					},
+				},
+			}},
+			want: &unstructured.Unstructured{Object: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"template": map[string]interface{}{
+						"spec": map[string]interface{}{
+							"imagePullSecrets": []interface{}{
+								map[string]interface{}{"name": "dr-registry"},
+							},
+							"volumes": []interface{}{
+								map[string]interface{}{"name": "creds", "secret": map[string]interface{}{"secretName": "dr-creds"}},
+								map[string]interface{}{"name": "projected", "projected": map[string]interface{}{
+									"sources": []interface{}{
+										map[string]interface{}{"secret": map[string]interface{}{"name": "dr-creds"}},
+									},
+								}},
+							},
+							"initContainers": []interface{}{
+								map[string]interface{}{
+									"name": "init-1",
+									"env": []interface{}{
+										map[string]interface{}{"name": "KEY", "valueFrom": map[string]interface{}{
+											"secretKeyRef": map[string]interface{}{"name": "dr-creds", "key": "key"},
+										}},
+									},
+								},
+							},
+						},
+					},
+				},
+			}},
+		},
+		{
+			name:          "a service account's secrets are remapped",
+			restore:       NewBuilder().CredentialSecretMappings("prod-creds", "dr-creds").Restore(),
+			groupResource: kuberesource.ServiceAccounts,
+			obj: &unstructured.Unstructured{Object: map[string]interface{}{
+				"secrets":          []interface{}{map[string]interface{}{"name": "prod-creds"}},
+				"imagePullSecrets": []interface{}{map[string]interface{}{"name": "prod-creds"}},
+			}},
+			want: &unstructured.Unstructured{Object: map[string]interface{}{
+				"secrets":          []interface{}{map[string]interface{}{"name": "dr-creds"}},
+				"imagePullSecrets": []interface{}{map[string]interface{}{"name": "dr-creds"}},
+			}},
+		},
+		{
+			name: "a secret referenced at a configured path is remapped",
+			restore: NewBuilder().
+				CredentialSecretMappings("prod-creds", "dr-creds").
+				CredentialSecretPaths("spec.credentials.secretName", "spec.missing.secretName").
+				Restore(),
+			groupResource: schema.GroupResource{Group: "example.com", Resource: "databases"},
+			obj: &unstructured.Unstructured{Object: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"credentials": map[string]interface{}{"secretName": "prod-creds"},
+				},
+			}},
+			want: &unstructured.Unstructured{Object: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"credentials": map[string]interface{}{"secretName": "dr-creds"},
+				},
+			}},
+		},
+		{
+			name:          "secret references are unchanged without credential secret mappings",
+			restore:       NewBuilder().CredentialSecretPaths("spec.credentials.secretName").Restore(),
+			groupResource: schema.GroupResource{Group: "example.com", Resource: "databases"},
+			obj: &unstructured.Unstructured{Object: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"credentials": map[string]interface{}{"secretName": "prod-creds"},
+				},
+			}},
+			want: &unstructured.Unstructured{Object: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"credentials": map[string]interface{}{"secretName": "prod-creds"},
+				},
+			}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := &context{
+				restore: tc.restore,
+				log:     velerotest.NewLogger(),
+			}
+
+			ctx.remapCredentialSecrets(tc.obj, tc.groupResource)
+			assert.Equal(t, tc.want, tc.obj)
+		})
+	}
+}
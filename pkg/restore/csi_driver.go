@@ -0,0 +1,184 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/heptio/velero/pkg/kuberesource"
+	"github.com/heptio/velero/pkg/util/kube"
+)
+
+var (
+	// volumeSnapshotContents is the group resource of CSI volume snapshot contents.
+	volumeSnapshotContents = schema.GroupResource{Group: "snapshot.storage.k8s.io", Resource: "volumesnapshotcontents"}
+
+	// csiDrivers is the group resource of the objects that list the CSI
+	// drivers installed in a cluster.
+	csiDrivers = schema.GroupResource{Group: "storage.k8s.io", Resource: "csidrivers"}
+)
+
+// csiDriverField returns the path of the field holding the CSI driver name of items
+// of the specified resource, or nil if the resource doesn't reference a CSI driver.
+func csiDriverField(groupResource schema.GroupResource) []string {
+	switch groupResource {
+	case kuberesource.PersistentVolumes:
+		return []string{"spec", "csi", "driver"}
+	case volumeSnapshotContents:
+		return []string{"spec", "driver"}
+	default:
+		return nil
+	}
+}
+
+// remapCSIDriver replaces the CSI driver name of a persistent volume or volume snapshot
+// content according to the restore's CSI driver mappings. A warning is returned if the
+// new driver isn't installed in the cluster.
+func (ctx *context) remapCSIDriver(obj *unstructured.Unstructured, groupResource schema.GroupResource) error {
+	if len(ctx.restore.Spec.CSIDriverMappings) == 0 {
+		return nil
+	}
+
+	field := csiDriverField(groupResource)
+	if field == nil {
+		return nil
+	}
+
+	driver, found, _ := unstructured.NestedString(obj.Object, field...)
+	if !found {
+		return nil
+	}
+	newDriver, ok := ctx.restore.Spec.CSIDriverMappings[driver]
+	if !ok {
+		return nil
+	}
+
+	ctx.log.Infof("Updating CSI driver of %s %s from %s to %s", groupResource, kube.NamespaceAndName(obj), driver, newDriver)
+	if err := unstructured.SetNestedField(obj.Object, newDriver, field...); err != nil {
+		return errors.Wrapf(err, "error updating CSI driver of %s %s", groupResource, kube.NamespaceAndName(obj))
+	}
+
+	installed, err := ctx.installedCSIDrivers()
+	if err != nil {
+		return errors.Wrapf(err, "unable to check that CSI driver %s for %s %s is installed", newDriver, groupResource, kube.NamespaceAndName(obj))
+	}
+	if installed != nil && !installed.Has(newDriver) {
+		return errors.Errorf("CSI driver %s for %s %s is not installed in the cluster", newDriver, groupResource, kube.NamespaceAndName(obj))
+	}
+
+	return nil
+}
+
+// remapCSIVolumeAttributes renames and rewrites the values of the CSI volume attributes
+// of the provided persistent volume according to the restore's CSI volume attribute
+// mappings for its driver. Volume snapshot contents don't carry volume attributes, so
+// only persistent volumes are remapped.
+func (ctx *context) remapCSIVolumeAttributes(obj *unstructured.Unstructured) {
+	if len(ctx.restore.Spec.CSIVolumeAttributeMappings) == 0 {
+		return
+	}
+
+	driver, _, _ := unstructured.NestedString(obj.Object, "spec", "csi", "driver")
+	attributes, found, _ := unstructured.NestedStringMap(obj.Object, "spec", "csi", "volumeAttributes")
+	if driver == "" || !found {
+		return
+	}
+
+	// mappings are applied to the attributes from the backup, so that a
+	// renamed attribute isn't rewritten again by another mapping
+	remapped := make(map[string]string, len(attributes))
+	for key, val := range attributes {
+		remapped[key] = val
+	}
+
+	for _, mapping := range ctx.restore.Spec.CSIVolumeAttributeMappings {
+		if mapping.Driver != driver {
+			continue
+		}
+		val, ok := attributes[mapping.Key]
+		if !ok {
+			continue
+		}
+
+		if newVal, ok := mapping.Values[val]; ok {
+			val = newVal
+		}
+		key := mapping.Key
+		if mapping.NewKey != "" {
+			delete(remapped, key)
+			key = mapping.NewKey
+		}
+
+		ctx.log.Infof("Updating CSI volume attribute %s of persistent volume %s to %s=%s", mapping.Key, obj.GetName(), key, val)
+		remapped[key] = val
+	}
+
+	unstructured.SetNestedStringMap(obj.Object, remapped, "spec", "csi", "volumeAttributes")
+}
+
+// installedCSIDrivers returns the names of the CSI drivers installed in the cluster,
+// according to its CSIDriver objects. It returns nil if the cluster doesn't serve
+// CSIDriver objects, in which case the installed drivers can't be determined.
+func (ctx *context) installedCSIDrivers() (sets.String, error) {
+	if ctx.csiDriversListed {
+		return ctx.csiDrivers, nil
+	}
+
+	if ctx.discoveryHelper == nil {
+		ctx.csiDriversListed = true
+		return nil, nil
+	}
+
+	gvr, apiResource, err := ctx.discoveryHelper.ResourceFor(csiDrivers.WithVersion(""))
+	if err != nil {
+		ctx.log.Debugf("Not checking installed CSI drivers because the cluster doesn't serve %s", csiDrivers)
+		ctx.csiDriversListed = true
+		return nil, nil
+	}
+
+	resourceClient, err := ctx.dynamicFactory.ClientForGroupVersionResource(gvr.GroupVersion(), apiResource, "")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	list, err := resourceClient.List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	installed := sets.NewString()
+	for _, item := range items {
+		driver, err := meta.Accessor(item)
+		if err != nil {
+			continue
+		}
+		installed.Insert(driver.GetName())
+	}
+
+	ctx.csiDrivers = installed
+	ctx.csiDriversListed = true
+	return installed, nil
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/heptio/velero/pkg/util/boolptr"
+	"github.com/heptio/velero/pkg/volume"
+)
+
+const (
+	csiSnapshotAPIGroup = "snapshot.storage.k8s.io"
+	csiSnapshotKind     = "VolumeSnapshot"
+
+	csiSnapshotReason = "provisioned from a CSI volume snapshot by its claim"
+)
+
+// csiSnapshot returns the CSI snapshot of the named persistent volume, or nil if
+// the volume wasn't snapshotted through CSI, or the restore doesn't restore volumes
+// from snapshots.
+func (ctx *context) csiSnapshot(pvName string) *volume.Snapshot {
+	if pvName == "" {
+		return nil
+	}
+
+	for _, snapshot := range ctx.volumeSnapshots {
+		if snapshot.Spec.PersistentVolumeName != pvName || snapshot.Spec.CSI == nil {
+			continue
+		}
+
+		if boolptr.IsSetToFalse(ctx.backup.Spec.SnapshotVolumes) || boolptr.IsSetToFalse(ctx.restore.Spec.RestorePVs) {
+			return nil
+		}
+		return snapshot
+	}
+
+	return nil
+}
+
+// setCSISnapshotDataSource sets the data source of the provided persistent volume
+// claim to the provided CSI snapshot's VolumeSnapshot, so the claim is provisioned
+// a new volume with the snapshot's data. The VolumeSnapshot is restored from the
+// backup like any other item.
+func setCSISnapshotDataSource(obj *unstructured.Unstructured, snapshot *volume.Snapshot) {
+	unstructured.SetNestedMap(obj.Object, map[string]interface{}{
+		"apiGroup": csiSnapshotAPIGroup,
+		"kind":     csiSnapshotKind,
+		"name":     snapshot.Spec.CSI.VolumeSnapshotName,
+	}, "spec", "dataSource")
+}
@@ -0,0 +1,229 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"encoding/base64"
+	"sort"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	api "github.com/heptio/velero/pkg/apis/velero/v1"
+	"github.com/heptio/velero/pkg/kuberesource"
+)
+
+// dataFields are the fields of config maps and secrets that hold keyed data.
+var dataFields = []string{"data", "binaryData", "stringData"}
+
+// findDataKeyMapping returns the restore's data key mapping for the specified key of
+// the specified config map or secret, as stored in the backup, and whether one was found.
+func (ctx *context) findDataKeyMapping(resource, namespace, name, key string) (api.DataKeyMapping, bool) {
+	for _, mapping := range ctx.restore.Spec.DataKeyMappings {
+		if mapping.Resource != resource || mapping.Name != name || mapping.Key != key {
+			continue
+		}
+		if mapping.Namespace != "" && mapping.Namespace != namespace {
+			continue
+		}
+		return mapping, true
+	}
+
+	return api.DataKeyMapping{}, false
+}
+
+// applyDataKeyMappings renames or removes the keys of the provided config map or
+// secret as specified by the restore's data key mappings. Each key is mapped once,
+// according to its name in the backup, and keys renamed to the name of a key that
+// isn't mapped replace it.
+func (ctx *context) applyDataKeyMappings(obj *unstructured.Unstructured, groupResource schema.GroupResource) {
+	if len(ctx.restore.Spec.DataKeyMappings) == 0 {
+		return
+	}
+	if groupResource != kuberesource.ConfigMaps && groupResource != kuberesource.Secrets {
+		return
+	}
+
+	for _, field := range dataFields {
+		data, ok := obj.Object[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		// the keys are sorted so that keys renamed to the same
+		// name are resolved the same way every time
+		keys := make([]string, 0, len(data))
+		for key := range data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		mappings := make(map[string]api.DataKeyMapping)
+		remapped := make(map[string]interface{}, len(data))
+		for _, key := range keys {
+			if mapping, ok := ctx.findDataKeyMapping(groupResource.Resource, obj.GetNamespace(), obj.GetName(), key); ok {
+				mappings[key] = mapping
+				continue
+			}
+			remapped[key] = data[key]
+		}
+		if len(mappings) == 0 {
+			continue
+		}
+
+		for _, key := range keys {
+			if mapping, ok := mappings[key]; ok && mapping.NewKey != "" {
+				remapped[mapping.NewKey] = data[key]
+			}
+		}
+
+		obj.Object[field] = remapped
+	}
+}
+
+// substituteSecretValues replaces the values of the keys in the data of the provided
+// secret that have a substitution in the restore's secret substitutions. Substitutions
+// are matched against the secret's namespace and name as stored in the backup, and
+// their values are base64-encoded as the data's values are.
+func (ctx *context) substituteSecretValues(obj *unstructured.Unstructured, groupResource schema.GroupResource) {
+	if len(ctx.restore.Spec.SecretSubstitutions) == 0 || groupResource != kuberesource.Secrets {
+		return
+	}
+
+	data, ok := obj.Object["data"].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	for _, substitution := range ctx.restore.Spec.SecretSubstitutions {
+		if substitution.Name != obj.GetName() || (substitution.Namespace != "" && substitution.Namespace != obj.GetNamespace()) {
+			continue
+		}
+		if _, ok := data[substitution.Key]; !ok {
+			continue
+		}
+
+		// the value itself isn't logged, since it's a secret
+		ctx.log.Infof("Substituting the value of key %s of secret %s/%s", substitution.Key, obj.GetNamespace(), obj.GetName())
+		data[substitution.Key] = base64.StdEncoding.EncodeToString([]byte(substitution.Value))
+	}
+}
+
+// describeDataKeyMapping returns a description of what the provided mapping does to its key.
+func describeDataKeyMapping(mapping api.DataKeyMapping) string {
+	if mapping.NewKey == "" {
+		return "removed"
+	}
+	return "renamed to " + mapping.NewKey
+}
+
+// checkDataKeyReferences returns an error for each reference in the pod spec embedded in
+// the provided item to a config map or secret key that is renamed or removed by the
+// restore's data key mappings, since the reference will no longer resolve after the restore.
+// Both volume items (items[].key) and, for volumes that project all keys, container
+// volume mounts (subPath) are checked.
+func (ctx *context) checkDataKeyReferences(obj *unstructured.Unstructured, groupResource schema.GroupResource) []error {
+	if len(ctx.restore.Spec.DataKeyMappings) == 0 {
+		return nil
+	}
+
+	podSpec, ok := getPodSpec(obj, groupResource)
+	if !ok {
+		return nil
+	}
+
+	// volumes that project all of their source's keys, where a subPath
+	// refers directly to a key
+	type keySource struct {
+		resource string
+		name     string
+	}
+	projectsAllKeys := make(map[string]keySource)
+
+	var errs []error
+
+	volumes, _, _ := unstructured.NestedSlice(podSpec, "volumes")
+	for _, v := range volumes {
+		volume, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		volumeName, _, _ := unstructured.NestedString(volume, "name")
+
+		var (
+			source   keySource
+			items    []interface{}
+			hasItems bool
+		)
+		if configMap, ok := volume["configMap"].(map[string]interface{}); ok {
+			source.resource = kuberesource.ConfigMaps.Resource
+			source.name, _, _ = unstructured.NestedString(configMap, "name")
+			items, hasItems, _ = unstructured.NestedSlice(configMap, "items")
+		} else if secret, ok := volume["secret"].(map[string]interface{}); ok {
+			source.resource = kuberesource.Secrets.Resource
+			source.name, _, _ = unstructured.NestedString(secret, "secretName")
+			items, hasItems, _ = unstructured.NestedSlice(secret, "items")
+		} else {
+			continue
+		}
+
+		if !hasItems {
+			projectsAllKeys[volumeName] = source
+			continue
+		}
+
+		for _, i := range items {
+			item, ok := i.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			key, _, _ := unstructured.NestedString(item, "key")
+			if mapping, ok := ctx.findDataKeyMapping(source.resource, obj.GetNamespace(), source.name, key); ok {
+				errs = append(errs, errors.Errorf("volume %s of %s %s/%s references key %s of %s %s, which is %s by the restore's data key mappings",
+					volumeName, groupResource, obj.GetNamespace(), obj.GetName(), key, source.resource, source.name, describeDataKeyMapping(mapping)))
+			}
+		}
+	}
+
+	forEachContainer(podSpec, func(container map[string]interface{}) {
+		containerName, _, _ := unstructured.NestedString(container, "name")
+
+		mounts, _, _ := unstructured.NestedSlice(container, "volumeMounts")
+		for _, m := range mounts {
+			mount, ok := m.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			volumeName, _, _ := unstructured.NestedString(mount, "name")
+			subPath, _, _ := unstructured.NestedString(mount, "subPath")
+			source, ok := projectsAllKeys[volumeName]
+			if !ok || subPath == "" {
+				continue
+			}
+
+			if mapping, ok := ctx.findDataKeyMapping(source.resource, obj.GetNamespace(), source.name, subPath); ok {
+				errs = append(errs, errors.Errorf("subPath %s of container %s in %s %s/%s references key %s of %s %s, which is %s by the restore's data key mappings",
+					subPath, containerName, groupResource, obj.GetNamespace(), obj.GetName(), subPath, source.resource, source.name, describeDataKeyMapping(mapping)))
+			}
+		}
+	})
+
+	return errs
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	api "github.com/heptio/velero/pkg/apis/velero/v1"
+)
+
+// decodeError is an error decoding an item file in the backup.
+type decodeError struct {
+	// path is the item file's path within the backup.
+	path string
+	err  error
+}
+
+func (e *decodeError) Error() string {
+	return fmt.Sprintf("error decoding %q: %v", e.path, e.err)
+}
+
+// recordDecodeError records that the item file at the provided path couldn't be decoded,
+// for each of the namespaces the item would have been restored into. Depending on the
+// restore's decode error policy, it's added to the warnings and the item recorded as
+// skipped, or it's added to the errors and the item recorded as failed.
+func (ctx *context) recordDecodeError(warnings, errs *Result, groupResource schema.GroupResource, namespaces []string, fullPath string, err error) {
+	decodeErr := &decodeError{
+		path: strings.TrimPrefix(fullPath, ctx.restoreDir+"/"),
+		err:  err,
+	}
+	name := itemFileName(filepath.Base(fullPath))
+
+	ctx.log.WithError(err).Errorf("Error decoding item file %s", decodeErr.path)
+
+	for _, namespace := range namespaces {
+		if ctx.restore.Spec.DecodeErrorPolicy == api.DecodeErrorPolicyWarn {
+			addToResult(warnings, namespace, decodeErr)
+			ctx.recordSkippedItem(groupResource, namespace, name, err.Error())
+			continue
+		}
+
+		addToResult(errs, namespace, decodeErr)
+		ctx.recordItemWithReason(groupResource, namespace, name, ItemOutcomeFailed, err.Error())
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/heptio/velero/pkg/client"
+	"github.com/heptio/velero/pkg/util/kube"
+)
+
+// driftSleep waits out the drift observation window. It's a variable so
+// tests can replace it.
+var driftSleep = time.Sleep
+
+// createdItem is an item created by the restore, as returned by the API
+// server, along with the client used to create it.
+type createdItem struct {
+	groupResource schema.GroupResource
+	namespace     string
+	client        client.Dynamic
+	obj           *unstructured.Unstructured
+}
+
+// observeDrift waits for the restore's drift observation window, then re-reads each
+// item created by the restore and returns a warning for each one whose generation was
+// bumped or whose spec differs from the version that was created. Items that can't be
+// re-read are also reported as warnings.
+func (ctx *context) observeDrift() Result {
+	warnings := Result{}
+
+	window := time.Duration(ctx.restore.Spec.ObserveDriftSeconds) * time.Second
+	ctx.log.Infof("Observing %d restored items for drift for %v", len(ctx.createdItems), window)
+	driftSleep(window)
+
+	for _, item := range ctx.createdItems {
+		current, err := item.client.Get(item.obj.GetName(), metav1.GetOptions{})
+		if err != nil {
+			addToResult(&warnings, item.namespace, errors.Wrapf(err, "error re-reading %s %s to observe drift", item.groupResource, kube.NamespaceAndName(item.obj)))
+			continue
+		}
+
+		if current.GetGeneration() != item.obj.GetGeneration() {
+			addToResult(&warnings, item.namespace, errors.Errorf("%s %s drifted after restore: generation changed from %d to %d",
+				item.groupResource, kube.NamespaceAndName(item.obj), item.obj.GetGeneration(), current.GetGeneration()))
+			continue
+		}
+
+		if !equality.Semantic.DeepEqual(item.obj.Object["spec"], current.Object["spec"]) {
+			addToResult(&warnings, item.namespace, errors.Errorf("%s %s drifted after restore: spec changed",
+				item.groupResource, kube.NamespaceAndName(item.obj)))
+		}
+	}
+
+	return warnings
+}
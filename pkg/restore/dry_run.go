@@ -0,0 +1,74 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/heptio/velero/pkg/client"
+	"github.com/heptio/velero/pkg/kuberesource"
+	"github.com/heptio/velero/pkg/util/kube"
+)
+
+// Reasons recorded in the item results of a dry run.
+const (
+	dryRunCreateReason    = "dry run: would be created"
+	dryRunExistsReason    = "dry run: already exists in the cluster"
+	dryRunProvisionReason = "dry run: would be provisioned from a snapshot"
+)
+
+// dryRunCreate records what restoring the provided item would do, without creating it:
+// an item that already exists in the cluster would be skipped, a persistent volume with
+// a snapshot would be provisioned from it, and any other item would be created. Existing
+// items are looked up, but nothing in the cluster is changed.
+func (ctx *context) dryRunCreate(obj *unstructured.Unstructured, groupResource schema.GroupResource, namespace string, resourceClient client.Dynamic, warnings, errs Result) (Result, Result) {
+	name := obj.GetName()
+
+	_, err := resourceClient.Get(name, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		ctx.log.Infof("Dry run: %s %s would be skipped because it already exists", groupResource, kube.NamespaceAndName(obj))
+		ctx.recordItemWithAction(groupResource, namespace, name, ItemOutcomeSkipped, ItemActionSkippedExists, dryRunExistsReason)
+	case apierrors.IsNotFound(err):
+		reason := dryRunCreateReason
+		if groupResource == kuberesource.PersistentVolumes && ctx.hasVolumeSnapshot(name) {
+			reason = dryRunProvisionReason
+		}
+
+		ctx.log.Infof("Dry run: %s %s %s", groupResource, kube.NamespaceAndName(obj), reason)
+		ctx.recordItemWithAction(groupResource, namespace, name, ItemOutcomeDryRun, ItemActionDryRun, reason)
+	default:
+		ctx.recordFailedItem(&errs, groupResource, namespace, name, errors.Wrapf(err, "error checking whether %s %s exists", groupResource, kube.NamespaceAndName(obj)))
+	}
+
+	return warnings, errs
+}
+
+// hasVolumeSnapshot returns true if the backup has a volume snapshot of the
+// specified persistent volume.
+func (ctx *context) hasVolumeSnapshot(pvName string) bool {
+	for _, snapshot := range ctx.volumeSnapshots {
+		if snapshot.Spec.PersistentVolumeName == pvName {
+			return true
+		}
+	}
+	return false
+}
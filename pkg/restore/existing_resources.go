@@ -0,0 +1,186 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"encoding/json"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/heptio/velero/pkg/client"
+	"github.com/heptio/velero/pkg/kuberesource"
+	"github.com/heptio/velero/pkg/util/kube"
+)
+
+// immutableFieldPaths maps group resources to the paths of fields that can't
+// be changed once they're set, so they're never patched on in-cluster items.
+var immutableFieldPaths = map[schema.GroupResource][][]string{
+	kuberesource.Services:               {{"spec", "clusterIP"}, {"spec", "clusterIPs"}},
+	kuberesource.PersistentVolumeClaims: {{"spec", "volumeName"}},
+}
+
+// keepImmutableFields sets the fields of desired that can't be changed once set
+// to their values in the in-cluster item, if the in-cluster item has them.
+func keepImmutableFields(desired, fromCluster *unstructured.Unstructured, groupResource schema.GroupResource) {
+	for _, path := range immutableFieldPaths[groupResource] {
+		val, found, err := unstructured.NestedFieldCopy(fromCluster.Object, path...)
+		if err != nil || !found {
+			continue
+		}
+		unstructured.SetNestedField(desired.Object, val, path...)
+	}
+}
+
+// updateExisting patches the in-cluster version of the provided item to match it, with
+// a three-way merge of the backed-up item, the item being restored and the in-cluster
+// item: fields that differ from the in-cluster item are set, fields that were removed
+// from the backed-up item are removed, and fields that were only added in the cluster
+// are left as they are. fromCluster must already have its metadata and status reset,
+// so the patch leaves the in-cluster item's other metadata and its status as they are.
+// It returns false if there's nothing to change other than fields that can't be
+// changed once set, in which case no patch is issued.
+func (ctx *context) updateExisting(obj, fromBackup, fromCluster *unstructured.Unstructured, groupResource schema.GroupResource, resourceClient client.Dynamic) (bool, error) {
+	desired := obj.DeepCopy()
+	keepImmutableFields(desired, fromCluster, groupResource)
+
+	// the backed-up item's metadata and status aren't restored, so
+	// they're not removals from the item being restored either
+	original, err := resetMetadataAndStatus(fromBackup.DeepCopy(), ctx.annotationFilter)
+	if err != nil {
+		return false, errors.Wrapf(err, "error resetting metadata of backed-up %s %s", groupResource, kube.NamespaceAndName(obj))
+	}
+
+	patchBytes, err := generateThreeWayPatch(original, desired, fromCluster)
+	if err != nil {
+		return false, errors.Wrapf(err, "error generating patch for %s %s", groupResource, kube.NamespaceAndName(obj))
+	}
+	if patchBytes == nil {
+		return false, nil
+	}
+
+	if _, err := resourceClient.Patch(obj.GetName(), patchBytes); err != nil {
+		return false, errors.Wrapf(err, "error updating %s %s", groupResource, kube.NamespaceAndName(obj))
+	}
+
+	return true, nil
+}
+
+// generateThreeWayPatch returns a JSON merge patch that changes current to match the
+// fields of modified, and removes the fields that were removed from original to get
+// modified, or nil if there's nothing to change. Fields of current that are in neither
+// original nor modified are left as they are.
+func generateThreeWayPatch(original, modified, current *unstructured.Unstructured) ([]byte, error) {
+	originalBytes, err := json.Marshal(original.Object)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to marshal original object")
+	}
+
+	modifiedBytes, err := json.Marshal(modified.Object)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to marshal modified object")
+	}
+
+	currentBytes, err := json.Marshal(current.Object)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to marshal in-cluster object")
+	}
+
+	// the additions and changes come from the in-cluster object, and
+	// the removals from the original object
+	additions, err := filteredMergePatch(currentBytes, modifiedBytes, false)
+	if err != nil {
+		return nil, err
+	}
+
+	removals, err := filteredMergePatch(originalBytes, modifiedBytes, true)
+	if err != nil {
+		return nil, err
+	}
+
+	patch := removals
+	mergeMergePatchInto(patch, additions)
+	if len(patch) == 0 {
+		return nil, nil
+	}
+
+	return json.Marshal(patch)
+}
+
+// filteredMergePatch returns the JSON merge patch from a to b, keeping only its
+// removals, i.e. its null values, if removals is true, or everything else if not.
+func filteredMergePatch(a, b []byte, removals bool) (map[string]interface{}, error) {
+	patchBytes, err := jsonpatch.CreateMergePatch(a, b)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create merge patch")
+	}
+
+	patch := make(map[string]interface{})
+	if err := json.Unmarshal(patchBytes, &patch); err != nil {
+		return nil, errors.Wrap(err, "unable to unmarshal merge patch")
+	}
+
+	return filterMergePatch(patch, removals), nil
+}
+
+// filterMergePatch returns the removals, i.e. null values, of the provided merge patch
+// if removals is true, or everything else if not.
+func filterMergePatch(patch map[string]interface{}, removals bool) map[string]interface{} {
+	res := make(map[string]interface{})
+
+	for key, val := range patch {
+		switch typed := val.(type) {
+		case nil:
+			if removals {
+				res[key] = nil
+			}
+		case map[string]interface{}:
+			// an empty map clears the field
+			if len(typed) == 0 {
+				if !removals {
+					res[key] = typed
+				}
+				continue
+			}
+			if filtered := filterMergePatch(typed, removals); len(filtered) > 0 {
+				res[key] = filtered
+			}
+		default:
+			if !removals {
+				res[key] = val
+			}
+		}
+	}
+
+	return res
+}
+
+// mergeMergePatchInto merges the provided merge patch into dst, so that applying dst
+// has the effect of applying both, with patch's values applied last.
+func mergeMergePatchInto(dst, patch map[string]interface{}) {
+	for key, val := range patch {
+		patchMap, ok := val.(map[string]interface{})
+		dstMap, dstOK := dst[key].(map[string]interface{})
+		if ok && dstOK && len(patchMap) > 0 {
+			mergeMergePatchInto(dstMap, patchMap)
+			continue
+		}
+		dst[key] = val
+	}
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	velerotest "github.com/heptio/velero/pkg/util/test"
+)
+
+func TestGenerateThreeWayPatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		modified string
+		current  string
+		want     string
+	}{
+		{
+			name:     "changed fields are set and fields added in the cluster are kept",
+			original: `{"kind":"ConfigMap","data":{"a":"1"}}`,
+			modified: `{"kind":"ConfigMap","data":{"a":"2"}}`,
+			current:  `{"kind":"ConfigMap","data":{"a":"1","b":"3"}}`,
+			want:     `{"data":{"a":"2"}}`,
+		},
+		{
+			name:     "fields removed from the original are removed",
+			original: `{"kind":"ConfigMap","data":{"a":"1","b":"2"}}`,
+			modified: `{"kind":"ConfigMap","data":{"a":"1"}}`,
+			current:  `{"kind":"ConfigMap","data":{"a":"1","b":"2","c":"3"}}`,
+			want:     `{"data":{"b":null}}`,
+		},
+		{
+			name:     "fields added in the cluster and changed by the restore are set",
+			original: `{"kind":"ConfigMap"}`,
+			modified: `{"kind":"ConfigMap","data":{"a":"2"}}`,
+			current:  `{"kind":"ConfigMap","data":{"a":"1"}}`,
+			want:     `{"data":{"a":"2"}}`,
+		},
+		{
+			name:     "there's no patch if only fields added in the cluster differ",
+			original: `{"kind":"ConfigMap","data":{"a":"1"}}`,
+			modified: `{"kind":"ConfigMap","data":{"a":"1"}}`,
+			current:  `{"kind":"ConfigMap","data":{"a":"1","b":"2"}}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			patch, err := generateThreeWayPatch(
+				velerotest.UnstructuredOrDie(tc.original),
+				velerotest.UnstructuredOrDie(tc.modified),
+				velerotest.UnstructuredOrDie(tc.current),
+			)
+			require.NoError(t, err)
+
+			if tc.want == "" {
+				assert.Nil(t, patch)
+				return
+			}
+			assert.JSONEq(t, tc.want, string(patch))
+		})
+	}
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// checkExpectedCounts compares the number of items of each resource in the restore's
+// expected counts against the number the restore created, or would have created if
+// it's a dry run, and returns a message for each resource whose counts differ.
+func (ctx *context) checkExpectedCounts() []string {
+	created := make(map[string]int)
+	for _, res := range ctx.itemResults {
+		if res.Outcome == ItemOutcomeCreated || res.Outcome == ItemOutcomeDryRun {
+			created[res.GroupResource]++
+		}
+	}
+
+	resources := make([]string, 0, len(ctx.restore.Spec.ExpectedCounts))
+	for resource := range ctx.restore.Spec.ExpectedCounts {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+
+	var mismatches []string
+	for _, resource := range resources {
+		expected := ctx.restore.Spec.ExpectedCounts[resource]
+		actual := created[schema.ParseGroupResource(resource).String()]
+		if actual != expected {
+			mismatches = append(mismatches, fmt.Sprintf("expected restore to create %d %s, but it created %d", expected, resource, actual))
+		}
+	}
+
+	return mismatches
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ValidateFieldExclusions checks the provided restore field exclusions for field
+// paths that can't be parsed. An error is returned for each invalid field path.
+func ValidateFieldExclusions(exclusions map[string][]string) []error {
+	// sort the resources so the errors are in a consistent order
+	var resources []string
+	for resource := range exclusions {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+
+	var errs []error
+	for _, resource := range resources {
+		for _, fieldPath := range exclusions[resource] {
+			if _, err := parseFieldPath(fieldPath); err != nil {
+				errs = append(errs, errors.Wrapf(err, "invalid field exclusion for resource %s", resource))
+			}
+		}
+	}
+	return errs
+}
+
+// excludeFields removes the fields that the restore's field exclusions exclude from
+// the items of the provided resource from the provided item. Fields the item doesn't
+// have are ignored.
+func (ctx *context) excludeFields(obj *unstructured.Unstructured, groupResource schema.GroupResource) {
+	for resource, fieldPaths := range ctx.restore.Spec.FieldExclusions {
+		if schema.ParseGroupResource(resource) != groupResource {
+			continue
+		}
+
+		for _, fieldPath := range fieldPaths {
+			fields, err := parseFieldPath(fieldPath)
+			if err != nil {
+				ctx.log.WithError(err).Warnf("Not excluding invalid field path from %s", groupResource)
+				continue
+			}
+			unstructured.RemoveNestedField(obj.Object, fields...)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// gatewayAPIGroup is the API group of the Gateway API resources.
+const gatewayAPIGroup = "gateway.networking.k8s.io"
+
+// gatewayResourceOrder is the order that Gateway API resources must be restored
+// in so that each is restored after the resources it references.
+var gatewayResourceOrder = []schema.GroupResource{
+	{Group: gatewayAPIGroup, Resource: "gatewayclasses"},
+	{Group: gatewayAPIGroup, Resource: "gateways"},
+	{Group: gatewayAPIGroup, Resource: "httproutes"},
+}
+
+// orderGatewayResources reorders the Gateway API resources within the provided
+// prioritized resources so that they follow gatewayResourceOrder. Only the positions
+// already occupied by Gateway API resources are reused, so all other resources keep
+// their priority.
+func orderGatewayResources(resources []schema.GroupResource) {
+	var positions []int
+	present := make(map[schema.GroupResource]bool)
+	for i, gr := range resources {
+		for _, ordered := range gatewayResourceOrder {
+			if gr == ordered {
+				positions = append(positions, i)
+				present[gr] = true
+			}
+		}
+	}
+
+	i := 0
+	for _, gr := range gatewayResourceOrder {
+		if present[gr] {
+			resources[positions[i]] = gr
+			i++
+		}
+	}
+}
+
+// remapGatewayReferences replaces the gateway class name of a Gateway API gateway, and
+// the backend reference names of a Gateway API route, according to the restore's gateway
+// class and backend reference mappings.
+func (ctx *context) remapGatewayReferences(obj *unstructured.Unstructured, groupResource schema.GroupResource) {
+	if groupResource.Group != gatewayAPIGroup {
+		return
+	}
+
+	if groupResource.Resource == "gateways" {
+		className, _, _ := unstructured.NestedString(obj.Object, "spec", "gatewayClassName")
+		if newClassName, ok := ctx.restore.Spec.GatewayClassMappings[className]; ok {
+			ctx.log.Infof("Updating gateway class name of gateway %s/%s from %s to %s", obj.GetNamespace(), obj.GetName(), className, newClassName)
+			unstructured.SetNestedField(obj.Object, newClassName, "spec", "gatewayClassName")
+		}
+		return
+	}
+
+	if len(ctx.restore.Spec.GatewayBackendRefMappings) == 0 {
+		return
+	}
+
+	rules, found, _ := unstructured.NestedSlice(obj.Object, "spec", "rules")
+	if !found {
+		return
+	}
+
+	for _, r := range rules {
+		rule, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		backendRefs, _, _ := unstructured.NestedSlice(rule, "backendRefs")
+		for _, b := range backendRefs {
+			backendRef, ok := b.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			name, _, _ := unstructured.NestedString(backendRef, "name")
+			if newName, ok := ctx.restore.Spec.GatewayBackendRefMappings[name]; ok {
+				ctx.log.Infof("Updating backend reference of %s %s/%s from %s to %s", groupResource, obj.GetNamespace(), obj.GetName(), name, newName)
+				backendRef["name"] = newName
+			}
+		}
+		rule["backendRefs"] = backendRefs
+	}
+	unstructured.SetNestedSlice(obj.Object, rules, "spec", "rules")
+}
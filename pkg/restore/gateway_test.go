@@ -0,0 +1,152 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	velerov1api "github.com/heptio/velero/pkg/apis/velero/v1"
+	velerotest "github.com/heptio/velero/pkg/util/test"
+)
+
+func TestOrderGatewayResources(t *testing.T) {
+	var (
+		pods           = schema.GroupResource{Resource: "pods"}
+		services       = schema.GroupResource{Resource: "services"}
+		gatewayClasses = schema.GroupResource{Group: gatewayAPIGroup, Resource: "gatewayclasses"}
+		gateways       = schema.GroupResource{Group: gatewayAPIGroup, Resource: "gateways"}
+		httpRoutes     = schema.GroupResource{Group: gatewayAPIGroup, Resource: "httproutes"}
+	)
+
+	tests := []struct {
+		name      string
+		resources []schema.GroupResource
+		want      []schema.GroupResource
+	}{
+		{
+			name:      "gateways are moved ahead of httproutes",
+			resources: []schema.GroupResource{httpRoutes, pods, gateways, services},
+			want:      []schema.GroupResource{gateways, pods, httpRoutes, services},
+		},
+		{
+			name:      "all gateway API resources are put in dependency order",
+			resources: []schema.GroupResource{httpRoutes, gateways, gatewayClasses},
+			want:      []schema.GroupResource{gatewayClasses, gateways, httpRoutes},
+		},
+		{
+			name:      "resources already in order are unchanged",
+			resources: []schema.GroupResource{pods, gateways, httpRoutes},
+			want:      []schema.GroupResource{pods, gateways, httpRoutes},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			orderGatewayResources(tc.resources)
+			assert.Equal(t, tc.want, tc.resources)
+		})
+	}
+}
+
+func TestRemapGatewayReferences(t *testing.T) {
+	tests := []struct {
+		name          string
+		restore       *velerov1api.Restore
+		groupResource schema.GroupResource
+		obj           *unstructured.Unstructured
+		want          *unstructured.Unstructured
+	}{
+		{
+			name:          "a gateway's class name is remapped",
+			restore:       NewBuilder().GatewayClassMappings("prod-class", "dr-class").Restore(),
+			groupResource: schema.GroupResource{Group: gatewayAPIGroup, Resource: "gateways"},
+			obj: &unstructured.Unstructured{Object: map[string]interface{}{
+				"spec": map[string]interface{}{"gatewayClassName": "prod-class"},
+			}},
+			want: &unstructured.Unstructured{Object: map[string]interface{}{
+				"spec": map[string]interface{}{"gatewayClassName": "dr-class"},
+			}},
+		},
+		{
+			name:          "a gateway's unmapped class name is unchanged",
+			restore:       NewBuilder().GatewayClassMappings("other-class", "dr-class").Restore(),
+			groupResource: schema.GroupResource{Group: gatewayAPIGroup, Resource: "gateways"},
+			obj: &unstructured.Unstructured{Object: map[string]interface{}{
+				"spec": map[string]interface{}{"gatewayClassName": "prod-class"},
+			}},
+			want: &unstructured.Unstructured{Object: map[string]interface{}{
+				"spec": map[string]interface{}{"gatewayClassName": "prod-class"},
+			}},
+		},
+		{
+			name:          "an httproute's backend ref is remapped",
+			restore:       NewBuilder().GatewayBackendRefMappings("svc-prod", "svc-dr").Restore(),
+			groupResource: schema.GroupResource{Group: gatewayAPIGroup, Resource: "httproutes"},
+			obj: &unstructured.Unstructured{Object: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"rules": []interface{}{
+						map[string]interface{}{
+							"backendRefs": []interface{}{
+								map[string]interface{}{"name": "svc-prod", "port": int64(80)},
+								map[string]interface{}{"name": "svc-other", "port": int64(80)},
+							},
+						},
+					},
+				},
+			}},
+			want: &unstructured.Unstructured{Object: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"rules": []interface{}{
+						map[string]interface{}{
+							"backendRefs": []interface{}{
+								map[string]interface{}{"name": "svc-dr", "port": int64(80)},
+								map[string]interface{}{"name": "svc-other", "port": int64(80)},
+							},
+						},
+					},
+				},
+			}},
+		},
+		{
+			name:          "resources outside the gateway API group are unchanged",
+			restore:       NewBuilder().GatewayClassMappings("prod-class", "dr-class").Restore(),
+			groupResource: schema.GroupResource{Group: "example.com", Resource: "gateways"},
+			obj: &unstructured.Unstructured{Object: map[string]interface{}{
+				"spec": map[string]interface{}{"gatewayClassName": "prod-class"},
+			}},
+			want: &unstructured.Unstructured{Object: map[string]interface{}{
+				"spec": map[string]interface{}{"gatewayClassName": "prod-class"},
+			}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := &context{
+				restore: tc.restore,
+				log:     velerotest.NewLogger(),
+			}
+
+			ctx.remapGatewayReferences(tc.obj, tc.groupResource)
+			assert.Equal(t, tc.want, tc.obj)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	api "github.com/heptio/velero/pkg/apis/velero/v1"
+	"github.com/heptio/velero/pkg/plugin/velero"
+)
+
+// isGenerateNameOnly returns true if the provided item has a generate name but no name.
+func isGenerateNameOnly(obj *unstructured.Unstructured) bool {
+	return obj.GetName() == "" && obj.GetGenerateName() != ""
+}
+
+// checkGenerateNamePolicy returns an error if the provided item, which has a generate
+// name but no name, shouldn't be restored according to the restore's generate name policy.
+func (ctx *context) checkGenerateNamePolicy(obj *unstructured.Unstructured, groupResource schema.GroupResource) error {
+	if ctx.restore.Spec.GenerateNamePolicy == api.GenerateNamePolicyGenerate {
+		return nil
+	}
+
+	return errors.Errorf("%s with generate name %q in namespace %q has no name. Restore with the %s generate name policy to create it with a generated name",
+		groupResource, obj.GetGenerateName(), obj.GetNamespace(), api.GenerateNamePolicyGenerate)
+}
+
+// recordGeneratedName records the provided item, which was created from an item with
+// only a generate name, as restored under the name the cluster assigned it, and returns
+// the assigned name.
+func (ctx *context) recordGeneratedName(createdObj *unstructured.Unstructured, groupResource schema.GroupResource, namespace string) string {
+	name := createdObj.GetName()
+	ctx.log.Infof("Restored %s with generate name %q as %s", groupResource, createdObj.GetGenerateName(), name)
+
+	ctx.restoredItems[velero.ResourceIdentifier{
+		GroupResource: groupResource,
+		Namespace:     namespace,
+		Name:          name,
+	}] = struct{}{}
+
+	return name
+}
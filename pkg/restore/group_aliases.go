@@ -0,0 +1,84 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"os"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	api "github.com/heptio/velero/pkg/apis/velero/v1"
+	"github.com/heptio/velero/pkg/util/kube"
+)
+
+// matchesKind returns true if the provided group alias applies to items of the
+// specified kind.
+func matchesKind(alias api.GroupAlias, kind string) bool {
+	if len(alias.Kinds) == 0 {
+		return true
+	}
+
+	for _, k := range alias.Kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// backupResourceDir returns the backup directory of the specified resource, or nil
+// if the backup has none. Resources in the target group of one of the restore's
+// group aliases are restored from the directory of the resource in the alias's
+// source group if the backup doesn't have a directory for them, as are resources
+// that one of the restore's group version kind mappings rewrites items as.
+func (ctx *context) backupResourceDir(resource schema.GroupResource, resourceDirs map[string]os.FileInfo) os.FileInfo {
+	if dir := resourceDirs[resource.String()]; dir != nil {
+		return dir
+	}
+
+	for _, alias := range ctx.restore.Spec.GroupAliases {
+		if alias.ToGroup != resource.Group {
+			continue
+		}
+
+		aliased := schema.GroupResource{Group: alias.FromGroup, Resource: resource.Resource}
+		if dir := resourceDirs[aliased.String()]; dir != nil {
+			return dir
+		}
+	}
+
+	return ctx.mappedResourceDir(resource, resourceDirs)
+}
+
+// applyGroupAlias rewrites the API group of the provided item, keeping its version,
+// according to the first of the restore's group aliases that matches the item's
+// group and kind.
+func (ctx *context) applyGroupAlias(obj *unstructured.Unstructured) {
+	gvk := obj.GroupVersionKind()
+
+	for _, alias := range ctx.restore.Spec.GroupAliases {
+		if alias.FromGroup != gvk.Group || !matchesKind(alias, gvk.Kind) {
+			continue
+		}
+
+		ctx.log.Infof("Rewriting API group of %s %s from %s to %s", gvk.Kind, kube.NamespaceAndName(obj), alias.FromGroup, alias.ToGroup)
+		gvk.Group = alias.ToGroup
+		obj.SetGroupVersionKind(gvk)
+		return
+	}
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/heptio/velero/pkg/util/kube"
+)
+
+// guessResource returns the resource of the provided group version kind, guessed
+// from its kind, since the backup doesn't record the resources of kinds.
+func guessResource(gvk schema.GroupVersionKind) schema.GroupVersionResource {
+	plural, _ := meta.UnsafeGuessKindToResource(gvk)
+	return plural
+}
+
+// servesResource returns true if discovery reports that the cluster serves the
+// specified group resource.
+func (ctx *context) servesResource(groupResource schema.GroupResource) bool {
+	if ctx.discoveryHelper == nil {
+		return false
+	}
+
+	_, _, err := ctx.discoveryHelper.ResourceFor(groupResource.WithVersion(""))
+	return err == nil
+}
+
+// mappedResourceDir returns the backup directory of the resource that one of the
+// restore's group version kind mappings rewrites as the specified resource, or nil
+// if there's none. Resources the cluster still serves are restored from their own
+// directory, so their items aren't restored twice.
+func (ctx *context) mappedResourceDir(resource schema.GroupResource, resourceDirs map[string]os.FileInfo) os.FileInfo {
+	for _, mapping := range ctx.restore.Spec.GroupVersionKindMappings {
+		if guessResource(schema.GroupVersionKind(mapping.To)).GroupResource() != resource {
+			continue
+		}
+
+		from := guessResource(schema.GroupVersionKind(mapping.From)).GroupResource()
+		if ctx.servesResource(from) {
+			continue
+		}
+		if dir := resourceDirs[from.String()]; dir != nil {
+			return dir
+		}
+	}
+
+	return nil
+}
+
+// remapGroupVersionKind rewrites the API group, version and kind of the provided item
+// according to the first of the restore's group version kind mappings that matches it,
+// and returns the group resource the item is restored as. An error is returned if the
+// cluster doesn't serve the item's new API, in which case the item is left unchanged.
+func (ctx *context) remapGroupVersionKind(obj *unstructured.Unstructured, groupResource schema.GroupResource) (schema.GroupResource, error) {
+	gvk := obj.GroupVersionKind()
+
+	for _, mapping := range ctx.restore.Spec.GroupVersionKindMappings {
+		if schema.GroupVersionKind(mapping.From) != gvk {
+			continue
+		}
+
+		to := schema.GroupVersionKind(mapping.To)
+		target := guessResource(to)
+		if ctx.discoveryHelper == nil {
+			return groupResource, errors.Errorf("not restoring %s %s as %s because the cluster's APIs can't be discovered", gvk.Kind, kube.NamespaceAndName(obj), to)
+		}
+		gvr, _, err := ctx.discoveryHelper.ResourceFor(target)
+		if err != nil {
+			return groupResource, errors.Errorf("not restoring %s %s as %s because the cluster doesn't serve %s", gvk.Kind, kube.NamespaceAndName(obj), to, target)
+		}
+
+		ctx.log.Infof("Rewriting %s %s from %s to %s", gvk.Kind, kube.NamespaceAndName(obj), gvk, to)
+		obj.SetGroupVersionKind(to)
+		return gvr.GroupResource(), nil
+	}
+
+	return groupResource, nil
+}
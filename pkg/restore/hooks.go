@@ -0,0 +1,271 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	corev1api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+
+	api "github.com/heptio/velero/pkg/apis/velero/v1"
+	"github.com/heptio/velero/pkg/client"
+	"github.com/heptio/velero/pkg/util/collections"
+	"github.com/heptio/velero/pkg/util/kube"
+)
+
+const (
+	podRestoreHookContainerAnnotationKey   = "post.hook.restore.velero.io/container"
+	podRestoreHookCommandAnnotationKey     = "post.hook.restore.velero.io/command"
+	podRestoreHookOnErrorAnnotationKey     = "post.hook.restore.velero.io/on-error"
+	podRestoreHookExecTimeoutAnnotationKey = "post.hook.restore.velero.io/exec-timeout"
+	podRestoreHookWaitTimeoutAnnotationKey = "post.hook.restore.velero.io/wait-timeout"
+
+	// annotationHookName is the name given to hooks specified
+	// by a pod's annotations.
+	annotationHookName = "<from-annotation>"
+)
+
+// restoreHook is a resolved RestoreResourceHookSpec.
+type restoreHook struct {
+	name          string
+	namespaces    *collections.IncludesExcludes
+	labelSelector labels.Selector
+	post          []api.RestoreResourceHook
+}
+
+// applicableTo returns true if the hook applies to a pod in the
+// provided namespace with the provided labels.
+func (h restoreHook) applicableTo(namespace string, labels labels.Set) bool {
+	if h.namespaces != nil && !h.namespaces.ShouldInclude(namespace) {
+		return false
+	}
+	if h.labelSelector != nil && !h.labelSelector.Matches(labels) {
+		return false
+	}
+	return true
+}
+
+// getRestoreHooks resolves the provided hook specs.
+func getRestoreHooks(hookSpecs []api.RestoreResourceHookSpec) ([]restoreHook, error) {
+	hooks := make([]restoreHook, 0, len(hookSpecs))
+
+	for _, s := range hookSpecs {
+		h := restoreHook{
+			name:       s.Name,
+			namespaces: collections.NewIncludesExcludes().Includes(s.IncludedNamespaces...).Excludes(s.ExcludedNamespaces...),
+			post:       s.PostHooks,
+		}
+
+		if s.LabelSelector != nil {
+			selector, err := metav1.LabelSelectorAsSelector(s.LabelSelector)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error parsing label selector of restore hook %s", s.Name)
+			}
+			h.labelSelector = selector
+		}
+
+		hooks = append(hooks, h)
+	}
+
+	return hooks, nil
+}
+
+// getPodRestoreHookFromAnnotations returns a RestoreExecHook based on the annotations, as long
+// as the 'command' annotation is present. If it is absent, this returns nil.
+func getPodRestoreHookFromAnnotations(annotations map[string]string, log logrus.FieldLogger) *api.RestoreExecHook {
+	commandValue := annotations[podRestoreHookCommandAnnotationKey]
+	if commandValue == "" {
+		return nil
+	}
+
+	var command []string
+	// check for json array
+	if commandValue[0] == '[' {
+		if err := json.Unmarshal([]byte(commandValue), &command); err != nil {
+			command = []string{commandValue}
+		}
+	} else {
+		command = append(command, commandValue)
+	}
+
+	onError := api.HookErrorMode(annotations[podRestoreHookOnErrorAnnotationKey])
+	if onError != api.HookErrorModeContinue && onError != api.HookErrorModeFail {
+		onError = ""
+	}
+
+	parseTimeout := func(key string) time.Duration {
+		value := annotations[key]
+		if value == "" {
+			return 0
+		}
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			log.WithError(errors.WithStack(err)).Warnf("Unable to parse restore hook annotation %s, ignoring it", key)
+			return 0
+		}
+		return timeout
+	}
+
+	return &api.RestoreExecHook{
+		Container:   annotations[podRestoreHookContainerAnnotationKey],
+		Command:     command,
+		OnError:     onError,
+		ExecTimeout: metav1.Duration{Duration: parseTimeout(podRestoreHookExecTimeoutAnnotationKey)},
+		WaitTimeout: metav1.Duration{Duration: parseTimeout(podRestoreHookWaitTimeoutAnnotationKey)},
+	}
+}
+
+// namedExecHook is an exec hook along with the name of the hook it's from.
+type namedExecHook struct {
+	name string
+	hook *api.RestoreExecHook
+}
+
+// getPodExecHooks returns the exec hooks to run in the provided restored pod. If the pod
+// specifies a hook via annotations, that takes priority over the restore's hook specs.
+func (ctx *context) getPodExecHooks(pod *unstructured.Unstructured) []namedExecHook {
+	if hook := getPodRestoreHookFromAnnotations(pod.GetAnnotations(), ctx.log); hook != nil {
+		return []namedExecHook{{name: annotationHookName, hook: hook}}
+	}
+
+	var hooks []namedExecHook
+	for _, h := range ctx.restoreHooks {
+		if !h.applicableTo(pod.GetNamespace(), labels.Set(pod.GetLabels())) {
+			continue
+		}
+		for _, hook := range h.post {
+			if hook.Exec != nil {
+				hooks = append(hooks, namedExecHook{name: h.name, hook: hook.Exec})
+			}
+		}
+	}
+
+	return hooks
+}
+
+// startPodHooks runs the provided restored pod's exec hooks, if it has any, in the
+// background once the pod is running. Their results are collected by waitForPodHooks.
+func (ctx *context) startPodHooks(pod *unstructured.Unstructured, resourceClient client.Dynamic) {
+	hooks := ctx.getPodExecHooks(pod)
+	if len(hooks) == 0 {
+		return
+	}
+
+	if ctx.podCommandExecutor == nil {
+		ctx.log.Warn("No pod command executor, not running pod's restore hooks")
+		return
+	}
+
+	ctx.hookWaitGroup.Add(1)
+	go func() {
+		defer ctx.hookWaitGroup.Done()
+
+		warnings, errs := ctx.runPodHooks(pod, resourceClient, hooks)
+
+		ctx.hookResultsLock.Lock()
+		defer ctx.hookResultsLock.Unlock()
+		merge(&ctx.hookWarnings, &warnings)
+		merge(&ctx.hookErrs, &errs)
+	}()
+}
+
+// waitForPodHooks waits for all started pod hooks to finish and returns their warnings
+// and errors.
+func (ctx *context) waitForPodHooks() (Result, Result) {
+	ctx.hookWaitGroup.Wait()
+
+	ctx.hookResultsLock.Lock()
+	defer ctx.hookResultsLock.Unlock()
+	return ctx.hookWarnings, ctx.hookErrs
+}
+
+// runPodHooks runs the provided exec hooks, in order, in the provided restored pod, waiting
+// for it to be running before each. A failed hook is recorded as an error if its error mode
+// is Fail, in which case the pod's remaining hooks aren't run, and as a warning otherwise.
+func (ctx *context) runPodHooks(pod *unstructured.Unstructured, resourceClient client.Dynamic, hooks []namedExecHook) (Result, Result) {
+	warnings, errs := Result{}, Result{}
+	namespace, name := pod.GetNamespace(), pod.GetName()
+
+	for _, h := range hooks {
+		hookLog := ctx.log.WithFields(logrus.Fields{
+			"pod":       kube.NamespaceAndName(pod),
+			"hookName":  h.name,
+			"hookType":  "exec",
+			"hookPhase": "post",
+		})
+
+		waitTimeout := h.hook.WaitTimeout.Duration
+		if waitTimeout == 0 {
+			waitTimeout = ctx.readinessTimeout()
+		}
+
+		running, err := waitForPodRunning(resourceClient, name, waitTimeout)
+		if err == nil {
+			err = ctx.podCommandExecutor.ExecutePodCommand(hookLog, running.UnstructuredContent(), namespace, name, h.name, &api.ExecHook{
+				Container: h.hook.Container,
+				Command:   h.hook.Command,
+				OnError:   h.hook.OnError,
+				Timeout:   h.hook.ExecTimeout,
+			})
+		}
+		if err == nil {
+			continue
+		}
+
+		hookLog.WithError(err).Error("Error executing restore hook")
+		err = errors.Wrapf(err, "error executing restore hook %s in pod %s", h.name, kube.NamespaceAndName(pod))
+		if h.hook.OnError == api.HookErrorModeFail {
+			addToResult(&errs, namespace, err)
+			break
+		}
+		addToResult(&warnings, namespace, err)
+	}
+
+	return warnings, errs
+}
+
+// waitForPodRunning waits up to the provided timeout for the named pod to be running,
+// and returns it. An error is returned if the pod finishes first.
+func waitForPodRunning(podClient client.Dynamic, name string, timeout time.Duration) (*unstructured.Unstructured, error) {
+	var pod *unstructured.Unstructured
+
+	err := poll(timeout, "pod to be running", func() (bool, error) {
+		obj, err := podClient.Get(name, metav1.GetOptions{})
+		if err != nil {
+			return false, errors.WithStack(err)
+		}
+
+		phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+		switch corev1api.PodPhase(phase) {
+		case corev1api.PodRunning:
+			pod = obj
+			return true, nil
+		case corev1api.PodSucceeded, corev1api.PodFailed:
+			return false, errors.Errorf("pod finished with phase %s before it was running", phase)
+		default:
+			return false, nil
+		}
+	})
+
+	return pod, err
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// hpaGroupResource is the resource that horizontal pod autoscalers are
+// stored under in a backup tarball.
+var hpaGroupResource = schema.GroupResource{Group: "autoscaling", Resource: "horizontalpodautoscalers"}
+
+// legacyWorkloadKinds are the kinds of workloads that were served by the extensions
+// group before they moved to the apps group, so scale targets in either group refer
+// to the same items.
+var legacyWorkloadKinds = sets.NewString("Deployment", "DaemonSet", "ReplicaSet")
+
+// hpaScaleTarget returns the key of a horizontal pod autoscaler's scale target with
+// the specified API group, kind and name, as "<group>/<kind>/<name>".
+func hpaScaleTarget(group, kind, name string) string {
+	if group == "extensions" && legacyWorkloadKinds.Has(kind) {
+		group = "apps"
+	}
+	return group + "/" + kind + "/" + name
+}
+
+// hpaScaleTargets returns the set of scale targets, as keyed by hpaScaleTarget, of the
+// horizontal pod autoscalers in the backup for the provided namespace. Results are
+// cached per namespace since they're read from the restore's item source.
+func (ctx *context) hpaScaleTargets(namespace string) sets.String {
+	if targets, ok := ctx.hpaTargets[namespace]; ok {
+		return targets
+	}
+
+	targets := sets.NewString()
+	if ctx.hpaTargets == nil {
+		ctx.hpaTargets = make(map[string]sets.String)
+	}
+	ctx.hpaTargets[namespace] = targets
+
+	names, err := ctx.getItemSource().ListItems(hpaGroupResource, namespace)
+	if err != nil {
+		ctx.log.WithError(err).Warnf("Error reading horizontal pod autoscalers for namespace %s", namespace)
+		return targets
+	}
+
+	for _, name := range names {
+		hpa, err := ctx.readItem(hpaGroupResource, namespace, name)
+		if err != nil {
+			ctx.log.WithError(err).Warnf("Error decoding horizontal pod autoscaler %s", name)
+			continue
+		}
+
+		apiVersion, _, _ := unstructured.NestedString(hpa.Object, "spec", "scaleTargetRef", "apiVersion")
+		kind, _, _ := unstructured.NestedString(hpa.Object, "spec", "scaleTargetRef", "kind")
+		name, _, _ := unstructured.NestedString(hpa.Object, "spec", "scaleTargetRef", "name")
+		gv, err := schema.ParseGroupVersion(apiVersion)
+		if err != nil {
+			ctx.log.WithError(err).Warnf("Error parsing scale target API version of horizontal pod autoscaler %s", hpa.GetName())
+			continue
+		}
+		if kind != "" && name != "" {
+			targets.Insert(hpaScaleTarget(gv.Group, kind, name))
+		}
+	}
+
+	return targets
+}
+
+// stripHPAManagedReplicas removes spec.replicas from the provided item if the
+// restore is configured to do so and the item is the scale target of a horizontal
+// pod autoscaler in the backup, leaving the autoscaler to set the replica count.
+// Scale targets are matched on their API group as well as their kind and name, so
+// items of same-named kinds in other groups are left as they are.
+func (ctx *context) stripHPAManagedReplicas(obj *unstructured.Unstructured) {
+	if !ctx.restore.Spec.StripHPAManagedReplicas || obj.GetNamespace() == "" {
+		return
+	}
+
+	if !ctx.hpaScaleTargets(obj.GetNamespace()).Has(hpaScaleTarget(obj.GroupVersionKind().Group, obj.GetKind(), obj.GetName())) {
+		return
+	}
+
+	ctx.log.Infof("Removing replicas from %s %s/%s because it's managed by a horizontal pod autoscaler", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+	unstructured.RemoveNestedField(obj.Object, "spec", "replicas")
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// includesItemName returns true if the restore's included resource names allow the
+// item of the provided resource with the provided namespace and name in the backup
+// to be restored into the provided target namespace. Resources without a list of
+// included names have all of their items included.
+func (ctx *context) includesItemName(groupResource schema.GroupResource, sourceNamespace, name, targetNamespace string) bool {
+	for resource, names := range ctx.restore.Spec.IncludedResourceNames {
+		if len(names) == 0 || schema.ParseGroupResource(resource) != groupResource {
+			continue
+		}
+
+		for _, included := range names {
+			if includedNameMatches(included, sourceNamespace, name, targetNamespace) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// includedNameMatches returns true if the provided included name, which is either a
+// name or a "namespace/name", matches an item with the provided name. A namespace
+// matches either the item's namespace in the backup or the namespace it's restored into.
+func includedNameMatches(included, sourceNamespace, name, targetNamespace string) bool {
+	parts := strings.SplitN(included, "/", 2)
+	if len(parts) == 1 {
+		return included == name
+	}
+
+	return parts[1] == name && (parts[0] == sourceNamespace || parts[0] == targetNamespace)
+}
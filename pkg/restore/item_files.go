@@ -0,0 +1,81 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Extensions of the item files in a backup. Items may be gzip-compressed
+// individually, e.g. by external tooling.
+const (
+	itemFileExt     = ".json"
+	gzipItemFileExt = ".json.gz"
+)
+
+// gzipMagic is the header that starts gzip-compressed data.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// isItemFile returns true if the provided file name is that of an item file,
+// compressed or not.
+func isItemFile(name string) bool {
+	return strings.HasSuffix(name, itemFileExt) || strings.HasSuffix(name, gzipItemFileExt)
+}
+
+// itemFileName returns the name of the item in the provided item file.
+func itemFileName(name string) string {
+	if strings.HasSuffix(name, gzipItemFileExt) {
+		return strings.TrimSuffix(name, gzipItemFileExt)
+	}
+	return strings.TrimSuffix(name, itemFileExt)
+}
+
+// readItemFile reads the item file at the provided path using readFile, decompressing it
+// if it's gzip-compressed. If there's no uncompressed item file at the path, the
+// gzip-compressed file alongside it is read instead.
+func readItemFile(readFile func(string) ([]byte, error), path string) ([]byte, error) {
+	data, err := readFile(path)
+	if os.IsNotExist(errors.Cause(err)) && strings.HasSuffix(path, itemFileExt) {
+		data, err = readFile(path + ".gz")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.HasPrefix(data, gzipMagic) {
+		return data, nil
+	}
+
+	gzr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error decompressing item file %s", path)
+	}
+	defer gzr.Close()
+
+	data, err = ioutil.ReadAll(gzr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error decompressing item file %s", path)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadItemFile(t *testing.T) {
+	compressed := new(bytes.Buffer)
+	gzw := gzip.NewWriter(compressed)
+	_, err := gzw.Write([]byte(`{"kind":"Pod"}`))
+	require.NoError(t, err)
+	require.NoError(t, gzw.Close())
+
+	files := map[string][]byte{
+		"plain/pod-1.json":   []byte(`{"kind":"Pod"}`),
+		"gzip/pod-1.json.gz": compressed.Bytes(),
+	}
+	readFile := func(path string) ([]byte, error) {
+		data, ok := files[path]
+		if !ok {
+			return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+		}
+		return data, nil
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{
+			name: "an uncompressed item file is read as is",
+			path: "plain/pod-1.json",
+		},
+		{
+			name: "a gzip-compressed item file is decompressed",
+			path: "gzip/pod-1.json.gz",
+		},
+		{
+			name: "a missing uncompressed item file falls back to the gzip-compressed one",
+			path: "gzip/pod-1.json",
+		},
+		{
+			name:    "a missing item file is an error",
+			path:    "missing/pod-1.json",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := readItemFile(readFile, tc.path)
+			if tc.wantErr {
+				assert.True(t, os.IsNotExist(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, `{"kind":"Pod"}`, string(data))
+		})
+	}
+}
+
+func TestItemFileName(t *testing.T) {
+	assert.True(t, isItemFile("pod-1.json"))
+	assert.True(t, isItemFile("pod-1.json.gz"))
+	assert.False(t, isItemFile("pod-1.yaml"))
+
+	assert.Equal(t, "pod-1", itemFileName("pod-1.json"))
+	assert.Equal(t, "pod-1", itemFileName("pod-1.json.gz"))
+}
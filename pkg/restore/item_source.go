@@ -0,0 +1,145 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/heptio/velero/pkg/util/filesystem"
+)
+
+// ItemSource supplies the items of each resource that a restore restores, so their
+// manifests can be stored somewhere other than the backup. The resources and namespaces
+// that are restored are still determined by the contents of the backup.
+type ItemSource interface {
+	// ListItems returns the names of the items of the specified resource in the
+	// specified namespace, or of its cluster-scoped items if namespace is empty.
+	ListItems(groupResource schema.GroupResource, namespace string) ([]string, error)
+
+	// ReadItem returns the JSON of the named item of the specified resource in the
+	// specified namespace, or of the named cluster-scoped item if namespace is empty.
+	ReadItem(groupResource schema.GroupResource, namespace, name string) ([]byte, error)
+}
+
+// fileSystemItemSource is an ItemSource that reads the item files of an extracted
+// backup.
+type fileSystemItemSource struct {
+	fileSystem filesystem.Interface
+	restoreDir string
+	log        logrus.FieldLogger
+}
+
+// NewFileSystemItemSource returns an ItemSource that reads the item files in the provided
+// directory, which is laid out like the contents of a backup.
+func NewFileSystemItemSource(dir string, log logrus.FieldLogger) ItemSource {
+	return &fileSystemItemSource{
+		fileSystem: filesystem.NewFileSystem(),
+		restoreDir: dir,
+		log:        log,
+	}
+}
+
+func (s *fileSystemItemSource) ListItems(groupResource schema.GroupResource, namespace string) ([]string, error) {
+	return s.listDir(getResourceDir(s.restoreDir, groupResource.String(), namespace))
+}
+
+func (s *fileSystemItemSource) ReadItem(groupResource schema.GroupResource, namespace, name string) ([]byte, error) {
+	return readItemFile(s.fileSystem.ReadFile, getItemFilePath(s.restoreDir, groupResource.String(), namespace, name))
+}
+
+// listDir returns the names of the items whose files are in the provided directory.
+func (s *fileSystemItemSource) listDir(dir string) ([]string, error) {
+	files, err := listItemFiles(s.fileSystem, dir, s.log)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, file := range files {
+		names = append(names, itemFileName(file.Name()))
+	}
+	return names, nil
+}
+
+// readDir returns the JSON of the named item whose file is in the provided directory.
+func (s *fileSystemItemSource) readDir(dir, name string) ([]byte, error) {
+	return readItemFile(s.fileSystem.ReadFile, filepath.Join(dir, name+itemFileExt))
+}
+
+// itemDir is a directory of the extracted backup with the items of a resource in a
+// single namespace, or the resource's cluster-scoped items if namespace is empty. The
+// resource and namespace are the ones the items have in the backup, which may differ
+// from the ones they're restored as.
+type itemDir struct {
+	groupResource schema.GroupResource
+	namespace     string
+	path          string
+}
+
+// listDirItems returns the names of the items in the provided directory, from the
+// restore's item source if it has one, or from the directory otherwise.
+func (ctx *context) listDirItems(dir itemDir) ([]string, error) {
+	if ctx.itemSource != nil {
+		return ctx.itemSource.ListItems(dir.groupResource, dir.namespace)
+	}
+	return ctx.backupItemSource().listDir(dir.path)
+}
+
+// readDirItem returns the named item in the provided directory, from the restore's item
+// source if it has one, or from the directory otherwise.
+func (ctx *context) readDirItem(dir itemDir, name string) (*unstructured.Unstructured, error) {
+	if ctx.itemSource != nil {
+		return ctx.readItem(dir.groupResource, dir.namespace, name)
+	}
+
+	data, err := ctx.backupItemSource().readDir(dir.path, name)
+	if err != nil {
+		return nil, err
+	}
+	return decodeItem(data)
+}
+
+// getItemSource returns the restore's item source, which defaults to the item files
+// of the extracted backup.
+func (ctx *context) getItemSource() ItemSource {
+	if ctx.itemSource != nil {
+		return ctx.itemSource
+	}
+
+	return ctx.backupItemSource()
+}
+
+// backupItemSource returns the item source that reads the item files of the extracted
+// backup.
+func (ctx *context) backupItemSource() *fileSystemItemSource {
+	return &fileSystemItemSource{
+		fileSystem: ctx.fileSystem,
+		restoreDir: ctx.restoreDir,
+		log:        ctx.log,
+	}
+}
+
+// hasItem returns true if the restore's item source has the named item of the specified
+// backup resource in the specified backup namespace.
+func (ctx *context) hasItem(groupResource schema.GroupResource, namespace, name string) bool {
+	_, err := ctx.getItemSource().ReadItem(groupResource, namespace, name)
+	return err == nil
+}
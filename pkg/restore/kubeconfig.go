@@ -0,0 +1,139 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"encoding/base64"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	api "github.com/heptio/velero/pkg/apis/velero/v1"
+	"github.com/heptio/velero/pkg/util/kube"
+)
+
+// kubeconfigKeys are the secret data keys that kubeconfigs are conventionally
+// stored under.
+var kubeconfigKeys = []string{"config", "kubeconfig"}
+
+// rewriteKubeconfigs rewrites the kubeconfigs stored in the provided secret according
+// to the restore's kubeconfig rewrites. Values that aren't kubeconfigs are left unchanged.
+// A warning is returned for each kubeconfig that can't be rewritten.
+func (ctx *context) rewriteKubeconfigs(obj *unstructured.Unstructured) []error {
+	rewrites := ctx.restore.Spec.KubeconfigRewrites
+	if rewrites == nil {
+		return nil
+	}
+
+	var warnings []error
+
+	// data values are base64-encoded, string data values aren't
+	for _, field := range []string{"data", "stringData"} {
+		data, ok := obj.Object[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		for _, key := range kubeconfigKeys {
+			val, ok := data[key].(string)
+			if !ok {
+				continue
+			}
+
+			raw := []byte(val)
+			if field == "data" {
+				decoded, err := base64.StdEncoding.DecodeString(val)
+				if err != nil {
+					continue
+				}
+				raw = decoded
+			}
+
+			rewritten, changed, err := rewriteKubeconfig(raw, rewrites)
+			if err != nil {
+				warnings = append(warnings, errors.Wrapf(err, "error rewriting kubeconfig in key %s of secret %s", key, kube.NamespaceAndName(obj)))
+				continue
+			}
+			if !changed {
+				continue
+			}
+
+			ctx.log.Infof("Rewriting kubeconfig in key %s of secret %s", key, kube.NamespaceAndName(obj))
+			if field == "data" {
+				data[key] = base64.StdEncoding.EncodeToString(rewritten)
+			} else {
+				data[key] = string(rewritten)
+			}
+		}
+	}
+
+	return warnings
+}
+
+// rewriteKubeconfig applies the provided rewrites to the provided kubeconfig, returning
+// the rewritten kubeconfig and whether anything changed. Data that can't be parsed as a
+// kubeconfig is returned unchanged.
+func rewriteKubeconfig(raw []byte, rewrites *api.KubeconfigRewrites) ([]byte, bool, error) {
+	config := make(map[string]interface{})
+	if err := yaml.Unmarshal(raw, &config); err != nil {
+		return raw, false, nil
+	}
+	if kind, _, _ := unstructured.NestedString(config, "kind"); kind != "Config" {
+		if _, ok := config["clusters"].([]interface{}); !ok {
+			return raw, false, nil
+		}
+	}
+
+	var changed bool
+	remap := func(parent map[string]interface{}, field string, mappings map[string]string) {
+		val, ok := parent[field].(string)
+		if !ok {
+			return
+		}
+		if newVal, ok := mappings[val]; ok && newVal != val {
+			parent[field] = newVal
+			changed = true
+		}
+	}
+
+	forEachMap(config["clusters"], func(cluster map[string]interface{}) {
+		remap(cluster, "name", rewrites.ClusterNames)
+		if details, ok := cluster["cluster"].(map[string]interface{}); ok {
+			remap(details, "server", rewrites.Servers)
+		}
+	})
+
+	forEachMap(config["contexts"], func(kubeContext map[string]interface{}) {
+		remap(kubeContext, "name", rewrites.ContextNames)
+		if details, ok := kubeContext["context"].(map[string]interface{}); ok {
+			remap(details, "cluster", rewrites.ClusterNames)
+		}
+	})
+
+	remap(config, "current-context", rewrites.ContextNames)
+
+	if !changed {
+		return raw, false, nil
+	}
+
+	rewritten, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, false, errors.WithStack(err)
+	}
+	return rewritten, true, nil
+}
@@ -0,0 +1,201 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	api "github.com/heptio/velero/pkg/apis/velero/v1"
+	"github.com/heptio/velero/pkg/util/kube"
+)
+
+// olderRevisionReason is why items that aren't among the latest revisions
+// of their resource are skipped.
+const olderRevisionReason = "not one of the latest revisions of its resource"
+
+// parseFieldPath splits the provided field path, such as revision or
+// metadata.annotations["revision"], into its fields.
+func parseFieldPath(fieldPath string) ([]string, error) {
+	var fields []string
+
+	for rest := fieldPath; rest != ""; {
+		if strings.HasPrefix(rest, "[") {
+			if !strings.HasPrefix(rest, `["`) {
+				return nil, errors.Errorf("invalid field path %q: keys must be quoted", fieldPath)
+			}
+			end := strings.Index(rest, `"]`)
+			if end < 0 {
+				return nil, errors.Errorf("invalid field path %q: unterminated key", fieldPath)
+			}
+			fields = append(fields, rest[2:end])
+			rest = strings.TrimPrefix(rest[end+2:], ".")
+			continue
+		}
+
+		end := strings.IndexAny(rest, ".[")
+		if end < 0 {
+			end = len(rest)
+		}
+		if end == 0 {
+			return nil, errors.Errorf("invalid field path %q: empty field", fieldPath)
+		}
+		fields = append(fields, rest[:end])
+		rest = rest[end:]
+		if strings.HasPrefix(rest, ".") {
+			rest = rest[1:]
+			if rest == "" {
+				return nil, errors.Errorf("invalid field path %q: empty field", fieldPath)
+			}
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil, errors.New("field path is empty")
+	}
+	return fields, nil
+}
+
+// ValidateLatestOnly checks the provided restore latest only filters for field paths
+// that can't be parsed and negative counts. An error is returned for each problem.
+func ValidateLatestOnly(filters []api.LatestOnlyFilter) []error {
+	var errs []error
+	for _, filter := range filters {
+		if _, err := parseFieldPath(filter.FieldPath); err != nil {
+			errs = append(errs, errors.Wrapf(err, "invalid latest only filter for resource %s", filter.Resource))
+		}
+		if filter.Count < 0 {
+			errs = append(errs, errors.Errorf("invalid latest only filter for resource %s: count must not be negative", filter.Resource))
+		}
+	}
+	return errs
+}
+
+// latestOnlyFilter returns the restore's latest only filter for the specified
+// resource, and whether it has one.
+func (ctx *context) latestOnlyFilter(groupResource schema.GroupResource) (api.LatestOnlyFilter, bool) {
+	for _, filter := range ctx.restore.Spec.LatestOnly {
+		gvr, _, err := ctx.discoveryHelper.ResourceFor(schema.ParseGroupResource(filter.Resource).WithVersion(""))
+		if err != nil {
+			continue
+		}
+		if gvr.GroupResource() == groupResource {
+			return filter, true
+		}
+	}
+	return api.LatestOnlyFilter{}, false
+}
+
+// revision returns the numeric value of the provided item's field at the provided
+// path. Items whose field is missing or isn't numeric are the oldest revisions, so
+// negative infinity is returned for them, along with an error.
+func revision(obj *unstructured.Unstructured, fields []string) (float64, error) {
+	val, found, err := unstructured.NestedFieldNoCopy(obj.Object, fields...)
+	if err != nil {
+		return math.Inf(-1), errors.WithStack(err)
+	}
+	if !found {
+		return math.Inf(-1), errors.New("field not found")
+	}
+
+	switch val := val.(type) {
+	case int64:
+		return float64(val), nil
+	case float64:
+		return val, nil
+	case string:
+		parsed, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return math.Inf(-1), errors.Errorf("value %q isn't numeric", val)
+		}
+		return parsed, nil
+	default:
+		return math.Inf(-1), errors.Errorf("value of type %T isn't numeric", val)
+	}
+}
+
+// olderRevisions returns the names of the provided items of the specified resource, read
+// from the specified resource and namespace in the backup, that aren't among the latest
+// revisions the restore's latest only filter for the resource restores. Items whose
+// revision can't be determined are returned as warnings in each of the provided namespaces.
+func (ctx *context) olderRevisions(groupResource, sourceResource schema.GroupResource, sourceNamespace string, names []string, namespaces []string) (sets.String, Result) {
+	warnings := Result{}
+
+	filter, ok := ctx.latestOnlyFilter(groupResource)
+	if !ok {
+		return nil, warnings
+	}
+	fields, err := parseFieldPath(filter.FieldPath)
+	if err != nil {
+		for _, namespace := range namespaces {
+			addToResult(&warnings, namespace, errors.Wrapf(err, "not filtering %s to their latest revisions", groupResource))
+		}
+		return nil, warnings
+	}
+	count := filter.Count
+	if count == 0 {
+		count = 1
+	}
+
+	type itemRevision struct {
+		item     string
+		name     string
+		revision float64
+	}
+	groups := make(map[string][]itemRevision)
+
+	for _, item := range names {
+		// items that can't be decoded are handled when they're restored
+		obj, err := ctx.readItem(sourceResource, sourceNamespace, item)
+		if err != nil {
+			continue
+		}
+
+		rev, err := revision(obj, fields)
+		if err != nil {
+			for _, namespace := range namespaces {
+				addToResult(&warnings, namespace, errors.Wrapf(err, "treating %s %s as its oldest revision because its revision field %s can't be read", groupResource, kube.NamespaceAndName(obj), filter.FieldPath))
+			}
+		}
+
+		group := obj.GetLabels()[filter.GroupByLabel]
+		groups[group] = append(groups[group], itemRevision{item: item, name: obj.GetName(), revision: rev})
+	}
+
+	older := sets.NewString()
+	for _, revisions := range groups {
+		sort.SliceStable(revisions, func(i, j int) bool {
+			if revisions[i].revision != revisions[j].revision {
+				return revisions[i].revision > revisions[j].revision
+			}
+			return revisions[i].name < revisions[j].name
+		})
+
+		for i := count; i < len(revisions); i++ {
+			older.Insert(revisions[i].item)
+		}
+	}
+
+	return older, warnings
+}
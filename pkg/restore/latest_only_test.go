@@ -0,0 +1,64 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	velerov1api "github.com/heptio/velero/pkg/apis/velero/v1"
+)
+
+func TestParseFieldPath(t *testing.T) {
+	tests := []struct {
+		fieldPath string
+		want      []string
+		wantErr   bool
+	}{
+		{fieldPath: "revision", want: []string{"revision"}},
+		{fieldPath: "spec.template.revision", want: []string{"spec", "template", "revision"}},
+		{fieldPath: `metadata.annotations["example.com/revision"]`, want: []string{"metadata", "annotations", "example.com/revision"}},
+		{fieldPath: `metadata.annotations["a.b"].c`, want: []string{"metadata", "annotations", "a.b", "c"}},
+		{fieldPath: "", wantErr: true},
+		{fieldPath: "spec..revision", wantErr: true},
+		{fieldPath: "spec.", wantErr: true},
+		{fieldPath: "metadata.annotations[revision]", wantErr: true},
+		{fieldPath: `metadata.annotations["revision`, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.fieldPath, func(t *testing.T) {
+			fields, err := parseFieldPath(tc.fieldPath)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, fields)
+		})
+	}
+}
+
+func TestValidateLatestOnly(t *testing.T) {
+	assert.Empty(t, ValidateLatestOnly(nil))
+	assert.Empty(t, ValidateLatestOnly([]velerov1api.LatestOnlyFilter{{Resource: "controllerrevisions", FieldPath: "revision", Count: 2}}))
+	assert.Len(t, ValidateLatestOnly([]velerov1api.LatestOnlyFilter{
+		{Resource: "controllerrevisions", FieldPath: "revision..", Count: -1},
+	}), 2)
+}
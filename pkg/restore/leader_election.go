@@ -0,0 +1,60 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/heptio/velero/pkg/kuberesource"
+	"github.com/heptio/velero/pkg/util/kube"
+)
+
+// defaultLeaderElectionAnnotations are the annotations that hold leader election
+// records if the restore doesn't name any.
+var defaultLeaderElectionAnnotations = []string{
+	"control-plane.alpha.kubernetes.io/leader",
+}
+
+// leaseHolderFields are the fields of a lease that record its current holder.
+var leaseHolderFields = []string{"holderIdentity", "acquireTime", "renewTime"}
+
+// clearLeaderElectionRecords removes the restore's leader election annotations from
+// the provided item and, if it's a lease, clears its holder, so the restored record
+// has to be acquired again rather than being treated as held by a stale leader.
+func (ctx *context) clearLeaderElectionRecords(obj *unstructured.Unstructured, groupResource schema.GroupResource) {
+	leaderAnnotations := ctx.restore.Spec.LeaderElectionAnnotations
+	if len(leaderAnnotations) == 0 {
+		leaderAnnotations = defaultLeaderElectionAnnotations
+	}
+
+	annotations := obj.GetAnnotations()
+	for _, annotation := range leaderAnnotations {
+		if _, ok := annotations[annotation]; ok {
+			ctx.log.Infof("Removing leader election annotation %s from %s %s", annotation, groupResource, kube.NamespaceAndName(obj))
+			delete(annotations, annotation)
+		}
+	}
+	obj.SetAnnotations(annotations)
+
+	if groupResource != kuberesource.Leases {
+		return
+	}
+	for _, field := range leaseHolderFields {
+		unstructured.RemoveNestedField(obj.Object, "spec", field)
+	}
+}
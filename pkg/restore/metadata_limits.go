@@ -0,0 +1,122 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/heptio/velero/pkg/util/kube"
+)
+
+const (
+	// maxAnnotationsTotalSize is the maximum combined size, in bytes, of
+	// an object's annotation keys and values.
+	maxAnnotationsTotalSize = 256 * 1024
+
+	// labelValueHashLength is the number of hex characters of the
+	// original value's hash kept at the end of a truncated label value.
+	labelValueHashLength = 10
+)
+
+// truncateLabelValue shortens the provided label value to the maximum label value
+// length. The result ends with a hash of the original value so that truncated
+// values which share a prefix remain distinct.
+func truncateLabelValue(value string) string {
+	if len(value) <= validation.LabelValueMaxLength {
+		return value
+	}
+
+	sum := sha256.Sum256([]byte(value))
+	hash := hex.EncodeToString(sum[:])[:labelValueHashLength]
+
+	return value[:validation.LabelValueMaxLength-labelValueHashLength] + hash
+}
+
+// annotationsSize returns the combined size of the provided annotations' keys and values.
+func annotationsSize(annotations map[string]string) int {
+	var size int
+	for k, v := range annotations {
+		size += len(k) + len(v)
+	}
+	return size
+}
+
+// enforceMetadataLimits checks the provided item's label values and annotations against
+// Kubernetes' size limits. If the restore truncates oversize metadata, oversize label values
+// are truncated and the largest annotations are dropped until the rest fit, with a warning
+// returned for each change. Otherwise, an error is returned for an item with oversize metadata.
+func (ctx *context) enforceMetadataLimits(obj *unstructured.Unstructured) ([]error, error) {
+	var warnings []error
+
+	labels := obj.GetLabels()
+	for key, value := range labels {
+		if len(value) <= validation.LabelValueMaxLength {
+			continue
+		}
+
+		if !ctx.restore.Spec.TruncateOversizeMetadata {
+			return nil, errors.Errorf("value of label %s on %s is %d characters, more than the maximum of %d",
+				key, kube.NamespaceAndName(obj), len(value), validation.LabelValueMaxLength)
+		}
+
+		labels[key] = truncateLabelValue(value)
+		warnings = append(warnings, errors.Errorf("truncated value of label %s on %s to %s", key, kube.NamespaceAndName(obj), labels[key]))
+	}
+	if len(warnings) > 0 {
+		obj.SetLabels(labels)
+	}
+
+	annotations := obj.GetAnnotations()
+	if size := annotationsSize(annotations); size > maxAnnotationsTotalSize {
+		if !ctx.restore.Spec.TruncateOversizeMetadata {
+			return nil, errors.Errorf("annotations on %s total %d bytes, more than the maximum of %d",
+				kube.NamespaceAndName(obj), size, maxAnnotationsTotalSize)
+		}
+
+		// drop the largest annotations first so as few as possible are lost
+		keys := make([]string, 0, len(annotations))
+		for key := range annotations {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			sizeI, sizeJ := len(keys[i])+len(annotations[keys[i]]), len(keys[j])+len(annotations[keys[j]])
+			if sizeI != sizeJ {
+				return sizeI > sizeJ
+			}
+			return keys[i] < keys[j]
+		})
+
+		for _, key := range keys {
+			if size <= maxAnnotationsTotalSize {
+				break
+			}
+
+			size -= len(key) + len(annotations[key])
+			delete(annotations, key)
+			warnings = append(warnings, errors.Errorf("dropped annotation %s from %s because the item's annotations exceed the maximum size", key, kube.NamespaceAndName(obj)))
+		}
+		obj.SetAnnotations(annotations)
+	}
+
+	return warnings, nil
+}
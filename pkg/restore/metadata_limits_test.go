@@ -0,0 +1,133 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	velerotest "github.com/heptio/velero/pkg/util/test"
+)
+
+func TestTruncateLabelValue(t *testing.T) {
+	long := strings.Repeat("a", 100)
+
+	truncated := truncateLabelValue(long)
+	assert.Len(t, truncated, validation.LabelValueMaxLength)
+	assert.Empty(t, validation.IsValidLabelValue(truncated))
+	assert.True(t, strings.HasPrefix(truncated, strings.Repeat("a", validation.LabelValueMaxLength-labelValueHashLength)))
+
+	// values that share a prefix are truncated to different values
+	assert.NotEqual(t, truncated, truncateLabelValue(long+"b"))
+
+	// values within the limit are unchanged
+	assert.Equal(t, "short", truncateLabelValue("short"))
+}
+
+func TestEnforceMetadataLimits(t *testing.T) {
+	longValue := strings.Repeat("v", 100)
+
+	newObj := func(labels, annotations map[string]string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+		obj.SetNamespace("ns-1")
+		obj.SetName("pod-1")
+		obj.SetLabels(labels)
+		obj.SetAnnotations(annotations)
+		return obj
+	}
+
+	tests := []struct {
+		name            string
+		truncate        bool
+		obj             *unstructured.Unstructured
+		wantErr         bool
+		wantWarnings    int
+		wantLabels      map[string]string
+		wantAnnotations []string
+	}{
+		{
+			name:         "an oversize label value is truncated when truncation is enabled",
+			truncate:     true,
+			obj:          newObj(map[string]string{"long": longValue, "short": "val"}, nil),
+			wantWarnings: 1,
+			wantLabels:   map[string]string{"long": truncateLabelValue(longValue), "short": "val"},
+		},
+		{
+			name:     "an oversize label value is an error when truncation is disabled",
+			truncate: false,
+			obj:      newObj(map[string]string{"long": longValue}, nil),
+			wantErr:  true,
+		},
+		{
+			name:     "the largest annotation is dropped when annotations are oversize",
+			truncate: true,
+			obj: newObj(nil, map[string]string{
+				"large":  strings.Repeat("x", maxAnnotationsTotalSize),
+				"medium": strings.Repeat("x", 1024),
+				"small":  "val",
+			}),
+			wantWarnings:    1,
+			wantAnnotations: []string{"medium", "small"},
+		},
+		{
+			name:     "oversize annotations are an error when truncation is disabled",
+			truncate: false,
+			obj:      newObj(nil, map[string]string{"large": strings.Repeat("x", maxAnnotationsTotalSize+1)}),
+			wantErr:  true,
+		},
+		{
+			name:         "metadata within the limits is unchanged",
+			truncate:     true,
+			obj:          newObj(map[string]string{"short": "val"}, map[string]string{"small": "val"}),
+			wantLabels:   map[string]string{"short": "val"},
+			wantWarnings: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := &context{
+				restore: NewBuilder().TruncateOversizeMetadata(tc.truncate).Restore(),
+				log:     velerotest.NewLogger(),
+			}
+
+			warnings, err := ctx.enforceMetadataLimits(tc.obj)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, warnings, tc.wantWarnings)
+
+			if tc.wantLabels != nil {
+				assert.Equal(t, tc.wantLabels, tc.obj.GetLabels())
+			}
+			if tc.wantAnnotations != nil {
+				var keys []string
+				for key := range tc.obj.GetAnnotations() {
+					keys = append(keys, key)
+				}
+				assert.ElementsMatch(t, tc.wantAnnotations, keys)
+			}
+		})
+	}
+}
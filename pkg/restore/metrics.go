@@ -0,0 +1,203 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	metricNamespace = "velero"
+	metricSubsystem = "restore"
+
+	restoreItemsTotal              = "items_total"
+	restoreItemsRestoredTotal      = "items_restored_total"
+	restoreItemsSkippedTotal       = "items_skipped_total"
+	restoreItemsFailedTotal        = "items_failed_total"
+	restoreErrorsTotal             = "errors_total"
+	restoreDurationSeconds         = "duration_seconds"
+	restoreResourceDurationSeconds = "resource_duration_seconds"
+	restoreVolumeBytesTotal        = "volume_bytes_total"
+
+	resourceLabel = "resource"
+	outcomeLabel  = "outcome"
+)
+
+// restoreMetrics are the Prometheus metrics recorded by the restorer across all of its
+// restores. A nil *restoreMetrics records nothing.
+type restoreMetrics struct {
+	items            *prometheus.CounterVec
+	itemsRestored    *prometheus.CounterVec
+	itemsSkipped     *prometheus.CounterVec
+	itemsFailed      *prometheus.CounterVec
+	errors           prometheus.Counter
+	duration         prometheus.Histogram
+	resourceDuration *prometheus.HistogramVec
+	volumeBytes      prometheus.Counter
+}
+
+// newRestoreMetrics returns restore metrics registered with the provided registerer.
+// If the registerer is nil, the metrics are recorded but not exposed.
+func newRestoreMetrics(registerer prometheus.Registerer) (*restoreMetrics, error) {
+	m := &restoreMetrics{
+		items: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: metricNamespace,
+				Subsystem: metricSubsystem,
+				Name:      restoreItemsTotal,
+				Help:      "Total number of items processed by restores, by resource and outcome",
+			},
+			[]string{resourceLabel, outcomeLabel},
+		),
+		itemsRestored: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: metricNamespace,
+				Subsystem: metricSubsystem,
+				Name:      restoreItemsRestoredTotal,
+				Help:      "Total number of items created or updated by restores, by resource",
+			},
+			[]string{resourceLabel},
+		),
+		itemsSkipped: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: metricNamespace,
+				Subsystem: metricSubsystem,
+				Name:      restoreItemsSkippedTotal,
+				Help:      "Total number of items skipped by restores, by resource",
+			},
+			[]string{resourceLabel},
+		),
+		itemsFailed: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: metricNamespace,
+				Subsystem: metricSubsystem,
+				Name:      restoreItemsFailedTotal,
+				Help:      "Total number of items restores failed to restore, by resource",
+			},
+			[]string{resourceLabel},
+		),
+		errors: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace: metricNamespace,
+				Subsystem: metricSubsystem,
+				Name:      restoreErrorsTotal,
+				Help:      "Total number of errors recorded by restores",
+			},
+		),
+		duration: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Namespace: metricNamespace,
+				Subsystem: metricSubsystem,
+				Name:      restoreDurationSeconds,
+				Help:      "Time taken to restore the items of a backup, in seconds",
+				Buckets:   prometheus.ExponentialBuckets(1, 4, 8),
+			},
+		),
+		resourceDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: metricNamespace,
+				Subsystem: metricSubsystem,
+				Name:      restoreResourceDurationSeconds,
+				Help:      "Time taken to restore the items of a resource, in seconds",
+				Buckets:   prometheus.ExponentialBuckets(0.1, 4, 8),
+			},
+			[]string{resourceLabel},
+		),
+		volumeBytes: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace: metricNamespace,
+				Subsystem: metricSubsystem,
+				Name:      restoreVolumeBytesTotal,
+				Help:      "Total capacity, in bytes, of persistent volumes restored from snapshots",
+			},
+		),
+	}
+
+	if registerer == nil {
+		return m, nil
+	}
+
+	for _, c := range []prometheus.Collector{m.items, m.itemsRestored, m.itemsSkipped, m.itemsFailed, m.errors, m.duration, m.resourceDuration, m.volumeBytes} {
+		if err := registerer.Register(c); err != nil {
+			return nil, errors.Wrap(err, "error registering restore metrics")
+		}
+	}
+
+	return m, nil
+}
+
+// observeItem counts an item of the specified resource with the specified outcome.
+func (m *restoreMetrics) observeItem(groupResource schema.GroupResource, outcome ItemOutcome) {
+	if m == nil {
+		return
+	}
+	m.items.WithLabelValues(groupResource.String(), string(outcome)).Inc()
+
+	switch outcome {
+	case ItemOutcomeCreated, ItemOutcomeUpdated:
+		m.itemsRestored.WithLabelValues(groupResource.String()).Inc()
+	case ItemOutcomeSkipped:
+		m.itemsSkipped.WithLabelValues(groupResource.String()).Inc()
+	case ItemOutcomeFailed:
+		m.itemsFailed.WithLabelValues(groupResource.String()).Inc()
+	}
+}
+
+// observeErrors counts the messages in the provided errors result.
+func (m *restoreMetrics) observeErrors(errs Result) {
+	if m == nil {
+		return
+	}
+	m.errors.Add(float64(countMessages(errs)))
+}
+
+// observeDuration records how long a restore took to restore the items of its backup.
+func (m *restoreMetrics) observeDuration(duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.duration.Observe(duration.Seconds())
+}
+
+// observeResourceDuration records how long the items of the specified resource took to restore.
+func (m *restoreMetrics) observeResourceDuration(groupResource schema.GroupResource, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.resourceDuration.WithLabelValues(groupResource.String()).Observe(duration.Seconds())
+}
+
+// observeVolume counts the capacity of the provided persistent volume, which was
+// restored from a snapshot. Volumes without a valid capacity aren't counted.
+func (m *restoreMetrics) observeVolume(pv *unstructured.Unstructured) {
+	if m == nil {
+		return
+	}
+
+	capacity, _, _ := unstructured.NestedString(pv.Object, "spec", "capacity", "storage")
+	quantity, err := resource.ParseQuantity(capacity)
+	if err != nil {
+		return
+	}
+	m.volumeBytes.Add(float64(quantity.Value()))
+}
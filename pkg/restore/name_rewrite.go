@@ -0,0 +1,221 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"bytes"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	api "github.com/heptio/velero/pkg/apis/velero/v1"
+	"github.com/heptio/velero/pkg/kuberesource"
+)
+
+// nameTemplateData is the data a name rewrite's template is executed with.
+type nameTemplateData struct {
+	Name      string
+	Namespace string
+	Resource  string
+}
+
+// parseNameTemplate parses the provided name rewrite template.
+func parseNameTemplate(text string) (*template.Template, error) {
+	return template.New("nameRewrite").Option("missingkey=error").Parse(text)
+}
+
+// ValidateNameRewrite returns an error if the provided name rewrite's
+// template can't be parsed.
+func ValidateNameRewrite(rewrite *api.NameRewrite) []error {
+	if rewrite == nil || rewrite.Template == "" {
+		return nil
+	}
+
+	if _, err := parseNameTemplate(rewrite.Template); err != nil {
+		return []error{errors.Wrap(err, "invalid name template")}
+	}
+	return nil
+}
+
+// rewritesNames returns true if the restore renames the items of the
+// specified group resource.
+func (ctx *context) rewritesNames(groupResource schema.GroupResource) bool {
+	rewrite := ctx.restore.Spec.NameRewrite
+	if rewrite == nil {
+		return false
+	}
+
+	if len(rewrite.Resources) == 0 {
+		return groupResource != kuberesource.Namespaces && groupResource != kuberesource.CustomResourceDefinitions
+	}
+	for _, resource := range rewrite.Resources {
+		if resource == groupResource.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// rewriteName returns the name the specified backed-up item is restored with.
+func (ctx *context) rewriteName(groupResource schema.GroupResource, namespace, name string) (string, error) {
+	rewrite := ctx.restore.Spec.NameRewrite
+
+	if rewrite.Template != "" {
+		tmpl, err := parseNameTemplate(rewrite.Template)
+		if err != nil {
+			return "", errors.Wrap(err, "invalid name template")
+		}
+
+		buf := new(bytes.Buffer)
+		if err := tmpl.Execute(buf, nameTemplateData{Name: name, Namespace: namespace, Resource: groupResource.String()}); err != nil {
+			return "", errors.Wrapf(err, "error executing name template for %s", name)
+		}
+		name = buf.String()
+	}
+
+	return rewrite.Prefix + name + rewrite.Suffix, nil
+}
+
+// rewriteReference returns the name that a reference from an item in the specified
+// backed-up namespace to the named item of the specified group resource is rewritten
+// to, or the name unchanged if the referenced item isn't renamed by the restore or
+// isn't in the backup.
+func (ctx *context) rewriteReference(groupResource schema.GroupResource, namespace, name string) (string, error) {
+	if name == "" || !ctx.rewritesNames(groupResource) {
+		return name, nil
+	}
+
+	if !ctx.hasItem(groupResource, namespace, name) {
+		return name, nil
+	}
+
+	return ctx.rewriteName(groupResource, namespace, name)
+}
+
+// applyNameRewrite renames the provided item, if the restore renames the items of its
+// group resource, and rewrites its references to other renamed items in the backup: a
+// persistent volume claim's volume, a persistent volume's claim, the claims, config maps,
+// secrets and service account of an embedded pod spec, and owner references. The item's
+// new name is returned. An item that can't be renamed is an error.
+func (ctx *context) applyNameRewrite(obj *unstructured.Unstructured, groupResource schema.GroupResource, backupNamespace string) (string, error) {
+	if ctx.restore.Spec.NameRewrite == nil {
+		return obj.GetName(), nil
+	}
+
+	var rewriteErr error
+	rewrite := func(parent map[string]interface{}, field string, refResource schema.GroupResource, namespace string) {
+		name, ok := parent[field].(string)
+		if !ok || rewriteErr != nil {
+			return
+		}
+		newName, err := ctx.rewriteReference(refResource, namespace, name)
+		if err != nil {
+			rewriteErr = err
+			return
+		}
+		if newName != name {
+			ctx.log.Infof("Updating reference to %s %s in %s %s/%s to %s", refResource, name, groupResource, backupNamespace, obj.GetName(), newName)
+			parent[field] = newName
+		}
+	}
+
+	switch groupResource {
+	case kuberesource.PersistentVolumeClaims:
+		if spec, ok := obj.Object["spec"].(map[string]interface{}); ok {
+			rewrite(spec, "volumeName", kuberesource.PersistentVolumes, "")
+		}
+	case kuberesource.PersistentVolumes:
+		if claimRef, found, _ := unstructured.NestedFieldNoCopy(obj.Object, "spec", "claimRef"); found {
+			if claimRef, ok := claimRef.(map[string]interface{}); ok {
+				namespace, _ := claimRef["namespace"].(string)
+				rewrite(claimRef, "name", kuberesource.PersistentVolumeClaims, namespace)
+			}
+		}
+	}
+
+	if podSpec, ok := getPodSpec(obj, groupResource); ok {
+		rewrite(podSpec, "serviceAccountName", kuberesource.ServiceAccounts, backupNamespace)
+
+		forEachMap(podSpec["volumes"], func(volume map[string]interface{}) {
+			if claim, ok := volume["persistentVolumeClaim"].(map[string]interface{}); ok {
+				rewrite(claim, "claimName", kuberesource.PersistentVolumeClaims, backupNamespace)
+			}
+			if configMap, ok := volume["configMap"].(map[string]interface{}); ok {
+				rewrite(configMap, "name", kuberesource.ConfigMaps, backupNamespace)
+			}
+			if secret, ok := volume["secret"].(map[string]interface{}); ok {
+				rewrite(secret, "secretName", kuberesource.Secrets, backupNamespace)
+			}
+		})
+
+		forEachContainer(podSpec, func(container map[string]interface{}) {
+			forEachMap(container["envFrom"], func(envFrom map[string]interface{}) {
+				if ref, ok := envFrom["configMapRef"].(map[string]interface{}); ok {
+					rewrite(ref, "name", kuberesource.ConfigMaps, backupNamespace)
+				}
+				if ref, ok := envFrom["secretRef"].(map[string]interface{}); ok {
+					rewrite(ref, "name", kuberesource.Secrets, backupNamespace)
+				}
+			})
+			forEachMap(container["env"], func(env map[string]interface{}) {
+				if ref, found, _ := unstructured.NestedFieldNoCopy(env, "valueFrom", "configMapKeyRef"); found {
+					if ref, ok := ref.(map[string]interface{}); ok {
+						rewrite(ref, "name", kuberesource.ConfigMaps, backupNamespace)
+					}
+				}
+				if ref, found, _ := unstructured.NestedFieldNoCopy(env, "valueFrom", "secretKeyRef"); found {
+					if ref, ok := ref.(map[string]interface{}); ok {
+						rewrite(ref, "name", kuberesource.Secrets, backupNamespace)
+					}
+				}
+			})
+		})
+	}
+
+	// owner references are removed from items in the backup, but
+	// restore item actions can add them back
+	if owners, found, _ := unstructured.NestedFieldNoCopy(obj.Object, "metadata", "ownerReferences"); found {
+		forEachMap(owners, func(owner map[string]interface{}) {
+			apiVersion, _ := owner["apiVersion"].(string)
+			kind, _ := owner["kind"].(string)
+			gv, err := schema.ParseGroupVersion(apiVersion)
+			if err != nil || kind == "" {
+				return
+			}
+			rewrite(owner, "name", guessResource(gv.WithKind(kind)).GroupResource(), backupNamespace)
+		})
+	}
+
+	if rewriteErr != nil {
+		return "", rewriteErr
+	}
+
+	if !ctx.rewritesNames(groupResource) {
+		return obj.GetName(), nil
+	}
+
+	newName, err := ctx.rewriteName(groupResource, backupNamespace, obj.GetName())
+	if err != nil {
+		return "", err
+	}
+	ctx.log.Infof("Renaming %s %s/%s to %s", groupResource, backupNamespace, obj.GetName(), newName)
+	obj.SetName(newName)
+
+	return newName, nil
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/heptio/velero/pkg/apis/velero/v1"
+)
+
+// missingNamespaceError is an error for a namespace that doesn't exist when the
+// restore's namespace creation policy requires namespaces to exist.
+type missingNamespaceError struct {
+	namespace string
+}
+
+func (e *missingNamespaceError) Error() string {
+	return fmt.Sprintf("namespace %s doesn't exist and the restore's namespace creation policy is %s", e.namespace, api.NamespaceCreationPolicyRequireExisting)
+}
+
+// requireNamespace returns a *missingNamespaceError if the specified namespace
+// doesn't exist, or an error if it can't be retrieved.
+func (ctx *context) requireNamespace(name string) error {
+	_, err := ctx.namespaceClient.Get(name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return &missingNamespaceError{namespace: name}
+	}
+	return errors.Wrapf(err, "error getting namespace %s", name)
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ValidateNamespaceMapping checks the provided restore namespace mapping for source
+// namespaces that are mapped to the same target namespace, since their items could
+// overwrite each other. An error naming the colliding source namespaces is returned
+// for each target namespace with more than one source.
+func ValidateNamespaceMapping(mapping map[string]string) []error {
+	sources := make(map[string][]string)
+	for source, target := range mapping {
+		sources[target] = append(sources[target], source)
+	}
+
+	targets := make([]string, 0, len(sources))
+	for target := range sources {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+
+	var errs []error
+	for _, target := range targets {
+		if len(sources[target]) < 2 {
+			continue
+		}
+
+		sort.Strings(sources[target])
+		errs = append(errs, errors.Errorf("namespaces %s are all mapped to namespace %s", strings.Join(sources[target], ", "), target))
+	}
+
+	return errs
+}
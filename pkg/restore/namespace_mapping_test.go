@@ -0,0 +1,69 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateNamespaceMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping map[string]string
+		want    []error
+	}{
+		{
+			name: "no mapping is valid",
+		},
+		{
+			name:    "a one-to-one mapping is valid",
+			mapping: map[string]string{"ns-1": "ns-a", "ns-2": "ns-b"},
+		},
+		{
+			name:    "an identity mapping is valid",
+			mapping: map[string]string{"ns-1": "ns-1", "ns-2": "ns-b"},
+		},
+		{
+			name:    "a many-to-one mapping is an error naming the sources",
+			mapping: map[string]string{"ns-3": "ns-a", "ns-1": "ns-a", "ns-2": "ns-b"},
+			want:    []error{errors.New("namespaces ns-1, ns-3 are all mapped to namespace ns-a")},
+		},
+		{
+			name:    "a mapping onto a namespace that's also mapped to itself is an error",
+			mapping: map[string]string{"ns-1": "ns-2", "ns-2": "ns-2"},
+			want:    []error{errors.New("namespaces ns-1, ns-2 are all mapped to namespace ns-2")},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			errs := ValidateNamespaceMapping(tc.mapping)
+
+			var got, want []string
+			for _, err := range errs {
+				got = append(got, err.Error())
+			}
+			for _, err := range tc.want {
+				want = append(want, err.Error())
+			}
+			assert.Equal(t, want, got)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"time"
+
+	api "github.com/heptio/velero/pkg/apis/velero/v1"
+)
+
+// namespaceReadySleep waits out the restore's namespace ready delay. It's a
+// variable so tests can replace it.
+var namespaceReadySleep = time.Sleep
+
+// overrideMapEntries sets each of the provided overrides in the provided map,
+// replacing any existing values, and returns the map. The map is created if
+// it's nil.
+func overrideMapEntries(m, overrides map[string]string) map[string]string {
+	if m == nil {
+		m = make(map[string]string)
+	}
+	for k, v := range overrides {
+		m[k] = v
+	}
+	return m
+}
+
+// existingNamespaceMetadata returns the metadata that's merged onto a namespace the
+// restore restores into if it already exists: the annotation recording the restore, plus
+// the restore's namespace labels and annotations if the existing resource policy is update.
+func (ctx *context) existingNamespaceMetadata() map[string]interface{} {
+	annotations := map[string]string{api.LastRestoreAnnotation: ctx.restore.Name}
+	metadata := map[string]interface{}{"annotations": annotations}
+
+	if ctx.restore.Spec.ExistingResourcePolicy != api.ExistingResourcePolicyUpdate {
+		return metadata
+	}
+
+	overrideMapEntries(annotations, ctx.restore.Spec.NamespaceAnnotations)
+	annotations[api.LastRestoreAnnotation] = ctx.restore.Name
+
+	if len(ctx.restore.Spec.NamespaceLabels) > 0 {
+		metadata["labels"] = ctx.restore.Spec.NamespaceLabels
+	}
+
+	return metadata
+}
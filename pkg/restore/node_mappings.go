@@ -0,0 +1,188 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"github.com/pkg/errors"
+	corev1api "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// nodeNameField is the only node field that node selector terms can match.
+const nodeNameField = "metadata.name"
+
+// nodeSelectorOperators maps the operators of node selector requirements to the
+// equivalent label selector operators.
+var nodeSelectorOperators = map[corev1api.NodeSelectorOperator]selection.Operator{
+	corev1api.NodeSelectorOpIn:           selection.In,
+	corev1api.NodeSelectorOpNotIn:        selection.NotIn,
+	corev1api.NodeSelectorOpExists:       selection.Exists,
+	corev1api.NodeSelectorOpDoesNotExist: selection.DoesNotExist,
+	corev1api.NodeSelectorOpGt:           selection.GreaterThan,
+	corev1api.NodeSelectorOpLt:           selection.LessThan,
+}
+
+// remapNodeSelectorRequirement renames and rewrites the values of the label referenced by
+// the provided node selector requirement according to the first of the restore's node
+// mappings for it. It returns true if the requirement was changed.
+func (ctx *context) remapNodeSelectorRequirement(requirement *corev1api.NodeSelectorRequirement) bool {
+	for _, mapping := range ctx.restore.Spec.NodeMappings {
+		if mapping.Key != requirement.Key {
+			continue
+		}
+
+		var changed bool
+		for i, val := range requirement.Values {
+			if newVal, ok := mapping.Values[val]; ok && newVal != val {
+				requirement.Values[i] = newVal
+				changed = true
+			}
+		}
+		if mapping.NewKey != "" && mapping.NewKey != requirement.Key {
+			requirement.Key = mapping.NewKey
+			changed = true
+		}
+		return changed
+	}
+
+	return false
+}
+
+// nodeSelectorRequirementsMatch returns true if all of the provided node selector
+// requirements match the provided set of labels or fields.
+func nodeSelectorRequirementsMatch(requirements []corev1api.NodeSelectorRequirement, set labels.Set) bool {
+	for _, requirement := range requirements {
+		op, ok := nodeSelectorOperators[requirement.Operator]
+		if !ok {
+			return false
+		}
+		r, err := labels.NewRequirement(requirement.Key, op, requirement.Values)
+		if err != nil || !r.Matches(set) {
+			return false
+		}
+	}
+	return true
+}
+
+// nodeSelectorTermMatches returns true if the provided node selector term matches the
+// node with the provided name and labels. A term without any requirements matches no
+// nodes.
+func nodeSelectorTermMatches(term corev1api.NodeSelectorTerm, name string, nodeLabels labels.Set) bool {
+	if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+		return false
+	}
+
+	return nodeSelectorRequirementsMatch(term.MatchExpressions, nodeLabels) &&
+		nodeSelectorRequirementsMatch(term.MatchFields, labels.Set{nodeNameField: name})
+}
+
+// clusterNodeLabels returns the labels of the cluster's nodes, by node name. The nodes
+// are listed once per restore.
+func (ctx *context) clusterNodeLabels() (map[string]labels.Set, error) {
+	if ctx.nodesListed {
+		return ctx.nodeLabels, nil
+	}
+
+	nodeResource := metav1.APIResource{Name: "nodes", Namespaced: false}
+	resourceClient, err := ctx.dynamicFactory.ClientForGroupVersionResource(schema.GroupVersion{Group: "", Version: "v1"}, nodeResource, "")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	list, err := resourceClient.List(metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	nodeLabels := make(map[string]labels.Set, len(items))
+	for _, item := range items {
+		node, err := meta.Accessor(item)
+		if err != nil {
+			continue
+		}
+		nodeLabels[node.GetName()] = labels.Set(node.GetLabels())
+	}
+
+	ctx.nodeLabels = nodeLabels
+	ctx.nodesListed = true
+	return nodeLabels, nil
+}
+
+// remapNodeAffinity rewrites the node labels referenced by the provided persistent volume's
+// node affinity according to the restore's node mappings. Requirements without a mapping
+// are restored unchanged. An error is returned if no node in the cluster satisfies the
+// volume's node affinity once it's remapped, or if that can't be determined.
+func (ctx *context) remapNodeAffinity(obj *unstructured.Unstructured) error {
+	affinityMap, found, err := unstructured.NestedMap(obj.Object, "spec", "nodeAffinity")
+	if err != nil || !found {
+		return nil
+	}
+
+	affinity := new(corev1api.VolumeNodeAffinity)
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(affinityMap, affinity); err != nil {
+		return errors.Wrapf(err, "error decoding node affinity of persistent volume %s", obj.GetName())
+	}
+	if affinity.Required == nil {
+		return nil
+	}
+
+	var changed bool
+	for i := range affinity.Required.NodeSelectorTerms {
+		term := &affinity.Required.NodeSelectorTerms[i]
+		for j := range term.MatchExpressions {
+			if ctx.remapNodeSelectorRequirement(&term.MatchExpressions[j]) {
+				changed = true
+			}
+		}
+	}
+
+	if changed {
+		ctx.log.Infof("Updating node affinity of persistent volume %s according to the restore's node mappings", obj.GetName())
+
+		res, err := runtime.DefaultUnstructuredConverter.ToUnstructured(affinity)
+		if err != nil {
+			return errors.Wrapf(err, "error encoding node affinity of persistent volume %s", obj.GetName())
+		}
+		if err := unstructured.SetNestedMap(obj.Object, res, "spec", "nodeAffinity"); err != nil {
+			return errors.Wrapf(err, "error setting node affinity of persistent volume %s", obj.GetName())
+		}
+	}
+
+	nodeLabels, err := ctx.clusterNodeLabels()
+	if err != nil {
+		return errors.Wrapf(err, "error listing nodes to check the node affinity of persistent volume %s", obj.GetName())
+	}
+	for name, set := range nodeLabels {
+		for _, term := range affinity.Required.NodeSelectorTerms {
+			if nodeSelectorTermMatches(term, name, set) {
+				return nil
+			}
+		}
+	}
+
+	return errors.Errorf("no node in the cluster satisfies the node affinity of persistent volume %s", obj.GetName())
+}
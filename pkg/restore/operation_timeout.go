@@ -0,0 +1,97 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/heptio/velero/pkg/client"
+)
+
+// itemOperationTimeoutError is the error for an item operation that didn't finish
+// within the restorer's item operation timeout.
+type itemOperationTimeoutError struct {
+	operation string
+	name      string
+	timeout   time.Duration
+}
+
+func (e *itemOperationTimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s waiting to %s %s", e.timeout, e.operation, e.name)
+}
+
+// isItemOperationTimeout returns true if the provided error is from an item operation
+// that timed out.
+func isItemOperationTimeout(err error) bool {
+	_, ok := errors.Cause(err).(*itemOperationTimeoutError)
+	return ok
+}
+
+// withItemOperationTimeout returns a client whose item gets, creates, patches and
+// applies that time out return item operation timeout errors, or the provided client
+// if there's no timeout. The requests of the restorer's clients are expected to be given
+// the item operation timeout when they're created.
+func (ctx *context) withItemOperationTimeout(resourceClient client.Dynamic) client.Dynamic {
+	if ctx.itemOperationTimeout <= 0 {
+		return resourceClient
+	}
+
+	return &timeoutClient{Dynamic: resourceClient, timeout: ctx.itemOperationTimeout}
+}
+
+// timeoutClient is a client.Dynamic that reports the item operations whose requests
+// time out as item operation timeouts.
+type timeoutClient struct {
+	client.Dynamic
+
+	timeout time.Duration
+}
+
+func (c *timeoutClient) Get(name string, opts metav1.GetOptions) (*unstructured.Unstructured, error) {
+	obj, err := c.Dynamic.Get(name, opts)
+	return obj, c.checkTimeout("get", name, err)
+}
+
+func (c *timeoutClient) Create(obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	createdObj, err := c.Dynamic.Create(obj)
+	return createdObj, c.checkTimeout("create", obj.GetName(), err)
+}
+
+func (c *timeoutClient) Patch(name string, data []byte) (*unstructured.Unstructured, error) {
+	obj, err := c.Dynamic.Patch(name, data)
+	return obj, c.checkTimeout("patch", name, err)
+}
+
+func (c *timeoutClient) Apply(obj *unstructured.Unstructured, fieldManager string, force bool) (*unstructured.Unstructured, error) {
+	appliedObj, err := c.Dynamic.Apply(obj, fieldManager, force)
+	return appliedObj, c.checkTimeout("apply", obj.GetName(), err)
+}
+
+// checkTimeout returns an item operation timeout error for the provided operation on the
+// named item if err is from a request that timed out, or err otherwise.
+func (c *timeoutClient) checkTimeout(operation, name string, err error) error {
+	if netErr, ok := errors.Cause(err).(net.Error); ok && netErr.Timeout() {
+		return &itemOperationTimeoutError{operation: operation, name: name, timeout: c.timeout}
+	}
+	return err
+}
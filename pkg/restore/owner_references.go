@@ -0,0 +1,67 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/heptio/velero/pkg/util/kube"
+)
+
+// recordRestoredUID records the UID in the cluster of a restored item that had the
+// provided UID in the backup, so dependents restored after it can refer to it.
+func (ctx *context) recordRestoredUID(sourceUID, uid types.UID) {
+	if !ctx.restore.Spec.RemapOwnerReferences || sourceUID == "" || uid == "" {
+		return
+	}
+
+	if ctx.restoredUIDs == nil {
+		ctx.restoredUIDs = make(map[types.UID]types.UID)
+	}
+	ctx.restoredUIDs[sourceUID] = uid
+}
+
+// remapOwnerReferences sets the provided owner references, which the item had in the
+// backup, on the provided item with the UIDs of the owners that were restored before
+// it. References to owners that weren't restored are dropped, since the garbage
+// collector would otherwise delete the item, unless the restore keeps them.
+func (ctx *context) remapOwnerReferences(obj *unstructured.Unstructured, ownerRefs []metav1.OwnerReference) {
+	if len(ownerRefs) == 0 {
+		return
+	}
+
+	var remapped []metav1.OwnerReference
+	for _, ref := range ownerRefs {
+		uid, ok := ctx.restoredUIDs[ref.UID]
+		if !ok {
+			if ctx.restore.Spec.KeepUnresolvedOwnerReferences {
+				remapped = append(remapped, ref)
+				continue
+			}
+
+			ctx.log.Infof("Dropping owner reference of %s to %s %s, which wasn't restored", kube.NamespaceAndName(obj), ref.Kind, ref.Name)
+			continue
+		}
+
+		ref.UID = uid
+		remapped = append(remapped, ref)
+	}
+
+	obj.SetOwnerReferences(remapped)
+}
@@ -0,0 +1,456 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/heptio/velero/pkg/client"
+	"github.com/heptio/velero/pkg/kuberesource"
+)
+
+// itemParallelism returns the number of items of the specified resource to create
+// concurrently, according to the restore's per-resource and overall parallelism.
+func (ctx *context) itemParallelism(groupResource schema.GroupResource) int {
+	for resource, parallelism := range ctx.restore.Spec.ResourceParallelism {
+		if schema.ParseGroupResource(resource) == groupResource && parallelism > 0 {
+			return parallelism
+		}
+	}
+
+	if ctx.restore.Spec.Parallelism > 0 {
+		return ctx.restore.Spec.Parallelism
+	}
+	return 1
+}
+
+// itemWorkers restores the items of a single resource with up to a fixed number of
+// them in progress at once.
+//
+// The restore context isn't safe for concurrent use, so while workers are running,
+// everything that touches it is done with ctx.itemLock held: the caller holds it
+// between calls to restore, and each worker holds it while restoring its item. A
+// worker only releases it while creating its item in the cluster (see
+// withoutItemLock), which is the part of restoring an item that's worth overlapping.
+type itemWorkers struct {
+	ctx      *context
+	sem      chan struct{}
+	nested   bool
+	wg       sync.WaitGroup
+	warnings Result
+	errs     Result
+}
+
+// startItemWorkers returns itemWorkers that restore up to the specified number of
+// items at once. If the number is one, items are restored synchronously by restore.
+// If items are already being restored concurrently, i.e. the resource is one of several
+// being restored at once, the caller already holds ctx.itemLock.
+func (ctx *context) startItemWorkers(parallelism int) *itemWorkers {
+	w := &itemWorkers{ctx: ctx}
+
+	if parallelism > 1 {
+		w.sem = make(chan struct{}, parallelism)
+		w.nested = ctx.parallelItems
+		if !w.nested {
+			ctx.itemLock.Lock()
+			ctx.parallelItems = true
+		}
+	}
+
+	return w
+}
+
+// restore runs the provided item restore, waiting for a worker to be available first.
+func (w *itemWorkers) restore(restoreItem func() (Result, Result)) {
+	if w.sem == nil {
+		warnings, errs := restoreItem()
+		merge(&w.warnings, &warnings)
+		merge(&w.errs, &errs)
+		return
+	}
+
+	// let running workers make progress while waiting for one to finish
+	w.ctx.itemLock.Unlock()
+	w.sem <- struct{}{}
+	w.ctx.itemLock.Lock()
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer func() { <-w.sem }()
+
+		w.ctx.itemLock.Lock()
+		defer w.ctx.itemLock.Unlock()
+
+		warnings, errs := restoreItem()
+		merge(&w.warnings, &warnings)
+		merge(&w.errs, &errs)
+	}()
+}
+
+// wait waits for all item restores to complete and returns their combined
+// warnings and errors.
+func (w *itemWorkers) wait() (Result, Result) {
+	if w.sem != nil {
+		w.ctx.itemLock.Unlock()
+		w.wg.Wait()
+		if w.nested {
+			w.ctx.itemLock.Lock()
+		} else {
+			w.ctx.parallelItems = false
+		}
+	}
+
+	return w.warnings, w.errs
+}
+
+// serialResources are resources that are always restored one at a time, in order,
+// since other resources depend on them.
+var serialResources = append([]schema.GroupResource{kuberesource.CustomResourceDefinitions}, gatewayResourceOrder...)
+
+// splitIndependentResources splits the restore's prioritized resources into the ones
+// restored one at a time, in order, and the independent ones that can be restored
+// concurrently. The resources named in the resource priorities, and all resources
+// before them, are restored in order, as are the serial resources, which keep their
+// relative order but are restored before the independent resources.
+func (ctx *context) splitIndependentResources() (serial, independent []schema.GroupResource) {
+	prioritized := sets.NewString()
+	for _, r := range ctx.resourcePriorities {
+		gvr, _, err := ctx.discoveryHelper.ResourceFor(schema.ParseGroupResource(r).WithVersion(""))
+		if err != nil {
+			continue
+		}
+		prioritized.Insert(gvr.GroupResource().String())
+	}
+
+	last := -1
+	for i, gr := range ctx.prioritizedResources {
+		if prioritized.Has(gr.String()) {
+			last = i
+		}
+	}
+
+	alwaysSerial := sets.NewString()
+	for _, gr := range serialResources {
+		alwaysSerial.Insert(gr.String())
+	}
+
+	for i, gr := range ctx.prioritizedResources {
+		if i <= last || alwaysSerial.Has(gr.String()) {
+			serial = append(serial, gr)
+		} else {
+			independent = append(independent, gr)
+		}
+	}
+
+	return serial, independent
+}
+
+// restoreIndependentResources restores the provided resources with up to the restore's
+// independent resource parallelism of them in progress at once. As with items restored
+// concurrently, each resource is restored with ctx.itemLock held, so everything that
+// touches the restore context, including the restore's results and restored items, is
+// serialized, and only item creates overlap. The first error that stops a resource from
+// being restored is returned once all of the resources are done.
+func (ctx *context) restoreIndependentResources(resources []schema.GroupResource, resourcesDir string, resourceDirs map[string]os.FileInfo, existingNamespaces sets.String) (Result, Result, error) {
+	var (
+		warnings, errs Result
+		firstErr       error
+		wg             sync.WaitGroup
+	)
+
+	sem := make(chan struct{}, ctx.restore.Spec.IndependentResourceParallelism)
+
+	ctx.itemLock.Lock()
+	ctx.parallelItems = true
+
+	for _, resource := range resources {
+		rscDir, ok := ctx.resourceDirToRestore(resource, resourceDirs)
+		if !ok {
+			continue
+		}
+
+		// let running resources make progress while waiting for one to finish
+		ctx.itemLock.Unlock()
+		sem <- struct{}{}
+		ctx.itemLock.Lock()
+
+		resource, sourceResource, resourcePath := resource, schema.ParseGroupResource(rscDir.Name()), filepath.Join(resourcesDir, rscDir.Name())
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			ctx.itemLock.Lock()
+			defer ctx.itemLock.Unlock()
+
+			span := ctx.startResourceSpan(resource)
+			w, e, err := ctx.restoreResourceDir(resource, sourceResource, resourcePath, existingNamespaces, span)
+			ctx.endResourceSpan(span)
+
+			merge(&warnings, &w)
+			merge(&errs, &e)
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}()
+	}
+
+	ctx.itemLock.Unlock()
+	wg.Wait()
+	ctx.parallelItems = false
+
+	return warnings, errs, firstErr
+}
+
+// namespaceTimedOutReason is why the items of a namespace are skipped once
+// restoring the items of a resource in it has timed out.
+const namespaceTimedOutReason = "the restore of its namespace timed out"
+
+// namespaceRestore is the restore of a resource's items from a single namespace
+// in the backup into its target namespaces.
+type namespaceRestore struct {
+	namespaces []string
+	source     itemDir
+}
+
+// restoreNamespaces restores the provided resource's items from each of the provided
+// namespaces with up to the restore's namespace parallelism of them in progress at
+// once. Each namespace's items are restored with ctx.itemLock held, as with items
+// restored concurrently, so only item creates overlap.
+func (ctx *context) restoreNamespaces(resource schema.GroupResource, nsRestores []namespaceRestore, span *resourceSpan) (Result, Result) {
+	var (
+		warnings, errs Result
+		wg             sync.WaitGroup
+	)
+
+	parallelism := ctx.restore.Spec.NamespaceParallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	sem := make(chan struct{}, parallelism)
+
+	// the resource may be one of several being restored at once,
+	// in which case the caller already holds the lock
+	nested := ctx.parallelItems
+	if !nested {
+		ctx.itemLock.Lock()
+		ctx.parallelItems = true
+	}
+
+	for _, nsRestore := range nsRestores {
+		// let running namespaces make progress while waiting for one to finish
+		ctx.itemLock.Unlock()
+		sem <- struct{}{}
+		ctx.itemLock.Lock()
+
+		nsRestore := nsRestore
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			ctx.itemLock.Lock()
+			defer ctx.itemLock.Unlock()
+
+			w, e := ctx.restoreNamespace(resource, nsRestore, span)
+			merge(&warnings, &w)
+			merge(&errs, &e)
+		}()
+	}
+
+	ctx.itemLock.Unlock()
+	wg.Wait()
+	if nested {
+		ctx.itemLock.Lock()
+	} else {
+		ctx.parallelItems = false
+	}
+
+	return warnings, errs
+}
+
+// restoreNamespace restores the provided resource's items from a single namespace,
+// with ctx.itemLock held. If the restore has a namespace timeout, each target namespace
+// gets a deadline the first time any of its items are restored, which holds for the rest
+// of the restore, so resources restored concurrently into it share it. If the deadline
+// passes, the namespace's remaining items are skipped, and an error is recorded for it
+// once, when it's abandoned for the rest of the restore.
+func (ctx *context) restoreNamespace(resource schema.GroupResource, nsRestore namespaceRestore, span *resourceSpan) (Result, Result) {
+	timeout := ctx.restore.Spec.NamespaceTimeout.Duration
+	if timeout <= 0 {
+		return ctx.restoreResourceInto(resource.String(), nsRestore.namespaces, nsRestore.source, span)
+	}
+
+	for _, namespace := range nsRestore.namespaces {
+		if _, ok := ctx.namespaceDeadlines[namespace]; !ok {
+			ctx.namespaceDeadlines[namespace] = time.Now().Add(timeout)
+		}
+	}
+
+	warnings, errs := ctx.restoreResourceInto(resource.String(), nsRestore.namespaces, nsRestore.source, span)
+
+	for _, namespace := range nsRestore.namespaces {
+		if !ctx.namespaceTimedOut([]string{namespace}) || ctx.abandonedNamespaces.Has(namespace) {
+			continue
+		}
+
+		ctx.log.Warnf("Timed out after %s restoring resource %s in namespace %s", timeout, resource, namespace)
+		addToResult(&errs, namespace, errors.Errorf("timed out after %s restoring resource %s", timeout, resource))
+		ctx.abandonedNamespaces.Insert(namespace)
+	}
+
+	return warnings, errs
+}
+
+// namespaceTimedOut returns true if any of the provided namespaces has a deadline
+// for restoring its items that has passed.
+func (ctx *context) namespaceTimedOut(namespaces []string) bool {
+	for _, namespace := range namespaces {
+		if deadline, ok := ctx.namespaceDeadlines[namespace]; ok && time.Now().After(deadline) {
+			return true
+		}
+	}
+	return false
+}
+
+// namespaceDeadlineError is the error for an item operation that wasn't attempted
+// because the deadline of the item's namespace had passed.
+type namespaceDeadlineError struct {
+	operation string
+	name      string
+	namespace string
+}
+
+func (e *namespaceDeadlineError) Error() string {
+	return fmt.Sprintf("gave up trying to %s %s because namespace %s timed out", e.operation, e.name, e.namespace)
+}
+
+// isNamespaceTimeout returns true if the provided error is from an item operation
+// that wasn't attempted because its namespace timed out.
+func isNamespaceTimeout(err error) bool {
+	_, ok := errors.Cause(err).(*namespaceDeadlineError)
+	return ok
+}
+
+// withNamespaceDeadline returns a client whose item gets, creates, patches and applies
+// fail once the deadline of the provided namespace has passed, or the provided client
+// if the namespace has no deadline. Each operation checks the deadline, so items that
+// are retried, updated, or waited for after they're created, e.g. for their readiness
+// or to run their hooks, stop when the namespace times out. It must be called with
+// ctx.itemLock held, but the client it returns doesn't touch the restore context.
+func (ctx *context) withNamespaceDeadline(resourceClient client.Dynamic, namespace string) client.Dynamic {
+	deadline, ok := ctx.namespaceDeadlines[namespace]
+	if !ok {
+		return resourceClient
+	}
+
+	return &deadlineClient{Dynamic: resourceClient, namespace: namespace, deadline: deadline}
+}
+
+// deadlineClient is a client.Dynamic that refuses item operations once the deadline
+// of its namespace has passed.
+type deadlineClient struct {
+	client.Dynamic
+
+	namespace string
+	deadline  time.Time
+}
+
+func (c *deadlineClient) Get(name string, opts metav1.GetOptions) (*unstructured.Unstructured, error) {
+	if err := c.checkDeadline("get", name); err != nil {
+		return nil, err
+	}
+	return c.Dynamic.Get(name, opts)
+}
+
+func (c *deadlineClient) Create(obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	if err := c.checkDeadline("create", obj.GetName()); err != nil {
+		return nil, err
+	}
+	return c.Dynamic.Create(obj)
+}
+
+func (c *deadlineClient) Patch(name string, data []byte) (*unstructured.Unstructured, error) {
+	if err := c.checkDeadline("patch", name); err != nil {
+		return nil, err
+	}
+	return c.Dynamic.Patch(name, data)
+}
+
+func (c *deadlineClient) Apply(obj *unstructured.Unstructured, fieldManager string, force bool) (*unstructured.Unstructured, error) {
+	if err := c.checkDeadline("apply", obj.GetName()); err != nil {
+		return nil, err
+	}
+	return c.Dynamic.Apply(obj, fieldManager, force)
+}
+
+// checkDeadline returns a namespace deadline error for the provided operation on the
+// named item if the client's namespace has timed out.
+func (c *deadlineClient) checkDeadline(operation, name string) error {
+	if time.Now().After(c.deadline) {
+		return &namespaceDeadlineError{operation: operation, name: name, namespace: c.namespace}
+	}
+	return nil
+}
+
+// createWithoutItemLock creates the provided item with ctx.itemLock released. The
+// create itself is bounded by the restorer's item operation timeout, and isn't attempted
+// if the item's client is past its namespace's deadline.
+func (ctx *context) createWithoutItemLock(obj *unstructured.Unstructured, resourceClient client.Dynamic) (*unstructured.Unstructured, error) {
+	var (
+		createdObj *unstructured.Unstructured
+		err        error
+	)
+	ctx.withoutItemLock(func() { createdObj, err = ctx.createOrApply(obj, resourceClient) })
+	return createdObj, err
+}
+
+// withoutItemLock calls fn with ctx.itemLock released, if items are being restored
+// concurrently. fn must not touch the restore context.
+func (ctx *context) withoutItemLock(fn func()) {
+	if !ctx.parallelItems {
+		fn()
+		return
+	}
+
+	ctx.itemLock.Unlock()
+	defer ctx.itemLock.Lock()
+	fn()
+}
+
+// startVolumeRestore waits until fewer than the restore's volume restore parallelism
+// of pod volume restores are running, and returns a func to call once the caller's
+// volume restore is done. It's safe to call concurrently.
+func (ctx *context) startVolumeRestore() (done func()) {
+	if ctx.volumeRestoreSlots == nil {
+		return func() {}
+	}
+
+	ctx.volumeRestoreSlots <- struct{}{}
+	return func() { <-ctx.volumeRestoreSlots }
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+
+	api "github.com/heptio/velero/pkg/apis/velero/v1"
+	"github.com/heptio/velero/pkg/client"
+	"github.com/heptio/velero/pkg/util/kube"
+)
+
+const (
+	// podSecurityEnforceLabel is the namespace label that sets the PodSecurity
+	// admission level enforced on the namespace's pods.
+	podSecurityEnforceLabel = "pod-security.kubernetes.io/enforce"
+
+	// podSecurityViolationPrefix starts the part of a PodSecurity admission
+	// rejection's message that names the enforced level and violated controls.
+	podSecurityViolationPrefix = `violates PodSecurity "`
+)
+
+// podSecurityViolation describes an item's rejection by PodSecurity admission.
+type podSecurityViolation struct {
+	// level is the enforced level and version, e.g. "restricted:latest".
+	level string
+
+	// controls are the checks of the level that the item failed.
+	controls []string
+}
+
+// getPodSecurityViolation returns the violation described by the provided create error,
+// or false if it's not a rejection by PodSecurity admission.
+func getPodSecurityViolation(err error) (podSecurityViolation, bool) {
+	if !apierrors.IsForbidden(err) {
+		return podSecurityViolation{}, false
+	}
+
+	msg := err.Error()
+	i := strings.Index(msg, podSecurityViolationPrefix)
+	if i < 0 {
+		return podSecurityViolation{}, false
+	}
+	msg = msg[i+len(podSecurityViolationPrefix):]
+
+	var violation podSecurityViolation
+	if i = strings.Index(msg, `": `); i < 0 {
+		violation.level = strings.TrimSuffix(msg, `"`)
+		return violation, true
+	}
+	violation.level = msg[:i]
+
+	// each violated control is followed by details in parentheses, which
+	// may themselves contain commas, e.g.
+	// allowPrivilegeEscalation != false (container "c" must set ...), runAsNonRoot != true (...)
+	var depth, start int
+	details := msg[i+len(`": `):]
+	for j, r := range details + "," {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth > 0 {
+				continue
+			}
+			control := details[start:j]
+			if k := strings.Index(control, " ("); k >= 0 {
+				control = control[:k]
+			}
+			if control = strings.TrimSpace(control); control != "" {
+				violation.controls = append(violation.controls, control)
+			}
+			start = j + 1
+		}
+	}
+
+	return violation, true
+}
+
+// handlePodSecurityRejection handles the rejection of the provided item by the PodSecurity
+// admission level enforced on its namespace according to the restore's pod security policy.
+// If the policy relaxes the namespace, the item is created again once its namespace is
+// relaxed. Otherwise, an error listing the violated controls is returned.
+func (ctx *context) handlePodSecurityRejection(obj *unstructured.Unstructured, violation podSecurityViolation, resourceClient client.Dynamic) (*unstructured.Unstructured, error) {
+	if ctx.restore.Spec.PodSecurityPolicy != api.PodSecurityPolicyRelaxNamespace {
+		return nil, errors.Errorf("%s was rejected by the PodSecurity %q level enforced on namespace %s, violating: %s. "+
+			"Relax the namespace's %s label, or restore with the %s pod security policy",
+			kube.NamespaceAndName(obj), violation.level, obj.GetNamespace(), strings.Join(violation.controls, "; "),
+			podSecurityEnforceLabel, api.PodSecurityPolicyRelaxNamespace)
+	}
+
+	if err := ctx.relaxPodSecurity(obj.GetNamespace()); err != nil {
+		return nil, err
+	}
+
+	ctx.log.Infof("Creating %s again now that the PodSecurity level of its namespace is relaxed", kube.NamespaceAndName(obj))
+
+	return ctx.createWithRetry(obj, resourceClient)
+}
+
+// relaxPodSecurity sets the PodSecurity enforce level of the provided namespace to
+// privileged, remembering its original level so that it can be put back by
+// restorePodSecurity. It's a no-op if the namespace has already been relaxed.
+func (ctx *context) relaxPodSecurity(namespace string) error {
+	if _, relaxed := ctx.relaxedNamespaces[namespace]; relaxed {
+		return nil
+	}
+
+	ns, err := ctx.namespaceClient.Get(namespace, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "error getting namespace %s to relax its PodSecurity level", namespace)
+	}
+
+	// a nil level removes the label when the namespace's level is put back
+	var original *string
+	if level, ok := ns.Labels[podSecurityEnforceLabel]; ok {
+		original = &level
+	}
+
+	if err := ctx.patchPodSecurityLevel(namespace, "privileged"); err != nil {
+		return errors.Wrapf(err, "error relaxing PodSecurity level of namespace %s", namespace)
+	}
+
+	ctx.log.Infof("Relaxed PodSecurity level of namespace %s to privileged for the rest of the restore", namespace)
+
+	if ctx.relaxedNamespaces == nil {
+		ctx.relaxedNamespaces = make(map[string]*string)
+	}
+	ctx.relaxedNamespaces[namespace] = original
+
+	return nil
+}
+
+// restorePodSecurity puts back the original PodSecurity enforce level of each namespace
+// relaxed by relaxPodSecurity, returning a warning for each namespace that fails.
+func (ctx *context) restorePodSecurity() Result {
+	warnings := Result{}
+
+	for namespace, original := range ctx.relaxedNamespaces {
+		var level interface{}
+		if original != nil {
+			level = *original
+		}
+
+		if err := ctx.patchPodSecurityLevel(namespace, level); err != nil {
+			addToResult(&warnings, namespace, errors.Wrapf(err, "error putting back PodSecurity level of namespace %s", namespace))
+			continue
+		}
+
+		ctx.log.Infof("Put back original PodSecurity level of namespace %s", namespace)
+	}
+
+	return warnings
+}
+
+// patchPodSecurityLevel sets the PodSecurity enforce label of the provided namespace to
+// the provided level, removing the label if the level is nil.
+func (ctx *context) patchPodSecurityLevel(namespace string, level interface{}) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{podSecurityEnforceLabel: level},
+		},
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	_, err = ctx.namespaceClient.Patch(namespace, types.MergePatchType, patch)
+	return errors.WithStack(err)
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/heptio/velero/pkg/kuberesource"
+)
+
+func TestGetPodSecurityViolation(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		want   podSecurityViolation
+		wantOK bool
+	}{
+		{
+			name: "a PodSecurity rejection has its level and violated controls",
+			err: apierrors.NewForbidden(kuberesource.Pods, "pod-1", errors.New(`violates PodSecurity "baseline:v1.25": `+
+				`host namespaces (hostNetwork=true), hostPort (container "c1" uses hostPorts 80, 443)`)),
+			want: podSecurityViolation{
+				level:    "baseline:v1.25",
+				controls: []string{"host namespaces", "hostPort"},
+			},
+			wantOK: true,
+		},
+		{
+			name:   "a forbidden error from something other than PodSecurity admission isn't a violation",
+			err:    apierrors.NewForbidden(kuberesource.Pods, "pod-1", errors.New("exceeded quota")),
+			wantOK: false,
+		},
+		{
+			name:   "an error that isn't forbidden isn't a violation",
+			err:    errors.New(`violates PodSecurity "restricted:latest": runAsNonRoot != true`),
+			wantOK: false,
+		},
+		{
+			name:   "no error isn't a violation",
+			wantOK: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := getPodSecurityViolation(tc.err)
+			assert.Equal(t, tc.wantOK, ok)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,229 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	api "github.com/heptio/velero/pkg/apis/velero/v1"
+)
+
+// podSpecPaths maps the group resources that embed a pod spec to the
+// path of the pod spec within an item's unstructured content.
+var podSpecPaths = map[schema.GroupResource][]string{
+	{Group: "", Resource: "pods"}:                   {"spec"},
+	{Group: "", Resource: "replicationcontrollers"}: {"spec", "template", "spec"},
+	{Group: "apps", Resource: "deployments"}:        {"spec", "template", "spec"},
+	{Group: "apps", Resource: "replicasets"}:        {"spec", "template", "spec"},
+	{Group: "apps", Resource: "statefulsets"}:       {"spec", "template", "spec"},
+	{Group: "apps", Resource: "daemonsets"}:         {"spec", "template", "spec"},
+	{Group: "extensions", Resource: "deployments"}:  {"spec", "template", "spec"},
+	{Group: "extensions", Resource: "replicasets"}:  {"spec", "template", "spec"},
+	{Group: "extensions", Resource: "daemonsets"}:   {"spec", "template", "spec"},
+	{Group: "batch", Resource: "jobs"}:              {"spec", "template", "spec"},
+	{Group: "batch", Resource: "cronjobs"}:          {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// getPodSpec returns the pod spec embedded in the provided item, or false if the
+// item's group resource doesn't embed one or the item doesn't have a pod spec. The
+// returned map is not a copy, so changes to it are reflected in the item.
+func getPodSpec(obj *unstructured.Unstructured, groupResource schema.GroupResource) (map[string]interface{}, bool) {
+	path, ok := podSpecPaths[groupResource]
+	if !ok {
+		return nil, false
+	}
+
+	val, found, err := unstructured.NestedFieldNoCopy(obj.Object, path...)
+	if err != nil || !found {
+		return nil, false
+	}
+
+	podSpec, ok := val.(map[string]interface{})
+	return podSpec, ok
+}
+
+// forEachContainer calls fn for each of the init containers and containers
+// in the provided pod spec. Changes made by fn are reflected in the pod spec.
+func forEachContainer(podSpec map[string]interface{}, fn func(container map[string]interface{})) {
+	for _, field := range []string{"initContainers", "containers"} {
+		containers, ok := podSpec[field].([]interface{})
+		if !ok {
+			continue
+		}
+
+		for _, c := range containers {
+			if container, ok := c.(map[string]interface{}); ok {
+				fn(container)
+			}
+		}
+	}
+}
+
+// transformPodSpec applies the pod spec overrides configured on the restore
+// to the pod spec embedded in the provided item, if it has one.
+func (ctx *context) transformPodSpec(obj *unstructured.Unstructured, groupResource schema.GroupResource) {
+	podSpec, ok := getPodSpec(obj, groupResource)
+	if !ok {
+		return
+	}
+
+	if policy := ctx.restore.Spec.ImagePullPolicyOverride; policy != "" {
+		forEachContainer(podSpec, func(container map[string]interface{}) {
+			container["imagePullPolicy"] = string(policy)
+		})
+	}
+
+	if schedulerName := ctx.restore.Spec.SchedulerName; schedulerName != "" {
+		podSpec["schedulerName"] = schedulerName
+	}
+
+	for _, rule := range ctx.restore.Spec.AddTolerations {
+		if err := addTolerations(podSpec, obj, rule); err != nil {
+			ctx.log.WithError(err).Warnf("Error adding tolerations to %s %s/%s", groupResource, obj.GetNamespace(), obj.GetName())
+		}
+	}
+
+	if selector := ctx.restore.Spec.StripPriorityClass; selector != nil {
+		if err := stripPriorityClass(podSpec, obj, selector); err != nil {
+			ctx.log.WithError(err).Warnf("Error removing priority class from %s %s/%s", groupResource, obj.GetNamespace(), obj.GetName())
+		}
+	}
+
+	if len(ctx.restore.Spec.HostAliasRewrites) > 0 {
+		rewriteHostAliases(podSpec, ctx.restore.Spec.HostAliasRewrites)
+	}
+}
+
+// rewriteHostAliases moves each hostname in the pod spec's host aliases that has a
+// rewrite to the alias for its new IP, or removes it if it's rewritten to an empty
+// string. Aliases keep their order, an alias for a new IP is added where the first
+// hostname moved to it was, and aliases left without hostnames are removed.
+func rewriteHostAliases(podSpec map[string]interface{}, rewrites map[string]string) {
+	aliases, ok := podSpec["hostAliases"].([]interface{})
+	if !ok {
+		return
+	}
+
+	var (
+		ips       []string
+		hostnames = make(map[string][]interface{})
+	)
+	for _, a := range aliases {
+		alias, ok := a.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ip, _ := alias["ip"].(string)
+
+		names, _ := alias["hostnames"].([]interface{})
+		for _, n := range names {
+			targetIP := ip
+			if name, ok := n.(string); ok {
+				if rewritten, ok := rewrites[name]; ok {
+					targetIP = rewritten
+				}
+			}
+			if targetIP == "" {
+				continue
+			}
+
+			if _, ok := hostnames[targetIP]; !ok {
+				ips = append(ips, targetIP)
+			}
+			hostnames[targetIP] = append(hostnames[targetIP], n)
+		}
+	}
+
+	if len(ips) == 0 {
+		delete(podSpec, "hostAliases")
+		return
+	}
+
+	rewritten := make([]interface{}, 0, len(ips))
+	for _, ip := range ips {
+		rewritten = append(rewritten, map[string]interface{}{
+			"ip":        ip,
+			"hostnames": hostnames[ip],
+		})
+	}
+	podSpec["hostAliases"] = rewritten
+}
+
+// stripPriorityClass removes the priority class name from the pod spec if the provided
+// selector selects the item the pod spec belongs to. The resolved priority is removed
+// too, since admission rejects a priority that doesn't match the defaulted class.
+func stripPriorityClass(podSpec map[string]interface{}, obj *unstructured.Unstructured, labelSelector *metav1.LabelSelector) error {
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return errors.Wrap(err, "invalid label selector")
+	}
+	if !selector.Matches(labels.Set(obj.GetLabels())) {
+		return nil
+	}
+
+	delete(podSpec, "priorityClassName")
+	delete(podSpec, "priority")
+	return nil
+}
+
+// addTolerations appends the provided rule's tolerations to the pod spec if the rule
+// selects the item the pod spec belongs to. Tolerations the pod spec already has are
+// skipped.
+func addTolerations(podSpec map[string]interface{}, obj *unstructured.Unstructured, rule api.TolerationRule) error {
+	if rule.LabelSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(rule.LabelSelector)
+		if err != nil {
+			return errors.Wrap(err, "invalid label selector")
+		}
+		if !selector.Matches(labels.Set(obj.GetLabels())) {
+			return nil
+		}
+	}
+
+	tolerations, _, _ := unstructured.NestedSlice(podSpec, "tolerations")
+
+	var added bool
+	for i := range rule.Tolerations {
+		toleration, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&rule.Tolerations[i])
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		var exists bool
+		for _, existing := range tolerations {
+			if equality.Semantic.DeepEqual(existing, toleration) {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			tolerations = append(tolerations, toleration)
+			added = true
+		}
+	}
+
+	if !added {
+		return nil
+	}
+	return unstructured.SetNestedSlice(podSpec, tolerations, "tolerations")
+}
@@ -0,0 +1,440 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1api "k8s.io/api/apps/v1"
+	batchv1beta1api "k8s.io/api/batch/v1beta1"
+	corev1api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	velerov1api "github.com/heptio/velero/pkg/apis/velero/v1"
+	"github.com/heptio/velero/pkg/kuberesource"
+	velerotest "github.com/heptio/velero/pkg/util/test"
+)
+
+func TestGetPodSpec(t *testing.T) {
+	tests := []struct {
+		name          string
+		obj           runtime.Object
+		groupResource schema.GroupResource
+		wantFound     bool
+	}{
+		{
+			name:          "pod spec is found for a pod",
+			obj:           &corev1api.Pod{Spec: corev1api.PodSpec{NodeName: "node-1"}},
+			groupResource: kuberesource.Pods,
+			wantFound:     true,
+		},
+		{
+			name:          "pod spec is found for a deployment's template",
+			obj:           &appsv1api.Deployment{Spec: appsv1api.DeploymentSpec{Template: corev1api.PodTemplateSpec{Spec: corev1api.PodSpec{NodeName: "node-1"}}}},
+			groupResource: schema.GroupResource{Group: "apps", Resource: "deployments"},
+			wantFound:     true,
+		},
+		{
+			name: "pod spec is found for a cronjob's job template",
+			obj: &batchv1beta1api.CronJob{Spec: batchv1beta1api.CronJobSpec{
+				JobTemplate: batchv1beta1api.JobTemplateSpec{},
+			}},
+			groupResource: schema.GroupResource{Group: "batch", Resource: "cronjobs"},
+			wantFound:     true,
+		},
+		{
+			name:          "pod spec is not found for a resource that doesn't embed one",
+			obj:           &corev1api.ConfigMap{Data: map[string]string{"key": "val"}},
+			groupResource: schema.GroupResource{Group: "", Resource: "configmaps"},
+			wantFound:     false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(tc.obj)
+			require.NoError(t, err)
+
+			_, found := getPodSpec(&unstructured.Unstructured{Object: u}, tc.groupResource)
+			assert.Equal(t, tc.wantFound, found)
+		})
+	}
+}
+
+func TestTransformPodSpecImagePullPolicy(t *testing.T) {
+	deployment := func(policy corev1api.PullPolicy) *appsv1api.Deployment {
+		return &appsv1api.Deployment{
+			Spec: appsv1api.DeploymentSpec{
+				Template: corev1api.PodTemplateSpec{
+					Spec: corev1api.PodSpec{
+						InitContainers: []corev1api.Container{{Name: "init", ImagePullPolicy: policy}},
+						Containers: []corev1api.Container{
+							{Name: "container-1", ImagePullPolicy: policy},
+							{Name: "container-2", ImagePullPolicy: policy},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		restore  *velerov1api.Restore
+		obj      *appsv1api.Deployment
+		expected *appsv1api.Deployment
+	}{
+		{
+			name:     "Never is overridden to IfNotPresent on all containers of a deployment",
+			restore:  NewBuilder().ImagePullPolicyOverride(corev1api.PullIfNotPresent).Restore(),
+			obj:      deployment(corev1api.PullNever),
+			expected: deployment(corev1api.PullIfNotPresent),
+		},
+		{
+			name:     "pull policy is unchanged when no override is specified",
+			restore:  NewBuilder().Restore(),
+			obj:      deployment(corev1api.PullNever),
+			expected: deployment(corev1api.PullNever),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := &context{
+				restore: tc.restore,
+				log:     velerotest.NewLogger(),
+			}
+
+			u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(tc.obj)
+			require.NoError(t, err)
+			obj := &unstructured.Unstructured{Object: u}
+
+			ctx.transformPodSpec(obj, schema.GroupResource{Group: "apps", Resource: "deployments"})
+
+			res := new(appsv1api.Deployment)
+			require.NoError(t, runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, res))
+			assert.Equal(t, tc.expected, res)
+		})
+	}
+}
+
+func TestTransformPodSpecAddTolerations(t *testing.T) {
+	gpuToleration := corev1api.Toleration{Key: "gpu", Operator: corev1api.TolerationOpExists, Effect: corev1api.TaintEffectNoSchedule}
+	spotToleration := corev1api.Toleration{Key: "spot", Operator: corev1api.TolerationOpEqual, Value: "true", Effect: corev1api.TaintEffectNoExecute}
+
+	deployment := func(labels map[string]string, tolerations ...corev1api.Toleration) *appsv1api.Deployment {
+		return &appsv1api.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns-1", Name: "deploy-1", Labels: labels},
+			Spec: appsv1api.DeploymentSpec{
+				Template: corev1api.PodTemplateSpec{
+					Spec: corev1api.PodSpec{
+						Containers:  []corev1api.Container{{Name: "container-1"}},
+						Tolerations: tolerations,
+					},
+				},
+			},
+		}
+	}
+
+	gpuRule := velerov1api.TolerationRule{
+		LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "trainer"}},
+		Tolerations:   []corev1api.Toleration{gpuToleration},
+	}
+
+	tests := []struct {
+		name     string
+		rules    []velerov1api.TolerationRule
+		obj      *appsv1api.Deployment
+		expected *appsv1api.Deployment
+	}{
+		{
+			name:     "toleration is appended to a matching deployment",
+			rules:    []velerov1api.TolerationRule{gpuRule},
+			obj:      deployment(map[string]string{"app": "trainer"}, spotToleration),
+			expected: deployment(map[string]string{"app": "trainer"}, spotToleration, gpuToleration),
+		},
+		{
+			name:     "non-matching deployment is unchanged",
+			rules:    []velerov1api.TolerationRule{gpuRule},
+			obj:      deployment(map[string]string{"app": "web"}, spotToleration),
+			expected: deployment(map[string]string{"app": "web"}, spotToleration),
+		},
+		{
+			name:     "toleration that the deployment already has is not added again",
+			rules:    []velerov1api.TolerationRule{gpuRule},
+			obj:      deployment(map[string]string{"app": "trainer"}, gpuToleration),
+			expected: deployment(map[string]string{"app": "trainer"}, gpuToleration),
+		},
+		{
+			name:     "rule without a selector applies to all deployments",
+			rules:    []velerov1api.TolerationRule{{Tolerations: []corev1api.Toleration{spotToleration}}},
+			obj:      deployment(map[string]string{"app": "web"}),
+			expected: deployment(map[string]string{"app": "web"}, spotToleration),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := &context{
+				restore: NewBuilder().AddTolerations(tc.rules...).Restore(),
+				log:     velerotest.NewLogger(),
+			}
+
+			u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(tc.obj)
+			require.NoError(t, err)
+			obj := &unstructured.Unstructured{Object: u}
+
+			ctx.transformPodSpec(obj, schema.GroupResource{Group: "apps", Resource: "deployments"})
+
+			res := new(appsv1api.Deployment)
+			require.NoError(t, runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, res))
+			assert.Equal(t, tc.expected, res)
+		})
+	}
+}
+
+func TestTransformPodSpecStripPriorityClass(t *testing.T) {
+	priority := int32(1000)
+
+	deployment := func(labels map[string]string, priorityClassName string, priority *int32) *appsv1api.Deployment {
+		return &appsv1api.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns-1", Name: "deploy-1", Labels: labels},
+			Spec: appsv1api.DeploymentSpec{
+				Template: corev1api.PodTemplateSpec{
+					Spec: corev1api.PodSpec{
+						Containers:        []corev1api.Container{{Name: "container-1"}},
+						PriorityClassName: priorityClassName,
+						Priority:          priority,
+					},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		selector *metav1.LabelSelector
+		obj      *appsv1api.Deployment
+		expected *appsv1api.Deployment
+	}{
+		{
+			name:     "priority class is removed from a matching deployment",
+			selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			obj:      deployment(map[string]string{"app": "web"}, "high-priority", &priority),
+			expected: deployment(map[string]string{"app": "web"}, "", nil),
+		},
+		{
+			name:     "non-matching deployment is unchanged",
+			selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			obj:      deployment(map[string]string{"app": "db"}, "high-priority", &priority),
+			expected: deployment(map[string]string{"app": "db"}, "high-priority", &priority),
+		},
+		{
+			name:     "empty selector removes the priority class from all deployments",
+			selector: &metav1.LabelSelector{},
+			obj:      deployment(map[string]string{"app": "db"}, "high-priority", &priority),
+			expected: deployment(map[string]string{"app": "db"}, "", nil),
+		},
+		{
+			name:     "nil selector leaves the priority class unchanged",
+			obj:      deployment(map[string]string{"app": "web"}, "high-priority", &priority),
+			expected: deployment(map[string]string{"app": "web"}, "high-priority", &priority),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := &context{
+				restore: NewBuilder().StripPriorityClass(tc.selector).Restore(),
+				log:     velerotest.NewLogger(),
+			}
+
+			u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(tc.obj)
+			require.NoError(t, err)
+			obj := &unstructured.Unstructured{Object: u}
+
+			ctx.transformPodSpec(obj, schema.GroupResource{Group: "apps", Resource: "deployments"})
+
+			res := new(appsv1api.Deployment)
+			require.NoError(t, runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, res))
+			assert.Equal(t, tc.expected, res)
+		})
+	}
+}
+
+func TestTransformPodSpecSchedulerName(t *testing.T) {
+	deployment := func(schedulerName string) *appsv1api.Deployment {
+		return &appsv1api.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns-1", Name: "deploy-1"},
+			Spec: appsv1api.DeploymentSpec{
+				Template: corev1api.PodTemplateSpec{
+					Spec: corev1api.PodSpec{
+						Containers:    []corev1api.Container{{Name: "container-1"}},
+						SchedulerName: schedulerName,
+					},
+				},
+			},
+		}
+	}
+
+	pod := func(schedulerName string) *corev1api.Pod {
+		return &corev1api.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns-1", Name: "pod-1"},
+			Spec: corev1api.PodSpec{
+				Containers:    []corev1api.Container{{Name: "container-1"}},
+				SchedulerName: schedulerName,
+			},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		restore       *velerov1api.Restore
+		groupResource schema.GroupResource
+		obj           runtime.Object
+		expected      runtime.Object
+	}{
+		{
+			name:          "scheduler name is set on a deployment's pod template",
+			restore:       NewBuilder().SchedulerName("volcano").Restore(),
+			groupResource: schema.GroupResource{Group: "apps", Resource: "deployments"},
+			obj:           deployment("default-scheduler"),
+			expected:      deployment("volcano"),
+		},
+		{
+			name:          "scheduler name is set on a bare pod",
+			restore:       NewBuilder().SchedulerName("volcano").Restore(),
+			groupResource: schema.GroupResource{Group: "", Resource: "pods"},
+			obj:           pod(""),
+			expected:      pod("volcano"),
+		},
+		{
+			name:          "scheduler name is unchanged when the restore doesn't specify one",
+			restore:       NewBuilder().Restore(),
+			groupResource: schema.GroupResource{Group: "", Resource: "pods"},
+			obj:           pod("default-scheduler"),
+			expected:      pod("default-scheduler"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := &context{
+				restore: tc.restore,
+				log:     velerotest.NewLogger(),
+			}
+
+			u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(tc.obj)
+			require.NoError(t, err)
+			obj := &unstructured.Unstructured{Object: u}
+
+			ctx.transformPodSpec(obj, tc.groupResource)
+
+			res := reflect.New(reflect.TypeOf(tc.expected).Elem()).Interface()
+			require.NoError(t, runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, res))
+			assert.Equal(t, tc.expected, res)
+		})
+	}
+}
+
+func TestTransformPodSpecHostAliasRewrites(t *testing.T) {
+	deployment := func(hostAliases ...corev1api.HostAlias) *appsv1api.Deployment {
+		return &appsv1api.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns-1", Name: "deploy-1"},
+			Spec: appsv1api.DeploymentSpec{
+				Template: corev1api.PodTemplateSpec{
+					Spec: corev1api.PodSpec{
+						Containers:  []corev1api.Container{{Name: "container-1"}},
+						HostAliases: hostAliases,
+					},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		restore  *velerov1api.Restore
+		obj      *appsv1api.Deployment
+		expected *appsv1api.Deployment
+	}{
+		{
+			name:     "a host alias's IP is remapped",
+			restore:  NewBuilder().HostAliasRewrites("db.internal", "10.1.0.5").Restore(),
+			obj:      deployment(corev1api.HostAlias{IP: "10.0.0.5", Hostnames: []string{"db.internal"}}),
+			expected: deployment(corev1api.HostAlias{IP: "10.1.0.5", Hostnames: []string{"db.internal"}}),
+		},
+		{
+			name:    "an obsolete hostname is dropped along with its emptied alias",
+			restore: NewBuilder().HostAliasRewrites("legacy.internal", "").Restore(),
+			obj: deployment(
+				corev1api.HostAlias{IP: "10.0.0.5", Hostnames: []string{"db.internal"}},
+				corev1api.HostAlias{IP: "10.0.0.9", Hostnames: []string{"legacy.internal"}},
+			),
+			expected: deployment(corev1api.HostAlias{IP: "10.0.0.5", Hostnames: []string{"db.internal"}}),
+		},
+		{
+			name:    "a remapped hostname moves to the alias for its new IP while the others keep their IP",
+			restore: NewBuilder().HostAliasRewrites("cache.internal", "10.1.0.7", "legacy.internal", "").Restore(),
+			obj: deployment(
+				corev1api.HostAlias{IP: "10.0.0.5", Hostnames: []string{"db.internal", "cache.internal", "legacy.internal"}},
+				corev1api.HostAlias{IP: "10.1.0.7", Hostnames: []string{"queue.internal"}},
+			),
+			expected: deployment(
+				corev1api.HostAlias{IP: "10.0.0.5", Hostnames: []string{"db.internal"}},
+				corev1api.HostAlias{IP: "10.1.0.7", Hostnames: []string{"cache.internal", "queue.internal"}},
+			),
+		},
+		{
+			name:     "host aliases are removed when all of their hostnames are dropped",
+			restore:  NewBuilder().HostAliasRewrites("legacy.internal", "").Restore(),
+			obj:      deployment(corev1api.HostAlias{IP: "10.0.0.9", Hostnames: []string{"legacy.internal"}}),
+			expected: deployment(),
+		},
+		{
+			name:     "host aliases without rewrites are unchanged",
+			restore:  NewBuilder().HostAliasRewrites("other.internal", "10.1.0.1").Restore(),
+			obj:      deployment(corev1api.HostAlias{IP: "10.0.0.5", Hostnames: []string{"db.internal"}}),
+			expected: deployment(corev1api.HostAlias{IP: "10.0.0.5", Hostnames: []string{"db.internal"}}),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := &context{
+				restore: tc.restore,
+				log:     velerotest.NewLogger(),
+			}
+
+			u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(tc.obj)
+			require.NoError(t, err)
+			obj := &unstructured.Unstructured{Object: u}
+
+			ctx.transformPodSpec(obj, schema.GroupResource{Group: "apps", Resource: "deployments"})
+
+			res := new(appsv1api.Deployment)
+			require.NoError(t, runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, res))
+			assert.Equal(t, tc.expected, res)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	api "github.com/heptio/velero/pkg/apis/velero/v1"
+	"github.com/heptio/velero/pkg/kuberesource"
+)
+
+// PreRestoreBackupper backs up the current state of the namespaces a restore is
+// about to restore into, so that the restore can be reverted.
+type PreRestoreBackupper interface {
+	// BackupNamespaces backs up the provided namespaces on behalf of the
+	// provided restore, returning once the backup has completed.
+	BackupNamespaces(restore *api.Restore, namespaces []string) error
+}
+
+// restoreTargetNamespaces returns the sorted names of the namespaces that the restore
+// restores at least one item into.
+func (ctx *context) restoreTargetNamespaces(resourcesDir string, resourceDirs map[string]os.FileInfo) ([]string, error) {
+	if ctx.scopeFilter() == api.ScopeFilterClusterOnly {
+		return nil, nil
+	}
+
+	namespaces := sets.NewString()
+
+	for _, resource := range ctx.prioritizedResources {
+		rscDir := ctx.backupResourceDir(resource, resourceDirs)
+		if resource == kuberesource.Namespaces || rscDir == nil || ctx.excludedByScope(resource) {
+			continue
+		}
+
+		nsSubDir := filepath.Join(resourcesDir, rscDir.Name(), api.NamespaceScopedDir)
+		exists, err := ctx.fileSystem.DirExists(nsSubDir)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			continue
+		}
+
+		nsDirs, err := ctx.fileSystem.ReadDir(nsSubDir)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, nsDir := range nsDirs {
+			if !nsDir.IsDir() || !ctx.namespaceIncludesExcludes.ShouldInclude(nsDir.Name()) {
+				continue
+			}
+
+			nsItems, err := ctx.getItemSource().ListItems(schema.ParseGroupResource(rscDir.Name()), nsDir.Name())
+			if err != nil {
+				return nil, err
+			}
+			if len(nsItems) > 0 {
+				namespaces.Insert(ctx.targetNamespaces(nsDir.Name())...)
+			}
+		}
+	}
+
+	return namespaces.List(), nil
+}
+
+// backupTargetNamespaces backs up the namespaces the restore restores into with the
+// restorer's pre-restore backupper, if the restore asks for it. It's a no-op for a
+// dry run, since nothing in the cluster is changed.
+func (ctx *context) backupTargetNamespaces(resourcesDir string, resourceDirs map[string]os.FileInfo) error {
+	if !ctx.restore.Spec.PreRestoreBackup || ctx.dryRun {
+		return nil
+	}
+
+	if ctx.preRestoreBackupper == nil {
+		return errors.New("restore requests a pre-restore backup, but no pre-restore backupper is configured")
+	}
+
+	namespaces, err := ctx.restoreTargetNamespaces(resourcesDir, resourceDirs)
+	if err != nil {
+		return err
+	}
+	if len(namespaces) == 0 {
+		ctx.log.Info("Skipping pre-restore backup because the restore doesn't restore into any namespaces")
+		return nil
+	}
+
+	ctx.log.Infof("Backing up namespaces %v before restoring into them", namespaces)
+	if err := ctx.preRestoreBackupper.BackupNamespaces(ctx.restore, namespaces); err != nil {
+		return errors.Wrap(err, "error backing up target namespaces before restoring")
+	}
+
+	return nil
+}
@@ -0,0 +1,199 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	api "github.com/heptio/velero/pkg/apis/velero/v1"
+	"github.com/heptio/velero/pkg/kuberesource"
+)
+
+// progressUpdateInterval is how often the progress of a running restore is
+// sent to the restorer's progress updater.
+var progressUpdateInterval = 10 * time.Second
+
+// ProgressUpdater records the progress of running restores, e.g. in their status.
+type ProgressUpdater interface {
+	// UpdateProgress records the current progress of the provided restore.
+	UpdateProgress(restore *api.Restore, progress api.RestoreProgress) error
+}
+
+// progressTracker counts the outcomes of a restore as it runs. It's safe for
+// concurrent use.
+type progressTracker struct {
+	lock     sync.Mutex
+	progress api.RestoreProgress
+}
+
+// get returns the current progress.
+func (t *progressTracker) get() api.RestoreProgress {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.progress
+}
+
+// setTotalItems sets the number of items the restore is expected to process.
+func (t *progressTracker) setTotalItems(total int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.progress.TotalItems = total
+}
+
+// recordOutcome counts an item with the provided outcome.
+func (t *progressTracker) recordOutcome(outcome ItemOutcome) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	switch outcome {
+	case ItemOutcomeCreated, ItemOutcomeUpdated:
+		t.progress.ItemsRestored++
+	case ItemOutcomeSkipped:
+		t.progress.ItemsSkipped++
+	}
+}
+
+// addWarnings counts the provided number of warnings.
+func (t *progressTracker) addWarnings(count int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.progress.Warnings += count
+}
+
+// countMessages returns the total number of messages in the provided result.
+func countMessages(r Result) int {
+	count := len(r.Velero) + len(r.Cluster)
+	for _, messages := range r.Namespaces {
+		count += len(messages)
+	}
+	return count
+}
+
+// startProgressUpdates sends the progress of the provided restore to the restorer's
+// progress updater every progressUpdateInterval until the returned func is called,
+// which sends the final progress. It's a no-op if the restorer has no progress updater.
+func (kr *kubernetesRestorer) startProgressUpdates(restore *api.Restore, tracker *progressTracker, log logrus.FieldLogger) (stop func()) {
+	if kr.progressUpdater == nil {
+		return func() {}
+	}
+
+	update := func() {
+		if err := kr.progressUpdater.UpdateProgress(restore, tracker.get()); err != nil {
+			log.WithError(err).Warn("Error updating restore progress")
+		}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(progressUpdateInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				update()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+		update()
+	}
+}
+
+// targetNamespaces returns the namespaces that items in the provided namespace in the
+// backup are restored into, according to the restore's namespace fanout and mapping.
+func (ctx *context) targetNamespaces(namespace string) []string {
+	if targets, ok := ctx.restore.Spec.NamespaceFanout[namespace]; ok {
+		return targets
+	}
+	if target, ok := ctx.restore.Spec.NamespaceMapping[namespace]; ok {
+		return []string{target}
+	}
+	return []string{namespace}
+}
+
+// countItems returns the number of items in the backup's resource directories that the
+// restore is expected to process: the item files of each of the restore's prioritized
+// resources, in the namespaces it includes, once for each namespace they're restored into.
+func (ctx *context) countItems(resourcesDir string, resourceDirs map[string]os.FileInfo) (int, error) {
+	var total int
+
+	for _, resource := range ctx.prioritizedResources {
+		rscDir := ctx.backupResourceDir(resource, resourceDirs)
+		if resource == kuberesource.Namespaces || rscDir == nil || ctx.excludedByScope(resource) {
+			continue
+		}
+
+		resourcePath := filepath.Join(resourcesDir, rscDir.Name())
+		sourceResource := schema.ParseGroupResource(rscDir.Name())
+
+		clusterItems, err := ctx.getItemSource().ListItems(sourceResource, "")
+		if err != nil {
+			return 0, err
+		}
+		total += len(clusterItems)
+
+		if ctx.restore.Spec.ClusterScopedOnly {
+			continue
+		}
+
+		nsSubDir := filepath.Join(resourcePath, api.NamespaceScopedDir)
+		exists, err := ctx.fileSystem.DirExists(nsSubDir)
+		if err != nil {
+			return 0, err
+		}
+		if !exists {
+			continue
+		}
+
+		nsDirs, err := ctx.fileSystem.ReadDir(nsSubDir)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, nsDir := range nsDirs {
+			if !nsDir.IsDir() || !ctx.namespaceIncludesExcludes.ShouldInclude(nsDir.Name()) {
+				continue
+			}
+
+			nsItems, err := ctx.getItemSource().ListItems(sourceResource, nsDir.Name())
+			if err != nil {
+				return 0, err
+			}
+			total += len(nsItems) * len(ctx.targetNamespaces(nsDir.Name()))
+		}
+	}
+
+	return total, nil
+}
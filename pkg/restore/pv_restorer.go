@@ -39,6 +39,8 @@ type pvRestorer struct {
 	volumeSnapshots         []*volume.Snapshot
 	volumeSnapshotterGetter VolumeSnapshotterGetter
 	snapshotLocationLister  listers.VolumeSnapshotLocationLister
+	dryRun                  bool
+	metrics                 *restoreMetrics
 }
 
 func (r *pvRestorer) executePVAction(obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
@@ -82,6 +84,11 @@ func (r *pvRestorer) executePVAction(obj *unstructured.Unstructured) (*unstructu
 		return obj, nil
 	}
 
+	if r.dryRun {
+		log.WithField("providerSnapshotID", snapshotInfo.providerSnapshotID).Info("Dry run: not restoring persistent volume from snapshot")
+		return obj, nil
+	}
+
 	volumeSnapshotter, err := r.volumeSnapshotterGetter.GetVolumeSnapshotter(snapshotInfo.location.Spec.Provider)
 	if err != nil {
 		return nil, errors.WithStack(err)
@@ -97,6 +104,7 @@ func (r *pvRestorer) executePVAction(obj *unstructured.Unstructured) (*unstructu
 	}
 
 	log.WithField("providerSnapshotID", snapshotInfo.providerSnapshotID).Info("successfully restored persistent volume from snapshot")
+	r.metrics.observeVolume(obj)
 
 	updated1, err := volumeSnapshotter.SetVolumeID(obj, volumeID)
 	if err != nil {
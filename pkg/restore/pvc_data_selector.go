@@ -0,0 +1,62 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/heptio/velero/pkg/kuberesource"
+	"github.com/heptio/velero/pkg/restic"
+)
+
+// selectPodVolumeData removes the restic snapshot annotations of the provided pod's
+// volumes whose persistent volume claims don't match the restore's PVC data selector,
+// so their data isn't restored and they're restored empty. Claims are matched by their
+// labels in the backup, and a claim that isn't in the backup doesn't match. Volumes
+// that aren't backed by a claim keep their annotations.
+func (ctx *context) selectPodVolumeData(obj *unstructured.Unstructured) {
+	if ctx.pvcDataSelector == nil || len(restic.GetPodSnapshotAnnotations(obj)) == 0 {
+		return
+	}
+
+	volumes, _, _ := unstructured.NestedSlice(obj.Object, "spec", "volumes")
+	forEachMap(volumes, func(volume map[string]interface{}) {
+		volumeName, _ := volume["name"].(string)
+		claimName, found, _ := unstructured.NestedString(volume, "persistentVolumeClaim", "claimName")
+		if !found || ctx.matchesPVCDataSelector(obj.GetNamespace(), claimName) {
+			return
+		}
+		if _, ok := restic.GetPodSnapshotAnnotations(obj)[volumeName]; !ok {
+			return
+		}
+
+		ctx.log.Infof("Not restoring data of volume %s of pod %s/%s because its claim %s doesn't match the restore's PVC data selector", volumeName, obj.GetNamespace(), obj.GetName(), claimName)
+		restic.RemovePodSnapshotAnnotation(obj, volumeName)
+	})
+}
+
+// matchesPVCDataSelector returns true if the specified persistent volume claim is
+// in the backup and its labels match the restore's PVC data selector.
+func (ctx *context) matchesPVCDataSelector(namespace, name string) bool {
+	pvc, err := ctx.readItem(kuberesource.PersistentVolumeClaims, namespace, name)
+	if err != nil {
+		return false
+	}
+
+	return ctx.pvcDataSelector.Matches(labels.Set(pvc.GetLabels()))
+}
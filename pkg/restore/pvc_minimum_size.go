@@ -0,0 +1,62 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// enforcePVCMinimumSize increases the storage request of the provided persistent
+// volume claim to the restore's minimum size if it requests less. A warning is
+// returned if the request is increased. Claims that are bound to a restored volume
+// are left unchanged, since they can't request more than the volume's capacity.
+func (ctx *context) enforcePVCMinimumSize(obj *unstructured.Unstructured) ([]error, error) {
+	minimum := ctx.restore.Spec.PVCMinimumSize
+	if minimum == nil {
+		return nil, nil
+	}
+
+	if volumeName, _, _ := unstructured.NestedString(obj.Object, "spec", "volumeName"); volumeName != "" {
+		return nil, nil
+	}
+
+	requested, found, err := unstructured.NestedString(obj.Object, "spec", "resources", "requests", "storage")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if !found {
+		return nil, nil
+	}
+
+	size, err := resource.ParseQuantity(requested)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parsing storage request of persistent volume claim %s/%s", obj.GetNamespace(), obj.GetName())
+	}
+	if size.Cmp(*minimum) >= 0 {
+		return nil, nil
+	}
+
+	ctx.log.Infof("Increasing storage request of persistent volume claim %s/%s from %s to %s", obj.GetNamespace(), obj.GetName(), requested, minimum.String())
+
+	if err := unstructured.SetNestedField(obj.Object, minimum.String(), "spec", "resources", "requests", "storage"); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return []error{errors.Errorf("increased storage request of persistent volume claim %s/%s from %s to the restore's minimum size of %s", obj.GetNamespace(), obj.GetName(), requested, minimum.String())}, nil
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/heptio/velero/pkg/kuberesource"
+	"github.com/heptio/velero/pkg/plugin/velero"
+	"github.com/heptio/velero/pkg/util/kube"
+)
+
+// recordRestoredBinding keeps track of the provided item, as created by the restore, if
+// it's a role binding or cluster role binding whose references are to be validated.
+func (ctx *context) recordRestoredBinding(obj *unstructured.Unstructured, groupResource schema.GroupResource) {
+	if !ctx.restore.Spec.ValidateRBACReferences {
+		return
+	}
+
+	if groupResource == kuberesource.RoleBindings || groupResource == kuberesource.ClusterRoleBindings {
+		ctx.restoredBindings = append(ctx.restoredBindings, obj)
+	}
+}
+
+// bindingReferences returns the role and service accounts referenced by the provided
+// role binding or cluster role binding. Subjects other than service accounts, e.g.
+// users and groups, aren't API objects, so they're not returned.
+func bindingReferences(binding *unstructured.Unstructured) []velero.ResourceIdentifier {
+	var refs []velero.ResourceIdentifier
+
+	kind, _, _ := unstructured.NestedString(binding.Object, "roleRef", "kind")
+	name, _, _ := unstructured.NestedString(binding.Object, "roleRef", "name")
+	switch kind {
+	case "Role":
+		refs = append(refs, velero.ResourceIdentifier{GroupResource: kuberesource.Roles, Namespace: binding.GetNamespace(), Name: name})
+	case "ClusterRole":
+		refs = append(refs, velero.ResourceIdentifier{GroupResource: kuberesource.ClusterRoles, Name: name})
+	}
+
+	subjects, _, _ := unstructured.NestedSlice(binding.Object, "subjects")
+	for _, s := range subjects {
+		subject, ok := s.(map[string]interface{})
+		if !ok || subject["kind"] != "ServiceAccount" {
+			continue
+		}
+
+		namespace, _ := subject["namespace"].(string)
+		if namespace == "" {
+			namespace = binding.GetNamespace()
+		}
+		name, _ := subject["name"].(string)
+
+		refs = append(refs, velero.ResourceIdentifier{GroupResource: kuberesource.ServiceAccounts, Namespace: namespace, Name: name})
+	}
+
+	return refs
+}
+
+// checkRBACReferences returns a warning for each role or service account referenced
+// by a restored role binding or cluster role binding that the restore didn't create or
+// update and that doesn't exist in the cluster.
+func (ctx *context) checkRBACReferences() Result {
+	warnings := Result{}
+
+	restored := sets.NewString()
+	for _, res := range ctx.itemResults {
+		if res.Outcome == ItemOutcomeCreated || res.Outcome == ItemOutcomeUpdated || res.Outcome == ItemOutcomeDryRun {
+			restored.Insert(getResourceID(schema.ParseGroupResource(res.GroupResource), res.Namespace, res.Name))
+		}
+	}
+
+	for _, binding := range ctx.restoredBindings {
+		for _, ref := range bindingReferences(binding) {
+			if restored.Has(getResourceID(ref.GroupResource, ref.Namespace, ref.Name)) {
+				continue
+			}
+
+			exists, err := ctx.existsInCluster(ref)
+			if err != nil {
+				addToResult(&warnings, binding.GetNamespace(), errors.Wrapf(err, "error checking reference of %s to %s", kube.NamespaceAndName(binding), getResourceID(ref.GroupResource, ref.Namespace, ref.Name)))
+				continue
+			}
+			if !exists {
+				addToResult(&warnings, binding.GetNamespace(), errors.Errorf("%s references %s, which doesn't exist", kube.NamespaceAndName(binding), getResourceID(ref.GroupResource, ref.Namespace, ref.Name)))
+			}
+		}
+	}
+
+	return warnings
+}
+
+// existsInCluster returns true if the specified item exists in the cluster.
+func (ctx *context) existsInCluster(id velero.ResourceIdentifier) (bool, error) {
+	if _, err := ctx.getFromCluster(id); err != nil {
+		if apierrors.IsNotFound(errors.Cause(err)) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// getFromCluster returns the specified item from the cluster.
+func (ctx *context) getFromCluster(id velero.ResourceIdentifier) (*unstructured.Unstructured, error) {
+	gvr, apiResource, err := ctx.discoveryHelper.ResourceFor(id.GroupResource.WithVersion(""))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	resourceClient, err := ctx.dynamicFactory.ClientForGroupVersionResource(gvr.GroupVersion(), apiResource, id.Namespace)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	obj, err := resourceClient.Get(id.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return obj, nil
+}
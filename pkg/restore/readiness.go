@@ -0,0 +1,181 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/heptio/velero/pkg/client"
+)
+
+// defaultReadinessTimeout is how long a restore waits for restored
+// resources to become ready if the restore doesn't specify a timeout.
+const defaultReadinessTimeout = 10 * time.Minute
+
+// readinessPollInterval is how often the restore re-checks resources
+// it's waiting on. It's a variable so tests can shorten it.
+var readinessPollInterval = time.Second
+
+// serviceReference identifies a service by namespace and name.
+type serviceReference struct {
+	namespace string
+	name      string
+}
+
+// readinessTimeout returns the restore's readiness timeout, or the default
+// if one isn't specified.
+func (ctx *context) readinessTimeout() time.Duration {
+	if ctx.restore.Spec.ReadinessTimeout.Duration > 0 {
+		return ctx.restore.Spec.ReadinessTimeout.Duration
+	}
+	return defaultReadinessTimeout
+}
+
+// poll calls condition every readiness poll interval, starting immediately, until it
+// returns true or an error, or until the provided timeout passes, in which case the
+// error says how long it waited for the provided description of what's waited for.
+func poll(timeout time.Duration, waitingFor string, condition wait.ConditionFunc) error {
+	err := wait.PollImmediate(readinessPollInterval, timeout, condition)
+	if err == wait.ErrWaitTimeout {
+		return errors.Errorf("timed out after %v waiting for %s", timeout, waitingFor)
+	}
+	return err
+}
+
+// pollItem polls the named item with the provided client until check returns true for
+// it, or until the provided timeout passes, as described by poll. An item that isn't
+// found is waited for, and other errors getting it end the wait.
+func pollItem(itemClient client.Dynamic, name string, timeout time.Duration, waitingFor string, log logrus.FieldLogger, check readinessCheck) error {
+	return poll(timeout, waitingFor, func() (bool, error) {
+		obj, err := itemClient.Get(name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			log.Debug("Item not found, waiting")
+			return false, nil
+		}
+		if err != nil {
+			return false, errors.WithStack(err)
+		}
+
+		return check(obj), nil
+	})
+}
+
+// hasTrueCondition returns true if the provided item has a status condition of the
+// specified type whose status is True.
+func hasTrueCondition(obj *unstructured.Unstructured, conditionType string) bool {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, condition := range conditions {
+		conditionMap, ok := condition.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if conditionMap["type"] == conditionType && conditionMap["status"] == "True" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getConversionWebhookService returns a reference to the service backing a custom
+// resource definition's conversion webhook, and whether one was found. Both the
+// apiextensions.k8s.io/v1beta1 (spec.conversion.webhookClientConfig) and v1
+// (spec.conversion.webhook.clientConfig) layouts are supported.
+func getConversionWebhookService(crd *unstructured.Unstructured) (serviceReference, bool) {
+	strategy, _, _ := unstructured.NestedString(crd.Object, "spec", "conversion", "strategy")
+	if strategy != "Webhook" {
+		return serviceReference{}, false
+	}
+
+	service, found, err := unstructured.NestedMap(crd.Object, "spec", "conversion", "webhookClientConfig", "service")
+	if err != nil || !found {
+		service, found, err = unstructured.NestedMap(crd.Object, "spec", "conversion", "webhook", "clientConfig", "service")
+	}
+	if err != nil || !found {
+		return serviceReference{}, false
+	}
+
+	ref := serviceReference{}
+	ref.namespace, _, _ = unstructured.NestedString(service, "namespace")
+	ref.name, _, _ = unstructured.NestedString(service, "name")
+	if ref.namespace == "" || ref.name == "" {
+		return serviceReference{}, false
+	}
+
+	return ref, true
+}
+
+// waitForConversionWebhooks waits for each service backing the conversion webhook
+// of a restored custom resource definition to have at least one ready endpoint, so
+// that instances of the definitions can be served when they're restored. Services that
+// aren't ready within the readiness timeout are returned as cluster-scoped warnings.
+func (ctx *context) waitForConversionWebhooks() Result {
+	warnings := Result{}
+
+	for _, svc := range ctx.conversionWebhookServices {
+		log := ctx.log.WithField("service", svc.namespace+"/"+svc.name)
+		log.Info("Waiting for CRD conversion webhook service to have ready endpoints")
+
+		endpointsClient, err := ctx.dynamicFactory.ClientForGroupVersionResource(
+			schema.GroupVersion{Group: "", Version: "v1"},
+			metav1.APIResource{Name: "endpoints", Namespaced: true},
+			svc.namespace,
+		)
+		if err != nil {
+			addToResult(&warnings, "", errors.Wrapf(err, "error getting endpoints client for CRD conversion webhook service %s/%s", svc.namespace, svc.name))
+			continue
+		}
+
+		err = pollItem(endpointsClient, svc.name, ctx.readinessTimeout(), "ready endpoints", log, hasReadyEndpoints)
+		if err != nil {
+			addToResult(&warnings, "", errors.Wrapf(err, "CRD conversion webhook service %s/%s is not ready", svc.namespace, svc.name))
+			continue
+		}
+
+		log.Info("CRD conversion webhook service is ready")
+	}
+
+	return warnings
+}
+
+// hasReadyEndpoints returns true if any of the provided endpoints object's
+// subsets contain a ready address.
+func hasReadyEndpoints(endpoints *unstructured.Unstructured) bool {
+	subsets, _, _ := unstructured.NestedSlice(endpoints.Object, "subsets")
+	for _, subset := range subsets {
+		subsetMap, ok := subset.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		addresses, _, _ := unstructured.NestedSlice(subsetMap, "addresses")
+		if len(addresses) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,240 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	api "github.com/heptio/velero/pkg/apis/velero/v1"
+	"github.com/heptio/velero/pkg/util/kube"
+)
+
+// ReplayWriter stores the items a restore creates, as they were created, so that a
+// later restore can replay them without reading the backup.
+type ReplayWriter interface {
+	// PutReplayItem stores the contents of an item at the provided path.
+	PutReplayItem(path string, contents io.Reader) error
+
+	// Close releases the writer's resources once the restore is done.
+	Close()
+}
+
+// ReplayWriterFactory can construct replay writers.
+type ReplayWriterFactory interface {
+	// NewReplayWriter returns a replay writer for use during a single
+	// Velero restore.
+	NewReplayWriter(restore *api.Restore) (ReplayWriter, error)
+}
+
+// ReplayReader reads the items a restore stored with a ReplayWriter.
+type ReplayReader interface {
+	// ListReplayItems returns the paths of the stored items.
+	ListReplayItems() ([]string, error)
+
+	// GetReplayItem returns the contents of the item stored at the provided path.
+	GetReplayItem(path string) (io.ReadCloser, error)
+}
+
+// replayItemPath returns the path an item is stored at in a replay. Paths follow
+// the layout of the backup tarball's resources directory.
+func replayItemPath(groupResource schema.GroupResource, namespace, name string) string {
+	if namespace == "" {
+		return path.Join(groupResource.String(), api.ClusterScopedDir, name+".json")
+	}
+	return path.Join(groupResource.String(), api.NamespaceScopedDir, namespace, name+".json")
+}
+
+// parseReplayItemPath returns the group resource and namespace of the item stored at
+// the provided path in a replay.
+func parseReplayItemPath(itemPath string) (schema.GroupResource, string, error) {
+	parts := strings.Split(itemPath, "/")
+
+	switch {
+	case len(parts) == 3 && parts[1] == api.ClusterScopedDir:
+		return schema.ParseGroupResource(parts[0]), "", nil
+	case len(parts) == 4 && parts[1] == api.NamespaceScopedDir:
+		return schema.ParseGroupResource(parts[0]), parts[2], nil
+	default:
+		return schema.GroupResource{}, "", errors.Errorf("invalid replay item path %s", itemPath)
+	}
+}
+
+// captureReplayItem stores the provided item, which the restore created with the
+// provided name, with the restore's replay writer. Items created with a generated name
+// are stored under the name they were given, so a replay re-creates the same item
+// instead of another one. It's a no-op if the restore doesn't capture a replay.
+func (ctx *context) captureReplayItem(obj *unstructured.Unstructured, groupResource schema.GroupResource, namespace, name string) error {
+	if ctx.replayWriter == nil {
+		return nil
+	}
+
+	if obj.GetName() == "" {
+		obj = obj.DeepCopy()
+		obj.SetName(name)
+		obj.SetGenerateName("")
+	}
+
+	contents, err := json.Marshal(obj)
+	if err != nil {
+		return errors.Wrapf(err, "error encoding %s for the restore's replay", getResourceID(groupResource, namespace, name))
+	}
+
+	var (
+		writer   = ctx.replayWriter
+		itemPath = replayItemPath(groupResource, namespace, name)
+	)
+	ctx.withoutItemLock(func() { err = writer.PutReplayItem(itemPath, bytes.NewReader(contents)) })
+	if err != nil {
+		return errors.Wrapf(err, "error storing %s for the restore's replay", getResourceID(groupResource, namespace, name))
+	}
+
+	return nil
+}
+
+// Replay re-creates the items stored in the provided replay, ordered by the restorer's
+// resource priorities, without transforming them or running restore item actions.
+func (kr *kubernetesRestorer) Replay(log logrus.FieldLogger, restore *api.Restore, replay ReplayReader) (Result, Result, ItemResults) {
+	warnings, errs := Result{}, Result{}
+
+	itemPaths, err := replay.ListReplayItems()
+	if err != nil {
+		addVeleroError(&errs, errors.Wrap(err, "error listing replay items"))
+		return warnings, errs, nil
+	}
+
+	resourcePriorities := getResourcePriorities(kr.resourcePriorities, restore.Spec.ResourcePriorities)
+	prioritizedResources, err := prioritizeResources(kr.discoveryHelper, resourcePriorities, restore.Spec.LowPriorityResources, getResourceIncludesExcludes(kr.discoveryHelper, nil, nil), restore.Spec.StrictResourcePriorities, log)
+	if err != nil {
+		addVeleroError(&errs, err)
+		return warnings, errs, nil
+	}
+	priorities := make(map[schema.GroupResource]int, len(prioritizedResources))
+	for i, resource := range prioritizedResources {
+		priorities[resource] = i
+	}
+
+	priority := func(itemPath string) int {
+		groupResource, _, _ := parseReplayItemPath(itemPath)
+		if p, ok := priorities[groupResource]; ok {
+			return p
+		}
+		return len(priorities)
+	}
+	sort.SliceStable(itemPaths, func(i, j int) bool { return priority(itemPaths[i]) < priority(itemPaths[j]) })
+
+	var (
+		itemResults        ItemResults
+		existingNamespaces = sets.NewString()
+	)
+	record := func(groupResource schema.GroupResource, namespace, name string, outcome ItemOutcome, action ItemAction, reason string) {
+		itemResults = append(itemResults, ItemResult{
+			GroupResource: groupResource.String(),
+			Namespace:     namespace,
+			Name:          name,
+			Outcome:       outcome,
+			Action:        action,
+			Reason:        reason,
+		})
+	}
+
+	for _, itemPath := range itemPaths {
+		groupResource, namespace, err := parseReplayItemPath(itemPath)
+		if err != nil {
+			addVeleroError(&errs, err)
+			continue
+		}
+		name := strings.TrimSuffix(path.Base(itemPath), ".json")
+
+		obj, err := readReplayItem(replay, itemPath)
+		if err != nil {
+			addToResult(&errs, namespace, err)
+			record(groupResource, namespace, name, ItemOutcomeFailed, ItemActionFailed, err.Error())
+			continue
+		}
+
+		if namespace != "" && !existingNamespaces.Has(namespace) {
+			ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}
+			if _, _, err := kube.EnsureNamespaceExistsAndIsReady(ns, kr.namespaceClient, kr.resourceTerminatingTimeout); err != nil {
+				addVeleroError(&errs, errors.Wrapf(err, "error ensuring namespace %s exists", namespace))
+				continue
+			}
+			existingNamespaces.Insert(namespace)
+		}
+
+		resourceClient, err := kr.dynamicFactory.ClientForGroupVersionResource(
+			obj.GroupVersionKind().GroupVersion(),
+			metav1.APIResource{Name: groupResource.Resource, Namespaced: namespace != ""},
+			namespace,
+		)
+		if err != nil {
+			addToResult(&errs, namespace, errors.Wrapf(err, "error getting client for %s", groupResource))
+			record(groupResource, namespace, name, ItemOutcomeFailed, ItemActionFailed, err.Error())
+			continue
+		}
+
+		log.Infof("Replaying %s %s", groupResource, kube.NamespaceAndName(obj))
+		_, err = resourceClient.Create(obj)
+		switch {
+		case apierrors.IsAlreadyExists(err):
+			record(groupResource, namespace, name, ItemOutcomeSkipped, ItemActionSkippedExists, alreadyExistsReason)
+		case err != nil:
+			err = errors.Wrapf(err, "error replaying %s", getResourceID(groupResource, namespace, name))
+			addToResult(&errs, namespace, err)
+			record(groupResource, namespace, name, ItemOutcomeFailed, ItemActionFailed, err.Error())
+		default:
+			record(groupResource, namespace, name, ItemOutcomeCreated, ItemActionCreated, replayedReason)
+		}
+	}
+
+	return warnings, errs, itemResults
+}
+
+// readReplayItem reads and decodes the item stored at the provided path in a replay.
+func readReplayItem(replay ReplayReader, itemPath string) (*unstructured.Unstructured, error) {
+	rc, err := replay.GetReplayItem(itemPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading replay item %s", itemPath)
+	}
+	defer rc.Close()
+
+	contents, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading replay item %s", itemPath)
+	}
+
+	obj := new(unstructured.Unstructured)
+	if err := json.Unmarshal(contents, obj); err != nil {
+		return nil, errors.Wrapf(err, "error decoding replay item %s", itemPath)
+	}
+
+	return obj, nil
+}
@@ -0,0 +1,298 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1api "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	api "github.com/heptio/velero/pkg/apis/velero/v1"
+	"github.com/heptio/velero/pkg/kuberesource"
+	"github.com/heptio/velero/pkg/util/kube"
+)
+
+// addResources adds the quantities of the provided resource list to total.
+func addResources(total, list corev1api.ResourceList) {
+	for name, quantity := range list {
+		sum := total[name]
+		sum.Add(quantity)
+		total[name] = sum
+	}
+}
+
+// podQuotaUsage returns the quota resources that the provided pod uses. A pod's
+// requests and limits are the larger of the sum of its containers' and the largest
+// of its init containers', since init containers run one at a time before the
+// other containers start. Pods that have finished don't use any quota.
+func podQuotaUsage(pod *corev1api.Pod) corev1api.ResourceList {
+	if pod.Status.Phase == corev1api.PodSucceeded || pod.Status.Phase == corev1api.PodFailed {
+		return nil
+	}
+
+	requests, limits := corev1api.ResourceList{}, corev1api.ResourceList{}
+	for _, container := range pod.Spec.Containers {
+		addResources(requests, container.Resources.Requests)
+		addResources(limits, container.Resources.Limits)
+	}
+	for _, container := range pod.Spec.InitContainers {
+		for name, quantity := range container.Resources.Requests {
+			if quantity.Cmp(requests[name]) > 0 {
+				requests[name] = quantity
+			}
+		}
+		for name, quantity := range container.Resources.Limits {
+			if quantity.Cmp(limits[name]) > 0 {
+				limits[name] = quantity
+			}
+		}
+	}
+
+	usage := corev1api.ResourceList{
+		corev1api.ResourcePods: *resource.NewQuantity(1, resource.DecimalSI),
+	}
+	for _, name := range []corev1api.ResourceName{corev1api.ResourceCPU, corev1api.ResourceMemory} {
+		if quantity, ok := requests[name]; ok {
+			usage[name] = quantity
+			usage[corev1api.ResourceName("requests."+string(name))] = quantity
+		}
+		if quantity, ok := limits[name]; ok {
+			usage[corev1api.ResourceName("limits."+string(name))] = quantity
+		}
+	}
+	return usage
+}
+
+// pvcQuotaUsage returns the quota resources that the provided persistent volume claim uses.
+func pvcQuotaUsage(pvc *corev1api.PersistentVolumeClaim) corev1api.ResourceList {
+	usage := corev1api.ResourceList{
+		corev1api.ResourcePersistentVolumeClaims: *resource.NewQuantity(1, resource.DecimalSI),
+	}
+	if quantity, ok := pvc.Spec.Resources.Requests[corev1api.ResourceStorage]; ok {
+		usage[corev1api.ResourceRequestsStorage] = quantity
+	}
+	return usage
+}
+
+// collectQuotaUsage returns the quota resources that the pods and persistent volume claims
+// the restore restores use, by the namespace they're restored into. Items that the restore's
+// label selectors, included resource names or latest only filters skip aren't counted, and
+// neither are items that can't be decoded, since they won't be restored. Items that can't be
+// read are returned as warnings, and are left out too.
+func (ctx *context) collectQuotaUsage(resourcesDir string, resourceDirs map[string]os.FileInfo) (map[string]corev1api.ResourceList, Result) {
+	usage := make(map[string]corev1api.ResourceList)
+	warnings := Result{}
+
+	for _, groupResource := range ctx.prioritizedResources {
+		if groupResource != kuberesource.Pods && groupResource != kuberesource.PersistentVolumeClaims {
+			continue
+		}
+		rscDir := ctx.backupResourceDir(groupResource, resourceDirs)
+		if rscDir == nil || ctx.excludedByScope(groupResource) {
+			continue
+		}
+		sourceResource := schema.ParseGroupResource(rscDir.Name())
+
+		nsSubDir := filepath.Join(resourcesDir, rscDir.Name(), api.NamespaceScopedDir)
+		exists, err := ctx.fileSystem.DirExists(nsSubDir)
+		if err != nil {
+			addVeleroError(&warnings, errors.Wrapf(err, "not checking resource quotas for %s", groupResource))
+			continue
+		}
+		if !exists {
+			continue
+		}
+
+		nsDirs, err := ctx.fileSystem.ReadDir(nsSubDir)
+		if err != nil {
+			addVeleroError(&warnings, errors.Wrapf(err, "not checking resource quotas for %s", groupResource))
+			continue
+		}
+
+		for _, nsDir := range nsDirs {
+			if !nsDir.IsDir() || !ctx.namespaceIncludesExcludes.ShouldInclude(nsDir.Name()) {
+				continue
+			}
+			sourceNamespace, targetNamespaces := nsDir.Name(), ctx.targetNamespaces(nsDir.Name())
+
+			names, err := ctx.getItemSource().ListItems(sourceResource, sourceNamespace)
+			if err != nil {
+				for _, namespace := range targetNamespaces {
+					addToResult(&warnings, namespace, errors.Wrapf(err, "not checking resource quotas for %s in namespace %s", groupResource, namespace))
+				}
+				continue
+			}
+
+			// the latest only filter's own warnings are reported when the items
+			// are restored
+			olderRevisions, _ := ctx.olderRevisions(groupResource, sourceResource, sourceNamespace, names, targetNamespaces)
+
+			for _, name := range names {
+				if olderRevisions.Has(name) {
+					continue
+				}
+
+				obj, err := ctx.readItem(sourceResource, sourceNamespace, name)
+				if err != nil {
+					ctx.log.WithError(err).Debugf("Not counting %s %s toward resource quotas because it can't be decoded", groupResource, name)
+					continue
+				}
+
+				if !ctx.selector.Matches(labels.Set(obj.GetLabels())) {
+					continue
+				}
+				if ctx.excludeSelector != nil && ctx.excludeSelector.Matches(labels.Set(obj.GetLabels())) {
+					continue
+				}
+
+				list, err := quotaUsage(obj, groupResource)
+				if err != nil {
+					for _, namespace := range targetNamespaces {
+						addToResult(&warnings, namespace, errors.Wrapf(err, "not counting %s %s toward resource quotas", groupResource, kube.NamespaceAndName(obj)))
+					}
+					continue
+				}
+
+				for _, namespace := range targetNamespaces {
+					if !ctx.includesItemName(groupResource, sourceNamespace, obj.GetName(), namespace) {
+						continue
+					}
+
+					if usage[namespace] == nil {
+						usage[namespace] = corev1api.ResourceList{}
+					}
+					addResources(usage[namespace], list)
+				}
+			}
+		}
+	}
+
+	return usage, warnings
+}
+
+// quotaUsage returns the quota resources that the provided pod or persistent volume
+// claim uses.
+func quotaUsage(obj *unstructured.Unstructured, groupResource schema.GroupResource) (corev1api.ResourceList, error) {
+	if groupResource == kuberesource.Pods {
+		pod := new(corev1api.Pod)
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), pod); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return podQuotaUsage(pod), nil
+	}
+
+	pvc := new(corev1api.PersistentVolumeClaim)
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), pvc); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return pvcQuotaUsage(pvc), nil
+}
+
+// exceededQuotas returns a description of each resource of the provided quotas that the
+// provided usage would exceed, given what the quota's namespace already uses.
+func exceededQuotas(quotas []corev1api.ResourceQuota, usage corev1api.ResourceList) []string {
+	var exceeded []string
+	for _, quota := range quotas {
+		names := make([]string, 0, len(quota.Spec.Hard))
+		for name := range quota.Spec.Hard {
+			names = append(names, string(name))
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			requested, ok := usage[corev1api.ResourceName(name)]
+			if !ok {
+				continue
+			}
+
+			hard := quota.Spec.Hard[corev1api.ResourceName(name)]
+			available := hard.DeepCopy()
+			available.Sub(quota.Status.Used[corev1api.ResourceName(name)])
+			if requested.Cmp(available) > 0 {
+				exceeded = append(exceeded, fmt.Sprintf("%s %s (%s requested, %s of %s available)", quota.Name, name, requested.String(), available.String(), hard.String()))
+			}
+		}
+	}
+	return exceeded
+}
+
+// checkResourceQuotas compares the quota resources that the restore's pods and persistent
+// volume claims use in each namespace against the namespace's resource quotas, and returns
+// a warning for each namespace whose quotas the restore would exceed. Problems checking the
+// quotas are warnings too, since they don't stop the restore.
+func (ctx *context) checkResourceQuotas(resourcesDir string, resourceDirs map[string]os.FileInfo) Result {
+	usage, warnings := ctx.collectQuotaUsage(resourcesDir, resourceDirs)
+
+	namespaces := make([]string, 0, len(usage))
+	for namespace := range usage {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	quotaResource := metav1.APIResource{Name: "resourcequotas", Namespaced: true}
+	for _, namespace := range namespaces {
+		quotaClient, err := ctx.dynamicFactory.ClientForGroupVersionResource(schema.GroupVersion{Group: "", Version: "v1"}, quotaResource, namespace)
+		if err != nil {
+			addToResult(&warnings, namespace, errors.Wrapf(err, "error getting resource quota client for namespace %s", namespace))
+			continue
+		}
+
+		list, err := quotaClient.List(metav1.ListOptions{})
+		if err != nil {
+			addToResult(&warnings, namespace, errors.Wrapf(err, "error listing resource quotas in namespace %s", namespace))
+			continue
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			addToResult(&warnings, namespace, errors.Wrapf(err, "error listing resource quotas in namespace %s", namespace))
+			continue
+		}
+
+		var quotas []corev1api.ResourceQuota
+		for _, item := range items {
+			unstructuredQuota, ok := item.(runtime.Unstructured)
+			if !ok {
+				continue
+			}
+			var quota corev1api.ResourceQuota
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(unstructuredQuota.UnstructuredContent(), &quota); err != nil {
+				addToResult(&warnings, namespace, errors.Wrapf(err, "error decoding resource quota in namespace %s", namespace))
+				continue
+			}
+			quotas = append(quotas, quota)
+		}
+
+		if exceeded := exceededQuotas(quotas, usage[namespace]); len(exceeded) > 0 {
+			ctx.log.Infof("Restore would exceed resource quotas in namespace %s: %s", namespace, strings.Join(exceeded, ", "))
+			addToResult(&warnings, namespace, errors.Errorf("restoring pods and persistent volume claims into namespace %s would exceed its resource quotas: %s", namespace, strings.Join(exceeded, ", ")))
+		}
+	}
+
+	return warnings
+}
@@ -21,14 +21,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
@@ -38,6 +39,7 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	kubeerrs "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -50,6 +52,7 @@ import (
 	"github.com/heptio/velero/pkg/kuberesource"
 	"github.com/heptio/velero/pkg/label"
 	"github.com/heptio/velero/pkg/plugin/velero"
+	"github.com/heptio/velero/pkg/podexec"
 	"github.com/heptio/velero/pkg/restic"
 	"github.com/heptio/velero/pkg/util/boolptr"
 	"github.com/heptio/velero/pkg/util/collections"
@@ -65,7 +68,8 @@ type VolumeSnapshotterGetter interface {
 
 // Restorer knows how to restore a backup.
 type Restorer interface {
-	// Restore restores the backup data from backupReader, returning warnings and errors.
+	// Restore restores the backup data from backupReader, returning warnings, errors,
+	// and the outcome of each item that was processed.
 	Restore(log logrus.FieldLogger,
 		restore *api.Restore,
 		backup *api.Backup,
@@ -74,7 +78,11 @@ type Restorer interface {
 		actions []velero.RestoreItemAction,
 		snapshotLocationLister listers.VolumeSnapshotLocationLister,
 		volumeSnapshotterGetter VolumeSnapshotterGetter,
-	) (Result, Result)
+	) (Result, Result, ItemResults)
+
+	// Replay re-creates the items stored in the provided replay of an earlier
+	// restore, returning warnings, errors, and the outcome of each item.
+	Replay(log logrus.FieldLogger, restore *api.Restore, replay ReplayReader) (Result, Result, ItemResults)
 }
 
 // kubernetesRestorer implements Restorer for restoring into a Kubernetes cluster.
@@ -85,33 +93,124 @@ type kubernetesRestorer struct {
 	resticRestorerFactory      restic.RestorerFactory
 	resticTimeout              time.Duration
 	resourceTerminatingTimeout time.Duration
+	createRetryPolicy          CreateRetryPolicy
+	itemOperationTimeout       time.Duration
 	resourcePriorities         []string
 	fileSystem                 filesystem.Interface
+	podCommandExecutor         podexec.PodCommandExecutor
+	tracer                     Tracer
+	progressUpdater            ProgressUpdater
+	checkpointer               Checkpointer
+	replayWriterFactory        ReplayWriterFactory
+	unresolvedReferencesWriter UnresolvedReferencesWriter
+	preRestoreBackupper        PreRestoreBackupper
+	itemSource                 ItemSource
+	metrics                    *restoreMetrics
 	logger                     logrus.FieldLogger
 }
 
-// prioritizeResources returns an ordered, fully-resolved list of resources to restore based on
-// the provided discovery helper, resource priorities, and included/excluded resources.
-func prioritizeResources(helper discovery.Helper, priorities []string, includedResources *collections.IncludesExcludes, logger logrus.FieldLogger) ([]schema.GroupResource, error) {
-	var ret []schema.GroupResource
+// getResourcePriorities returns the resource priorities to use for a restore: the
+// restore's own priorities if it has any, otherwise the server's defaults.
+func getResourcePriorities(defaults, restorePriorities []string) []string {
+	if len(restorePriorities) > 0 {
+		return restorePriorities
+	}
+	return defaults
+}
+
+// ValidateLowPriorityResources checks that none of the provided low-priority resources
+// are in the resource priorities a restore with the provided priorities is restored with,
+// which are the provided defaults if it has none, since a resource can't be restored both
+// first and last. An error is returned for each resource that's in both, and for each
+// resource that's a low-priority resource more than once.
+func ValidateLowPriorityResources(defaultPriorities, restorePriorities, lowPriorities []string) []error {
+	prioritized := sets.NewString()
+	for _, r := range getResourcePriorities(defaultPriorities, restorePriorities) {
+		prioritized.Insert(schema.ParseGroupResource(r).String())
+	}
+
+	var errs []error
+	seen := sets.NewString()
+	for _, r := range lowPriorities {
+		gr := schema.ParseGroupResource(r).String()
+		if seen.Has(gr) {
+			errs = append(errs, errors.Errorf("resource %s is a low-priority resource more than once", r))
+			continue
+		}
+		seen.Insert(gr)
+
+		if prioritized.Has(gr) {
+			errs = append(errs, errors.Errorf("resource %s is both a resource priority and a low-priority resource", r))
+		}
+	}
+	return errs
+}
+
+// MissingResourceError is returned when a restore's resource priorities are strict
+// and name resources the cluster doesn't serve.
+type MissingResourceError struct {
+	// Resources are the priorities the cluster doesn't serve, in priority order.
+	Resources []string
+}
+
+func (e *MissingResourceError) Error() string {
+	return fmt.Sprintf("resource priorities name resources the cluster doesn't serve: %s", strings.Join(e.Resources, ", "))
+}
 
+// prioritizeResources returns an ordered, fully-resolved list of resources to restore based on
+// the provided discovery helper, resource priorities, low-priority resources, and included/excluded
+// resources. Prioritized resources come first, in priority order, followed by all other resources
+// in alphabetical order, followed by the low-priority resources, in the order they're listed.
+// Priorities naming resources the cluster doesn't serve are ignored, unless strictPriorities is
+// set, in which case a *MissingResourceError naming them is returned. A resource that's both
+// prioritized and low-priority is an error.
+func prioritizeResources(helper discovery.Helper, priorities, lowPriorities []string, includedResources *collections.IncludesExcludes, strictPriorities bool, logger logrus.FieldLogger) ([]schema.GroupResource, error) {
 	// set keeps track of resolved GroupResource names
 	set := sets.NewString()
 
-	// start by resolving priorities into GroupResources and adding them to ret
-	for _, r := range priorities {
-		gvr, _, err := helper.ResourceFor(schema.ParseGroupResource(r).WithVersion(""))
-		if err != nil {
-			return nil, err
-		}
-		gr := gvr.GroupResource()
+	// priorities the cluster doesn't serve are ignored, unless
+	// they're strict
+	var missing []string
 
-		if !includedResources.ShouldInclude(gr.String()) {
-			logger.WithField("groupResource", gr).Info("Not including resource")
-			continue
+	// resolve resolves priorities into included GroupResources. Low-priority
+	// resources that are already prioritized are an error.
+	resolve := func(priorities []string, lowPriority bool) ([]schema.GroupResource, error) {
+		var resolved []schema.GroupResource
+		for _, r := range priorities {
+			gvr, _, err := helper.ResourceFor(schema.ParseGroupResource(r).WithVersion(""))
+			if err != nil {
+				logger.WithError(err).WithField("resource", r).Info("Ignoring resource priority for a resource the cluster doesn't serve")
+				missing = append(missing, r)
+				continue
+			}
+			gr := gvr.GroupResource()
+
+			if lowPriority && set.Has(gr.String()) {
+				return nil, errors.Errorf("resource %s is both a resource priority and a low-priority resource", gr)
+			}
+
+			if !includedResources.ShouldInclude(gr.String()) {
+				logger.WithField("groupResource", gr).Info("Not including resource")
+				continue
+			}
+			resolved = append(resolved, gr)
+			set.Insert(gr.String())
 		}
-		ret = append(ret, gr)
-		set.Insert(gr.String())
+		return resolved, nil
+	}
+
+	// start by resolving priorities into GroupResources and adding them to ret
+	ret, err := resolve(priorities, false)
+	if err != nil {
+		return nil, err
+	}
+	last, err := resolve(lowPriorities, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if strictPriorities && len(missing) > 0 {
+		return nil, &MissingResourceError{Resources: missing}
 	}
 
 	// go through everything we got from discovery and add anything not in "set" to byName
@@ -142,8 +241,9 @@ func prioritizeResources(helper discovery.Helper, priorities []string, includedR
 		return byName[i].String() < byName[j].String()
 	})
 
-	// combine prioritized with by-name
+	// combine prioritized with by-name, and the low-priority resources
 	ret = append(ret, byName...)
+	ret = append(ret, last...)
 
 	return ret, nil
 }
@@ -157,8 +257,28 @@ func NewKubernetesRestorer(
 	resticRestorerFactory restic.RestorerFactory,
 	resticTimeout time.Duration,
 	resourceTerminatingTimeout time.Duration,
+	createRetryPolicy CreateRetryPolicy,
+	itemOperationTimeout time.Duration,
+	podCommandExecutor podexec.PodCommandExecutor,
+	tracer Tracer,
+	progressUpdater ProgressUpdater,
+	checkpointer Checkpointer,
+	replayWriterFactory ReplayWriterFactory,
+	unresolvedReferencesWriter UnresolvedReferencesWriter,
+	preRestoreBackupper PreRestoreBackupper,
+	itemSource ItemSource,
+	metricsRegisterer prometheus.Registerer,
 	logger logrus.FieldLogger,
 ) (Restorer, error) {
+	if tracer == nil {
+		tracer = noopTracer{}
+	}
+
+	metrics, err := newRestoreMetrics(metricsRegisterer)
+	if err != nil {
+		return nil, err
+	}
+
 	return &kubernetesRestorer{
 		discoveryHelper:            discoveryHelper,
 		dynamicFactory:             dynamicFactory,
@@ -166,15 +286,26 @@ func NewKubernetesRestorer(
 		resticRestorerFactory:      resticRestorerFactory,
 		resticTimeout:              resticTimeout,
 		resourceTerminatingTimeout: resourceTerminatingTimeout,
+		createRetryPolicy:          createRetryPolicy,
+		itemOperationTimeout:       itemOperationTimeout,
 		resourcePriorities:         resourcePriorities,
 		logger:                     logger,
 		fileSystem:                 filesystem.NewFileSystem(),
+		podCommandExecutor:         podCommandExecutor,
+		tracer:                     tracer,
+		progressUpdater:            progressUpdater,
+		checkpointer:               checkpointer,
+		replayWriterFactory:        replayWriterFactory,
+		unresolvedReferencesWriter: unresolvedReferencesWriter,
+		preRestoreBackupper:        preRestoreBackupper,
+		itemSource:                 itemSource,
+		metrics:                    metrics,
 	}, nil
 }
 
 // Restore executes a restore into the target Kubernetes cluster according to the restore spec
 // and using data from the provided backup/backup reader. Returns a warnings and errors RestoreResult,
-// respectively, summarizing info about the restore.
+// respectively, summarizing info about the restore, along with the outcome of each item.
 func (kr *kubernetesRestorer) Restore(
 	log logrus.FieldLogger,
 	restore *api.Restore,
@@ -184,7 +315,7 @@ func (kr *kubernetesRestorer) Restore(
 	actions []velero.RestoreItemAction,
 	snapshotLocationLister listers.VolumeSnapshotLocationLister,
 	volumeSnapshotterGetter VolumeSnapshotterGetter,
-) (Result, Result) {
+) (Result, Result, ItemResults) {
 	// metav1.LabelSelectorAsSelector converts a nil LabelSelector to a
 	// Nothing Selector, i.e. a selector that matches nothing. We want
 	// a selector that matches everything. This can be accomplished by
@@ -196,24 +327,52 @@ func (kr *kubernetesRestorer) Restore(
 
 	selector, err := metav1.LabelSelectorAsSelector(ls)
 	if err != nil {
-		return Result{}, Result{Velero: []string{err.Error()}}
+		return Result{}, Result{Velero: []string{err.Error()}}, nil
+	}
+
+	// a nil exclude LabelSelector is converted to a Nothing Selector,
+	// which is what we want: nothing is excluded.
+	excludeSelector, err := metav1.LabelSelectorAsSelector(restore.Spec.LabelSelectorExclude)
+	if err != nil {
+		return Result{}, Result{Velero: []string{err.Error()}}, nil
+	}
+
+	// a nil PVC data selector means the data of all volumes is restored
+	var pvcDataSelector labels.Selector
+	if restore.Spec.RestorePVCDataSelector != nil {
+		pvcDataSelector, err = metav1.LabelSelectorAsSelector(restore.Spec.RestorePVCDataSelector)
+		if err != nil {
+			return Result{}, Result{Velero: []string{err.Error()}}, nil
+		}
+	}
+
+	readinessChecks, err := getReadinessChecks(kr.discoveryHelper, restore.Spec.WaitForReady, log)
+	if err != nil {
+		return Result{}, Result{Velero: []string{err.Error()}}, nil
 	}
 
 	// get resource includes-excludes
 	resourceIncludesExcludes := getResourceIncludesExcludes(kr.discoveryHelper, restore.Spec.IncludedResources, restore.Spec.ExcludedResources)
-	prioritizedResources, err := prioritizeResources(kr.discoveryHelper, kr.resourcePriorities, resourceIncludesExcludes, log)
+	resourcePriorities := getResourcePriorities(kr.resourcePriorities, restore.Spec.ResourcePriorities)
+	prioritizedResources, err := prioritizeResources(kr.discoveryHelper, resourcePriorities, restore.Spec.LowPriorityResources, resourceIncludesExcludes, restore.Spec.StrictResourcePriorities, log)
 	if err != nil {
-		return Result{}, Result{Velero: []string{err.Error()}}
+		return Result{}, Result{Velero: []string{err.Error()}}, nil
 	}
+	orderGatewayResources(prioritizedResources)
 
 	// get namespace includes-excludes
 	namespaceIncludesExcludes := collections.NewIncludesExcludes().
 		Includes(restore.Spec.IncludedNamespaces...).
-		Excludes(restore.Spec.ExcludedNamespaces...)
+		Excludes(getNamespaceExcludes(restore, backup)...)
 
 	resolvedActions, err := resolveActions(actions, kr.discoveryHelper)
 	if err != nil {
-		return Result{}, Result{Velero: []string{err.Error()}}
+		return Result{}, Result{Velero: []string{err.Error()}}, nil
+	}
+
+	restoreHooks, err := getRestoreHooks(restore.Spec.Hooks.Resources)
+	if err != nil {
+		return Result{}, Result{Velero: []string{err.Error()}}, nil
 	}
 
 	podVolumeTimeout := kr.resticTimeout
@@ -233,8 +392,23 @@ func (kr *kubernetesRestorer) Restore(
 	if kr.resticRestorerFactory != nil {
 		resticRestorer, err = kr.resticRestorerFactory.NewRestorer(ctx, restore)
 		if err != nil {
-			return Result{}, Result{Velero: []string{err.Error()}}
+			return Result{}, Result{Velero: []string{err.Error()}}, nil
+		}
+	}
+
+	// a resumed restore skips the items in its last checkpoint
+	checkpointed, err := kr.loadCheckpoint(restore)
+	if err != nil {
+		return Result{}, Result{Velero: []string{err.Error()}}, nil
+	}
+
+	var replayWriter ReplayWriter
+	if restore.Spec.CaptureReplay && !restore.Spec.DryRun && kr.replayWriterFactory != nil {
+		replayWriter, err = kr.replayWriterFactory.NewReplayWriter(restore)
+		if err != nil {
+			return Result{}, Result{Velero: []string{err.Error()}}, nil
 		}
+		defer replayWriter.Close()
 	}
 
 	pvRestorer := &pvRestorer{
@@ -245,6 +419,8 @@ func (kr *kubernetesRestorer) Restore(
 		volumeSnapshots:         volumeSnapshots,
 		volumeSnapshotterGetter: volumeSnapshotterGetter,
 		snapshotLocationLister:  snapshotLocationLister,
+		dryRun:                  restore.Spec.DryRun,
+		metrics:                 kr.metrics,
 	}
 
 	restoreCtx := &context{
@@ -253,10 +429,17 @@ func (kr *kubernetesRestorer) Restore(
 		restore:                    restore,
 		resourceIncludesExcludes:   resourceIncludesExcludes,
 		namespaceIncludesExcludes:  namespaceIncludesExcludes,
+		resourcePriorities:         resourcePriorities,
 		prioritizedResources:       prioritizedResources,
 		selector:                   selector,
+		excludeSelector:            excludeSelector,
+		pvcDataSelector:            pvcDataSelector,
+		readinessChecks:            readinessChecks,
+		namespaceDeadlines:         make(map[string]time.Time),
+		abandonedNamespaces:        sets.NewString(),
 		log:                        log,
 		dynamicFactory:             kr.dynamicFactory,
+		discoveryHelper:            kr.discoveryHelper,
 		fileSystem:                 kr.fileSystem,
 		namespaceClient:            kr.namespaceClient,
 		actions:                    resolvedActions,
@@ -266,6 +449,22 @@ func (kr *kubernetesRestorer) Restore(
 		pvRestorer:                 pvRestorer,
 		volumeSnapshots:            volumeSnapshots,
 		resourceTerminatingTimeout: kr.resourceTerminatingTimeout,
+		createRetryPolicy:          kr.createRetryPolicy,
+		itemOperationTimeout:       kr.itemOperationTimeout,
+		dryRun:                     restore.Spec.DryRun,
+		podCommandExecutor:         kr.podCommandExecutor,
+		preRestoreBackupper:        kr.preRestoreBackupper,
+		itemSource:                 kr.itemSource,
+		checkpointer:               kr.checkpointer,
+		checkpoint:                 newRestoreCheckpoint(restore, checkpointed),
+		replayWriter:               replayWriter,
+		unresolvedReferencesWriter: kr.unresolvedReferencesWriter,
+		metrics:                    kr.metrics,
+		restoreHooks:               restoreHooks,
+		annotationFilter: annotationFilter{
+			keepPrefixes:  restore.Spec.KeepAnnotationPrefixes,
+			stripPrefixes: restore.Spec.StripAnnotationPrefixes,
+		},
 		extractor: &backupExtractor{
 			log:        log,
 			fileSystem: kr.fileSystem,
@@ -274,7 +473,46 @@ func (kr *kubernetesRestorer) Restore(
 		restoredItems:   make(map[velero.ResourceIdentifier]struct{}),
 	}
 
-	return restoreCtx.execute()
+	if restore.Spec.VolumeRestoreParallelism > 0 {
+		restoreCtx.volumeRestoreSlots = make(chan struct{}, restore.Spec.VolumeRestoreParallelism)
+	}
+
+	restoreCtx.tracer = kr.tracer
+	restoreCtx.restoreSpan = kr.tracer.StartSpan(RestoreSpanName, nil,
+		Attribute{Key: "restore", Value: kube.NamespaceAndName(restore)},
+		Attribute{Key: "backup", Value: kube.NamespaceAndName(backup)},
+	)
+	defer restoreCtx.restoreSpan.End()
+
+	stopProgressUpdates := kr.startProgressUpdates(restore, &restoreCtx.progress, log)
+	start := time.Now()
+	warnings, errs := restoreCtx.execute()
+	stopProgressUpdates()
+	restoreCtx.metrics.observeDuration(time.Since(start))
+	restoreCtx.metrics.observeErrors(errs)
+	restoreCtx.restoreSpan.SetAttributes(outcomeAttributes(restoreCtx.itemResults)...)
+
+	return warnings, errs, restoreCtx.itemResults
+}
+
+// getNamespaceExcludes returns the namespaces to exclude from the restore. These are the
+// restore's excluded namespaces, plus, if the restore respects the backup's exclusions, the
+// backup's excluded namespaces that aren't explicitly included by the restore.
+func getNamespaceExcludes(restore *api.Restore, backup *api.Backup) []string {
+	excludes := append([]string{}, restore.Spec.ExcludedNamespaces...)
+	if !restore.Spec.RespectBackupExclusions {
+		return excludes
+	}
+
+	explicitIncludes := sets.NewString(restore.Spec.IncludedNamespaces...)
+	for _, ns := range backup.Spec.ExcludedNamespaces {
+		if ns == "*" || explicitIncludes.Has(ns) {
+			continue
+		}
+		excludes = append(excludes, ns)
+	}
+
+	return excludes
 }
 
 // getResourceIncludesExcludes takes the lists of resources to include and exclude, uses the
@@ -304,8 +542,12 @@ type resolvedAction struct {
 	resourceIncludesExcludes  *collections.IncludesExcludes
 	namespaceIncludesExcludes *collections.IncludesExcludes
 	selector                  labels.Selector
+	priority                  int
 }
 
+// resolveActions resolves the resources, namespaces and label selector that each of the
+// provided actions applies to, and orders the actions by decreasing priority. Actions
+// with the same priority keep the order they're provided in.
 func resolveActions(actions []velero.RestoreItemAction, helper discovery.Helper) ([]resolvedAction, error) {
 	var resolved []resolvedAction
 
@@ -325,16 +567,28 @@ func resolveActions(actions []velero.RestoreItemAction, helper discovery.Helper)
 			}
 		}
 
+		var priority int
+		if prioritized, ok := action.(velero.PrioritizedRestoreItemAction); ok {
+			if priority, err = prioritized.Priority(); err != nil {
+				return nil, err
+			}
+		}
+
 		res := resolvedAction{
 			RestoreItemAction:         action,
 			resourceIncludesExcludes:  resources,
 			namespaceIncludesExcludes: namespaces,
 			selector:                  selector,
+			priority:                  priority,
 		}
 
 		resolved = append(resolved, res)
 	}
 
+	sort.SliceStable(resolved, func(i, j int) bool {
+		return resolved[i].priority > resolved[j].priority
+	})
+
 	return resolved, nil
 }
 
@@ -345,8 +599,16 @@ type context struct {
 	restoreDir                 string
 	resourceIncludesExcludes   *collections.IncludesExcludes
 	namespaceIncludesExcludes  *collections.IncludesExcludes
+	resourcePriorities         []string
 	prioritizedResources       []schema.GroupResource
 	selector                   labels.Selector
+	excludeSelector            labels.Selector
+	pvcDataSelector            labels.Selector
+	readinessChecks            map[schema.GroupResource]readinessCheck
+	namespaceDeadlines         map[string]time.Time
+	abandonedNamespaces        sets.String
+	restoredUIDs               map[types.UID]types.UID
+	restoredCRDs               []createdItem
 	log                        logrus.FieldLogger
 	dynamicFactory             client.DynamicFactory
 	fileSystem                 filesystem.Interface
@@ -359,9 +621,49 @@ type context struct {
 	pvRestorer                 PVRestorer
 	volumeSnapshots            []*volume.Snapshot
 	resourceTerminatingTimeout time.Duration
+	createRetryPolicy          CreateRetryPolicy
+	itemOperationTimeout       time.Duration
 	extractor                  *backupExtractor
 	resourceClients            map[resourceClientKey]client.Dynamic
 	restoredItems              map[velero.ResourceIdentifier]struct{}
+	pendingItems               map[velero.ResourceIdentifier]struct{}
+	skippedItems               map[velero.ResourceIdentifier]struct{}
+	csiDrivers                 sets.String
+	csiDriversListed           bool
+	nodeLabels                 map[string]labels.Set
+	nodesListed                bool
+	itemLock                   sync.Mutex
+	parallelItems              bool
+	dryRun                     bool
+	restoredBindings           []*unstructured.Unstructured
+	restoredReferences         []itemReference
+	preBoundVolumes            []*unstructured.Unstructured
+	podCommandExecutor         podexec.PodCommandExecutor
+	preRestoreBackupper        PreRestoreBackupper
+	itemSource                 ItemSource
+	checkpointer               Checkpointer
+	checkpoint                 *restoreCheckpoint
+	replayWriter               ReplayWriter
+	unresolvedReferencesWriter UnresolvedReferencesWriter
+	restoreHooks               []restoreHook
+	hookWaitGroup              sync.WaitGroup
+	hookResultsLock            sync.Mutex
+	hookWarnings               Result
+	hookErrs                   Result
+	annotationFilter           annotationFilter
+	volumeRestoreSlots         chan struct{}
+	configReferences           sets.String
+	relaxedNamespaces          map[string]*string
+	progress                   progressTracker
+	metrics                    *restoreMetrics
+	conversionWebhookServices  []serviceReference
+	itemResults                ItemResults
+	hpaTargets                 map[string]sets.String
+	discoveryHelper            discovery.Helper
+	defaultNamespaceEnsured    bool
+	createdItems               []createdItem
+	tracer                     Tracer
+	restoreSpan                Span
 }
 
 type resourceClientKey struct {
@@ -379,16 +681,27 @@ func (ctx *context) execute() (Result, Result) {
 	}
 	defer ctx.fileSystem.RemoveAll(dir)
 
-	// need to set this for additionalItems to be restored
-	ctx.restoreDir = dir
+	// need to set this for additionalItems to be restored. Restores of a
+	// partial backup read it from the sub-path of the extracted tarball
+	// that holds its resources directory.
+	ctx.restoreDir = filepath.Join(dir, ctx.restore.Spec.SourceSubPath)
 
 	return ctx.restoreFromDir()
 }
 
 // restoreFromDir executes a restore based on backup data contained within a local
 // directory, ctx.restoreDir.
-func (ctx *context) restoreFromDir() (Result, Result) {
-	warnings, errs := Result{}, Result{}
+func (ctx *context) restoreFromDir() (warnings, errs Result) {
+	warnings, errs = Result{}, Result{}
+
+	// put back the PodSecurity levels of the namespaces relaxed for the restore
+	// however the restore ends, so that none are left privileged
+	defer func() {
+		if len(ctx.relaxedNamespaces) > 0 {
+			w := ctx.restorePodSecurity()
+			merge(&warnings, &w)
+		}
+	}()
 
 	// Make sure the top level "resources" dir exists:
 	resourcesDir := filepath.Join(ctx.restoreDir, api.ResourcesDir)
@@ -414,77 +727,269 @@ func (ctx *context) restoreFromDir() (Result, Result) {
 		resourceDirsMap[rscName] = rscDir
 	}
 
+	if ctx.restore.Spec.ReportUnreferenced || ctx.restore.Spec.PruneUnreferenced {
+		if ctx.configReferences, err = ctx.collectConfigReferences(resourcesDir); err != nil {
+			addVeleroError(&errs, err)
+			return warnings, errs
+		}
+	}
+
+	totalItems, err := ctx.countItems(resourcesDir, resourceDirsMap)
+	if err != nil {
+		addVeleroError(&errs, err)
+		return warnings, errs
+	}
+	ctx.progress.setTotalItems(totalItems)
+
+	// capture the state of the namespaces before anything in them changes, so
+	// that the restore can be reverted
+	if err := ctx.backupTargetNamespaces(resourcesDir, resourceDirsMap); err != nil {
+		addVeleroError(&errs, err)
+		return warnings, errs
+	}
+
+	// warn about namespaces whose quotas the restore would exceed before
+	// anything is restored into them
+	if ctx.restore.Spec.CheckResourceQuotas {
+		w := ctx.checkResourceQuotas(resourcesDir, resourceDirsMap)
+		merge(&warnings, &w)
+	}
+
 	existingNamespaces := sets.NewString()
 
-	for _, resource := range ctx.prioritizedResources {
-		// we don't want to explicitly restore namespace API objs because we'll handle
-		// them as a special case prior to restoring anything into them
-		if resource == kuberesource.Namespaces {
-			continue
-		}
+	serial, independent := ctx.prioritizedResources, []schema.GroupResource(nil)
+	if ctx.restore.Spec.IndependentResourceParallelism > 1 {
+		serial, independent = ctx.splitIndependentResources()
+	}
 
-		rscDir := resourceDirsMap[resource.String()]
-		if rscDir == nil {
+	// serial can grow as it's restored, so it's not ranged over
+	for i := 0; i < len(serial); i++ {
+		resource := serial[i]
+
+		rscDir, ok := ctx.resourceDirToRestore(resource, resourceDirsMap)
+		if !ok {
 			continue
 		}
 
-		resourcePath := filepath.Join(resourcesDir, rscDir.Name())
+		span := ctx.startResourceSpan(resource)
+		w, e, err := ctx.restoreResourceDir(resource, schema.ParseGroupResource(rscDir.Name()), filepath.Join(resourcesDir, rscDir.Name()), existingNamespaces, span)
+		ctx.endResourceSpan(span)
 
-		clusterSubDir := filepath.Join(resourcePath, api.ClusterScopedDir)
-		clusterSubDirExists, err := ctx.fileSystem.DirExists(clusterSubDir)
+		merge(&warnings, &w)
+		merge(&errs, &e)
 		if err != nil {
 			addVeleroError(&errs, err)
 			return warnings, errs
 		}
-		if clusterSubDirExists {
-			w, e := ctx.restoreResource(resource.String(), "", clusterSubDir)
-			merge(&warnings, &w)
-			merge(&errs, &e)
-			continue
+
+		// the resources of CRDs the restore created weren't served when the
+		// restore's resources were prioritized, so restore them after the
+		// remaining resources
+		if resource == kuberesource.CustomResourceDefinitions && ctx.restore.Spec.WaitForCRDsEstablished && !ctx.dryRun {
+			added := ctx.newlyServedResources(ctx.prioritizedResources)
+			serial = append(serial, added...)
+			ctx.prioritizedResources = append(ctx.prioritizedResources, added...)
 		}
+	}
 
-		nsSubDir := filepath.Join(resourcePath, api.NamespaceScopedDir)
-		nsSubDirExists, err := ctx.fileSystem.DirExists(nsSubDir)
+	if len(independent) > 0 {
+		w, e, err := ctx.restoreIndependentResources(independent, resourcesDir, resourceDirsMap, existingNamespaces)
+		merge(&warnings, &w)
+		merge(&errs, &e)
 		if err != nil {
 			addVeleroError(&errs, err)
 			return warnings, errs
 		}
-		if !nsSubDirExists {
+	}
+
+	// TODO timeout?
+	ctx.log.Debug("Waiting on global wait group")
+	waitErrs := ctx.globalWaitGroup.Wait()
+	ctx.log.Debug("Done waiting on global wait group")
+
+	for _, err := range waitErrs {
+		// TODO not ideal to be adding these to Velero-level errors
+		// rather than a specific namespace, but don't have a way
+		// to track the namespace right now.
+		errs.Velero = append(errs.Velero, err.Error())
+	}
+
+	hookWarnings, hookErrs := ctx.waitForPodHooks()
+	merge(&warnings, &hookWarnings)
+	merge(&errs, &hookErrs)
+
+	if len(ctx.restore.Spec.ExpectedCounts) > 0 {
+		mismatches := ctx.checkExpectedCounts()
+		if ctx.restore.Spec.StrictExpectedCounts {
+			errs.Velero = append(errs.Velero, mismatches...)
+		} else {
+			warnings.Velero = append(warnings.Velero, mismatches...)
+		}
+	}
+
+	// the unresolved references report includes the roles and service
+	// accounts of role bindings, so they're not also checked on their own
+	if ctx.restore.Spec.ReportUnresolvedReferences {
+		w := ctx.reportUnresolvedReferences()
+		merge(&warnings, &w)
+	} else if ctx.restore.Spec.ValidateRBACReferences {
+		w := ctx.checkRBACReferences()
+		merge(&warnings, &w)
+	}
+
+	if len(ctx.preBoundVolumes) > 0 {
+		w := ctx.checkVolumeBindings()
+		merge(&warnings, &w)
+	}
+
+	if ctx.restore.Spec.ObserveDriftSeconds > 0 && !ctx.dryRun {
+		w := ctx.observeDrift()
+		merge(&warnings, &w)
+	}
+
+	if ctx.restore.Spec.VerifyAfterRestore && !ctx.dryRun {
+		failures, w := ctx.verifyRestoredItems()
+		errs.VerificationFailures = append(errs.VerificationFailures, failures...)
+		merge(&warnings, &w)
+	}
+
+	return warnings, errs
+}
+
+// resourceDirToRestore returns the backup directory of the specified resource and
+// whether its items should be restored. Namespaces are never restored from their
+// directory, since they're created as the items in them are restored.
+func (ctx *context) resourceDirToRestore(resource schema.GroupResource, resourceDirs map[string]os.FileInfo) (os.FileInfo, bool) {
+	// we don't want to explicitly restore namespace API objs because we'll handle
+	// them as a special case prior to restoring anything into them
+	if resource == kuberesource.Namespaces {
+		return nil, false
+	}
+
+	rscDir := ctx.backupResourceDir(resource, resourceDirs)
+	if rscDir == nil {
+		return nil, false
+	}
+
+	if ctx.excludedByScope(resource) {
+		ctx.log.Infof("Skipping resource %s because the restore's scope filter is %s", resource, ctx.scopeFilter())
+		return nil, false
+	}
+
+	return rscDir, true
+}
+
+// restoreResourceDir restores the items of the specified resource from its directory in
+// the backup, which holds the items of sourceResource, ensuring the namespaces they're
+// restored into exist first. Namespaces known to exist are tracked in existingNamespaces.
+// An error is returned if the directory can't be read, in which case the restore
+// shouldn't continue.
+func (ctx *context) restoreResourceDir(resource, sourceResource schema.GroupResource, resourcePath string, existingNamespaces sets.String, span *resourceSpan) (Result, Result, error) {
+	warnings, errs := Result{}, Result{}
+
+	clusterSubDir := filepath.Join(resourcePath, api.ClusterScopedDir)
+	clusterSubDirExists, err := ctx.fileSystem.DirExists(clusterSubDir)
+	if err != nil {
+		return warnings, errs, err
+	}
+	if clusterSubDirExists {
+		w, e := ctx.restoreResourceInto(resource.String(), []string{""}, itemDir{groupResource: sourceResource, path: clusterSubDir}, span)
+		merge(&warnings, &w)
+		merge(&errs, &e)
+
+		// don't move past the CRDs until they're established and discovery
+		// serves their resources, since their instances can't be created
+		// until then.
+		if resource == kuberesource.CustomResourceDefinitions && ctx.restore.Spec.WaitForCRDsEstablished && !ctx.dryRun {
+			w := ctx.waitForCRDsEstablished()
+			merge(&warnings, &w)
+		}
+
+		// don't move past the CRDs until their conversion webhooks can serve
+		// requests, since instances of the CRDs can't be created until then.
+		if resource == kuberesource.CustomResourceDefinitions && ctx.restore.Spec.WaitForCRDConversionWebhooks && !ctx.dryRun {
+			w := ctx.waitForConversionWebhooks()
+			merge(&warnings, &w)
+		}
+		return warnings, errs, nil
+	}
+
+	if ctx.scopeFilter() == api.ScopeFilterClusterOnly {
+		ctx.log.Infof("Skipping resource %s because it's namespaced and the restore is cluster-scoped only", resource)
+		return warnings, errs, nil
+	}
+
+	nsSubDir := filepath.Join(resourcePath, api.NamespaceScopedDir)
+	nsSubDirExists, err := ctx.fileSystem.DirExists(nsSubDir)
+	if err != nil {
+		return warnings, errs, err
+	}
+	if !nsSubDirExists {
+		return warnings, errs, nil
+	}
+
+	nsDirs, err := ctx.fileSystem.ReadDir(nsSubDir)
+	if err != nil {
+		return warnings, errs, err
+	}
+
+	isolateNamespaces := ctx.restore.Spec.NamespaceParallelism > 1 || ctx.restore.Spec.NamespaceTimeout.Duration > 0
+	var nsRestores []namespaceRestore
+	for _, nsDir := range nsDirs {
+		if !nsDir.IsDir() {
+			continue
+		}
+		nsName := nsDir.Name()
+		nsItemDir := itemDir{groupResource: sourceResource, namespace: nsName, path: filepath.Join(nsSubDir, nsName)}
+
+		if !ctx.namespaceIncludesExcludes.ShouldInclude(nsName) {
+			ctx.log.Infof("Skipping namespace %s", nsName)
 			continue
 		}
 
-		nsDirs, err := ctx.fileSystem.ReadDir(nsSubDir)
+		// don't create target namespaces for a namespace with no items
+		// of this resource, e.g. in a filtered backup
+		nsItems, err := ctx.listDirItems(nsItemDir)
 		if err != nil {
 			addVeleroError(&errs, err)
-			return warnings, errs
+			continue
+		}
+		if len(nsItems) == 0 {
+			ctx.log.Infof("No items to restore for resource '%s' in namespace %s", resource, nsName)
+			continue
 		}
 
-		for _, nsDir := range nsDirs {
-			if !nsDir.IsDir() {
-				continue
-			}
-			nsName := nsDir.Name()
-			nsPath := filepath.Join(nsSubDir, nsName)
-
-			if !ctx.namespaceIncludesExcludes.ShouldInclude(nsName) {
-				ctx.log.Infof("Skipping namespace %s", nsName)
+		// fetch mapped NS names
+		mappedNsNames := ctx.targetNamespaces(nsName)
+
+		var readyNsNames []string
+		for _, mappedNsName := range mappedNsNames {
+			// a namespace that timed out doesn't hold up the rest of
+			// the restore again
+			if ctx.abandonedNamespaces.Has(mappedNsName) {
+				ctx.log.Infof("Skipping resource %s in namespace %s because %s", resource, mappedNsName, namespaceTimedOutReason)
+				for _, name := range nsItems {
+					ctx.recordSkippedItem(resource, mappedNsName, name, namespaceTimedOutReason)
+				}
 				continue
 			}
 
-			// fetch mapped NS name
-			mappedNsName := nsName
-			if target, ok := ctx.restore.Spec.NamespaceMapping[nsName]; ok {
-				mappedNsName = target
-			}
-
 			// if we don't know whether this namespace exists yet, attempt to create
 			// it in order to ensure it exists. Try to get it from the backup tarball
 			// (in order to get any backed-up metadata), but if we don't find it there,
 			// create a blank one.
 			if !existingNamespaces.Has(mappedNsName) {
 				logger := ctx.log.WithField("namespace", nsName)
-				ns := getNamespace(logger, getItemFilePath(ctx.restoreDir, "namespaces", "", nsName), mappedNsName)
-				if _, err := kube.EnsureNamespaceExistsAndIsReady(ns, ctx.namespaceClient, ctx.resourceTerminatingTimeout); err != nil {
+				ns := getNamespace(logger, ctx.getItemSource(), nsName, mappedNsName)
+				if ctx.dryRun {
+					logger.Infof("Dry run: not ensuring namespace %s exists", mappedNsName)
+				} else if err := ctx.ensureNamespace(ns); err != nil {
+					if _, ok := err.(*missingNamespaceError); ok {
+						for _, name := range nsItems {
+							ctx.recordFailedItem(&errs, resource, mappedNsName, name, err)
+						}
+						continue
+					}
 					addVeleroError(&errs, err)
 					continue
 				}
@@ -494,45 +999,60 @@ func (ctx *context) restoreFromDir() (Result, Result) {
 				existingNamespaces.Insert(mappedNsName)
 			}
 
-			w, e := ctx.restoreResource(resource.String(), mappedNsName, nsPath)
-			merge(&warnings, &w)
-			merge(&errs, &e)
+			readyNsNames = append(readyNsNames, mappedNsName)
+		}
+		if len(readyNsNames) == 0 {
+			continue
 		}
-	}
 
-	// TODO timeout?
-	ctx.log.Debug("Waiting on global wait group")
-	waitErrs := ctx.globalWaitGroup.Wait()
-	ctx.log.Debug("Done waiting on global wait group")
+		// namespaces are restored in isolation from each other once their
+		// target namespaces are ready, if the restore restores them
+		// concurrently or times them out
+		if isolateNamespaces {
+			nsRestores = append(nsRestores, namespaceRestore{namespaces: readyNsNames, source: nsItemDir})
+			continue
+		}
 
-	for _, err := range waitErrs {
-		// TODO not ideal to be adding these to Velero-level errors
-		// rather than a specific namespace, but don't have a way
-		// to track the namespace right now.
-		errs.Velero = append(errs.Velero, err.Error())
+		w, e := ctx.restoreResourceInto(resource.String(), readyNsNames, nsItemDir, span)
+		merge(&warnings, &w)
+		merge(&errs, &e)
 	}
 
-	return warnings, errs
+	if isolateNamespaces {
+		w, e := ctx.restoreNamespaces(resource, nsRestores, span)
+		merge(&warnings, &w)
+		merge(&errs, &e)
+	}
+
+	return warnings, errs, nil
 }
 
+// getItemFilePath returns the path of the uncompressed item file of the specified item.
+// Readers of the path fall back to the gzip-compressed item file if there's none.
 func getItemFilePath(rootDir, groupResource, namespace, name string) string {
+	return filepath.Join(getResourceDir(rootDir, groupResource, namespace), name+itemFileExt)
+}
+
+// getResourceDir returns the directory of the specified resource's items in the
+// specified namespace, or of its cluster-scoped items if namespace is empty.
+func getResourceDir(rootDir, groupResource, namespace string) string {
 	switch namespace {
 	case "":
-		return filepath.Join(rootDir, api.ResourcesDir, groupResource, api.ClusterScopedDir, name+".json")
+		return filepath.Join(rootDir, api.ResourcesDir, groupResource, api.ClusterScopedDir)
 	default:
-		return filepath.Join(rootDir, api.ResourcesDir, groupResource, api.NamespaceScopedDir, namespace, name+".json")
+		return filepath.Join(rootDir, api.ResourcesDir, groupResource, api.NamespaceScopedDir, namespace)
 	}
 }
 
 // getNamespace returns a namespace API object that we should attempt to
-// create before restoring anything into it. It will come from the backup
-// tarball if it exists, else will be a new one. If from the tarball, it
-// will retain its labels, annotations, and spec.
-func getNamespace(logger logrus.FieldLogger, path, remappedName string) *v1.Namespace {
+// create before restoring anything into it. It will come from the provided
+// item source if it exists, else will be a new one. If from the item source,
+// it will retain its labels, annotations, and spec.
+func getNamespace(logger logrus.FieldLogger, source ItemSource, name, remappedName string) *v1.Namespace {
 	var nsBytes []byte
 	var err error
 
-	if nsBytes, err = ioutil.ReadFile(path); err != nil {
+	if nsBytes, err = source.ReadItem(kuberesource.Namespaces, "", name); err != nil {
 		return &v1.Namespace{
 			ObjectMeta: metav1.ObjectMeta{
 				Name: remappedName,
@@ -560,11 +1080,56 @@ func getNamespace(logger logrus.FieldLogger, path, remappedName string) *v1.Name
 	}
 }
 
+// ensureNamespace ensures the provided namespace exists and is ready, and annotates
+// it with the restore's name. The annotation is merged into an existing namespace's
+// annotations. If the restore's namespace creation policy doesn't create namespaces,
+// the namespace is left unchanged, and a *missingNamespaceError is returned if the
+// policy requires it to exist and it doesn't.
+func (ctx *context) ensureNamespace(ns *v1.Namespace) error {
+	switch ctx.restore.Spec.NamespaceCreationPolicy {
+	case api.NamespaceCreationPolicySkipCreate:
+		ctx.log.Infof("Not ensuring namespace %s exists because the restore's namespace creation policy is %s", ns.Name, api.NamespaceCreationPolicySkipCreate)
+		return nil
+	case api.NamespaceCreationPolicyRequireExisting:
+		return ctx.requireNamespace(ns.Name)
+	}
+
+	ns.Labels = overrideMapEntries(ns.Labels, ctx.restore.Spec.NamespaceLabels)
+	ns.Annotations = overrideMapEntries(ns.Annotations, ctx.restore.Spec.NamespaceAnnotations)
+	ns.Annotations[api.LastRestoreAnnotation] = ctx.restore.Name
+
+	_, created, err := kube.EnsureNamespaceExistsAndIsReady(ns, ctx.namespaceClient, ctx.resourceTerminatingTimeout)
+	if err != nil {
+		return err
+	}
+
+	// the namespace may have already existed, in which case
+	// it wasn't created with the annotation
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": ctx.existingNamespaceMetadata(),
+	})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if _, err := ctx.namespaceClient.Patch(ns.Name, types.MergePatchType, patch); err != nil {
+		return errors.Wrapf(err, "error annotating namespace %s", ns.Name)
+	}
+
+	// only a namespace the restore created may still be being set up
+	if delay := ctx.restore.Spec.NamespaceReadyDelay.Duration; delay > 0 && created {
+		ctx.log.Infof("Waiting %v for namespace %s to be set up before restoring items into it", delay, ns.Name)
+		ctx.withoutItemLock(func() { namespaceReadySleep(delay) })
+	}
+
+	return nil
+}
+
 // merge combines two RestoreResult objects into one
 // by appending the corresponding lists to one another.
 func merge(a, b *Result) {
 	a.Cluster = append(a.Cluster, b.Cluster...)
 	a.Velero = append(a.Velero, b.Velero...)
+	a.VerificationFailures = append(a.VerificationFailures, b.VerificationFailures...)
 	for k, v := range b.Namespaces {
 		if a.Namespaces == nil {
 			a.Namespaces = make(map[string][]string)
@@ -709,50 +1274,235 @@ func (ctx *context) shouldRestore(name string, pvClient client.Dynamic) (bool, e
 // restoreResource restores the specified cluster or namespace scoped resource. If namespace is
 // empty we are restoring a cluster level resource, otherwise into the specified namespace.
 func (ctx *context) restoreResource(resource, namespace, resourcePath string) (Result, Result) {
+	return ctx.restoreResourceInto(resource, []string{namespace}, itemDir{groupResource: schema.ParseGroupResource(resource), namespace: namespace, path: resourcePath}, nil)
+}
+
+// restoreResourceInto restores the specified cluster or namespace scoped resource into each
+// of the specified namespaces from the provided directory of the backup. A single empty
+// namespace restores a cluster level resource. Each item is decoded from the backup once
+// and a copy of it is restored into each namespace.
+func (ctx *context) restoreResourceInto(resource string, namespaces []string, source itemDir, span *resourceSpan) (Result, Result) {
 	warnings, errs := Result{}, Result{}
 
-	if ctx.restore.Spec.IncludeClusterResources != nil && !*ctx.restore.Spec.IncludeClusterResources && namespace == "" {
+	clusterScoped := len(namespaces) == 1 && namespaces[0] == ""
+
+	if ctx.restore.Spec.IncludeClusterResources != nil && !*ctx.restore.Spec.IncludeClusterResources && clusterScoped {
 		ctx.log.Infof("Skipping resource %s because it's cluster-scoped", resource)
 		return warnings, errs
 	}
 
-	if namespace != "" {
-		ctx.log.Infof("Restoring resource '%s' into namespace '%s' from: %s", resource, namespace, resourcePath)
+	if !clusterScoped {
+		ctx.log.Infof("Restoring resource '%s' into namespaces '%s' from: %s", resource, strings.Join(namespaces, ", "), source.path)
 	} else {
-		ctx.log.Infof("Restoring cluster level resource '%s' from: %s", resource, resourcePath)
+		ctx.log.Infof("Restoring cluster level resource '%s' from: %s", resource, source.path)
 	}
 
-	files, err := ctx.fileSystem.ReadDir(resourcePath)
+	groupResource := schema.ParseGroupResource(resource)
+
+	names, err := ctx.listDirItems(source)
 	if err != nil {
-		addToResult(&errs, namespace, fmt.Errorf("error reading %q resource directory: %v", resource, err))
+		for _, namespace := range namespaces {
+			addToResult(&errs, namespace, fmt.Errorf("error reading %q resource directory: %v", resource, err))
+		}
 		return warnings, errs
 	}
-	if len(files) == 0 {
+	if len(names) == 0 {
+		ctx.log.Infof("No items to restore for resource '%s' in: %s", resource, source.path)
 		return warnings, errs
 	}
 
-	groupResource := schema.ParseGroupResource(resource)
+	// only the latest revisions of the resource's items are restored, if the
+	// restore filters them
+	olderRevisions, w := ctx.olderRevisions(groupResource, source.groupResource, source.namespace, names, namespaces)
+	merge(&warnings, &w)
 
-	for _, file := range files {
-		fullPath := filepath.Join(resourcePath, file.Name())
-		obj, err := ctx.unmarshal(fullPath)
+	ready := new(readyItems)
+	workers := ctx.startItemWorkers(ctx.itemParallelism(groupResource))
+
+	for i, name := range names {
+		// the rest of the items of a namespace that timed out are skipped
+		if ctx.namespaceTimedOut(namespaces) {
+			for _, name := range names[i:] {
+				for _, namespace := range namespaces {
+					ctx.recordSkippedItem(groupResource, namespace, name, namespaceTimedOutReason)
+				}
+			}
+			break
+		}
+
+		if olderRevisions.Has(name) {
+			ctx.log.Infof("Skipping %s %s because it's %s", groupResource, name, olderRevisionReason)
+			for _, namespace := range namespaces {
+				ctx.recordSkippedItem(groupResource, namespace, name, olderRevisionReason)
+			}
+			continue
+		}
+		obj, err := ctx.readDirItem(source, name)
 		if err != nil {
-			addToResult(&errs, namespace, fmt.Errorf("error decoding %q: %v", strings.Replace(fullPath, ctx.restoreDir+"/", "", -1), err))
+			ctx.recordDecodeError(&warnings, &errs, groupResource, namespaces, filepath.Join(source.path, name+itemFileExt), err)
 			continue
 		}
 
+		// items of renamed API groups are restored under their new group
+		ctx.applyGroupAlias(obj)
+
 		if !ctx.selector.Matches(labels.Set(obj.GetLabels())) {
 			continue
 		}
+		if ctx.excludeSelector != nil && ctx.excludeSelector.Matches(labels.Set(obj.GetLabels())) {
+			ctx.log.Infof("Skipping %s %s because it matches the restore's exclude label selector", groupResource, kube.NamespaceAndName(obj))
+			continue
+		}
 
-		w, e := ctx.restoreItem(obj, groupResource, namespace)
-		merge(&warnings, &w)
-		merge(&errs, &e)
+		// items of deprecated APIs are restored under the APIs that replaced them
+		itemResource, err := ctx.remapGroupVersionKind(obj, groupResource)
+		if err != nil {
+			ctx.log.Warn(err)
+			for _, namespace := range namespaces {
+				addToResult(&warnings, namespace, err)
+				ctx.recordSkippedItem(groupResource, namespace, obj.GetName(), err.Error())
+			}
+			continue
+		}
+
+		sourceNamespace := obj.GetNamespace()
+
+		for i, namespace := range namespaces {
+			if !ctx.includesItemName(itemResource, sourceNamespace, obj.GetName(), namespace) {
+				ctx.log.Infof("Skipping %s %s because it isn't in the restore's included resource names", itemResource, kube.NamespaceAndName(obj))
+				continue
+			}
+
+			if namespace == "" && ctx.isNamespaced(itemResource) {
+				namespace, err = ctx.resolveMissingNamespace(itemResource, obj)
+				if err != nil {
+					ctx.recordFailedItem(&errs, itemResource, "", obj.GetName(), err)
+					continue
+				}
+			}
+
+			// restoring an item modifies it, so each additional
+			// namespace gets its own copy of the decoded item
+			item := obj
+			if i < len(namespaces)-1 {
+				item = obj.DeepCopy()
+			}
+
+			namespace := namespace
+			workers.restore(func() (Result, Result) {
+				return ctx.traceItem(span, itemResource, namespace, item.GetName(), func() (Result, Result) {
+					return ctx.restoreItem(item, itemResource, namespace, ready)
+				})
+			})
+		}
 	}
 
+	w, e := workers.wait()
+	merge(&warnings, &w)
+	merge(&errs, &e)
+
+	// don't move on to the next resource until the created items the
+	// restore waits for are ready
+	readyWarnings, readyErrs := ctx.waitForReadyItems(ready)
+	merge(&w, &readyWarnings)
+	merge(&warnings, &readyWarnings)
+	merge(&errs, &readyErrs)
+
+	ctx.progress.addWarnings(countMessages(w))
+
 	return warnings, errs
 }
 
+// listItemFiles returns the item files in the provided resource directory of fileSystem,
+// logging the entries that are skipped to log. A missing or empty directory has no item
+// files, and entries that aren't item files, such as subdirectories, are ignored.
+func listItemFiles(fileSystem filesystem.Interface, dir string, log logrus.FieldLogger) ([]os.FileInfo, error) {
+	exists, err := fileSystem.DirExists(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	entries, err := fileSystem.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []os.FileInfo
+	for _, entry := range entries {
+		if entry.IsDir() || !isItemFile(entry.Name()) {
+			log.Debugf("Skipping %s in %s because it's not an item file", entry.Name(), dir)
+			continue
+		}
+		files = append(files, entry)
+	}
+
+	return files, nil
+}
+
+// isNamespaced returns true if discovery reports the specified group resource as
+// namespace-scoped.
+func (ctx *context) isNamespaced(groupResource schema.GroupResource) bool {
+	if ctx.discoveryHelper == nil {
+		return false
+	}
+
+	_, apiResource, err := ctx.discoveryHelper.ResourceFor(groupResource.WithVersion(""))
+	if err != nil {
+		return false
+	}
+	return apiResource.Namespaced
+}
+
+// scopeFilter returns the restore's scope filter. A cluster-scoped only restore is the
+// same as one whose scope filter is ClusterOnly.
+func (ctx *context) scopeFilter() api.ScopeFilter {
+	if ctx.restore.Spec.ClusterScopedOnly {
+		return api.ScopeFilterClusterOnly
+	}
+	return ctx.restore.Spec.ScopeFilter
+}
+
+// excludedByScope returns true if the restore's scope filter excludes the specified
+// group resource, according to whether discovery reports it as namespace-scoped.
+func (ctx *context) excludedByScope(groupResource schema.GroupResource) bool {
+	switch ctx.scopeFilter() {
+	case api.ScopeFilterClusterOnly:
+		return ctx.isNamespaced(groupResource)
+	case api.ScopeFilterNamespacedOnly:
+		return !ctx.isNamespaced(groupResource)
+	default:
+		return false
+	}
+}
+
+// resolveMissingNamespace returns the namespace to restore an item of a namespaced
+// resource into when the item doesn't have a namespace in the backup, according to
+// the restore's missing namespace policy, or an error if the item shouldn't be restored.
+func (ctx *context) resolveMissingNamespace(groupResource schema.GroupResource, obj *unstructured.Unstructured) (string, error) {
+	switch ctx.restore.Spec.MissingNamespacePolicy {
+	case api.MissingNamespacePolicyUseDefault:
+		if ctx.restore.Spec.DefaultNamespace == "" {
+			return "", errors.Errorf("%s %s has no namespace and the restore does not specify a default namespace", groupResource, obj.GetName())
+		}
+
+		if !ctx.defaultNamespaceEnsured && !ctx.dryRun {
+			ns := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ctx.restore.Spec.DefaultNamespace}}
+			if err := ctx.ensureNamespace(ns); err != nil {
+				return "", errors.Wrapf(err, "error ensuring default namespace %s exists", ns.Name)
+			}
+			ctx.defaultNamespaceEnsured = true
+		}
+
+		ctx.log.Infof("Restoring %s %s into default namespace %s because it has no namespace", groupResource, obj.GetName(), ctx.restore.Spec.DefaultNamespace)
+		return ctx.restore.Spec.DefaultNamespace, nil
+	default:
+		return "", errors.Errorf("%s %s is namespaced but has no namespace", groupResource, obj.GetName())
+	}
+}
+
 func (ctx *context) getResourceClient(groupResource schema.GroupResource, obj *unstructured.Unstructured, namespace string) (client.Dynamic, error) {
 	key := resourceClientKey{
 		resource:  groupResource,
@@ -781,6 +1531,60 @@ func (ctx *context) getResourceClient(groupResource schema.GroupResource, obj *u
 	return client, nil
 }
 
+// recordItem records the outcome of restoring the specified item.
+func (ctx *context) recordItem(groupResource schema.GroupResource, namespace, name string, outcome ItemOutcome) {
+	ctx.recordItemWithReason(groupResource, namespace, name, outcome, "")
+}
+
+// skippedByActionReason is recorded for items that a restore item action
+// told the restore to skip.
+const skippedByActionReason = "skipped by a restore item action"
+
+// skippedByAction returns true if a restore item action told the restore
+// to skip the specified item.
+func (ctx *context) skippedByAction(groupResource schema.GroupResource, namespace, name string) bool {
+	_, skipped := ctx.skippedItems[velero.ResourceIdentifier{GroupResource: groupResource, Namespace: namespace, Name: name}]
+	return skipped
+}
+
+// recordSkippedItem records that the specified item was skipped, and why.
+func (ctx *context) recordSkippedItem(groupResource schema.GroupResource, namespace, name, reason string) {
+	ctx.recordItemWithReason(groupResource, namespace, name, ItemOutcomeSkipped, reason)
+}
+
+// recordFailedItem adds the provided error to errs and records that the
+// specified item failed to restore because of it.
+func (ctx *context) recordFailedItem(errs *Result, groupResource schema.GroupResource, namespace, name string, err error) {
+	addToResult(errs, namespace, err)
+	ctx.recordItemWithReason(groupResource, namespace, name, ItemOutcomeFailed, err.Error())
+}
+
+// recordItemWithReason records the outcome of restoring the specified item, and why,
+// with the default action for the outcome.
+func (ctx *context) recordItemWithReason(groupResource schema.GroupResource, namespace, name string, outcome ItemOutcome, reason string) {
+	ctx.recordItemWithAction(groupResource, namespace, name, outcome, defaultItemAction(outcome), reason)
+}
+
+// recordItemWithAction records the outcome of restoring the specified item, the action
+// the restore took for it, and why.
+func (ctx *context) recordItemWithAction(groupResource schema.GroupResource, namespace, name string, outcome ItemOutcome, action ItemAction, reason string) {
+	ctx.itemResults = append(ctx.itemResults, ItemResult{
+		GroupResource: groupResource.String(),
+		Namespace:     namespace,
+		Name:          name,
+		Outcome:       outcome,
+		Action:        action,
+		Reason:        reason,
+	})
+
+	ctx.progress.recordOutcome(outcome)
+	ctx.metrics.observeItem(groupResource, outcome)
+
+	if outcome == ItemOutcomeCreated || outcome == ItemOutcomeUpdated {
+		ctx.checkpointItem(getResourceID(groupResource, namespace, name))
+	}
+}
+
 func getResourceID(groupResource schema.GroupResource, namespace, name string) string {
 	if namespace == "" {
 		return fmt.Sprintf("%s/%s", groupResource.String(), name)
@@ -789,7 +1593,7 @@ func getResourceID(groupResource schema.GroupResource, namespace, name string) s
 	return fmt.Sprintf("%s/%s/%s", groupResource.String(), namespace, name)
 }
 
-func (ctx *context) restoreItem(obj *unstructured.Unstructured, groupResource schema.GroupResource, namespace string) (Result, Result) {
+func (ctx *context) restoreItem(obj *unstructured.Unstructured, groupResource schema.GroupResource, namespace string, ready *readyItems) (Result, Result) {
 	warnings, errs := Result{}, Result{}
 	resourceID := getResourceID(groupResource, namespace, obj.GetName())
 
@@ -805,11 +1609,29 @@ func (ctx *context) restoreItem(obj *unstructured.Unstructured, groupResource sc
 		return warnings, errs
 	}
 
+	if ctx.excludedByScope(groupResource) {
+		ctx.log.WithFields(logrus.Fields{
+			"namespace":     obj.GetNamespace(),
+			"name":          obj.GetName(),
+			"groupResource": groupResource.String(),
+		}).Infof("Not restoring item because the restore's scope filter is %s", ctx.scopeFilter())
+		return warnings, errs
+	}
+
 	// Check if namespace/cluster-scoped resource should be restored. We need
 	// to do this here since this method may be getting called for an additional
 	// item which is in a namespace that's excluded, or which is cluster-scoped
 	// and should be excluded.
 	if namespace != "" {
+		if ctx.scopeFilter() == api.ScopeFilterClusterOnly {
+			ctx.log.WithFields(logrus.Fields{
+				"namespace":     obj.GetNamespace(),
+				"name":          obj.GetName(),
+				"groupResource": groupResource.String(),
+			}).Info("Not restoring item because it's namespaced and the restore is cluster-scoped only")
+			return warnings, errs
+		}
+
 		if !ctx.namespaceIncludesExcludes.ShouldInclude(namespace) {
 			ctx.log.WithFields(logrus.Fields{
 				"namespace":     obj.GetNamespace(),
@@ -829,47 +1651,118 @@ func (ctx *context) restoreItem(obj *unstructured.Unstructured, groupResource sc
 		}
 	}
 
+	itemKey := velero.ResourceIdentifier{
+		GroupResource: groupResource,
+		Namespace:     namespace,
+		Name:          obj.GetName(),
+	}
+
+	// items without a name can't be referenced by other items, so they're
+	// only surfaced once and aren't tracked until they're created
+	generateNameOnly := isGenerateNameOnly(obj)
+	if generateNameOnly {
+		if err := ctx.checkGenerateNamePolicy(obj, groupResource); err != nil {
+			ctx.recordFailedItem(&errs, groupResource, namespace, obj.GetGenerateName(), err)
+			return warnings, errs
+		}
+	} else {
+		// Check if we've already restored this, or are in the process of restoring
+		// it. Items can be surfaced more than once across the whole restore, e.g. as
+		// an additional item of an item in another group and again from their own
+		// resource's directory, so this is checked before anything is recorded for
+		// the item.
+		if _, exists := ctx.restoredItems[itemKey]; exists {
+			ctx.log.Infof("Skipping %s because it's already been restored.", resourceID)
+			return warnings, errs
+		}
+		if _, pending := ctx.pendingItems[itemKey]; pending {
+			ctx.log.Infof("Skipping %s because it's already being restored.", resourceID)
+			return warnings, errs
+		}
+		if ctx.pendingItems == nil {
+			ctx.pendingItems = make(map[velero.ResourceIdentifier]struct{})
+		}
+		ctx.pendingItems[itemKey] = struct{}{}
+		defer func() {
+			delete(ctx.pendingItems, itemKey)
+			ctx.restoredItems[itemKey] = struct{}{}
+		}()
+
+		// a resumed restore doesn't restore the items it checkpointed again
+		if ctx.checkpoint.has(resourceID) {
+			ctx.log.Infof("Skipping %s because it was restored before the restore was resumed.", resourceID)
+			ctx.recordItemWithAction(groupResource, namespace, obj.GetName(), ItemOutcomeSkipped, ItemActionSkippedExists, checkpointedReason)
+			return warnings, errs
+		}
+	}
+
 	// make a copy of object retrieved from backup
 	// to make it available unchanged inside restore actions
 	itemFromBackup := obj.DeepCopy()
 
+	name := obj.GetName()
+
 	complete, err := isCompleted(obj, groupResource)
 	if err != nil {
-		addToResult(&errs, namespace, fmt.Errorf("error checking completion of %q: %v", resourceID, err))
+		ctx.recordFailedItem(&errs, groupResource, namespace, name, fmt.Errorf("error checking completion of %q: %v", resourceID, err))
 		return warnings, errs
 	}
 	if complete {
 		ctx.log.Infof("%s is complete - skipping", kube.NamespaceAndName(obj))
+		ctx.recordItem(groupResource, namespace, name, ItemOutcomeSkipped)
 		return warnings, errs
 	}
 
-	name := obj.GetName()
-
-	// Check if we've already restored this
-	itemKey := velero.ResourceIdentifier{
-		GroupResource: groupResource,
-		Namespace:     namespace,
-		Name:          name,
+	if maxAge := ctx.restore.Spec.MaxObjectAge.Duration; maxAge > 0 {
+		if created := obj.GetCreationTimestamp(); !created.IsZero() && time.Since(created.Time) > maxAge {
+			ctx.log.Infof("%s is older than the maximum object age of %v - skipping", kube.NamespaceAndName(obj), maxAge)
+			ctx.recordSkippedItem(groupResource, namespace, name, fmt.Sprintf("created at %s, older than the maximum object age of %v", created.UTC().Format(time.RFC3339), maxAge))
+			return warnings, errs
+		}
 	}
-	if _, exists := ctx.restoredItems[itemKey]; exists {
-		ctx.log.Infof("Skipping %s because it's already been restored.", resourceID)
-		return warnings, errs
+
+	if ctx.configReferences != nil && !ctx.isReferencedConfig(obj, groupResource) {
+		if ctx.restore.Spec.PruneUnreferenced {
+			ctx.log.Infof("%s is not referenced by any restored workload - skipping", kube.NamespaceAndName(obj))
+			ctx.recordSkippedItem(groupResource, namespace, name, unreferencedReason)
+			return warnings, errs
+		}
+
+		addToResult(&warnings, namespace, errors.Errorf("%s %s is not referenced by any restored workload", groupResource, kube.NamespaceAndName(obj)))
 	}
-	ctx.restoredItems[itemKey] = struct{}{}
 
 	// TODO: move to restore item action if/when we add a ShouldRestore() method to the interface
 	if groupResource == kuberesource.Pods && obj.GetAnnotations()[v1.MirrorPodAnnotationKey] != "" {
 		ctx.log.Infof("Not restoring pod because it's a mirror pod")
+		ctx.recordItem(groupResource, namespace, name, ItemOutcomeSkipped)
 		return warnings, errs
 	}
 
 	resourceClient, err := ctx.getResourceClient(groupResource, obj, namespace)
 	if err != nil {
-		addVeleroError(&errs, fmt.Errorf("error getting resource client for namespace %q, resource %q: %v", namespace, &groupResource, err))
+		err = fmt.Errorf("error getting resource client for namespace %q, resource %q: %v", namespace, &groupResource, err)
+		addVeleroError(&errs, err)
+		ctx.recordItemWithReason(groupResource, namespace, name, ItemOutcomeFailed, err.Error())
 		return warnings, errs
 	}
+	resourceClient = ctx.withNamespaceDeadline(ctx.withItemOperationTimeout(resourceClient), namespace)
+
+	transforms := ctx.newAppliedTransforms()
+
+	// createdAction and createdReason are how the item was created, and
+	// why, if it wasn't created as backed up
+	createdAction, createdReason := ItemActionCreated, ""
 
 	if groupResource == kuberesource.PersistentVolumes {
+		// a volume snapshotted through CSI is provisioned from its
+		// VolumeSnapshot when its claim is restored, so the PV isn't
+		// restored and no volume snapshotter is used
+		if snapshot := ctx.csiSnapshot(name); snapshot != nil {
+			ctx.log.Infof("Not restoring PV because it's provisioned from CSI volume snapshot %s when its claim is restored", snapshot.Spec.CSI.VolumeSnapshotName)
+			ctx.recordItemWithAction(groupResource, namespace, name, ItemOutcomeSkipped, ItemActionProvisionedPV, csiSnapshotReason)
+			return warnings, errs
+		}
+
 		var hasSnapshot bool
 
 		for _, snapshot := range ctx.volumeSnapshots {
@@ -882,38 +1775,85 @@ func (ctx *context) restoreItem(obj *unstructured.Unstructured, groupResource sc
 		if !hasSnapshot && hasDeleteReclaimPolicy(obj.Object) {
 			ctx.log.Infof("Not restoring PV because it doesn't have a snapshot and its reclaim policy is Delete.")
 			ctx.pvsToProvision.Insert(name)
+			ctx.recordItemWithAction(groupResource, namespace, name, ItemOutcomeSkipped, ItemActionProvisionedPV, dynamicProvisionReason)
 			return warnings, errs
 		}
 
 		// Check if the PV exists in the cluster before attempting to create
-		// a volume from the snapshot, in order to avoid orphaned volumes (GH #609)
-		shouldRestoreSnapshot, err := ctx.shouldRestore(name, resourceClient)
-		if err != nil {
-			addToResult(&errs, namespace, errors.Wrapf(err, "error waiting on in-cluster persistentvolume %s", name))
-			return warnings, errs
+		// a volume from the snapshot, in order to avoid orphaned volumes (GH #609).
+		// A dry run doesn't wait on in-cluster PVs, since its PV action doesn't
+		// create volumes.
+		shouldRestoreSnapshot := true
+		if !ctx.dryRun {
+			shouldRestoreSnapshot, err = ctx.shouldRestore(name, resourceClient)
+			if err != nil {
+				ctx.recordFailedItem(&errs, groupResource, namespace, name, errors.Wrapf(err, "error waiting on in-cluster persistentvolume %s", name))
+				return warnings, errs
+			}
 		}
 
+		// remap the storage class before any volume is created for the PV
+		transforms.track(transformStorageClassMappings, obj, func() { ctx.remapStorageClass(obj, groupResource) })
+
 		// PV's existence will be recorded later. Just skip the volume restore logic.
 		if shouldRestoreSnapshot {
 			// restore the PV from snapshot (if applicable)
+			beforePVAction := transforms.snapshot(obj)
 			updatedObj, err := ctx.pvRestorer.executePVAction(obj)
 			if err != nil {
-				addToResult(&errs, namespace, fmt.Errorf("error executing PVAction for %s: %v", resourceID, err))
+				ctx.recordFailedItem(&errs, groupResource, namespace, name, fmt.Errorf("error executing PVAction for %s: %v", resourceID, err))
 				return warnings, errs
 			}
+			transforms.recordIfChanged(transformPVSnapshotRestore, beforePVAction, updatedObj)
+
+			// the PV action returns the PV itself unless it restored
+			// its volume from a snapshot
+			if updatedObj != obj {
+				createdAction, createdReason = ItemActionProvisionedPV, snapshotProvisionReason
+			}
 			obj = updatedObj
+
+			// bind the PV to its claim before the claim is restored,
+			// so no other claim can bind to it first
+			if ctx.restore.Spec.PreBindVolumes {
+				transforms.track(transformPreBindVolume, obj, func() { ctx.preBindVolume(obj, itemFromBackup) })
+			}
 		} else if err != nil {
-			addToResult(&errs, namespace, fmt.Errorf("error checking existence for PV %s: %v", name, err))
+			ctx.recordFailedItem(&errs, groupResource, namespace, name, fmt.Errorf("error checking existence for PV %s: %v", name, err))
 			return warnings, errs
 		}
 	}
 
+	// the item's UID and owner references from the backup are cleared out
+	// with its other metadata, but are needed to remap its owner references
+	sourceUID, ownerRefs := obj.GetUID(), obj.GetOwnerReferences()
+
 	// clear out non-core metadata fields & status
-	if obj, err = resetMetadataAndStatus(obj); err != nil {
-		addToResult(&errs, namespace, err)
+	if obj, err = resetMetadataAndStatus(obj, ctx.annotationFilter); err != nil {
+		ctx.recordFailedItem(&errs, groupResource, namespace, name, err)
 		return warnings, errs
 	}
 
+	// remove the fields the restore excludes from the resource's items
+	transforms.track(transformFieldExclusions, obj, func() { ctx.excludeFields(obj, groupResource) })
+
+	// clear stale leader election records, unless the restore keeps them
+	if !ctx.restore.Spec.KeepLeaderElectionRecords {
+		transforms.track(transformLeaderElectionRecords, obj, func() { ctx.clearLeaderElectionRecords(obj, groupResource) })
+	}
+
+	// point the item's owner references at the owners' restored UIDs, if the
+	// restore remaps them
+	if ctx.restore.Spec.RemapOwnerReferences {
+		transforms.track(transformOwnerReferences, obj, func() { ctx.remapOwnerReferences(obj, ownerRefs) })
+	}
+
+	// only restore the restic data of the pod's volumes whose claims
+	// match the restore's PVC data selector
+	if groupResource == kuberesource.Pods {
+		transforms.track(transformPVCDataSelector, obj, func() { ctx.selectPodVolumeData(obj) })
+	}
+
 	for _, action := range ctx.getApplicableActions(groupResource, namespace) {
 		if !action.selector.Matches(labels.Set(obj.GetLabels())) {
 			return warnings, errs
@@ -921,32 +1861,41 @@ func (ctx *context) restoreItem(obj *unstructured.Unstructured, groupResource sc
 
 		ctx.log.Infof("Executing item action for %v", &groupResource)
 
+		beforeAction := transforms.snapshot(obj)
+
 		executeOutput, err := action.Execute(&velero.RestoreItemActionExecuteInput{
 			Item:           obj,
 			ItemFromBackup: itemFromBackup,
 			Restore:        ctx.restore,
 		})
 		if err != nil {
-			addToResult(&errs, namespace, fmt.Errorf("error preparing %s: %v", resourceID, err))
+			ctx.recordFailedItem(&errs, groupResource, namespace, name, fmt.Errorf("error preparing %s: %v", resourceID, err))
 			return warnings, errs
 		}
 
 		if executeOutput.SkipRestore {
 			ctx.log.Infof("Skipping restore of %s: %v because a registered plugin discarded it", obj.GroupVersionKind().Kind, name)
+			if ctx.skippedItems == nil {
+				ctx.skippedItems = make(map[velero.ResourceIdentifier]struct{})
+			}
+			ctx.skippedItems[itemKey] = struct{}{}
+			ctx.recordSkippedItem(groupResource, namespace, name, skippedByActionReason)
 			return warnings, errs
 		}
 		unstructuredObj, ok := executeOutput.UpdatedItem.(*unstructured.Unstructured)
 		if !ok {
-			addToResult(&errs, namespace, fmt.Errorf("%s: unexpected type %T", resourceID, executeOutput.UpdatedItem))
+			ctx.recordFailedItem(&errs, groupResource, namespace, name, fmt.Errorf("%s: unexpected type %T", resourceID, executeOutput.UpdatedItem))
 			return warnings, errs
 		}
 
+		transforms.recordIfChanged(transformRestoreItemAction, beforeAction, unstructuredObj)
 		obj = unstructuredObj
 
 		for _, additionalItem := range executeOutput.AdditionalItems {
 			itemPath := getItemFilePath(ctx.restoreDir, additionalItem.GroupResource.String(), additionalItem.Namespace, additionalItem.Name)
 
-			if _, err := ctx.fileSystem.Stat(itemPath); err != nil {
+			data, err := ctx.getItemSource().ReadItem(additionalItem.GroupResource, additionalItem.Namespace, additionalItem.Name)
+			if err != nil {
 				ctx.log.WithError(err).WithFields(logrus.Fields{
 					"additionalResource":          additionalItem.GroupResource.String(),
 					"additionalResourceNamespace": additionalItem.Namespace,
@@ -957,12 +1906,6 @@ func (ctx *context) restoreItem(obj *unstructured.Unstructured, groupResource sc
 				continue
 			}
 
-			additionalResourceID := getResourceID(additionalItem.GroupResource, additionalItem.Namespace, additionalItem.Name)
-			additionalObj, err := ctx.unmarshal(itemPath)
-			if err != nil {
-				addToResult(&errs, namespace, errors.Wrapf(err, "error restoring additional item %s", additionalResourceID))
-			}
-
 			additionalItemNamespace := additionalItem.Namespace
 			if additionalItemNamespace != "" {
 				if remapped, ok := ctx.restore.Spec.NamespaceMapping[additionalItemNamespace]; ok {
@@ -970,7 +1913,13 @@ func (ctx *context) restoreItem(obj *unstructured.Unstructured, groupResource sc
 				}
 			}
 
-			w, e := ctx.restoreItem(additionalObj, additionalItem.GroupResource, additionalItemNamespace)
+			additionalObj, err := decodeItem(data)
+			if err != nil {
+				ctx.recordDecodeError(&warnings, &errs, additionalItem.GroupResource, []string{additionalItemNamespace}, itemPath, err)
+				continue
+			}
+
+			w, e := ctx.restoreItem(additionalObj, additionalItem.GroupResource, additionalItemNamespace, ready)
 			merge(&warnings, &w)
 			merge(&errs, &e)
 		}
@@ -987,12 +1936,27 @@ func (ctx *context) restoreItem(obj *unstructured.Unstructured, groupResource sc
 	if groupResource == kuberesource.PersistentVolumeClaims {
 		pvc := new(v1.PersistentVolumeClaim)
 		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), pvc); err != nil {
-			addToResult(&errs, namespace, err)
+			ctx.recordFailedItem(&errs, groupResource, namespace, name, err)
 			return warnings, errs
 		}
 
-		if pvc.Spec.VolumeName != "" && ctx.pvsToProvision.Has(pvc.Spec.VolumeName) {
-			ctx.log.Infof("Resetting PersistentVolumeClaim %s/%s for dynamic provisioning because its PV %v has a reclaim policy of Delete", namespace, name, pvc.Spec.VolumeName)
+		// a claim whose PV was skipped by an action would never bind, so it's
+		// provisioned a new volume instead
+		csiSnapshot := ctx.csiSnapshot(pvc.Spec.VolumeName)
+
+		var resetReason string
+		switch {
+		case pvc.Spec.VolumeName == "":
+		case csiSnapshot != nil:
+			resetReason = "is provisioned from a CSI volume snapshot"
+		case ctx.pvsToProvision.Has(pvc.Spec.VolumeName):
+			resetReason = "has a reclaim policy of Delete"
+		case ctx.skippedByAction(kuberesource.PersistentVolumes, "", pvc.Spec.VolumeName):
+			resetReason = "was skipped by a restore item action"
+		}
+
+		if resetReason != "" {
+			ctx.log.Infof("Resetting PersistentVolumeClaim %s/%s for dynamic provisioning because its PV %v %s", namespace, name, pvc.Spec.VolumeName, resetReason)
 
 			// use the unstructured helpers here since we're only deleting and
 			// the unstructured converter will add back (empty) fields for metadata
@@ -1002,42 +1966,247 @@ func (ctx *context) restoreItem(obj *unstructured.Unstructured, groupResource sc
 			delete(annotations, "pv.kubernetes.io/bind-completed")
 			delete(annotations, "pv.kubernetes.io/bound-by-controller")
 			obj.SetAnnotations(annotations)
+
+			transforms.record(transformPVCVolumeNameReset)
+		}
+
+		if csiSnapshot != nil {
+			ctx.log.Infof("Setting data source of PersistentVolumeClaim %s/%s to CSI volume snapshot %s", namespace, name, csiSnapshot.Spec.CSI.VolumeSnapshotName)
+			setCSISnapshotDataSource(obj, csiSnapshot)
+			transforms.record(transformCSISnapshotDataSource)
+		}
+	}
+
+	// replace the values of secret keys with the restore's substitutions
+	transforms.track(transformSecretSubstitutions, obj, func() { ctx.substituteSecretValues(obj, groupResource) })
+
+	// rename or remove config map and secret keys, and warn about any
+	// pod spec references to keys that won't exist after the restore
+	transforms.track(transformDataKeyMappings, obj, func() { ctx.applyDataKeyMappings(obj, groupResource) })
+	for _, err := range ctx.checkDataKeyReferences(obj, groupResource) {
+		addToResult(&warnings, namespace, err)
+	}
+
+	// point kubeconfigs stored in secrets at the target cluster
+	if groupResource == kuberesource.Secrets {
+		transforms.track(transformKubeconfigRewrites, obj, func() {
+			for _, err := range ctx.rewriteKubeconfigs(obj) {
+				addToResult(&warnings, namespace, err)
+			}
+		})
+	}
+
+	// don't restore a second default storage class
+	if groupResource == kuberesource.StorageClasses {
+		transforms.track(transformDefaultStorageClass, obj, func() {
+			if err := ctx.resolveDefaultStorageClass(obj, resourceClient); err != nil {
+				addToResult(&warnings, namespace, err)
+			}
+		})
+	}
+
+	// remap the storage classes of claims
+	if groupResource == kuberesource.PersistentVolumeClaims {
+		transforms.track(transformStorageClassMappings, obj, func() { ctx.remapStorageClass(obj, groupResource) })
+	}
+
+	// pick a storage class for dynamically provisioned claims
+	if groupResource == kuberesource.PersistentVolumeClaims {
+		transforms.track(transformStorageClassPreference, obj, func() {
+			if err := ctx.chooseStorageClass(obj); err != nil {
+				addToResult(&warnings, namespace, err)
+			}
+		})
+
+		// raise the storage request of claims smaller than the target allows
+		sizeWarnings, err := ctx.enforcePVCMinimumSize(obj)
+		if err != nil {
+			ctx.recordFailedItem(&errs, groupResource, namespace, name, errors.Wrapf(err, "error restoring %s", resourceID))
+			return warnings, errs
+		}
+		for _, w := range sizeWarnings {
+			addToResult(&warnings, namespace, w)
+		}
+		if len(sizeWarnings) > 0 {
+			transforms.record(transformPVCMinimumSize)
+		}
+	}
+
+	// rewrite the CSI volume attributes of persistent volumes, before their
+	// drivers are remapped since mappings are keyed by the backed-up driver
+	if groupResource == kuberesource.PersistentVolumes {
+		transforms.track(transformCSIVolumeAttributes, obj, func() { ctx.remapCSIVolumeAttributes(obj) })
+	}
+
+	// point the node affinity of persistent volumes at the cluster's nodes
+	if groupResource == kuberesource.PersistentVolumes && len(ctx.restore.Spec.NodeMappings) > 0 {
+		transforms.track(transformNodeMappings, obj, func() {
+			if err := ctx.remapNodeAffinity(obj); err != nil {
+				addToResult(&warnings, namespace, err)
+			}
+		})
+	}
+
+	// remap the CSI drivers of persistent volumes and volume snapshot contents
+	transforms.track(transformCSIDriverMappings, obj, func() {
+		if err := ctx.remapCSIDriver(obj, groupResource); err != nil {
+			addToResult(&warnings, namespace, err)
 		}
+	})
+
+	// remap any Gateway API references configured on the restore
+	transforms.track(transformGatewayMappings, obj, func() { ctx.remapGatewayReferences(obj, groupResource) })
+
+	// point references to credential secrets at the secrets mapped by the restore
+	transforms.track(transformCredentialSecretMappings, obj, func() { ctx.remapCredentialSecrets(obj, groupResource) })
+
+	// apply any pod spec overrides configured on the restore
+	transforms.track(transformPodSpecOverrides, obj, func() { ctx.transformPodSpec(obj, groupResource) })
+
+	// relax volume settings that locked-down clusters may reject
+	volumeSettingsWarnings := ctx.relaxVolumeSettings(obj, groupResource)
+	for _, w := range volumeSettingsWarnings {
+		addToResult(&warnings, namespace, w)
+	}
+	if len(volumeSettingsWarnings) > 0 {
+		transforms.record(transformRelaxVolumeSettings)
+	}
+
+	// apply any service field overrides configured on the restore
+	transforms.track(transformServiceFieldOverrides, obj, func() { ctx.overrideServiceFields(obj, groupResource) })
+
+	// keep restored Velero objects from acting on the cluster
+	transforms.track(transformSuspendVeleroObjects, obj, func() { ctx.suspendVeleroObject(obj, groupResource) })
+
+	// fix up fields that the cluster's Kubernetes version has removed or renamed
+	fixupWarnings, err := ctx.applyVersionFixups(obj, groupResource)
+	if err != nil {
+		ctx.recordFailedItem(&errs, groupResource, namespace, name, errors.Wrapf(err, "error restoring %s", resourceID))
+		return warnings, errs
+	}
+	for _, w := range fixupWarnings {
+		addToResult(&warnings, namespace, w)
+	}
+	if len(fixupWarnings) > 0 {
+		transforms.record(transformVersionFixups)
 	}
 
+	// let horizontal pod autoscalers in the backup own their targets' replica counts
+	transforms.track(transformHPAManagedReplicas, obj, func() { ctx.stripHPAManagedReplicas(obj) })
+
+	// create workloads scaled down so they can be validated before they run
+	transforms.track(transformScaleDown, obj, func() { ctx.scaleDownWorkload(obj, groupResource) })
+
 	// necessary because we may have remapped the namespace
 	// if the namespace is blank, don't create the key
 	originalNamespace := obj.GetNamespace()
 	if namespace != "" {
 		obj.SetNamespace(namespace)
+
+		if namespace != originalNamespace {
+			transforms.record(transformNamespaceMapping)
+		}
+	}
+
+	// rename the item and its references to other renamed items, if the restore
+	// rewrites names, once the item's other fields are final
+	if !generateNameOnly {
+		var rewriteErr error
+		transforms.track(transformNameRewrite, obj, func() { name, rewriteErr = ctx.applyNameRewrite(obj, groupResource, originalNamespace) })
+		if rewriteErr != nil {
+			ctx.recordFailedItem(&errs, groupResource, namespace, obj.GetName(), errors.Wrapf(rewriteErr, "error rewriting name of %s", resourceID))
+			return warnings, errs
+		}
 	}
 
+	// add the restore's extra labels before the restore labels, so they can't
+	// replace the restore and backup name labels
+	addExtraLabels(obj, ctx.restore.Spec.ExtraLabels, ctx.restore.Spec.OverwriteExistingLabels)
+
 	// label the resource with the restore's name and the restored backup's name
 	// for easy identification of all cluster resources created by this restore
-	// and which backup they came from
-	addRestoreLabels(obj, ctx.restore.Name, ctx.restore.Spec.BackupName)
+	// and which backup they came from, unless the restore skips them
+	if !ctx.restore.Spec.SkipRestoreLabels {
+		addRestoreLabels(obj, ctx.restore.Name, ctx.restore.Spec.BackupName)
+	}
+
+	// catch oversize labels and annotations here, since the API server
+	// would otherwise reject the item
+	metadataWarnings, err := ctx.enforceMetadataLimits(obj)
+	if err != nil {
+		ctx.recordFailedItem(&errs, groupResource, namespace, name, errors.Wrapf(err, "error restoring %s", resourceID))
+		return warnings, errs
+	}
+	for _, w := range metadataWarnings {
+		addToResult(&warnings, namespace, w)
+	}
+	if len(metadataWarnings) > 0 {
+		transforms.record(transformMetadataLimits)
+	}
+
+	// note which transforms changed the item, if the restore asks for it
+	transforms.annotate(obj)
+
+	if ctx.dryRun {
+		return ctx.dryRunCreate(obj, groupResource, namespace, resourceClient, warnings, errs)
+	}
 
 	ctx.log.Infof("Attempting to restore %s: %v", obj.GroupVersionKind().Kind, name)
-	createdObj, restoreErr := resourceClient.Create(obj)
+	createdObj, restoreErr := ctx.createWithRetry(obj, resourceClient)
+	if violation, ok := getPodSecurityViolation(restoreErr); ok {
+		createdObj, restoreErr = ctx.handlePodSecurityRejection(obj, violation, resourceClient)
+	}
+	if isApplyConflict(restoreErr) {
+		ctx.log.Infof("Not restoring %s because it conflicts with another field manager: %v", kube.NamespaceAndName(obj), restoreErr)
+		addToResult(&warnings, namespace, errors.Errorf("not restored: %s %s: %v", resourceID, applyConflictReason, restoreErr))
+		ctx.recordSkippedItem(groupResource, namespace, name, applyConflictReason)
+		return warnings, errs
+	}
 	if apierrors.IsAlreadyExists(restoreErr) {
+		// unless the in-cluster object gets updated below, the
+		// backed-up version isn't restored.
+		outcome, action, reason := ItemOutcomeSkipped, ItemActionSkippedExists, alreadyExistsReason
+		defer func() { ctx.recordItemWithAction(groupResource, namespace, name, outcome, action, reason) }()
+
+		// the item fails if getting or updating the in-cluster version
+		// times out, or its namespace does, and is left as it is on
+		// other errors
+		addExistingItemError := func(err error) {
+			if isItemOperationTimeout(err) || isNamespaceTimeout(err) {
+				outcome, action, reason = ItemOutcomeFailed, ItemActionFailed, err.Error()
+				addToResult(&errs, namespace, err)
+				return
+			}
+			addToResult(&warnings, namespace, err)
+		}
+
 		fromCluster, err := resourceClient.Get(name, metav1.GetOptions{})
 		if err != nil {
 			ctx.log.Infof("Error retrieving cluster version of %s: %v", kube.NamespaceAndName(obj), err)
-			addToResult(&warnings, namespace, err)
+			addExistingItemError(err)
 			return warnings, errs
 		}
+		// dependents of the item refer to the version in the cluster
+		ctx.recordRestoredUID(sourceUID, fromCluster.GetUID())
+
 		// Remove insubstantial metadata
-		fromCluster, err = resetMetadataAndStatus(fromCluster)
+		fromCluster, err = resetMetadataAndStatus(fromCluster, ctx.annotationFilter)
 		if err != nil {
 			ctx.log.Infof("Error trying to reset metadata for %s: %v", kube.NamespaceAndName(obj), err)
 			addToResult(&warnings, namespace, err)
 			return warnings, errs
 		}
 
+		// The object from the cluster may not have the restore's extra labels either,
+		// so add them the same way they were added to the object we attempted to restore.
+		addExtraLabels(fromCluster, ctx.restore.Spec.ExtraLabels, ctx.restore.Spec.OverwriteExistingLabels)
+
 		// We know the object from the cluster won't have the backup/restore name labels, so
-		// copy them from the object we attempted to restore.
-		labels := obj.GetLabels()
-		addRestoreLabels(fromCluster, labels[api.RestoreNameLabel], labels[api.BackupNameLabel])
+		// copy them from the object we attempted to restore, if it has them.
+		if !ctx.restore.Spec.SkipRestoreLabels {
+			labels := obj.GetLabels()
+			addRestoreLabels(fromCluster, labels[api.RestoreNameLabel], labels[api.BackupNameLabel])
+		}
 
 		if !equality.Semantic.DeepEqual(fromCluster, obj) {
 			switch groupResource {
@@ -1061,13 +2230,29 @@ func (ctx *context) restoreItem(obj *unstructured.Unstructured, groupResource sc
 					return warnings, errs
 				}
 
-				_, err = resourceClient.Patch(name, patchBytes)
-				if err != nil {
-					addToResult(&warnings, namespace, err)
+				if _, err := resourceClient.Patch(name, patchBytes); err != nil {
+					addExistingItemError(err)
 				} else {
 					ctx.log.Infof("ServiceAccount %s successfully updated", kube.NamespaceAndName(obj))
+					outcome, action, reason = ItemOutcomeUpdated, ItemActionUpdated, ""
 				}
 			default:
+				if ctx.restore.Spec.ExistingResourcePolicy == api.ExistingResourcePolicySkipQuiet {
+					ctx.log.Infof("Skipping restore of %s: %v because it already exists in the cluster", obj.GroupVersionKind().Kind, name)
+					break
+				}
+
+				if ctx.restore.Spec.ExistingResourcePolicy == api.ExistingResourcePolicyUpdate {
+					updated, err := ctx.updateExisting(obj, itemFromBackup, fromCluster, groupResource, resourceClient)
+					if err != nil {
+						addExistingItemError(err)
+					} else if updated {
+						ctx.log.Infof("%s %s successfully updated", obj.GroupVersionKind().Kind, kube.NamespaceAndName(obj))
+						outcome, action, reason = ItemOutcomeUpdated, ItemActionUpdated, ""
+					}
+					break
+				}
+
 				e := errors.Errorf("not restored: %s and is different from backed up version.", restoreErr)
 				addToResult(&warnings, namespace, e)
 			}
@@ -1081,10 +2266,60 @@ func (ctx *context) restoreItem(obj *unstructured.Unstructured, groupResource sc
 	// Error was something other than an AlreadyExists
 	if restoreErr != nil {
 		ctx.log.Infof("error restoring %s: %v", name, restoreErr)
-		addToResult(&errs, namespace, fmt.Errorf("error restoring %s: %v", resourceID, restoreErr))
+		ctx.recordFailedItem(&errs, groupResource, namespace, name, fmt.Errorf("error restoring %s: %v", resourceID, restoreErr))
 		return warnings, errs
 	}
 
+	if generateNameOnly {
+		name = ctx.recordGeneratedName(createdObj, groupResource, namespace)
+	}
+
+	if err := ctx.captureReplayItem(obj, groupResource, namespace, name); err != nil {
+		ctx.log.Warn(err)
+		addToResult(&warnings, namespace, err)
+	}
+
+	// applying an item that's already in the cluster updates it
+	createdOutcome := ItemOutcomeCreated
+	if ctx.restore.Spec.ApplyMethod == api.ApplyMethodServerSideApply && appliedOverExisting(createdObj) {
+		createdOutcome, createdAction, createdReason = ItemOutcomeUpdated, ItemActionUpdated, ""
+	}
+
+	ctx.recordItemWithAction(groupResource, namespace, name, createdOutcome, createdAction, createdReason)
+	ctx.recordRestoredUID(sourceUID, createdObj.GetUID())
+	ctx.recordRestoredBinding(createdObj, groupResource)
+	ctx.recordRestoredReferences(createdObj, groupResource)
+	ctx.recordPreBoundVolume(createdObj, groupResource)
+	if ctx.restore.Spec.ObserveDriftSeconds > 0 {
+		ctx.createdItems = append(ctx.createdItems, createdItem{
+			groupResource: groupResource,
+			namespace:     namespace,
+			client:        resourceClient,
+			obj:           createdObj,
+		})
+	}
+
+	ready.add(ctx, createdItem{
+		groupResource: groupResource,
+		namespace:     namespace,
+		client:        resourceClient,
+		obj:           createdObj,
+	})
+
+	if groupResource == kuberesource.CustomResourceDefinitions && ctx.restore.Spec.WaitForCRDsEstablished {
+		ctx.restoredCRDs = append(ctx.restoredCRDs, createdItem{
+			groupResource: groupResource,
+			client:        resourceClient,
+			obj:           createdObj,
+		})
+	}
+
+	if groupResource == kuberesource.CustomResourceDefinitions && ctx.restore.Spec.WaitForCRDConversionWebhooks {
+		if svc, ok := getConversionWebhookService(obj); ok {
+			ctx.conversionWebhookServices = append(ctx.conversionWebhookServices, svc)
+		}
+	}
+
 	if groupResource == kuberesource.Pods && len(restic.GetPodSnapshotAnnotations(obj)) > 0 {
 		if ctx.resticRestorer == nil {
 			ctx.log.Warn("No restic restorer, not restoring pod's volumes")
@@ -1096,6 +2331,9 @@ func (ctx *context) restoreItem(obj *unstructured.Unstructured, groupResource sc
 					return []error{err}
 				}
 
+				done := ctx.startVolumeRestore()
+				defer done()
+
 				if errs := ctx.resticRestorer.RestorePodVolumes(ctx.restore, pod, originalNamespace, ctx.backup.Spec.StorageLocation, ctx.log); errs != nil {
 					ctx.log.WithError(kubeerrs.NewAggregate(errs)).Error("unable to successfully complete restic restores of pod's volumes")
 					return errs
@@ -1106,6 +2344,10 @@ func (ctx *context) restoreItem(obj *unstructured.Unstructured, groupResource sc
 		}
 	}
 
+	if groupResource == kuberesource.Pods {
+		ctx.startPodHooks(createdObj, resourceClient)
+	}
+
 	return warnings, errs
 }
 
@@ -1114,7 +2356,59 @@ func hasDeleteReclaimPolicy(obj map[string]interface{}) bool {
 	return policy == string(v1.PersistentVolumeReclaimDelete)
 }
 
-func resetMetadataAndStatus(obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+// annotationFilter filters the annotations of restored items by key prefix. The
+// zero value keeps all annotations.
+type annotationFilter struct {
+	// keepPrefixes, if non-empty, are the prefixes of the keys of
+	// the only annotations that are kept.
+	keepPrefixes []string
+
+	// stripPrefixes are the prefixes of the keys of annotations
+	// that are removed, even if they have a keep prefix.
+	stripPrefixes []string
+}
+
+// keeps returns true if the filter keeps the annotation with the provided key.
+func (f annotationFilter) keeps(key string) bool {
+	for _, prefix := range f.stripPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return false
+		}
+	}
+
+	if len(f.keepPrefixes) == 0 {
+		return true
+	}
+	for _, prefix := range f.keepPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// filter removes the annotations the filter doesn't keep from the provided metadata.
+func (f annotationFilter) filter(metadata map[string]interface{}) {
+	if len(f.keepPrefixes) == 0 && len(f.stripPrefixes) == 0 {
+		return
+	}
+
+	annotations, ok := metadata["annotations"].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	for key := range annotations {
+		if !f.keeps(key) {
+			delete(annotations, key)
+		}
+	}
+	if len(annotations) == 0 {
+		delete(metadata, "annotations")
+	}
+}
+
+func resetMetadataAndStatus(obj *unstructured.Unstructured, annotations annotationFilter) (*unstructured.Unstructured, error) {
 	res, ok := obj.Object["metadata"]
 	if !ok {
 		return nil, errors.New("metadata not found")
@@ -1127,10 +2421,16 @@ func resetMetadataAndStatus(obj *unstructured.Unstructured) (*unstructured.Unstr
 	for k := range metadata {
 		switch k {
 		case "name", "namespace", "labels", "annotations":
+		case "generateName":
+			// items without a name are created with their generate name
+			if name, _ := metadata["name"].(string); name != "" {
+				delete(metadata, k)
+			}
 		default:
 			delete(metadata, k)
 		}
 	}
+	annotations.filter(metadata)
 
 	// Never restore status
 	delete(obj.UnstructuredContent(), "status")
@@ -1153,44 +2453,44 @@ func addRestoreLabels(obj metav1.Object, restoreName, backupName string) {
 	obj.SetLabels(labels)
 }
 
-// isCompleted returns whether or not an object is considered completed.
-// Used to identify whether or not an object should be restored. Only Jobs or Pods are considered
-func isCompleted(obj *unstructured.Unstructured, groupResource schema.GroupResource) (bool, error) {
-	switch groupResource {
-	case kuberesource.Pods:
-		phase, _, err := unstructured.NestedString(obj.UnstructuredContent(), "status", "phase")
-		if err != nil {
-			return false, errors.WithStack(err)
-		}
-		if phase == string(v1.PodFailed) || phase == string(v1.PodSucceeded) {
-			return true, nil
-		}
+// addExtraLabels adds the provided extra labels to the provided object. Labels
+// the object already has keep their values unless overwrite is true.
+func addExtraLabels(obj metav1.Object, extraLabels map[string]string, overwrite bool) {
+	if len(extraLabels) == 0 {
+		return
+	}
 
-	case kuberesource.Jobs:
-		ct, found, err := unstructured.NestedString(obj.UnstructuredContent(), "status", "completionTime")
-		if err != nil {
-			return false, errors.WithStack(err)
-		}
-		if found && ct != "" {
-			return true, nil
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+
+	for key, val := range extraLabels {
+		if _, ok := labels[key]; ok && !overwrite {
+			continue
 		}
+		labels[key] = val
 	}
-	// Assume any other resource isn't complete and can be restored
-	return false, nil
-}
 
-// unmarshal reads the specified file, unmarshals the JSON contained within it
-// and returns an Unstructured object.
-func (ctx *context) unmarshal(filePath string) (*unstructured.Unstructured, error) {
-	var obj unstructured.Unstructured
+	obj.SetLabels(labels)
+}
 
-	bytes, err := ctx.fileSystem.ReadFile(filePath)
+// readItem reads the named item of the specified backup resource in the specified
+// backup namespace from the restore's item source, and returns it as an Unstructured object.
+func (ctx *context) readItem(groupResource schema.GroupResource, namespace, name string) (*unstructured.Unstructured, error) {
+	bytes, err := ctx.getItemSource().ReadItem(groupResource, namespace, name)
 	if err != nil {
 		return nil, err
 	}
 
-	err = json.Unmarshal(bytes, &obj)
-	if err != nil {
+	return decodeItem(bytes)
+}
+
+// decodeItem unmarshals the provided JSON into an Unstructured object.
+func decodeItem(data []byte) (*unstructured.Unstructured, error) {
+	var obj unstructured.Unstructured
+
+	if err := json.Unmarshal(data, &obj); err != nil {
 		return nil, err
 	}
 
@@ -20,37 +20,62 @@ import (
 	"archive/tar"
 	"bytes"
 	"compress/gzip"
+	go_context "context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
 	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	appsv1api "k8s.io/api/apps/v1"
+	autoscalingv1api "k8s.io/api/autoscaling/v1"
 	corev1api "k8s.io/api/core/v1"
+	rbacv1api "k8s.io/api/rbac/v1"
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apimachinery/pkg/version"
 	"k8s.io/client-go/dynamic"
 	kubetesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/yaml"
 
 	velerov1api "github.com/heptio/velero/pkg/apis/velero/v1"
 	"github.com/heptio/velero/pkg/backup"
 	"github.com/heptio/velero/pkg/client"
 	"github.com/heptio/velero/pkg/discovery"
+	"github.com/heptio/velero/pkg/generated/clientset/versioned/fake"
+	informers "github.com/heptio/velero/pkg/generated/informers/externalversions"
 	"github.com/heptio/velero/pkg/kuberesource"
 	"github.com/heptio/velero/pkg/plugin/velero"
+	"github.com/heptio/velero/pkg/restic"
 	"github.com/heptio/velero/pkg/test"
 	"github.com/heptio/velero/pkg/util/encode"
+	"github.com/heptio/velero/pkg/util/filesystem"
 	kubeutil "github.com/heptio/velero/pkg/util/kube"
 	testutil "github.com/heptio/velero/pkg/util/test"
+	"github.com/heptio/velero/pkg/volume"
 )
 
 // TestRestoreResourceFiltering runs restores with different combinations
@@ -248,6 +273,67 @@ func TestRestoreResourceFiltering(t *testing.T) {
 				test.PVs():         {"/pv-1"},
 			},
 		},
+		{
+			name:    "exclude label selector skips matching resources",
+			restore: defaultRestore().LabelSelectorExclude(&metav1.LabelSelector{MatchLabels: map[string]string{"velero.io/exclude-from-restore": "true"}}).Restore(),
+			backup:  defaultBackup().Backup(),
+			tarball: newTarWriter(t).
+				addItems("pods",
+					test.NewPod("ns-1", "pod-1", test.WithLabels("velero.io/exclude-from-restore", "true")),
+					test.NewPod("ns-2", "pod-2"),
+				).
+				addItems("deployments.apps",
+					test.NewDeployment("ns-1", "deploy-1"),
+					test.NewDeployment("ns-2", "deploy-2", test.WithLabels("velero.io/exclude-from-restore", "true")),
+				).
+				addItems("persistentvolumes",
+					test.NewPV("pv-1", test.WithLabels("velero.io/exclude-from-restore", "true")),
+					test.NewPV("pv-2", test.WithLabels("velero.io/exclude-from-restore", "false")),
+				).
+				done(),
+			apiResources: []*test.APIResource{
+				test.Pods(),
+				test.Deployments(),
+				test.PVs(),
+			},
+			want: map[*test.APIResource][]string{
+				test.Pods():        {"ns-2/pod-2"},
+				test.Deployments(): {"ns-1/deploy-1"},
+				test.PVs():         {"/pv-2"},
+			},
+		},
+		{
+			name: "resources matching both the label selector and the exclude label selector are skipped",
+			restore: defaultRestore().
+				LabelSelector(&metav1.LabelSelector{MatchLabels: map[string]string{"a": "b"}}).
+				LabelSelectorExclude(&metav1.LabelSelector{MatchLabels: map[string]string{"c": "d"}}).
+				Restore(),
+			backup: defaultBackup().Backup(),
+			tarball: newTarWriter(t).
+				addItems("pods",
+					test.NewPod("ns-1", "pod-1", test.WithLabels("a", "b")),
+					test.NewPod("ns-2", "pod-2", test.WithLabels("a", "b", "c", "d")),
+				).
+				addItems("deployments.apps",
+					test.NewDeployment("ns-1", "deploy-1", test.WithLabels("c", "d")),
+					test.NewDeployment("ns-2", "deploy-2", test.WithLabels("a", "b")),
+				).
+				addItems("persistentvolumes",
+					test.NewPV("pv-1", test.WithLabels("a", "b", "c", "d")),
+					test.NewPV("pv-2", test.WithLabels("a", "b")),
+				).
+				done(),
+			apiResources: []*test.APIResource{
+				test.Pods(),
+				test.Deployments(),
+				test.PVs(),
+			},
+			want: map[*test.APIResource][]string{
+				test.Pods():        {"ns-1/pod-1"},
+				test.Deployments(): {"ns-2/deploy-2"},
+				test.PVs():         {"/pv-2"},
+			},
+		},
 		{
 			name:    "should include cluster-scoped resources if restoring subset of namespaces and IncludeClusterResources=true",
 			restore: defaultRestore().IncludedNamespaces("ns-1").IncludeClusterResources(true).Restore(),
@@ -502,7 +588,7 @@ func TestRestoreResourceFiltering(t *testing.T) {
 			}
 			require.NoError(t, h.restorer.discoveryHelper.Refresh())
 
-			warnings, errs := h.restorer.Restore(
+			warnings, errs, _ := h.restorer.Restore(
 				h.log,
 				tc.restore,
 				tc.backup,
@@ -561,7 +647,7 @@ func TestRestoreNamespaceMapping(t *testing.T) {
 			}
 			require.NoError(t, h.restorer.discoveryHelper.Refresh())
 
-			warnings, errs := h.restorer.Restore(
+			warnings, errs, _ := h.restorer.Restore(
 				h.log,
 				tc.restore,
 				tc.backup,
@@ -639,7 +725,7 @@ func TestRestoreResourcePriorities(t *testing.T) {
 		}
 		require.NoError(t, h.restorer.discoveryHelper.Refresh())
 
-		warnings, errs := h.restorer.Restore(
+		warnings, errs, _ := h.restorer.Restore(
 			h.log,
 			tc.restore,
 			tc.backup,
@@ -667,6 +753,7 @@ func TestInvalidTarballContents(t *testing.T) {
 		apiResources []*test.APIResource
 		tarball      io.Reader
 		want         map[*test.APIResource][]string
+		wantWarnings Result
 		wantErrs     Result
 	}{
 		{
@@ -701,6 +788,56 @@ func TestInvalidTarballContents(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:    "a malformed item among several valid ones is reported as an error and the rest are restored",
+			restore: defaultRestore().Restore(),
+			backup:  defaultBackup().Backup(),
+			tarball: newTarWriter(t).
+				addItems("pods",
+					test.NewPod("ns-1", "pod-1"),
+					test.NewPod("ns-2", "pod-3"),
+				).
+				add("resources/pods/namespaces/ns-1/pod-2.json", []byte(`{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "pod-2"`)).
+				addItems("secrets",
+					test.NewSecret("ns-1", "secret-1"),
+				).
+				done(),
+			apiResources: []*test.APIResource{
+				test.Pods(),
+				test.Secrets(),
+			},
+			want: map[*test.APIResource][]string{
+				test.Pods():    {"ns-1/pod-1", "ns-2/pod-3"},
+				test.Secrets(): {"ns-1/secret-1"},
+			},
+			wantErrs: Result{
+				Namespaces: map[string][]string{
+					"ns-1": {"error decoding \"resources/pods/namespaces/ns-1/pod-2.json\": unexpected end of JSON input"},
+				},
+			},
+		},
+		{
+			name:    "invalid JSON is reported as a warning when the decode error policy is Warn",
+			restore: defaultRestore().DecodeErrorPolicy(velerov1api.DecodeErrorPolicyWarn).Restore(),
+			backup:  defaultBackup().Backup(),
+			tarball: newTarWriter(t).
+				add("resources/pods/namespaces/ns-1/pod-1.json", []byte("invalid JSON")).
+				addItems("pods",
+					test.NewPod("ns-1", "pod-2"),
+				).
+				done(),
+			apiResources: []*test.APIResource{
+				test.Pods(),
+			},
+			want: map[*test.APIResource][]string{
+				test.Pods(): {"ns-1/pod-2"},
+			},
+			wantWarnings: Result{
+				Namespaces: map[string][]string{
+					"ns-1": {"error decoding \"resources/pods/namespaces/ns-1/pod-1.json\": invalid character 'i' looking for beginning of value"},
+				},
+			},
+		},
 	}
 
 	for _, tc := range tests {
@@ -712,7 +849,7 @@ func TestInvalidTarballContents(t *testing.T) {
 			}
 			require.NoError(t, h.restorer.discoveryHelper.Refresh())
 
-			warnings, errs := h.restorer.Restore(
+			warnings, errs, _ := h.restorer.Restore(
 				h.log,
 				tc.restore,
 				tc.backup,
@@ -723,7 +860,7 @@ func TestInvalidTarballContents(t *testing.T) {
 				nil, // volume snapshotter getter
 			)
 
-			assertEmptyResults(t, warnings)
+			assert.Equal(t, tc.wantWarnings, warnings)
 			assert.Equal(t, tc.wantErrs, errs)
 			assertAPIContents(t, h, tc.want)
 		})
@@ -796,6 +933,23 @@ func TestRestoreItems(t *testing.T) {
 				),
 			},
 		},
+		{
+			name:    "protection finalizers get removed from persistent volumes and claims",
+			restore: defaultRestore().Restore(),
+			backup:  defaultBackup().Backup(),
+			tarball: newTarWriter(t).
+				addItems("persistentvolumes", test.NewPV("pv-1", test.WithFinalizers("kubernetes.io/pv-protection"))).
+				addItems("persistentvolumeclaims", test.NewPVC("ns-1", "pvc-1", test.WithFinalizers("kubernetes.io/pvc-protection"))).
+				done(),
+			apiResources: []*test.APIResource{
+				test.PVs(),
+				test.PVCs(),
+			},
+			want: []*test.APIResource{
+				test.PVs(test.NewPV("pv-1", test.WithLabels("velero.io/backup-name", "backup-1", "velero.io/restore-name", "restore-1"))),
+				test.PVCs(test.NewPVC("ns-1", "pvc-1", test.WithLabels("velero.io/backup-name", "backup-1", "velero.io/restore-name", "restore-1"))),
+			},
+		},
 		{
 			name:    "object gets labeled with full backup and restore names when they're both shorter than 63 characters",
 			restore: defaultRestore().Restore(),
@@ -908,7 +1062,7 @@ func TestRestoreItems(t *testing.T) {
 				h.addItems(t, r)
 			}
 
-			warnings, errs := h.restorer.Restore(
+			warnings, errs, _ := h.restorer.Restore(
 				h.log,
 				tc.restore,
 				tc.backup,
@@ -1098,7 +1252,7 @@ func TestRestoreActionsRunForCorrectItems(t *testing.T) {
 				actions = append(actions, action)
 			}
 
-			warnings, errs := h.restorer.Restore(
+			warnings, errs, _ := h.restorer.Restore(
 				h.log,
 				tc.restore,
 				tc.backup,
@@ -1122,6 +1276,87 @@ func TestRestoreActionsRunForCorrectItems(t *testing.T) {
 
 // pluggableAction is a restore item action that can be plugged with an Execute
 // function body at runtime.
+// prioritizedAction is a restore item action with a priority that records its name
+// each time it's executed.
+type prioritizedAction struct {
+	name     string
+	priority int
+	executed *[]string
+}
+
+func (a *prioritizedAction) AppliesTo() (velero.ResourceSelector, error) {
+	return velero.ResourceSelector{}, nil
+}
+
+func (a *prioritizedAction) Priority() (int, error) {
+	return a.priority, nil
+}
+
+func (a *prioritizedAction) Execute(input *velero.RestoreItemActionExecuteInput) (*velero.RestoreItemActionExecuteOutput, error) {
+	*a.executed = append(*a.executed, a.name)
+	return velero.NewRestoreItemActionExecuteOutput(input.Item), nil
+}
+
+// TestRestoreActionPriorities runs restores with restore item actions of differing priorities,
+// and verifies that they're executed in decreasing order of priority regardless of the order
+// they're registered in, with actions of the same priority executed in registration order.
+func TestRestoreActionPriorities(t *testing.T) {
+	tests := []struct {
+		name    string
+		actions []prioritizedAction
+		want    []string
+	}{
+		{
+			name:    "a higher priority action registered last runs first",
+			actions: []prioritizedAction{{name: "pod-rewrite", priority: 0}, {name: "pvc-rewrite", priority: 10}},
+			want:    []string{"pvc-rewrite", "pod-rewrite"},
+		},
+		{
+			name:    "a higher priority action registered first runs first",
+			actions: []prioritizedAction{{name: "pvc-rewrite", priority: 10}, {name: "pod-rewrite", priority: 0}},
+			want:    []string{"pvc-rewrite", "pod-rewrite"},
+		},
+		{
+			name:    "actions with the same priority run in registration order",
+			actions: []prioritizedAction{{name: "action-1", priority: 5}, {name: "action-2", priority: 5}, {name: "action-3", priority: -1}},
+			want:    []string{"action-1", "action-2", "action-3"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.Pods())
+
+			var executed []string
+			var actions []velero.RestoreItemAction
+			for i := range tc.actions {
+				action := tc.actions[i]
+				action.executed = &executed
+				actions = append(actions, &action)
+			}
+
+			tarball := newTarWriter(t).
+				addItems("pods", test.NewPod("ns-1", "pod-1")).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				defaultRestore().Restore(),
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				actions,
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, warnings, errs)
+			assert.Equal(t, tc.want, executed)
+		})
+	}
+}
+
 type pluggableAction struct {
 	selector    velero.ResourceSelector
 	executeFunc func(*velero.RestoreItemActionExecuteInput) (*velero.RestoreItemActionExecuteOutput, error)
@@ -1233,7 +1468,7 @@ func TestRestoreActionModifications(t *testing.T) {
 				}
 			}
 
-			warnings, errs := h.restorer.Restore(
+			warnings, errs, _ := h.restorer.Restore(
 				h.log,
 				tc.restore,
 				tc.backup,
@@ -1396,7 +1631,7 @@ func TestRestoreActionAdditionalItems(t *testing.T) {
 				h.addItems(t, r)
 			}
 
-			warnings, errs := h.restorer.Restore(
+			warnings, errs, _ := h.restorer.Restore(
 				h.log,
 				tc.restore,
 				tc.backup,
@@ -1413,6 +1648,128 @@ func TestRestoreActionAdditionalItems(t *testing.T) {
 	}
 }
 
+// TestRestoreActionSkipRestore runs restores with restore item actions that tell the restore
+// to skip items, and verifies that the skipped items aren't created while the items that
+// depend on them are.
+func TestRestoreActionSkipRestore(t *testing.T) {
+	skippingAction := func(resource string) *pluggableAction {
+		return &pluggableAction{
+			selector: velero.ResourceSelector{IncludedResources: []string{resource}},
+			executeFunc: func(input *velero.RestoreItemActionExecuteInput) (*velero.RestoreItemActionExecuteOutput, error) {
+				obj := input.Item.(*unstructured.Unstructured)
+				if obj.GetName() != "skip-me" {
+					return velero.NewRestoreItemActionExecuteOutput(input.Item), nil
+				}
+				return velero.NewRestoreItemActionExecuteOutput(input.Item).WithoutRestore(), nil
+			},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		tarball     io.Reader
+		actions     []velero.RestoreItemAction
+		want        map[*test.APIResource][]string
+		wantSkipped []string
+	}{
+		{
+			name: "an item skipped by an action isn't created",
+			tarball: newTarWriter(t).
+				addItems("secrets", test.NewSecret("ns-1", "skip-me"), test.NewSecret("ns-1", "secret-1")).
+				addItems("pods", test.NewPod("ns-1", "pod-1")).
+				done(),
+			actions: []velero.RestoreItemAction{skippingAction("secrets")},
+			want: map[*test.APIResource][]string{
+				test.Secrets(): {"ns-1/secret-1"},
+				test.Pods():    {"ns-1/pod-1"},
+			},
+			wantSkipped: []string{"ns-1/skip-me"},
+		},
+		{
+			name: "an item skipped by an action isn't restored as another item's additional item",
+			tarball: newTarWriter(t).
+				addItems("secrets", test.NewSecret("ns-1", "skip-me")).
+				addItems("pods", test.NewPod("ns-1", "pod-1")).
+				done(),
+			actions: []velero.RestoreItemAction{
+				skippingAction("secrets"),
+				&pluggableAction{
+					selector: velero.ResourceSelector{IncludedResources: []string{"pods"}},
+					executeFunc: func(input *velero.RestoreItemActionExecuteInput) (*velero.RestoreItemActionExecuteOutput, error) {
+						return &velero.RestoreItemActionExecuteOutput{
+							UpdatedItem: input.Item,
+							AdditionalItems: []velero.ResourceIdentifier{
+								{GroupResource: kuberesource.Secrets, Namespace: "ns-1", Name: "skip-me"},
+							},
+						}, nil
+					},
+				},
+			},
+			want: map[*test.APIResource][]string{
+				test.Secrets(): nil,
+				test.Pods():    {"ns-1/pod-1"},
+			},
+			wantSkipped: []string{"ns-1/skip-me"},
+		},
+		{
+			name: "a claim whose volume is skipped by an action is restored for dynamic provisioning",
+			tarball: newTarWriter(t).
+				addItems("persistentvolumes", test.NewPV("skip-me")).
+				addItems("persistentvolumeclaims", test.NewPVC("ns-1", "pvc-1", func(obj metav1.Object) {
+					obj.(*corev1api.PersistentVolumeClaim).Spec.VolumeName = "skip-me"
+				})).
+				done(),
+			actions: []velero.RestoreItemAction{skippingAction("persistentvolumes")},
+			want: map[*test.APIResource][]string{
+				test.PVs():  nil,
+				test.PVCs(): {"ns-1/pvc-1"},
+			},
+			wantSkipped: []string{"/skip-me"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.restorer.resourcePriorities = []string{"persistentvolumes", "persistentvolumeclaims", "secrets", "pods"}
+
+			for _, r := range []*test.APIResource{test.PVs(), test.PVCs(), test.Secrets(), test.Pods()} {
+				h.addItems(t, r)
+			}
+
+			warnings, errs, results := h.restorer.Restore(
+				h.log,
+				defaultRestore().Restore(),
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tc.tarball,
+				tc.actions,
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, warnings, errs)
+			assertAPIContents(t, h, tc.want)
+
+			var skipped []string
+			for _, res := range results {
+				if res.Outcome == ItemOutcomeSkipped && res.Reason == skippedByActionReason {
+					skipped = append(skipped, res.Namespace+"/"+res.Name)
+				}
+			}
+			assert.Equal(t, tc.wantSkipped, skipped)
+
+			// none of the restored claims are bound to a volume that wasn't restored
+			pvcs, err := h.DynamicClient.Resource(corev1api.SchemeGroupVersion.WithResource("persistentvolumeclaims")).Namespace("ns-1").List(metav1.ListOptions{})
+			require.NoError(t, err)
+			for _, pvc := range pvcs.Items {
+				volumeName, _, _ := unstructured.NestedString(pvc.Object, "spec", "volumeName")
+				assert.Empty(t, volumeName)
+			}
+		})
+	}
+}
+
 // TestShouldRestore runs the ShouldRestore function for various permutations of
 // existing/nonexisting/being-deleted PVs, PVCs, and namespaces, and verifies the
 // result/error matches expectations.
@@ -1618,77 +1975,6555 @@ func TestShouldRestore(t *testing.T) {
 	}
 }
 
-func assertRestoredItems(t *testing.T, h *harness, want []*test.APIResource) {
-	t.Helper()
+// TestRestoreWaitsForCRDConversionWebhooks runs restores of CRDs that use conversion
+// webhooks and verifies that the restore waits for the webhooks' services to have ready
+// endpoints, or records a warning if they don't become ready in time.
+func TestRestoreWaitsForCRDConversionWebhooks(t *testing.T) {
+	defer func(interval time.Duration) { readinessPollInterval = interval }(readinessPollInterval)
+	readinessPollInterval = time.Millisecond
+
+	crdWithWebhook := test.NewCRD("widgets.example.com")
+	crdWithWebhook.Spec.Conversion = &apiextv1beta1.CustomResourceConversion{
+		Strategy: apiextv1beta1.WebhookConverter,
+		WebhookClientConfig: &apiextv1beta1.WebhookClientConfig{
+			Service: &apiextv1beta1.ServiceReference{Namespace: "ns-1", Name: "widget-webhook"},
+		},
+	}
 
-	for _, resource := range want {
-		resourceClient := h.DynamicClient.Resource(resource.GVR())
-		for _, item := range resource.Items {
-			var client dynamic.ResourceInterface
-			if item.GetNamespace() != "" {
-				client = resourceClient.Namespace(item.GetNamespace())
-			} else {
-				client = resourceClient
-			}
+	tests := []struct {
+		name         string
+		restore      *velerov1api.Restore
+		tarball      io.Reader
+		readyAfter   int
+		wantGets     int
+		wantWarnings bool
+	}{
+		{
+			name:    "restore waits until the webhook service has ready endpoints",
+			restore: defaultRestore().WaitForCRDConversionWebhooks(true).Restore(),
+			tarball: newTarWriter(t).
+				addItems("customresourcedefinitions.apiextensions.k8s.io", crdWithWebhook).
+				done(),
+			readyAfter: 3,
+			wantGets:   3,
+		},
+		{
+			name:    "restore does not wait when the option is not set",
+			restore: defaultRestore().Restore(),
+			tarball: newTarWriter(t).
+				addItems("customresourcedefinitions.apiextensions.k8s.io", crdWithWebhook).
+				done(),
+			readyAfter: 3,
+			wantGets:   0,
+		},
+		{
+			name:    "restore does not wait for CRDs without conversion webhooks",
+			restore: defaultRestore().WaitForCRDConversionWebhooks(true).Restore(),
+			tarball: newTarWriter(t).
+				addItems("customresourcedefinitions.apiextensions.k8s.io", test.NewCRD("gadgets.example.com")).
+				done(),
+			readyAfter: 3,
+			wantGets:   0,
+		},
+		{
+			name:    "a warning is recorded when the webhook service is not ready before the timeout",
+			restore: defaultRestore().WaitForCRDConversionWebhooks(true).ReadinessTimeout(20 * time.Millisecond).Restore(),
+			tarball: newTarWriter(t).
+				addItems("customresourcedefinitions.apiextensions.k8s.io", crdWithWebhook).
+				done(),
+			readyAfter:   -1,
+			wantWarnings: true,
+		},
+	}
 
-			res, err := client.Get(item.GetName(), metav1.GetOptions{})
-			if !assert.NoError(t, err) {
-				continue
-			}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.CRDs())
 
-			itemJSON, err := json.Marshal(item)
-			if !assert.NoError(t, err) {
-				continue
-			}
+			var gets int
+			h.DynamicClient.PrependReactor("get", "endpoints", func(action kubetesting.Action) (bool, runtime.Object, error) {
+				gets++
 
-			t.Logf("%v", string(itemJSON))
+				endpoints := &corev1api.Endpoints{
+					TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Endpoints"},
+					ObjectMeta: metav1.ObjectMeta{Namespace: "ns-1", Name: "widget-webhook"},
+				}
+				if tc.readyAfter > 0 && gets >= tc.readyAfter {
+					endpoints.Subsets = []corev1api.EndpointSubset{
+						{Addresses: []corev1api.EndpointAddress{{IP: "10.0.0.1"}}},
+					}
+				}
 
-			u := make(map[string]interface{})
-			if !assert.NoError(t, json.Unmarshal(itemJSON, &u)) {
-				continue
-			}
-			want := &unstructured.Unstructured{Object: u}
+				obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(endpoints)
+				require.NoError(t, err)
+				return true, &unstructured.Unstructured{Object: obj}, nil
+			})
 
-			// These fields get non-nil zero values in the unstructured objects if they're
-			// empty in the structured objects. Remove them to make comparison easier.
-			unstructured.RemoveNestedField(want.Object, "metadata", "creationTimestamp")
-			unstructured.RemoveNestedField(want.Object, "status")
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tc.tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
 
-			assert.Equal(t, want, res)
+			assertEmptyResults(t, errs)
+			if tc.wantWarnings {
+				assert.Len(t, warnings.Cluster, 1)
+			} else {
+				assertEmptyResults(t, warnings)
+				assert.Equal(t, tc.wantGets, gets)
+			}
+		})
+	}
+}
+
+// TestRestoreWaitForReady runs restores of pods and deployments that the restore waits
+// for to become ready, and verifies that the restore polls each created item until it's
+// ready, and that items that aren't ready in time are warnings, or errors if the restore
+// fails on readiness timeouts.
+func TestRestoreWaitForReady(t *testing.T) {
+	defer func(interval time.Duration) { readinessPollInterval = interval }(readinessPollInterval)
+	readinessPollInterval = time.Millisecond
+
+	readyPod := func(obj *unstructured.Unstructured) {
+		unstructured.SetNestedSlice(obj.Object, []interface{}{
+			map[string]interface{}{"type": "Ready", "status": "True"},
+		}, "status", "conditions")
+	}
+	availableDeployment := func(obj *unstructured.Unstructured) {
+		unstructured.SetNestedField(obj.Object, int64(2), "status", "availableReplicas")
+	}
+
+	replicas := int32(2)
+	deployment := test.NewDeployment("ns-1", "deploy-1")
+	deployment.Spec.Replicas = &replicas
+
+	tests := []struct {
+		name                 string
+		restore              *velerov1api.Restore
+		resource             string
+		tarball              io.Reader
+		makeReady            func(*unstructured.Unstructured)
+		readyAfter           int
+		wantGets             int
+		wantWarnings         bool
+		wantErrs             bool
+		wantNamespaceTimeout bool
+	}{
+		{
+			name:     "restore waits until a restored pod is ready",
+			restore:  defaultRestore().WaitForReady("pods").Restore(),
+			resource: "pods",
+			tarball: newTarWriter(t).
+				addItems("pods", test.NewPod("ns-1", "pod-1")).
+				done(),
+			makeReady:  readyPod,
+			readyAfter: 3,
+			wantGets:   3,
+		},
+		{
+			name:     "restore waits until a restored deployment's replicas are available",
+			restore:  defaultRestore().WaitForReady("deployments").Restore(),
+			resource: "deployments",
+			tarball: newTarWriter(t).
+				addItems("deployments.apps", deployment).
+				done(),
+			makeReady:  availableDeployment,
+			readyAfter: 2,
+			wantGets:   2,
+		},
+		{
+			name:     "restore doesn't wait for resources it isn't asked to",
+			restore:  defaultRestore().WaitForReady("deployments").Restore(),
+			resource: "pods",
+			tarball: newTarWriter(t).
+				addItems("pods", test.NewPod("ns-1", "pod-1")).
+				done(),
+			makeReady:  readyPod,
+			readyAfter: 3,
+			wantGets:   0,
+		},
+		{
+			name:     "a pod that isn't ready before the timeout is a warning",
+			restore:  defaultRestore().WaitForReady("pods").ReadinessTimeout(20 * time.Millisecond).Restore(),
+			resource: "pods",
+			tarball: newTarWriter(t).
+				addItems("pods", test.NewPod("ns-1", "pod-1")).
+				done(),
+			makeReady:    readyPod,
+			readyAfter:   -1,
+			wantWarnings: true,
+		},
+		{
+			name:     "a pod that isn't ready before the timeout is an error if the restore fails on readiness timeouts",
+			restore:  defaultRestore().WaitForReady("pods").ReadinessTimeout(20 * time.Millisecond).FailOnReadinessTimeout(true).Restore(),
+			resource: "pods",
+			tarball: newTarWriter(t).
+				addItems("pods", test.NewPod("ns-1", "pod-1")).
+				done(),
+			makeReady:  readyPod,
+			readyAfter: -1,
+			wantErrs:   true,
+		},
+		{
+			name:     "restore stops waiting for a pod whose namespace times out",
+			restore:  defaultRestore().WaitForReady("pods").ReadinessTimeout(5 * time.Second).NamespaceTimeout(20 * time.Millisecond).Restore(),
+			resource: "pods",
+			tarball: newTarWriter(t).
+				addItems("pods", test.NewPod("ns-1", "pod-1")).
+				done(),
+			makeReady:            readyPod,
+			readyAfter:           -1,
+			wantNamespaceTimeout: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.Pods())
+			h.addItems(t, test.Deployments())
+
+			var gets int
+			h.DynamicClient.PrependReactor("get", tc.resource, func(action kubetesting.Action) (bool, runtime.Object, error) {
+				gets++
+
+				var obj metav1.Object = test.NewPod("ns-1", "pod-1")
+				if tc.resource == "deployments" {
+					obj = deployment.DeepCopy()
+				}
+				res, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+				require.NoError(t, err)
+
+				item := &unstructured.Unstructured{Object: res}
+				if tc.readyAfter > 0 && gets >= tc.readyAfter {
+					tc.makeReady(item)
+				}
+				return true, item, nil
+			})
+
+			start := time.Now()
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tc.tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			switch {
+			case tc.wantNamespaceTimeout:
+				// the pod isn't ready, and its namespace timed out
+				// long before the readiness timeout
+				assert.Len(t, warnings.Namespaces["ns-1"], 1)
+				assert.Len(t, errs.Namespaces["ns-1"], 1)
+				assert.True(t, time.Since(start) < tc.restore.Spec.ReadinessTimeout.Duration)
+			case tc.wantWarnings:
+				assertEmptyResults(t, errs)
+				assert.Len(t, warnings.Namespaces["ns-1"], 1)
+			case tc.wantErrs:
+				assertEmptyResults(t, warnings)
+				assert.Len(t, errs.Namespaces["ns-1"], 1)
+			default:
+				assertEmptyResults(t, warnings, errs)
+				assert.Equal(t, tc.wantGets, gets)
+			}
+		})
+	}
+}
+
+// TestRestoreSourceSubPath runs restores of a backup whose resources directory isn't at
+// the root of the tarball, and verifies that items are restored from the restore's source
+// sub-path.
+func TestRestoreSourceSubPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		restore  *velerov1api.Restore
+		wantErrs bool
+	}{
+		{
+			name:    "items are restored from the source sub-path",
+			restore: defaultRestore().SourceSubPath("extracted/backup-1").Restore(),
+		},
+		{
+			name:     "a restore without the source sub-path doesn't find the resources directory",
+			restore:  defaultRestore().Restore(),
+			wantErrs: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.Pods())
+			h.addItems(t, test.PVs())
+
+			tarball := newTarWriter(t).
+				add("extracted/backup-1/resources/pods/namespaces/ns-1/pod-1.json", test.NewPod("ns-1", "pod-1")).
+				add("extracted/backup-1/resources/persistentvolumes/cluster/pv-1.json", test.NewPV("pv-1")).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			if tc.wantErrs {
+				assert.NotEmpty(t, errs.Velero)
+				return
+			}
+			assertEmptyResults(t, warnings, errs)
+
+			assertAPIContents(t, h, map[*test.APIResource][]string{
+				test.Pods(): {"ns-1/pod-1"},
+				test.PVs():  {"/pv-1"},
+			})
+		})
+	}
+}
+
+// TestRestoreRemapOwnerReferences runs restores of a config map and pods that refer to it, or
+// to an owner that isn't in the backup, as their owner, and verifies that the pods' owner
+// references are only restored when the restore remaps them, pointing at the config map's UID
+// in the cluster, and that references to owners that weren't restored are dropped unless the
+// restore keeps them.
+func TestRestoreRemapOwnerReferences(t *testing.T) {
+	ownerRef := func(name string, uid types.UID) metav1.OwnerReference {
+		return metav1.OwnerReference{APIVersion: "v1", Kind: "ConfigMap", Name: name, UID: uid}
+	}
+	withOwnerRefs := func(refs ...metav1.OwnerReference) func(obj metav1.Object) {
+		return func(obj metav1.Object) {
+			obj.SetOwnerReferences(refs)
 		}
 	}
+
+	tests := []struct {
+		name         string
+		restore      *velerov1api.Restore
+		apiResources []*test.APIResource
+		want         map[string][]metav1.OwnerReference
+	}{
+		{
+			name:    "owner references aren't restored by default",
+			restore: defaultRestore().Restore(),
+			want: map[string][]metav1.OwnerReference{
+				"pod-1": nil,
+				"pod-2": nil,
+			},
+		},
+		{
+			name:    "owner references are rewritten to the restored owner's UID, and dropped for owners that weren't restored",
+			restore: defaultRestore().RemapOwnerReferences(true).Restore(),
+			want: map[string][]metav1.OwnerReference{
+				"pod-1": {ownerRef("cm-1", "restored-uid")},
+				"pod-2": nil,
+			},
+		},
+		{
+			name:    "owner references to owners that weren't restored are kept when the restore keeps them",
+			restore: defaultRestore().RemapOwnerReferences(true).KeepUnresolvedOwnerReferences(true).Restore(),
+			want: map[string][]metav1.OwnerReference{
+				"pod-1": {ownerRef("cm-1", "restored-uid")},
+				"pod-2": {ownerRef("cm-gone", "source-uid-2")},
+			},
+		},
+		{
+			name:    "owner references are rewritten to the UID of an owner that already exists in the cluster",
+			restore: defaultRestore().RemapOwnerReferences(true).Restore(),
+			apiResources: []*test.APIResource{
+				test.ConfigMaps(test.NewConfigMap("ns-1", "cm-1", func(obj metav1.Object) { obj.SetUID("existing-uid") })),
+			},
+			want: map[string][]metav1.OwnerReference{
+				"pod-1": {ownerRef("cm-1", "existing-uid")},
+				"pod-2": nil,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.Pods())
+			if tc.apiResources == nil {
+				h.addItems(t, test.ConfigMaps())
+			}
+			for _, r := range tc.apiResources {
+				h.addItems(t, r)
+			}
+
+			// the fake dynamic client doesn't assign UIDs to created items
+			h.DynamicClient.PrependReactor("create", "configmaps", func(action kubetesting.Action) (bool, runtime.Object, error) {
+				action.(kubetesting.CreateAction).GetObject().(*unstructured.Unstructured).SetUID("restored-uid")
+				return false, nil, nil
+			})
+
+			tarball := newTarWriter(t).
+				addItems("configmaps", test.NewConfigMap("ns-1", "cm-1", func(obj metav1.Object) { obj.SetUID("source-uid-1") })).
+				addItems("pods",
+					test.NewPod("ns-1", "pod-1", withOwnerRefs(ownerRef("cm-1", "source-uid-1"))),
+					test.NewPod("ns-1", "pod-2", withOwnerRefs(ownerRef("cm-gone", "source-uid-2"))),
+				).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, warnings, errs)
+
+			for name, want := range tc.want {
+				res, err := h.DynamicClient.Resource(test.Pods().GVR()).Namespace("ns-1").Get(name, metav1.GetOptions{})
+				require.NoError(t, err)
+				assert.Equal(t, want, res.GetOwnerReferences(), name)
+			}
+		})
+	}
 }
 
-// assertResourceCreationOrder ensures that resources were created in the expected
-// order. Any resources *not* in resourcePriorities are required to come *after* all
-// resources in any order.
-func assertResourceCreationOrder(t *testing.T, resourcePriorities []string, createdResources []resourceID) {
-	// lastSeen tracks the index in 'resourcePriorities' of the last resource type
-	// we saw created. Once we've seen a resource in 'resourcePriorities', we should
-	// never see another instance of a prior resource.
-	lastSeen := 0
+// TestRestoreWaitForCRDsEstablished runs restores of a CRD and an instance of it, whose
+// resource isn't served until the CRD is established, and verifies that the restore waits
+// for the CRD to be established, refreshes discovery, and then restores the instance.
+func TestRestoreWaitForCRDsEstablished(t *testing.T) {
+	defer func(interval time.Duration) { readinessPollInterval = interval }(readinessPollInterval)
+	readinessPollInterval = time.Millisecond
+
+	widgets := &test.APIResource{
+		Group:      "example.com",
+		Version:    "v1",
+		Name:       "widgets",
+		Namespaced: true,
+	}
+	widget := []byte(`{"apiVersion":"example.com/v1","kind":"Widget","metadata":{"namespace":"ns-1","name":"widget-1"}}`)
 
-	// Find the index in 'resourcePriorities' of the resource type for
-	// the current item, if it exists. This index ('current') *must*
-	// be greater than or equal to 'lastSeen', which was the last resource
-	// we saw, since otherwise the current resource would be out of order. By
-	// initializing current to len(ordered), we're saying that if the resource
-	// is not explicitly in orderedResources, then it must come *after*
-	// all orderedResources.
-	for _, r := range createdResources {
-		current := len(resourcePriorities)
-		for i, item := range resourcePriorities {
-			if item == r.groupResource {
-				current = i
-				break
+	tests := []struct {
+		name             string
+		restore          *velerov1api.Restore
+		establishedAfter int
+		wantGets         int
+		wantWidgets      []string
+		wantWarnings     bool
+	}{
+		{
+			name:             "the instance is restored once the CRD is established",
+			restore:          defaultRestore().WaitForCRDsEstablished(true).Restore(),
+			establishedAfter: 3,
+			wantGets:         3,
+			wantWidgets:      []string{"widget-1"},
+		},
+		{
+			name:             "the restore doesn't wait for the CRD or restore the instance when the option is not set",
+			restore:          defaultRestore().Restore(),
+			establishedAfter: 3,
+			wantGets:         0,
+		},
+		{
+			name:             "a warning is recorded when the CRD is not established before the timeout",
+			restore:          defaultRestore().WaitForCRDsEstablished(true).ReadinessTimeout(20 * time.Millisecond).Restore(),
+			establishedAfter: -1,
+			wantWarnings:     true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.CRDs())
+
+			// the resource is served once the CRD is created
+			h.DynamicClient.PrependReactor("create", "customresourcedefinitions", func(action kubetesting.Action) (bool, runtime.Object, error) {
+				h.DiscoveryClient.WithAPIResource(widgets)
+				return false, nil, nil
+			})
+
+			var gets int
+			h.DynamicClient.PrependReactor("get", "customresourcedefinitions", func(action kubetesting.Action) (bool, runtime.Object, error) {
+				gets++
+
+				crd := test.NewCRD("widgets.example.com")
+				if tc.establishedAfter > 0 && gets >= tc.establishedAfter {
+					crd.Status.Conditions = []apiextv1beta1.CustomResourceDefinitionCondition{
+						{Type: apiextv1beta1.Established, Status: apiextv1beta1.ConditionTrue},
+					}
+				}
+
+				obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(crd)
+				require.NoError(t, err)
+				return true, &unstructured.Unstructured{Object: obj}, nil
+			})
+
+			tarball := newTarWriter(t).
+				addItems("customresourcedefinitions.apiextensions.k8s.io", test.NewCRD("widgets.example.com")).
+				add("resources/widgets.example.com/namespaces/ns-1/widget-1.json", widget).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, errs)
+			if tc.wantWarnings {
+				assert.Len(t, warnings.Cluster, 1)
+				return
+			}
+			assertEmptyResults(t, warnings)
+			assert.Equal(t, tc.wantGets, gets)
+
+			list, err := h.DynamicClient.Resource(widgets.GVR()).Namespace("ns-1").List(metav1.ListOptions{})
+			require.NoError(t, err)
+			var names []string
+			for _, item := range list.Items {
+				names = append(names, item.GetName())
 			}
+			assert.Equal(t, tc.wantWidgets, names)
+		})
+	}
+}
+
+// TestRestoreLatestOnly runs restores of controller revisions with latest only filters, and
+// verifies that only the latest revisions of each group are restored, that the older ones
+// are skipped, and that revisions whose field can't be read are the oldest, with a warning.
+func TestRestoreLatestOnly(t *testing.T) {
+	newRevision := func(name, app string, revision interface{}) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "ControllerRevision",
+			"metadata": map[string]interface{}{
+				"namespace":   "ns-1",
+				"name":        name,
+				"labels":      map[string]interface{}{"app": app},
+				"annotations": map[string]interface{}{"example.com/revision": fmt.Sprint(revision)},
+			},
+		}}
+		if revision != nil {
+			obj.Object["revision"] = revision
 		}
+		return obj
+	}
 
-		// the index of the current resource must be the same as or greater than the index of
-		// the last resource we saw for the restored order to be correct.
-		assert.True(t, current >= lastSeen, "%s was restored out of order", r.groupResource)
-		lastSeen = current
+	controllerRevisions := &test.APIResource{
+		Group:      "apps",
+		Version:    "v1",
+		Name:       "controllerrevisions",
+		Namespaced: true,
 	}
+
+	tests := []struct {
+		name   string
+		filter velerov1api.LatestOnlyFilter
+		want   []string
+	}{
+		{
+			name:   "only the latest revision is restored",
+			filter: velerov1api.LatestOnlyFilter{Resource: "controllerrevisions", FieldPath: "revision"},
+			want:   []string{"web-3"},
+		},
+		{
+			name:   "the latest revisions are restored up to the count",
+			filter: velerov1api.LatestOnlyFilter{Resource: "controllerrevisions", FieldPath: "revision", Count: 2},
+			want:   []string{"web-2", "web-3"},
+		},
+		{
+			name:   "the latest revision of each group is restored",
+			filter: velerov1api.LatestOnlyFilter{Resource: "controllerrevisions", FieldPath: "revision", GroupByLabel: "app"},
+			want:   []string{"db-1", "web-3"},
+		},
+		{
+			name:   "revisions are ordered by an annotation",
+			filter: velerov1api.LatestOnlyFilter{Resource: "controllerrevisions", FieldPath: `metadata.annotations["example.com/revision"]`, Count: 4},
+			want:   []string{"web-1", "web-2", "web-3", "db-1"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, controllerRevisions)
+
+			tarball := newTarWriter(t)
+			for _, obj := range []*unstructured.Unstructured{
+				newRevision("web-1", "web", int64(1)),
+				newRevision("web-3", "web", int64(3)),
+				newRevision("web-2", "web", int64(2)),
+				newRevision("db-1", "db", int64(1)),
+				newRevision("web-x", "web", nil),
+			} {
+				data, err := json.Marshal(obj)
+				require.NoError(t, err)
+				tarball.add("resources/controllerrevisions.apps/namespaces/ns-1/"+obj.GetName()+".json", data)
+			}
+
+			warnings, errs, itemResults := h.restorer.Restore(
+				h.log,
+				defaultRestore().LatestOnly(tc.filter).Restore(),
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball.done(),
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			// web-x has no revision, so it's the oldest
+			assertEmptyResults(t, errs)
+			assert.Len(t, warnings.Namespaces["ns-1"], 1)
+
+			list, err := h.DynamicClient.Resource(controllerRevisions.GVR()).Namespace("ns-1").List(metav1.ListOptions{})
+			require.NoError(t, err)
+			var names []string
+			for _, item := range list.Items {
+				names = append(names, item.GetName())
+			}
+			assert.ElementsMatch(t, tc.want, names)
+
+			var skipped int
+			for _, res := range itemResults {
+				if res.Outcome == ItemOutcomeSkipped {
+					assert.Equal(t, olderRevisionReason, res.Reason)
+					skipped++
+				}
+			}
+			assert.Equal(t, 5-len(tc.want), skipped)
+		})
+	}
+}
+
+// TestRestoreFieldExclusions runs restores of a pod with field exclusions, and verifies
+// that the excluded fields are removed from the created pod, that excluding a field the
+// pod doesn't have is a no-op, and that exclusions of other resources are ignored.
+func TestRestoreFieldExclusions(t *testing.T) {
+	pod := test.NewPod("ns-1", "pod-1", func(obj metav1.Object) {
+		obj.SetAnnotations(map[string]string{"example.com/node": "node-1", "other": "val"})
+		obj.(*corev1api.Pod).Spec.NodeName = "node-1"
+		obj.(*corev1api.Pod).Spec.Hostname = "pod-1"
+	})
+
+	tests := []struct {
+		name            string
+		restore         *velerov1api.Restore
+		wantNodeName    bool
+		wantAnnotations map[string]string
+	}{
+		{
+			name:            "an excluded field is removed",
+			restore:         defaultRestore().FieldExclusions("pods", "spec.nodeName").Restore(),
+			wantAnnotations: map[string]string{"example.com/node": "node-1", "other": "val"},
+		},
+		{
+			name:            "an excluded annotation is removed",
+			restore:         defaultRestore().FieldExclusions("pods", "spec.nodeName", `metadata.annotations["example.com/node"]`).Restore(),
+			wantAnnotations: map[string]string{"other": "val"},
+		},
+		{
+			name:            "excluding a field the item doesn't have is a no-op",
+			restore:         defaultRestore().FieldExclusions("pods", "spec.priorityClassName", "spec.missing.field").Restore(),
+			wantNodeName:    true,
+			wantAnnotations: map[string]string{"example.com/node": "node-1", "other": "val"},
+		},
+		{
+			name:            "exclusions of other resources are ignored",
+			restore:         defaultRestore().FieldExclusions("services", "spec.nodeName").Restore(),
+			wantNodeName:    true,
+			wantAnnotations: map[string]string{"example.com/node": "node-1", "other": "val"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.Pods())
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				newTarWriter(t).addItems("pods", pod).done(),
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, warnings, errs)
+
+			res, err := h.DynamicClient.Resource(test.Pods().GVR()).Namespace("ns-1").Get("pod-1", metav1.GetOptions{})
+			require.NoError(t, err)
+
+			_, found, err := unstructured.NestedString(res.Object, "spec", "nodeName")
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantNodeName, found)
+
+			hostname, _, err := unstructured.NestedString(res.Object, "spec", "hostname")
+			require.NoError(t, err)
+			assert.Equal(t, "pod-1", hostname)
+
+			assert.Equal(t, tc.wantAnnotations, res.GetAnnotations())
+		})
+	}
+}
+
+// TestRestoreLeaderElectionRecords runs restores of a PVC with a leader election
+// annotation and a held lease, and verifies that the annotation is removed and the
+// lease's holder cleared unless the restore keeps leader election records, and that
+// the restore's leader election annotations replace the default ones.
+func TestRestoreLeaderElectionRecords(t *testing.T) {
+	leases := &test.APIResource{
+		Group:      "coordination.k8s.io",
+		Version:    "v1",
+		Name:       "leases",
+		Namespaced: true,
+	}
+
+	lease := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "coordination.k8s.io/v1",
+		"kind":       "Lease",
+		"metadata": map[string]interface{}{
+			"namespace": "ns-1",
+			"name":      "lease-1",
+		},
+		"spec": map[string]interface{}{
+			"holderIdentity":       "controller-1",
+			"leaseDurationSeconds": int64(15),
+			"acquireTime":          "2019-06-25T18:27:35.000000Z",
+			"renewTime":            "2019-06-25T18:27:37.000000Z",
+		},
+	}}
+
+	pvc := test.NewPVC("ns-1", "pvc-1", func(obj metav1.Object) {
+		obj.SetAnnotations(map[string]string{
+			"control-plane.alpha.kubernetes.io/leader": `{"holderIdentity":"controller-1","leaseDurationSeconds":15}`,
+			"example.com/leader":                       "controller-1",
+		})
+	})
+
+	tests := []struct {
+		name            string
+		restore         *velerov1api.Restore
+		wantAnnotations []string
+		wantHolder      bool
+	}{
+		{
+			name:            "leader election records are cleared by default",
+			restore:         defaultRestore().Restore(),
+			wantAnnotations: []string{"example.com/leader"},
+		},
+		{
+			name:            "leader election records are kept if the restore keeps them",
+			restore:         defaultRestore().KeepLeaderElectionRecords(true).Restore(),
+			wantAnnotations: []string{"control-plane.alpha.kubernetes.io/leader", "example.com/leader"},
+			wantHolder:      true,
+		},
+		{
+			name:            "the restore's leader election annotations replace the default ones",
+			restore:         defaultRestore().LeaderElectionAnnotations("example.com/leader").Restore(),
+			wantAnnotations: []string{"control-plane.alpha.kubernetes.io/leader"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.PVCs())
+			h.addItems(t, leases)
+
+			leaseData, err := json.Marshal(lease)
+			require.NoError(t, err)
+
+			tarball := newTarWriter(t).addItems("persistentvolumeclaims", pvc)
+			tarball.add("resources/leases.coordination.k8s.io/namespaces/ns-1/lease-1.json", leaseData)
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball.done(),
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, warnings, errs)
+
+			res, err := h.DynamicClient.Resource(test.PVCs().GVR()).Namespace("ns-1").Get("pvc-1", metav1.GetOptions{})
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tc.wantAnnotations, sets.StringKeySet(res.GetAnnotations()).List())
+
+			res, err = h.DynamicClient.Resource(leases.GVR()).Namespace("ns-1").Get("lease-1", metav1.GetOptions{})
+			require.NoError(t, err)
+			for _, field := range []string{"holderIdentity", "acquireTime", "renewTime"} {
+				_, found, err := unstructured.NestedFieldNoCopy(res.Object, "spec", field)
+				require.NoError(t, err)
+				assert.Equal(t, tc.wantHolder, found, field)
+			}
+			duration, _, err := unstructured.NestedInt64(res.Object, "spec", "leaseDurationSeconds")
+			require.NoError(t, err)
+			assert.Equal(t, int64(15), duration)
+		})
+	}
+}
+
+// applyFactory is a dynamic factory whose clients record the field managers that
+// items are applied as, and apply items over existing ones the way the API server
+// would. Applying a label that an existing item has with a different value, and that
+// another field manager owns, is a conflict unless the apply is forced.
+type applyFactory struct {
+	client.DynamicFactory
+
+	fieldManagers []string
+}
+
+func (f *applyFactory) ClientForGroupVersionResource(gv schema.GroupVersion, resource metav1.APIResource, namespace string) (client.Dynamic, error) {
+	c, err := f.DynamicFactory.ClientForGroupVersionResource(gv, resource, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return &applyClient{Dynamic: c, factory: f}, nil
+}
+
+type applyClient struct {
+	client.Dynamic
+
+	factory *applyFactory
+}
+
+func (c *applyClient) Apply(obj *unstructured.Unstructured, fieldManager string, force bool) (*unstructured.Unstructured, error) {
+	c.factory.fieldManagers = append(c.factory.fieldManagers, fieldManager)
+
+	existing, err := c.Dynamic.Get(obj.GetName(), metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	var managedFields []metav1.ManagedFieldsEntry
+	if existing != nil {
+		for _, entry := range existing.GetManagedFields() {
+			if entry.Manager == fieldManager {
+				continue
+			}
+			for key, val := range obj.GetLabels() {
+				if existingVal, ok := existing.GetLabels()[key]; ok && existingVal != val && !force {
+					return nil, &apierrors.StatusError{ErrStatus: metav1.Status{
+						Status: metav1.StatusFailure,
+						Code:   http.StatusConflict,
+						Reason: metav1.StatusReasonConflict,
+						Details: &metav1.StatusDetails{
+							Name: obj.GetName(),
+							Causes: []metav1.StatusCause{
+								{Type: metav1.CauseTypeFieldManagerConflict, Message: fmt.Sprintf("conflict with %q", entry.Manager), Field: ".metadata.labels." + key},
+							},
+						},
+					}}
+				}
+			}
+			managedFields = append(managedFields, entry)
+		}
+	}
+
+	applied, err := c.Dynamic.Apply(obj, fieldManager, force)
+	if err != nil {
+		return nil, err
+	}
+	applied.SetManagedFields(append(managedFields, metav1.ManagedFieldsEntry{Manager: fieldManager, Operation: metav1.ManagedFieldsOperationApply}))
+	return applied, nil
+}
+
+// TestRestoreServerSideApply runs restores of config maps with server-side apply and
+// verifies that the items are applied with a server-side apply patch as velero instead
+// of being created, that items that already exist in the cluster are applied over and
+// recorded as updated, and that items that conflict with fields another field manager
+// owns are skipped with a warning unless the restore forces the apply.
+func TestRestoreServerSideApply(t *testing.T) {
+	ownedByKubectl := func(obj metav1.Object) {
+		obj.SetManagedFields([]metav1.ManagedFieldsEntry{{Manager: "kubectl", Operation: metav1.ManagedFieldsOperationUpdate}})
+	}
+
+	tests := []struct {
+		name         string
+		restore      *velerov1api.Restore
+		existing     *corev1api.ConfigMap
+		wantWarnings int
+		want         []string
+		wantSkipped  []string
+		wantUpdated  []string
+	}{
+		{
+			name:    "items are applied",
+			restore: defaultRestore().ApplyMethod(velerov1api.ApplyMethodServerSideApply).Restore(),
+			want:    []string{"ns-1/cm-1", "ns-1/cm-2"},
+		},
+		{
+			name:        "an existing item owned by another field manager is applied and recorded as updated",
+			restore:     defaultRestore().ApplyMethod(velerov1api.ApplyMethodServerSideApply).Restore(),
+			existing:    test.NewConfigMap("ns-1", "cm-2", test.WithLabels("app", "backed-up", "in-cluster", "true"), ownedByKubectl),
+			want:        []string{"ns-1/cm-1", "ns-1/cm-2"},
+			wantUpdated: []string{"ns-1/cm-2"},
+		},
+		{
+			name:         "an existing item that conflicts with another field manager is skipped with a warning",
+			restore:      defaultRestore().ApplyMethod(velerov1api.ApplyMethodServerSideApply).Restore(),
+			existing:     test.NewConfigMap("ns-1", "cm-2", test.WithLabels("app", "in-cluster"), ownedByKubectl),
+			wantWarnings: 1,
+			want:         []string{"ns-1/cm-1"},
+			wantSkipped:  []string{"ns-1/cm-2"},
+		},
+		{
+			name:        "an existing item that conflicts with another field manager is applied when forced",
+			restore:     defaultRestore().ApplyMethod(velerov1api.ApplyMethodServerSideApply).ForceApply(true).Restore(),
+			existing:    test.NewConfigMap("ns-1", "cm-2", test.WithLabels("app", "in-cluster"), ownedByKubectl),
+			want:        []string{"ns-1/cm-1", "ns-1/cm-2"},
+			wantUpdated: []string{"ns-1/cm-2"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			if tc.existing != nil {
+				h.addItems(t, test.ConfigMaps(tc.existing))
+			} else {
+				h.addItems(t, test.ConfigMaps())
+			}
+			setupActions := len(h.DynamicClient.Actions())
+
+			// the fake client can't apply, so apply patches are
+			// recorded and echoed back without being persisted
+			var applied []string
+			h.DynamicClient.PrependReactor("patch", "configmaps", func(action kubetesting.Action) (bool, runtime.Object, error) {
+				patch := action.(kubetesting.PatchAction)
+				if patch.GetPatchType() != types.ApplyPatchType {
+					return true, nil, errors.Errorf("unexpected patch type %s", patch.GetPatchType())
+				}
+
+				obj := new(unstructured.Unstructured)
+				if err := obj.UnmarshalJSON(patch.GetPatch()); err != nil {
+					return true, nil, err
+				}
+				applied = append(applied, patch.GetNamespace()+"/"+patch.GetName())
+				return true, obj, nil
+			})
+
+			factory := &applyFactory{DynamicFactory: h.restorer.dynamicFactory}
+			h.restorer.dynamicFactory = factory
+
+			tarball := newTarWriter(t).
+				addItems("configmaps",
+					test.NewConfigMap("ns-1", "cm-1"),
+					test.NewConfigMap("ns-1", "cm-2", test.WithLabels("app", "backed-up")),
+				).
+				done()
+
+			warnings, errs, results := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, errs)
+			assert.Len(t, warnings.Namespaces["ns-1"], tc.wantWarnings)
+			assert.Equal(t, tc.want, applied)
+
+			for _, action := range h.DynamicClient.Actions()[setupActions:] {
+				assert.False(t, action.Matches("create", "configmaps"), "config map was created instead of applied")
+			}
+			for _, fieldManager := range factory.fieldManagers {
+				assert.Equal(t, applyFieldManager, fieldManager)
+			}
+
+			var skipped, updated []string
+			for _, res := range results {
+				switch res.Outcome {
+				case ItemOutcomeSkipped:
+					assert.Equal(t, applyConflictReason, res.Reason)
+					skipped = append(skipped, res.Namespace+"/"+res.Name)
+				case ItemOutcomeUpdated:
+					updated = append(updated, res.Namespace+"/"+res.Name)
+				}
+			}
+			assert.Equal(t, tc.wantSkipped, skipped)
+			assert.Equal(t, tc.wantUpdated, updated)
+		})
+	}
+}
+
+// TestRestoreResourceQuotaCheck runs restores of persistent volume claims and pods into
+// namespaces with resource quotas, and verifies that a single warning is recorded for a
+// namespace whose quota the restored items would exceed, but only when the restore checks
+// resource quotas, and that items the restore skips aren't counted.
+func TestRestoreResourceQuotaCheck(t *testing.T) {
+	withStorageRequest := func(size string) func(obj metav1.Object) {
+		return func(obj metav1.Object) {
+			obj.(*corev1api.PersistentVolumeClaim).Spec.Resources.Requests = corev1api.ResourceList{
+				corev1api.ResourceStorage: resource.MustParse(size),
+			}
+		}
+	}
+	withCPURequest := func(cpu string) func(obj metav1.Object) {
+		return func(obj metav1.Object) {
+			obj.(*corev1api.Pod).Spec.Containers = []corev1api.Container{{
+				Name: "container-1",
+				Resources: corev1api.ResourceRequirements{
+					Requests: corev1api.ResourceList{corev1api.ResourceCPU: resource.MustParse(cpu)},
+				},
+			}}
+		}
+	}
+	withHard := func(hard corev1api.ResourceList) func(obj metav1.Object) {
+		return func(obj metav1.Object) {
+			obj.(*corev1api.ResourceQuota).Spec.Hard = hard
+		}
+	}
+
+	allPVCs := []string{"ns-1/pvc-1", "ns-1/pvc-2", "ns-2/pvc-1"}
+
+	tests := []struct {
+		name         string
+		restore      *velerov1api.Restore
+		wantWarnings bool
+		wantPVCs     []string
+	}{
+		{
+			name:     "quotas aren't checked by default",
+			restore:  defaultRestore().Restore(),
+			wantPVCs: allPVCs,
+		},
+		{
+			name:         "a namespace whose quota would be exceeded gets a warning",
+			restore:      defaultRestore().CheckResourceQuotas(true).Restore(),
+			wantWarnings: true,
+			wantPVCs:     allPVCs,
+		},
+		{
+			name: "items the restore's label selector skips aren't counted",
+			restore: defaultRestore().
+				CheckResourceQuotas(true).
+				LabelSelector(&metav1.LabelSelector{MatchLabels: map[string]string{"restore": "true"}}).
+				Restore(),
+			wantPVCs: []string{"ns-1/pvc-1", "ns-2/pvc-1"},
+		},
+		{
+			name: "items that aren't in the restore's included resource names aren't counted",
+			restore: defaultRestore().
+				CheckResourceQuotas(true).
+				IncludedResourceNames("persistentvolumeclaims", "pvc-1").
+				Restore(),
+			wantPVCs: []string{"ns-1/pvc-1", "ns-2/pvc-1"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.ResourceQuotas(
+				test.NewResourceQuota("ns-1", "quota-1", withHard(corev1api.ResourceList{
+					corev1api.ResourceRequestsStorage:        resource.MustParse("5Gi"),
+					corev1api.ResourcePersistentVolumeClaims: resource.MustParse("10"),
+					corev1api.ResourceRequestsCPU:            resource.MustParse("1"),
+				})),
+				test.NewResourceQuota("ns-2", "quota-1", withHard(corev1api.ResourceList{
+					corev1api.ResourceRequestsStorage: resource.MustParse("20Gi"),
+				})),
+			))
+			h.addItems(t, test.PVCs())
+			h.addItems(t, test.Pods())
+
+			tarball := newTarWriter(t).
+				addItems("persistentvolumeclaims",
+					test.NewPVC("ns-1", "pvc-1", withStorageRequest("3Gi"), test.WithLabels("restore", "true")),
+					test.NewPVC("ns-1", "pvc-2", withStorageRequest("3Gi")),
+					test.NewPVC("ns-2", "pvc-1", withStorageRequest("3Gi"), test.WithLabels("restore", "true")),
+				).
+				addItems("pods",
+					test.NewPod("ns-1", "pod-1", withCPURequest("500m"), test.WithLabels("restore", "true")),
+				).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, errs)
+			assert.Empty(t, warnings.Namespaces["ns-2"])
+			if !tc.wantWarnings {
+				assert.Empty(t, warnings.Namespaces["ns-1"])
+			} else if assert.Len(t, warnings.Namespaces["ns-1"], 1) {
+				assert.Contains(t, warnings.Namespaces["ns-1"][0], "quota-1 requests.storage (6Gi requested, 5Gi of 5Gi available)")
+				assert.NotContains(t, warnings.Namespaces["ns-1"][0], "requests.cpu")
+			}
+
+			// the check only warns, so the items are still restored
+			assertAPIContents(t, h, map[*test.APIResource][]string{
+				test.PVCs(): tc.wantPVCs,
+				test.Pods(): {"ns-1/pod-1"},
+			})
+		})
+	}
+}
+
+// TestRestoreNodeMappings runs restores of a persistent volume with zonal node affinity and
+// node mappings, and verifies that the created volume's node affinity references the mapped
+// labels, and that a warning is recorded when no node satisfies the volume's node affinity.
+func TestRestoreNodeMappings(t *testing.T) {
+	withZoneAffinity := func(key, zone string) func(obj metav1.Object) {
+		return func(obj metav1.Object) {
+			obj.(*corev1api.PersistentVolume).Spec.NodeAffinity = &corev1api.VolumeNodeAffinity{
+				Required: &corev1api.NodeSelector{
+					NodeSelectorTerms: []corev1api.NodeSelectorTerm{{
+						MatchExpressions: []corev1api.NodeSelectorRequirement{{
+							Key:      key,
+							Operator: corev1api.NodeSelectorOpIn,
+							Values:   []string{zone},
+						}},
+					}},
+				},
+			}
+		}
+	}
+
+	tests := []struct {
+		name         string
+		restore      *velerov1api.Restore
+		pv           *corev1api.PersistentVolume
+		wantKey      string
+		wantValues   []string
+		wantWarnings int
+	}{
+		{
+			name: "a mapped zone is rewritten",
+			restore: defaultRestore().NodeMappings(velerov1api.NodeMapping{
+				Key:    "topology.kubernetes.io/zone",
+				Values: map[string]string{"us-east-1a": "us-west-2a"},
+			}).Restore(),
+			pv:         test.NewPV("pv-1", withZoneAffinity("topology.kubernetes.io/zone", "us-east-1a")),
+			wantKey:    "topology.kubernetes.io/zone",
+			wantValues: []string{"us-west-2a"},
+		},
+		{
+			name: "a mapped label is renamed along with its values",
+			restore: defaultRestore().NodeMappings(velerov1api.NodeMapping{
+				Key:    "failure-domain.beta.kubernetes.io/zone",
+				NewKey: "topology.kubernetes.io/zone",
+				Values: map[string]string{"us-east-1a": "us-west-2a"},
+			}).Restore(),
+			pv:         test.NewPV("pv-1", withZoneAffinity("failure-domain.beta.kubernetes.io/zone", "us-east-1a")),
+			wantKey:    "topology.kubernetes.io/zone",
+			wantValues: []string{"us-west-2a"},
+		},
+		{
+			name: "an unmapped zone that no node is in passes through with a warning",
+			restore: defaultRestore().NodeMappings(velerov1api.NodeMapping{
+				Key:    "topology.kubernetes.io/zone",
+				Values: map[string]string{"us-east-1b": "us-west-2a"},
+			}).Restore(),
+			pv:           test.NewPV("pv-1", withZoneAffinity("topology.kubernetes.io/zone", "us-east-1a")),
+			wantKey:      "topology.kubernetes.io/zone",
+			wantValues:   []string{"us-east-1a"},
+			wantWarnings: 1,
+		},
+		{
+			name: "an unmapped zone that a node is in passes through",
+			restore: defaultRestore().NodeMappings(velerov1api.NodeMapping{
+				Key:    "topology.kubernetes.io/region",
+				Values: map[string]string{"us-east-1": "us-west-2"},
+			}).Restore(),
+			pv:         test.NewPV("pv-1", withZoneAffinity("topology.kubernetes.io/zone", "us-west-2a")),
+			wantKey:    "topology.kubernetes.io/zone",
+			wantValues: []string{"us-west-2a"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.Nodes(
+				test.NewNode("node-1", test.WithLabels("topology.kubernetes.io/zone", "us-west-2a")),
+			))
+			h.addItems(t, test.PVs())
+
+			tarball := newTarWriter(t).
+				addItems("persistentvolumes", tc.pv).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, errs)
+			assert.Len(t, warnings.Cluster, tc.wantWarnings)
+
+			res, err := h.DynamicClient.Resource(test.PVs().GVR()).Get("pv-1", metav1.GetOptions{})
+			require.NoError(t, err)
+			pv := new(corev1api.PersistentVolume)
+			require.NoError(t, runtime.DefaultUnstructuredConverter.FromUnstructured(res.Object, pv))
+			require.NotNil(t, pv.Spec.NodeAffinity)
+			requirement := pv.Spec.NodeAffinity.Required.NodeSelectorTerms[0].MatchExpressions[0]
+			assert.Equal(t, tc.wantKey, requirement.Key)
+			assert.Equal(t, tc.wantValues, requirement.Values)
+		})
+	}
+}
+
+// TestRestoreScaleDownOnRestore runs restores of a deployment with 3 replicas, and verifies
+// that the created deployment has 0 replicas, is paused, and records its original replica
+// count only when the restore scales down on restore.
+func TestRestoreScaleDownOnRestore(t *testing.T) {
+	replicas := int32(3)
+	deployment := test.NewDeployment("ns-1", "deploy-1", func(obj metav1.Object) {
+		obj.(*appsv1api.Deployment).Spec.Replicas = &replicas
+	})
+
+	tests := []struct {
+		name           string
+		restore        *velerov1api.Restore
+		wantReplicas   int64
+		wantPaused     bool
+		wantAnnotation string
+	}{
+		{
+			name:           "the deployment is scaled down and paused when the flag is set",
+			restore:        defaultRestore().ScaleDownOnRestore(true).Restore(),
+			wantReplicas:   0,
+			wantPaused:     true,
+			wantAnnotation: "3",
+		},
+		{
+			name:         "the deployment keeps its replicas when the flag is not set",
+			restore:      defaultRestore().Restore(),
+			wantReplicas: 3,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.Deployments())
+
+			tarball := newTarWriter(t).
+				addItems("deployments.apps", deployment).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, warnings, errs)
+
+			res, err := h.DynamicClient.Resource(test.Deployments().GVR()).Namespace("ns-1").Get("deploy-1", metav1.GetOptions{})
+			require.NoError(t, err)
+
+			replicas, _, err := unstructured.NestedInt64(res.Object, "spec", "replicas")
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantReplicas, replicas)
+
+			paused, _, err := unstructured.NestedBool(res.Object, "spec", "paused")
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantPaused, paused)
+
+			assert.Equal(t, tc.wantAnnotation, res.GetAnnotations()[velerov1api.OriginalReplicasAnnotation])
+		})
+	}
+}
+
+// TestRestoreItemOperationTimeout runs restores of config maps in two namespaces, where
+// the client calls for items in one namespace hang, and verifies that the hanging items
+// are recorded as failed once the item operation timeout passes, while the restore goes
+// on to restore the rest of the items.
+func TestRestoreItemOperationTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		apiResource *test.APIResource
+		blockGets   bool
+		wantAPI     []string
+	}{
+		{
+			name:        "items whose creates hang are failed",
+			apiResource: test.ConfigMaps(),
+			wantAPI:     []string{"ns-2/cm-1", "ns-2/cm-2"},
+		},
+		{
+			name: "existing items whose gets hang are failed",
+			apiResource: test.ConfigMaps(
+				test.NewConfigMap("ns-1", "cm-1"),
+				test.NewConfigMap("ns-1", "cm-2"),
+			),
+			blockGets: true,
+			wantAPI:   []string{"ns-1/cm-1", "ns-1/cm-2", "ns-2/cm-1", "ns-2/cm-2"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, tc.apiResource)
+
+			factory := &blockingFactory{
+				DynamicFactory: h.restorer.dynamicFactory,
+				namespace:      "ns-1",
+				blockGets:      tc.blockGets,
+				timeout:        50 * time.Millisecond,
+				release:        make(chan struct{}),
+			}
+			defer close(factory.release)
+			h.restorer.dynamicFactory = factory
+			h.restorer.itemOperationTimeout = 50 * time.Millisecond
+
+			tarball := newTarWriter(t).
+				addItems("configmaps",
+					test.NewConfigMap("ns-1", "cm-1"),
+					test.NewConfigMap("ns-1", "cm-2"),
+					test.NewConfigMap("ns-2", "cm-1"),
+					test.NewConfigMap("ns-2", "cm-2"),
+				).
+				done()
+
+			warnings, errs, results := h.restorer.Restore(
+				h.log,
+				defaultRestore().Restore(),
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, warnings)
+			assert.Len(t, errs.Namespaces["ns-1"], 2)
+			assert.Empty(t, errs.Namespaces["ns-2"])
+
+			assertAPIContents(t, h, map[*test.APIResource][]string{
+				test.ConfigMaps(): tc.wantAPI,
+			})
+
+			outcomes := make(map[string]ItemOutcome)
+			for _, res := range results {
+				outcomes[res.Namespace+"/"+res.Name] = res.Outcome
+				if res.Outcome == ItemOutcomeFailed {
+					assert.Contains(t, res.Reason, "timed out after 50ms")
+				}
+			}
+			assert.Equal(t, map[string]ItemOutcome{
+				"ns-1/cm-1": ItemOutcomeFailed,
+				"ns-1/cm-2": ItemOutcomeFailed,
+				"ns-2/cm-1": ItemOutcomeCreated,
+				"ns-2/cm-2": ItemOutcomeCreated,
+			}, outcomes)
+		})
+	}
+}
+
+// memoryItemSource is an ItemSource that supplies items from memory, and records
+// the items that are read from it.
+type memoryItemSource struct {
+	items map[string][]byte
+	read  []string
+}
+
+func newMemoryItemSource() *memoryItemSource {
+	return &memoryItemSource{items: make(map[string][]byte)}
+}
+
+func (s *memoryItemSource) addItems(t *testing.T, groupResource string, items ...metav1.Object) *memoryItemSource {
+	t.Helper()
+
+	for _, item := range items {
+		data, err := encode.Encode(item.(runtime.Object), "json")
+		require.NoError(t, err)
+		s.items[memoryItemKey(schema.ParseGroupResource(groupResource), item.GetNamespace(), item.GetName())] = data
+	}
+	return s
+}
+
+func memoryItemKey(groupResource schema.GroupResource, namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", groupResource, namespace, name)
+}
+
+func (s *memoryItemSource) ListItems(groupResource schema.GroupResource, namespace string) ([]string, error) {
+	prefix := memoryItemKey(groupResource, namespace, "")
+
+	var names []string
+	for key := range s.items {
+		if strings.HasPrefix(key, prefix) {
+			names = append(names, strings.TrimPrefix(key, prefix))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *memoryItemSource) ReadItem(groupResource schema.GroupResource, namespace, name string) ([]byte, error) {
+	key := memoryItemKey(groupResource, namespace, name)
+	s.read = append(s.read, key)
+
+	data, ok := s.items[key]
+	if !ok {
+		return nil, errors.Errorf("item %s not found", key)
+	}
+	return data, nil
+}
+
+// TestRestoreFromItemSource runs restores of a persistent volume and the claim bound to it,
+// once from the backup's item files and once from an in-memory item source, and verifies
+// that the items are read from the item source and restored identically.
+func TestRestoreFromItemSource(t *testing.T) {
+	pv := test.NewPV("pv-1", func(obj metav1.Object) {
+		pv := obj.(*corev1api.PersistentVolume)
+		pv.Spec.PersistentVolumeReclaimPolicy = corev1api.PersistentVolumeReclaimRetain
+		pv.Spec.ClaimRef = &corev1api.ObjectReference{Namespace: "ns-1", Name: "pvc-1"}
+	})
+	pvc := test.NewPVC("ns-1", "pvc-1", func(obj metav1.Object) {
+		obj.(*corev1api.PersistentVolumeClaim).Spec.VolumeName = "pv-1"
+	})
+
+	restore := func(itemSource ItemSource) (Result, Result, []ItemResult, []*unstructured.Unstructured) {
+		h := newHarness(t)
+		h.addItems(t, test.PVs())
+		h.addItems(t, test.PVCs())
+		h.restorer.itemSource = itemSource
+
+		tarball := newTarWriter(t).
+			addItems("persistentvolumes", pv).
+			addItems("persistentvolumeclaims", pvc).
+			done()
+
+		warnings, errs, results := h.restorer.Restore(
+			h.log,
+			defaultRestore().Restore(),
+			defaultBackup().Backup(),
+			nil, // volume snapshots
+			tarball,
+			nil, // actions
+			nil, // snapshot location lister
+			nil, // volume snapshotter getter
+		)
+
+		restoredPV, err := h.DynamicClient.Resource(test.PVs().GVR()).Get("pv-1", metav1.GetOptions{})
+		require.NoError(t, err)
+		restoredPVC, err := h.DynamicClient.Resource(test.PVCs().GVR()).Namespace("ns-1").Get("pvc-1", metav1.GetOptions{})
+		require.NoError(t, err)
+
+		return warnings, errs, results, []*unstructured.Unstructured{restoredPV, restoredPVC}
+	}
+
+	wantWarnings, wantErrs, wantResults, wantRestored := restore(nil)
+
+	source := newMemoryItemSource().
+		addItems(t, "persistentvolumes", pv).
+		addItems(t, "persistentvolumeclaims", pvc)
+	warnings, errs, results, restored := restore(source)
+
+	assert.Contains(t, source.read, "persistentvolumes//pv-1")
+	assert.Contains(t, source.read, "persistentvolumeclaims/ns-1/pvc-1")
+	assert.Contains(t, source.read, "namespaces//ns-1")
+
+	assertEmptyResults(t, wantWarnings, wantErrs)
+	assert.Equal(t, wantWarnings, warnings)
+	assert.Equal(t, wantErrs, errs)
+	assert.Equal(t, wantResults, results)
+	assert.Equal(t, wantRestored, restored)
+}
+
+// TestRestoreItemResultsByNamespace runs a restore spanning multiple namespaces and
+// verifies that the per-item outcomes and warnings are correctly aggregated by the
+// namespace they were restored into.
+func TestRestoreItemResultsByNamespace(t *testing.T) {
+	h := newHarness(t)
+	h.addItems(t, test.Pods(
+		test.NewPod("ns-1", "pod-2", test.WithLabels("key-1", "val-1")),
+		test.NewPod("ns-2", "pod-4"),
+	))
+	h.addItems(t, test.PVs())
+
+	tarball := newTarWriter(t).
+		addItems("pods",
+			test.NewPod("ns-1", "pod-1"),
+			test.NewPod("ns-1", "pod-2"),
+			test.NewPod("ns-2", "pod-3"),
+			test.NewPod("ns-2", "pod-4"),
+		).
+		addItems("persistentvolumes", test.NewPV("pv-1")).
+		done()
+
+	warnings, errs, itemResults := h.restorer.Restore(
+		h.log,
+		defaultRestore().Restore(),
+		defaultBackup().Backup(),
+		nil, // volume snapshots
+		tarball,
+		nil, // actions
+		nil, // snapshot location lister
+		nil, // volume snapshotter getter
+	)
+	assertEmptyResults(t, errs)
+
+	summaries := itemResults.ByNamespace(warnings)
+	require.Len(t, summaries, 3)
+
+	// pod-2 already exists and differs from the backed-up version, so it's
+	// skipped with a warning.
+	assert.Equal(t, 1, summaries["ns-1"].Created)
+	assert.Equal(t, 1, summaries["ns-1"].Skipped)
+	assert.Len(t, summaries["ns-1"].Warnings, 1)
+
+	// pod-4 already exists and is identical, so it's skipped without a warning.
+	assert.Equal(t, 1, summaries["ns-2"].Created)
+	assert.Equal(t, 1, summaries["ns-2"].Skipped)
+	assert.Empty(t, summaries["ns-2"].Warnings)
+
+	assert.Equal(t, NamespaceSummary{Created: 1}, summaries[""])
+}
+
+// TestRestoreItemRecords runs a restore of a PV that's dynamically provisioned by its
+// claim, the claim, and a config map that already exists, and verifies that the serialized
+// item records contain an entry with the action taken for each of them.
+func TestRestoreItemRecords(t *testing.T) {
+	h := newHarness(t)
+	h.restorer.resourcePriorities = []string{"persistentvolumes", "persistentvolumeclaims"}
+	h.addItems(t, test.PVs())
+	h.addItems(t, test.PVCs())
+	h.addItems(t, test.ConfigMaps(test.NewConfigMap("ns-1", "cm-1")))
+
+	tarball := newTarWriter(t).
+		addItems("persistentvolumes", test.NewPV("pv-1", func(obj metav1.Object) {
+			obj.(*corev1api.PersistentVolume).Spec.PersistentVolumeReclaimPolicy = corev1api.PersistentVolumeReclaimDelete
+		})).
+		addItems("persistentvolumeclaims", test.NewPVC("ns-1", "pvc-1", func(obj metav1.Object) {
+			obj.(*corev1api.PersistentVolumeClaim).Spec.VolumeName = "pv-1"
+		})).
+		addItems("configmaps", test.NewConfigMap("ns-1", "cm-1")).
+		done()
+
+	warnings, errs, itemResults := h.restorer.Restore(
+		h.log,
+		defaultRestore().Restore(),
+		defaultBackup().Backup(),
+		nil, // volume snapshots
+		tarball,
+		nil, // actions
+		nil, // snapshot location lister
+		nil, // volume snapshotter getter
+	)
+
+	assertEmptyResults(t, warnings, errs)
+
+	data, err := json.Marshal(itemResults.Records())
+	require.NoError(t, err)
+
+	var records []map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &records))
+
+	assert.ElementsMatch(t, []map[string]interface{}{
+		{"resource": "persistentvolumes", "name": "pv-1", "action": "provisioned-pv", "reason": dynamicProvisionReason},
+		{"resource": "persistentvolumeclaims", "namespace": "ns-1", "name": "pvc-1", "action": "created"},
+		{"resource": "configmaps", "namespace": "ns-1", "name": "cm-1", "action": "skipped-exists", "reason": alreadyExistsReason},
+	}, records)
+}
+
+// TestRestoreStripHPAManagedReplicas runs restores of deployments, some of which are
+// the scale target of a horizontal pod autoscaler in the backup, and verifies that
+// spec.replicas is removed from only the autoscaled deployments when requested, matching
+// scale targets on their API group as well as their kind and name.
+func TestRestoreStripHPAManagedReplicas(t *testing.T) {
+	deployment := func(name string) *appsv1api.Deployment {
+		replicas := int32(3)
+		return test.NewDeployment("ns-1", name, func(obj metav1.Object) {
+			obj.(*appsv1api.Deployment).Spec.Replicas = &replicas
+		})
+	}
+
+	hpa := func(name, targetAPIVersion, targetName string) *autoscalingv1api.HorizontalPodAutoscaler {
+		return &autoscalingv1api.HorizontalPodAutoscaler{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "autoscaling/v1", Kind: "HorizontalPodAutoscaler"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns-1", Name: name},
+			Spec: autoscalingv1api.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv1api.CrossVersionObjectReference{APIVersion: targetAPIVersion, Kind: "Deployment", Name: targetName},
+				MaxReplicas:    10,
+			},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		restore      *velerov1api.Restore
+		wantReplicas map[string]bool
+	}{
+		{
+			name:         "replicas are removed from deployments with a matching HPA when the flag is set",
+			restore:      defaultRestore().StripHPAManagedReplicas(true).Restore(),
+			wantReplicas: map[string]bool{"deploy-1": false, "deploy-2": true, "deploy-3": true, "deploy-4": false},
+		},
+		{
+			name:         "replicas are kept on all deployments when the flag is not set",
+			restore:      defaultRestore().Restore(),
+			wantReplicas: map[string]bool{"deploy-1": true, "deploy-2": true, "deploy-3": true, "deploy-4": true},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.Deployments())
+
+			tarball := newTarWriter(t).
+				addItems("deployments.apps", deployment("deploy-1"), deployment("deploy-2"), deployment("deploy-3"), deployment("deploy-4")).
+				addItems("horizontalpodautoscalers.autoscaling",
+					hpa("hpa-1", "apps/v1", "deploy-1"),
+					// a same-named kind in another group doesn't match
+					hpa("hpa-3", "example.com/v1", "deploy-3"),
+					// deployments were served by extensions before apps
+					hpa("hpa-4", "extensions/v1beta1", "deploy-4"),
+				).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, warnings, errs)
+
+			for name, want := range tc.wantReplicas {
+				res, err := h.DynamicClient.Resource(test.Deployments().GVR()).Namespace("ns-1").Get(name, metav1.GetOptions{})
+				require.NoError(t, err)
+
+				_, found, err := unstructured.NestedFieldNoCopy(res.Object, "spec", "replicas")
+				require.NoError(t, err)
+				assert.Equal(t, want, found, "unexpected presence of spec.replicas on %s", name)
+			}
+		})
+	}
+}
+
+// TestRestoreItemsWithoutNamespace runs restores of a namespaced resource whose items
+// were backed up without a namespace, and verifies that the items are either restored
+// into the default namespace or recorded as errors according to the restore's policy.
+func TestRestoreItemsWithoutNamespace(t *testing.T) {
+	tests := []struct {
+		name        string
+		restore     *velerov1api.Restore
+		want        map[*test.APIResource][]string
+		wantErrs    int
+		wantOutcome ItemOutcome
+	}{
+		{
+			name:        "items are restored into the default namespace when the policy is UseDefault",
+			restore:     defaultRestore().MissingNamespacePolicy(velerov1api.MissingNamespacePolicyUseDefault).DefaultNamespace("ns-default").Restore(),
+			want:        map[*test.APIResource][]string{test.Pods(): {"ns-default/pod-1"}},
+			wantOutcome: ItemOutcomeCreated,
+		},
+		{
+			name:        "items are recorded as errors when the policy is UseDefault but no default namespace is specified",
+			restore:     defaultRestore().MissingNamespacePolicy(velerov1api.MissingNamespacePolicyUseDefault).Restore(),
+			want:        map[*test.APIResource][]string{test.Pods(): {}},
+			wantErrs:    1,
+			wantOutcome: ItemOutcomeFailed,
+		},
+		{
+			name:        "items are recorded as errors when no policy is specified",
+			restore:     defaultRestore().Restore(),
+			want:        map[*test.APIResource][]string{test.Pods(): {}},
+			wantErrs:    1,
+			wantOutcome: ItemOutcomeFailed,
+		},
+		{
+			name:        "items are recorded as errors when the policy is Error",
+			restore:     defaultRestore().MissingNamespacePolicy(velerov1api.MissingNamespacePolicyError).DefaultNamespace("ns-default").Restore(),
+			want:        map[*test.APIResource][]string{test.Pods(): {}},
+			wantErrs:    1,
+			wantOutcome: ItemOutcomeFailed,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.Pods())
+
+			tarball := newTarWriter(t).
+				addItems("pods", test.NewPod("", "pod-1")).
+				done()
+
+			warnings, errs, itemResults := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, warnings)
+			assert.Len(t, errs.Cluster, tc.wantErrs)
+			assertAPIContents(t, h, tc.want)
+
+			require.Len(t, itemResults, 1)
+			assert.Equal(t, tc.wantOutcome, itemResults[0].Outcome)
+		})
+	}
+}
+
+// TestRestoreDataKeyMappings runs restores of config maps whose keys are renamed or
+// removed by data key mappings, alongside pods that mount them, and verifies that the
+// keys are remapped and that pod references to remapped keys produce warnings.
+func TestRestoreDataKeyMappings(t *testing.T) {
+	configMap := test.NewConfigMap("ns-1", "cm-1", func(obj metav1.Object) {
+		obj.(*corev1api.ConfigMap).Data = map[string]string{"old-key": "val-1", "other-key": "val-2"}
+	})
+
+	configMapVolume := func(items ...corev1api.KeyToPath) corev1api.Volume {
+		return corev1api.Volume{
+			Name: "config",
+			VolumeSource: corev1api.VolumeSource{
+				ConfigMap: &corev1api.ConfigMapVolumeSource{
+					LocalObjectReference: corev1api.LocalObjectReference{Name: "cm-1"},
+					Items:                items,
+				},
+			},
+		}
+	}
+
+	pod := func(volume corev1api.Volume, subPath string) *corev1api.Pod {
+		return test.NewPod("ns-1", "pod-1", func(obj metav1.Object) {
+			obj.(*corev1api.Pod).Spec = corev1api.PodSpec{
+				Volumes: []corev1api.Volume{volume},
+				Containers: []corev1api.Container{
+					{
+						Name:         "container-1",
+						VolumeMounts: []corev1api.VolumeMount{{Name: "config", MountPath: "/etc/config", SubPath: subPath}},
+					},
+				},
+			}
+		})
+	}
+
+	renameOldKey := velerov1api.DataKeyMapping{Resource: "configmaps", Name: "cm-1", Key: "old-key", NewKey: "new-key"}
+
+	tests := []struct {
+		name         string
+		restore      *velerov1api.Restore
+		pod          *corev1api.Pod
+		wantKeys     []string
+		wantData     map[string]string
+		wantWarnings int
+	}{
+		{
+			name:         "a subPath referencing a renamed key produces a warning",
+			restore:      defaultRestore().DataKeyMappings(renameOldKey).Restore(),
+			pod:          pod(configMapVolume(), "old-key"),
+			wantKeys:     []string{"new-key", "other-key"},
+			wantWarnings: 1,
+		},
+		{
+			name:         "a volume item referencing a removed key produces a warning",
+			restore:      defaultRestore().DataKeyMappings(velerov1api.DataKeyMapping{Resource: "configmaps", Name: "cm-1", Key: "old-key"}).Restore(),
+			pod:          pod(configMapVolume(corev1api.KeyToPath{Key: "old-key", Path: "config.yaml"}), "config.yaml"),
+			wantKeys:     []string{"other-key"},
+			wantWarnings: 1,
+		},
+		{
+			name:         "a subPath referencing an unmapped key does not produce a warning",
+			restore:      defaultRestore().DataKeyMappings(renameOldKey).Restore(),
+			pod:          pod(configMapVolume(), "other-key"),
+			wantKeys:     []string{"new-key", "other-key"},
+			wantWarnings: 0,
+		},
+		{
+			name:         "a mapping for a different config map does not produce a warning",
+			restore:      defaultRestore().DataKeyMappings(velerov1api.DataKeyMapping{Resource: "configmaps", Name: "cm-2", Key: "old-key", NewKey: "new-key"}).Restore(),
+			pod:          pod(configMapVolume(), "old-key"),
+			wantKeys:     []string{"old-key", "other-key"},
+			wantWarnings: 0,
+		},
+		{
+			name: "each key is mapped once, by its backed-up name",
+			restore: defaultRestore().DataKeyMappings(
+				velerov1api.DataKeyMapping{Resource: "configmaps", Name: "cm-1", Key: "old-key", NewKey: "other-key"},
+				velerov1api.DataKeyMapping{Resource: "configmaps", Name: "cm-1", Key: "other-key", NewKey: "third-key"},
+			).Restore(),
+			pod:          pod(configMapVolume(), "unmapped-key"),
+			wantKeys:     []string{"other-key", "third-key"},
+			wantData:     map[string]string{"other-key": "val-1", "third-key": "val-2"},
+			wantWarnings: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.ConfigMaps())
+			h.addItems(t, test.Pods())
+
+			tarball := newTarWriter(t).
+				addItems("configmaps", configMap).
+				addItems("pods", tc.pod).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, errs)
+			assert.Len(t, warnings.Namespaces["ns-1"], tc.wantWarnings)
+
+			res, err := h.DynamicClient.Resource(test.ConfigMaps().GVR()).Namespace("ns-1").Get("cm-1", metav1.GetOptions{})
+			require.NoError(t, err)
+
+			data, _, err := unstructured.NestedStringMap(res.Object, "data")
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tc.wantKeys, sets.StringKeySet(data).List())
+			if tc.wantData != nil {
+				assert.Equal(t, tc.wantData, data)
+			}
+		})
+	}
+}
+
+// TestRestoreSecretSubstitutions runs restores of a secret with secret substitutions, and
+// verifies that only the values of the substituted keys of the matching secret are
+// replaced, base64-encoded, and that other keys and secrets are restored unchanged.
+func TestRestoreSecretSubstitutions(t *testing.T) {
+	type secretKey struct {
+		namespace, name string
+	}
+
+	newSecret := func(namespace, name string) *corev1api.Secret {
+		return test.NewSecret(namespace, name, func(obj metav1.Object) {
+			obj.(*corev1api.Secret).Data = map[string][]byte{
+				".dockerconfigjson": []byte(`{"auths":{"source.example.com":{}}}`),
+				"other-key":         []byte("val-1"),
+			}
+		})
+	}
+
+	substitution := velerov1api.SecretSubstitution{
+		Name:  "registry",
+		Key:   ".dockerconfigjson",
+		Value: `{"auths":{"target.example.com":{}}}`,
+	}
+
+	tests := []struct {
+		name         string
+		substitution velerov1api.SecretSubstitution
+		want         map[secretKey]map[string][]byte
+	}{
+		{
+			name:         "a substitution replaces its key in matching secrets in all namespaces",
+			substitution: substitution,
+			want: map[secretKey]map[string][]byte{
+				{"ns-1", "registry"}: {".dockerconfigjson": []byte(`{"auths":{"target.example.com":{}}}`), "other-key": []byte("val-1")},
+				{"ns-2", "registry"}: {".dockerconfigjson": []byte(`{"auths":{"target.example.com":{}}}`), "other-key": []byte("val-1")},
+				{"ns-1", "other"}:    {".dockerconfigjson": []byte(`{"auths":{"source.example.com":{}}}`), "other-key": []byte("val-1")},
+			},
+		},
+		{
+			name: "a substitution with a namespace only replaces its key in that namespace",
+			substitution: func() velerov1api.SecretSubstitution {
+				s := substitution
+				s.Namespace = "ns-2"
+				return s
+			}(),
+			want: map[secretKey]map[string][]byte{
+				{"ns-1", "registry"}: {".dockerconfigjson": []byte(`{"auths":{"source.example.com":{}}}`), "other-key": []byte("val-1")},
+				{"ns-2", "registry"}: {".dockerconfigjson": []byte(`{"auths":{"target.example.com":{}}}`), "other-key": []byte("val-1")},
+				{"ns-1", "other"}:    {".dockerconfigjson": []byte(`{"auths":{"source.example.com":{}}}`), "other-key": []byte("val-1")},
+			},
+		},
+		{
+			name:         "a substitution for a key a secret doesn't have leaves it unchanged",
+			substitution: velerov1api.SecretSubstitution{Name: "registry", Key: "missing", Value: "val-2"},
+			want: map[secretKey]map[string][]byte{
+				{"ns-1", "registry"}: {".dockerconfigjson": []byte(`{"auths":{"source.example.com":{}}}`), "other-key": []byte("val-1")},
+				{"ns-2", "registry"}: {".dockerconfigjson": []byte(`{"auths":{"source.example.com":{}}}`), "other-key": []byte("val-1")},
+				{"ns-1", "other"}:    {".dockerconfigjson": []byte(`{"auths":{"source.example.com":{}}}`), "other-key": []byte("val-1")},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.Secrets())
+
+			tarball := newTarWriter(t).
+				addItems("secrets",
+					newSecret("ns-1", "registry"),
+					newSecret("ns-2", "registry"),
+					newSecret("ns-1", "other"),
+				).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				defaultRestore().SecretSubstitutions(tc.substitution).Restore(),
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, warnings, errs)
+
+			for key, want := range tc.want {
+				res, err := h.DynamicClient.Resource(test.Secrets().GVR()).Namespace(key.namespace).Get(key.name, metav1.GetOptions{})
+				require.NoError(t, err)
+
+				secret := new(corev1api.Secret)
+				require.NoError(t, runtime.DefaultUnstructuredConverter.FromUnstructured(res.Object, secret))
+				assert.Equal(t, want, secret.Data, "%s/%s", key.namespace, key.name)
+			}
+		})
+	}
+}
+
+// TestRestoreObserveDrift runs a restore with a drift observation window during which
+// one of the restored items is changed in the cluster, and verifies that only the
+// changed item is reported as having drifted.
+func TestRestoreObserveDrift(t *testing.T) {
+	h := newHarness(t)
+	h.addItems(t, test.Pods())
+
+	tarball := newTarWriter(t).
+		addItems("pods",
+			test.NewPod("ns-1", "pod-1"),
+			test.NewPod("ns-1", "pod-2"),
+		).
+		done()
+
+	// simulate a controller reconciling pod-1 during the observation window
+	var slept time.Duration
+	driftSleep = func(d time.Duration) {
+		slept = d
+
+		podClient := h.DynamicClient.Resource(test.Pods().GVR()).Namespace("ns-1")
+		pod, err := podClient.Get("pod-1", metav1.GetOptions{})
+		require.NoError(t, err)
+		require.NoError(t, unstructured.SetNestedField(pod.Object, "node-1", "spec", "nodeName"))
+		_, err = podClient.Update(pod, metav1.UpdateOptions{})
+		require.NoError(t, err)
+	}
+	defer func() { driftSleep = time.Sleep }()
+
+	warnings, errs, _ := h.restorer.Restore(
+		h.log,
+		defaultRestore().ObserveDriftSeconds(30).Restore(),
+		defaultBackup().Backup(),
+		nil, // volume snapshots
+		tarball,
+		nil, // actions
+		nil, // snapshot location lister
+		nil, // volume snapshotter getter
+	)
+
+	assertEmptyResults(t, errs)
+	assert.Equal(t, 30*time.Second, slept)
+	require.Len(t, warnings.Namespaces["ns-1"], 1)
+	assert.Contains(t, warnings.Namespaces["ns-1"][0], "ns-1/pod-1 drifted after restore")
+}
+
+// readCountingFileSystem is a filesystem.Interface that counts the number of
+// times each file is read.
+type readCountingFileSystem struct {
+	filesystem.Interface
+	reads map[string]int
+}
+
+func (fs *readCountingFileSystem) ReadFile(filename string) ([]byte, error) {
+	fs.reads[filepath.Base(filename)]++
+	return fs.Interface.ReadFile(filename)
+}
+
+// TestRestoreNamespaceFanout runs a restore that fans a source namespace out to
+// multiple target namespaces, and verifies that each item is decoded from the backup
+// once and created in every target namespace.
+func TestRestoreNamespaceFanout(t *testing.T) {
+	h := newHarness(t)
+	h.addItems(t, test.Pods())
+
+	fs := &readCountingFileSystem{Interface: h.restorer.fileSystem, reads: make(map[string]int)}
+	h.restorer.fileSystem = fs
+
+	tarball := newTarWriter(t).
+		addItems("pods",
+			test.NewPod("ns-1", "pod-1"),
+			test.NewPod("ns-2", "pod-2"),
+		).
+		done()
+
+	warnings, errs, _ := h.restorer.Restore(
+		h.log,
+		defaultRestore().NamespaceFanout("ns-1", "ns-a", "ns-b", "ns-c").Restore(),
+		defaultBackup().Backup(),
+		nil, // volume snapshots
+		tarball,
+		nil, // actions
+		nil, // snapshot location lister
+		nil, // volume snapshotter getter
+	)
+
+	assertEmptyResults(t, warnings, errs)
+	assertAPIContents(t, h, map[*test.APIResource][]string{
+		test.Pods(): {"ns-a/pod-1", "ns-b/pod-1", "ns-c/pod-1", "ns-2/pod-2"},
+	})
+	assert.Equal(t, 1, fs.reads["pod-1.json"])
+}
+
+// BenchmarkRestoreNamespaceFanout measures a restore that fans a source namespace
+// containing a single item out to many target namespaces.
+func BenchmarkRestoreNamespaceFanout(b *testing.B) {
+	targets := make([]string, 50)
+	for i := range targets {
+		targets[i] = fmt.Sprintf("ns-%d", i)
+	}
+
+	backupData := newTarWriter(b).
+		addItems("pods", test.NewPod("ns-1", "pod-1")).
+		done().
+		Bytes()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		h := newHarness(b)
+		h.addItems(b, test.Pods())
+		h.log = testutil.NewLogger()
+		b.StartTimer()
+
+		_, errs, _ := h.restorer.Restore(
+			h.log,
+			defaultRestore().NamespaceFanout("ns-1", targets...).Restore(),
+			defaultBackup().Backup(),
+			nil, // volume snapshots
+			bytes.NewReader(backupData),
+			nil, // actions
+			nil, // snapshot location lister
+			nil, // volume snapshotter getter
+		)
+		require.Empty(b, errs.Namespaces)
+	}
+}
+
+// TestRestoreRespectBackupExclusions runs restores of a backup that recorded excluded
+// namespaces, and verifies that those namespaces are skipped when the restore respects
+// the backup's exclusions unless they're explicitly included.
+func TestRestoreRespectBackupExclusions(t *testing.T) {
+	tests := []struct {
+		name    string
+		restore *velerov1api.Restore
+		want    []string
+	}{
+		{
+			name:    "a backup-excluded namespace is skipped by default when respecting backup exclusions",
+			restore: defaultRestore().RespectBackupExclusions(true).Restore(),
+			want:    []string{"ns-1/pod-1"},
+		},
+		{
+			name:    "a backup-excluded namespace is restored when explicitly included",
+			restore: defaultRestore().RespectBackupExclusions(true).IncludedNamespaces("ns-1", "ns-2").Restore(),
+			want:    []string{"ns-1/pod-1", "ns-2/pod-2"},
+		},
+		{
+			name:    "a backup-excluded namespace is restored when not respecting backup exclusions",
+			restore: defaultRestore().Restore(),
+			want:    []string{"ns-1/pod-1", "ns-2/pod-2"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.Pods())
+
+			tarball := newTarWriter(t).
+				addItems("pods",
+					test.NewPod("ns-1", "pod-1"),
+					test.NewPod("ns-2", "pod-2"),
+				).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().ExcludedNamespaces("ns-2").Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, warnings, errs)
+			assertAPIContents(t, h, map[*test.APIResource][]string{test.Pods(): tc.want})
+		})
+	}
+}
+
+// TestRestoreMaxObjectAge runs a restore with a maximum object age, and verifies that
+// items created before the threshold are skipped with a reason while newer items are
+// restored.
+func TestRestoreMaxObjectAge(t *testing.T) {
+	h := newHarness(t)
+	h.addItems(t, test.Jobs())
+
+	tarball := newTarWriter(t).
+		addItems("jobs.batch",
+			test.NewJob("ns-1", "old-job", test.WithCreationTimestamp(time.Now().Add(-48*time.Hour))),
+			test.NewJob("ns-1", "recent-job", test.WithCreationTimestamp(time.Now().Add(-time.Hour))),
+		).
+		done()
+
+	warnings, errs, itemResults := h.restorer.Restore(
+		h.log,
+		defaultRestore().MaxObjectAge(24*time.Hour).Restore(),
+		defaultBackup().Backup(),
+		nil, // volume snapshots
+		tarball,
+		nil, // actions
+		nil, // snapshot location lister
+		nil, // volume snapshotter getter
+	)
+
+	assertEmptyResults(t, warnings, errs)
+	assertAPIContents(t, h, map[*test.APIResource][]string{test.Jobs(): {"ns-1/recent-job"}})
+
+	outcomes := make(map[string]ItemResult)
+	for _, res := range itemResults {
+		outcomes[res.Name] = res
+	}
+	assert.Equal(t, ItemOutcomeCreated, outcomes["recent-job"].Outcome)
+	assert.Equal(t, ItemOutcomeSkipped, outcomes["old-job"].Outcome)
+	assert.Contains(t, outcomes["old-job"].Reason, "older than the maximum object age")
+}
+
+// TestRestoreDefaultStorageClass runs restores of a storage class marked as the default,
+// and verifies that its default class annotation is removed, with a warning, only when
+// the cluster already has a different default storage class.
+func TestRestoreDefaultStorageClass(t *testing.T) {
+	defaultAnnotation := test.WithAnnotations("storageclass.kubernetes.io/is-default-class", "true")
+
+	tests := []struct {
+		name           string
+		restore        *velerov1api.Restore
+		existing       []metav1.Object
+		wantAnnotation bool
+		wantWarnings   int
+	}{
+		{
+			name:           "annotation is removed when the cluster already has a default",
+			restore:        defaultRestore().Restore(),
+			existing:       []metav1.Object{test.NewStorageClass("existing-default", defaultAnnotation)},
+			wantAnnotation: false,
+			wantWarnings:   1,
+		},
+		{
+			name:           "annotation is kept when the cluster has no default",
+			restore:        defaultRestore().Restore(),
+			existing:       []metav1.Object{test.NewStorageClass("existing-non-default")},
+			wantAnnotation: true,
+		},
+		{
+			name:           "annotation is kept when the restore keeps default storage class annotations",
+			restore:        defaultRestore().KeepDefaultStorageClassAnnotation(true).Restore(),
+			existing:       []metav1.Object{test.NewStorageClass("existing-default", defaultAnnotation)},
+			wantAnnotation: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.StorageClasses(tc.existing...))
+
+			tarball := newTarWriter(t).
+				addItems("storageclasses.storage.k8s.io", test.NewStorageClass("sc-1", defaultAnnotation)).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, errs)
+			assert.Len(t, warnings.Cluster, tc.wantWarnings)
+
+			res, err := h.DynamicClient.Resource(test.StorageClasses().GVR()).Get("sc-1", metav1.GetOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantAnnotation, isDefaultStorageClass(res))
+		})
+	}
+}
+
+// recordingSpan is a Span that records its name, parent, attributes, and
+// whether it was ended.
+type recordingSpan struct {
+	name       string
+	parent     *recordingSpan
+	attributes map[string]interface{}
+	ended      bool
+}
+
+func (s *recordingSpan) SetAttributes(attributes ...Attribute) {
+	for _, attr := range attributes {
+		s.attributes[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordingSpan) End() {
+	s.ended = true
+}
+
+// recordingTracer is a Tracer that records all of the spans it starts.
+type recordingTracer struct {
+	spans []*recordingSpan
+}
+
+func (t *recordingTracer) StartSpan(name string, parent Span, attributes ...Attribute) Span {
+	span := &recordingSpan{name: name, attributes: make(map[string]interface{})}
+	if parent != nil {
+		span.parent = parent.(*recordingSpan)
+	}
+	span.SetAttributes(attributes...)
+
+	t.spans = append(t.spans, span)
+	return span
+}
+
+// TestRestoreTracing runs a restore of a PV and a PVC with a recording tracer, and
+// verifies that a span is emitted for the restore, each restored resource, and each
+// item, with the expected hierarchy and attributes.
+func TestRestoreTracing(t *testing.T) {
+	h := newHarness(t)
+	h.addItems(t, test.PVs())
+	h.addItems(t, test.PVCs())
+
+	tracer := new(recordingTracer)
+	h.restorer.tracer = tracer
+
+	tarball := newTarWriter(t).
+		addItems("persistentvolumes", test.NewPV("pv-1")).
+		addItems("persistentvolumeclaims", test.NewPVC("ns-1", "pvc-1")).
+		done()
+
+	warnings, errs, _ := h.restorer.Restore(
+		h.log,
+		defaultRestore().TraceItems(true).Restore(),
+		defaultBackup().Backup(),
+		nil, // volume snapshots
+		tarball,
+		nil, // actions
+		nil, // snapshot location lister
+		nil, // volume snapshotter getter
+	)
+	assertEmptyResults(t, warnings, errs)
+
+	// describe each span by its name, group resource and item name attributes,
+	// and its parent, e.g. "restore.item pods/pod-1 < restore.resource pods < restore"
+	var describe func(span *recordingSpan) string
+	describe = func(span *recordingSpan) string {
+		desc := span.name
+		if gr, ok := span.attributes["groupResource"]; ok {
+			desc += " " + gr.(string)
+		}
+		if name, ok := span.attributes["name"]; ok {
+			desc += "/" + name.(string)
+		}
+		if span.parent != nil {
+			desc += " < " + describe(span.parent)
+		}
+		return desc
+	}
+
+	var got []string
+	for _, span := range tracer.spans {
+		assert.True(t, span.ended, "span %s was not ended", describe(span))
+		got = append(got, describe(span))
+	}
+
+	assert.ElementsMatch(t, []string{
+		"restore",
+		"restore.resource persistentvolumes < restore",
+		"restore.item persistentvolumes/pv-1 < restore.resource persistentvolumes < restore",
+		"restore.resource persistentvolumeclaims < restore",
+		"restore.item persistentvolumeclaims/pvc-1 < restore.resource persistentvolumeclaims < restore",
+	}, got)
+
+	for _, span := range tracer.spans {
+		switch span.name {
+		case RestoreSpanName:
+			assert.Equal(t, 2, span.attributes["items.created"])
+		case ResourceSpanName:
+			assert.Equal(t, 1, span.attributes["items.total"])
+			assert.Equal(t, 1, span.attributes["items.created"])
+		case ItemSpanName:
+			assert.Equal(t, string(ItemOutcomeCreated), span.attributes["outcome"])
+		}
+	}
+}
+
+// TestRestoreTracingIndependentResources runs a restore of config maps and secrets that
+// are restored concurrently with a recording tracer, and verifies that each resource gets
+// its own span, with its own items' spans under it and outcomes counted.
+func TestRestoreTracingIndependentResources(t *testing.T) {
+	h := newHarness(t)
+	h.addItems(t, test.ConfigMaps())
+	h.addItems(t, test.Secrets())
+
+	tracer := new(recordingTracer)
+	h.restorer.tracer = tracer
+
+	tarball := newTarWriter(t).
+		addItems("configmaps", test.NewConfigMap("ns-1", "cm-1"), test.NewConfigMap("ns-1", "cm-2")).
+		addItems("secrets", test.NewSecret("ns-1", "secret-1"), test.NewSecret("ns-1", "secret-2")).
+		done()
+
+	warnings, errs, _ := h.restorer.Restore(
+		h.log,
+		defaultRestore().TraceItems(true).IndependentResourceParallelism(2).Parallelism(2).Restore(),
+		defaultBackup().Backup(),
+		nil, // volume snapshots
+		tarball,
+		nil, // actions
+		nil, // snapshot location lister
+		nil, // volume snapshotter getter
+	)
+	assertEmptyResults(t, warnings, errs)
+
+	resourceSpans := make(map[string]*recordingSpan)
+	for _, span := range tracer.spans {
+		assert.True(t, span.ended, "span %s was not ended", span.name)
+		if span.name == ResourceSpanName {
+			resourceSpans[span.attributes["groupResource"].(string)] = span
+		}
+	}
+	require.Len(t, resourceSpans, 2)
+
+	for resource, span := range resourceSpans {
+		assert.Equal(t, 2, span.attributes["items.total"], resource)
+		assert.Equal(t, 2, span.attributes["items.created"], resource)
+	}
+
+	for _, span := range tracer.spans {
+		if span.name == ItemSpanName {
+			assert.Equal(t, resourceSpans[span.attributes["groupResource"].(string)], span.parent)
+		}
+	}
+}
+
+// TestRestoreAdditionalItemsAcrossGroups runs a restore where an action for one group
+// returns an additional item from another group that's also in the backup, and verifies
+// that the additional item is only created once.
+func TestRestoreAdditionalItemsAcrossGroups(t *testing.T) {
+	h := newHarness(t)
+	h.restorer.resourcePriorities = []string{"pods", "configmaps"}
+
+	recorder := &createRecorder{t: t}
+	h.DynamicClient.PrependReactor("create", "*", recorder.reactor())
+
+	h.DiscoveryClient.WithAPIResource(test.Pods())
+	h.DiscoveryClient.WithAPIResource(test.ConfigMaps())
+	require.NoError(t, h.restorer.discoveryHelper.Refresh())
+
+	action := new(recordResourcesAction).ForResource("pods").WithAdditionalItems([]velero.ResourceIdentifier{
+		{GroupResource: kuberesource.ConfigMaps, Namespace: "ns-1", Name: "cm-1"},
+	})
+
+	tarball := newTarWriter(t).
+		addItems("pods", test.NewPod("ns-1", "pod-1")).
+		addItems("configmaps", test.NewConfigMap("ns-1", "cm-1")).
+		done()
+
+	warnings, errs, _ := h.restorer.Restore(
+		h.log,
+		defaultRestore().Restore(),
+		defaultBackup().Backup(),
+		nil, // volume snapshots
+		tarball,
+		[]velero.RestoreItemAction{action},
+		nil, // snapshot location lister
+		nil, // volume snapshotter getter
+	)
+
+	assertEmptyResults(t, warnings, errs)
+
+	var configMapCreates int
+	for _, res := range recorder.resources {
+		if res.groupResource == kuberesource.ConfigMaps.String() {
+			configMapCreates++
+		}
+	}
+	assert.Equal(t, 1, configMapCreates)
+	assertAPIContents(t, h, map[*test.APIResource][]string{
+		test.Pods():       {"ns-1/pod-1"},
+		test.ConfigMaps(): {"ns-1/cm-1"},
+	})
+}
+
+// TestRestoreExpectedCounts runs restores with expected counts of created items, and
+// verifies that mismatches are reported as warnings, or as errors when the restore's
+// expected counts are strict.
+func TestRestoreExpectedCounts(t *testing.T) {
+	tests := []struct {
+		name         string
+		restore      *velerov1api.Restore
+		wantWarnings []string
+		wantErrs     []string
+	}{
+		{
+			name:    "matching counts produce no warnings or errors",
+			restore: defaultRestore().ExpectedCount("pods", 2).ExpectedCount("deployments.apps", 1).Restore(),
+		},
+		{
+			name:         "a mismatched count produces a warning",
+			restore:      defaultRestore().ExpectedCount("pods", 3).ExpectedCount("deployments.apps", 1).Restore(),
+			wantWarnings: []string{"expected restore to create 3 pods, but it created 2"},
+		},
+		{
+			name:     "a mismatched count produces an error when expected counts are strict",
+			restore:  defaultRestore().ExpectedCount("pods", 2).ExpectedCount("deployments.apps", 0).StrictExpectedCounts(true).Restore(),
+			wantErrs: []string{"expected restore to create 0 deployments.apps, but it created 1"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.Pods())
+			h.addItems(t, test.Deployments())
+
+			tarball := newTarWriter(t).
+				addItems("pods", test.NewPod("ns-1", "pod-1"), test.NewPod("ns-1", "pod-2")).
+				addItems("deployments.apps", test.NewDeployment("ns-1", "deploy-1")).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assert.Equal(t, tc.wantWarnings, warnings.Velero)
+			assert.Equal(t, tc.wantErrs, errs.Velero)
+		})
+	}
+}
+
+// TestRestoreCSIDriverMappings runs restores of persistent volumes provisioned by CSI drivers,
+// and verifies that drivers are remapped according to the restore's CSI driver mappings, with
+// a warning when the new driver isn't installed in the cluster.
+func TestRestoreCSIDriverMappings(t *testing.T) {
+	withCSIDriver := func(driver string) func(metav1.Object) {
+		return func(obj metav1.Object) {
+			obj.(*corev1api.PersistentVolume).Spec.CSI = &corev1api.CSIPersistentVolumeSource{
+				Driver:       driver,
+				VolumeHandle: "handle-1",
+			}
+		}
+	}
+
+	tests := []struct {
+		name         string
+		restore      *velerov1api.Restore
+		csiDrivers   *test.APIResource
+		wantDriver   string
+		wantWarnings int
+	}{
+		{
+			name:       "a mapped driver is replaced",
+			restore:    defaultRestore().CSIDriverMappings("old.csi.example.com", "new.csi.example.com").Restore(),
+			csiDrivers: test.CSIDrivers(test.NewCSIDriver("new.csi.example.com")),
+			wantDriver: "new.csi.example.com",
+		},
+		{
+			name:         "a mapped driver that isn't installed is replaced with a warning",
+			restore:      defaultRestore().CSIDriverMappings("old.csi.example.com", "new.csi.example.com").Restore(),
+			csiDrivers:   test.CSIDrivers(test.NewCSIDriver("other.csi.example.com")),
+			wantDriver:   "new.csi.example.com",
+			wantWarnings: 1,
+		},
+		{
+			name:       "a mapped driver is replaced without a warning when the cluster doesn't serve CSI drivers",
+			restore:    defaultRestore().CSIDriverMappings("old.csi.example.com", "new.csi.example.com").Restore(),
+			wantDriver: "new.csi.example.com",
+		},
+		{
+			name:       "an unmapped driver is unchanged",
+			restore:    defaultRestore().CSIDriverMappings("other.csi.example.com", "new.csi.example.com").Restore(),
+			csiDrivers: test.CSIDrivers(test.NewCSIDriver("new.csi.example.com")),
+			wantDriver: "old.csi.example.com",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.PVs())
+			if tc.csiDrivers != nil {
+				h.addItems(t, tc.csiDrivers)
+			}
+
+			tarball := newTarWriter(t).
+				addItems("persistentvolumes", test.NewPV("pv-1", withCSIDriver("old.csi.example.com"))).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assert.Empty(t, errs.Velero)
+			assert.Empty(t, errs.Cluster)
+			assert.Len(t, warnings.Cluster, tc.wantWarnings)
+
+			res, err := h.DynamicClient.Resource(test.PVs().GVR()).Get("pv-1", metav1.GetOptions{})
+			require.NoError(t, err)
+			driver, _, _ := unstructured.NestedString(res.Object, "spec", "csi", "driver")
+			assert.Equal(t, tc.wantDriver, driver)
+		})
+	}
+}
+
+// TestRestoreCSIVolumeAttributeMappings runs restores of a CSI persistent volume with CSI
+// volume attribute mappings, and verifies that the mapped attributes of the volume's driver
+// are rewritten and the rest are unchanged.
+func TestRestoreCSIVolumeAttributeMappings(t *testing.T) {
+	withCSIAttributes := func(obj metav1.Object) {
+		obj.(*corev1api.PersistentVolume).Spec.CSI = &corev1api.CSIPersistentVolumeSource{
+			Driver:       "old.csi.example.com",
+			VolumeHandle: "handle-1",
+			VolumeAttributes: map[string]string{
+				"pool":   "gold",
+				"fsType": "ext4",
+			},
+		}
+	}
+
+	tests := []struct {
+		name           string
+		restore        *velerov1api.Restore
+		wantAttributes map[string]string
+	}{
+		{
+			name: "a mapped value of the driver's attribute is rewritten",
+			restore: defaultRestore().CSIVolumeAttributeMappings(velerov1api.CSIVolumeAttributeMapping{
+				Driver: "old.csi.example.com",
+				Key:    "pool",
+				Values: map[string]string{"gold": "fast-ssd"},
+			}).Restore(),
+			wantAttributes: map[string]string{"pool": "fast-ssd", "fsType": "ext4"},
+		},
+		{
+			name: "the driver's attribute is renamed, keeping unmapped values",
+			restore: defaultRestore().CSIVolumeAttributeMappings(velerov1api.CSIVolumeAttributeMapping{
+				Driver: "old.csi.example.com",
+				Key:    "pool",
+				NewKey: "storagePool",
+				Values: map[string]string{"silver": "standard"},
+			}).Restore(),
+			wantAttributes: map[string]string{"storagePool": "gold", "fsType": "ext4"},
+		},
+		{
+			name: "mappings are keyed by the backed-up driver when the driver is also remapped",
+			restore: defaultRestore().
+				CSIDriverMappings("old.csi.example.com", "new.csi.example.com").
+				CSIVolumeAttributeMappings(velerov1api.CSIVolumeAttributeMapping{
+					Driver: "old.csi.example.com",
+					Key:    "pool",
+					Values: map[string]string{"gold": "fast-ssd"},
+				}).Restore(),
+			wantAttributes: map[string]string{"pool": "fast-ssd", "fsType": "ext4"},
+		},
+		{
+			name: "another driver's mappings don't apply",
+			restore: defaultRestore().CSIVolumeAttributeMappings(velerov1api.CSIVolumeAttributeMapping{
+				Driver: "other.csi.example.com",
+				Key:    "pool",
+				Values: map[string]string{"gold": "fast-ssd"},
+			}).Restore(),
+			wantAttributes: map[string]string{"pool": "gold", "fsType": "ext4"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.PVs())
+
+			tarball := newTarWriter(t).
+				addItems("persistentvolumes", test.NewPV("pv-1", withCSIAttributes)).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, warnings, errs)
+
+			res, err := h.DynamicClient.Resource(test.PVs().GVR()).Get("pv-1", metav1.GetOptions{})
+			require.NoError(t, err)
+			attributes, _, _ := unstructured.NestedStringMap(res.Object, "spec", "csi", "volumeAttributes")
+			assert.Equal(t, tc.wantAttributes, attributes)
+		})
+	}
+}
+
+// TestRestoreAnnotateTransforms runs restores of a persistent volume claim that's reset for
+// dynamic provisioning and given a preferred storage class, and verifies that the claim is
+// annotated with both transforms only when the restore annotates transforms.
+func TestRestoreAnnotateTransforms(t *testing.T) {
+	tests := []struct {
+		name           string
+		restore        *velerov1api.Restore
+		wantAnnotation string
+	}{
+		{
+			name: "claim is annotated with the transforms applied to it",
+			restore: defaultRestore().
+				AnnotateTransforms(true).
+				StorageClassPreferences(velerov1api.StorageClassPreference{
+					StorageClassName: "rwo-class",
+					AccessModes:      []corev1api.PersistentVolumeAccessMode{corev1api.ReadWriteOnce},
+				}).
+				Restore(),
+			wantAnnotation: "pvc-volume-name-reset,storage-class-preferences",
+		},
+		{
+			name: "claim is not annotated when the restore doesn't annotate transforms",
+			restore: defaultRestore().
+				StorageClassPreferences(velerov1api.StorageClassPreference{
+					StorageClassName: "rwo-class",
+					AccessModes:      []corev1api.PersistentVolumeAccessMode{corev1api.ReadWriteOnce},
+				}).
+				Restore(),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.restorer.resourcePriorities = []string{"persistentvolumes", "persistentvolumeclaims"}
+			h.addItems(t, test.PVs())
+			h.addItems(t, test.PVCs())
+
+			tarball := newTarWriter(t).
+				addItems("persistentvolumes", test.NewPV("pv-1", func(obj metav1.Object) {
+					obj.(*corev1api.PersistentVolume).Spec.PersistentVolumeReclaimPolicy = corev1api.PersistentVolumeReclaimDelete
+				})).
+				addItems("persistentvolumeclaims", test.NewPVC("ns-1", "pvc-1", func(obj metav1.Object) {
+					pvc := obj.(*corev1api.PersistentVolumeClaim)
+					pvc.Spec.VolumeName = "pv-1"
+					pvc.Spec.AccessModes = []corev1api.PersistentVolumeAccessMode{corev1api.ReadWriteOnce}
+				})).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, warnings, errs)
+
+			res, err := h.DynamicClient.Resource(test.PVCs().GVR()).Namespace("ns-1").Get("pvc-1", metav1.GetOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantAnnotation, res.GetAnnotations()[velerov1api.AppliedTransformsAnnotation])
+
+			storageClassName, _, _ := unstructured.NestedString(res.Object, "spec", "storageClassName")
+			assert.Equal(t, "rwo-class", storageClassName)
+		})
+	}
+}
+
+// TestRestoreClusterScopedOnly runs cluster-scoped only restores of a backup containing
+// cluster-scoped and namespaced items, and verifies that only the cluster-scoped items are
+// restored and no namespaces are created, whether the restore is cluster-scoped only or
+// its scope filter is ClusterOnly.
+func TestRestoreClusterScopedOnly(t *testing.T) {
+	tests := []struct {
+		name    string
+		restore *velerov1api.Restore
+	}{
+		{
+			name:    "cluster-scoped only restore",
+			restore: defaultRestore().ClusterScopedOnly(true).Restore(),
+		},
+		{
+			name:    "ClusterOnly scope filter",
+			restore: defaultRestore().ScopeFilter(velerov1api.ScopeFilterClusterOnly).Restore(),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.PVs())
+			h.addItems(t, test.ClusterRoles())
+			h.addItems(t, test.Pods())
+			h.addItems(t, test.Deployments())
+
+			tarball := newTarWriter(t).
+				addItems("persistentvolumes", test.NewPV("pv-1")).
+				addItems("clusterroles.rbac.authorization.k8s.io", test.NewClusterRole("role-1")).
+				addItems("pods", test.NewPod("ns-1", "pod-1")).
+				addItems("deployments.apps", test.NewDeployment("ns-2", "deploy-1")).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, warnings, errs)
+			assertAPIContents(t, h, map[*test.APIResource][]string{
+				test.PVs():          {"/pv-1"},
+				test.ClusterRoles(): {"/role-1"},
+				test.Pods():         {},
+				test.Deployments():  {},
+			})
+
+			namespaces, err := h.KubeClient.CoreV1().Namespaces().List(metav1.ListOptions{})
+			require.NoError(t, err)
+			assert.Empty(t, namespaces.Items)
+		})
+	}
+}
+
+// TestRestoreScopeFilter runs restores of a backup containing cluster-scoped and namespaced
+// items with each scope filter, and verifies that only items of resources that discovery
+// reports as being in the filter's scope are restored.
+func TestRestoreScopeFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter velerov1api.ScopeFilter
+		want   map[*test.APIResource][]string
+	}{
+		{
+			name:   "ClusterOnly restores only cluster-scoped resources",
+			filter: velerov1api.ScopeFilterClusterOnly,
+			want: map[*test.APIResource][]string{
+				test.PVs():          {"/pv-1"},
+				test.ClusterRoles(): {"/role-1"},
+				test.Pods():         {},
+				test.Deployments():  {},
+			},
+		},
+		{
+			name:   "NamespacedOnly restores only namespaced resources",
+			filter: velerov1api.ScopeFilterNamespacedOnly,
+			want: map[*test.APIResource][]string{
+				test.PVs():          {},
+				test.ClusterRoles(): {},
+				test.Pods():         {"ns-1/pod-1"},
+				test.Deployments():  {"ns-2/deploy-1"},
+			},
+		},
+		{
+			name:   "Both restores all resources",
+			filter: velerov1api.ScopeFilterBoth,
+			want: map[*test.APIResource][]string{
+				test.PVs():          {"/pv-1"},
+				test.ClusterRoles(): {"/role-1"},
+				test.Pods():         {"ns-1/pod-1"},
+				test.Deployments():  {"ns-2/deploy-1"},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.PVs())
+			h.addItems(t, test.ClusterRoles())
+			h.addItems(t, test.Pods())
+			h.addItems(t, test.Deployments())
+
+			tarball := newTarWriter(t).
+				addItems("persistentvolumes", test.NewPV("pv-1")).
+				addItems("clusterroles.rbac.authorization.k8s.io", test.NewClusterRole("role-1")).
+				addItems("pods", test.NewPod("ns-1", "pod-1")).
+				addItems("deployments.apps", test.NewDeployment("ns-2", "deploy-1")).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				defaultRestore().ScopeFilter(tc.filter).Restore(),
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, warnings, errs)
+			assertAPIContents(t, h, tc.want)
+		})
+	}
+}
+
+// concurrencyTrackingFactory is a dynamic factory whose clients record the maximum
+// number of concurrent creates of each resource, and the maximum number of resources
+// with creates in flight at once. Creates are slowed down so that concurrent ones overlap.
+type concurrencyTrackingFactory struct {
+	client.DynamicFactory
+
+	lock         sync.Mutex
+	inFlight     map[string]int
+	max          map[string]int
+	maxResources int
+}
+
+func (f *concurrencyTrackingFactory) ClientForGroupVersionResource(gv schema.GroupVersion, resource metav1.APIResource, namespace string) (client.Dynamic, error) {
+	c, err := f.DynamicFactory.ClientForGroupVersionResource(gv, resource, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return &concurrencyTrackingClient{Dynamic: c, factory: f, resource: resource.Name}, nil
+}
+
+type concurrencyTrackingClient struct {
+	client.Dynamic
+
+	factory  *concurrencyTrackingFactory
+	resource string
+}
+
+func (c *concurrencyTrackingClient) Create(obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	f := c.factory
+
+	f.lock.Lock()
+	f.inFlight[c.resource]++
+	if f.inFlight[c.resource] > f.max[c.resource] {
+		f.max[c.resource] = f.inFlight[c.resource]
+	}
+	var resources int
+	for _, n := range f.inFlight {
+		if n > 0 {
+			resources++
+		}
+	}
+	if resources > f.maxResources {
+		f.maxResources = resources
+	}
+	f.lock.Unlock()
+
+	time.Sleep(50 * time.Millisecond)
+
+	f.lock.Lock()
+	f.inFlight[c.resource]--
+	f.lock.Unlock()
+
+	return c.Dynamic.Create(obj)
+}
+
+// TestRestoreResourceParallelism runs a restore with parallelism configured overall and
+// overridden for CRDs, and verifies that CRDs are created one at a time while config maps
+// are created concurrently.
+func TestRestoreResourceParallelism(t *testing.T) {
+	h := newHarness(t)
+	h.addItems(t, test.CRDs())
+	h.addItems(t, test.ConfigMaps())
+
+	factory := &concurrencyTrackingFactory{
+		DynamicFactory: h.restorer.dynamicFactory,
+		inFlight:       make(map[string]int),
+		max:            make(map[string]int),
+	}
+	h.restorer.dynamicFactory = factory
+
+	tarball := newTarWriter(t).
+		addItems("customresourcedefinitions.apiextensions.k8s.io",
+			test.NewCRD("crd-1"),
+			test.NewCRD("crd-2"),
+			test.NewCRD("crd-3"),
+		).
+		addItems("configmaps",
+			test.NewConfigMap("ns-1", "cm-1"),
+			test.NewConfigMap("ns-1", "cm-2"),
+			test.NewConfigMap("ns-1", "cm-3"),
+			test.NewConfigMap("ns-1", "cm-4"),
+		).
+		done()
+
+	warnings, errs, _ := h.restorer.Restore(
+		h.log,
+		defaultRestore().Parallelism(4).ResourceParallelism("customresourcedefinitions.apiextensions.k8s.io", 1).Restore(),
+		defaultBackup().Backup(),
+		nil, // volume snapshots
+		tarball,
+		nil, // actions
+		nil, // snapshot location lister
+		nil, // volume snapshotter getter
+	)
+
+	assertEmptyResults(t, warnings, errs)
+	assertAPIContents(t, h, map[*test.APIResource][]string{
+		test.CRDs():       {"/crd-1", "/crd-2", "/crd-3"},
+		test.ConfigMaps(): {"ns-1/cm-1", "ns-1/cm-2", "ns-1/cm-3", "ns-1/cm-4"},
+	})
+
+	assert.Equal(t, 1, factory.max["customresourcedefinitions"])
+	assert.True(t, factory.max["configmaps"] > 1, "expected config maps to be created concurrently")
+}
+
+// TestRestoreIndependentResourceParallelism runs restores of many config maps, secrets
+// and service accounts, none of which are in the resource priorities, and verifies that
+// all of them are created, with their warnings and errors merged into the results, and
+// that the resources are only restored concurrently with independent resource parallelism.
+func TestRestoreIndependentResourceParallelism(t *testing.T) {
+	tests := []struct {
+		name             string
+		parallelism      int
+		wantConcurrently bool
+	}{
+		{
+			name:        "resources are restored one at a time without independent resource parallelism",
+			parallelism: 0,
+		},
+		{
+			name:             "resources are restored concurrently with independent resource parallelism",
+			parallelism:      3,
+			wantConcurrently: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.ConfigMaps(test.NewConfigMap("ns-2", "cm-1", func(obj metav1.Object) {
+				obj.(*corev1api.ConfigMap).Data = map[string]string{"key": "in-cluster"}
+			})))
+			h.addItems(t, test.Secrets())
+			h.addItems(t, test.ServiceAccounts())
+
+			factory := &concurrencyTrackingFactory{
+				DynamicFactory: h.restorer.dynamicFactory,
+				inFlight:       make(map[string]int),
+				max:            make(map[string]int),
+			}
+			h.restorer.dynamicFactory = factory
+
+			h.DynamicClient.PrependReactor("create", "secrets", func(action kubetesting.Action) (bool, runtime.Object, error) {
+				accessor, err := meta.Accessor(action.(kubetesting.CreateAction).GetObject())
+				require.NoError(t, err)
+				if accessor.GetName() != "invalid" {
+					return false, nil, nil
+				}
+				return true, nil, apierrors.NewInvalid(schema.GroupKind{Kind: "Secret"}, accessor.GetName(), field.ErrorList{
+					field.Required(field.NewPath("type"), ""),
+				})
+			})
+
+			var configMaps, secrets []metav1.Object
+			for i := 1; i <= 6; i++ {
+				namespace := fmt.Sprintf("ns-%d", i%2+1)
+				configMaps = append(configMaps, test.NewConfigMap(namespace, fmt.Sprintf("cm-%d", i), func(obj metav1.Object) {
+					obj.(*corev1api.ConfigMap).Data = map[string]string{"key": "backup"}
+				}))
+				secrets = append(secrets, test.NewSecret(namespace, fmt.Sprintf("secret-%d", i)))
+			}
+			secrets = append(secrets, test.NewSecret("ns-2", "invalid"))
+
+			tarball := newTarWriter(t).
+				addItems("configmaps", configMaps...).
+				addItems("secrets", secrets...).
+				addItems("serviceaccounts", test.NewServiceAccount("ns-1", "sa-1"), test.NewServiceAccount("ns-2", "sa-2")).
+				done()
+
+			warnings, errs, results := h.restorer.Restore(
+				h.log,
+				defaultRestore().IndependentResourceParallelism(tc.parallelism).Parallelism(2).Restore(),
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			// the in-cluster config map differs from the backed-up one, and the
+			// invalid secret is rejected
+			assert.Len(t, warnings.Namespaces["ns-2"], 1)
+			assert.Len(t, errs.Namespaces["ns-2"], 1)
+			assert.Empty(t, warnings.Namespaces["ns-1"])
+			assert.Empty(t, errs.Namespaces["ns-1"])
+			assert.Len(t, results, 15)
+
+			assertAPIContents(t, h, map[*test.APIResource][]string{
+				test.ConfigMaps():      {"ns-1/cm-2", "ns-1/cm-4", "ns-1/cm-6", "ns-2/cm-1", "ns-2/cm-3", "ns-2/cm-5"},
+				test.Secrets():         {"ns-1/secret-2", "ns-1/secret-4", "ns-1/secret-6", "ns-2/secret-1", "ns-2/secret-3", "ns-2/secret-5"},
+				test.ServiceAccounts(): {"ns-1/sa-1", "ns-2/sa-2"},
+			})
+
+			if tc.wantConcurrently {
+				assert.True(t, factory.maxResources > 1, "expected resources to be restored concurrently")
+			} else {
+				assert.Equal(t, 1, factory.maxResources)
+			}
+		})
+	}
+}
+
+// blockingFactory is a dynamic factory whose clients block creates of items in a
+// namespace, and gets too if blockGets is set, until released. Like the restorer's
+// clients, a blocked request fails once its timeout passes, if it has one.
+type blockingFactory struct {
+	client.DynamicFactory
+
+	namespace string
+	blockGets bool
+	timeout   time.Duration
+	release   chan struct{}
+}
+
+func (f *blockingFactory) ClientForGroupVersionResource(gv schema.GroupVersion, resource metav1.APIResource, namespace string) (client.Dynamic, error) {
+	c, err := f.DynamicFactory.ClientForGroupVersionResource(gv, resource, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return &blockingClient{Dynamic: c, factory: f, namespace: namespace}, nil
+}
+
+type blockingClient struct {
+	client.Dynamic
+
+	factory   *blockingFactory
+	namespace string
+}
+
+func (c *blockingClient) Get(name string, opts metav1.GetOptions) (*unstructured.Unstructured, error) {
+	if c.factory.blockGets && c.namespace == c.factory.namespace {
+		if err := c.block("GET", name); err != nil {
+			return nil, err
+		}
+	}
+	return c.Dynamic.Get(name, opts)
+}
+
+func (c *blockingClient) Create(obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	if obj.GetNamespace() == c.factory.namespace {
+		if err := c.block("POST", obj.GetName()); err != nil {
+			return nil, err
+		}
+	}
+	return c.Dynamic.Create(obj)
+}
+
+// block waits until the client's factory is released, returning the error of a request
+// that timed out if the factory's timeout passes first.
+func (c *blockingClient) block(method, name string) error {
+	var timeout <-chan time.Time
+	if c.factory.timeout > 0 {
+		timeout = time.After(c.factory.timeout)
+	}
+
+	select {
+	case <-c.factory.release:
+		return nil
+	case <-timeout:
+		return &url.Error{Op: method, URL: name, Err: go_context.DeadlineExceeded}
+	}
+}
+
+// TestRestoreNamespaceTimeout runs restores of config maps and secrets in three namespaces,
+// where creates in one namespace hang, and verifies that the hanging namespace times out,
+// with its remaining items skipped, while the other namespaces' items are all restored.
+// Each namespace's deadline starts with its first restored item and lasts for the rest of
+// the restore, so the secrets get a namespace of their own that isn't held up waiting for
+// the config maps.
+func TestRestoreNamespaceTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		parallelism int
+	}{
+		{
+			name:        "a namespace that times out doesn't stall namespaces restored after it",
+			parallelism: 0,
+		},
+		{
+			name:        "a namespace that times out doesn't stall namespaces restored concurrently",
+			parallelism: 2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.ConfigMaps())
+			h.addItems(t, test.Secrets())
+
+			factory := &blockingFactory{
+				DynamicFactory: h.restorer.dynamicFactory,
+				namespace:      "ns-1",
+				timeout:        150 * time.Millisecond,
+				release:        make(chan struct{}),
+			}
+			defer close(factory.release)
+			h.restorer.dynamicFactory = factory
+			h.restorer.itemOperationTimeout = 150 * time.Millisecond
+
+			tarball := newTarWriter(t).
+				addItems("configmaps",
+					test.NewConfigMap("ns-1", "cm-1"),
+					test.NewConfigMap("ns-1", "cm-2"),
+					test.NewConfigMap("ns-2", "cm-1"),
+					test.NewConfigMap("ns-2", "cm-2"),
+				).
+				addItems("secrets",
+					test.NewSecret("ns-1", "secret-1"),
+					test.NewSecret("ns-3", "secret-1"),
+				).
+				done()
+
+			warnings, errs, results := h.restorer.Restore(
+				h.log,
+				defaultRestore().NamespaceParallelism(tc.parallelism).NamespaceTimeout(100*time.Millisecond).Restore(),
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			// the hanging config map's create times out after the
+			// namespace does
+			assertEmptyResults(t, warnings)
+			assert.Len(t, errs.Namespaces["ns-1"], 2)
+			assert.Empty(t, errs.Namespaces["ns-2"])
+			assert.Empty(t, errs.Namespaces["ns-3"])
+
+			assertAPIContents(t, h, map[*test.APIResource][]string{
+				test.ConfigMaps(): {"ns-2/cm-1", "ns-2/cm-2"},
+				test.Secrets():    {"ns-3/secret-1"},
+			})
+
+			var skipped []string
+			for _, res := range results {
+				if res.Outcome == ItemOutcomeSkipped {
+					assert.Equal(t, namespaceTimedOutReason, res.Reason)
+					skipped = append(skipped, res.Namespace+"/"+res.Name)
+				}
+			}
+			assert.ElementsMatch(t, []string{"ns-1/cm-2", "ns-1/secret-1"}, skipped)
+		})
+	}
+}
+
+// TestWithNamespaceDeadline verifies that the item operations of a client for a namespace
+// whose deadline has passed fail without being attempted, and that clients for namespaces
+// without a deadline are left as they are.
+func TestWithNamespaceDeadline(t *testing.T) {
+	// the fake client fails the test on any call
+	resourceClient := &testutil.FakeDynamicClient{}
+
+	ctx := &context{
+		namespaceDeadlines: map[string]time.Time{
+			"ns-1": time.Now().Add(-time.Second),
+		},
+	}
+
+	assert.Equal(t, resourceClient, ctx.withNamespaceDeadline(resourceClient, "ns-2"))
+
+	deadlineClient := ctx.withNamespaceDeadline(resourceClient, "ns-1")
+
+	_, err := deadlineClient.Get("cm-1", metav1.GetOptions{})
+	assert.True(t, isNamespaceTimeout(err))
+
+	_, err = deadlineClient.Create(testutil.UnstructuredOrDie(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"namespace":"ns-1","name":"cm-1"}}`))
+	assert.True(t, isNamespaceTimeout(err))
+
+	_, err = deadlineClient.Patch("cm-1", []byte(`{}`))
+	assert.True(t, isNamespaceTimeout(err))
+
+	_, err = deadlineClient.Apply(testutil.UnstructuredOrDie(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"namespace":"ns-1","name":"cm-1"}}`), applyFieldManager, false)
+	assert.True(t, isNamespaceTimeout(err))
+}
+
+// TestSplitIndependentResources verifies that the resources named in the resource priorities,
+// those before them, and the serial resources are restored in order, while the rest are
+// independent.
+func TestSplitIndependentResources(t *testing.T) {
+	h := newHarness(t)
+	for _, resource := range []*test.APIResource{test.Pods(), test.ConfigMaps(), test.Secrets(), test.CRDs()} {
+		h.DiscoveryClient.WithAPIResource(resource)
+	}
+	require.NoError(t, h.restorer.discoveryHelper.Refresh())
+
+	ctx := &context{
+		discoveryHelper:    h.restorer.discoveryHelper,
+		resourcePriorities: []string{"pods", "configmaps"},
+		prioritizedResources: []schema.GroupResource{
+			kuberesource.Pods,
+			{Resource: "services"},
+			kuberesource.ConfigMaps,
+			{Resource: "endpoints"},
+			kuberesource.Secrets,
+			kuberesource.CustomResourceDefinitions,
+		},
+	}
+
+	serial, independent := ctx.splitIndependentResources()
+	assert.Equal(t, []schema.GroupResource{
+		kuberesource.Pods,
+		{Resource: "services"},
+		kuberesource.ConfigMaps,
+		kuberesource.CustomResourceDefinitions,
+	}, serial)
+	assert.Equal(t, []schema.GroupResource{{Resource: "endpoints"}, kuberesource.Secrets}, independent)
+}
+
+// concurrencyTrackingResticRestorer is a restic restorer factory and restorer that
+// tracks the most pod volume restores it has had running at once.
+type concurrencyTrackingResticRestorer struct {
+	lock     sync.Mutex
+	inFlight int
+	max      int
+	pods     []string
+}
+
+func (r *concurrencyTrackingResticRestorer) NewRestorer(go_context.Context, *velerov1api.Restore) (restic.Restorer, error) {
+	return r, nil
+}
+
+func (r *concurrencyTrackingResticRestorer) RestorePodVolumes(restore *velerov1api.Restore, pod *corev1api.Pod, sourceNamespace, backupLocation string, log logrus.FieldLogger) []error {
+	r.lock.Lock()
+	r.inFlight++
+	if r.inFlight > r.max {
+		r.max = r.inFlight
+	}
+	r.pods = append(r.pods, pod.Name)
+	r.lock.Unlock()
+
+	time.Sleep(50 * time.Millisecond)
+
+	r.lock.Lock()
+	r.inFlight--
+	r.lock.Unlock()
+
+	return nil
+}
+
+// TestRestoreVolumeRestoreParallelism runs a restore of pods with restic volume snapshots,
+// and verifies that no more pod volume restores than the restore's volume restore
+// parallelism run at once.
+func TestRestoreVolumeRestoreParallelism(t *testing.T) {
+	h := newHarness(t)
+	h.addItems(t, test.Pods())
+
+	resticRestorer := new(concurrencyTrackingResticRestorer)
+	h.restorer.resticRestorerFactory = resticRestorer
+
+	var pods []metav1.Object
+	for i := 1; i <= 6; i++ {
+		pods = append(pods, test.NewPod("ns-1", fmt.Sprintf("pod-%d", i), test.WithAnnotations("snapshot.velero.io/volume-1", "snapshot-1")))
+	}
+
+	warnings, errs, _ := h.restorer.Restore(
+		h.log,
+		defaultRestore().Parallelism(6).VolumeRestoreParallelism(2).Restore(),
+		defaultBackup().Backup(),
+		nil, // volume snapshots
+		newTarWriter(t).addItems("pods", pods...).done(),
+		nil, // actions
+		nil, // snapshot location lister
+		nil, // volume snapshotter getter
+	)
+
+	assertEmptyResults(t, warnings, errs)
+	assert.Len(t, resticRestorer.pods, 6)
+	assert.Equal(t, 2, resticRestorer.max)
+}
+
+// TestRestoreDryRun runs a dry-run restore, and verifies that nothing is created in the
+// cluster and no volumes are created from snapshots, while the item results record what
+// the restore would have done without counting any item as restored.
+func TestRestoreDryRun(t *testing.T) {
+	h := newHarness(t)
+	h.addItems(t, test.Pods(test.NewPod("ns-1", "pod-1")))
+	h.addItems(t, test.PVs())
+	h.DynamicClient.ClearActions()
+	h.KubeClient.ClearActions()
+
+	registry := prometheus.NewRegistry()
+	var err error
+	h.restorer.metrics, err = newRestoreMetrics(registry)
+	require.NoError(t, err)
+
+	updater := new(recordingProgressUpdater)
+	h.restorer.progressUpdater = updater
+
+	tracer := new(recordingTracer)
+	h.restorer.tracer = tracer
+
+	locationsInformer := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0).Velero().V1().VolumeSnapshotLocations()
+	require.NoError(t, locationsInformer.Informer().GetStore().Add(
+		testutil.NewTestVolumeSnapshotLocation().WithName("loc-1").WithProvider("provider-1").VolumeSnapshotLocation,
+	))
+
+	tarball := newTarWriter(t).
+		addItems("pods",
+			test.NewPod("ns-1", "pod-1"),
+			test.NewPod("ns-2", "pod-2"),
+		).
+		addItems("persistentvolumes",
+			test.NewPV("pv-1"),
+			test.NewPV("pv-2"),
+		).
+		done()
+
+	warnings, errs, itemResults := h.restorer.Restore(
+		h.log,
+		defaultRestore().DryRun(true).Restore(),
+		defaultBackup().Backup(),
+		[]*volume.Snapshot{newSnapshot("pv-1", "loc-1", "type-1", "az-1", "snap-1", 1)},
+		tarball,
+		nil, // actions
+		locationsInformer.Lister(),
+		// no volume snapshotters are available, so using one is an error
+		providerToVolumeSnapshotterMap{},
+	)
+
+	assertEmptyResults(t, warnings, errs)
+
+	for _, action := range h.DynamicClient.Actions() {
+		assert.NotContains(t, []string{"create", "patch", "update", "delete"}, action.GetVerb(), "unexpected %s of %s", action.GetVerb(), action.GetResource())
+	}
+	for _, action := range h.KubeClient.Actions() {
+		assert.NotContains(t, []string{"create", "patch", "update", "delete"}, action.GetVerb(), "unexpected %s of %s", action.GetVerb(), action.GetResource())
+	}
+	assertAPIContents(t, h, map[*test.APIResource][]string{
+		test.Pods(): {"ns-1/pod-1"},
+		test.PVs():  {},
+	})
+
+	assert.ElementsMatch(t, ItemResults{
+		{GroupResource: "pods", Namespace: "ns-1", Name: "pod-1", Outcome: ItemOutcomeSkipped, Action: ItemActionSkippedExists, Reason: dryRunExistsReason},
+		{GroupResource: "pods", Namespace: "ns-2", Name: "pod-2", Outcome: ItemOutcomeDryRun, Action: ItemActionDryRun, Reason: dryRunCreateReason},
+		{GroupResource: "persistentvolumes", Name: "pv-1", Outcome: ItemOutcomeDryRun, Action: ItemActionDryRun, Reason: dryRunProvisionReason},
+		{GroupResource: "persistentvolumes", Name: "pv-2", Outcome: ItemOutcomeDryRun, Action: ItemActionDryRun, Reason: dryRunCreateReason},
+	}, itemResults)
+
+	assert.Empty(t, gatherMetrics(t, registry)["velero_restore_items_restored_total"].GetMetric())
+
+	require.NotEmpty(t, updater.updates)
+	assert.Equal(t, 0, updater.updates[len(updater.updates)-1].ItemsRestored)
+	assert.Equal(t, 1, updater.updates[len(updater.updates)-1].ItemsSkipped)
+
+	for _, span := range tracer.spans {
+		if span.name == RestoreSpanName {
+			assert.Equal(t, 0, span.attributes["items.created"])
+			assert.Equal(t, 3, span.attributes["items.dryRun"])
+		}
+	}
+}
+
+// TestRestoreAnnotatesNamespaces runs a restore into a new namespace and an existing one,
+// and verifies that both are annotated with the restore's name, with the existing
+// namespace's other annotations kept.
+func TestRestoreAnnotatesNamespaces(t *testing.T) {
+	h := newHarness(t)
+	h.addItems(t, test.Pods())
+
+	_, err := h.KubeClient.CoreV1().Namespaces().Create(test.NewNamespace("ns-1",
+		test.WithAnnotations("foo", "bar", velerov1api.LastRestoreAnnotation, "restore-0"),
+	))
+	require.NoError(t, err)
+
+	tarball := newTarWriter(t).
+		addItems("pods",
+			test.NewPod("ns-1", "pod-1"),
+			test.NewPod("ns-2", "pod-2"),
+		).
+		done()
+
+	warnings, errs, _ := h.restorer.Restore(
+		h.log,
+		defaultRestore().Restore(),
+		defaultBackup().Backup(),
+		nil, // volume snapshots
+		tarball,
+		nil, // actions
+		nil, // snapshot location lister
+		nil, // volume snapshotter getter
+	)
+
+	assertEmptyResults(t, warnings, errs)
+
+	ns1, err := h.KubeClient.CoreV1().Namespaces().Get("ns-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"foo": "bar", velerov1api.LastRestoreAnnotation: "restore-1"}, ns1.Annotations)
+
+	ns2, err := h.KubeClient.CoreV1().Namespaces().Get("ns-2", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{velerov1api.LastRestoreAnnotation: "restore-1"}, ns2.Annotations)
+}
+
+// TestRestoreNamespaceCreationPolicy runs restores into an existing namespace and one that
+// doesn't exist with each namespace creation policy, and verifies that only the create
+// policy creates or annotates namespaces, and that the require existing policy fails the
+// items of the namespace that doesn't exist.
+func TestRestoreNamespaceCreationPolicy(t *testing.T) {
+	missingErr := "namespace ns-2 doesn't exist and the restore's namespace creation policy is RequireExisting"
+
+	tests := []struct {
+		name                    string
+		policy                  velerov1api.NamespaceCreationPolicy
+		wantErrs                Result
+		wantNamespaces          []string
+		wantPods                []string
+		wantExistingAnnotations map[string]string
+		wantFailedItemNames     []string
+	}{
+		{
+			name:                    "namespaces that don't exist are created by default",
+			wantNamespaces:          []string{"ns-1", "ns-2"},
+			wantPods:                []string{"ns-1/pod-1", "ns-2/pod-2"},
+			wantExistingAnnotations: map[string]string{"foo": "bar", velerov1api.LastRestoreAnnotation: "restore-1"},
+		},
+		{
+			name:                    "items of namespaces that don't exist fail when namespaces are required to exist",
+			policy:                  velerov1api.NamespaceCreationPolicyRequireExisting,
+			wantErrs:                Result{Namespaces: map[string][]string{"ns-2": {missingErr}}},
+			wantNamespaces:          []string{"ns-1"},
+			wantPods:                []string{"ns-1/pod-1"},
+			wantExistingAnnotations: map[string]string{"foo": "bar"},
+			wantFailedItemNames:     []string{"pod-2"},
+		},
+		{
+			name:                    "items are restored into namespaces that aren't created when creation is skipped",
+			policy:                  velerov1api.NamespaceCreationPolicySkipCreate,
+			wantNamespaces:          []string{"ns-1"},
+			wantPods:                []string{"ns-1/pod-1", "ns-2/pod-2"},
+			wantExistingAnnotations: map[string]string{"foo": "bar"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.Pods())
+
+			_, err := h.KubeClient.CoreV1().Namespaces().Create(test.NewNamespace("ns-1", test.WithAnnotations("foo", "bar")))
+			require.NoError(t, err)
+
+			tarball := newTarWriter(t).
+				addItems("pods",
+					test.NewPod("ns-1", "pod-1"),
+					test.NewPod("ns-2", "pod-2"),
+				).
+				done()
+
+			warnings, errs, itemResults := h.restorer.Restore(
+				h.log,
+				defaultRestore().NamespaceCreationPolicy(tc.policy).Restore(),
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, warnings)
+			assert.Equal(t, tc.wantErrs, errs)
+
+			namespaces, err := h.KubeClient.CoreV1().Namespaces().List(metav1.ListOptions{})
+			require.NoError(t, err)
+			var namespaceNames []string
+			for _, ns := range namespaces.Items {
+				namespaceNames = append(namespaceNames, ns.Name)
+			}
+			assert.ElementsMatch(t, tc.wantNamespaces, namespaceNames)
+
+			ns1, err := h.KubeClient.CoreV1().Namespaces().Get("ns-1", metav1.GetOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantExistingAnnotations, ns1.Annotations)
+
+			pods, err := h.DynamicClient.Resource(test.Pods().GVR()).List(metav1.ListOptions{})
+			require.NoError(t, err)
+			var podNames []string
+			for _, pod := range pods.Items {
+				podNames = append(podNames, pod.GetNamespace()+"/"+pod.GetName())
+			}
+			assert.ElementsMatch(t, tc.wantPods, podNames)
+
+			var failed []string
+			for _, res := range itemResults {
+				if res.Outcome == ItemOutcomeFailed {
+					failed = append(failed, res.Name)
+				}
+			}
+			assert.Equal(t, tc.wantFailedItemNames, failed)
+		})
+	}
+}
+
+// TestRestoreNamespaceMetadata runs restores with namespace labels and annotations into a
+// new namespace and an existing one, and verifies that the new namespace is created with
+// them, and that the existing namespace only gets them under the update policy.
+func TestRestoreNamespaceMetadata(t *testing.T) {
+	tests := []struct {
+		name                    string
+		policy                  velerov1api.ExistingResourcePolicy
+		wantExistingLabels      map[string]string
+		wantExistingAnnotations map[string]string
+	}{
+		{
+			name:                    "an existing namespace keeps its labels without the update policy",
+			wantExistingLabels:      map[string]string{"team": "a"},
+			wantExistingAnnotations: map[string]string{velerov1api.LastRestoreAnnotation: "restore-1"},
+		},
+		{
+			name:                    "an existing namespace gets the labels and annotations under the update policy",
+			policy:                  velerov1api.ExistingResourcePolicyUpdate,
+			wantExistingLabels:      map[string]string{"team": "a", "istio-injection": "enabled", podSecurityEnforceLabel: "baseline"},
+			wantExistingAnnotations: map[string]string{velerov1api.LastRestoreAnnotation: "restore-1", "owner": "platform"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.Pods())
+
+			_, err := h.KubeClient.CoreV1().Namespaces().Create(test.NewNamespace("ns-1", test.WithLabels("team", "a")))
+			require.NoError(t, err)
+
+			tarball := newTarWriter(t).
+				addItems("pods",
+					test.NewPod("ns-1", "pod-1"),
+					test.NewPod("ns-2", "pod-2"),
+				).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				defaultRestore().
+					ExistingResourcePolicy(tc.policy).
+					NamespaceLabels("istio-injection", "enabled", podSecurityEnforceLabel, "baseline").
+					NamespaceAnnotations("owner", "platform").
+					Restore(),
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, warnings, errs)
+
+			ns2, err := h.KubeClient.CoreV1().Namespaces().Get("ns-2", metav1.GetOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, map[string]string{"istio-injection": "enabled", podSecurityEnforceLabel: "baseline"}, ns2.Labels)
+			assert.Equal(t, map[string]string{velerov1api.LastRestoreAnnotation: "restore-1", "owner": "platform"}, ns2.Annotations)
+
+			ns1, err := h.KubeClient.CoreV1().Namespaces().Get("ns-1", metav1.GetOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantExistingLabels, ns1.Labels)
+			assert.Equal(t, tc.wantExistingAnnotations, ns1.Annotations)
+		})
+	}
+}
+
+// TestRestoreReportUnresolvedReferences runs a restore of a deployment and persistent
+// volume claims that reference secrets, config maps and storage classes, and verifies
+// that only the references to items that neither were restored nor exist in the cluster
+// are reported, grouped by referenced type and referencing item.
+func TestRestoreReportUnresolvedReferences(t *testing.T) {
+	deployment := test.NewDeployment("ns-1", "web", func(obj metav1.Object) {
+		obj.(*appsv1api.Deployment).Spec.Template.Spec.Containers = []corev1api.Container{
+			{
+				Name: "web",
+				EnvFrom: []corev1api.EnvFromSource{
+					{ConfigMapRef: &corev1api.ConfigMapEnvSource{LocalObjectReference: corev1api.LocalObjectReference{Name: "settings"}}},
+					{SecretRef: &corev1api.SecretEnvSource{LocalObjectReference: corev1api.LocalObjectReference{Name: "db-creds"}}},
+				},
+			},
+		}
+		obj.(*appsv1api.Deployment).Spec.Template.Spec.Volumes = []corev1api.Volume{
+			{Name: "creds", VolumeSource: corev1api.VolumeSource{Secret: &corev1api.SecretVolumeSource{SecretName: "db-creds"}}},
+		}
+	})
+	pvc := func(name, storageClass string) *corev1api.PersistentVolumeClaim {
+		return test.NewPVC("ns-1", name, func(obj metav1.Object) {
+			obj.(*corev1api.PersistentVolumeClaim).Spec.StorageClassName = &storageClass
+		})
+	}
+
+	tests := []struct {
+		name         string
+		restore      *velerov1api.Restore
+		wantReport   UnresolvedReferences
+		wantWarnings []string
+	}{
+		{
+			name:    "missing secrets and storage classes are reported",
+			restore: defaultRestore().ReportUnresolvedReferences(true).Restore(),
+			wantReport: UnresolvedReferences{
+				"secrets":                       {"deployments.apps/ns-1/web": {"ns-1/db-creds"}},
+				"storageclasses.storage.k8s.io": {"persistentvolumeclaims/ns-1/data": {"fast"}},
+			},
+			wantWarnings: []string{"2 restored items have unresolved references, which are listed in the restore's unresolved references report"},
+		},
+		{
+			name:    "references are not reported when the restore doesn't report them",
+			restore: defaultRestore().Restore(),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.Deployments())
+			h.addItems(t, test.ConfigMaps())
+			h.addItems(t, test.Secrets())
+			h.addItems(t, test.PVCs())
+			h.addItems(t, test.StorageClasses(test.NewStorageClass("standard")))
+
+			writer := new(fakeUnresolvedReferencesWriter)
+			h.restorer.unresolvedReferencesWriter = writer
+
+			tarball := newTarWriter(t).
+				addItems("deployments.apps", deployment).
+				addItems("configmaps", test.NewConfigMap("ns-1", "settings")).
+				addItems("persistentvolumeclaims", pvc("data", "fast"), pvc("logs", "standard")).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, errs)
+			assert.Empty(t, warnings.Namespaces)
+			assert.Equal(t, tc.wantWarnings, warnings.Velero)
+			assert.Equal(t, tc.wantReport, writer.report)
+		})
+	}
+}
+
+// fakeUnresolvedReferencesWriter keeps the last unresolved references report it's given.
+type fakeUnresolvedReferencesWriter struct {
+	report UnresolvedReferences
+}
+
+func (w *fakeUnresolvedReferencesWriter) PutUnresolvedReferences(_ *velerov1api.Restore, report UnresolvedReferences) error {
+	w.report = report
+	return nil
+}
+
+// TestRestoreVerifyAfterRestore runs restores of pods, one of which the cluster reports
+// as missing once it's been created, and verifies that it's recorded as a verification
+// failure only when the restore verifies its items.
+func TestRestoreVerifyAfterRestore(t *testing.T) {
+	tests := []struct {
+		name         string
+		restore      *velerov1api.Restore
+		wantFailures []string
+	}{
+		{
+			name:         "a created item that's missing is a verification failure",
+			restore:      defaultRestore().VerifyAfterRestore(true).Restore(),
+			wantFailures: []string{"pods/ns-1/pod-2 was restored but doesn't exist in the cluster"},
+		},
+		{
+			name:    "items are not verified when the restore doesn't verify them",
+			restore: defaultRestore().Restore(),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.Pods())
+
+			// simulate a webhook that silently drops pod-2
+			h.DynamicClient.PrependReactor("get", "pods", func(action kubetesting.Action) (bool, runtime.Object, error) {
+				if name := action.(kubetesting.GetAction).GetName(); name == "pod-2" {
+					return true, nil, apierrors.NewNotFound(schema.GroupResource{Resource: "pods"}, name)
+				}
+				return false, nil, nil
+			})
+
+			tarball := newTarWriter(t).
+				addItems("pods",
+					test.NewPod("ns-1", "pod-1"),
+					test.NewPod("ns-1", "pod-2"),
+				).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, warnings)
+			assert.Equal(t, tc.wantFailures, errs.VerificationFailures)
+			assert.Empty(t, errs.Velero)
+			assert.Empty(t, errs.Cluster)
+			assert.Empty(t, errs.Namespaces)
+		})
+	}
+}
+
+// TestRestoreGroupAliases runs a restore of a custom resource whose API group has been
+// renamed since the backup, and verifies that the item is restored under the new group.
+func TestRestoreGroupAliases(t *testing.T) {
+	widget := func(apiVersion, kind string) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+		obj.SetAPIVersion(apiVersion)
+		obj.SetKind(kind)
+		obj.SetNamespace("ns-1")
+		obj.SetName("widget-1")
+		return obj
+	}
+
+	widgets := &test.APIResource{
+		Group:      "new.example.com",
+		Version:    "v1",
+		Name:       "widgets",
+		Namespaced: true,
+	}
+
+	tests := []struct {
+		name           string
+		restore        *velerov1api.Restore
+		wantAPIVersion string
+	}{
+		{
+			name: "an alias for all kinds rewrites the item's group",
+			restore: defaultRestore().GroupAliases(velerov1api.GroupAlias{
+				FromGroup: "old.example.com",
+				ToGroup:   "new.example.com",
+			}).Restore(),
+			wantAPIVersion: "new.example.com/v1",
+		},
+		{
+			name: "an alias for the item's kind rewrites the item's group",
+			restore: defaultRestore().GroupAliases(velerov1api.GroupAlias{
+				FromGroup: "old.example.com",
+				ToGroup:   "new.example.com",
+				Kinds:     []string{"Widget"},
+			}).Restore(),
+			wantAPIVersion: "new.example.com/v1",
+		},
+		{
+			name: "an alias for other kinds doesn't rewrite the item's group",
+			restore: defaultRestore().GroupAliases(velerov1api.GroupAlias{
+				FromGroup: "old.example.com",
+				ToGroup:   "new.example.com",
+				Kinds:     []string{"Gadget"},
+			}).Restore(),
+			wantAPIVersion: "old.example.com/v1",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, widgets)
+
+			var created *unstructured.Unstructured
+			h.DynamicClient.PrependReactor("create", "widgets", func(action kubetesting.Action) (bool, runtime.Object, error) {
+				created = action.(kubetesting.CreateAction).GetObject().(*unstructured.Unstructured)
+				return false, nil, nil
+			})
+
+			tarball := newTarWriter(t).
+				addItems("widgets.old.example.com", widget("old.example.com/v1", "Widget")).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, warnings, errs)
+			require.NotNil(t, created)
+			assert.Equal(t, tc.wantAPIVersion, created.GetAPIVersion())
+			assert.Equal(t, "widget-1", created.GetName())
+		})
+	}
+}
+
+// TestRestoreGroupVersionKindMappings runs restores of an Ingress backed up under a
+// deprecated API, and verifies that it's restored under the API it's mapped to if the
+// cluster serves that API, and skipped with a warning otherwise.
+func TestRestoreGroupVersionKindMappings(t *testing.T) {
+	ingress := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	ingress.SetAPIVersion("extensions/v1beta1")
+	ingress.SetKind("Ingress")
+	ingress.SetNamespace("ns-1")
+	ingress.SetName("ingress-1")
+
+	newIngressResource := func(group, version string) *test.APIResource {
+		return &test.APIResource{
+			Group:      group,
+			Version:    version,
+			Name:       "ingresses",
+			Namespaced: true,
+		}
+	}
+
+	mapping := func(to metav1.GroupVersionKind) velerov1api.GroupVersionKindMapping {
+		return velerov1api.GroupVersionKindMapping{
+			From: metav1.GroupVersionKind{Group: "extensions", Version: "v1beta1", Kind: "Ingress"},
+			To:   to,
+		}
+	}
+
+	tests := []struct {
+		name         string
+		apiResources []*test.APIResource
+		restore      *velerov1api.Restore
+		want         []string
+		wantWarnings int
+	}{
+		{
+			name:         "an Ingress is restored under the API it's mapped to",
+			apiResources: []*test.APIResource{newIngressResource("networking.k8s.io", "v1")},
+			restore:      defaultRestore().GroupVersionKindMappings(mapping(metav1.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"})).Restore(),
+			want:         []string{"networking.k8s.io/v1"},
+		},
+		{
+			name: "an Ingress is restored once when the cluster still serves its deprecated API",
+			apiResources: []*test.APIResource{
+				newIngressResource("extensions", "v1beta1"),
+				newIngressResource("networking.k8s.io", "v1"),
+			},
+			restore: defaultRestore().GroupVersionKindMappings(mapping(metav1.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"})).Restore(),
+			want:    []string{"networking.k8s.io/v1"},
+		},
+		{
+			name:         "an Ingress mapped to an API the cluster doesn't serve is skipped with a warning",
+			apiResources: []*test.APIResource{newIngressResource("networking.k8s.io", "v1")},
+			restore:      defaultRestore().GroupVersionKindMappings(mapping(metav1.GroupVersionKind{Group: "networking.k8s.io", Version: "v2", Kind: "Ingress"})).Restore(),
+			wantWarnings: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			for _, r := range tc.apiResources {
+				h.addItems(t, r)
+			}
+
+			var created []string
+			h.DynamicClient.PrependReactor("create", "ingresses", func(action kubetesting.Action) (bool, runtime.Object, error) {
+				created = append(created, action.(kubetesting.CreateAction).GetObject().(*unstructured.Unstructured).GetAPIVersion())
+				return false, nil, nil
+			})
+
+			tarball := newTarWriter(t).
+				addItems("ingresses.extensions", ingress.DeepCopy()).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, errs)
+			assert.Len(t, warnings.Namespaces["ns-1"], tc.wantWarnings)
+			assert.Equal(t, tc.want, created)
+		})
+	}
+}
+
+// TestRestoreSuspendVeleroObjects runs a restore of a Velero schedule, and verifies
+// that it's restored paused if the restore suspends Velero objects.
+func TestRestoreSuspendVeleroObjects(t *testing.T) {
+	schedules := &test.APIResource{
+		Group:      "velero.io",
+		Version:    "v1",
+		Name:       "schedules",
+		Namespaced: true,
+	}
+
+	tests := []struct {
+		name       string
+		restore    *velerov1api.Restore
+		wantPaused bool
+	}{
+		{
+			name:       "a schedule is restored paused when the restore suspends Velero objects",
+			restore:    defaultRestore().SuspendVeleroObjects(true).Restore(),
+			wantPaused: true,
+		},
+		{
+			name:       "a schedule is restored unchanged when the restore doesn't suspend Velero objects",
+			restore:    defaultRestore().Restore(),
+			wantPaused: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, schedules)
+
+			schedule := &velerov1api.Schedule{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "velero.io/v1", Kind: "Schedule"},
+				ObjectMeta: metav1.ObjectMeta{Namespace: "velero", Name: "schedule-1"},
+				Spec:       velerov1api.ScheduleSpec{Schedule: "@every 5m"},
+			}
+
+			tarball := newTarWriter(t).
+				addItems("schedules.velero.io", schedule).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, warnings, errs)
+
+			res, err := h.DynamicClient.Resource(schedules.GVR()).Namespace("velero").Get("schedule-1", metav1.GetOptions{})
+			require.NoError(t, err)
+
+			paused, _, err := unstructured.NestedBool(res.Object, "spec", "paused")
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantPaused, paused)
+		})
+	}
+}
+
+// TestRestorePVCMinimumSize runs restores of a persistent volume claim with a minimum
+// claim size, and verifies that the created claim's storage request is increased to the
+// minimum but never decreased.
+func TestRestorePVCMinimumSize(t *testing.T) {
+	withStorageRequest := func(size string) func(obj metav1.Object) {
+		return func(obj metav1.Object) {
+			obj.(*corev1api.PersistentVolumeClaim).Spec.Resources.Requests = corev1api.ResourceList{
+				corev1api.ResourceStorage: resource.MustParse(size),
+			}
+		}
+	}
+
+	tests := []struct {
+		name         string
+		restore      *velerov1api.Restore
+		requested    string
+		want         string
+		wantWarnings int
+	}{
+		{
+			name:         "a claim smaller than the minimum size is increased to it",
+			restore:      defaultRestore().PVCMinimumSize(resource.MustParse("1Gi")).Restore(),
+			requested:    "1Mi",
+			want:         "1Gi",
+			wantWarnings: 1,
+		},
+		{
+			name:      "a claim larger than the minimum size is unchanged",
+			restore:   defaultRestore().PVCMinimumSize(resource.MustParse("1Gi")).Restore(),
+			requested: "2Gi",
+			want:      "2Gi",
+		},
+		{
+			name:      "a claim is unchanged when the restore doesn't have a minimum size",
+			restore:   defaultRestore().Restore(),
+			requested: "1Mi",
+			want:      "1Mi",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.PVCs())
+
+			tarball := newTarWriter(t).
+				addItems("persistentvolumeclaims", test.NewPVC("ns-1", "pvc-1", withStorageRequest(tc.requested))).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, errs)
+			assert.Len(t, warnings.Namespaces["ns-1"], tc.wantWarnings)
+
+			res, err := h.DynamicClient.Resource(test.PVCs().GVR()).Namespace("ns-1").Get("pvc-1", metav1.GetOptions{})
+			require.NoError(t, err)
+
+			pvc := new(corev1api.PersistentVolumeClaim)
+			require.NoError(t, runtime.DefaultUnstructuredConverter.FromUnstructured(res.Object, pvc))
+			want := resource.MustParse(tc.want)
+			got := pvc.Spec.Resources.Requests[corev1api.ResourceStorage]
+			assert.Zero(t, want.Cmp(got), "want storage request %s, got %s", want.String(), got.String())
+		})
+	}
+}
+
+// TestRestoreNamespaceReadyDelay runs a restore into a new namespace and an existing one
+// with a namespace ready delay, and verifies that the delay is waited out after the new
+// namespace is created and before the first item is created in it, but not for the
+// existing namespace.
+func TestRestoreNamespaceReadyDelay(t *testing.T) {
+	h := newHarness(t)
+	h.addItems(t, test.Pods())
+
+	_, err := h.KubeClient.CoreV1().Namespaces().Create(test.NewNamespace("ns-2"))
+	require.NoError(t, err)
+
+	var events []string
+	h.KubeClient.PrependReactor("create", "namespaces", func(action kubetesting.Action) (bool, runtime.Object, error) {
+		events = append(events, "create namespace "+action.(kubetesting.CreateAction).GetObject().(metav1.Object).GetName())
+		return false, nil, nil
+	})
+	h.DynamicClient.PrependReactor("create", "pods", func(action kubetesting.Action) (bool, runtime.Object, error) {
+		obj := action.(kubetesting.CreateAction).GetObject().(metav1.Object)
+		events = append(events, "create pod "+obj.GetNamespace()+"/"+obj.GetName())
+		return false, nil, nil
+	})
+
+	namespaceReadySleep = func(d time.Duration) {
+		events = append(events, "sleep "+d.String())
+	}
+	defer func() { namespaceReadySleep = time.Sleep }()
+
+	tarball := newTarWriter(t).
+		addItems("pods",
+			test.NewPod("ns-1", "pod-1"),
+			test.NewPod("ns-2", "pod-2"),
+		).
+		done()
+
+	warnings, errs, _ := h.restorer.Restore(
+		h.log,
+		defaultRestore().NamespaceReadyDelay(5*time.Second).Restore(),
+		defaultBackup().Backup(),
+		nil, // volume snapshots
+		tarball,
+		nil, // actions
+		nil, // snapshot location lister
+		nil, // volume snapshotter getter
+	)
+
+	assertEmptyResults(t, warnings, errs)
+	assert.Equal(t, []string{
+		"create namespace ns-1",
+		"sleep 5s",
+		"create pod ns-1/pod-1",
+		"create pod ns-2/pod-2",
+	}, events)
+}
+
+// TestRestoreValidateRBACReferences runs restores of a role binding, and verifies that a
+// warning is recorded for each role or service account it references that neither was
+// restored nor exists in the cluster.
+func TestRestoreValidateRBACReferences(t *testing.T) {
+	binding := test.NewRoleBinding("ns-1", "rb-1", func(obj metav1.Object) {
+		rb := obj.(*rbacv1api.RoleBinding)
+		rb.RoleRef = rbacv1api.RoleRef{APIGroup: "rbac.authorization.k8s.io", Kind: "Role", Name: "role-1"}
+		rb.Subjects = []rbacv1api.Subject{
+			{Kind: "ServiceAccount", Name: "sa-1"},
+			{Kind: "User", Name: "user-1"},
+		}
+	})
+
+	tests := []struct {
+		name         string
+		restore      *velerov1api.Restore
+		tarball      io.Reader
+		roles        *test.APIResource
+		wantWarnings []string
+	}{
+		{
+			name:    "a reference to an absent role is a warning",
+			restore: defaultRestore().ValidateRBACReferences(true).Restore(),
+			tarball: newTarWriter(t).
+				addItems("rolebindings.rbac.authorization.k8s.io", binding).
+				addItems("serviceaccounts", test.NewServiceAccount("ns-1", "sa-1")).
+				done(),
+			roles:        test.Roles(),
+			wantWarnings: []string{"ns-1/rb-1 references roles.rbac.authorization.k8s.io/ns-1/role-1, which doesn't exist"},
+		},
+		{
+			name:    "references to restored items are not warnings",
+			restore: defaultRestore().ValidateRBACReferences(true).Restore(),
+			tarball: newTarWriter(t).
+				addItems("rolebindings.rbac.authorization.k8s.io", binding).
+				addItems("roles.rbac.authorization.k8s.io", test.NewRole("ns-1", "role-1")).
+				addItems("serviceaccounts", test.NewServiceAccount("ns-1", "sa-1")).
+				done(),
+			roles: test.Roles(),
+		},
+		{
+			name:    "references to items in the cluster are not warnings",
+			restore: defaultRestore().ValidateRBACReferences(true).Restore(),
+			tarball: newTarWriter(t).
+				addItems("rolebindings.rbac.authorization.k8s.io", binding).
+				addItems("serviceaccounts", test.NewServiceAccount("ns-1", "sa-1")).
+				done(),
+			roles: test.Roles(test.NewRole("ns-1", "role-1")),
+		},
+		{
+			name:    "references are not checked when the restore doesn't validate them",
+			restore: defaultRestore().Restore(),
+			tarball: newTarWriter(t).
+				addItems("rolebindings.rbac.authorization.k8s.io", binding).
+				done(),
+			roles: test.Roles(),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.RoleBindings())
+			h.addItems(t, test.ServiceAccounts())
+			h.addItems(t, tc.roles)
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tc.tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, errs)
+			assert.Equal(t, tc.wantWarnings, warnings.Namespaces["ns-1"])
+		})
+	}
+}
+
+// TestRestoreWithHooks runs restores of pods with restore hooks and verifies that the
+// hooks are run once the pods are running, with failures recorded as warnings unless the
+// hook's error mode is Fail. It uses a MockPodCommandExecutor since hooks can't actually
+// be executed in running pods during the unit test.
+func TestRestoreWithHooks(t *testing.T) {
+	defer func(interval time.Duration) { readinessPollInterval = interval }(readinessPollInterval)
+	readinessPollInterval = time.Millisecond
+
+	type expectedCall struct {
+		hookName string
+		hook     *velerov1api.ExecHook
+		err      error
+	}
+
+	hookSpec := func(onError velerov1api.HookErrorMode) velerov1api.RestoreHooks {
+		return velerov1api.RestoreHooks{
+			Resources: []velerov1api.RestoreResourceHookSpec{
+				{
+					Name:               "hook-1",
+					IncludedNamespaces: []string{"ns-1"},
+					PostHooks: []velerov1api.RestoreResourceHook{
+						{
+							Exec: &velerov1api.RestoreExecHook{
+								Container:   "container-1",
+								Command:     []string{"ls", "/tmp"},
+								OnError:     onError,
+								ExecTimeout: metav1.Duration{Duration: time.Minute},
+								WaitTimeout: metav1.Duration{Duration: 100 * time.Millisecond},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		restore      *velerov1api.Restore
+		tarball      io.Reader
+		podPhase     corev1api.PodPhase
+		wantCalls    []*expectedCall
+		wantWarnings []string
+		wantErrs     []string
+	}{
+		{
+			name:     "a spec hook runs in a matching pod once it's running",
+			restore:  defaultRestore().Hooks(hookSpec("")).Restore(),
+			tarball:  newTarWriter(t).addItems("pods", test.NewPod("ns-1", "pod-1")).done(),
+			podPhase: corev1api.PodRunning,
+			wantCalls: []*expectedCall{
+				{
+					hookName: "hook-1",
+					hook: &velerov1api.ExecHook{
+						Container: "container-1",
+						Command:   []string{"ls", "/tmp"},
+						Timeout:   metav1.Duration{Duration: time.Minute},
+					},
+				},
+			},
+		},
+		{
+			name:     "a spec hook doesn't run in a pod in a namespace it doesn't include",
+			restore:  defaultRestore().Hooks(hookSpec("")).Restore(),
+			tarball:  newTarWriter(t).addItems("pods", test.NewPod("ns-2", "pod-1")).done(),
+			podPhase: corev1api.PodRunning,
+		},
+		{
+			name:    "a hook from a pod's annotations takes priority over spec hooks",
+			restore: defaultRestore().Hooks(hookSpec("")).Restore(),
+			tarball: newTarWriter(t).
+				addItems("pods", test.NewPod("ns-1", "pod-1", test.WithAnnotations(
+					"post.hook.restore.velero.io/container", "container-2",
+					"post.hook.restore.velero.io/command", `["/bin/replay", "--wal"]`,
+					"post.hook.restore.velero.io/exec-timeout", "5m",
+				))).
+				done(),
+			podPhase: corev1api.PodRunning,
+			wantCalls: []*expectedCall{
+				{
+					hookName: "<from-annotation>",
+					hook: &velerov1api.ExecHook{
+						Container: "container-2",
+						Command:   []string{"/bin/replay", "--wal"},
+						Timeout:   metav1.Duration{Duration: 5 * time.Minute},
+					},
+				},
+			},
+		},
+		{
+			name:     "a failed hook is a warning by default",
+			restore:  defaultRestore().Hooks(hookSpec("")).Restore(),
+			tarball:  newTarWriter(t).addItems("pods", test.NewPod("ns-1", "pod-1")).done(),
+			podPhase: corev1api.PodRunning,
+			wantCalls: []*expectedCall{
+				{
+					hookName: "hook-1",
+					hook: &velerov1api.ExecHook{
+						Container: "container-1",
+						Command:   []string{"ls", "/tmp"},
+						Timeout:   metav1.Duration{Duration: time.Minute},
+					},
+					err: errors.New("exit code 1"),
+				},
+			},
+			wantWarnings: []string{"error executing restore hook hook-1 in pod ns-1/pod-1: exit code 1"},
+		},
+		{
+			name:     "a failed hook is an error when its error mode is Fail",
+			restore:  defaultRestore().Hooks(hookSpec(velerov1api.HookErrorModeFail)).Restore(),
+			tarball:  newTarWriter(t).addItems("pods", test.NewPod("ns-1", "pod-1")).done(),
+			podPhase: corev1api.PodRunning,
+			wantCalls: []*expectedCall{
+				{
+					hookName: "hook-1",
+					hook: &velerov1api.ExecHook{
+						Container: "container-1",
+						Command:   []string{"ls", "/tmp"},
+						OnError:   velerov1api.HookErrorModeFail,
+						Timeout:   metav1.Duration{Duration: time.Minute},
+					},
+					err: errors.New("exit code 1"),
+				},
+			},
+			wantErrs: []string{"error executing restore hook hook-1 in pod ns-1/pod-1: exit code 1"},
+		},
+		{
+			name:         "a hook doesn't run in a pod that isn't running within the wait timeout",
+			restore:      defaultRestore().Hooks(hookSpec("")).Restore(),
+			tarball:      newTarWriter(t).addItems("pods", test.NewPod("ns-1", "pod-1")).done(),
+			podPhase:     corev1api.PodPending,
+			wantWarnings: []string{"error executing restore hook hook-1 in pod ns-1/pod-1: timed out after 100ms waiting for pod to be running"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.Pods())
+
+			podCommandExecutor := new(testutil.MockPodCommandExecutor)
+			h.restorer.podCommandExecutor = podCommandExecutor
+			defer podCommandExecutor.AssertExpectations(t)
+
+			for _, call := range tc.wantCalls {
+				podCommandExecutor.On("ExecutePodCommand",
+					mock.Anything,
+					mock.Anything,
+					"ns-1",
+					"pod-1",
+					call.hookName,
+					call.hook,
+				).Return(call.err)
+			}
+
+			h.DynamicClient.PrependReactor("get", "pods", func(action kubetesting.Action) (bool, runtime.Object, error) {
+				pod := test.NewPod(action.GetNamespace(), action.(kubetesting.GetAction).GetName())
+				pod.Status.Phase = tc.podPhase
+
+				obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pod)
+				require.NoError(t, err)
+				return true, &unstructured.Unstructured{Object: obj}, nil
+			})
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tc.tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assert.Equal(t, tc.wantWarnings, warnings.Namespaces["ns-1"])
+			assert.Equal(t, tc.wantErrs, errs.Namespaces["ns-1"])
+		})
+	}
+}
+
+// TestRestoreStorageClassMappings runs restores of persistent volumes and claims with
+// storage class mappings, and verifies that the created items have their storage classes
+// remapped, while unmapped and empty storage classes are unchanged.
+func TestRestoreStorageClassMappings(t *testing.T) {
+	withStorageClass := func(storageClassName string) func(obj metav1.Object) {
+		return func(obj metav1.Object) {
+			switch obj := obj.(type) {
+			case *corev1api.PersistentVolume:
+				obj.Spec.StorageClassName = storageClassName
+			case *corev1api.PersistentVolumeClaim:
+				obj.Spec.StorageClassName = &storageClassName
+			}
+		}
+	}
+
+	h := newHarness(t)
+	h.addItems(t, test.PVs())
+	h.addItems(t, test.PVCs())
+
+	tarball := newTarWriter(t).
+		addItems("persistentvolumes",
+			test.NewPV("pv-1", withStorageClass("example-nfs")),
+			test.NewPV("pv-2", withStorageClass("other")),
+		).
+		addItems("persistentvolumeclaims",
+			test.NewPVC("ns-1", "pvc-1", withStorageClass("example-nfs")),
+			test.NewPVC("ns-1", "pvc-2", withStorageClass("other")),
+			test.NewPVC("ns-1", "pvc-3", withStorageClass("")),
+		).
+		done()
+
+	warnings, errs, _ := h.restorer.Restore(
+		h.log,
+		defaultRestore().StorageClassMappings("example-nfs", "standard", "", "standard").Restore(),
+		defaultBackup().Backup(),
+		nil, // volume snapshots
+		tarball,
+		nil, // actions
+		nil, // snapshot location lister
+		nil, // volume snapshotter getter
+	)
+
+	assertEmptyResults(t, warnings, errs)
+
+	want := map[string]string{
+		"persistentvolumes//pv-1":           "standard",
+		"persistentvolumes//pv-2":           "other",
+		"persistentvolumeclaims/ns-1/pvc-1": "standard",
+		"persistentvolumeclaims/ns-1/pvc-2": "other",
+		"persistentvolumeclaims/ns-1/pvc-3": "",
+	}
+	got := make(map[string]string)
+	for _, resource := range []string{"persistentvolumes", "persistentvolumeclaims"} {
+		list, err := h.DynamicClient.Resource(corev1api.SchemeGroupVersion.WithResource(resource)).List(metav1.ListOptions{})
+		require.NoError(t, err)
+
+		for _, item := range list.Items {
+			storageClassName, _, _ := unstructured.NestedString(item.Object, "spec", "storageClassName")
+			got[resource+"/"+item.GetNamespace()+"/"+item.GetName()] = storageClassName
+		}
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestRestoreUnreferencedConfig(t *testing.T) {
+	withVolumes := func(obj metav1.Object) {
+		obj.(*corev1api.Pod).Spec.Volumes = []corev1api.Volume{
+			{
+				Name: "config",
+				VolumeSource: corev1api.VolumeSource{
+					ConfigMap: &corev1api.ConfigMapVolumeSource{LocalObjectReference: corev1api.LocalObjectReference{Name: "cm-1"}},
+				},
+			},
+			{
+				Name: "creds",
+				VolumeSource: corev1api.VolumeSource{
+					Secret: &corev1api.SecretVolumeSource{SecretName: "secret-1"},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		restore      *velerov1api.Restore
+		wantWarnings Result
+		want         []string
+	}{
+		{
+			name:    "unreferenced config maps and secrets are restored when neither reporting nor pruning",
+			restore: defaultRestore().Restore(),
+			want:    []string{"cm-1", "cm-2"},
+		},
+		{
+			name:    "unreferenced config maps are reported when reporting",
+			restore: defaultRestore().ReportUnreferenced(true).Restore(),
+			wantWarnings: Result{
+				Namespaces: map[string][]string{"ns-1": {"configmaps ns-1/cm-2 is not referenced by any restored workload"}},
+			},
+			want: []string{"cm-1", "cm-2"},
+		},
+		{
+			name:    "unreferenced config maps are skipped when pruning",
+			restore: defaultRestore().PruneUnreferenced(true).Restore(),
+			want:    []string{"cm-1"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.ConfigMaps())
+			h.addItems(t, test.Secrets())
+			h.addItems(t, test.Pods())
+
+			tarball := newTarWriter(t).
+				addItems("configmaps", test.NewConfigMap("ns-1", "cm-1"), test.NewConfigMap("ns-1", "cm-2")).
+				addItems("secrets", test.NewSecret("ns-1", "secret-1")).
+				addItems("pods", test.NewPod("ns-1", "pod-1", withVolumes)).
+				done()
+
+			warnings, errs, results := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, errs)
+			assert.Equal(t, tc.wantWarnings, warnings)
+
+			configMaps, err := h.DynamicClient.Resource(corev1api.SchemeGroupVersion.WithResource("configmaps")).Namespace("ns-1").List(metav1.ListOptions{})
+			require.NoError(t, err)
+			var got []string
+			for _, item := range configMaps.Items {
+				got = append(got, item.GetName())
+			}
+			assert.ElementsMatch(t, tc.want, got)
+
+			if tc.restore.Spec.PruneUnreferenced {
+				var skipped []string
+				for _, res := range results {
+					if res.Outcome == ItemOutcomeSkipped {
+						skipped = append(skipped, res.Name+": "+res.Reason)
+					}
+				}
+				assert.Equal(t, []string{"cm-2: " + unreferencedReason}, skipped)
+			}
+		})
+	}
+}
+
+// TestRestorePodSecurityRejection runs restores of a pod that's rejected by the PodSecurity
+// admission level enforced on its namespace until the namespace is relaxed, and verifies the
+// outcome with each pod security policy.
+func TestRestorePodSecurityRejection(t *testing.T) {
+	rejection := `violates PodSecurity "restricted:latest": ` +
+		`allowPrivilegeEscalation != false (container "c1" must set securityContext.allowPrivilegeEscalation=false), ` +
+		`unrestricted capabilities (container "c1" must set securityContext.capabilities.drop=["ALL"]), ` +
+		`runAsNonRoot != true (pod or container "c1" must set securityContext.runAsNonRoot=true)`
+
+	tests := []struct {
+		name     string
+		restore  *velerov1api.Restore
+		wantErrs Result
+		wantPods []string
+	}{
+		{
+			name:    "a rejected pod is an error listing the violated controls by default",
+			restore: defaultRestore().Restore(),
+			wantErrs: Result{
+				Namespaces: map[string][]string{
+					"ns-1": {`error restoring pods/ns-1/pod-1: ns-1/pod-1 was rejected by the PodSecurity "restricted:latest" level enforced on namespace ns-1, ` +
+						`violating: allowPrivilegeEscalation != false; unrestricted capabilities; runAsNonRoot != true. ` +
+						`Relax the namespace's pod-security.kubernetes.io/enforce label, or restore with the RelaxNamespace pod security policy`},
+				},
+			},
+		},
+		{
+			name:     "a rejected pod is created once its namespace is relaxed, and the namespace's level is put back",
+			restore:  defaultRestore().PodSecurityPolicy(velerov1api.PodSecurityPolicyRelaxNamespace).Restore(),
+			wantPods: []string{"pod-1"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.Pods())
+
+			_, err := h.KubeClient.CoreV1().Namespaces().Create(test.NewNamespace("ns-1", test.WithLabels(podSecurityEnforceLabel, "restricted")))
+			require.NoError(t, err)
+
+			h.DynamicClient.PrependReactor("create", "pods", func(action kubetesting.Action) (bool, runtime.Object, error) {
+				ns, err := h.KubeClient.CoreV1().Namespaces().Get(action.GetNamespace(), metav1.GetOptions{})
+				require.NoError(t, err)
+				if ns.Labels[podSecurityEnforceLabel] == "privileged" {
+					return false, nil, nil
+				}
+
+				name := action.(kubetesting.CreateAction).GetObject().(metav1.Object).GetName()
+				return true, nil, apierrors.NewForbidden(kuberesource.Pods, name, errors.New(rejection))
+			})
+
+			tarball := newTarWriter(t).
+				addItems("pods", test.NewPod("ns-1", "pod-1")).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, warnings)
+			assert.Equal(t, tc.wantErrs, errs)
+
+			pods, err := h.DynamicClient.Resource(corev1api.SchemeGroupVersion.WithResource("pods")).Namespace("ns-1").List(metav1.ListOptions{})
+			require.NoError(t, err)
+			var got []string
+			for _, item := range pods.Items {
+				got = append(got, item.GetName())
+			}
+			assert.Equal(t, tc.wantPods, got)
+
+			ns, err := h.KubeClient.CoreV1().Namespaces().Get("ns-1", metav1.GetOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, "restricted", ns.Labels[podSecurityEnforceLabel])
+		})
+	}
+}
+
+// recordingProgressUpdater is a ProgressUpdater that records each update of a restore's progress.
+type recordingProgressUpdater struct {
+	lock    sync.Mutex
+	updates []velerov1api.RestoreProgress
+}
+
+func (u *recordingProgressUpdater) UpdateProgress(restore *velerov1api.Restore, progress velerov1api.RestoreProgress) error {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	u.updates = append(u.updates, progress)
+	return nil
+}
+
+// TestRestoreProgress runs a restore of persistent volumes and claims, some of which already
+// exist in the cluster, and verifies that the final progress update counts all of the items
+// in the backup, the items restored and skipped, and the warnings.
+func TestRestoreProgress(t *testing.T) {
+	h := newHarness(t)
+	h.addItems(t, test.PVs(test.NewPV("pv-2", func(obj metav1.Object) {
+		obj.(*corev1api.PersistentVolume).Spec.StorageClassName = "in-cluster"
+	})))
+	h.addItems(t, test.PVCs(test.NewPVC("ns-1", "pvc-2")))
+
+	updater := new(recordingProgressUpdater)
+	h.restorer.progressUpdater = updater
+
+	tarball := newTarWriter(t).
+		addItems("persistentvolumes", test.NewPV("pv-1"), test.NewPV("pv-2")).
+		addItems("persistentvolumeclaims", test.NewPVC("ns-1", "pvc-1"), test.NewPVC("ns-1", "pvc-2")).
+		done()
+
+	warnings, errs, _ := h.restorer.Restore(
+		h.log,
+		defaultRestore().Restore(),
+		defaultBackup().Backup(),
+		nil, // volume snapshots
+		tarball,
+		nil, // actions
+		nil, // snapshot location lister
+		nil, // volume snapshotter getter
+	)
+
+	assertEmptyResults(t, errs)
+	assert.Len(t, warnings.Cluster, 1)
+
+	require.NotEmpty(t, updater.updates)
+	assert.Equal(t, velerov1api.RestoreProgress{
+		TotalItems:    4,
+		ItemsRestored: 2,
+		ItemsSkipped:  2,
+		Warnings:      1,
+	}, updater.updates[len(updater.updates)-1])
+}
+
+// TestRestoreExistingResourcePolicy runs a restore of a config map that already exists in the
+// cluster with different data twice, and verifies that each restore skips the config map and
+// only records a warning for it under the none policy.
+func TestRestoreExistingResourcePolicy(t *testing.T) {
+	withData := func(val string) func(obj metav1.Object) {
+		return func(obj metav1.Object) {
+			obj.(*corev1api.ConfigMap).Data = map[string]string{"key": val}
+		}
+	}
+
+	tests := []struct {
+		name         string
+		policy       velerov1api.ExistingResourcePolicy
+		wantWarnings Result
+	}{
+		{
+			name:   "an existing item that differs is a warning under the none policy",
+			policy: velerov1api.ExistingResourcePolicyNone,
+			wantWarnings: Result{
+				Namespaces: map[string][]string{"ns-1": {`not restored: configmaps "cm-1" already exists and is different from backed up version.`}},
+			},
+		},
+		{
+			name:         "an existing item that differs is skipped without a warning under the skipQuiet policy",
+			policy:       velerov1api.ExistingResourcePolicySkipQuiet,
+			wantWarnings: Result{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.ConfigMaps(test.NewConfigMap("ns-1", "cm-1", withData("in-cluster"))))
+
+			for i := 0; i < 2; i++ {
+				tarball := newTarWriter(t).
+					addItems("configmaps", test.NewConfigMap("ns-1", "cm-1", withData("backed-up"))).
+					done()
+
+				warnings, errs, results := h.restorer.Restore(
+					h.log,
+					defaultRestore().ExistingResourcePolicy(tc.policy).Restore(),
+					defaultBackup().Backup(),
+					nil, // volume snapshots
+					tarball,
+					nil, // actions
+					nil, // snapshot location lister
+					nil, // volume snapshotter getter
+				)
+
+				assertEmptyResults(t, errs)
+				assert.Equal(t, tc.wantWarnings, warnings)
+				require.Len(t, results, 1)
+				assert.Equal(t, ItemOutcomeSkipped, results[0].Outcome)
+			}
+
+			res, err := h.DynamicClient.Resource(test.ConfigMaps().GVR()).Namespace("ns-1").Get("cm-1", metav1.GetOptions{})
+			require.NoError(t, err)
+			data, _, _ := unstructured.NestedStringMap(res.Object, "data")
+			assert.Equal(t, map[string]string{"key": "in-cluster"}, data)
+		})
+	}
+}
+
+// TestRestoreExistingResourcePolicyUpdate runs restores of items that already exist in the
+// cluster with different specs under the update policy, and verifies that the in-cluster items
+// are patched to match the backup while keeping their immutable fields and the fields only
+// added in the cluster, and that a rejected patch is recorded as a warning.
+func TestRestoreExistingResourcePolicyUpdate(t *testing.T) {
+	tests := []struct {
+		name         string
+		inCluster    *test.APIResource
+		backup       metav1.Object
+		rejectPatch  bool
+		wantOutcome  ItemOutcome
+		wantWarnings int
+		check        func(t *testing.T, obj *unstructured.Unstructured)
+	}{
+		{
+			name: "a config map is patched to the backed-up data and keeps the keys added in the cluster",
+			inCluster: test.ConfigMaps(test.NewConfigMap("ns-1", "cm-1", func(obj metav1.Object) {
+				obj.(*corev1api.ConfigMap).Data = map[string]string{"key": "in-cluster", "extra": "val"}
+			})),
+			backup: test.NewConfigMap("ns-1", "cm-1", func(obj metav1.Object) {
+				obj.(*corev1api.ConfigMap).Data = map[string]string{"key": "backed-up"}
+			}),
+			wantOutcome: ItemOutcomeUpdated,
+			check: func(t *testing.T, obj *unstructured.Unstructured) {
+				data, _, _ := unstructured.NestedStringMap(obj.Object, "data")
+				assert.Equal(t, map[string]string{"key": "backed-up", "extra": "val"}, data)
+			},
+		},
+		{
+			name: "a service is patched to the backed-up selector and keeps its cluster IP",
+			inCluster: test.Services(test.NewService("ns-1", "svc-1", func(obj metav1.Object) {
+				obj.(*corev1api.Service).Spec.ClusterIP = "10.96.0.10"
+				obj.(*corev1api.Service).Spec.Selector = map[string]string{"app": "in-cluster"}
+			})),
+			backup: test.NewService("ns-1", "svc-1", func(obj metav1.Object) {
+				obj.(*corev1api.Service).Spec.Selector = map[string]string{"app": "backed-up"}
+			}),
+			wantOutcome: ItemOutcomeUpdated,
+			check: func(t *testing.T, obj *unstructured.Unstructured) {
+				selector, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "selector")
+				assert.Equal(t, map[string]string{"app": "backed-up"}, selector)
+				clusterIP, _, _ := unstructured.NestedString(obj.Object, "spec", "clusterIP")
+				assert.Equal(t, "10.96.0.10", clusterIP)
+			},
+		},
+		{
+			name: "a rejected patch of a bound claim is a warning and the claim is unchanged",
+			inCluster: test.PVCs(test.NewPVC("ns-1", "pvc-1", func(obj metav1.Object) {
+				className := "in-cluster"
+				obj.(*corev1api.PersistentVolumeClaim).Spec.StorageClassName = &className
+				obj.(*corev1api.PersistentVolumeClaim).Spec.VolumeName = "pv-1"
+			})),
+			backup: test.NewPVC("ns-1", "pvc-1", func(obj metav1.Object) {
+				className := "backed-up"
+				obj.(*corev1api.PersistentVolumeClaim).Spec.StorageClassName = &className
+			}),
+			rejectPatch:  true,
+			wantOutcome:  ItemOutcomeSkipped,
+			wantWarnings: 1,
+			check: func(t *testing.T, obj *unstructured.Unstructured) {
+				className, _, _ := unstructured.NestedString(obj.Object, "spec", "storageClassName")
+				assert.Equal(t, "in-cluster", className)
+				volumeName, _, _ := unstructured.NestedString(obj.Object, "spec", "volumeName")
+				assert.Equal(t, "pv-1", volumeName)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, tc.inCluster)
+
+			var patches []string
+			h.DynamicClient.PrependReactor("patch", "*", func(action kubetesting.Action) (bool, runtime.Object, error) {
+				patch := action.(kubetesting.PatchAction)
+				patches = append(patches, string(patch.GetPatch()))
+				if !tc.rejectPatch {
+					return false, nil, nil
+				}
+				return true, nil, apierrors.NewInvalid(schema.GroupKind{Kind: "PersistentVolumeClaim"}, patch.GetName(), field.ErrorList{
+					field.Forbidden(field.NewPath("spec"), "spec is immutable after creation except resources.requests for bound claims"),
+				})
+			})
+
+			tarball := newTarWriter(t).
+				addItems(tc.inCluster.Name, tc.backup).
+				done()
+
+			warnings, errs, results := h.restorer.Restore(
+				h.log,
+				defaultRestore().ExistingResourcePolicy(velerov1api.ExistingResourcePolicyUpdate).Restore(),
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, errs)
+			assert.Len(t, warnings.Namespaces["ns-1"], tc.wantWarnings)
+			require.Len(t, results, 1)
+			assert.Equal(t, tc.wantOutcome, results[0].Outcome)
+
+			// fields that can't be changed once set are never patched
+			require.Len(t, patches, 1)
+			assert.NotContains(t, patches[0], "clusterIP")
+			assert.NotContains(t, patches[0], "volumeName")
+
+			res, err := h.DynamicClient.Resource(tc.inCluster.GVR()).Namespace("ns-1").Get(tc.backup.GetName(), metav1.GetOptions{})
+			require.NoError(t, err)
+			tc.check(t, res)
+		})
+	}
+}
+
+// TestRestoreServiceFieldOverrides runs a restore of services of different types with
+// service field overrides, and verifies that each override is only set on the services
+// whose type supports it.
+func TestRestoreServiceFieldOverrides(t *testing.T) {
+	withSpec := func(serviceType corev1api.ServiceType, policy corev1api.ServiceExternalTrafficPolicyType, healthCheckNodePort int32) func(obj metav1.Object) {
+		return func(obj metav1.Object) {
+			svc := obj.(*corev1api.Service)
+			svc.Spec.Type = serviceType
+			svc.Spec.ExternalTrafficPolicy = policy
+			svc.Spec.HealthCheckNodePort = healthCheckNodePort
+		}
+	}
+
+	h := newHarness(t)
+	h.addItems(t, test.Services())
+
+	tarball := newTarWriter(t).
+		addItems("services",
+			test.NewService("ns-1", "lb-1", withSpec(corev1api.ServiceTypeLoadBalancer, corev1api.ServiceExternalTrafficPolicyTypeLocal, 30000)),
+			test.NewService("ns-1", "nodeport-1", withSpec(corev1api.ServiceTypeNodePort, corev1api.ServiceExternalTrafficPolicyTypeLocal, 0)),
+			test.NewService("ns-1", "clusterip-1", withSpec(corev1api.ServiceTypeClusterIP, "", 0)),
+		).
+		done()
+
+	allocate := false
+	warnings, errs, _ := h.restorer.Restore(
+		h.log,
+		defaultRestore().ServiceFieldOverrides(velerov1api.ServiceFieldOverrides{
+			ExternalTrafficPolicy:         corev1api.ServiceExternalTrafficPolicyTypeCluster,
+			InternalTrafficPolicy:         "Cluster",
+			AllocateLoadBalancerNodePorts: &allocate,
+		}).Restore(),
+		defaultBackup().Backup(),
+		nil, // volume snapshots
+		tarball,
+		nil, // actions
+		nil, // snapshot location lister
+		nil, // volume snapshotter getter
+	)
+
+	assertEmptyResults(t, warnings, errs)
+
+	get := func(name string) *unstructured.Unstructured {
+		res, err := h.DynamicClient.Resource(test.Services().GVR()).Namespace("ns-1").Get(name, metav1.GetOptions{})
+		require.NoError(t, err)
+		return res
+	}
+
+	lb := get("lb-1")
+	policy, _, _ := unstructured.NestedString(lb.Object, "spec", "externalTrafficPolicy")
+	assert.Equal(t, "Cluster", policy)
+	_, found, _ := unstructured.NestedFieldNoCopy(lb.Object, "spec", "healthCheckNodePort")
+	assert.False(t, found)
+	allocated, found, _ := unstructured.NestedBool(lb.Object, "spec", "allocateLoadBalancerNodePorts")
+	assert.True(t, found)
+	assert.False(t, allocated)
+
+	nodePort := get("nodeport-1")
+	policy, _, _ = unstructured.NestedString(nodePort.Object, "spec", "externalTrafficPolicy")
+	assert.Equal(t, "Cluster", policy)
+	_, found, _ = unstructured.NestedFieldNoCopy(nodePort.Object, "spec", "allocateLoadBalancerNodePorts")
+	assert.False(t, found)
+
+	clusterIP := get("clusterip-1")
+	_, found, _ = unstructured.NestedFieldNoCopy(clusterIP.Object, "spec", "externalTrafficPolicy")
+	assert.False(t, found)
+	policy, _, _ = unstructured.NestedString(clusterIP.Object, "spec", "internalTrafficPolicy")
+	assert.Equal(t, "Cluster", policy)
+}
+
+// TestRestoreVersionCompatibilityMode runs restores of a service with a field that Kubernetes
+// 1.22 removed into clusters at different versions, and verifies that the field is only
+// dropped, or the service only rejected, when the cluster's version doesn't support it.
+func TestRestoreVersionCompatibilityMode(t *testing.T) {
+	tests := []struct {
+		name              string
+		mode              velerov1api.VersionCompatibilityMode
+		serverMinor       string
+		wantTopologyKeys  bool
+		wantWarnings      int
+		wantErrs          int
+		wantServiceExists bool
+	}{
+		{
+			name:              "the field is dropped in fixup mode on a version that removed it",
+			mode:              velerov1api.VersionCompatibilityModeFixup,
+			serverMinor:       "22",
+			wantWarnings:      1,
+			wantServiceExists: true,
+		},
+		{
+			name:              "the field is kept in fixup mode on a version that supports it",
+			mode:              velerov1api.VersionCompatibilityModeFixup,
+			serverMinor:       "21",
+			wantTopologyKeys:  true,
+			wantServiceExists: true,
+		},
+		{
+			name:              "the field is kept when the restore has no version compatibility mode",
+			serverMinor:       "22",
+			wantTopologyKeys:  true,
+			wantServiceExists: true,
+		},
+		{
+			name:        "the service is an error in strict mode on a version that removed the field",
+			mode:        velerov1api.VersionCompatibilityModeStrict,
+			serverMinor: "22+",
+			wantErrs:    1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.Services())
+
+			h.DiscoveryClient.FakedServerVersion = &version.Info{Major: "1", Minor: tc.serverMinor}
+			require.NoError(t, h.restorer.discoveryHelper.Refresh())
+
+			u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(test.NewService("ns-1", "svc-1"))
+			require.NoError(t, err)
+			svc := &unstructured.Unstructured{Object: u}
+			require.NoError(t, unstructured.SetNestedStringSlice(svc.Object, []string{"kubernetes.io/hostname", "*"}, "spec", "topologyKeys"))
+
+			tarball := newTarWriter(t).
+				addItems("services", svc).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				defaultRestore().VersionCompatibilityMode(tc.mode).Restore(),
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assert.Len(t, warnings.Namespaces["ns-1"], tc.wantWarnings)
+			assert.Len(t, errs.Namespaces["ns-1"], tc.wantErrs)
+
+			res, err := h.DynamicClient.Resource(test.Services().GVR()).Namespace("ns-1").Get("svc-1", metav1.GetOptions{})
+			if !tc.wantServiceExists {
+				assert.True(t, apierrors.IsNotFound(err))
+				return
+			}
+			require.NoError(t, err)
+			_, found, _ := unstructured.NestedFieldNoCopy(res.Object, "spec", "topologyKeys")
+			assert.Equal(t, tc.wantTopologyKeys, found)
+		})
+	}
+}
+
+// TestRestorePreBindVolumes runs restores of a bound persistent volume and claim into a
+// remapped namespace, and verifies that the volume is created first with a claim reference
+// to the remapped claim, that the claim is created bound to the volume, and that a volume
+// left reserved for a claim that wasn't restored is a warning.
+func TestRestorePreBindVolumes(t *testing.T) {
+	tests := []struct {
+		name         string
+		restore      *velerov1api.Restore
+		rejectClaim  bool
+		wantClaimRef *corev1api.ObjectReference
+		wantWarnings Result
+	}{
+		{
+			name:    "the volume is bound to the remapped claim before the claim is created",
+			restore: defaultRestore().NamespaceMappings("ns-1", "ns-2").PreBindVolumes(true).Restore(),
+			wantClaimRef: &corev1api.ObjectReference{
+				APIVersion: "v1",
+				Kind:       "PersistentVolumeClaim",
+				Namespace:  "ns-2",
+				Name:       "pvc-1",
+			},
+		},
+		{
+			name:        "a volume reserved for a claim that fails to restore is a warning",
+			restore:     defaultRestore().NamespaceMappings("ns-1", "ns-2").PreBindVolumes(true).Restore(),
+			rejectClaim: true,
+			wantClaimRef: &corev1api.ObjectReference{
+				APIVersion: "v1",
+				Kind:       "PersistentVolumeClaim",
+				Namespace:  "ns-2",
+				Name:       "pvc-1",
+			},
+			wantWarnings: Result{
+				Namespaces: map[string][]string{"ns-2": {"persistent volume pv-1 is reserved for claim ns-2/pvc-1, which doesn't exist"}},
+			},
+		},
+		{
+			name:    "the volume's claim reference is removed when the restore doesn't pre-bind volumes",
+			restore: defaultRestore().NamespaceMappings("ns-1", "ns-2").Restore(),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.restorer.resourcePriorities = []string{"persistentvolumes", "persistentvolumeclaims"}
+			h.addItems(t, test.PVs())
+			h.addItems(t, test.PVCs())
+
+			if tc.rejectClaim {
+				h.DynamicClient.PrependReactor("create", "persistentvolumeclaims", func(action kubetesting.Action) (bool, runtime.Object, error) {
+					return true, nil, errors.New("claim rejected")
+				})
+			}
+			recorder := &createRecorder{t: t}
+			h.DynamicClient.PrependReactor("create", "*", recorder.reactor())
+
+			pv := test.NewPV("pv-1", func(obj metav1.Object) {
+				pv := obj.(*corev1api.PersistentVolume)
+				pv.Spec.PersistentVolumeReclaimPolicy = corev1api.PersistentVolumeReclaimRetain
+				pv.Spec.ClaimRef = &corev1api.ObjectReference{
+					Kind:      "PersistentVolumeClaim",
+					Namespace: "ns-1",
+					Name:      "pvc-1",
+					UID:       "uid-1",
+				}
+			})
+			pvc := test.NewPVC("ns-1", "pvc-1", func(obj metav1.Object) {
+				obj.(*corev1api.PersistentVolumeClaim).Spec.VolumeName = "pv-1"
+			})
+
+			tarball := newTarWriter(t).
+				addItems("persistentvolumes", pv).
+				addItems("persistentvolumeclaims", pvc).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assert.Equal(t, tc.wantWarnings, warnings)
+			if tc.rejectClaim {
+				assert.Len(t, errs.Namespaces["ns-2"], 1)
+			} else {
+				assertEmptyResults(t, errs)
+			}
+
+			var creates []resourceID
+			for _, res := range recorder.resources {
+				if res.groupResource == kuberesource.PersistentVolumes.String() || res.groupResource == kuberesource.PersistentVolumeClaims.String() {
+					creates = append(creates, res)
+				}
+			}
+			assert.Equal(t, []resourceID{
+				{groupResource: kuberesource.PersistentVolumes.String(), nsAndName: "/pv-1"},
+				{groupResource: kuberesource.PersistentVolumeClaims.String(), nsAndName: "ns-2/pvc-1"},
+			}, creates)
+
+			res, err := h.DynamicClient.Resource(test.PVs().GVR()).Get("pv-1", metav1.GetOptions{})
+			require.NoError(t, err)
+			restoredPV := new(corev1api.PersistentVolume)
+			require.NoError(t, runtime.DefaultUnstructuredConverter.FromUnstructured(res.Object, restoredPV))
+			assert.Equal(t, tc.wantClaimRef, restoredPV.Spec.ClaimRef)
+
+			if tc.rejectClaim {
+				return
+			}
+			res, err = h.DynamicClient.Resource(test.PVCs().GVR()).Namespace("ns-2").Get("pvc-1", metav1.GetOptions{})
+			require.NoError(t, err)
+			volumeName, _, _ := unstructured.NestedString(res.Object, "spec", "volumeName")
+			assert.Equal(t, "pv-1", volumeName)
+		})
+	}
+}
+
+// TestRestoreCreateRetries runs restores whose item creates fail with different errors,
+// and verifies that only creates failing with transient errors are retried, up to the
+// restorer's maximum number of attempts, and that creates of items with a generated
+// name aren't retried after timing out.
+func TestRestoreCreateRetries(t *testing.T) {
+	configMaps := schema.GroupResource{Resource: "configmaps"}
+	conflict := apierrors.NewConflict(configMaps, "cm-1", errors.New("the object has been modified"))
+
+	tests := []struct {
+		name         string
+		inCluster    bool
+		generateName bool
+		failures     []error
+		wantCreates  int
+		wantOutcome  ItemOutcome
+		wantErrs     int
+	}{
+		{
+			name:        "an item whose create conflicts twice is created on the third attempt",
+			failures:    []error{conflict, conflict},
+			wantCreates: 3,
+			wantOutcome: ItemOutcomeCreated,
+		},
+		{
+			name: "an item whose create times out and is throttled is created on the third attempt",
+			failures: []error{
+				apierrors.NewServerTimeout(configMaps, "create", 1),
+				apierrors.NewTooManyRequests("too many requests", 1),
+			},
+			wantCreates: 3,
+			wantOutcome: ItemOutcomeCreated,
+		},
+		{
+			name:        "an item whose create keeps conflicting fails after the maximum number of attempts",
+			failures:    []error{conflict, conflict, conflict},
+			wantCreates: 3,
+			wantOutcome: ItemOutcomeFailed,
+			wantErrs:    1,
+		},
+		{
+			name: "an invalid item's create isn't retried",
+			failures: []error{
+				apierrors.NewInvalid(schema.GroupKind{Kind: "ConfigMap"}, "cm-1", field.ErrorList{field.Required(field.NewPath("data"), "")}),
+			},
+			wantCreates: 1,
+			wantOutcome: ItemOutcomeFailed,
+			wantErrs:    1,
+		},
+		{
+			name:        "an item that already exists isn't created again",
+			inCluster:   true,
+			wantCreates: 1,
+			wantOutcome: ItemOutcomeSkipped,
+		},
+		{
+			name:         "an item with a generated name whose create times out isn't created again",
+			generateName: true,
+			failures:     []error{apierrors.NewServerTimeout(configMaps, "create", 1)},
+			wantCreates:  1,
+			wantOutcome:  ItemOutcomeFailed,
+			wantErrs:     1,
+		},
+		{
+			name:         "an item with a generated name whose create conflicts is created on the second attempt",
+			generateName: true,
+			failures:     []error{conflict},
+			wantCreates:  2,
+			wantOutcome:  ItemOutcomeCreated,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.restorer.createRetryPolicy = CreateRetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+
+			cm := test.NewConfigMap("ns-1", "cm-1")
+			if tc.generateName {
+				cm = test.NewConfigMap("ns-1", "", func(obj metav1.Object) { obj.SetGenerateName("cm-") })
+			}
+			if tc.inCluster {
+				h.addItems(t, test.ConfigMaps(cm))
+			} else {
+				h.addItems(t, test.ConfigMaps())
+			}
+
+			var creates int
+			h.DynamicClient.PrependReactor("create", "configmaps", func(kubetesting.Action) (bool, runtime.Object, error) {
+				creates++
+				if creates <= len(tc.failures) {
+					return true, nil, tc.failures[creates-1]
+				}
+				return false, nil, nil
+			})
+
+			tarball := newTarWriter(t).
+				add("resources/configmaps/namespaces/ns-1/cm-1.json", cm).
+				done()
+
+			warnings, errs, results := h.restorer.Restore(
+				h.log,
+				defaultRestore().GenerateNamePolicy(velerov1api.GenerateNamePolicyGenerate).Restore(),
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, warnings)
+			assert.Len(t, errs.Namespaces["ns-1"], tc.wantErrs)
+			assert.Equal(t, tc.wantCreates, creates)
+			require.Len(t, results, 1)
+			assert.Equal(t, tc.wantOutcome, results[0].Outcome)
+		})
+	}
+}
+
+// TestRestoreIncludedResourceNames runs restores of a backup with several pods and
+// configmaps with included resource names for pods, and verifies that only the
+// included pods, matched by name or by their backed-up or remapped namespace and
+// name, are created, while configmaps are restored in full.
+func TestRestoreIncludedResourceNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		restore  *velerov1api.Restore
+		wantPods []string
+	}{
+		{
+			name:     "a name includes the pods with that name in any namespace",
+			restore:  defaultRestore().IncludedResourceNames("pods", "pod-2").Restore(),
+			wantPods: []string{"ns-1/pod-2", "ns-2/pod-2"},
+		},
+		{
+			name:     "a namespace-qualified name includes only the pod in that namespace",
+			restore:  defaultRestore().IncludedResourceNames("pods", "ns-2/pod-2").Restore(),
+			wantPods: []string{"ns-2/pod-2"},
+		},
+		{
+			name: "a name qualified with the backed-up namespace includes the pod restored into its mapped namespace",
+			restore: defaultRestore().
+				NamespaceMappings("ns-1", "ns-3").
+				IncludedResourceNames("pods", "ns-1/pod-1").
+				Restore(),
+			wantPods: []string{"ns-3/pod-1"},
+		},
+		{
+			name: "a name qualified with the mapped namespace includes the pod restored into it",
+			restore: defaultRestore().
+				NamespaceMappings("ns-1", "ns-3").
+				IncludedResourceNames("pods", "ns-3/pod-1").
+				Restore(),
+			wantPods: []string{"ns-3/pod-1"},
+		},
+		{
+			name:     "an empty list includes all pods",
+			restore:  defaultRestore().IncludedResourceNames("pods").Restore(),
+			wantPods: []string{"ns-1/pod-1", "ns-1/pod-2", "ns-1/pod-3", "ns-2/pod-2"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+
+			recorder := &createRecorder{t: t}
+			h.DynamicClient.PrependReactor("create", "*", recorder.reactor())
+
+			h.DiscoveryClient.WithAPIResource(test.Pods())
+			h.DiscoveryClient.WithAPIResource(test.ConfigMaps())
+			require.NoError(t, h.restorer.discoveryHelper.Refresh())
+
+			tarball := newTarWriter(t).
+				addItems("pods",
+					test.NewPod("ns-1", "pod-1"),
+					test.NewPod("ns-1", "pod-2"),
+					test.NewPod("ns-1", "pod-3"),
+					test.NewPod("ns-2", "pod-2"),
+				).
+				addItems("configmaps", test.NewConfigMap("ns-1", "cm-1")).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, warnings, errs)
+
+			var pods, configMaps []string
+			for _, res := range recorder.resources {
+				switch res.groupResource {
+				case "pods":
+					pods = append(pods, res.nsAndName)
+				case "configmaps":
+					configMaps = append(configMaps, res.nsAndName)
+				}
+			}
+			assert.ElementsMatch(t, tc.wantPods, pods)
+			assert.Len(t, configMaps, 1)
+		})
+	}
+}
+
+// recordingPreRestoreBackupper is a PreRestoreBackupper that records the namespaces it's
+// asked to back up, along with how many items had been created when it was called.
+type recordingPreRestoreBackupper struct {
+	recorder   *createRecorder
+	namespaces [][]string
+	creates    []int
+	err        error
+}
+
+func (b *recordingPreRestoreBackupper) BackupNamespaces(restore *velerov1api.Restore, namespaces []string) error {
+	b.namespaces = append(b.namespaces, namespaces)
+	b.creates = append(b.creates, len(b.recorder.resources))
+	return b.err
+}
+
+// TestRestorePreRestoreBackup runs restores of pods into mapped namespaces with and without
+// a pre-restore backup, and verifies that the target namespaces are backed up before any
+// item is created, and that nothing is restored if the backup fails.
+func TestRestorePreRestoreBackup(t *testing.T) {
+	tests := []struct {
+		name           string
+		restore        *velerov1api.Restore
+		backupErr      error
+		wantNamespaces [][]string
+		wantErrs       int
+		wantPods       int
+	}{
+		{
+			name: "the restore's target namespaces are backed up before items are created",
+			restore: defaultRestore().
+				PreRestoreBackup(true).
+				NamespaceMappings("ns-1", "ns-4").
+				Restore(),
+			wantNamespaces: [][]string{{"ns-2", "ns-3", "ns-4"}},
+			wantPods:       3,
+		},
+		{
+			name:           "nothing is restored if the pre-restore backup fails",
+			restore:        defaultRestore().PreRestoreBackup(true).Restore(),
+			backupErr:      errors.New("backup failed"),
+			wantNamespaces: [][]string{{"ns-1", "ns-2", "ns-3"}},
+			wantErrs:       1,
+		},
+		{
+			name:     "nothing is backed up without a pre-restore backup",
+			restore:  defaultRestore().Restore(),
+			wantPods: 3,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+
+			recorder := &createRecorder{t: t}
+			h.DynamicClient.PrependReactor("create", "*", recorder.reactor())
+
+			backupper := &recordingPreRestoreBackupper{recorder: recorder, err: tc.backupErr}
+			h.restorer.preRestoreBackupper = backupper
+
+			h.DiscoveryClient.WithAPIResource(test.Pods())
+			require.NoError(t, h.restorer.discoveryHelper.Refresh())
+
+			tarball := newTarWriter(t).
+				addItems("pods",
+					test.NewPod("ns-1", "pod-1"),
+					test.NewPod("ns-2", "pod-2"),
+					test.NewPod("ns-3", "pod-3"),
+				).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, warnings)
+			assert.Len(t, errs.Velero, tc.wantErrs)
+			assert.Equal(t, tc.wantNamespaces, backupper.namespaces)
+			for _, creates := range backupper.creates {
+				assert.Zero(t, creates)
+			}
+
+			var pods int
+			for _, res := range recorder.resources {
+				if res.groupResource == "pods" {
+					pods++
+				}
+			}
+			assert.Equal(t, tc.wantPods, pods)
+		})
+	}
+}
+
+// TestRestoreKubeconfigRewrites runs a restore of secrets holding a kubeconfig and other
+// data with kubeconfig rewrites, and verifies that only the kubeconfig is rewritten.
+func TestRestoreKubeconfigRewrites(t *testing.T) {
+	kubeconfig := `apiVersion: v1
+kind: Config
+clusters:
+- name: old-cluster
+  cluster:
+    server: https://old.example.com:6443
+contexts:
+- name: old-context
+  context:
+    cluster: old-cluster
+    user: admin
+current-context: old-context
+users:
+- name: admin
+  user:
+    token: abc
+`
+	binary := []byte{0x00, 0xff, 0x10, 0x80}
+
+	h := newHarness(t)
+	h.addItems(t, test.Secrets())
+
+	tarball := newTarWriter(t).
+		addItems("secrets",
+			test.NewSecret("ns-1", "kubeconfig", func(obj metav1.Object) {
+				obj.(*corev1api.Secret).Data = map[string][]byte{
+					"kubeconfig": []byte(kubeconfig),
+					"other":      binary,
+				}
+			}),
+			test.NewSecret("ns-1", "binary", func(obj metav1.Object) {
+				obj.(*corev1api.Secret).Data = map[string][]byte{"config": binary}
+			}),
+		).
+		done()
+
+	warnings, errs, _ := h.restorer.Restore(
+		h.log,
+		defaultRestore().KubeconfigRewrites(velerov1api.KubeconfigRewrites{
+			Servers:      map[string]string{"https://old.example.com:6443": "https://new.example.com:6443"},
+			ClusterNames: map[string]string{"old-cluster": "new-cluster"},
+			ContextNames: map[string]string{"old-context": "new-context"},
+		}).Restore(),
+		defaultBackup().Backup(),
+		nil, // volume snapshots
+		tarball,
+		nil, // actions
+		nil, // snapshot location lister
+		nil, // volume snapshotter getter
+	)
+
+	assertEmptyResults(t, warnings, errs)
+
+	getSecret := func(name string) *corev1api.Secret {
+		res, err := h.DynamicClient.Resource(test.Secrets().GVR()).Namespace("ns-1").Get(name, metav1.GetOptions{})
+		require.NoError(t, err)
+
+		secret := new(corev1api.Secret)
+		require.NoError(t, runtime.DefaultUnstructuredConverter.FromUnstructured(res.Object, secret))
+		return secret
+	}
+
+	secret := getSecret("kubeconfig")
+	assert.Equal(t, binary, secret.Data["other"])
+
+	config := make(map[string]interface{})
+	require.NoError(t, yaml.Unmarshal(secret.Data["kubeconfig"], &config))
+
+	server, _, _ := unstructured.NestedString(config["clusters"].([]interface{})[0].(map[string]interface{}), "cluster", "server")
+	assert.Equal(t, "https://new.example.com:6443", server)
+	assert.Equal(t, "new-cluster", config["clusters"].([]interface{})[0].(map[string]interface{})["name"])
+
+	kubeContext := config["contexts"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "new-context", kubeContext["name"])
+	contextCluster, _, _ := unstructured.NestedString(kubeContext, "context", "cluster")
+	assert.Equal(t, "new-cluster", contextCluster)
+	assert.Equal(t, "new-context", config["current-context"])
+
+	// the rest of the kubeconfig is kept
+	token, _, _ := unstructured.NestedString(config["users"].([]interface{})[0].(map[string]interface{}), "user", "token")
+	assert.Equal(t, "abc", token)
+
+	// data that isn't a kubeconfig is unchanged, even under a kubeconfig key
+	assert.Equal(t, binary, getSecret("binary").Data["config"])
+}
+
+// TestRestoreGenerateNamePolicy runs restores of items that have a generate name but
+// no name, and verifies that they're created with a generated name and recorded under it
+// when the restore's generate name policy allows it, and are errors otherwise.
+func TestRestoreGenerateNamePolicy(t *testing.T) {
+	newTarball := func() io.Reader {
+		return newTarWriter(t).
+			add("resources/configmaps/namespaces/ns-1/cm-abcde.json", test.NewConfigMap("ns-1", "", func(obj metav1.Object) {
+				obj.SetGenerateName("cm-")
+			})).
+			addItems("configmaps", test.NewConfigMap("ns-1", "cm-1")).
+			done()
+	}
+
+	noNameErr := `configmaps with generate name "cm-" in namespace "ns-1" has no name. Restore with the Generate generate name policy to create it with a generated name`
+
+	tests := []struct {
+		name        string
+		policy      velerov1api.GenerateNamePolicy
+		want        []string
+		wantResults ItemResults
+		wantErrs    int
+	}{
+		{
+			name:   "items are created with a generated name when the policy is Generate",
+			policy: velerov1api.GenerateNamePolicyGenerate,
+			want:   []string{"ns-1/cm-1", "ns-1/cm-00001"},
+			wantResults: ItemResults{
+				{GroupResource: "configmaps", Namespace: "ns-1", Name: "cm-00001", Outcome: ItemOutcomeCreated, Action: ItemActionCreated},
+				{GroupResource: "configmaps", Namespace: "ns-1", Name: "cm-1", Outcome: ItemOutcomeCreated, Action: ItemActionCreated},
+			},
+		},
+		{
+			name:   "items are errors when the policy is Error",
+			policy: velerov1api.GenerateNamePolicyError,
+			want:   []string{"ns-1/cm-1"},
+			wantResults: ItemResults{
+				{GroupResource: "configmaps", Namespace: "ns-1", Name: "cm-", Outcome: ItemOutcomeFailed, Action: ItemActionFailed, Reason: noNameErr},
+				{GroupResource: "configmaps", Namespace: "ns-1", Name: "cm-1", Outcome: ItemOutcomeCreated, Action: ItemActionCreated},
+			},
+			wantErrs: 1,
+		},
+		{
+			name: "items are errors when the policy is empty",
+			want: []string{"ns-1/cm-1"},
+			wantResults: ItemResults{
+				{GroupResource: "configmaps", Namespace: "ns-1", Name: "cm-", Outcome: ItemOutcomeFailed, Action: ItemActionFailed, Reason: noNameErr},
+				{GroupResource: "configmaps", Namespace: "ns-1", Name: "cm-1", Outcome: ItemOutcomeCreated, Action: ItemActionCreated},
+			},
+			wantErrs: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.ConfigMaps())
+
+			// the fake dynamic client doesn't generate names, so
+			// assign one the way the API server would
+			var generated int
+			h.DynamicClient.PrependReactor("create", "configmaps", func(action kubetesting.Action) (bool, runtime.Object, error) {
+				accessor, err := meta.Accessor(action.(kubetesting.CreateAction).GetObject())
+				require.NoError(t, err)
+				if accessor.GetName() == "" && accessor.GetGenerateName() != "" {
+					generated++
+					accessor.SetName(fmt.Sprintf("%s%05d", accessor.GetGenerateName(), generated))
+				}
+				return false, nil, nil
+			})
+
+			warnings, errs, itemResults := h.restorer.Restore(
+				h.log,
+				defaultRestore().GenerateNamePolicy(tc.policy).Restore(),
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				newTarball(),
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assert.Empty(t, warnings.Velero)
+			assert.Empty(t, warnings.Cluster)
+			assert.Empty(t, warnings.Namespaces)
+			assert.Len(t, errs.Namespaces["ns-1"], tc.wantErrs)
+
+			assertAPIContents(t, h, map[*test.APIResource][]string{
+				test.ConfigMaps(): tc.want,
+			})
+			assert.ElementsMatch(t, tc.wantResults, itemResults)
+		})
+	}
+}
+
+// gatherMetrics returns the metric families gathered from the provided registry, keyed by name.
+func gatherMetrics(t *testing.T, registry *prometheus.Registry) map[string]*dto.MetricFamily {
+	t.Helper()
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	res := make(map[string]*dto.MetricFamily, len(families))
+	for _, family := range families {
+		res[family.GetName()] = family
+	}
+	return res
+}
+
+// TestRestoreMetrics runs a restore of a persistent volume from a snapshot and of config maps,
+// one of which already exists in the cluster, and verifies the metrics recorded in the
+// restorer's registry.
+func TestRestoreMetrics(t *testing.T) {
+	h := newHarness(t)
+	h.addItems(t, test.PVs())
+	h.addItems(t, test.ConfigMaps(test.NewConfigMap("ns-1", "cm-2", func(obj metav1.Object) {
+		obj.(*corev1api.ConfigMap).Data = map[string]string{"key": "in-cluster"}
+	})))
+
+	registry := prometheus.NewRegistry()
+	var err error
+	h.restorer.metrics, err = newRestoreMetrics(registry)
+	require.NoError(t, err)
+
+	locationsInformer := informers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0).Velero().V1().VolumeSnapshotLocations()
+	require.NoError(t, locationsInformer.Informer().GetStore().Add(
+		testutil.NewTestVolumeSnapshotLocation().WithName("loc-1").WithProvider("provider-1").VolumeSnapshotLocation,
+	))
+
+	tarball := newTarWriter(t).
+		addItems("persistentvolumes", test.NewPV("pv-1", func(obj metav1.Object) {
+			obj.(*corev1api.PersistentVolume).Spec.Capacity = corev1api.ResourceList{
+				corev1api.ResourceStorage: resource.MustParse("1Gi"),
+			}
+		})).
+		addItems("configmaps",
+			test.NewConfigMap("ns-1", "cm-1"),
+			test.NewConfigMap("ns-1", "cm-2"),
+		).
+		done()
+
+	warnings, errs, _ := h.restorer.Restore(
+		h.log,
+		defaultRestore().Restore(),
+		defaultBackup().Backup(),
+		[]*volume.Snapshot{newSnapshot("pv-1", "loc-1", "type-1", "az-1", "snap-1", 1)},
+		tarball,
+		nil, // actions
+		locationsInformer.Lister(),
+		providerToVolumeSnapshotterMap{"provider-1": &testutil.FakeVolumeSnapshotter{}},
+	)
+
+	assertEmptyResults(t, errs)
+	assert.Len(t, warnings.Namespaces["ns-1"], 1)
+
+	families := gatherMetrics(t, registry)
+
+	items := make(map[string]float64)
+	for _, metric := range families["velero_restore_items_total"].GetMetric() {
+		labels := make(map[string]string)
+		for _, label := range metric.GetLabel() {
+			labels[label.GetName()] = label.GetValue()
+		}
+		items[labels[resourceLabel]+"/"+labels[outcomeLabel]] += metric.GetCounter().GetValue()
+	}
+	assert.Equal(t, map[string]float64{
+		"persistentvolumes/" + string(ItemOutcomeCreated): 1,
+		"configmaps/" + string(ItemOutcomeCreated):        1,
+		"configmaps/" + string(ItemOutcomeSkipped):        1,
+	}, items)
+
+	// countsByResource returns the values of the provided family's counters, by resource
+	countsByResource := func(family *dto.MetricFamily) map[string]float64 {
+		counts := make(map[string]float64)
+		for _, metric := range family.GetMetric() {
+			counts[metric.GetLabel()[0].GetValue()] += metric.GetCounter().GetValue()
+		}
+		return counts
+	}
+	assert.Equal(t, map[string]float64{"persistentvolumes": 1, "configmaps": 1}, countsByResource(families["velero_restore_items_restored_total"]))
+	assert.Equal(t, map[string]float64{"configmaps": 1}, countsByResource(families["velero_restore_items_skipped_total"]))
+	assert.Empty(t, countsByResource(families["velero_restore_items_failed_total"]))
+
+	assert.Equal(t, float64(0), families["velero_restore_errors_total"].GetMetric()[0].GetCounter().GetValue())
+	assert.Equal(t, float64(1<<30), families["velero_restore_volume_bytes_total"].GetMetric()[0].GetCounter().GetValue())
+
+	durations := make(map[string]uint64)
+	for _, metric := range families["velero_restore_resource_duration_seconds"].GetMetric() {
+		durations[metric.GetLabel()[0].GetValue()] = metric.GetHistogram().GetSampleCount()
+	}
+	assert.Equal(t, map[string]uint64{"persistentvolumes": 1, "configmaps": 1}, durations)
+
+	assert.Equal(t, uint64(1), families["velero_restore_duration_seconds"].GetMetric()[0].GetHistogram().GetSampleCount())
+}
+
+// TestNewRestoreMetrics verifies that restore metrics can be created without a registerer,
+// and that registering them twice with the same registerer is an error.
+func TestNewRestoreMetrics(t *testing.T) {
+	metrics, err := newRestoreMetrics(nil)
+	require.NoError(t, err)
+	metrics.observeItem(kuberesource.Pods, ItemOutcomeCreated)
+
+	registry := prometheus.NewRegistry()
+	_, err = newRestoreMetrics(registry)
+	require.NoError(t, err)
+	_, err = newRestoreMetrics(registry)
+	assert.Error(t, err)
+}
+
+func assertRestoredItems(t *testing.T, h *harness, want []*test.APIResource) {
+	t.Helper()
+
+	for _, resource := range want {
+		resourceClient := h.DynamicClient.Resource(resource.GVR())
+		for _, item := range resource.Items {
+			var client dynamic.ResourceInterface
+			if item.GetNamespace() != "" {
+				client = resourceClient.Namespace(item.GetNamespace())
+			} else {
+				client = resourceClient
+			}
+
+			res, err := client.Get(item.GetName(), metav1.GetOptions{})
+			if !assert.NoError(t, err) {
+				continue
+			}
+
+			itemJSON, err := json.Marshal(item)
+			if !assert.NoError(t, err) {
+				continue
+			}
+
+			t.Logf("%v", string(itemJSON))
+
+			u := make(map[string]interface{})
+			if !assert.NoError(t, json.Unmarshal(itemJSON, &u)) {
+				continue
+			}
+			want := &unstructured.Unstructured{Object: u}
+
+			// These fields get non-nil zero values in the unstructured objects if they're
+			// empty in the structured objects. Remove them to make comparison easier.
+			unstructured.RemoveNestedField(want.Object, "metadata", "creationTimestamp")
+			unstructured.RemoveNestedField(want.Object, "status")
+
+			assert.Equal(t, want, res)
+		}
+	}
+}
+
+// assertResourceCreationOrder ensures that resources were created in the expected
+// order. Any resources *not* in resourcePriorities are required to come *after* all
+// resources in any order.
+func assertResourceCreationOrder(t *testing.T, resourcePriorities []string, createdResources []resourceID) {
+	// lastSeen tracks the index in 'resourcePriorities' of the last resource type
+	// we saw created. Once we've seen a resource in 'resourcePriorities', we should
+	// never see another instance of a prior resource.
+	lastSeen := 0
+
+	// Find the index in 'resourcePriorities' of the resource type for
+	// the current item, if it exists. This index ('current') *must*
+	// be greater than or equal to 'lastSeen', which was the last resource
+	// we saw, since otherwise the current resource would be out of order. By
+	// initializing current to len(ordered), we're saying that if the resource
+	// is not explicitly in orderedResources, then it must come *after*
+	// all orderedResources.
+	for _, r := range createdResources {
+		current := len(resourcePriorities)
+		for i, item := range resourcePriorities {
+			if item == r.groupResource {
+				current = i
+				break
+			}
+		}
+
+		// the index of the current resource must be the same as or greater than the index of
+		// the last resource we saw for the restored order to be correct.
+		assert.True(t, current >= lastSeen, "%s was restored out of order", r.groupResource)
+		lastSeen = current
+	}
+}
+
+// TestRestoreSkipRestoreLabels runs restores with and without the restore labels, and
+// verifies that restored items only carry the restore and backup name labels when the
+// restore doesn't skip them.
+func TestRestoreSkipRestoreLabels(t *testing.T) {
+	tests := []struct {
+		name       string
+		restore    *velerov1api.Restore
+		wantLabels map[string]string
+	}{
+		{
+			name:    "items are labeled with the restore and backup names by default",
+			restore: defaultRestore().Restore(),
+			wantLabels: map[string]string{
+				"app":                        "web",
+				velerov1api.BackupNameLabel:  "backup-1",
+				velerov1api.RestoreNameLabel: "restore-1",
+			},
+		},
+		{
+			name:       "items aren't labeled when the restore skips the restore labels",
+			restore:    defaultRestore().SkipRestoreLabels(true).Restore(),
+			wantLabels: map[string]string{"app": "web"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.Pods())
+
+			tarball := newTarWriter(t).
+				addItems("pods", test.NewPod("ns-1", "pod-1", test.WithLabels("app", "web"))).
+				done()
+
+			// the label selector still matches the backed-up labels
+			restore := tc.restore.DeepCopy()
+			restore.Spec.LabelSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, warnings, errs)
+
+			res, err := h.DynamicClient.Resource(test.Pods().GVR()).Namespace("ns-1").Get("pod-1", metav1.GetOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantLabels, res.GetLabels())
+		})
+	}
+}
+
+// TestRestoreExtraLabels runs restores with extra labels, and verifies that restored
+// items get the extra labels, and that labels they already have in the backup keep
+// their values unless the restore overwrites existing labels.
+func TestRestoreExtraLabels(t *testing.T) {
+	tests := []struct {
+		name       string
+		restore    *velerov1api.Restore
+		wantLabels map[string]string
+	}{
+		{
+			name:    "extra labels are added without replacing existing labels",
+			restore: defaultRestore().ExtraLabels("company.io/restored-at", "2019-07-01", "env", "dr", "app", "db").Restore(),
+			wantLabels: map[string]string{
+				"app":                        "web",
+				"company.io/restored-at":     "2019-07-01",
+				"env":                        "dr",
+				velerov1api.BackupNameLabel:  "backup-1",
+				velerov1api.RestoreNameLabel: "restore-1",
+			},
+		},
+		{
+			name:    "extra labels replace existing labels when the restore overwrites them",
+			restore: defaultRestore().ExtraLabels("env", "dr", "app", "db").OverwriteExistingLabels(true).Restore(),
+			wantLabels: map[string]string{
+				"app":                        "db",
+				"env":                        "dr",
+				velerov1api.BackupNameLabel:  "backup-1",
+				velerov1api.RestoreNameLabel: "restore-1",
+			},
+		},
+		{
+			name:    "extra labels don't replace the restore and backup name labels",
+			restore: defaultRestore().ExtraLabels(velerov1api.RestoreNameLabel, "other").OverwriteExistingLabels(true).Restore(),
+			wantLabels: map[string]string{
+				"app":                        "web",
+				velerov1api.BackupNameLabel:  "backup-1",
+				velerov1api.RestoreNameLabel: "restore-1",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.Pods())
+
+			tarball := newTarWriter(t).
+				addItems("pods", test.NewPod("ns-1", "pod-1", test.WithLabels("app", "web"))).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, warnings, errs)
+
+			res, err := h.DynamicClient.Resource(test.Pods().GVR()).Namespace("ns-1").Get("pod-1", metav1.GetOptions{})
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantLabels, res.GetLabels())
+		})
+	}
+}
+
+// TestRestoreGzipItemFiles runs restores of a backup with an uncompressed item file and
+// of a backup with the same item gzip-compressed, and verifies that the item is restored
+// identically from both.
+func TestRestoreGzipItemFiles(t *testing.T) {
+	pod := test.NewPod("ns-1", "pod-1", test.WithLabels("app", "web"))
+
+	data, err := encode.Encode(pod, "json")
+	require.NoError(t, err)
+
+	compressed := new(bytes.Buffer)
+	gzw := gzip.NewWriter(compressed)
+	_, err = gzw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, gzw.Close())
+
+	restorePod := func(tarball io.Reader) *unstructured.Unstructured {
+		h := newHarness(t)
+		h.addItems(t, test.Pods())
+
+		warnings, errs, _ := h.restorer.Restore(
+			h.log,
+			defaultRestore().Restore(),
+			defaultBackup().Backup(),
+			nil, // volume snapshots
+			tarball,
+			nil, // actions
+			nil, // snapshot location lister
+			nil, // volume snapshotter getter
+		)
+		assertEmptyResults(t, warnings, errs)
+
+		res, err := h.DynamicClient.Resource(test.Pods().GVR()).Namespace("ns-1").Get("pod-1", metav1.GetOptions{})
+		require.NoError(t, err)
+		return res
+	}
+
+	uncompressed := restorePod(newTarWriter(t).addItems("pods", pod).done())
+	fromGzip := restorePod(newTarWriter(t).add("resources/pods/namespaces/ns-1/pod-1.json.gz", compressed.Bytes()).done())
+
+	assert.Equal(t, uncompressed, fromGzip)
+	assert.Equal(t, "web", fromGzip.GetLabels()["app"])
+}
+
+// fakeReplay stores the items a restore captures for its replay in memory, and
+// reads them back for a replay.
+type fakeReplay struct {
+	items  map[string][]byte
+	closed bool
+}
+
+func (r *fakeReplay) NewReplayWriter(*velerov1api.Restore) (ReplayWriter, error) {
+	return r, nil
+}
+
+func (r *fakeReplay) PutReplayItem(path string, contents io.Reader) error {
+	data, err := ioutil.ReadAll(contents)
+	if err != nil {
+		return err
+	}
+	if r.items == nil {
+		r.items = make(map[string][]byte)
+	}
+	r.items[path] = data
+	return nil
+}
+
+func (r *fakeReplay) Close() {
+	r.closed = true
+}
+
+func (r *fakeReplay) ListReplayItems() ([]string, error) {
+	var paths []string
+	for path := range r.items {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func (r *fakeReplay) GetReplayItem(path string) (io.ReadCloser, error) {
+	data, ok := r.items[path]
+	if !ok {
+		return nil, errors.Errorf("replay item %s not found", path)
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// TestRestoreCaptureReplay runs restores of a PV and a PVC, and verifies that the items
+// are stored, as they were created, for the restore's replay if it captures one.
+func TestRestoreCaptureReplay(t *testing.T) {
+	tests := []struct {
+		name    string
+		restore *velerov1api.Restore
+		want    []string
+	}{
+		{
+			name:    "the created items are stored when the restore captures a replay",
+			restore: defaultRestore().CaptureReplay(true).Restore(),
+			want:    []string{"persistentvolumeclaims/namespaces/ns-1/pvc-1.json", "persistentvolumes/cluster/pv-1.json"},
+		},
+		{
+			name:    "nothing is stored when the restore doesn't capture a replay",
+			restore: defaultRestore().Restore(),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.PVs())
+			h.addItems(t, test.PVCs())
+
+			replay := new(fakeReplay)
+			h.restorer.replayWriterFactory = replay
+
+			tarball := newTarWriter(t).
+				addItems("persistentvolumes", test.NewPV("pv-1")).
+				addItems("persistentvolumeclaims", test.NewPVC("ns-1", "pvc-1")).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				tc.restore,
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, warnings, errs)
+
+			paths, err := replay.ListReplayItems()
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, paths)
+			assert.Equal(t, tc.want != nil, replay.closed)
+
+			for _, path := range paths {
+				obj := new(unstructured.Unstructured)
+				require.NoError(t, json.Unmarshal(replay.items[path], obj))
+				assert.Equal(t, "restore-1", obj.GetLabels()[velerov1api.RestoreNameLabel])
+			}
+		})
+	}
+}
+
+// TestRestoreReplay replays the items a restore of a PV and a PVC captured into a new
+// cluster, and verifies that they're re-created in priority order.
+func TestRestoreReplay(t *testing.T) {
+	captured := newHarness(t)
+	captured.addItems(t, test.PVs())
+	captured.addItems(t, test.PVCs())
+
+	replay := new(fakeReplay)
+	captured.restorer.replayWriterFactory = replay
+
+	tarball := newTarWriter(t).
+		addItems("persistentvolumes", test.NewPV("pv-1")).
+		addItems("persistentvolumeclaims", test.NewPVC("ns-1", "pvc-1")).
+		done()
+
+	warnings, errs, _ := captured.restorer.Restore(captured.log, defaultRestore().CaptureReplay(true).Restore(), defaultBackup().Backup(), nil, tarball, nil, nil, nil)
+	assertEmptyResults(t, warnings, errs)
+
+	h := newHarness(t)
+	h.addItems(t, test.PVs())
+	h.addItems(t, test.PVCs())
+	h.restorer.resourcePriorities = []string{"persistentvolumes", "persistentvolumeclaims"}
+
+	recorder := &createRecorder{t: t}
+	h.DynamicClient.PrependReactor("create", "*", recorder.reactor())
+
+	restore := NewNamedBuilder(velerov1api.DefaultNamespace, "restore-2").Backup("backup-1").ReplayRestoreName("restore-1").Restore()
+	warnings, errs, itemResults := h.restorer.Replay(h.log, restore, replay)
+	assertEmptyResults(t, warnings, errs)
+
+	assert.Equal(t, []resourceID{
+		{groupResource: "persistentvolumes", nsAndName: "/pv-1"},
+		{groupResource: "persistentvolumeclaims", nsAndName: "ns-1/pvc-1"},
+	}, recorder.resources)
+	assert.Equal(t, ItemResults{
+		{GroupResource: "persistentvolumes", Name: "pv-1", Outcome: ItemOutcomeCreated, Action: ItemActionCreated, Reason: replayedReason},
+		{GroupResource: "persistentvolumeclaims", Namespace: "ns-1", Name: "pvc-1", Outcome: ItemOutcomeCreated, Action: ItemActionCreated, Reason: replayedReason},
+	}, itemResults)
+
+	pvc, err := h.DynamicClient.Resource(test.PVCs().GVR()).Namespace("ns-1").Get("pvc-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "restore-1", pvc.GetLabels()[velerov1api.RestoreNameLabel])
+}
+
+// TestRestoreReplayGeneratedName replays a config map that a restore created with a
+// generated name, and verifies that it's re-created under the name it was given.
+func TestRestoreReplayGeneratedName(t *testing.T) {
+	captured := newHarness(t)
+	captured.addItems(t, test.ConfigMaps())
+
+	// the fake dynamic client doesn't generate names, so
+	// assign one the way the API server would
+	captured.DynamicClient.PrependReactor("create", "configmaps", func(action kubetesting.Action) (bool, runtime.Object, error) {
+		accessor, err := meta.Accessor(action.(kubetesting.CreateAction).GetObject())
+		require.NoError(t, err)
+		if accessor.GetName() == "" && accessor.GetGenerateName() != "" {
+			accessor.SetName(accessor.GetGenerateName() + "00001")
+		}
+		return false, nil, nil
+	})
+
+	replay := new(fakeReplay)
+	captured.restorer.replayWriterFactory = replay
+
+	tarball := newTarWriter(t).
+		add("resources/configmaps/namespaces/ns-1/cm-abcde.json", test.NewConfigMap("ns-1", "", func(obj metav1.Object) {
+			obj.SetGenerateName("cm-")
+		})).
+		done()
+
+	restore := defaultRestore().CaptureReplay(true).GenerateNamePolicy(velerov1api.GenerateNamePolicyGenerate).Restore()
+	warnings, errs, _ := captured.restorer.Restore(captured.log, restore, defaultBackup().Backup(), nil, tarball, nil, nil, nil)
+	assertEmptyResults(t, warnings, errs)
+
+	paths, err := replay.ListReplayItems()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"configmaps/namespaces/ns-1/cm-00001.json"}, paths)
+
+	h := newHarness(t)
+	h.addItems(t, test.ConfigMaps())
+
+	warnings, errs, itemResults := h.restorer.Replay(h.log, NewNamedBuilder(velerov1api.DefaultNamespace, "restore-2").Backup("backup-1").ReplayRestoreName("restore-1").Restore(), replay)
+	assertEmptyResults(t, warnings, errs)
+
+	assert.Equal(t, ItemResults{
+		{GroupResource: "configmaps", Namespace: "ns-1", Name: "cm-00001", Outcome: ItemOutcomeCreated, Action: ItemActionCreated, Reason: replayedReason},
+	}, itemResults)
+	assertAPIContents(t, h, map[*test.APIResource][]string{
+		test.ConfigMaps(): {"ns-1/cm-00001"},
+	})
+}
+
+// TestRestoreNameRewrite runs restores of a persistent volume, the claim bound to it and
+// a pod that mounts the claim with different name rewrites, and verifies that the items
+// are renamed and their references point at the renamed items.
+func TestRestoreNameRewrite(t *testing.T) {
+	tests := []struct {
+		name      string
+		rewrite   *velerov1api.NameRewrite
+		wantPV    string
+		wantPVC   string
+		wantPod   string
+		wantClaim string
+	}{
+		{
+			name:      "a suffix renames all items and their references",
+			rewrite:   &velerov1api.NameRewrite{Suffix: "-restored"},
+			wantPV:    "pv-1-restored",
+			wantPVC:   "pvc-1-restored",
+			wantPod:   "pod-1-restored",
+			wantClaim: "pvc-1-restored",
+		},
+		{
+			name:      "a prefix is applied to the name the template produces",
+			rewrite:   &velerov1api.NameRewrite{Prefix: "dr-", Template: "{{if .Namespace}}{{.Namespace}}-{{end}}{{.Name}}"},
+			wantPV:    "dr-pv-1",
+			wantPVC:   "dr-ns-1-pvc-1",
+			wantPod:   "dr-ns-1-pod-1",
+			wantClaim: "dr-ns-1-pvc-1",
+		},
+		{
+			name:      "only the items of the rewrite's resources are renamed",
+			rewrite:   &velerov1api.NameRewrite{Suffix: "-restored", Resources: []string{"persistentvolumes"}},
+			wantPV:    "pv-1-restored",
+			wantPVC:   "pvc-1",
+			wantPod:   "pod-1",
+			wantClaim: "pvc-1",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.PVs())
+			h.addItems(t, test.PVCs())
+			h.addItems(t, test.Pods())
+
+			pv := test.NewPV("pv-1", func(obj metav1.Object) {
+				obj.(*corev1api.PersistentVolume).Spec.PersistentVolumeReclaimPolicy = corev1api.PersistentVolumeReclaimRetain
+			})
+			pvc := test.NewPVC("ns-1", "pvc-1", func(obj metav1.Object) {
+				obj.(*corev1api.PersistentVolumeClaim).Spec.VolumeName = "pv-1"
+			})
+			pod := test.NewPod("ns-1", "pod-1", func(obj metav1.Object) {
+				obj.(*corev1api.Pod).Spec.Volumes = []corev1api.Volume{{
+					Name: "data",
+					VolumeSource: corev1api.VolumeSource{
+						PersistentVolumeClaim: &corev1api.PersistentVolumeClaimVolumeSource{ClaimName: "pvc-1"},
+					},
+				}}
+			})
+
+			tarball := newTarWriter(t).
+				addItems("persistentvolumes", pv).
+				addItems("persistentvolumeclaims", pvc).
+				addItems("pods", pod).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				defaultRestore().NameRewrite(tc.rewrite).Restore(),
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, warnings, errs)
+
+			_, err := h.DynamicClient.Resource(test.PVs().GVR()).Get(tc.wantPV, metav1.GetOptions{})
+			require.NoError(t, err)
+
+			res, err := h.DynamicClient.Resource(test.PVCs().GVR()).Namespace("ns-1").Get(tc.wantPVC, metav1.GetOptions{})
+			require.NoError(t, err)
+			volumeName, _, _ := unstructured.NestedString(res.Object, "spec", "volumeName")
+			assert.Equal(t, tc.wantPV, volumeName)
+
+			res, err = h.DynamicClient.Resource(test.Pods().GVR()).Namespace("ns-1").Get(tc.wantPod, metav1.GetOptions{})
+			require.NoError(t, err)
+			restoredPod := new(corev1api.Pod)
+			require.NoError(t, runtime.DefaultUnstructuredConverter.FromUnstructured(res.Object, restoredPod))
+			require.Len(t, restoredPod.Spec.Volumes, 1)
+			assert.Equal(t, tc.wantClaim, restoredPod.Spec.Volumes[0].PersistentVolumeClaim.ClaimName)
+		})
+	}
+}
+
+// volumeRecordingResticRestorer is a restic restorer factory and restorer that
+// records the volumes of each pod whose data it's asked to restore.
+type volumeRecordingResticRestorer struct {
+	lock    sync.Mutex
+	volumes map[string][]string
+}
+
+func (r *volumeRecordingResticRestorer) NewRestorer(go_context.Context, *velerov1api.Restore) (restic.Restorer, error) {
+	return r, nil
+}
+
+func (r *volumeRecordingResticRestorer) RestorePodVolumes(restore *velerov1api.Restore, pod *corev1api.Pod, sourceNamespace, backupLocation string, log logrus.FieldLogger) []error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.volumes == nil {
+		r.volumes = make(map[string][]string)
+	}
+	for volume := range restic.GetPodSnapshotAnnotations(pod) {
+		r.volumes[pod.Name] = append(r.volumes[pod.Name], volume)
+	}
+	sort.Strings(r.volumes[pod.Name])
+
+	return nil
+}
+
+// TestRestorePVCDataSelector runs restores of a pod with restic snapshots of volumes
+// backed by claims and of a volume that isn't, and verifies that only the data of the
+// volumes whose claims match the restore's PVC data selector is restored, along with
+// the data of the volume without a claim, while all the claims are restored.
+func TestRestorePVCDataSelector(t *testing.T) {
+	tests := []struct {
+		name        string
+		selector    *metav1.LabelSelector
+		wantVolumes []string
+	}{
+		{
+			name:        "the data of all volumes is restored without a selector",
+			wantVolumes: []string{"db", "scratch", "web"},
+		},
+		{
+			name:        "only the data of volumes whose claims match the selector is restored",
+			selector:    &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+			wantVolumes: []string{"db", "scratch"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newHarness(t)
+			h.addItems(t, test.Pods())
+			h.addItems(t, test.PVCs())
+
+			resticRestorer := new(volumeRecordingResticRestorer)
+			h.restorer.resticRestorerFactory = resticRestorer
+
+			pod := test.NewPod("ns-1", "pod-1",
+				test.WithAnnotations(
+					"snapshot.velero.io/db", "snapshot-1",
+					"snapshot.velero.io/web", "snapshot-2",
+					"snapshot.velero.io/scratch", "snapshot-3",
+				),
+				func(obj metav1.Object) {
+					claimVolume := func(name, claimName string) corev1api.Volume {
+						return corev1api.Volume{
+							Name: name,
+							VolumeSource: corev1api.VolumeSource{
+								PersistentVolumeClaim: &corev1api.PersistentVolumeClaimVolumeSource{ClaimName: claimName},
+							},
+						}
+					}
+					obj.(*corev1api.Pod).Spec.Volumes = []corev1api.Volume{
+						claimVolume("db", "db-data"),
+						claimVolume("web", "web-data"),
+						{Name: "scratch", VolumeSource: corev1api.VolumeSource{EmptyDir: &corev1api.EmptyDirVolumeSource{}}},
+					}
+				},
+			)
+
+			tarball := newTarWriter(t).
+				addItems("pods", pod).
+				addItems("persistentvolumeclaims",
+					test.NewPVC("ns-1", "db-data", test.WithLabels("app", "db")),
+					test.NewPVC("ns-1", "web-data", test.WithLabels("app", "web")),
+				).
+				done()
+
+			warnings, errs, _ := h.restorer.Restore(
+				h.log,
+				defaultRestore().RestorePVCDataSelector(tc.selector).Restore(),
+				defaultBackup().Backup(),
+				nil, // volume snapshots
+				tarball,
+				nil, // actions
+				nil, // snapshot location lister
+				nil, // volume snapshotter getter
+			)
+
+			assertEmptyResults(t, warnings, errs)
+			assert.Equal(t, map[string][]string{"pod-1": tc.wantVolumes}, resticRestorer.volumes)
+
+			for _, name := range []string{"db-data", "web-data"} {
+				_, err := h.DynamicClient.Resource(test.PVCs().GVR()).Namespace("ns-1").Get(name, metav1.GetOptions{})
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// fakeCheckpointer records the items in the last checkpoint of a restore.
+type fakeCheckpointer struct {
+	checkpoints int
+	items       []string
+}
+
+func (c *fakeCheckpointer) Checkpoint(_ *velerov1api.Restore, restoredItems []string) error {
+	c.checkpoints++
+	c.items = restoredItems
+	return nil
+}
+
+func (c *fakeCheckpointer) LoadCheckpoint(*velerov1api.Restore) ([]string, error) {
+	return c.items, nil
+}
+
+// TestRestoreCheckpoints runs a checkpointed restore that's interrupted partway through,
+// then resumes it from its last checkpoint, and verifies that the resumed restore only
+// restores the items that weren't checkpointed.
+func TestRestoreCheckpoints(t *testing.T) {
+	h := newHarness(t)
+	h.addItems(t, test.Pods())
+
+	checkpointer := new(fakeCheckpointer)
+	h.restorer.checkpointer = checkpointer
+
+	// simulate the restore being interrupted before it restores pod-3
+	interrupted := true
+	h.DynamicClient.PrependReactor("create", "pods", func(action kubetesting.Action) (bool, runtime.Object, error) {
+		accessor, err := meta.Accessor(action.(kubetesting.CreateAction).GetObject())
+		require.NoError(t, err)
+		if interrupted && accessor.GetName() == "pod-3" {
+			return true, nil, errors.New("restore interrupted")
+		}
+		return false, nil, nil
+	})
+
+	newTarball := func() io.Reader {
+		return newTarWriter(t).
+			addItems("pods",
+				test.NewPod("ns-1", "pod-1"),
+				test.NewPod("ns-1", "pod-2"),
+				test.NewPod("ns-1", "pod-3"),
+			).
+			done()
+	}
+
+	restore := defaultRestore().CheckpointInterval(1).Restore()
+
+	_, errs, _ := h.restorer.Restore(h.log, restore, defaultBackup().Backup(), nil, newTarball(), nil, nil, nil)
+	assert.NotEmpty(t, errs.Namespaces["ns-1"])
+	assert.Equal(t, []string{"pods/ns-1/pod-1", "pods/ns-1/pod-2"}, checkpointer.items)
+
+	// resume the restore from its last checkpoint, which is loaded
+	// from the checkpointer
+	interrupted = false
+	creates := &createRecorder{t: t}
+	h.DynamicClient.PrependReactor("create", "*", creates.reactor())
+
+	warnings, errs, itemResults := h.restorer.Restore(h.log, restore, defaultBackup().Backup(), nil, newTarball(), nil, nil, nil)
+	assertEmptyResults(t, warnings, errs)
+
+	assert.Equal(t, []resourceID{{groupResource: "pods", nsAndName: "ns-1/pod-3"}}, creates.resources)
+	assert.ElementsMatch(t, ItemResults{
+		{GroupResource: "pods", Namespace: "ns-1", Name: "pod-1", Outcome: ItemOutcomeSkipped, Action: ItemActionSkippedExists, Reason: checkpointedReason},
+		{GroupResource: "pods", Namespace: "ns-1", Name: "pod-2", Outcome: ItemOutcomeSkipped, Action: ItemActionSkippedExists, Reason: checkpointedReason},
+		{GroupResource: "pods", Namespace: "ns-1", Name: "pod-3", Outcome: ItemOutcomeCreated, Action: ItemActionCreated},
+	}, itemResults)
+	assert.Equal(t, []string{"pods/ns-1/pod-1", "pods/ns-1/pod-2", "pods/ns-1/pod-3"}, checkpointer.items)
+}
+
+// TestRestoreResumesFromLoadedCheckpoint runs a checkpointed restore whose checkpointer
+// has a checkpoint from before a server restart, and verifies that the items in the
+// checkpoint aren't created again.
+func TestRestoreResumesFromLoadedCheckpoint(t *testing.T) {
+	h := newHarness(t)
+	h.addItems(t, test.Pods())
+	h.addItems(t, test.PVs())
+
+	h.restorer.checkpointer = &fakeCheckpointer{items: []string{"pods/ns-1/pod-1", "persistentvolumes/pv-1"}}
+
+	creates := &createRecorder{t: t}
+	h.DynamicClient.PrependReactor("create", "*", creates.reactor())
+
+	tarball := newTarWriter(t).
+		addItems("pods",
+			test.NewPod("ns-1", "pod-1"),
+			test.NewPod("ns-1", "pod-2"),
+		).
+		addItems("persistentvolumes", test.NewPV("pv-1")).
+		done()
+
+	restore := defaultRestore().CheckpointInterval(10).Restore()
+	restore.Status.Phase = velerov1api.RestorePhaseInProgress
+
+	warnings, errs, _ := h.restorer.Restore(h.log, restore, defaultBackup().Backup(), nil, tarball, nil, nil, nil)
+	assertEmptyResults(t, warnings, errs)
+
+	assert.Equal(t, []resourceID{{groupResource: "pods", nsAndName: "ns-1/pod-2"}}, creates.resources)
 }
 
 type resourceID struct {
@@ -1755,17 +8590,18 @@ func assertEmptyResults(t *testing.T, res ...Result) {
 		assert.Empty(t, r.Cluster)
 		assert.Empty(t, r.Namespaces)
 		assert.Empty(t, r.Velero)
+		assert.Empty(t, r.VerificationFailures)
 	}
 }
 
 type tarWriter struct {
-	t   *testing.T
+	t   testing.TB
 	buf *bytes.Buffer
 	gzw *gzip.Writer
 	tw  *tar.Writer
 }
 
-func newTarWriter(t *testing.T) *tarWriter {
+func newTarWriter(t testing.TB) *tarWriter {
 	tw := new(tarWriter)
 	tw.t = t
 	tw.buf = new(bytes.Buffer)
@@ -1837,7 +8673,7 @@ type harness struct {
 	log      logrus.FieldLogger
 }
 
-func newHarness(t *testing.T) *harness {
+func newHarness(t testing.TB) *harness {
 	t.Helper()
 
 	apiServer := test.NewAPIServer(t)
@@ -1855,6 +8691,7 @@ func newHarness(t *testing.T) *harness {
 			resourceTerminatingTimeout: time.Minute,
 			logger:                     log,
 			fileSystem:                 testutil.NewFakeFileSystem(),
+			tracer:                     noopTracer{},
 
 			// unsupported
 			resticRestorerFactory: nil,
@@ -1864,7 +8701,7 @@ func newHarness(t *testing.T) *harness {
 	}
 }
 
-func (h *harness) addItems(t *testing.T, resource *test.APIResource) {
+func (h *harness) addItems(t testing.TB, resource *test.APIResource) {
 	t.Helper()
 
 	h.DiscoveryClient.WithAPIResource(resource)
@@ -48,12 +48,17 @@ import (
 
 func TestPrioritizeResources(t *testing.T) {
 	tests := []struct {
-		name         string
-		apiResources map[string][]string
-		priorities   []string
-		includes     []string
-		excludes     []string
-		expected     []string
+		name              string
+		apiResources      map[string][]string
+		priorities        []string
+		restorePriorities []string
+		lowPriorities     []string
+		includes          []string
+		excludes          []string
+		strict            bool
+		expected          []string
+		wantMissing       []string
+		wantErr           bool
 	}{
 		{
 			name: "priorities & ordering are correctly applied",
@@ -83,6 +88,89 @@ func TestPrioritizeResources(t *testing.T) {
 			excludes:   []string{"ooo", "pods"},
 			expected:   []string{"namespaces", "configmaps", "aaa", "bbb", "ddd", "sss"},
 		},
+		{
+			name: "group patterns are correctly applied",
+			apiResources: map[string][]string{
+				"v1":      {"configmaps", "namespaces", "pods"},
+				"apps/v1": {"deployments", "statefulsets"},
+			},
+			priorities: []string{"namespaces", "configmaps", "pods"},
+			includes:   []string{"namespaces", "apps/*"},
+			expected:   []string{"namespaces", "deployments", "statefulsets"},
+		},
+		{
+			name: "empty restore priorities fall back to the default priorities",
+			apiResources: map[string][]string{
+				"v1": {"aaa", "bbb", "configmaps", "ddd", "namespaces", "ooo", "pods", "sss"},
+			},
+			priorities:        []string{"namespaces", "configmaps", "pods"},
+			restorePriorities: []string{},
+			includes:          []string{"*"},
+			expected:          []string{"namespaces", "configmaps", "pods", "aaa", "bbb", "ddd", "ooo", "sss"},
+		},
+		{
+			name: "restore priorities replace the default priorities",
+			apiResources: map[string][]string{
+				"v1": {"aaa", "bbb", "configmaps", "ddd", "namespaces", "ooo", "pods", "sss"},
+			},
+			priorities:        []string{"namespaces", "configmaps", "pods"},
+			restorePriorities: []string{"namespaces", "sss", "ooo"},
+			includes:          []string{"*"},
+			expected:          []string{"namespaces", "sss", "ooo", "aaa", "bbb", "configmaps", "ddd", "pods"},
+		},
+		{
+			name: "priorities for resources the cluster doesn't serve are ignored",
+			apiResources: map[string][]string{
+				"v1": {"configmaps", "namespaces", "pods"},
+			},
+			priorities: []string{"namespaces", "widgets.example.com", "pods"},
+			includes:   []string{"*"},
+			expected:   []string{"namespaces", "pods", "configmaps"},
+		},
+		{
+			name: "strict priorities for resources the cluster doesn't serve are an error",
+			apiResources: map[string][]string{
+				"v1": {"configmaps", "namespaces", "pods"},
+			},
+			priorities:  []string{"namespaces", "widgets.example.com", "pods", "gadgets"},
+			includes:    []string{"*"},
+			strict:      true,
+			wantMissing: []string{"widgets.example.com", "gadgets"},
+		},
+		{
+			name: "low-priority resources are pinned to the end in order",
+			apiResources: map[string][]string{
+				"v1":                              {"aaa", "bbb", "configmaps", "namespaces", "pods"},
+				"networking.k8s.io/v1":            {"networkpolicies"},
+				"admissionregistration.k8s.io/v1": {"validatingwebhookconfigurations"},
+			},
+			priorities:    []string{"namespaces", "configmaps"},
+			lowPriorities: []string{"validatingwebhookconfigurations", "networkpolicies.networking.k8s.io"},
+			includes:      []string{"*"},
+			expected:      []string{"namespaces", "configmaps", "aaa", "bbb", "pods", "validatingwebhookconfigurations", "networkpolicies"},
+		},
+		{
+			name: "excluded low-priority resources aren't restored",
+			apiResources: map[string][]string{
+				"v1":                   {"configmaps", "namespaces", "pods"},
+				"networking.k8s.io/v1": {"networkpolicies"},
+			},
+			priorities:    []string{"namespaces"},
+			lowPriorities: []string{"networkpolicies"},
+			includes:      []string{"*"},
+			excludes:      []string{"networkpolicies.networking.k8s.io"},
+			expected:      []string{"namespaces", "configmaps", "pods"},
+		},
+		{
+			name: "a resource that's both prioritized and low-priority is an error",
+			apiResources: map[string][]string{
+				"v1": {"configmaps", "namespaces", "pods"},
+			},
+			priorities:    []string{"namespaces", "pods"},
+			lowPriorities: []string{"pods"},
+			includes:      []string{"*"},
+			wantErr:       true,
+		},
 	}
 
 	logger := velerotest.NewLogger()
@@ -114,7 +202,16 @@ func TestPrioritizeResources(t *testing.T) {
 
 			includesExcludes := collections.NewIncludesExcludes().Includes(tc.includes...).Excludes(tc.excludes...)
 
-			result, err := prioritizeResources(helper, tc.priorities, includesExcludes, logger)
+			result, err := prioritizeResources(helper, getResourcePriorities(tc.priorities, tc.restorePriorities), tc.lowPriorities, includesExcludes, tc.strict, logger)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if tc.wantMissing != nil {
+				require.IsType(t, &MissingResourceError{}, err)
+				assert.Equal(t, tc.wantMissing, err.(*MissingResourceError).Resources)
+				return
+			}
 			require.NoError(t, err)
 
 			require.Equal(t, len(tc.expected), len(result))
@@ -128,6 +225,57 @@ func TestPrioritizeResources(t *testing.T) {
 	}
 }
 
+func TestValidateLowPriorityResources(t *testing.T) {
+	tests := []struct {
+		name          string
+		defaults      []string
+		priorities    []string
+		lowPriorities []string
+		wantErrs      int
+	}{
+		{
+			name:          "distinct priorities and low-priority resources are valid",
+			priorities:    []string{"namespaces", "pods"},
+			lowPriorities: []string{"networkpolicies.networking.k8s.io"},
+		},
+		{
+			name:          "no low-priority resources is valid",
+			priorities:    []string{"namespaces", "pods"},
+			lowPriorities: nil,
+		},
+		{
+			name:          "each resource in both lists is an error",
+			priorities:    []string{"namespaces", "pods", "networkpolicies.networking.k8s.io"},
+			lowPriorities: []string{"networkpolicies.networking.k8s.io", "pods", "services"},
+			wantErrs:      2,
+		},
+		{
+			name:          "the default priorities are checked if the restore has none",
+			defaults:      []string{"namespaces", "pods"},
+			lowPriorities: []string{"pods", "services"},
+			wantErrs:      1,
+		},
+		{
+			name:          "the default priorities aren't checked if the restore has its own",
+			defaults:      []string{"namespaces", "pods"},
+			priorities:    []string{"namespaces"},
+			lowPriorities: []string{"pods", "services"},
+		},
+		{
+			name:          "each repeated low-priority resource is an error",
+			priorities:    []string{"namespaces"},
+			lowPriorities: []string{"services", "services", "jobs.batch", "jobs.batch"},
+			wantErrs:      2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Len(t, ValidateLowPriorityResources(tc.defaults, tc.priorities, tc.lowPriorities), tc.wantErrs)
+		})
+	}
+}
+
 func TestRestoringPVsWithoutSnapshots(t *testing.T) {
 	pv := `apiVersion: v1
 kind: PersistentVolume
@@ -206,9 +354,11 @@ status:
 	tests := []struct {
 		name                          string
 		haveSnapshot                  bool
+		haveCSISnapshot               bool
 		reclaimPolicy                 string
 		expectPVCVolumeName           bool
 		expectedPVCAnnotationsMissing sets.String
+		expectPVCDataSource           bool
 		expectPVCreation              bool
 		expectPVFound                 bool
 	}{
@@ -272,6 +422,22 @@ status:
 			expectPVCreation:    false,
 			expectPVFound:       true,
 		},
+		{
+			name:                          "backup has CSI snapshot, reclaim policy delete",
+			haveCSISnapshot:               true,
+			reclaimPolicy:                 "Delete",
+			expectPVCVolumeName:           false,
+			expectedPVCAnnotationsMissing: sets.NewString("pv.kubernetes.io/bind-completed", "pv.kubernetes.io/bound-by-controller"),
+			expectPVCDataSource:           true,
+		},
+		{
+			name:                          "backup has CSI snapshot, reclaim policy retain",
+			haveCSISnapshot:               true,
+			reclaimPolicy:                 "Retain",
+			expectPVCVolumeName:           false,
+			expectedPVCAnnotationsMissing: sets.NewString("pv.kubernetes.io/bind-completed", "pv.kubernetes.io/bound-by-controller"),
+			expectPVCDataSource:           true,
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -356,6 +522,17 @@ status:
 					},
 				})
 			}
+			if test.haveCSISnapshot {
+				ctx.volumeSnapshots = append(ctx.volumeSnapshots, &volume.Snapshot{
+					Spec: volume.SnapshotSpec{
+						PersistentVolumeName: "pvc-6a74b5af-78a5-11e8-a0d8-e2ad1e9734ce",
+						CSI: &volume.CSISnapshotSpec{
+							VolumeSnapshotName: "nfs-snapshot",
+							Driver:             "example.com/nfs",
+						},
+					},
+				})
+			}
 
 			unstructuredPVMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pvObj)
 			require.NoError(t, err)
@@ -370,8 +547,9 @@ status:
 				pvcClient.On("Get", pvcObj.Name, mock.Anything).Return(inClusterPVC, nil)
 			}
 
-			// Only set up the client expectation if the test has the proper prerequisites
-			if test.haveSnapshot || test.reclaimPolicy != "Delete" {
+			// Only set up the client expectation if the test has the proper prerequisites.
+			// PVs with a CSI snapshot are skipped before checking for an in-cluster PV.
+			if !test.haveCSISnapshot && (test.haveSnapshot || test.reclaimPolicy != "Delete") {
 				pvClient.On("Get", unstructuredPV.GetName(), metav1.GetOptions{}).Return(&unstructured.Unstructured{}, k8serrors.NewNotFound(schema.GroupResource{Resource: "persistentvolumes"}, unstructuredPV.GetName()))
 			}
 
@@ -384,7 +562,7 @@ status:
 				pvRestorer.On("executePVAction", pvToRestore).Return(restoredPV, nil)
 			}
 
-			resetMetadataAndStatus(unstructuredPV)
+			resetMetadataAndStatus(unstructuredPV, annotationFilter{})
 			addRestoreLabels(unstructuredPV, ctx.restore.Name, ctx.restore.Spec.BackupName)
 			unstructuredPV.Object["foo"] = "bar"
 
@@ -401,6 +579,12 @@ status:
 			assert.Equal(t, Result{}, errors)
 			assert.Empty(t, warnings.Cluster)
 
+			// PVs with a CSI snapshot are provisioned by their claims rather
+			// than restored from a volume snapshotter's snapshot
+			if test.haveCSISnapshot {
+				pvRestorer.AssertNotCalled(t, "executePVAction", mock.Anything)
+			}
+
 			// Prep PVC restore
 			// Handle expectations
 			if !test.expectPVCVolumeName {
@@ -409,13 +593,24 @@ status:
 			for _, key := range test.expectedPVCAnnotationsMissing.List() {
 				delete(pvcObj.Annotations, key)
 			}
+			// the PVC's stale leader election record is always removed
+			require.Contains(t, pvcObj.Annotations, "control-plane.alpha.kubernetes.io/leader")
+			delete(pvcObj.Annotations, "control-plane.alpha.kubernetes.io/leader")
+			if test.expectPVCDataSource {
+				apiGroup := "snapshot.storage.k8s.io"
+				pvcObj.Spec.DataSource = &v1.TypedLocalObjectReference{
+					APIGroup: &apiGroup,
+					Kind:     "VolumeSnapshot",
+					Name:     "nfs-snapshot",
+				}
+			}
 
 			// Recreate the unstructured PVC since the object was edited.
 			unstructuredPVCMap, err = runtime.DefaultUnstructuredConverter.ToUnstructured(pvcObj)
 			require.NoError(t, err)
 			unstructuredPVC = &unstructured.Unstructured{Object: unstructuredPVCMap}
 
-			resetMetadataAndStatus(unstructuredPVC)
+			resetMetadataAndStatus(unstructuredPVC, annotationFilter{})
 			addRestoreLabels(unstructuredPVC, ctx.restore.Name, ctx.restore.Spec.BackupName)
 
 			createdPVC := unstructuredPVC.DeepCopy()
@@ -444,10 +639,73 @@ func (r *mockPVRestorer) executePVAction(obj *unstructured.Unstructured) (*unstr
 	return args.Get(0).(*unstructured.Unstructured), args.Error(1)
 }
 
+func TestRestoreResourceEmptyDirectories(t *testing.T) {
+	tests := []struct {
+		name         string
+		fileSystem   *velerotest.FakeFileSystem
+		resourcePath string
+	}{
+		{
+			name:         "empty resource directory",
+			fileSystem:   velerotest.NewFakeFileSystem().WithDirectory("bak/resources/pods/namespaces/ns-1"),
+			resourcePath: "bak/resources/pods/namespaces/ns-1",
+		},
+		{
+			name:         "missing resource directory",
+			fileSystem:   velerotest.NewFakeFileSystem().WithDirectory("bak/resources"),
+			resourcePath: "bak/resources/pods/namespaces/ns-1",
+		},
+		{
+			name:         "resource directory containing only an empty subdirectory",
+			fileSystem:   velerotest.NewFakeFileSystem().WithDirectory("bak/resources/pods/namespaces/ns-1/nested"),
+			resourcePath: "bak/resources/pods/namespaces/ns-1",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := &context{
+				restore:    NewBuilder().Restore(),
+				restoreDir: "bak",
+				fileSystem: tc.fileSystem,
+				selector:   labels.Everything(),
+				log:        velerotest.NewLogger(),
+			}
+
+			warnings, errs := ctx.restoreResource("pods", "ns-1", tc.resourcePath)
+			assert.Equal(t, Result{}, warnings)
+			assert.Equal(t, Result{}, errs)
+			assert.Empty(t, ctx.itemResults)
+		})
+	}
+}
+
+func TestRestoreFromDirSkipsEmptyNamespaceDirectories(t *testing.T) {
+	ctx := &context{
+		restore:                   NewBuilder().Restore(),
+		restoreDir:                "bak",
+		fileSystem:                velerotest.NewFakeFileSystem().WithDirectory("bak/resources/pods/namespaces/ns-1"),
+		prioritizedResources:      []schema.GroupResource{kuberesource.Pods},
+		namespaceIncludesExcludes: collections.NewIncludesExcludes(),
+		selector:                  labels.Everything(),
+		tracer:                    noopTracer{},
+		log:                       velerotest.NewLogger(),
+		// no namespace client is set, so this fails if the empty
+		// namespace directory causes the namespace to be created
+		namespaceClient: nil,
+	}
+
+	warnings, errs := ctx.restoreFromDir()
+	assert.Equal(t, Result{}, warnings)
+	assert.Equal(t, Result{}, errs)
+	assert.Empty(t, ctx.itemResults)
+}
+
 func TestResetMetadataAndStatus(t *testing.T) {
 	tests := []struct {
 		name        string
 		obj         *unstructured.Unstructured
+		annotations annotationFilter
 		expectedErr bool
 		expectedRes *unstructured.Unstructured
 	}{
@@ -462,17 +720,60 @@ func TestResetMetadataAndStatus(t *testing.T) {
 			expectedErr: false,
 			expectedRes: NewTestUnstructured().WithMetadata("name", "namespace", "labels", "annotations").Unstructured,
 		},
+		{
+			name:        "keep generateName when there's no name",
+			obj:         NewTestUnstructured().WithMetadataField("generateName", "blah-").WithMetadataField("uid", "foo").Unstructured,
+			expectedErr: false,
+			expectedRes: NewTestUnstructured().WithMetadataField("generateName", "blah-").Unstructured,
+		},
+		{
+			name:        "don't keep generateName when there's a name",
+			obj:         NewTestUnstructured().WithMetadataField("name", "blah").WithMetadataField("generateName", "blah-").Unstructured,
+			expectedErr: false,
+			expectedRes: NewTestUnstructured().WithMetadataField("name", "blah").Unstructured,
+		},
+		{
+			name:        "don't keep finalizers",
+			obj:         NewTestUnstructured().WithMetadata().WithMetadataField("finalizers", []interface{}{"kubernetes.io/pv-protection"}).Unstructured,
+			expectedErr: false,
+			expectedRes: NewTestUnstructured().WithMetadata().Unstructured,
+		},
 		{
 			name:        "don't keep status",
 			obj:         NewTestUnstructured().WithMetadata().WithStatus().Unstructured,
 			expectedErr: false,
 			expectedRes: NewTestUnstructured().WithMetadata().Unstructured,
 		},
+		{
+			name: "keep annotations with a configured prefix and remove stripped ones",
+			obj: NewTestUnstructured().WithMetadata().WithAnnotations(
+				"ours.example.com/reconcile-state",
+				"ours.example.com/generation",
+				"kubectl.kubernetes.io/last-applied-configuration",
+				"other.example.com/annotation",
+			).Unstructured,
+			annotations: annotationFilter{
+				keepPrefixes:  []string{"ours.example.com/", "kubectl.kubernetes.io/"},
+				stripPrefixes: []string{"kubectl.kubernetes.io/last-applied-configuration"},
+			},
+			expectedErr: false,
+			expectedRes: NewTestUnstructured().WithMetadata().WithAnnotations(
+				"ours.example.com/reconcile-state",
+				"ours.example.com/generation",
+			).Unstructured,
+		},
+		{
+			name:        "remove annotations when none are kept",
+			obj:         NewTestUnstructured().WithMetadata().WithAnnotations("kubectl.kubernetes.io/last-applied-configuration").Unstructured,
+			annotations: annotationFilter{stripPrefixes: []string{"kubectl.kubernetes.io/"}},
+			expectedErr: false,
+			expectedRes: NewTestUnstructured().WithMetadata().Unstructured,
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			res, err := resetMetadataAndStatus(test.obj)
+			res, err := resetMetadataAndStatus(test.obj, test.annotations)
 
 			if assert.Equal(t, test.expectedErr, err != nil) {
 				assert.Equal(t, test.expectedRes, res)
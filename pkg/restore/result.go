@@ -32,4 +32,201 @@ type Result struct {
 	// Namespaces is a map of namespace name to slice of messages
 	// related to restoring namespace-scoped resources.
 	Namespaces map[string][]string `json:"namespaces,omitempty"`
+
+	// VerificationFailures is a slice of messages about restored items
+	// that couldn't be found in the cluster once the restore completed.
+	VerificationFailures []string `json:"verificationFailures,omitempty"`
+}
+
+// ItemOutcome describes what a restore did with a single item
+// from the backup.
+type ItemOutcome string
+
+const (
+	// ItemOutcomeCreated means the item was created in the cluster.
+	ItemOutcomeCreated ItemOutcome = "created"
+
+	// ItemOutcomeUpdated means the item already existed in the cluster
+	// and was updated to match the backed-up version.
+	ItemOutcomeUpdated ItemOutcome = "updated"
+
+	// ItemOutcomeSkipped means the item was intentionally not restored,
+	// e.g. because it already existed in the cluster.
+	ItemOutcomeSkipped ItemOutcome = "skipped"
+
+	// ItemOutcomeFailed means an error occurred restoring the item.
+	ItemOutcomeFailed ItemOutcome = "failed"
+
+	// ItemOutcomeDryRun means the item doesn't exist in the cluster and
+	// would have been created, but wasn't because the restore is a dry run.
+	ItemOutcomeDryRun ItemOutcome = "dry-run"
+)
+
+// ItemResult records the outcome of restoring a single item.
+type ItemResult struct {
+	// GroupResource is the group-qualified resource name of the item,
+	// e.g. "deployments.apps".
+	GroupResource string `json:"groupResource"`
+
+	// Namespace is the namespace the item was restored into, after any
+	// namespace mapping was applied. Empty for cluster-scoped items.
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name is the name of the item.
+	Name string `json:"name"`
+
+	// Outcome is what the restore did with the item.
+	Outcome ItemOutcome `json:"outcome"`
+
+	// Action is the action the restore took for the item, which
+	// distinguishes why items with the same outcome got it.
+	Action ItemAction `json:"action"`
+
+	// Reason explains the outcome, if the restore recorded why. Optional.
+	Reason string `json:"reason,omitempty"`
+}
+
+// ItemResults is the list of per-item outcomes for a restore.
+type ItemResults []ItemResult
+
+// Reasons recorded for items that aren't restored as backed up.
+const (
+	alreadyExistsReason     = "already exists in the cluster"
+	dynamicProvisionReason  = "dynamically provisioned by its claim because it has no snapshot and a reclaim policy of Delete"
+	snapshotProvisionReason = "provisioned from a volume snapshot"
+	checkpointedReason      = "restored before the restore was resumed"
+	replayedReason          = "re-created from a replay"
+)
+
+// ItemAction is the action a restore took for a single item, as recorded
+// in the restore's machine-readable results.
+type ItemAction string
+
+const (
+	// ItemActionCreated means the item was created as backed up.
+	ItemActionCreated ItemAction = "created"
+
+	// ItemActionUpdated means the item already existed in the cluster
+	// and was updated to match the backed-up version.
+	ItemActionUpdated ItemAction = "updated"
+
+	// ItemActionSkippedExists means the item wasn't restored because it
+	// already exists in the cluster.
+	ItemActionSkippedExists ItemAction = "skipped-exists"
+
+	// ItemActionSkippedPolicy means the item wasn't restored because of
+	// the restore's configuration, or because a plugin skipped it.
+	ItemActionSkippedPolicy ItemAction = "skipped-policy"
+
+	// ItemActionProvisionedPV means the persistent volume was provisioned
+	// from a snapshot, or is provisioned by its claim, rather than being
+	// restored as backed up.
+	ItemActionProvisionedPV ItemAction = "provisioned-pv"
+
+	// ItemActionFailed means an error occurred restoring the item.
+	ItemActionFailed ItemAction = "failed"
+
+	// ItemActionDryRun means the item would have been created, or provisioned
+	// if it's a persistent volume, but the restore is a dry run.
+	ItemActionDryRun ItemAction = "dry-run"
+)
+
+// defaultItemAction returns the action for an item with the provided outcome that
+// the restore recorded no more specific action for.
+func defaultItemAction(outcome ItemOutcome) ItemAction {
+	switch outcome {
+	case ItemOutcomeCreated:
+		return ItemActionCreated
+	case ItemOutcomeUpdated:
+		return ItemActionUpdated
+	case ItemOutcomeSkipped:
+		return ItemActionSkippedPolicy
+	case ItemOutcomeDryRun:
+		return ItemActionDryRun
+	default:
+		return ItemActionFailed
+	}
+}
+
+// ItemRecord is the machine-readable record of what a restore did with a
+// single item.
+type ItemRecord struct {
+	Resource  string     `json:"resource"`
+	Namespace string     `json:"namespace,omitempty"`
+	Name      string     `json:"name"`
+	Action    ItemAction `json:"action"`
+	Reason    string     `json:"reason,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// Records returns the machine-readable record of each item result.
+func (r ItemResults) Records() []ItemRecord {
+	records := make([]ItemRecord, 0, len(r))
+	for _, item := range r {
+		record := ItemRecord{
+			Resource:  item.GroupResource,
+			Namespace: item.Namespace,
+			Name:      item.Name,
+			Action:    item.Action,
+		}
+		if record.Action == ItemActionFailed {
+			record.Error = item.Reason
+		} else {
+			record.Reason = item.Reason
+		}
+
+		records = append(records, record)
+	}
+	return records
+}
+
+// NamespaceSummary aggregates the item outcomes and warnings of a
+// restore for a single namespace.
+type NamespaceSummary struct {
+	Created  int      `json:"created"`
+	Updated  int      `json:"updated"`
+	Skipped  int      `json:"skipped"`
+	Failed   int      `json:"failed"`
+	DryRun   int      `json:"dryRun,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// ByNamespace aggregates the item outcomes, along with the provided warnings,
+// by the namespace they were restored into. Cluster-scoped items and warnings
+// are keyed by the empty string.
+func (r ItemResults) ByNamespace(warnings Result) map[string]NamespaceSummary {
+	summaries := make(map[string]NamespaceSummary)
+
+	for _, item := range r {
+		summary := summaries[item.Namespace]
+
+		switch item.Outcome {
+		case ItemOutcomeCreated:
+			summary.Created++
+		case ItemOutcomeUpdated:
+			summary.Updated++
+		case ItemOutcomeSkipped:
+			summary.Skipped++
+		case ItemOutcomeFailed:
+			summary.Failed++
+		case ItemOutcomeDryRun:
+			summary.DryRun++
+		}
+
+		summaries[item.Namespace] = summary
+	}
+
+	if len(warnings.Cluster) > 0 {
+		summary := summaries[""]
+		summary.Warnings = append(summary.Warnings, warnings.Cluster...)
+		summaries[""] = summary
+	}
+
+	for ns, w := range warnings.Namespaces {
+		summary := summaries[ns]
+		summary.Warnings = append(summary.Warnings, w...)
+		summaries[ns] = summary
+	}
+
+	return summaries
 }
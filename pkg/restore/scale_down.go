@@ -0,0 +1,76 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	api "github.com/heptio/velero/pkg/apis/velero/v1"
+	"github.com/heptio/velero/pkg/util/kube"
+)
+
+// scaledWorkloads are the group resources of the workloads that are scaled down by
+// restores that scale down on restore, and whether their rollouts can be paused.
+var scaledWorkloads = map[schema.GroupResource]bool{
+	{Group: "apps", Resource: "deployments"}:        true,
+	{Group: "extensions", Resource: "deployments"}:  true,
+	{Group: "apps", Resource: "statefulsets"}:       false,
+	{Group: "apps", Resource: "replicasets"}:        false,
+	{Group: "extensions", Resource: "replicasets"}:  false,
+	{Group: "", Resource: "replicationcontrollers"}: false,
+}
+
+// scaleDownWorkload sets the replica count of the provided workload to zero, and pauses
+// its rollout if it can be paused, if the restore scales down on restore. The backed-up
+// replica count is recorded in an annotation, so the workload can be scaled back up once
+// it's been validated. Workloads without a replica count have the default of one.
+func (ctx *context) scaleDownWorkload(obj *unstructured.Unstructured, groupResource schema.GroupResource) {
+	if !ctx.restore.Spec.ScaleDownOnRestore {
+		return
+	}
+
+	pausable, ok := scaledWorkloads[groupResource]
+	if !ok {
+		return
+	}
+
+	replicas, found, err := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	if err != nil {
+		ctx.log.WithError(err).Warnf("Not scaling down %s %s because its replica count can't be read", groupResource, kube.NamespaceAndName(obj))
+		return
+	}
+	if !found {
+		replicas = 1
+	}
+
+	ctx.log.Infof("Scaling down %s %s from %d replicas", groupResource, kube.NamespaceAndName(obj), replicas)
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[api.OriginalReplicasAnnotation] = strconv.FormatInt(replicas, 10)
+	obj.SetAnnotations(annotations)
+
+	unstructured.SetNestedField(obj.Object, int64(0), "spec", "replicas")
+	if pausable {
+		unstructured.SetNestedField(obj.Object, true, "spec", "paused")
+	}
+}
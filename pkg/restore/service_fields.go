@@ -0,0 +1,60 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	corev1api "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/heptio/velero/pkg/kuberesource"
+	"github.com/heptio/velero/pkg/util/kube"
+)
+
+// overrideServiceFields sets the traffic policy fields of the provided service to the
+// restore's service field overrides. Each field is only set if the service's type
+// supports it, so the API server doesn't reject the service.
+func (ctx *context) overrideServiceFields(obj *unstructured.Unstructured, groupResource schema.GroupResource) {
+	overrides := ctx.restore.Spec.ServiceFieldOverrides
+	if overrides == nil || groupResource != kuberesource.Services {
+		return
+	}
+
+	serviceType, _, _ := unstructured.NestedString(obj.Object, "spec", "type")
+	if serviceType == "" {
+		serviceType = string(corev1api.ServiceTypeClusterIP)
+	}
+
+	if policy := overrides.ExternalTrafficPolicy; policy != "" && (serviceType == string(corev1api.ServiceTypeNodePort) || serviceType == string(corev1api.ServiceTypeLoadBalancer)) {
+		ctx.log.Infof("Setting external traffic policy of service %s to %s", kube.NamespaceAndName(obj), policy)
+		unstructured.SetNestedField(obj.Object, string(policy), "spec", "externalTrafficPolicy")
+
+		// a health check node port is only allowed with the Local policy
+		if policy != corev1api.ServiceExternalTrafficPolicyTypeLocal {
+			unstructured.RemoveNestedField(obj.Object, "spec", "healthCheckNodePort")
+		}
+	}
+
+	if policy := overrides.InternalTrafficPolicy; policy != "" && serviceType != string(corev1api.ServiceTypeExternalName) {
+		ctx.log.Infof("Setting internal traffic policy of service %s to %s", kube.NamespaceAndName(obj), policy)
+		unstructured.SetNestedField(obj.Object, policy, "spec", "internalTrafficPolicy")
+	}
+
+	if allocate := overrides.AllocateLoadBalancerNodePorts; allocate != nil && serviceType == string(corev1api.ServiceTypeLoadBalancer) {
+		unstructured.SetNestedField(obj.Object, *allocate, "spec", "allocateLoadBalancerNodePorts")
+	}
+}
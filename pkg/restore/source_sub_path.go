@@ -0,0 +1,40 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ValidateSourceSubPath checks that the provided restore source sub-path is a
+// relative path that stays within the extracted backup.
+func ValidateSourceSubPath(subPath string) []error {
+	if subPath == "" {
+		return nil
+	}
+
+	if path.IsAbs(subPath) {
+		return []error{errors.Errorf("source sub-path %s must be relative", subPath)}
+	}
+	if cleaned := path.Clean(subPath); cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return []error{errors.Errorf("source sub-path %s must not refer outside of the backup", subPath)}
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSourceSubPath(t *testing.T) {
+	assert.Empty(t, ValidateSourceSubPath(""))
+	assert.Empty(t, ValidateSourceSubPath("foo"))
+	assert.Empty(t, ValidateSourceSubPath("foo/bar/../baz"))
+	assert.Len(t, ValidateSourceSubPath("/foo"), 1)
+	assert.Len(t, ValidateSourceSubPath(".."), 1)
+	assert.Len(t, ValidateSourceSubPath("foo/../../bar"), 1)
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"github.com/pkg/errors"
+	corev1api "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	api "github.com/heptio/velero/pkg/apis/velero/v1"
+	"github.com/heptio/velero/pkg/client"
+	"github.com/heptio/velero/pkg/util/kube"
+)
+
+// defaultStorageClassAnnotations are the annotations that mark a storage
+// class as the cluster's default.
+var defaultStorageClassAnnotations = []string{
+	"storageclass.kubernetes.io/is-default-class",
+	"storageclass.beta.kubernetes.io/is-default-class",
+}
+
+// isDefaultStorageClass returns true if the provided storage class is annotated
+// as the cluster's default.
+func isDefaultStorageClass(obj metav1.Object) bool {
+	for _, annotation := range defaultStorageClassAnnotations {
+		if obj.GetAnnotations()[annotation] == "true" {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveDefaultStorageClass removes the default class annotations from the provided
+// storage class if it's marked as the default and the cluster already has a different
+// default storage class. A warning is returned if the annotations are removed.
+func (ctx *context) resolveDefaultStorageClass(obj *unstructured.Unstructured, resourceClient client.Dynamic) error {
+	if ctx.restore.Spec.KeepDefaultStorageClassAnnotation || !isDefaultStorageClass(obj) {
+		return nil
+	}
+
+	list, err := resourceClient.List(metav1.ListOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "error listing storage classes to check for an existing default for %s", obj.GetName())
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return errors.Wrapf(err, "error listing storage classes to check for an existing default for %s", obj.GetName())
+	}
+
+	for _, item := range items {
+		existing, err := meta.Accessor(item)
+		if err != nil {
+			continue
+		}
+		if existing.GetName() == obj.GetName() || !isDefaultStorageClass(existing) {
+			continue
+		}
+
+		annotations := obj.GetAnnotations()
+		for _, annotation := range defaultStorageClassAnnotations {
+			delete(annotations, annotation)
+		}
+		obj.SetAnnotations(annotations)
+
+		return errors.Errorf("removed default class annotation from storage class %s because storage class %s is already the cluster's default", obj.GetName(), existing.GetName())
+	}
+
+	return nil
+}
+
+// remapStorageClass replaces the storage class of the provided persistent volume or
+// persistent volume claim according to the restore's storage class mappings. Storage
+// classes without a mapping, including an empty one, are left unchanged.
+func (ctx *context) remapStorageClass(obj *unstructured.Unstructured, groupResource schema.GroupResource) {
+	if len(ctx.restore.Spec.StorageClassMappings) == 0 {
+		return
+	}
+
+	storageClassName, _, _ := unstructured.NestedString(obj.Object, "spec", "storageClassName")
+	if newName, ok := ctx.restore.Spec.StorageClassMappings[storageClassName]; ok && storageClassName != "" {
+		ctx.log.Infof("Updating storage class of %s %s from %s to %s", groupResource, kube.NamespaceAndName(obj), storageClassName, newName)
+		unstructured.SetNestedField(obj.Object, newName, "spec", "storageClassName")
+	}
+
+	// the beta annotation takes precedence over the spec field,
+	// so it's remapped too
+	annotations := obj.GetAnnotations()
+	if storageClassName, ok := annotations[corev1api.BetaStorageClassAnnotation]; ok {
+		if newName, ok := ctx.restore.Spec.StorageClassMappings[storageClassName]; ok && storageClassName != "" {
+			annotations[corev1api.BetaStorageClassAnnotation] = newName
+			obj.SetAnnotations(annotations)
+		}
+	}
+}
+
+// supportsClaim returns true if the provided storage class preference supports all
+// of the provided persistent volume claim's access modes and its volume mode.
+func supportsClaim(preference api.StorageClassPreference, pvc *corev1api.PersistentVolumeClaim) bool {
+	for _, mode := range pvc.Spec.AccessModes {
+		var supported bool
+		for _, supportedMode := range preference.AccessModes {
+			if mode == supportedMode {
+				supported = true
+				break
+			}
+		}
+		if !supported {
+			return false
+		}
+	}
+
+	volumeMode := corev1api.PersistentVolumeFilesystem
+	if pvc.Spec.VolumeMode != nil {
+		volumeMode = *pvc.Spec.VolumeMode
+	}
+
+	volumeModes := preference.VolumeModes
+	if len(volumeModes) == 0 {
+		volumeModes = []corev1api.PersistentVolumeMode{corev1api.PersistentVolumeFilesystem}
+	}
+	for _, supportedMode := range volumeModes {
+		if volumeMode == supportedMode {
+			return true
+		}
+	}
+
+	return false
+}
+
+// chooseStorageClass sets the storage class of the provided persistent volume claim to
+// the first of the restore's storage class preferences that supports the claim, if the
+// claim will be dynamically provisioned. Claims with an explicitly empty storage class
+// are statically bound, so they're left as they are. An error is returned if no
+// preference supports the claim, in which case its storage class is left unchanged.
+func (ctx *context) chooseStorageClass(obj *unstructured.Unstructured) error {
+	if len(ctx.restore.Spec.StorageClassPreferences) == 0 {
+		return nil
+	}
+
+	// claims that are bound to a restored volume must keep the
+	// volume's storage class
+	if volumeName, _, _ := unstructured.NestedString(obj.Object, "spec", "volumeName"); volumeName != "" {
+		return nil
+	}
+
+	// an empty storage class, unlike an unset one, means the claim
+	// is only bound to volumes without a class
+	if storageClassName, found, _ := unstructured.NestedString(obj.Object, "spec", "storageClassName"); found && storageClassName == "" {
+		return nil
+	}
+	if storageClassName, ok := obj.GetAnnotations()[corev1api.BetaStorageClassAnnotation]; ok && storageClassName == "" {
+		return nil
+	}
+
+	pvc := new(corev1api.PersistentVolumeClaim)
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.UnstructuredContent(), pvc); err != nil {
+		return errors.WithStack(err)
+	}
+
+	for _, preference := range ctx.restore.Spec.StorageClassPreferences {
+		if !supportsClaim(preference, pvc) {
+			continue
+		}
+
+		ctx.log.Infof("Setting storage class of persistent volume claim %s/%s to %s", obj.GetNamespace(), obj.GetName(), preference.StorageClassName)
+
+		// the beta annotation takes precedence over the spec field, so
+		// remove it to avoid leaving the claim with conflicting classes
+		annotations := obj.GetAnnotations()
+		if _, ok := annotations[corev1api.BetaStorageClassAnnotation]; ok {
+			delete(annotations, corev1api.BetaStorageClassAnnotation)
+			obj.SetAnnotations(annotations)
+		}
+
+		return unstructured.SetNestedField(obj.Object, preference.StorageClassName, "spec", "storageClassName")
+	}
+
+	return errors.Errorf("none of the restore's storage class preferences support the access modes and volume mode of persistent volume claim %s/%s", obj.GetNamespace(), obj.GetName())
+}
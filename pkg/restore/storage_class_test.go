@@ -0,0 +1,150 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	velerov1api "github.com/heptio/velero/pkg/apis/velero/v1"
+	"github.com/heptio/velero/pkg/test"
+	velerotest "github.com/heptio/velero/pkg/util/test"
+)
+
+func TestChooseStorageClass(t *testing.T) {
+	block := corev1api.PersistentVolumeBlock
+	originalClass := "original-class"
+	emptyClass := ""
+
+	preferences := []velerov1api.StorageClassPreference{
+		{
+			StorageClassName: "rwo-class",
+			AccessModes:      []corev1api.PersistentVolumeAccessMode{corev1api.ReadWriteOnce},
+		},
+		{
+			StorageClassName: "rwx-class",
+			AccessModes:      []corev1api.PersistentVolumeAccessMode{corev1api.ReadWriteOnce, corev1api.ReadWriteMany},
+		},
+		{
+			StorageClassName: "rwx-class-2",
+			AccessModes:      []corev1api.PersistentVolumeAccessMode{corev1api.ReadWriteMany},
+		},
+		{
+			StorageClassName: "block-class",
+			AccessModes:      []corev1api.PersistentVolumeAccessMode{corev1api.ReadWriteOnce},
+			VolumeModes:      []corev1api.PersistentVolumeMode{corev1api.PersistentVolumeBlock},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		pvc       *corev1api.PersistentVolumeClaim
+		want      string
+		wantFound bool
+		wantErr   bool
+	}{
+		{
+			name: "an RWX claim gets the first preference that supports RWX",
+			pvc: test.NewPVC("ns-1", "pvc-1", func(obj metav1.Object) {
+				obj.(*corev1api.PersistentVolumeClaim).Spec.AccessModes = []corev1api.PersistentVolumeAccessMode{corev1api.ReadWriteMany}
+			}),
+			want: "rwx-class",
+		},
+		{
+			name: "an RWO claim gets the first preference",
+			pvc: test.NewPVC("ns-1", "pvc-1", func(obj metav1.Object) {
+				obj.(*corev1api.PersistentVolumeClaim).Spec.AccessModes = []corev1api.PersistentVolumeAccessMode{corev1api.ReadWriteOnce}
+			}),
+			want: "rwo-class",
+		},
+		{
+			name: "a block-mode claim gets the first preference that supports block volumes",
+			pvc: test.NewPVC("ns-1", "pvc-1", func(obj metav1.Object) {
+				pvc := obj.(*corev1api.PersistentVolumeClaim)
+				pvc.Spec.AccessModes = []corev1api.PersistentVolumeAccessMode{corev1api.ReadWriteOnce}
+				pvc.Spec.VolumeMode = &block
+			}),
+			want: "block-class",
+		},
+		{
+			name: "a claim bound to a volume keeps its storage class",
+			pvc: test.NewPVC("ns-1", "pvc-1", func(obj metav1.Object) {
+				pvc := obj.(*corev1api.PersistentVolumeClaim)
+				pvc.Spec.AccessModes = []corev1api.PersistentVolumeAccessMode{corev1api.ReadWriteMany}
+				pvc.Spec.VolumeName = "pv-1"
+				pvc.Spec.StorageClassName = &originalClass
+			}),
+			want: "original-class",
+		},
+		{
+			name: "a claim with an empty storage class keeps it",
+			pvc: test.NewPVC("ns-1", "pvc-1", func(obj metav1.Object) {
+				pvc := obj.(*corev1api.PersistentVolumeClaim)
+				pvc.Spec.AccessModes = []corev1api.PersistentVolumeAccessMode{corev1api.ReadWriteMany}
+				pvc.Spec.StorageClassName = &emptyClass
+			}),
+			want:      "",
+			wantFound: true,
+		},
+		{
+			name: "a claim with an empty beta storage class annotation keeps it",
+			pvc: test.NewPVC("ns-1", "pvc-1", test.WithAnnotations(corev1api.BetaStorageClassAnnotation, ""), func(obj metav1.Object) {
+				obj.(*corev1api.PersistentVolumeClaim).Spec.AccessModes = []corev1api.PersistentVolumeAccessMode{corev1api.ReadWriteMany}
+			}),
+			want: "",
+		},
+		{
+			name: "a claim that no preference supports is an error",
+			pvc: test.NewPVC("ns-1", "pvc-1", func(obj metav1.Object) {
+				obj.(*corev1api.PersistentVolumeClaim).Spec.AccessModes = []corev1api.PersistentVolumeAccessMode{corev1api.ReadOnlyMany}
+			}),
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := &context{
+				restore: NewBuilder().StorageClassPreferences(preferences...).Restore(),
+				log:     velerotest.NewLogger(),
+			}
+
+			u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(tc.pvc)
+			require.NoError(t, err)
+			obj := &unstructured.Unstructured{Object: u}
+
+			err = ctx.chooseStorageClass(obj)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			storageClassName, found, _ := unstructured.NestedString(obj.Object, "spec", "storageClassName")
+			assert.Equal(t, tc.want, storageClassName)
+			if tc.want == "" {
+				assert.Equal(t, tc.wantFound, found)
+			}
+		})
+	}
+}
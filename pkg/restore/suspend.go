@@ -0,0 +1,49 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	api "github.com/heptio/velero/pkg/apis/velero/v1"
+	"github.com/heptio/velero/pkg/util/kube"
+)
+
+// veleroObjectSuspenders set Velero objects of the group resources they're keyed
+// by to a suspended state, so that restoring them doesn't trigger Velero.
+var veleroObjectSuspenders = map[schema.GroupResource]func(obj *unstructured.Unstructured){
+	api.SchemeGroupVersion.WithResource("schedules").GroupResource(): func(obj *unstructured.Unstructured) {
+		unstructured.SetNestedField(obj.Object, true, "spec", "paused")
+	},
+}
+
+// suspendVeleroObject sets the provided Velero object to a suspended state if the
+// restore suspends Velero objects.
+func (ctx *context) suspendVeleroObject(obj *unstructured.Unstructured, groupResource schema.GroupResource) {
+	if !ctx.restore.Spec.SuspendVeleroObjects {
+		return
+	}
+
+	suspend, ok := veleroObjectSuspenders[groupResource]
+	if !ok {
+		return
+	}
+
+	ctx.log.Infof("Suspending %s %s", groupResource, kube.NamespaceAndName(obj))
+	suspend(obj)
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Span names emitted by the restorer.
+const (
+	RestoreSpanName  = "restore"
+	ResourceSpanName = "restore.resource"
+	ItemSpanName     = "restore.item"
+)
+
+// Attribute is a key/value pair attached to a span.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Span is a unit of work within a restore. It mirrors the subset of an
+// OpenTelemetry span that the restorer needs, so an OpenTelemetry tracer
+// can be adapted to it.
+type Span interface {
+	// SetAttributes adds the provided attributes to the span.
+	SetAttributes(attributes ...Attribute)
+
+	// End completes the span.
+	End()
+}
+
+// Tracer creates spans for the phases of a restore.
+type Tracer interface {
+	// StartSpan starts a span with the provided name and attributes. If
+	// parent is non-nil, the new span is its child.
+	StartSpan(name string, parent Span, attributes ...Attribute) Span
+}
+
+// noopTracer is a Tracer whose spans do nothing.
+type noopTracer struct{}
+
+func (noopTracer) StartSpan(string, Span, ...Attribute) Span { return noopSpan{} }
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attribute) {}
+func (noopSpan) End()                       {}
+
+// outcomeAttributes returns attributes counting the outcomes of the provided item results.
+func outcomeAttributes(results ItemResults) []Attribute {
+	counts := make(map[ItemOutcome]int)
+	for _, res := range results {
+		counts[res.Outcome]++
+	}
+
+	return []Attribute{
+		{Key: "items.total", Value: len(results)},
+		{Key: "items.created", Value: counts[ItemOutcomeCreated]},
+		{Key: "items.updated", Value: counts[ItemOutcomeUpdated]},
+		{Key: "items.skipped", Value: counts[ItemOutcomeSkipped]},
+		{Key: "items.failed", Value: counts[ItemOutcomeFailed]},
+		{Key: "items.dryRun", Value: counts[ItemOutcomeDryRun]},
+	}
+}
+
+// resourceSpan is the span of the restore of a resource's items.
+type resourceSpan struct {
+	Span
+
+	groupResource schema.GroupResource
+	start         int
+	started       time.Time
+}
+
+// startResourceSpan starts a span for the restore of the specified group resource's
+// items as a child of the restore span.
+func (ctx *context) startResourceSpan(groupResource schema.GroupResource) *resourceSpan {
+	return &resourceSpan{
+		Span:          ctx.tracer.StartSpan(ResourceSpanName, ctx.restoreSpan, Attribute{Key: "groupResource", Value: groupResource.String()}),
+		groupResource: groupResource,
+		start:         len(ctx.itemResults),
+		started:       time.Now(),
+	}
+}
+
+// endResourceSpan records the outcomes of the items of the provided span's resource
+// restored since it started and how long they took, and ends the span. Other
+// resources may be restored at the same time, so only the span's own resource's
+// items are counted.
+func (ctx *context) endResourceSpan(span *resourceSpan) {
+	var results ItemResults
+	for _, res := range ctx.itemResults[span.start:] {
+		if res.GroupResource == span.groupResource.String() {
+			results = append(results, res)
+		}
+	}
+
+	span.SetAttributes(outcomeAttributes(results)...)
+	span.End()
+
+	ctx.metrics.observeResourceDuration(span.groupResource, time.Since(span.started))
+}
+
+// traceItem calls restore to restore the specified item, wrapping it in an item span
+// under the provided resource span, or the restore span if it's nil, if the restore
+// traces individual items.
+func (ctx *context) traceItem(rscSpan *resourceSpan, groupResource schema.GroupResource, namespace, name string, restore func() (Result, Result)) (Result, Result) {
+	if !ctx.restore.Spec.TraceItems {
+		return restore()
+	}
+
+	parent := ctx.restoreSpan
+	if rscSpan != nil {
+		parent = rscSpan.Span
+	}
+
+	span := ctx.tracer.StartSpan(ItemSpanName, parent,
+		Attribute{Key: "groupResource", Value: groupResource.String()},
+		Attribute{Key: "namespace", Value: namespace},
+		Attribute{Key: "name", Value: name},
+	)
+	defer span.End()
+
+	start := len(ctx.itemResults)
+	warnings, errs := restore()
+
+	// any additional items restored along with the item have their
+	// outcomes recorded before the item's own
+	if results := ctx.itemResults[start:]; len(results) > 0 {
+		span.SetAttributes(Attribute{Key: "outcome", Value: string(results[len(results)-1].Outcome)})
+	}
+
+	return warnings, errs
+}
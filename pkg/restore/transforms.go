@@ -0,0 +1,132 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	api "github.com/heptio/velero/pkg/apis/velero/v1"
+)
+
+// Names of the transforms recorded in an item's applied transforms annotation.
+const (
+	transformPVSnapshotRestore        = "pv-snapshot-restore"
+	transformPreBindVolume            = "pre-bind-volume"
+	transformRestoreItemAction        = "restore-item-action"
+	transformPVCVolumeNameReset       = "pvc-volume-name-reset"
+	transformCSISnapshotDataSource    = "csi-snapshot-data-source"
+	transformSecretSubstitutions      = "secret-substitutions"
+	transformDataKeyMappings          = "data-key-mappings"
+	transformKubeconfigRewrites       = "kubeconfig-rewrites"
+	transformDefaultStorageClass      = "default-storage-class"
+	transformStorageClassPreference   = "storage-class-preferences"
+	transformStorageClassMappings     = "storage-class-mappings"
+	transformCSIVolumeAttributes      = "csi-volume-attribute-mappings"
+	transformCSIDriverMappings        = "csi-driver-mappings"
+	transformGatewayMappings          = "gateway-mappings"
+	transformCredentialSecretMappings = "credential-secret-mappings"
+	transformPodSpecOverrides         = "pod-spec-overrides"
+	transformServiceFieldOverrides    = "service-field-overrides"
+	transformVersionFixups            = "version-fixups"
+	transformHPAManagedReplicas       = "hpa-managed-replicas"
+	transformNamespaceMapping         = "namespace-mapping"
+	transformNameRewrite              = "name-rewrite"
+	transformMetadataLimits           = "metadata-limits"
+	transformSuspendVeleroObjects     = "suspend-velero-objects"
+	transformRelaxVolumeSettings      = "relax-volume-settings"
+	transformPVCMinimumSize           = "pvc-minimum-size"
+	transformPVCDataSelector          = "pvc-data-selector"
+	transformOwnerReferences          = "owner-references"
+	transformFieldExclusions          = "field-exclusions"
+	transformLeaderElectionRecords    = "leader-election-records"
+	transformNodeMappings             = "node-mappings"
+	transformScaleDown                = "scale-down"
+)
+
+// appliedTransforms records the transforms that change a single item during
+// its restore. When disabled, it doesn't copy or compare the item.
+type appliedTransforms struct {
+	enabled bool
+	names   []string
+}
+
+// newAppliedTransforms returns an appliedTransforms that's enabled if the
+// restore annotates items with their applied transforms.
+func (ctx *context) newAppliedTransforms() *appliedTransforms {
+	return &appliedTransforms{enabled: ctx.restore.Spec.AnnotateTransforms}
+}
+
+// record records that the named transform changed the item.
+func (t *appliedTransforms) record(name string) {
+	if !t.enabled {
+		return
+	}
+
+	for _, existing := range t.names {
+		if existing == name {
+			return
+		}
+	}
+	t.names = append(t.names, name)
+}
+
+// snapshot returns a copy of the provided item to compare against once a
+// transform has run, or nil if recording is disabled.
+func (t *appliedTransforms) snapshot(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	if !t.enabled {
+		return nil
+	}
+	return obj.DeepCopy()
+}
+
+// recordIfChanged records the named transform if the item differs from its
+// snapshot taken before the transform ran.
+func (t *appliedTransforms) recordIfChanged(name string, before, after *unstructured.Unstructured) {
+	if before == nil {
+		return
+	}
+
+	if !equality.Semantic.DeepEqual(before.Object, after.Object) {
+		t.record(name)
+	}
+}
+
+// track runs the provided in-place transform of the item, recording the named
+// transform if it changed the item.
+func (t *appliedTransforms) track(name string, obj *unstructured.Unstructured, transform func()) {
+	before := t.snapshot(obj)
+	transform()
+	t.recordIfChanged(name, before, obj)
+}
+
+// annotate sets the item's applied transforms annotation to the recorded
+// transforms, in the order they were applied.
+func (t *appliedTransforms) annotate(obj *unstructured.Unstructured) {
+	if !t.enabled || len(t.names) == 0 {
+		return
+	}
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[api.AppliedTransformsAnnotation] = strings.Join(t.names, ",")
+	obj.SetAnnotations(annotations)
+}
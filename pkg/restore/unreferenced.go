@@ -0,0 +1,183 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	api "github.com/heptio/velero/pkg/apis/velero/v1"
+	"github.com/heptio/velero/pkg/kuberesource"
+	"github.com/heptio/velero/pkg/plugin/velero"
+)
+
+const unreferencedReason = "not referenced by any restored workload"
+
+// configReferences returns the config maps and secrets referenced by the provided
+// item's embedded pod spec, or by the provided item if it's a service account, in the
+// item's namespace.
+func configReferences(obj *unstructured.Unstructured, groupResource schema.GroupResource) []velero.ResourceIdentifier {
+	var refs []velero.ResourceIdentifier
+
+	add := func(groupResource schema.GroupResource, parent map[string]interface{}, field string) {
+		if name, ok := parent[field].(string); ok && name != "" {
+			refs = append(refs, velero.ResourceIdentifier{GroupResource: groupResource, Namespace: obj.GetNamespace(), Name: name})
+		}
+	}
+
+	if podSpec, ok := getPodSpec(obj, groupResource); ok {
+		forEachMap(podSpec["imagePullSecrets"], func(ref map[string]interface{}) { add(kuberesource.Secrets, ref, "name") })
+
+		forEachMap(podSpec["volumes"], func(volume map[string]interface{}) {
+			if configMap, ok := volume["configMap"].(map[string]interface{}); ok {
+				add(kuberesource.ConfigMaps, configMap, "name")
+			}
+			if secret, ok := volume["secret"].(map[string]interface{}); ok {
+				add(kuberesource.Secrets, secret, "secretName")
+			}
+			if projected, ok := volume["projected"].(map[string]interface{}); ok {
+				forEachMap(projected["sources"], func(source map[string]interface{}) {
+					if configMap, ok := source["configMap"].(map[string]interface{}); ok {
+						add(kuberesource.ConfigMaps, configMap, "name")
+					}
+					if secret, ok := source["secret"].(map[string]interface{}); ok {
+						add(kuberesource.Secrets, secret, "name")
+					}
+				})
+			}
+		})
+
+		forEachContainer(podSpec, func(container map[string]interface{}) {
+			forEachMap(container["envFrom"], func(envFrom map[string]interface{}) {
+				if configMapRef, ok := envFrom["configMapRef"].(map[string]interface{}); ok {
+					add(kuberesource.ConfigMaps, configMapRef, "name")
+				}
+				if secretRef, ok := envFrom["secretRef"].(map[string]interface{}); ok {
+					add(kuberesource.Secrets, secretRef, "name")
+				}
+			})
+			forEachMap(container["env"], func(env map[string]interface{}) {
+				valueFrom, ok := env["valueFrom"].(map[string]interface{})
+				if !ok {
+					return
+				}
+				if configMapKeyRef, ok := valueFrom["configMapKeyRef"].(map[string]interface{}); ok {
+					add(kuberesource.ConfigMaps, configMapKeyRef, "name")
+				}
+				if secretKeyRef, ok := valueFrom["secretKeyRef"].(map[string]interface{}); ok {
+					add(kuberesource.Secrets, secretKeyRef, "name")
+				}
+			})
+		})
+	}
+
+	if groupResource == kuberesource.ServiceAccounts {
+		forEachMap(obj.Object["secrets"], func(ref map[string]interface{}) { add(kuberesource.Secrets, ref, "name") })
+		forEachMap(obj.Object["imagePullSecrets"], func(ref map[string]interface{}) { add(kuberesource.Secrets, ref, "name") })
+	}
+
+	return refs
+}
+
+// collectConfigReferences returns the config maps and secrets referenced by the workloads
+// and service accounts in the backup that the restore includes, as resource IDs in the
+// items' namespaces in the backup. Items that can't be decoded are ignored here, and are
+// reported when they're restored.
+func (ctx *context) collectConfigReferences(resourcesDir string) (sets.String, error) {
+	refs := sets.NewString()
+
+	groupResources := []schema.GroupResource{kuberesource.ServiceAccounts}
+	for groupResource := range podSpecPaths {
+		groupResources = append(groupResources, groupResource)
+	}
+
+	for _, groupResource := range groupResources {
+		if !ctx.resourceIncludesExcludes.ShouldInclude(groupResource.String()) {
+			continue
+		}
+
+		nsSubDir := filepath.Join(resourcesDir, groupResource.String(), api.NamespaceScopedDir)
+		exists, err := ctx.fileSystem.DirExists(nsSubDir)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if !exists {
+			continue
+		}
+
+		nsDirs, err := ctx.fileSystem.ReadDir(nsSubDir)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		for _, nsDir := range nsDirs {
+			if !nsDir.IsDir() || !ctx.namespaceIncludesExcludes.ShouldInclude(nsDir.Name()) {
+				continue
+			}
+
+			names, err := ctx.getItemSource().ListItems(groupResource, nsDir.Name())
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+
+			for _, name := range names {
+				if err := ctx.addConfigReferences(refs, groupResource, nsDir.Name(), name); err != nil {
+					ctx.log.WithError(err).Debugf("Ignoring %s %s/%s when collecting config references", groupResource, nsDir.Name(), name)
+				}
+			}
+		}
+	}
+
+	return refs, nil
+}
+
+// addConfigReferences adds the config references of the named item in the backup
+// to refs, if the item matches the restore's label selectors.
+func (ctx *context) addConfigReferences(refs sets.String, groupResource schema.GroupResource, namespace, name string) error {
+	obj, err := ctx.readItem(groupResource, namespace, name)
+	if err != nil {
+		return err
+	}
+
+	if !ctx.selector.Matches(labels.Set(obj.GetLabels())) {
+		return nil
+	}
+	if ctx.excludeSelector != nil && ctx.excludeSelector.Matches(labels.Set(obj.GetLabels())) {
+		return nil
+	}
+
+	for _, ref := range configReferences(obj, groupResource) {
+		refs.Insert(getResourceID(ref.GroupResource, ref.Namespace, ref.Name))
+	}
+	return nil
+}
+
+// isReferencedConfig returns false if the provided item is a config map or secret
+// that isn't referenced by any of the workloads or service accounts the restore
+// includes. Items of other resources are always considered referenced.
+func (ctx *context) isReferencedConfig(obj *unstructured.Unstructured, groupResource schema.GroupResource) bool {
+	if groupResource != kuberesource.ConfigMaps && groupResource != kuberesource.Secrets {
+		return true
+	}
+
+	return ctx.configReferences.Has(getResourceID(groupResource, obj.GetNamespace(), obj.GetName()))
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+	corev1api "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	api "github.com/heptio/velero/pkg/apis/velero/v1"
+	"github.com/heptio/velero/pkg/kuberesource"
+	"github.com/heptio/velero/pkg/plugin/velero"
+)
+
+// ingressResources are the group resources of ingresses, whose backends
+// reference services.
+var ingressResources = []schema.GroupResource{
+	{Group: "extensions", Resource: "ingresses"},
+	{Group: "networking.k8s.io", Resource: "ingresses"},
+}
+
+// itemReference is a reference from a restored item to another item.
+type itemReference struct {
+	from velero.ResourceIdentifier
+	to   velero.ResourceIdentifier
+}
+
+// UnresolvedReferences is a report of the references from restored items to items
+// that neither were restored nor exist in the cluster. It maps the group resource of
+// the referenced items to the resource ID of each referencing item, and that to the
+// namespaces and names of the items it references.
+type UnresolvedReferences map[string]map[string][]string
+
+// UnresolvedReferencesWriter stores the unresolved references reports of restores, e.g.
+// in the backup storage location of the restored backup.
+type UnresolvedReferencesWriter interface {
+	// PutUnresolvedReferences stores the provided report of the provided restore.
+	PutUnresolvedReferences(restore *api.Restore, report UnresolvedReferences) error
+}
+
+// add adds the provided reference to the report, if it's not already in it.
+func (r UnresolvedReferences) add(ref itemReference) {
+	referencing := r[ref.to.GroupResource.String()]
+	if referencing == nil {
+		referencing = make(map[string][]string)
+		r[ref.to.GroupResource.String()] = referencing
+	}
+
+	fromID := getResourceID(ref.from.GroupResource, ref.from.Namespace, ref.from.Name)
+	to := referencedName(ref.to)
+	for _, existing := range referencing[fromID] {
+		if existing == to {
+			return
+		}
+	}
+	referencing[fromID] = append(referencing[fromID], to)
+}
+
+// referencedName returns the namespace and name of the specified item, or just
+// its name if it's cluster-scoped.
+func referencedName(id velero.ResourceIdentifier) string {
+	if id.Namespace == "" {
+		return id.Name
+	}
+	return id.Namespace + "/" + id.Name
+}
+
+// storageClassReferences returns the storage class referenced by the provided
+// persistent volume or persistent volume claim, if any.
+func storageClassReferences(obj *unstructured.Unstructured, groupResource schema.GroupResource) []velero.ResourceIdentifier {
+	if groupResource != kuberesource.PersistentVolumes && groupResource != kuberesource.PersistentVolumeClaims {
+		return nil
+	}
+
+	// the beta annotation takes precedence over the spec field
+	name := obj.GetAnnotations()[corev1api.BetaStorageClassAnnotation]
+	if name == "" {
+		name, _, _ = unstructured.NestedString(obj.Object, "spec", "storageClassName")
+	}
+	if name == "" {
+		return nil
+	}
+
+	return []velero.ResourceIdentifier{{GroupResource: kuberesource.StorageClasses, Name: name}}
+}
+
+// serviceReferences returns the services referenced by the provided item's stateful
+// set service name or ingress backends, in the item's namespace.
+func serviceReferences(obj *unstructured.Unstructured, groupResource schema.GroupResource) []velero.ResourceIdentifier {
+	var refs []velero.ResourceIdentifier
+
+	add := func(parent map[string]interface{}) {
+		if name, ok := parent["serviceName"].(string); ok && name != "" {
+			refs = append(refs, velero.ResourceIdentifier{GroupResource: kuberesource.Services, Namespace: obj.GetNamespace(), Name: name})
+		}
+	}
+
+	if groupResource == (schema.GroupResource{Group: "apps", Resource: "statefulsets"}) {
+		if spec, found, _ := unstructured.NestedMap(obj.Object, "spec"); found {
+			add(spec)
+		}
+	}
+
+	for _, ingressResource := range ingressResources {
+		if groupResource != ingressResource {
+			continue
+		}
+
+		if backend, found, _ := unstructured.NestedMap(obj.Object, "spec", "backend"); found {
+			add(backend)
+		}
+		rules, _, _ := unstructured.NestedSlice(obj.Object, "spec", "rules")
+		forEachMap(rules, func(rule map[string]interface{}) {
+			paths, _, _ := unstructured.NestedSlice(rule, "http", "paths")
+			forEachMap(paths, func(path map[string]interface{}) {
+				if backend, ok := path["backend"].(map[string]interface{}); ok {
+					add(backend)
+				}
+			})
+		})
+	}
+
+	return refs
+}
+
+// recordRestoredReferences keeps track of the references of the provided item, as created
+// by the restore, if the restore reports unresolved references.
+func (ctx *context) recordRestoredReferences(obj *unstructured.Unstructured, groupResource schema.GroupResource) {
+	if !ctx.restore.Spec.ReportUnresolvedReferences {
+		return
+	}
+
+	from := velero.ResourceIdentifier{GroupResource: groupResource, Namespace: obj.GetNamespace(), Name: obj.GetName()}
+
+	refs := configReferences(obj, groupResource)
+	refs = append(refs, storageClassReferences(obj, groupResource)...)
+	refs = append(refs, serviceReferences(obj, groupResource)...)
+	if groupResource == kuberesource.RoleBindings || groupResource == kuberesource.ClusterRoleBindings {
+		refs = append(refs, bindingReferences(obj)...)
+	}
+
+	for _, to := range refs {
+		ctx.restoredReferences = append(ctx.restoredReferences, itemReference{from: from, to: to})
+	}
+}
+
+// unresolvedReferences returns a report of the references of restored items to items
+// that the restore didn't create or update and that don't exist in the cluster. A
+// warning is returned for each reference that can't be checked.
+func (ctx *context) unresolvedReferences() (UnresolvedReferences, Result) {
+	report := UnresolvedReferences{}
+	warnings := Result{}
+
+	restored := sets.NewString()
+	for _, res := range ctx.itemResults {
+		if res.Outcome == ItemOutcomeCreated || res.Outcome == ItemOutcomeUpdated || res.Outcome == ItemOutcomeDryRun {
+			restored.Insert(getResourceID(schema.ParseGroupResource(res.GroupResource), res.Namespace, res.Name))
+		}
+	}
+
+	existing := make(map[string]bool)
+	for _, ref := range ctx.restoredReferences {
+		toID := getResourceID(ref.to.GroupResource, ref.to.Namespace, ref.to.Name)
+		if restored.Has(toID) {
+			continue
+		}
+
+		exists, checked := existing[toID]
+		if !checked {
+			var err error
+			if exists, err = ctx.existsInCluster(ref.to); err != nil {
+				addToResult(&warnings, ref.from.Namespace, errors.Wrapf(err, "error checking reference of %s to %s", getResourceID(ref.from.GroupResource, ref.from.Namespace, ref.from.Name), toID))
+				continue
+			}
+			existing[toID] = exists
+		}
+
+		if !exists {
+			report.add(ref)
+		}
+	}
+
+	// items are restored concurrently, so sort the referenced items
+	// to keep the report stable
+	for _, referencing := range report {
+		for _, to := range referencing {
+			sort.Strings(to)
+		}
+	}
+
+	return report, warnings
+}
+
+// reportUnresolvedReferences stores the report of the restore's unresolved references with
+// the restorer's unresolved references writer, and returns a single warning that counts
+// the restored items with unresolved references, if there are any.
+func (ctx *context) reportUnresolvedReferences() Result {
+	report, warnings := ctx.unresolvedReferences()
+
+	if ctx.unresolvedReferencesWriter != nil {
+		if err := ctx.unresolvedReferencesWriter.PutUnresolvedReferences(ctx.restore, report); err != nil {
+			addVeleroError(&warnings, errors.Wrap(err, "error storing the restore's unresolved references report"))
+		}
+	}
+
+	referencing := sets.NewString()
+	for _, items := range report {
+		for fromID := range items {
+			referencing.Insert(fromID)
+		}
+	}
+	if referencing.Len() > 0 {
+		err := errors.Errorf("%d restored items have unresolved references, which are listed in the restore's unresolved references report", referencing.Len())
+		ctx.log.WithField("unresolvedReferences", report).Warn(err.Error())
+		addVeleroError(&warnings, err)
+	}
+
+	return warnings
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/heptio/velero/pkg/kuberesource"
+	"github.com/heptio/velero/pkg/plugin/velero"
+)
+
+func TestUnresolvedReferencesAdd(t *testing.T) {
+	job := velero.ResourceIdentifier{GroupResource: kuberesource.Jobs, Namespace: "ns-1", Name: "job-1"}
+	pvc := velero.ResourceIdentifier{GroupResource: kuberesource.PersistentVolumeClaims, Namespace: "ns-1", Name: "pvc-1"}
+
+	report := UnresolvedReferences{}
+	report.add(itemReference{from: job, to: velero.ResourceIdentifier{GroupResource: kuberesource.Secrets, Namespace: "ns-1", Name: "secret-1"}})
+	report.add(itemReference{from: job, to: velero.ResourceIdentifier{GroupResource: kuberesource.Secrets, Namespace: "ns-1", Name: "secret-2"}})
+	// references from the same item to the same item are only reported once
+	report.add(itemReference{from: job, to: velero.ResourceIdentifier{GroupResource: kuberesource.Secrets, Namespace: "ns-1", Name: "secret-1"}})
+	report.add(itemReference{from: pvc, to: velero.ResourceIdentifier{GroupResource: kuberesource.StorageClasses, Name: "fast"}})
+
+	assert.Equal(t, UnresolvedReferences{
+		"secrets": {
+			"jobs.batch/ns-1/job-1": {"ns-1/secret-1", "ns-1/secret-2"},
+		},
+		"storageclasses.storage.k8s.io": {
+			"persistentvolumeclaims/ns-1/pvc-1": {"fast"},
+		},
+	}, report)
+}
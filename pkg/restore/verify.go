@@ -0,0 +1,61 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/heptio/velero/pkg/plugin/velero"
+)
+
+// verifyRestoredItems gets each item the restore created or updated from the cluster,
+// and returns a verification failure for each one that doesn't exist, e.g. because an
+// admission webhook dropped it. A warning is returned for each item that can't be
+// checked.
+func (ctx *context) verifyRestoredItems() ([]string, Result) {
+	var failures []string
+	warnings := Result{}
+
+	for _, res := range ctx.itemResults {
+		if res.Outcome != ItemOutcomeCreated && res.Outcome != ItemOutcomeUpdated {
+			continue
+		}
+
+		id := velero.ResourceIdentifier{
+			GroupResource: schema.ParseGroupResource(res.GroupResource),
+			Namespace:     res.Namespace,
+			Name:          res.Name,
+		}
+		resourceID := getResourceID(id.GroupResource, id.Namespace, id.Name)
+
+		if _, err := ctx.getFromCluster(id); err != nil {
+			if apierrors.IsNotFound(errors.Cause(err)) {
+				ctx.log.Warnf("Restored item %s doesn't exist in the cluster", resourceID)
+				failures = append(failures, fmt.Sprintf("%s was restored but doesn't exist in the cluster", resourceID))
+				continue
+			}
+
+			addToResult(&warnings, id.Namespace, errors.Wrapf(err, "error verifying that %s exists", resourceID))
+		}
+	}
+
+	return failures, warnings
+}
@@ -0,0 +1,187 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/version"
+
+	api "github.com/heptio/velero/pkg/apis/velero/v1"
+	"github.com/heptio/velero/pkg/kuberesource"
+	"github.com/heptio/velero/pkg/util/kube"
+)
+
+// podSeccompAnnotation is the deprecated annotation that set the seccomp
+// profile of a pod before the pod security context's seccomp profile field.
+const podSeccompAnnotation = "seccomp.security.alpha.kubernetes.io/pod"
+
+// versionFixup changes items of the resources it applies to so that clusters
+// at or after a Kubernetes minor version accept them.
+type versionFixup struct {
+	// minMinor is the first minor version of Kubernetes 1.x that doesn't
+	// support the field.
+	minMinor int
+
+	// field and change describe the field and how it's fixed up, for
+	// warnings and errors.
+	field  string
+	change string
+
+	appliesTo func(groupResource schema.GroupResource) bool
+
+	// apply fixes up the provided item, returning true if it changed it.
+	apply func(obj *unstructured.Unstructured, groupResource schema.GroupResource) bool
+}
+
+// versionFixups is the registry of fixups applied to restored items according
+// to the cluster's Kubernetes version.
+var versionFixups = []versionFixup{
+	{
+		minMinor:  22,
+		field:     "spec.topologyKeys",
+		change:    "removed",
+		appliesTo: func(groupResource schema.GroupResource) bool { return groupResource == kuberesource.Services },
+		apply: func(obj *unstructured.Unstructured, _ schema.GroupResource) bool {
+			if _, found, _ := unstructured.NestedFieldNoCopy(obj.Object, "spec", "topologyKeys"); !found {
+				return false
+			}
+			unstructured.RemoveNestedField(obj.Object, "spec", "topologyKeys")
+			return true
+		},
+	},
+	{
+		minMinor: 27,
+		field:    "annotation " + podSeccompAnnotation,
+		change:   "moved to the pod security context's seccomp profile",
+		appliesTo: func(groupResource schema.GroupResource) bool {
+			_, ok := podSpecPaths[groupResource]
+			return ok
+		},
+		apply: moveSeccompAnnotation,
+	},
+}
+
+// serverMinorVersion returns the minor version of the provided Kubernetes 1.x
+// version, or false if it's not a valid 1.x version.
+func serverMinorVersion(info *version.Info) (int, bool) {
+	if info == nil || info.Major != "1" {
+		return 0, false
+	}
+
+	// providers may add a suffix, e.g. "22+"
+	minor, err := strconv.Atoi(strings.TrimRight(info.Minor, "+"))
+	if err != nil {
+		return 0, false
+	}
+	return minor, true
+}
+
+// applyVersionFixups applies the fixups for the cluster's Kubernetes version to the
+// provided item according to the restore's version compatibility mode. In fixup mode,
+// a warning is returned for each fixup applied. In strict mode, the item is unchanged,
+// and an error is returned if it needs a fixup. No fixups are applied if the cluster's
+// version isn't known.
+func (ctx *context) applyVersionFixups(obj *unstructured.Unstructured, groupResource schema.GroupResource) ([]error, error) {
+	mode := ctx.restore.Spec.VersionCompatibilityMode
+	if mode != api.VersionCompatibilityModeFixup && mode != api.VersionCompatibilityModeStrict {
+		return nil, nil
+	}
+
+	minor, ok := serverMinorVersion(ctx.discoveryHelper.ServerVersion())
+	if !ok {
+		return nil, nil
+	}
+
+	var warnings []error
+	for _, fixup := range versionFixups {
+		if minor < fixup.minMinor || !fixup.appliesTo(groupResource) {
+			continue
+		}
+
+		if mode == api.VersionCompatibilityModeStrict {
+			if fixup.apply(obj.DeepCopy(), groupResource) {
+				return nil, errors.Errorf("%s of %s %s isn't supported by the cluster's Kubernetes version 1.%d", fixup.field, groupResource, kube.NamespaceAndName(obj), minor)
+			}
+			continue
+		}
+
+		if fixup.apply(obj, groupResource) {
+			warnings = append(warnings, errors.Errorf("%s of %s %s %s because Kubernetes 1.%d and later don't support it", fixup.field, groupResource, kube.NamespaceAndName(obj), fixup.change, fixup.minMinor))
+		}
+	}
+
+	return warnings, nil
+}
+
+// moveSeccompAnnotation removes the deprecated pod seccomp annotation from the pod,
+// or pod template, of the provided item, and sets the pod security context's seccomp
+// profile to the annotation's profile, unless the security context already has one.
+func moveSeccompAnnotation(obj *unstructured.Unstructured, groupResource schema.GroupResource) bool {
+	podSpec, ok := getPodSpec(obj, groupResource)
+	if !ok {
+		return false
+	}
+
+	// the pod's metadata is the pod spec's sibling
+	specPath := podSpecPaths[groupResource]
+	annotationsPath := append(append([]string{}, specPath[:len(specPath)-1]...), "metadata", "annotations")
+
+	annotations, _, err := unstructured.NestedStringMap(obj.Object, annotationsPath...)
+	if err != nil {
+		return false
+	}
+	value, ok := annotations[podSeccompAnnotation]
+	if !ok {
+		return false
+	}
+
+	delete(annotations, podSeccompAnnotation)
+	if len(annotations) == 0 {
+		unstructured.RemoveNestedField(obj.Object, annotationsPath...)
+	} else {
+		unstructured.SetNestedStringMap(obj.Object, annotations, annotationsPath...)
+	}
+
+	if _, found, _ := unstructured.NestedFieldNoCopy(podSpec, "securityContext", "seccompProfile"); found {
+		return true
+	}
+	if profile := seccompProfile(value); profile != nil {
+		unstructured.SetNestedMap(podSpec, profile, "securityContext", "seccompProfile")
+	}
+
+	return true
+}
+
+// seccompProfile returns the seccomp profile field for the provided value of the
+// deprecated seccomp annotation, or nil if the value isn't valid.
+func seccompProfile(value string) map[string]interface{} {
+	switch {
+	case value == "runtime/default" || value == "docker/default":
+		return map[string]interface{}{"type": "RuntimeDefault"}
+	case value == "unconfined":
+		return map[string]interface{}{"type": "Unconfined"}
+	case strings.HasPrefix(value, "localhost/"):
+		return map[string]interface{}{"type": "Localhost", "localhostProfile": strings.TrimPrefix(value, "localhost/")}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/version"
+
+	"github.com/heptio/velero/pkg/kuberesource"
+)
+
+func TestServerMinorVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		info      *version.Info
+		wantMinor int
+		wantOK    bool
+	}{
+		{name: "a plain version", info: &version.Info{Major: "1", Minor: "27"}, wantMinor: 27, wantOK: true},
+		{name: "a provider suffix is ignored", info: &version.Info{Major: "1", Minor: "22+"}, wantMinor: 22, wantOK: true},
+		{name: "an unknown version", info: nil},
+		{name: "a version that isn't 1.x", info: &version.Info{Major: "2", Minor: "0"}},
+		{name: "an invalid minor version", info: &version.Info{Major: "1", Minor: "x"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			minor, ok := serverMinorVersion(tc.info)
+			assert.Equal(t, tc.wantOK, ok)
+			assert.Equal(t, tc.wantMinor, minor)
+		})
+	}
+}
+
+func TestMoveSeccompAnnotation(t *testing.T) {
+	tests := []struct {
+		name            string
+		obj             map[string]interface{}
+		wantChanged     bool
+		wantAnnotations map[string]string
+		wantProfile     map[string]interface{}
+	}{
+		{
+			name: "a pod's annotation is moved to its security context",
+			obj: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]interface{}{podSeccompAnnotation: "runtime/default", "foo": "bar"},
+				},
+				"spec": map[string]interface{}{},
+			},
+			wantChanged:     true,
+			wantAnnotations: map[string]string{"foo": "bar"},
+			wantProfile:     map[string]interface{}{"type": "RuntimeDefault"},
+		},
+		{
+			name: "a localhost profile keeps its path",
+			obj: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]interface{}{podSeccompAnnotation: "localhost/profiles/audit.json"},
+				},
+				"spec": map[string]interface{}{},
+			},
+			wantChanged: true,
+			wantProfile: map[string]interface{}{"type": "Localhost", "localhostProfile": "profiles/audit.json"},
+		},
+		{
+			name: "an existing seccomp profile isn't overwritten",
+			obj: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]interface{}{podSeccompAnnotation: "unconfined"},
+				},
+				"spec": map[string]interface{}{
+					"securityContext": map[string]interface{}{
+						"seccompProfile": map[string]interface{}{"type": "RuntimeDefault"},
+					},
+				},
+			},
+			wantChanged: true,
+			wantProfile: map[string]interface{}{"type": "RuntimeDefault"},
+		},
+		{
+			name: "a pod without the annotation is unchanged",
+			obj: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]interface{}{"foo": "bar"},
+				},
+				"spec": map[string]interface{}{},
+			},
+			wantAnnotations: map[string]string{"foo": "bar"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			obj := &unstructured.Unstructured{Object: tc.obj}
+
+			assert.Equal(t, tc.wantChanged, moveSeccompAnnotation(obj, kuberesource.Pods))
+
+			annotations, _, err := unstructured.NestedStringMap(obj.Object, "metadata", "annotations")
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantAnnotations, annotations)
+
+			profile, _, err := unstructured.NestedMap(obj.Object, "spec", "securityContext", "seccompProfile")
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantProfile, profile)
+		})
+	}
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/heptio/velero/pkg/kuberesource"
+	"github.com/heptio/velero/pkg/plugin/velero"
+)
+
+// restoresClaim returns true if the restore restores the specified persistent
+// volume claim from the backup.
+func (ctx *context) restoresClaim(namespace, name string) bool {
+	if !ctx.resourceIncludesExcludes.ShouldInclude(kuberesource.PersistentVolumeClaims.String()) || !ctx.namespaceIncludesExcludes.ShouldInclude(namespace) {
+		return false
+	}
+
+	obj, err := ctx.readItem(kuberesource.PersistentVolumeClaims, namespace, name)
+	if err != nil {
+		return false
+	}
+
+	if !ctx.selector.Matches(labels.Set(obj.GetLabels())) {
+		return false
+	}
+	if ctx.excludeSelector != nil && ctx.excludeSelector.Matches(labels.Set(obj.GetLabels())) {
+		return false
+	}
+	return true
+}
+
+// preBindVolume sets the claim reference of the provided persistent volume to the claim
+// it was bound to in the backup, as restored into its target namespace, if the restore
+// restores the claim into a single namespace. The reference doesn't include the backed-up
+// claim's UID, since the restored claim is a new object.
+func (ctx *context) preBindVolume(obj, fromBackup *unstructured.Unstructured) {
+	namespace, _, _ := unstructured.NestedString(fromBackup.Object, "spec", "claimRef", "namespace")
+	name, _, _ := unstructured.NestedString(fromBackup.Object, "spec", "claimRef", "name")
+	if namespace == "" || name == "" || !ctx.restoresClaim(namespace, name) {
+		return
+	}
+
+	targets := ctx.targetNamespaces(namespace)
+	if len(targets) != 1 {
+		ctx.log.Infof("Not binding persistent volume %s to its claim because the claim is restored into %d namespaces", obj.GetName(), len(targets))
+		return
+	}
+
+	ctx.log.Infof("Binding persistent volume %s to claim %s/%s", obj.GetName(), targets[0], name)
+	unstructured.SetNestedMap(obj.Object, map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "PersistentVolumeClaim",
+		"namespace":  targets[0],
+		"name":       name,
+	}, "spec", "claimRef")
+}
+
+// recordPreBoundVolume keeps track of the provided item, as created by the restore, if
+// it's a persistent volume that was bound to its claim before the claim was restored.
+func (ctx *context) recordPreBoundVolume(obj *unstructured.Unstructured, groupResource schema.GroupResource) {
+	if !ctx.restore.Spec.PreBindVolumes || groupResource != kuberesource.PersistentVolumes {
+		return
+	}
+
+	if _, found, _ := unstructured.NestedMap(obj.Object, "spec", "claimRef"); found {
+		ctx.preBoundVolumes = append(ctx.preBoundVolumes, obj)
+	}
+}
+
+// checkVolumeBindings returns a warning for each persistent volume that was bound to its
+// claim before the claim was restored, if the volume is now bound to a different claim or
+// the claim is bound to a different volume.
+func (ctx *context) checkVolumeBindings() Result {
+	warnings := Result{}
+
+	for _, pv := range ctx.preBoundVolumes {
+		namespace, _, _ := unstructured.NestedString(pv.Object, "spec", "claimRef", "namespace")
+		name, _, _ := unstructured.NestedString(pv.Object, "spec", "claimRef", "name")
+
+		fromCluster, err := ctx.getFromCluster(velero.ResourceIdentifier{GroupResource: kuberesource.PersistentVolumes, Name: pv.GetName()})
+		if err != nil {
+			addToResult(&warnings, namespace, errors.Wrapf(err, "error checking binding of persistent volume %s", pv.GetName()))
+			continue
+		}
+		boundNamespace, _, _ := unstructured.NestedString(fromCluster.Object, "spec", "claimRef", "namespace")
+		boundName, _, _ := unstructured.NestedString(fromCluster.Object, "spec", "claimRef", "name")
+		if boundNamespace != namespace || boundName != name {
+			addToResult(&warnings, namespace, errors.Errorf("persistent volume %s is bound to claim %s/%s instead of %s/%s", pv.GetName(), boundNamespace, boundName, namespace, name))
+			continue
+		}
+
+		claim, err := ctx.getFromCluster(velero.ResourceIdentifier{GroupResource: kuberesource.PersistentVolumeClaims, Namespace: namespace, Name: name})
+		if apierrors.IsNotFound(errors.Cause(err)) {
+			addToResult(&warnings, namespace, errors.Errorf("persistent volume %s is reserved for claim %s/%s, which doesn't exist", pv.GetName(), namespace, name))
+			continue
+		}
+		if err != nil {
+			addToResult(&warnings, namespace, errors.Wrapf(err, "error checking binding of persistent volume %s", pv.GetName()))
+			continue
+		}
+		if volumeName, _, _ := unstructured.NestedString(claim.Object, "spec", "volumeName"); volumeName != "" && volumeName != pv.GetName() {
+			addToResult(&warnings, namespace, errors.Errorf("claim %s/%s is bound to persistent volume %s instead of %s", namespace, name, volumeName, pv.GetName()))
+		}
+	}
+
+	return warnings
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"github.com/pkg/errors"
+	corev1api "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/heptio/velero/pkg/util/kube"
+)
+
+// relaxVolumeSettings relaxes the volume settings of the pod spec embedded in the
+// provided item, if it has one, according to the restore's volume settings
+// relaxation. A warning is returned for each volume mount or device changed.
+func (ctx *context) relaxVolumeSettings(obj *unstructured.Unstructured, groupResource schema.GroupResource) []error {
+	settings := ctx.restore.Spec.RelaxVolumeSettings
+	if settings == nil {
+		return nil
+	}
+
+	podSpec, ok := getPodSpec(obj, groupResource)
+	if !ok {
+		return nil
+	}
+
+	var warnings []error
+	forEachContainer(podSpec, func(container map[string]interface{}) {
+		containerName, _ := container["name"].(string)
+
+		if settings.ConvertVolumeDevices {
+			if devices, ok := container["volumeDevices"].([]interface{}); ok {
+				mounts, _ := container["volumeMounts"].([]interface{})
+				for _, d := range devices {
+					device, ok := d.(map[string]interface{})
+					if !ok {
+						continue
+					}
+					mounts = append(mounts, map[string]interface{}{
+						"name":      device["name"],
+						"mountPath": device["devicePath"],
+					})
+					warnings = append(warnings, errors.Errorf("converted volume device %v of container %s in %s %s to a volume mount", device["name"], containerName, groupResource, kube.NamespaceAndName(obj)))
+				}
+				container["volumeMounts"] = mounts
+				delete(container, "volumeDevices")
+			}
+		}
+
+		mounts, ok := container["volumeMounts"].([]interface{})
+		if !ok {
+			return
+		}
+		for _, m := range mounts {
+			mount, ok := m.(map[string]interface{})
+			if !ok || mount["mountPropagation"] != string(corev1api.MountPropagationBidirectional) {
+				continue
+			}
+
+			// no mount propagation is the same as None
+			delete(mount, "mountPropagation")
+			warnings = append(warnings, errors.Errorf("downgraded mount propagation of volume mount %v of container %s in %s %s from %s to %s", mount["name"], containerName, groupResource, kube.NamespaceAndName(obj), corev1api.MountPropagationBidirectional, corev1api.MountPropagationNone))
+		}
+	})
+
+	return warnings
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1api "k8s.io/api/apps/v1"
+	corev1api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	velerov1api "github.com/heptio/velero/pkg/apis/velero/v1"
+	velerotest "github.com/heptio/velero/pkg/util/test"
+)
+
+func TestRelaxVolumeSettings(t *testing.T) {
+	bidirectional := corev1api.MountPropagationBidirectional
+	hostToContainer := corev1api.MountPropagationHostToContainer
+
+	deployment := func(container corev1api.Container) *appsv1api.Deployment {
+		container.Name = "container-1"
+		return &appsv1api.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns-1", Name: "deploy-1"},
+			Spec: appsv1api.DeploymentSpec{
+				Template: corev1api.PodTemplateSpec{
+					Spec: corev1api.PodSpec{Containers: []corev1api.Container{container}},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		restore      *velerov1api.Restore
+		obj          *appsv1api.Deployment
+		expected     *appsv1api.Deployment
+		wantWarnings int
+	}{
+		{
+			name:    "bidirectional mount propagation on a deployment's pod template is downgraded",
+			restore: NewBuilder().RelaxVolumeSettings(velerov1api.RelaxVolumeSettings{}).Restore(),
+			obj: deployment(corev1api.Container{
+				VolumeMounts: []corev1api.VolumeMount{
+					{Name: "vol-1", MountPath: "/data", MountPropagation: &bidirectional},
+					{Name: "vol-2", MountPath: "/host", MountPropagation: &hostToContainer},
+				},
+			}),
+			expected: deployment(corev1api.Container{
+				VolumeMounts: []corev1api.VolumeMount{
+					{Name: "vol-1", MountPath: "/data"},
+					{Name: "vol-2", MountPath: "/host", MountPropagation: &hostToContainer},
+				},
+			}),
+			wantWarnings: 1,
+		},
+		{
+			name:    "volume devices are converted to volume mounts when the restore converts them",
+			restore: NewBuilder().RelaxVolumeSettings(velerov1api.RelaxVolumeSettings{ConvertVolumeDevices: true}).Restore(),
+			obj: deployment(corev1api.Container{
+				VolumeDevices: []corev1api.VolumeDevice{{Name: "vol-1", DevicePath: "/dev/xvda"}},
+			}),
+			expected: deployment(corev1api.Container{
+				VolumeMounts: []corev1api.VolumeMount{{Name: "vol-1", MountPath: "/dev/xvda"}},
+			}),
+			wantWarnings: 1,
+		},
+		{
+			name:    "volume devices are unchanged when the restore doesn't convert them",
+			restore: NewBuilder().RelaxVolumeSettings(velerov1api.RelaxVolumeSettings{}).Restore(),
+			obj: deployment(corev1api.Container{
+				VolumeDevices: []corev1api.VolumeDevice{{Name: "vol-1", DevicePath: "/dev/xvda"}},
+			}),
+			expected: deployment(corev1api.Container{
+				VolumeDevices: []corev1api.VolumeDevice{{Name: "vol-1", DevicePath: "/dev/xvda"}},
+			}),
+		},
+		{
+			name:    "volume settings are unchanged when the restore doesn't relax them",
+			restore: NewBuilder().Restore(),
+			obj: deployment(corev1api.Container{
+				VolumeMounts: []corev1api.VolumeMount{{Name: "vol-1", MountPath: "/data", MountPropagation: &bidirectional}},
+			}),
+			expected: deployment(corev1api.Container{
+				VolumeMounts: []corev1api.VolumeMount{{Name: "vol-1", MountPath: "/data", MountPropagation: &bidirectional}},
+			}),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := &context{
+				restore: tc.restore,
+				log:     velerotest.NewLogger(),
+			}
+
+			u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(tc.obj)
+			require.NoError(t, err)
+			obj := &unstructured.Unstructured{Object: u}
+
+			warnings := ctx.relaxVolumeSettings(obj, schema.GroupResource{Group: "apps", Resource: "deployments"})
+			assert.Len(t, warnings, tc.wantWarnings)
+
+			res := new(appsv1api.Deployment)
+			require.NoError(t, runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, res))
+			assert.Equal(t, tc.expected, res)
+		})
+	}
+}
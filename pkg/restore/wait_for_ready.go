@@ -0,0 +1,130 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package restore
+
+import (
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/heptio/velero/pkg/discovery"
+	"github.com/heptio/velero/pkg/kuberesource"
+	"github.com/heptio/velero/pkg/util/kube"
+)
+
+// readinessCheck returns true if the provided restored item is ready.
+type readinessCheck func(obj *unstructured.Unstructured) bool
+
+// readinessChecks are the readiness checks of the resources a restore
+// can wait for.
+var readinessChecks = map[schema.GroupResource]readinessCheck{
+	kuberesource.Pods:                              isPodReady,
+	{Group: "apps", Resource: "deployments"}:       isDeploymentAvailable,
+	{Group: "extensions", Resource: "deployments"}: isDeploymentAvailable,
+}
+
+// isPodReady returns true if the provided pod has a true Ready condition.
+func isPodReady(obj *unstructured.Unstructured) bool {
+	return hasTrueCondition(obj, "Ready")
+}
+
+// isDeploymentAvailable returns true if all of the provided deployment's desired
+// replicas are available.
+func isDeploymentAvailable(obj *unstructured.Unstructured) bool {
+	desired, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	if !found {
+		// the API server defaults the replicas to 1
+		desired = 1
+	}
+	available, _, _ := unstructured.NestedInt64(obj.Object, "status", "availableReplicas")
+
+	return available >= desired
+}
+
+// getReadinessChecks resolves the provided resources a restore waits for into their
+// readiness checks. Resources the cluster doesn't serve are ignored, and resources
+// without a readiness check are an error.
+func getReadinessChecks(helper discovery.Helper, resources []string, log logrus.FieldLogger) (map[schema.GroupResource]readinessCheck, error) {
+	if len(resources) == 0 {
+		return nil, nil
+	}
+
+	checks := make(map[schema.GroupResource]readinessCheck)
+	for _, r := range resources {
+		gvr, _, err := helper.ResourceFor(schema.ParseGroupResource(r).WithVersion(""))
+		if err != nil {
+			log.WithError(err).WithField("resource", r).Info("Not waiting for readiness of a resource the cluster doesn't serve")
+			continue
+		}
+		gr := gvr.GroupResource()
+
+		check, ok := readinessChecks[gr]
+		if !ok {
+			return nil, errors.Errorf("can't wait for readiness of resource %s, only pods and deployments are supported", r)
+		}
+		checks[gr] = check
+	}
+
+	return checks, nil
+}
+
+// readyItems collects the items created by the restore of a single resource that
+// the restore waits for to become ready. Several resources can be restored at once,
+// so each restore of a resource has its own.
+type readyItems struct {
+	items []createdItem
+}
+
+// add adds the provided created item if the restore waits for its resource to
+// become ready.
+func (r *readyItems) add(ctx *context, item createdItem) {
+	if _, ok := ctx.readinessChecks[item.groupResource]; ok {
+		r.items = append(r.items, item)
+	}
+}
+
+// waitForReadyItems waits for each of the provided items to become ready. Items that
+// aren't ready within the readiness timeout are returned as warnings, or as errors if
+// the restore fails on readiness timeouts. Other items are restored while it waits.
+func (ctx *context) waitForReadyItems(ready *readyItems) (Result, Result) {
+	warnings, errs := Result{}, Result{}
+
+	timeout := ctx.readinessTimeout()
+
+	for _, item := range ready.items {
+		log := ctx.log.WithField("groupResource", item.groupResource.String()).WithField("item", kube.NamespaceAndName(item.obj))
+		log.Info("Waiting for restored item to become ready")
+
+		check := ctx.readinessChecks[item.groupResource]
+		var err error
+		ctx.withoutItemLock(func() { err = pollItem(item.client, item.obj.GetName(), timeout, "it to become ready", log, check) })
+		if err != nil {
+			err = errors.Wrapf(err, "%s %s is not ready", item.groupResource, kube.NamespaceAndName(item.obj))
+			if ctx.restore.Spec.FailOnReadinessTimeout {
+				addToResult(&errs, item.namespace, err)
+			} else {
+				addToResult(&warnings, item.namespace, err)
+			}
+			continue
+		}
+
+		log.Info("Restored item is ready")
+	}
+
+	return warnings, errs
+}
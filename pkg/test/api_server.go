@@ -38,7 +38,7 @@ type APIServer struct {
 
 // NewAPIServer constructs an APIServer with all of its clients
 // initialized.
-func NewAPIServer(t *testing.T) *APIServer {
+func NewAPIServer(t testing.TB) *APIServer {
 	t.Helper()
 
 	var (
@@ -20,7 +20,12 @@ import (
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	storagev1beta1 "k8s.io/api/storage/v1beta1"
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
@@ -90,6 +95,17 @@ func Secrets(items ...metav1.Object) *APIResource {
 	}
 }
 
+func ConfigMaps(items ...metav1.Object) *APIResource {
+	return &APIResource{
+		Group:      "",
+		Version:    "v1",
+		Name:       "configmaps",
+		ShortName:  "cm",
+		Namespaced: true,
+		Items:      items,
+	}
+}
+
 func Deployments(items ...metav1.Object) *APIResource {
 	return &APIResource{
 		Group:      "apps",
@@ -112,6 +128,17 @@ func ExtensionsDeployments(items ...metav1.Object) *APIResource {
 	}
 }
 
+func Jobs(items ...metav1.Object) *APIResource {
+	return &APIResource{
+		Group:      "batch",
+		Version:    "v1",
+		Name:       "jobs",
+		ShortName:  "job",
+		Namespaced: true,
+		Items:      items,
+	}
+}
+
 func Namespaces(items ...metav1.Object) *APIResource {
 	return &APIResource{
 		Group:      "",
@@ -134,6 +161,101 @@ func ServiceAccounts(items ...metav1.Object) *APIResource {
 	}
 }
 
+func Services(items ...metav1.Object) *APIResource {
+	return &APIResource{
+		Group:      "",
+		Version:    "v1",
+		Name:       "services",
+		ShortName:  "svc",
+		Namespaced: true,
+		Items:      items,
+	}
+}
+
+func Nodes(items ...metav1.Object) *APIResource {
+	return &APIResource{
+		Group:      "",
+		Version:    "v1",
+		Name:       "nodes",
+		ShortName:  "no",
+		Namespaced: false,
+		Items:      items,
+	}
+}
+
+func ResourceQuotas(items ...metav1.Object) *APIResource {
+	return &APIResource{
+		Group:      "",
+		Version:    "v1",
+		Name:       "resourcequotas",
+		ShortName:  "quota",
+		Namespaced: true,
+		Items:      items,
+	}
+}
+
+func StorageClasses(items ...metav1.Object) *APIResource {
+	return &APIResource{
+		Group:      "storage.k8s.io",
+		Version:    "v1",
+		Name:       "storageclasses",
+		ShortName:  "sc",
+		Namespaced: false,
+		Items:      items,
+	}
+}
+
+func CSIDrivers(items ...metav1.Object) *APIResource {
+	return &APIResource{
+		Group:      "storage.k8s.io",
+		Version:    "v1beta1",
+		Name:       "csidrivers",
+		Namespaced: false,
+		Items:      items,
+	}
+}
+
+func ClusterRoles(items ...metav1.Object) *APIResource {
+	return &APIResource{
+		Group:      "rbac.authorization.k8s.io",
+		Version:    "v1",
+		Name:       "clusterroles",
+		Namespaced: false,
+		Items:      items,
+	}
+}
+
+func Roles(items ...metav1.Object) *APIResource {
+	return &APIResource{
+		Group:      "rbac.authorization.k8s.io",
+		Version:    "v1",
+		Name:       "roles",
+		Namespaced: true,
+		Items:      items,
+	}
+}
+
+func RoleBindings(items ...metav1.Object) *APIResource {
+	return &APIResource{
+		Group:      "rbac.authorization.k8s.io",
+		Version:    "v1",
+		Name:       "rolebindings",
+		Namespaced: true,
+		Items:      items,
+	}
+}
+
+func CRDs(items ...metav1.Object) *APIResource {
+	return &APIResource{
+		Group:      "apiextensions.k8s.io",
+		Version:    "v1beta1",
+		Name:       "customresourcedefinitions",
+		ShortName:  "crd",
+		Namespaced: false,
+		Items:      items,
+	}
+}
+
 type ObjectOpts func(metav1.Object)
 
 func NewPod(ns, name string, opts ...ObjectOpts) *corev1.Pod {
@@ -200,6 +322,22 @@ func NewSecret(ns, name string, opts ...ObjectOpts) *corev1.Secret {
 	return obj
 }
 
+func NewConfigMap(ns, name string, opts ...ObjectOpts) *corev1.ConfigMap {
+	obj := &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: objectMeta(ns, name),
+	}
+
+	for _, opt := range opts {
+		opt(obj)
+	}
+
+	return obj
+}
+
 func NewDeployment(ns, name string, opts ...ObjectOpts) *appsv1.Deployment {
 	obj := &appsv1.Deployment{
 		TypeMeta: metav1.TypeMeta{
@@ -216,6 +354,22 @@ func NewDeployment(ns, name string, opts ...ObjectOpts) *appsv1.Deployment {
 	return obj
 }
 
+func NewJob(ns, name string, opts ...ObjectOpts) *batchv1.Job {
+	obj := &batchv1.Job{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Job",
+			APIVersion: "batch/v1",
+		},
+		ObjectMeta: objectMeta(ns, name),
+	}
+
+	for _, opt := range opts {
+		opt(obj)
+	}
+
+	return obj
+}
+
 func NewServiceAccount(ns, name string, opts ...ObjectOpts) *corev1.ServiceAccount {
 	obj := &corev1.ServiceAccount{
 		TypeMeta: metav1.TypeMeta{
@@ -232,6 +386,54 @@ func NewServiceAccount(ns, name string, opts ...ObjectOpts) *corev1.ServiceAccou
 	return obj
 }
 
+func NewService(ns, name string, opts ...ObjectOpts) *corev1.Service {
+	obj := &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Service",
+			APIVersion: "v1",
+		},
+		ObjectMeta: objectMeta(ns, name),
+	}
+
+	for _, opt := range opts {
+		opt(obj)
+	}
+
+	return obj
+}
+
+func NewNode(name string, opts ...ObjectOpts) *corev1.Node {
+	obj := &corev1.Node{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Node",
+			APIVersion: "v1",
+		},
+		ObjectMeta: objectMeta("", name),
+	}
+
+	for _, opt := range opts {
+		opt(obj)
+	}
+
+	return obj
+}
+
+func NewResourceQuota(ns, name string, opts ...ObjectOpts) *corev1.ResourceQuota {
+	obj := &corev1.ResourceQuota{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ResourceQuota",
+			APIVersion: "v1",
+		},
+		ObjectMeta: objectMeta(ns, name),
+	}
+
+	for _, opt := range opts {
+		opt(obj)
+	}
+
+	return obj
+}
+
 func NewNamespace(name string, opts ...ObjectOpts) *corev1.Namespace {
 	obj := &corev1.Namespace{
 		TypeMeta: metav1.TypeMeta{
@@ -248,6 +450,102 @@ func NewNamespace(name string, opts ...ObjectOpts) *corev1.Namespace {
 	return obj
 }
 
+func NewStorageClass(name string, opts ...ObjectOpts) *storagev1.StorageClass {
+	obj := &storagev1.StorageClass{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "StorageClass",
+			APIVersion: "storage.k8s.io/v1",
+		},
+		ObjectMeta: objectMeta("", name),
+	}
+
+	for _, opt := range opts {
+		opt(obj)
+	}
+
+	return obj
+}
+
+func NewCSIDriver(name string, opts ...ObjectOpts) *storagev1beta1.CSIDriver {
+	obj := &storagev1beta1.CSIDriver{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "CSIDriver",
+			APIVersion: "storage.k8s.io/v1beta1",
+		},
+		ObjectMeta: objectMeta("", name),
+	}
+
+	for _, opt := range opts {
+		opt(obj)
+	}
+
+	return obj
+}
+
+func NewClusterRole(name string, opts ...ObjectOpts) *rbacv1.ClusterRole {
+	obj := &rbacv1.ClusterRole{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ClusterRole",
+			APIVersion: "rbac.authorization.k8s.io/v1",
+		},
+		ObjectMeta: objectMeta("", name),
+	}
+
+	for _, opt := range opts {
+		opt(obj)
+	}
+
+	return obj
+}
+
+func NewRole(ns, name string, opts ...ObjectOpts) *rbacv1.Role {
+	obj := &rbacv1.Role{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Role",
+			APIVersion: "rbac.authorization.k8s.io/v1",
+		},
+		ObjectMeta: objectMeta(ns, name),
+	}
+
+	for _, opt := range opts {
+		opt(obj)
+	}
+
+	return obj
+}
+
+func NewRoleBinding(ns, name string, opts ...ObjectOpts) *rbacv1.RoleBinding {
+	obj := &rbacv1.RoleBinding{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "RoleBinding",
+			APIVersion: "rbac.authorization.k8s.io/v1",
+		},
+		ObjectMeta: objectMeta(ns, name),
+	}
+
+	for _, opt := range opts {
+		opt(obj)
+	}
+
+	return obj
+}
+
+func NewCRD(name string, opts ...ObjectOpts) *apiextv1beta1.CustomResourceDefinition {
+	obj := &apiextv1beta1.CustomResourceDefinition{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "CustomResourceDefinition",
+			APIVersion: "apiextensions.k8s.io/v1beta1",
+		},
+		ObjectMeta: objectMeta("", name),
+	}
+
+	for _, opt := range opts {
+		opt(obj)
+	}
+
+	return obj
+}
+
 func objectMeta(ns, name string) metav1.ObjectMeta {
 	return metav1.ObjectMeta{
 		Namespace: ns,
@@ -315,6 +613,12 @@ func WithFinalizers(vals ...string) func(obj metav1.Object) {
 
 // WithDeletionTimestamp is a functional option that applies the specified
 // deletion timestamp to an object.
+func WithCreationTimestamp(val time.Time) func(obj metav1.Object) {
+	return func(obj metav1.Object) {
+		obj.SetCreationTimestamp(metav1.Time{Time: val})
+	}
+}
+
 func WithDeletionTimestamp(val time.Time) func(obj metav1.Object) {
 	return func(obj metav1.Object) {
 		obj.SetDeletionTimestamp(&metav1.Time{Time: val})
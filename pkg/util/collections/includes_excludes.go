@@ -24,6 +24,9 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 )
 
+// globStringSet is a set of items that are matched as glob patterns. Patterns
+// containing a '/' are matched against the "group/resource" form of group-qualified
+// resources, e.g. "apps/*" matches "deployments.apps".
 type globStringSet struct {
 	sets.String
 }
@@ -33,23 +36,49 @@ func newGlobStringSet() globStringSet {
 }
 
 func (gss globStringSet) match(match string) bool {
-	for _, item := range gss.List() {
-		g, err := glob.Compile(item)
+	for _, pattern := range gss.List() {
+		if pattern == match {
+			return true
+		}
+
+		name := match
+		if strings.Contains(pattern, "/") {
+			name = groupResourcePath(match)
+		}
+		g, err := glob.Compile(pattern)
 		if err != nil {
-			return false
+			continue
 		}
-		if g.Match(match) {
+		if g.Match(name) {
 			return true
 		}
 	}
 	return false
 }
 
+// groupResourcePath returns the "group/resource" form of the provided group-qualified
+// resource, e.g. "apps/deployments" for "deployments.apps". Resources in the core
+// group have an empty group, e.g. "/pods".
+func groupResourcePath(groupResource string) string {
+	parts := strings.SplitN(groupResource, ".", 2)
+	if len(parts) == 1 {
+		return "/" + parts[0]
+	}
+	return parts[1] + "/" + parts[0]
+}
+
+// isGlobPattern returns true if the provided item contains any of the special
+// characters of a glob pattern.
+func isGlobPattern(item string) bool {
+	return strings.ContainsAny(item, `*?[{\`)
+}
+
 // IncludesExcludes is a type that manages lists of included
 // and excluded items. The logic implemented is that everything
 // in the included list except those items in the excluded list
-// should be included. '*' in the includes list means "include
-// everything", but it is not valid in the exclude list.
+// should be included. Items in either list may be glob patterns.
+// '*' in the includes list means "include everything", but it is
+// not valid in the exclude list.
 type IncludesExcludes struct {
 	includes globStringSet
 	excludes globStringSet
@@ -148,18 +177,25 @@ func ValidateIncludesExcludes(includesList, excludesList []string) []error {
 		}
 	}
 
+	for _, itm := range includes.Union(excludes).List() {
+		if _, err := glob.Compile(itm); err != nil {
+			errs = append(errs, errors.Errorf("invalid pattern: %v", itm))
+		}
+	}
+
 	return errs
 }
 
 // GenerateIncludesExcludes constructs an IncludesExcludes struct by taking the provided
 // include/exclude slices, applying the specified mapping function to each item in them,
 // and adding the output of the function to the new struct. If the mapping function returns
-// an empty string for an item, it is omitted from the result.
+// an empty string for an item, it is omitted from the result. Glob patterns are added as-is,
+// since they can't be mapped.
 func GenerateIncludesExcludes(includes, excludes []string, mapFunc func(string) string) *IncludesExcludes {
 	res := NewIncludesExcludes()
 
 	for _, item := range includes {
-		if item == "*" {
+		if isGlobPattern(item) {
 			res.Includes(item)
 			continue
 		}
@@ -172,6 +208,12 @@ func GenerateIncludesExcludes(includes, excludes []string, mapFunc func(string)
 	}
 
 	for _, item := range excludes {
+		// '*' isn't a valid exclude, so it's left to the mapping function
+		if item != "*" && isGlobPattern(item) {
+			res.Excludes(item)
+			continue
+		}
+
 		key := mapFunc(item)
 		if key == "" {
 			continue
@@ -102,6 +102,61 @@ func TestShouldInclude(t *testing.T) {
 			check:    "bar.foo",
 			should:   true,
 		},
+		{
+			name:     "group pattern include",
+			includes: []string{"apps/*"},
+			check:    "deployments.apps",
+			should:   true,
+		},
+		{
+			name:     "group pattern include doesn't match core resources",
+			includes: []string{"apps/*"},
+			check:    "pods",
+			should:   false,
+		},
+		{
+			name:     "group pattern include doesn't match other groups",
+			includes: []string{"apps/*"},
+			check:    "deployments.extensions",
+			should:   false,
+		},
+		{
+			name:     "group pattern exclude",
+			includes: []string{"*"},
+			excludes: []string{"apps/*"},
+			check:    "statefulsets.apps",
+			should:   false,
+		},
+		{
+			name:     "group suffix include",
+			includes: []string{"*.example.com"},
+			check:    "widgets.stable.example.com",
+			should:   true,
+		},
+		{
+			name:     "group suffix include fail",
+			includes: []string{"*.example.com"},
+			check:    "widgets.example.org",
+			should:   false,
+		},
+		{
+			name:     "character class include",
+			includes: []string{"ns-[12]"},
+			check:    "ns-2",
+			should:   true,
+		},
+		{
+			name:     "alternation include",
+			includes: []string{"{pods,secrets}"},
+			check:    "secrets",
+			should:   true,
+		},
+		{
+			name:     "invalid pattern only matches exactly",
+			includes: []string{"ns-["},
+			check:    "ns-[",
+			should:   true,
+		},
 	}
 
 	for _, test := range tests {
@@ -146,6 +201,16 @@ func TestValidateIncludesExcludes(t *testing.T) {
 			excludes: []string{"bar"},
 			expected: []error{errors.New("excludes list cannot contain an item in the includes list: bar")},
 		},
+		{
+			name:     "patterns are allowed",
+			includes: []string{"apps/*", "*.example.com"},
+			excludes: []string{"ns-?"},
+		},
+		{
+			name:     "invalid patterns are not allowed",
+			includes: []string{"foo["},
+			expected: []error{errors.New("invalid pattern: foo[")},
+		},
 	}
 
 	for _, test := range tests {
@@ -193,3 +258,19 @@ func TestIncludeExcludeString(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateIncludesExcludes(t *testing.T) {
+	mapFunc := func(item string) string {
+		if item == "unknown" {
+			return ""
+		}
+		return item + ".mapped"
+	}
+
+	ie := GenerateIncludesExcludes([]string{"foo", "unknown", "apps/*"}, []string{"bar", "*.example.com"}, mapFunc)
+
+	assert.Equal(t, []string{"apps/*", "foo.mapped"}, ie.GetIncludes())
+	assert.Equal(t, []string{"*.example.com", "bar.mapped"}, ie.GetExcludes())
+	assert.True(t, ie.ShouldInclude("deployments.apps"))
+	assert.False(t, ie.ShouldInclude("pods"))
+}
@@ -42,6 +42,7 @@ func TestEnsureNamespaceExistsAndIsReady(t *testing.T) {
 		expectCreate   bool
 		alreadyExists  bool
 		expectedResult bool
+		expectedCreate bool
 	}{
 		{
 			name:           "namespace found, not deleting",
@@ -64,6 +65,7 @@ func TestEnsureNamespaceExistsAndIsReady(t *testing.T) {
 			name:           "namespace not found, successfully created",
 			expectCreate:   true,
 			expectedResult: true,
+			expectedCreate: true,
 		},
 		{
 			name:           "namespace not found initially, create returns already exists error, returned namespace is ready",
@@ -113,9 +115,10 @@ func TestEnsureNamespaceExistsAndIsReady(t *testing.T) {
 				nsClient.On("Create", namespace).Return(namespace, nil)
 			}
 
-			result, _ := EnsureNamespaceExistsAndIsReady(namespace, nsClient, timeout)
+			result, created, _ := EnsureNamespaceExistsAndIsReady(namespace, nsClient, timeout)
 
 			assert.Equal(t, test.expectedResult, result)
+			assert.Equal(t, test.expectedCreate, created)
 		})
 	}
 
@@ -0,0 +1,136 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"bytes"
+	"sync"
+)
+
+// LogBuffer is an io.Writer that keeps the most recent lines written to it, up
+// to a maximum number of lines, so that they can be read while they're still
+// being written. Lines are numbered from 0 in the order they're written,
+// including the ones that no longer fit in the buffer.
+type LogBuffer struct {
+	mu sync.RWMutex
+
+	// lines is a ring of the retained lines, where line n is at
+	// lines[n % len(lines)].
+	lines   []string
+	total   int64
+	partial []byte
+}
+
+// NewLogBuffer returns a LogBuffer that keeps at most maxLines lines.
+func NewLogBuffer(maxLines int) *LogBuffer {
+	if maxLines < 1 {
+		maxLines = 1
+	}
+
+	return &LogBuffer{
+		lines: make([]string, maxLines),
+	}
+}
+
+// Write adds the complete lines in p to the buffer, evicting the oldest lines
+// once it's full. A trailing partial line is kept until the rest of it is written.
+func (b *LogBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	data := append(b.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+
+		b.lines[b.total%int64(len(b.lines))] = string(data[:i])
+		b.total++
+		data = data[i+1:]
+	}
+	b.partial = append([]byte(nil), data...)
+
+	return len(p), nil
+}
+
+// Lines returns the retained lines numbered offset and later, and the number of
+// the next line to be written, to pass as the offset of the next call. Lines
+// numbered offset and later that have already been evicted are skipped.
+func (b *LogBuffer) Lines(offset int64) ([]string, int64) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	first := b.total - int64(len(b.lines))
+	if first < 0 {
+		first = 0
+	}
+	if offset < first {
+		offset = first
+	}
+
+	var lines []string
+	for n := offset; n < b.total; n++ {
+		lines = append(lines, b.lines[n%int64(len(b.lines))])
+	}
+	return lines, b.total
+}
+
+// LogBuffers are the log buffers of running operations, such as restores, keyed
+// by the operation's namespace and name.
+type LogBuffers struct {
+	mu sync.RWMutex
+
+	maxLines int
+	buffers  map[string]*LogBuffer
+}
+
+// NewLogBuffers returns a LogBuffers whose buffers keep at most maxLines lines.
+func NewLogBuffers(maxLines int) *LogBuffers {
+	return &LogBuffers{
+		maxLines: maxLines,
+		buffers:  make(map[string]*LogBuffer),
+	}
+}
+
+// Add returns a new, empty log buffer for the specified key, replacing any
+// existing buffer for it.
+func (b *LogBuffers) Add(key string) *LogBuffer {
+	buffer := NewLogBuffer(b.maxLines)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.buffers[key] = buffer
+	return buffer
+}
+
+// Get returns the log buffer for the specified key, or nil if there isn't one.
+func (b *LogBuffers) Get(key string) *LogBuffer {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.buffers[key]
+}
+
+// Remove removes the log buffer for the specified key, if there is one.
+func (b *LogBuffers) Remove(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.buffers, key)
+}
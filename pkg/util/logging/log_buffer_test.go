@@ -0,0 +1,100 @@
+/*
+Copyright 2019 the Velero contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogBuffer(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxLines  int
+		writes    []string
+		offset    int64
+		wantLines []string
+		wantNext  int64
+	}{
+		{
+			name:      "complete lines are returned in order",
+			maxLines:  10,
+			writes:    []string{"line-0\nline-1\n", "line-2\n"},
+			wantLines: []string{"line-0", "line-1", "line-2"},
+			wantNext:  3,
+		},
+		{
+			name:      "a partial line isn't returned until it's complete",
+			maxLines:  10,
+			writes:    []string{"line-0\nli", "ne-1\nline-"},
+			wantLines: []string{"line-0", "line-1"},
+			wantNext:  2,
+		},
+		{
+			name:      "lines before the offset aren't returned",
+			maxLines:  10,
+			writes:    []string{"line-0\nline-1\nline-2\n"},
+			offset:    2,
+			wantLines: []string{"line-2"},
+			wantNext:  3,
+		},
+		{
+			name:      "evicted lines are skipped",
+			maxLines:  2,
+			writes:    []string{"line-0\nline-1\nline-2\nline-3\n"},
+			offset:    1,
+			wantLines: []string{"line-2", "line-3"},
+			wantNext:  4,
+		},
+		{
+			name:     "an offset past the written lines returns no lines",
+			maxLines: 2,
+			writes:   []string{"line-0\n"},
+			offset:   5,
+			wantNext: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			buffer := NewLogBuffer(tc.maxLines)
+			for _, w := range tc.writes {
+				n, err := fmt.Fprint(buffer, w)
+				assert.NoError(t, err)
+				assert.Equal(t, len(w), n)
+			}
+
+			lines, next := buffer.Lines(tc.offset)
+			assert.Equal(t, tc.wantLines, lines)
+			assert.Equal(t, tc.wantNext, next)
+		})
+	}
+}
+
+func TestLogBuffers(t *testing.T) {
+	buffers := NewLogBuffers(10)
+	assert.Nil(t, buffers.Get("ns-1/restore-1"))
+
+	buffer := buffers.Add("ns-1/restore-1")
+	assert.Equal(t, buffer, buffers.Get("ns-1/restore-1"))
+	assert.Nil(t, buffers.Get("ns-1/restore-2"))
+
+	buffers.Remove("ns-1/restore-1")
+	assert.Nil(t, buffers.Get("ns-1/restore-1"))
+}
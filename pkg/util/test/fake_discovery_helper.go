@@ -23,6 +23,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/version"
 	"k8s.io/client-go/discovery"
 )
 
@@ -31,6 +32,7 @@ type FakeDiscoveryHelper struct {
 	Mapper             meta.RESTMapper
 	AutoReturnResource bool
 	APIGroupsList      []metav1.APIGroup
+	ServerVersionInfo  *version.Info
 }
 
 func NewFakeDiscoveryHelper(autoReturnResource bool, resources map[schema.GroupVersionResource]schema.GroupVersionResource) *FakeDiscoveryHelper {
@@ -125,6 +127,10 @@ func (dh *FakeDiscoveryHelper) APIGroups() []metav1.APIGroup {
 	return dh.APIGroupsList
 }
 
+func (dh *FakeDiscoveryHelper) ServerVersion() *version.Info {
+	return dh.ServerVersionInfo
+}
+
 type FakeServerResourcesInterface struct {
 	ResourceList []*metav1.APIResourceList
 	FailedGroups map[schema.GroupVersion]error
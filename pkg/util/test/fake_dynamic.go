@@ -68,3 +68,8 @@ func (c *FakeDynamicClient) Patch(name string, data []byte) (*unstructured.Unstr
 	args := c.Called(name, data)
 	return args.Get(0).(*unstructured.Unstructured), args.Error(1)
 }
+
+func (c *FakeDynamicClient) Apply(obj *unstructured.Unstructured, fieldManager string, force bool) (*unstructured.Unstructured, error) {
+	args := c.Called(obj, fieldManager, force)
+	return args.Get(0).(*unstructured.Unstructured), args.Error(1)
+}
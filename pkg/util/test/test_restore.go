@@ -80,6 +80,21 @@ func (r *TestRestore) WithRestorePVs(value bool) *TestRestore {
 	return r
 }
 
+func (r *TestRestore) WithIncludeClusterResources(value bool) *TestRestore {
+	r.Spec.IncludeClusterResources = &value
+	return r
+}
+
+func (r *TestRestore) WithScopeFilter(filter api.ScopeFilter) *TestRestore {
+	r.Spec.ScopeFilter = filter
+	return r
+}
+
+func (r *TestRestore) WithClusterScopedOnly(value bool) *TestRestore {
+	r.Spec.ClusterScopedOnly = value
+	return r
+}
+
 func (r *TestRestore) WithMappedNamespace(from string, to string) *TestRestore {
 	if r.Spec.NamespaceMapping == nil {
 		r.Spec.NamespaceMapping = make(map[string]string)
@@ -97,3 +112,8 @@ func (r *TestRestore) WithExcludedResource(resource string) *TestRestore {
 	r.Spec.ExcludedResources = append(r.Spec.ExcludedResources, resource)
 	return r
 }
+
+func (r *TestRestore) WithReplayRestoreName(name string) *TestRestore {
+	r.Spec.ReplayRestoreName = name
+	return r
+}
@@ -54,6 +54,11 @@ func (s *TestSchedule) WithCronSchedule(cronExpression string) *TestSchedule {
 	return s
 }
 
+func (s *TestSchedule) WithPaused(paused bool) *TestSchedule {
+	s.Spec.Paused = paused
+	return s
+}
+
 func (s *TestSchedule) WithLastBackupTime(timeString string) *TestSchedule {
 	t, _ := time.Parse("2006-01-02 15:04:05", timeString)
 	s.Status.LastBackup = metav1.Time{Time: t}
@@ -53,6 +53,21 @@ type SnapshotSpec struct {
 	// VolumeIOPS is the optional value of provisioned IOPS for the
 	// disk/volume in the cloud provider API.
 	VolumeIOPS *int64 `json:"volumeIOPS,omitempty"`
+
+	// CSI identifies the CSI VolumeSnapshot of the volume's claim, if the
+	// volume was snapshotted through CSI rather than a volume snapshotter.
+	CSI *CSISnapshotSpec `json:"csi,omitempty"`
+}
+
+// CSISnapshotSpec identifies the CSI VolumeSnapshot taken of a persistent
+// volume's claim as part of a Velero backup.
+type CSISnapshotSpec struct {
+	// VolumeSnapshotName is the name of the VolumeSnapshot, which is in
+	// the namespace of the volume's claim.
+	VolumeSnapshotName string `json:"volumeSnapshotName"`
+
+	// Driver is the CSI driver that took the snapshot.
+	Driver string `json:"driver,omitempty"`
 }
 
 type SnapshotStatus struct {